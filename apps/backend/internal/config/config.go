@@ -25,6 +25,9 @@ type Config struct {
 	Khalti        *KhaltiConfig        `koanf:"khalti"`
 	AWS           AWSConfig            `koanf:"aws" validate:"required"`
 	Stripe        *StripeConfig        `koanf:"stripe"`
+	SMS           *SMSConfig           `koanf:"sms"`
+	Push          *PushConfig          `koanf:"push"`
+	Email         *EmailConfig         `koanf:"email"`
 }
 
 type KafkaConfig struct {
@@ -64,12 +67,35 @@ type StripeConfig struct {
 	WebhookSecret string   `koanf:"webhook_secret"`
 }
 
+// SMSConfig selects the SMS gateway. Without it (or with provider "log")
+// messages are only written to the log, e.g. in development.
+type SMSConfig struct {
+	Provider string `koanf:"provider" validate:"required,oneof=sparrow aakash log"`
+	Token    string `koanf:"token" validate:"required_unless=Provider log"`
+	From     string `koanf:"from"` // Sparrow sender identity
+}
+
+// PushConfig holds the VAPID key pair web push is signed with (base64url,
+// as printed by `npx web-push generate-vapid-keys`). Without it the push
+// channel is off.
+type PushConfig struct {
+	VAPIDPublicKey  string `koanf:"vapid_public_key" validate:"required"`
+	VAPIDPrivateKey string `koanf:"vapid_private_key" validate:"required"`
+	Subject         string `koanf:"subject" validate:"required"` // mailto: or https: contact for push services
+}
+
 type ElasticsearchConfig struct {
 	Address string `koanf:"address" validate:"required"`
 }
 
 type Primary struct {
 	Env string `koanf:"env" validate:"required"`
+	// Timezone is the IANA zone the business runs in; reports are bucketed
+	// in its local time. Defaults to Asia/Kathmandu.
+	Timezone string `koanf:"timezone" validate:"omitempty,timezone"`
+	// FrontendURL is where links in emails point, e.g. to accept a vendor
+	// invitation. Defaults to the Stripe frontend URL.
+	FrontendURL string `koanf:"frontend_url" validate:"omitempty,url"`
 }
 
 type ServerConfig struct {
@@ -100,8 +126,34 @@ type IntegrationConfig struct {
 	ResendAPIKey string `koanf:"resend_api_key" validate:"required"`
 }
 
+// EmailConfig selects how transactional emails are sent and who they come
+// from. Without it they go through Resend as "Khajaride
+// <onboarding@resend.dev>", Resend's test sender.
+type EmailConfig struct {
+	// Provider is resend (default), smtp, file (writes .eml files to FileDir)
+	// or memory (keeps them in the process, e.g. in tests)
+	Provider    string      `koanf:"provider" validate:"omitempty,oneof=resend smtp file memory"`
+	FromName    string      `koanf:"from_name"`
+	FromAddress string      `koanf:"from_address" validate:"omitempty,email"`
+	ReplyTo     string      `koanf:"reply_to" validate:"omitempty,email"`
+	SMTP        *SMTPConfig `koanf:"smtp" validate:"required_if=Provider smtp"`
+	FileDir     string      `koanf:"file_dir"`
+	// WebhookSecret verifies Resend's delivery webhooks (Svix signed)
+	WebhookSecret string `koanf:"webhook_secret"`
+}
+
+type SMTPConfig struct {
+	Host     string `koanf:"host" validate:"required"`
+	Port     int    `koanf:"port" validate:"required"`
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+}
+
 type AuthConfig struct {
 	SecretKey string `koanf:"secret_key" validate:"required"`
+	// ClerkUsersFile replaces the Clerk API with a local JSON dump of users
+	// when reconciling, e.g. in development
+	ClerkUsersFile string `koanf:"clerk_users_file"`
 }
 type WebhookConfig struct {
 	ClerkWebhookSigningSecret string `koanf:"clerk_webhook_signing_secret" validate:"required"`
@@ -133,6 +185,13 @@ func LoadConfig() (*Config, error) {
 		logger.Fatal().Err(err).Msg("config validation failed")
 	}
 
+	if mainConfig.Primary.Timezone == "" {
+		mainConfig.Primary.Timezone = "Asia/Kathmandu"
+	}
+	if mainConfig.Primary.FrontendURL == "" && mainConfig.Stripe != nil {
+		mainConfig.Primary.FrontendURL = mainConfig.Stripe.FrontEndURL
+	}
+
 	// Set default observability config if not provided
 	if mainConfig.Observability == nil {
 		mainConfig.Observability = DefaultObservabilityConfig()
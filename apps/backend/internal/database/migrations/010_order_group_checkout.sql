@@ -0,0 +1,91 @@
+-- =========================
+-- ORDER GROUPS: one checkout per cart session
+-- =========================
+
+ALTER TABLE order_groups
+    ADD COLUMN cart_session_id TEXT REFERENCES cart_sessions(id) ON DELETE SET NULL;
+
+ALTER TABLE order_groups DROP CONSTRAINT IF EXISTS order_groups_payment_status_check;
+ALTER TABLE order_groups
+    ADD CONSTRAINT order_groups_payment_status_check CHECK (
+        payment_status IN ('unpaid', 'paid', 'partially_refunded', 'refunded', 'failed')
+    );
+
+CREATE INDEX idx_order_groups_user_id ON order_groups(user_id);
+CREATE INDEX idx_order_groups_cart_session_id ON order_groups(cart_session_id);
+
+
+ALTER TABLE order_vendors
+    ADD COLUMN order_group_id TEXT REFERENCES order_groups(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_order_vendors_order_group_id ON order_vendors(order_group_id);
+
+
+
+-- =========================
+-- ORDER GROUP PAYMENTS (one combined payment per group)
+-- =========================
+
+CREATE TABLE order_group_payments (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    order_group_id TEXT NOT NULL REFERENCES order_groups(id) ON DELETE CASCADE,
+    payment_gateway TEXT,
+    transaction_id TEXT,
+    charge_ref TEXT,                 -- stripe charge id, used as source_transaction for transfers
+    amount NUMERIC(12,2) NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('initiated', 'success', 'failed', 'refunded', 'partially_refunded')),
+    method TEXT,
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_order_group_payments_group_id ON order_group_payments(order_group_id);
+CREATE INDEX idx_order_group_payments_transaction_id ON order_group_payments(transaction_id);
+
+CREATE TRIGGER set_updated_at_order_group_payments
+    BEFORE UPDATE ON order_group_payments
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- ORDER VENDOR SPLITS (per-vendor share of a group payment)
+-- =========================
+
+CREATE TABLE order_vendor_splits (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    order_group_id TEXT NOT NULL REFERENCES order_groups(id) ON DELETE CASCADE,
+    order_vendor_id TEXT NOT NULL UNIQUE REFERENCES order_vendors(id) ON DELETE CASCADE,
+    vendor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    stripe_account_id TEXT,
+
+    amount NUMERIC(12,2) NOT NULL,        -- what the customer paid for this vendor
+    commission NUMERIC(12,2) NOT NULL DEFAULT 0,
+    vendor_share NUMERIC(12,2) GENERATED ALWAYS AS (amount - commission) STORED,
+
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (
+        status IN (
+            'pending',         -- awaiting payment / settlement
+            'transferred',     -- moved to the vendor's connected account
+            'transfer_failed', -- payment ok but transfer failed, retry later
+            'refund_pending',  -- vendor rejected, refund must be issued manually
+            'refunded'         -- vendor rejected and customer refunded
+        )
+    ),
+    transfer_ref TEXT,
+    refund_ref TEXT,
+    remarks TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_vendor_splits_group_id ON order_vendor_splits(order_group_id);
+CREATE INDEX idx_order_vendor_splits_status ON order_vendor_splits(status);
+
+CREATE TRIGGER set_updated_at_order_vendor_splits
+    BEFORE UPDATE ON order_vendor_splits
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
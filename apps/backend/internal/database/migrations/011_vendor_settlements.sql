@@ -0,0 +1,158 @@
+-- =========================
+-- COMMISSION RATE & SETTLEMENT FREQUENCY
+-- =========================
+
+-- percentage kept by the platform, same unit as vendors.vat / vendor_service_charge
+ALTER TABLE vendors
+    ADD COLUMN commission_rate NUMERIC(5,2) NOT NULL DEFAULT 3.00 CHECK (commission_rate >= 0 AND commission_rate <= 100);
+
+ALTER TABLE payout_accounts
+    ADD COLUMN settlement_frequency TEXT NOT NULL DEFAULT 'weekly' CHECK (settlement_frequency IN ('daily', 'weekly'));
+
+
+
+-- =========================
+-- VENDOR SETTLEMENTS (one batch per payout account and period)
+-- =========================
+
+CREATE TABLE vendor_settlements (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    payout_account_id TEXT NOT NULL REFERENCES payout_accounts(id),
+    vendor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'manual')),
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+
+    gross_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    commission_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    refund_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    adjustment_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    net_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'USD',
+
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (
+        status IN (
+            'pending',     -- batched, payout not sent yet
+            'processing',  -- payout sent, waiting for the provider
+            'paid',
+            'failed'       -- payout failed, retried until max attempts
+        )
+    ),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_retry_at TIMESTAMPTZ,
+    payout_id TEXT REFERENCES payouts(id) ON DELETE SET NULL,
+    transaction_ref TEXT,
+    paid_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vendor_settlements_account ON vendor_settlements(payout_account_id);
+CREATE INDEX idx_vendor_settlements_vendor_user ON vendor_settlements(vendor_user_id);
+CREATE INDEX idx_vendor_settlements_status ON vendor_settlements(status);
+
+CREATE TRIGGER set_updated_at_vendor_settlements
+    BEFORE UPDATE ON vendor_settlements
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- VENDOR EARNINGS (accrual ledger, one row per money movement)
+-- =========================
+
+CREATE TABLE vendor_earnings (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    vendor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    payout_account_id TEXT REFERENCES payout_accounts(id) ON DELETE SET NULL,
+    order_id TEXT REFERENCES order_vendors(id) ON DELETE SET NULL,
+
+    entry_type TEXT NOT NULL CHECK (entry_type IN ('order', 'refund', 'adjustment')),
+    gross_amount NUMERIC(12,2) NOT NULL DEFAULT 0,  -- signed: refunds are negative
+    commission_rate NUMERIC(5,2) NOT NULL DEFAULT 0,
+    commission NUMERIC(12,2) NOT NULL DEFAULT 0,     -- signed: refunds give commission back
+    net_amount NUMERIC(12,2) GENERATED ALWAYS AS (gross_amount - commission) STORED,
+    description TEXT,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+
+    settlement_id TEXT REFERENCES vendor_settlements(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vendor_earnings_vendor_user ON vendor_earnings(vendor_user_id);
+CREATE INDEX idx_vendor_earnings_unsettled ON vendor_earnings(payout_account_id) WHERE settlement_id IS NULL;
+CREATE INDEX idx_vendor_earnings_settlement ON vendor_earnings(settlement_id);
+
+-- an order accrues at most once, and is refunded at most once
+CREATE UNIQUE INDEX idx_vendor_earnings_order_entry
+    ON vendor_earnings(order_id, entry_type)
+    WHERE order_id IS NOT NULL AND entry_type IN ('order', 'refund');
+
+
+
+-- =========================
+-- HELPER FUNCTION: accrue_vendor_earning(order_vendor_id)
+-- =========================
+
+CREATE OR REPLACE FUNCTION accrue_vendor_earning(p_order_vendor_id TEXT)
+RETURNS VOID LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO vendor_earnings (
+        vendor_id, vendor_user_id, payout_account_id, order_id,
+        entry_type, gross_amount, commission_rate, commission, description
+    )
+    SELECT
+        v.id,
+        v.vendor_user_id,
+        (
+            SELECT pa.id FROM payout_accounts pa
+            WHERE pa.owner_id = v.vendor_user_id AND pa.owner_type = 'vendor'
+            ORDER BY pa.is_default DESC, pa.created_at
+            LIMIT 1
+        ),
+        ov.id,
+        'order',
+        ov.total,
+        v.commission_rate,
+        ROUND(ov.total * v.commission_rate / 100, 2),
+        'Order ' || ov.id
+    FROM order_vendors ov
+    JOIN vendors v ON v.id = ov.vendor_id
+    WHERE ov.id = p_order_vendor_id
+    ON CONFLICT DO NOTHING;
+END;
+$$;
+
+
+-- =========================
+-- HELPER FUNCTION: accrue_vendor_refund(order_vendor_id, amount)
+-- =========================
+
+-- Reverses the earning of a refunded order (or part of it). The commission is
+-- given back pro rata to the refunded amount.
+CREATE OR REPLACE FUNCTION accrue_vendor_refund(p_order_vendor_id TEXT, p_amount NUMERIC)
+RETURNS VOID LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO vendor_earnings (
+        vendor_id, vendor_user_id, payout_account_id, order_id,
+        entry_type, gross_amount, commission_rate, commission, description
+    )
+    SELECT
+        e.vendor_id,
+        e.vendor_user_id,
+        e.payout_account_id,
+        e.order_id,
+        'refund',
+        -p_amount,
+        e.commission_rate,
+        -ROUND(p_amount * e.commission_rate / 100, 2),
+        'Refund of order ' || e.order_id
+    FROM vendor_earnings e
+    WHERE e.order_id = p_order_vendor_id AND e.entry_type = 'order'
+    ON CONFLICT DO NOTHING;
+END;
+$$;
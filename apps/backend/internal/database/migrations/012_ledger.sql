@@ -0,0 +1,117 @@
+-- =========================
+-- LEDGER ACCOUNTS
+-- =========================
+
+CREATE TABLE ledger_accounts (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    code TEXT NOT NULL UNIQUE,          -- e.g. platform:revenue, vendor_payable:<user_id>
+    name TEXT NOT NULL,
+
+    account_type TEXT NOT NULL CHECK (
+        account_type IN ('asset', 'liability', 'revenue', 'expense', 'equity')
+    ),
+    owner_type TEXT NOT NULL CHECK (owner_type IN ('platform', 'vendor', 'customer', 'gateway')),
+    owner_id TEXT,                      -- vendor/customer user id, gateway name, NULL for platform
+    currency TEXT NOT NULL DEFAULT 'USD',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ledger_accounts_owner ON ledger_accounts(owner_type, owner_id);
+
+
+
+-- =========================
+-- JOURNAL ENTRIES (immutable, one per money movement)
+-- =========================
+
+CREATE TABLE journal_entries (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    entry_type TEXT NOT NULL CHECK (
+        entry_type IN (
+            'payment_capture',   -- customer paid through a gateway
+            'commission',        -- platform keeps its cut of an order
+            'refund',            -- order refunded, owed back to the customer
+            'refund_disbursed',  -- refund sent back through the gateway
+            'payout',            -- vendor settlement paid out
+            'adjustment'         -- manual correction by an admin
+        )
+    ),
+    description TEXT,
+    reference_type TEXT,                -- order_vendor, vendor_settlement, vendor_earning ...
+    reference_id TEXT,
+    idempotency_key TEXT NOT NULL UNIQUE, -- same movement is never posted twice
+    currency TEXT NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_journal_entries_reference ON journal_entries(reference_type, reference_id);
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+
+
+CREATE TABLE journal_lines (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    entry_id TEXT NOT NULL REFERENCES journal_entries(id),
+    account_id TEXT NOT NULL REFERENCES ledger_accounts(id),
+    debit NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK ((debit = 0) <> (credit = 0))   -- exactly one side per line
+);
+
+CREATE INDEX idx_journal_lines_entry ON journal_lines(entry_id);
+CREATE INDEX idx_journal_lines_account ON journal_lines(account_id);
+
+
+
+-- =========================
+-- IMMUTABILITY: entries are corrected by posting new ones
+-- =========================
+
+CREATE OR REPLACE FUNCTION prevent_journal_mutation()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$;
+
+CREATE TRIGGER journal_entries_immutable
+    BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_journal_mutation();
+
+CREATE TRIGGER journal_lines_immutable
+    BEFORE UPDATE OR DELETE ON journal_lines
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_journal_mutation();
+
+
+
+-- =========================
+-- INVARIANT: debits equal credits, checked at commit
+-- =========================
+
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+    v_debit NUMERIC;
+    v_credit NUMERIC;
+BEGIN
+    SELECT COALESCE(SUM(debit), 0), COALESCE(SUM(credit), 0)
+    INTO v_debit, v_credit
+    FROM journal_lines
+    WHERE entry_id = NEW.entry_id;
+
+    IF v_debit <> v_credit THEN
+        RAISE EXCEPTION 'journal entry % is unbalanced: debit % credit %', NEW.entry_id, v_debit, v_credit;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+CREATE CONSTRAINT TRIGGER journal_lines_balanced
+    AFTER INSERT ON journal_lines
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_journal_entry_balanced();
@@ -0,0 +1,62 @@
+-- =========================
+-- INVOICE NUMBER SEQUENCES (one gapless counter per vendor)
+-- =========================
+
+CREATE TABLE vendor_invoice_sequences (
+    vendor_id TEXT PRIMARY KEY REFERENCES vendors(id) ON DELETE CASCADE,
+    last_number INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER set_updated_at_vendor_invoice_sequences
+    BEFORE UPDATE ON vendor_invoice_sequences
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- INVOICES (tax invoice / receipt, one per vendor order)
+-- =========================
+
+CREATE TABLE invoices (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    order_id TEXT NOT NULL UNIQUE REFERENCES order_vendors(id),
+    vendor_id TEXT NOT NULL REFERENCES vendors(id),
+    user_id TEXT NOT NULL REFERENCES users(id),
+
+    sequence_number INT NOT NULL,
+    invoice_number TEXT NOT NULL UNIQUE,      -- e.g. INV-1A2B3C-000042
+
+    -- snapshot at the time of issue, the invoice must not change afterwards
+    vendor_name TEXT NOT NULL,
+    vendor_pan_vat TEXT,                      -- from the approved pan_vat_registration document
+    vendor_address TEXT,
+    customer_name TEXT,
+    customer_email TEXT,
+
+    subtotal NUMERIC(10,2) NOT NULL,
+    delivery_charge NUMERIC(10,2) NOT NULL DEFAULT 0,
+    service_charge NUMERIC(10,2) NOT NULL DEFAULT 0,
+    service_charge_rate NUMERIC(10,2) NOT NULL DEFAULT 0,
+    vat NUMERIC(10,2) NOT NULL DEFAULT 0,
+    vat_rate NUMERIC(10,2) NOT NULL DEFAULT 0,
+    discount NUMERIC(10,2) NOT NULL DEFAULT 0,
+    total NUMERIC(10,2) NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'USD',
+
+    file_key TEXT,                            -- S3 key of the rendered PDF
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (vendor_id, sequence_number)
+);
+
+CREATE INDEX idx_invoices_vendor_id ON invoices(vendor_id);
+CREATE INDEX idx_invoices_user_id ON invoices(user_id);
+
+CREATE TRIGGER set_updated_at_invoices
+    BEFORE UPDATE ON invoices
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
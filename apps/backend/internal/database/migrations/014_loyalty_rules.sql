@@ -0,0 +1,116 @@
+-- =========================
+-- LOYALTY PROGRAM SETTINGS (single row)
+-- =========================
+
+CREATE TABLE loyalty_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    points_per_npr NUMERIC(10,4) NOT NULL DEFAULT 0.01 CHECK (points_per_npr >= 0), -- 1 point per NPR 100
+    expiry_days INT NOT NULL DEFAULT 365 CHECK (expiry_days > 0),                   -- lifetime of earned points
+    tier_window_days INT NOT NULL DEFAULT 365 CHECK (tier_window_days > 0),         -- rolling spend window for tiers
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO loyalty_settings DEFAULT VALUES;
+
+CREATE TRIGGER set_updated_at_loyalty_settings
+    BEFORE UPDATE ON loyalty_settings
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- CUSTOMER TIERS (by delivered spend in the rolling window)
+-- =========================
+
+CREATE TABLE loyalty_tiers (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    min_spend NUMERIC(12,2) NOT NULL UNIQUE CHECK (min_spend >= 0),
+    earn_multiplier NUMERIC(4,2) NOT NULL DEFAULT 1.00 CHECK (earn_multiplier > 0)
+);
+
+INSERT INTO loyalty_tiers (code, name, min_spend, earn_multiplier) VALUES
+    ('bronze',   'Bronze',        0, 1.00),
+    ('silver',   'Silver',    10000, 1.25),
+    ('gold',     'Gold',      50000, 1.50),
+    ('platinum', 'Platinum', 150000, 2.00);
+
+ALTER TABLE users
+    ADD COLUMN loyalty_tier TEXT NOT NULL DEFAULT 'bronze' REFERENCES loyalty_tiers(code);
+
+
+
+-- =========================
+-- VENDOR MULTIPLIERS & PROMOTIONS
+-- =========================
+
+ALTER TABLE vendors
+    ADD COLUMN loyalty_multiplier NUMERIC(4,2) NOT NULL DEFAULT 1.00 CHECK (loyalty_multiplier > 0);
+
+CREATE TABLE loyalty_promotions (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    name TEXT NOT NULL,                                    -- "Dashain double points"
+    multiplier NUMERIC(4,2) NOT NULL DEFAULT 2.00 CHECK (multiplier > 0),
+    vendor_id TEXT REFERENCES vendors(id) ON DELETE CASCADE, -- NULL = every vendor
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_loyalty_promotions_window ON loyalty_promotions(starts_at, ends_at) WHERE is_active;
+
+CREATE TRIGGER set_updated_at_loyalty_promotions
+    BEFORE UPDATE ON loyalty_promotions
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- POINT LOTS & EXPIRY
+-- =========================
+
+ALTER TABLE loyalty_points_ledger DROP CONSTRAINT IF EXISTS loyalty_points_ledger_transaction_type_check;
+ALTER TABLE loyalty_points_ledger
+    ADD CONSTRAINT loyalty_points_ledger_transaction_type_check CHECK (
+        transaction_type IN ('EARN', 'REDEEM', 'ADJUST', 'EXPIRE')
+    );
+
+-- every credit is a lot; debits consume the oldest lots first (FIFO)
+ALTER TABLE loyalty_points_ledger
+    ADD COLUMN remaining_points NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (remaining_points >= 0),
+    ADD COLUMN expires_at TIMESTAMP;
+
+-- existing credits become lots, minus what was already spent, oldest first
+WITH lots AS (
+    SELECT id, user_id, points_change,
+           SUM(points_change) OVER (PARTITION BY user_id ORDER BY performed_at, id) AS running
+    FROM loyalty_points_ledger
+    WHERE points_change > 0
+),
+spent AS (
+    SELECT user_id, -SUM(points_change) AS spent
+    FROM loyalty_points_ledger
+    WHERE points_change < 0
+    GROUP BY user_id
+)
+UPDATE loyalty_points_ledger l
+SET remaining_points = GREATEST(0, LEAST(lots.points_change, lots.running - COALESCE(spent.spent, 0))),
+    expires_at = l.performed_at + INTERVAL '365 days'
+FROM lots
+LEFT JOIN spent ON spent.user_id = lots.user_id
+WHERE l.id = lots.id;
+
+CREATE INDEX idx_loyalty_points_ledger_lots ON loyalty_points_ledger(user_id, performed_at) WHERE remaining_points > 0;
+CREATE INDEX idx_loyalty_points_ledger_expiry ON loyalty_points_ledger(expires_at) WHERE remaining_points > 0;
+
+-- an order earns points once
+CREATE UNIQUE INDEX uniq_loyalty_points_ledger_earn_reference
+    ON loyalty_points_ledger(reference_type, reference_id)
+    WHERE transaction_type = 'EARN' AND reference_id IS NOT NULL;
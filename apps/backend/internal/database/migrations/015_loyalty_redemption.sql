@@ -0,0 +1,84 @@
+-- =========================
+-- REDEMPTION RULES
+-- =========================
+
+ALTER TABLE loyalty_settings
+    ADD COLUMN npr_per_point NUMERIC(10,4) NOT NULL DEFAULT 1.00 CHECK (npr_per_point > 0),           -- value of one point at checkout
+    ADD COLUMN max_redeem_percent NUMERIC(5,2) NOT NULL DEFAULT 20.00 CHECK (max_redeem_percent BETWEEN 0 AND 100); -- of the subtotal
+
+
+
+-- =========================
+-- POINTS APPLIED TO CARTS & ORDERS
+-- =========================
+-- Points applied to a cart are only a reservation; they are written to
+-- loyalty_points_ledger when the order is paid.
+
+ALTER TABLE cart_vendors
+    ADD COLUMN loyalty_points_used NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (loyalty_points_used >= 0),
+    ADD COLUMN loyalty_discount NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (loyalty_discount >= 0);
+
+ALTER TABLE cart_vendors DROP COLUMN total;
+ALTER TABLE cart_vendors
+    ADD COLUMN total NUMERIC(10,2) GENERATED ALWAYS AS (
+        (subtotal + COALESCE(delivery_charge,0) + vat + vendor_service_charge) - vendor_discount - loyalty_discount
+    ) STORED;
+
+ALTER TABLE order_vendors
+    ADD COLUMN loyalty_points_used NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (loyalty_points_used >= 0),
+    ADD COLUMN loyalty_discount NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (loyalty_discount >= 0);
+
+ALTER TABLE order_vendors DROP COLUMN total;
+ALTER TABLE order_vendors
+    ADD COLUMN total NUMERIC(10,2) GENERATED ALWAYS AS (
+        (subtotal + delivery_charge + vat + vendor_service_charge) - vendor_discount - loyalty_discount
+    ) STORED;
+
+ALTER TABLE invoices
+    ADD COLUMN loyalty_discount NUMERIC(10,2) NOT NULL DEFAULT 0;
+
+-- an order redeems once and is reversed once
+CREATE UNIQUE INDEX uniq_loyalty_points_ledger_redeem_reference
+    ON loyalty_points_ledger(reference_type, reference_id)
+    WHERE transaction_type = 'REDEEM' AND reference_id IS NOT NULL;
+
+CREATE UNIQUE INDEX uniq_loyalty_points_ledger_reversal_reference
+    ON loyalty_points_ledger(reference_type, reference_id)
+    WHERE reference_type = 'ORDER_REVERSAL';
+
+
+
+-- =========================
+-- recalc_cart_vendor_totals: keep applied points within the cap
+-- =========================
+
+CREATE OR REPLACE FUNCTION recalc_cart_vendor_totals(p_cart_vendor_id TEXT)
+RETURNS VOID AS $$
+DECLARE
+    v_subtotal NUMERIC(10,2);
+BEGIN
+    -- Calculate subtotal from cart items
+    SELECT COALESCE(SUM(subtotal), 0) INTO v_subtotal
+    FROM cart_items
+    WHERE cart_vendor_id = p_cart_vendor_id;
+
+    -- Update cart_vendor totals using vendor rates
+    UPDATE cart_vendors cv
+    SET
+        subtotal = v_subtotal,
+        vendor_service_charge = ROUND(v_subtotal * v.vendor_service_charge / 100, 2),
+        vat = ROUND(v_subtotal * v.vat / 100, 2),
+        vendor_discount = ROUND(v_subtotal * v.vendor_discount / 100, 2)
+    FROM vendors v
+    WHERE cv.vendor_id = v.id
+    AND cv.id = p_cart_vendor_id;
+
+    -- Points applied earlier may exceed the cap once items are removed
+    UPDATE cart_vendors cv
+    SET loyalty_points_used = LEAST(cv.loyalty_points_used, FLOOR(v_subtotal * s.max_redeem_percent / 100 / s.npr_per_point)),
+        loyalty_discount = ROUND(LEAST(cv.loyalty_points_used, FLOOR(v_subtotal * s.max_redeem_percent / 100 / s.npr_per_point)) * s.npr_per_point, 2)
+    FROM loyalty_settings s
+    WHERE cv.id = p_cart_vendor_id
+    AND cv.loyalty_points_used > 0;
+END;
+$$ LANGUAGE plpgsql;
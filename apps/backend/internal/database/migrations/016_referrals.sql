@@ -0,0 +1,85 @@
+-- =========================
+-- REFERRAL PROGRAM SETTINGS (single row)
+-- =========================
+
+CREATE TABLE referral_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    reward_type TEXT NOT NULL DEFAULT 'points' CHECK (reward_type IN ('points', 'coupon')), -- what the referrer gets
+    referrer_points NUMERIC(10,2) NOT NULL DEFAULT 100 CHECK (referrer_points >= 0),
+    referee_points NUMERIC(10,2) NOT NULL DEFAULT 50 CHECK (referee_points >= 0),       -- welcome bonus, 0 = none
+    coupon_value NUMERIC(10,2) NOT NULL DEFAULT 100 CHECK (coupon_value > 0),           -- flat NPR off
+    coupon_min_order_amount NUMERIC(10,2) NOT NULL DEFAULT 300 CHECK (coupon_min_order_amount >= 0),
+    coupon_valid_days INT NOT NULL DEFAULT 30 CHECK (coupon_valid_days > 0),
+    max_rewards_per_referrer INT NOT NULL DEFAULT 20 CHECK (max_rewards_per_referrer > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO referral_settings DEFAULT VALUES;
+
+CREATE TRIGGER set_updated_at_referral_settings
+    BEFORE UPDATE ON referral_settings
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- REFERRAL CODES (one per user)
+-- =========================
+
+CREATE TABLE referral_codes (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(16) UNIQUE NOT NULL,             -- e.g., "KR7M2QXD"
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+
+-- =========================
+-- REFERRALS (one per referee)
+-- =========================
+
+CREATE TABLE referrals (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    referrer_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referee_id TEXT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE, -- a user is referred once
+    code VARCHAR(16) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'rewarded', 'rejected')),
+    rejection_reason TEXT,                         -- self_referral, same_phone, same_device, limit_reached ...
+
+    -- fingerprint captured at signup for the fraud checks
+    signup_phone TEXT,
+    signup_device_id TEXT,
+
+    -- reward, issued when the referee's first order is delivered
+    qualifying_order_id TEXT REFERENCES order_vendors(id) ON DELETE SET NULL,
+    reward_type TEXT CHECK (reward_type IN ('points', 'coupon')),
+    coupon_id TEXT REFERENCES coupons(id) ON DELETE SET NULL, -- set when reward_type = 'coupon'
+    rewarded_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (referrer_id <> referee_id)
+);
+
+CREATE INDEX idx_referrals_referrer_id ON referrals(referrer_id, status);
+CREATE INDEX idx_referrals_signup_phone ON referrals(signup_phone) WHERE signup_phone IS NOT NULL;
+CREATE INDEX idx_referrals_signup_device_id ON referrals(signup_device_id) WHERE signup_device_id IS NOT NULL;
+CREATE INDEX idx_referrals_pending ON referrals(created_at) WHERE status = 'pending';
+
+CREATE TRIGGER set_updated_at_referrals
+    BEFORE UPDATE ON referrals
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- SINGLE-USE COUPONS
+-- =========================
+
+-- reward coupons belong to one user
+ALTER TABLE coupons
+    ADD COLUMN user_id TEXT REFERENCES users(id) ON DELETE CASCADE; -- NULL = anyone
@@ -0,0 +1,28 @@
+-- =========================
+-- CLERK USER SYNC
+-- =========================
+
+ALTER TABLE users
+    ADD COLUMN clerk_updated_at BIGINT NOT NULL DEFAULT 0, -- Clerk updated_at (ms) of the last applied state
+    ADD COLUMN last_sign_in_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;                     -- deleted in Clerk
+
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+
+
+-- =========================
+-- CLERK WEBHOOK EVENTS (processed deliveries)
+-- =========================
+-- Svix retries and may deliver out of order: the id makes a retry a no-op
+-- and a user.deleted row keeps a late user.created from reviving the user.
+
+CREATE TABLE clerk_webhook_events (
+    id TEXT PRIMARY KEY,                           -- svix-id header
+    type TEXT NOT NULL,                            -- user.created, user.deleted ...
+    object_id TEXT,                                -- user id (user.*, session.*) or email id
+    event_timestamp BIGINT NOT NULL,               -- ms, from the event envelope
+    received_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_clerk_webhook_events_object ON clerk_webhook_events(object_id, type);
@@ -0,0 +1,13 @@
+-- =========================
+-- ACCOUNT DELETION
+-- =========================
+-- Deleting an account anonymizes it instead of removing the row: orders,
+-- payments, invoices and ledger entries keep pointing at it.
+
+ALTER TABLE users
+    ADD COLUMN deletion_requested_at TIMESTAMPTZ,
+    ADD COLUMN deletion_scheduled_at TIMESTAMPTZ,  -- end of the grace period
+    ADD COLUMN anonymized_at TIMESTAMPTZ;
+
+CREATE INDEX idx_users_deletion_due ON users(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL AND anonymized_at IS NULL;
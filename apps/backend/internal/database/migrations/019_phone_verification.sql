@@ -0,0 +1,12 @@
+-- =========================
+-- PHONE VERIFICATION (OTP)
+-- =========================
+-- An OTP is a user_2fa_tokens row of type 'phone_otp': token holds the
+-- HMAC of the code, never the code itself.
+
+ALTER TABLE user_2fa_tokens
+    ADD COLUMN phone_number VARCHAR(20),           -- number the code was sent to
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0;    -- wrong codes entered
+
+CREATE INDEX idx_user_2fa_tokens_open ON user_2fa_tokens(user_id, token_type, created_at DESC)
+    WHERE used = FALSE;
@@ -0,0 +1,42 @@
+-- =========================
+-- FAVORITE COUNTS
+-- =========================
+-- vendors.favorite_count and menu_item_stats.favorite_count are kept in step
+-- with favorites by the repository, in the same transaction.
+
+-- favorites point at vendors or menu items without a foreign key; drop the
+-- ones whose entity is gone
+DELETE FROM favorites f
+WHERE (f.entity_type = 'restaurant' AND NOT EXISTS (SELECT 1 FROM vendors v WHERE v.id = f.entity_id))
+   OR (f.entity_type = 'menu_item' AND NOT EXISTS (SELECT 1 FROM menu_items mi WHERE mi.id = f.entity_id))
+   OR f.entity_type NOT IN ('restaurant', 'menu_item');
+
+ALTER TABLE favorites
+    ADD CONSTRAINT favorites_entity_type_check CHECK (entity_type IN ('restaurant', 'menu_item'));
+
+CREATE INDEX idx_favorites_user_created ON favorites(user_id, created_at DESC);
+
+
+-- menu_item_stats holds one row of aggregates per menu item
+DELETE FROM menu_item_stats a
+USING menu_item_stats b
+WHERE a.menu_item_id = b.menu_item_id AND a.id > b.id;
+
+ALTER TABLE menu_item_stats
+    DROP COLUMN user_id,
+    ADD CONSTRAINT menu_item_stats_menu_item_id_key UNIQUE (menu_item_id);
+
+
+-- backfill
+UPDATE vendors v
+SET favorite_count = (
+    SELECT COUNT(*) FROM favorites f
+    WHERE f.entity_type = 'restaurant' AND f.entity_id = v.id
+);
+
+INSERT INTO menu_item_stats (menu_item_id, favorite_count)
+SELECT entity_id, COUNT(*)
+FROM favorites
+WHERE entity_type = 'menu_item'
+GROUP BY entity_id
+ON CONFLICT (menu_item_id) DO UPDATE SET favorite_count = EXCLUDED.favorite_count;
@@ -0,0 +1,17 @@
+-- =========================
+-- MENU ITEM STATS (recommendations)
+-- =========================
+-- Aggregates over delivered orders, rebuilt nightly by the recommendation
+-- job. favorite_count stays with the favorites repository.
+
+ALTER TABLE menu_item_stats
+    ADD COLUMN unique_customers INT NOT NULL DEFAULT 0,
+    ADD COLUMN reorder_count INT NOT NULL DEFAULT 0,         -- customers who ordered it more than once
+    ADD COLUMN reorder_rate NUMERIC(5,4) NOT NULL DEFAULT 0, -- reorder_count / unique_customers
+    ALTER COLUMN last_ordered TYPE TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_menu_item_stats_reorder_rate ON menu_item_stats(reorder_rate DESC);
+
+-- co-occurrence ("people also ordered") looks baskets up by item
+CREATE INDEX IF NOT EXISTS idx_order_items_menu_item_id ON order_items(menu_item_id);
+
@@ -0,0 +1,84 @@
+-- =========================
+-- ORDER NOTIFICATIONS (one row per recipient and channel)
+-- =========================
+-- message is the rendered body; in_app rows are the user's inbox and are
+-- delivered as soon as they are written, the other channels are delivered
+-- by the job server and move pending -> sent | failed.
+
+ALTER TABLE order_notifications
+    ADD COLUMN recipient_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    ADD COLUMN event_type TEXT NOT NULL DEFAULT 'legacy',         -- order.accepted, payment.failed ...
+    ADD COLUMN channel TEXT NOT NULL DEFAULT 'in_app'
+        CHECK (channel IN ('email', 'sms', 'push', 'in_app')),
+    ADD COLUMN title TEXT,
+    ADD COLUMN data JSONB NOT NULL DEFAULT '{}',                  -- deep-link data for the apps
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN read_at TIMESTAMPTZ,
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN sent_at DROP NOT NULL,
+    ALTER COLUMN sent_at DROP DEFAULT,
+    DROP CONSTRAINT IF EXISTS order_notifications_delivery_status_check,
+    ALTER COLUMN delivery_status SET DEFAULT 'pending',
+    ALTER COLUMN delivery_status SET NOT NULL,
+    ADD CONSTRAINT order_notifications_delivery_status_check
+        CHECK (delivery_status IN ('pending', 'sent', 'delivered', 'failed'));
+
+-- the same event is notified once per recipient and channel, however often
+-- the trigger runs (payment redirect + webhook)
+CREATE UNIQUE INDEX uq_order_notifications_event
+    ON order_notifications(order_id, event_type, recipient_type, recipient_id, channel);
+
+CREATE INDEX idx_order_notifications_inbox
+    ON order_notifications(recipient_id, created_at DESC) WHERE channel = 'in_app';
+
+CREATE TRIGGER set_updated_at_order_notifications
+    BEFORE UPDATE ON order_notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- NOTIFICATION PREFERENCES
+-- =========================
+-- No row means every channel is on. The in-app inbox cannot be turned off.
+
+CREATE TABLE notification_preferences (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    sms_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    push_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER set_updated_at_notification_preferences
+    BEFORE UPDATE ON notification_preferences
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- WEB PUSH SUBSCRIPTIONS (one per browser)
+-- =========================
+
+CREATE TABLE push_subscriptions (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh TEXT NOT NULL,
+    auth TEXT NOT NULL,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions(user_id);
+
+CREATE TRIGGER set_updated_at_push_subscriptions
+    BEFORE UPDATE ON push_subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,47 @@
+-- =========================
+-- EMAIL LOG (one row per outbound email)
+-- =========================
+-- status moves sent -> delivered | delivery_delayed | bounced | complained as
+-- the provider reports back (Resend webhooks). failed means the provider
+-- refused it; suppressed means it was never handed to the provider.
+
+CREATE TABLE email_log (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    provider TEXT NOT NULL,                -- resend, smtp, file, memory
+    provider_message_id TEXT,
+    template TEXT,                          -- NULL for pre-rendered (Clerk) emails
+    to_address TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (
+        status IN ('sent', 'failed', 'suppressed', 'delivered', 'delivery_delayed', 'bounced', 'complained')
+    ),
+    error TEXT,
+    sent_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_log_provider_message_id ON email_log(provider_message_id);
+CREATE INDEX idx_email_log_to_address ON email_log(LOWER(to_address), created_at DESC);
+
+CREATE TRIGGER set_updated_at_email_log
+    BEFORE UPDATE ON email_log
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- EMAIL SUPPRESSIONS
+-- =========================
+-- Addresses that hard-bounced or marked us as spam are not emailed again;
+-- providers penalise senders that keep trying. address is stored lowercased.
+
+CREATE TABLE email_suppressions (
+    address TEXT PRIMARY KEY,
+    reason TEXT NOT NULL CHECK (reason IN ('bounce', 'complaint', 'manual')),
+    detail TEXT,
+    provider_message_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,58 @@
+-- =========================
+-- VENDOR DOCUMENTS
+-- =========================
+-- Documents live in the private upload bucket; the row keeps the object key
+-- and reads go through presigned URLs. A vendor has one current document
+-- per type: uploading again replaces it and starts its review over.
+
+ALTER TABLE vendor_documents RENAME COLUMN document_url TO file_key;
+
+ALTER TABLE vendor_documents
+    ADD COLUMN content_type TEXT,
+    ADD COLUMN file_size BIGINT,
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_vendor_documents_vendor_id;
+CREATE UNIQUE INDEX idx_vendor_documents_vendor_user_type ON vendor_documents(vendor_user_id, document_type);
+
+CREATE TRIGGER set_updated_at_vendor_documents
+    BEFORE UPDATE ON vendor_documents
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- VENDOR VERIFICATION LOGS
+-- =========================
+-- One row per status change. admin_id is NULL for the vendor's own actions
+-- (submit, go live) and for automatic ones.
+
+ALTER TABLE vendor_verification_logs
+    DROP CONSTRAINT IF EXISTS vendor_verification_logs_action_check;
+
+ALTER TABLE vendor_verification_logs
+    ADD CONSTRAINT vendor_verification_logs_action_check CHECK (
+        action IN ('submitted', 'approved', 'rejected', 'requested_changes', 'activated', 'suspended', 'reinstated')
+    ),
+    ADD COLUMN from_status TEXT,
+    ADD COLUMN to_status TEXT;
+
+CREATE INDEX idx_vendor_verification_logs_vendor_id ON vendor_verification_logs(vendor_id, created_at DESC);
+
+
+
+-- =========================
+-- VENDORS
+-- =========================
+
+ALTER TABLE vendors ADD COLUMN submitted_at TIMESTAMPTZ;   -- last submission, orders the review queue
+
+CREATE INDEX idx_vendors_status ON vendors(status);
+
+-- Vendors onboarded before verification existed already trade on the
+-- platform; those with a menu are grandfathered in as active.
+UPDATE vendors v
+SET status = 'active'
+WHERE v.status = 'draft'
+  AND EXISTS (SELECT 1 FROM menu_items mi WHERE mi.vendor_id = v.id);
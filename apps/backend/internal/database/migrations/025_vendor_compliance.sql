@@ -0,0 +1,34 @@
+-- =========================
+-- VENDORS
+-- =========================
+-- Why a suspended vendor is suspended: compliance suspensions are made by
+-- the daily expiry check when a required document lapses and are lifted
+-- automatically once its renewal is approved; admin suspensions are only
+-- lifted by an admin.
+
+ALTER TABLE vendors ADD COLUMN suspension_reason TEXT CHECK (
+    suspension_reason IN ('admin', 'compliance')
+);
+
+UPDATE vendors SET suspension_reason = 'admin' WHERE status = 'suspended';
+
+
+
+-- =========================
+-- VENDOR DOCUMENT EXPIRY WARNINGS
+-- =========================
+-- One row per reminder sent, so each of the 30/7/1 day warnings goes out once
+-- per document and expiry date. A renewed document has a new expiry date and
+-- is warned about again.
+
+CREATE TABLE vendor_document_expiry_warnings (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    document_id TEXT NOT NULL REFERENCES vendor_documents(id) ON DELETE CASCADE,
+    expiry_date DATE NOT NULL,
+    days_before INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (document_id, expiry_date, days_before)
+);
+
+CREATE INDEX idx_vendor_documents_expiry_date ON vendor_documents(expiry_date) WHERE expiry_date IS NOT NULL;
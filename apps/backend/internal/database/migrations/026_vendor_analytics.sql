@@ -0,0 +1,114 @@
+-- =========================
+-- VENDOR ANALYTICS ROLLUPS
+-- =========================
+-- Aggregates behind the vendor analytics API, kept up to date by the
+-- analytics consumer. Buckets are in the local time of the platform
+-- timezone (config primary.timezone) as plain timestamps/dates, so an hour
+-- or day means the same thing to the vendor as on their clock. Orders are
+-- bucketed by when they were placed; a later status change (delivery,
+-- cancellation) recomputes the bucket the order was placed in.
+--
+-- The rollups can always be rebuilt from the source tables: deleting the
+-- row in analytics_refresh_state makes the next refresh start over (e.g.
+-- after changing the timezone).
+
+
+
+-- =========================
+-- VENDOR SALES (hourly)
+-- =========================
+-- orders counts everything placed except failed checkouts; revenue, items
+-- and the delivered counts only cover delivered orders.
+
+CREATE TABLE vendor_sales_hourly (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL,  -- local hour the orders were placed in
+    orders INT NOT NULL DEFAULT 0,
+    delivered_orders INT NOT NULL DEFAULT 0,
+    cancelled_orders INT NOT NULL DEFAULT 0,
+    revenue NUMERIC(12,2) NOT NULL DEFAULT 0,
+    items_sold INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (vendor_id, bucket)
+);
+
+
+
+-- =========================
+-- VENDOR ITEM SALES (daily, delivered orders)
+-- =========================
+
+CREATE TABLE vendor_item_sales_daily (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    menu_item_id TEXT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    orders INT NOT NULL DEFAULT 0,
+    quantity INT NOT NULL DEFAULT 0,
+    revenue NUMERIC(12,2) NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (vendor_id, day, menu_item_id)
+);
+
+
+
+-- =========================
+-- VENDOR CUSTOMERS (daily, delivered orders)
+-- =========================
+-- One row per customer and day they ordered; distinct and returning
+-- customers over a range are counted from it.
+
+CREATE TABLE vendor_customer_daily (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    user_id TEXT NOT NULL,
+    orders INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (vendor_id, day, user_id)
+);
+
+CREATE INDEX idx_vendor_customer_daily_user ON vendor_customer_daily(vendor_id, user_id, day);
+
+
+
+-- =========================
+-- VENDOR EARNINGS (daily)
+-- =========================
+-- vendor_earnings by the day they accrued: what the vendor is owed before
+-- it is batched into settlements.
+
+CREATE TABLE vendor_earnings_daily (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    gross_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    commission NUMERIC(12,2) NOT NULL DEFAULT 0,
+    refund_amount NUMERIC(12,2) NOT NULL DEFAULT 0,  -- negative
+    net_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (vendor_id, day)
+);
+
+
+
+-- =========================
+-- REFRESH STATE
+-- =========================
+-- refreshed_through is the high-water mark of source changes (order and
+-- earning rows) already rolled up.
+
+CREATE TABLE analytics_refresh_state (
+    name TEXT PRIMARY KEY,
+    refreshed_through TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER set_updated_at_analytics_refresh_state
+    BEFORE UPDATE ON analytics_refresh_state
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- the refresh looks for orders and earnings changed since the mark, then
+-- re-reads the vendor's rows in each changed bucket
+CREATE INDEX IF NOT EXISTS idx_order_vendors_updated_at ON order_vendors(updated_at);
+CREATE INDEX IF NOT EXISTS idx_order_vendors_vendor_created ON order_vendors(vendor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_vendor_earnings_created_at ON vendor_earnings(created_at);
+CREATE INDEX IF NOT EXISTS idx_vendor_earnings_vendor_created ON vendor_earnings(vendor_id, created_at);
@@ -0,0 +1,87 @@
+-- =========================
+-- VENDOR MEMBERS
+-- =========================
+-- The users who run a vendor and what they may do there. vendors.vendor_user_id
+-- stays the owner's account: payouts, earnings and documents are keyed to
+-- it. Every vendor has exactly one owner membership, kept in step with
+-- vendor_user_id by the trigger below; staff join through invitations.
+
+CREATE TABLE vendor_members (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'manager', 'cashier', 'kitchen')),
+    invited_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (vendor_id, user_id)
+);
+
+CREATE UNIQUE INDEX idx_vendor_members_owner ON vendor_members(vendor_id) WHERE role = 'owner';
+CREATE INDEX idx_vendor_members_user ON vendor_members(user_id);
+
+CREATE TRIGGER set_updated_at_vendor_members
+    BEFORE UPDATE ON vendor_members
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+INSERT INTO vendor_members (vendor_id, user_id, role)
+SELECT id, vendor_user_id, 'owner' FROM vendors
+ON CONFLICT DO NOTHING;
+
+
+
+-- =========================
+-- HELPER FUNCTION: sync_vendor_owner()
+-- =========================
+-- New vendors (single and bulk inserts alike) get their owner membership.
+
+CREATE OR REPLACE FUNCTION sync_vendor_owner()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO vendor_members (vendor_id, user_id, role)
+    VALUES (NEW.id, NEW.vendor_user_id, 'owner')
+    ON CONFLICT (vendor_id, user_id) DO UPDATE SET role = 'owner';
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER trg_vendor_owner_member
+    AFTER INSERT ON vendors
+    FOR EACH ROW
+    EXECUTE FUNCTION sync_vendor_owner();
+
+
+
+-- =========================
+-- VENDOR INVITATIONS
+-- =========================
+-- Invitations are sent by email and accepted by whoever signs in with that
+-- address. Only a hash of the token is stored; the token itself is only in
+-- the email. A vendor has at most one pending invitation per address.
+
+CREATE TABLE vendor_invitations (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('manager', 'cashier', 'kitchen')),
+    token_hash TEXT NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked')),
+    invited_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    accepted_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_vendor_invitations_pending_email
+    ON vendor_invitations(vendor_id, LOWER(email)) WHERE status = 'pending';
+
+CREATE TRIGGER set_updated_at_vendor_invitations
+    BEFORE UPDATE ON vendor_invitations
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
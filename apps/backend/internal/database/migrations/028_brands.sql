@@ -0,0 +1,182 @@
+-- =========================
+-- BRANDS (restaurant chains)
+-- =========================
+-- A brand groups the outlets of a chain. Each outlet is still a vendors row
+-- with its own address, hours, delivery zone, status, orders and members;
+-- the brand holds the shared identity and the master menu. Standalone
+-- vendors have no brand and keep working as before.
+
+CREATE TABLE brands (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    owner_user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(150) NOT NULL,
+    about TEXT,
+    cuisine VARCHAR(100),
+    cuisine_tags TEXT[],
+    logo_image_name TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_brands_owner ON brands(owner_user_id);
+
+CREATE TRIGGER set_updated_at_brands
+    BEFORE UPDATE ON brands
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- OUTLETS
+-- =========================
+-- The owner of a brand owns all of its outlets, so vendor_user_id is only
+-- unique among standalone vendors. An outlet's payout account overrides the
+-- owner's default one; earnings accrue to it.
+
+ALTER TABLE vendors
+    ADD COLUMN brand_id TEXT REFERENCES brands(id) ON DELETE SET NULL,
+    ADD COLUMN outlet_name VARCHAR(100),                  -- e.g. "Thamel", "Jhamsikhel"
+    ADD COLUMN delivery_radius_km NUMERIC(4,1) CHECK (delivery_radius_km > 0),  -- NULL: no limit
+    ADD COLUMN payout_account_id TEXT REFERENCES payout_accounts(id) ON DELETE SET NULL;
+
+ALTER TABLE vendors DROP CONSTRAINT IF EXISTS vendors_vendor_user_id_key;
+CREATE UNIQUE INDEX idx_vendors_standalone_owner ON vendors(vendor_user_id) WHERE brand_id IS NULL;
+CREATE INDEX idx_vendors_owner ON vendors(vendor_user_id);
+CREATE INDEX idx_vendors_brand ON vendors(brand_id);
+
+
+
+-- =========================
+-- MASTER MENU
+-- =========================
+-- A menu item belongs to either a vendor or a brand. Brand items are the
+-- master menu shared by all outlets of the brand.
+
+ALTER TABLE menu_items
+    ADD COLUMN brand_id TEXT REFERENCES brands(id) ON DELETE CASCADE,
+    ALTER COLUMN vendor_id DROP NOT NULL,
+    ADD CONSTRAINT menu_items_owner_check CHECK ((vendor_id IS NULL) <> (brand_id IS NULL));
+
+CREATE INDEX idx_menu_items_brand ON menu_items(brand_id);
+
+CREATE TABLE brand_menu_categories (
+    brand_id TEXT NOT NULL REFERENCES brands(id) ON DELETE CASCADE,
+    category_id TEXT NOT NULL REFERENCES menu_categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (brand_id, category_id)
+);
+
+CREATE INDEX idx_brand_menu_categories_category ON brand_menu_categories(category_id);
+
+
+
+-- =========================
+-- OUTLET MENU OVERRIDES
+-- =========================
+-- An outlet's own price and/or availability for a master menu item. NULL
+-- keeps the master value; an item the brand marks unavailable stays
+-- unavailable everywhere.
+
+CREATE TABLE outlet_menu_overrides (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    menu_item_id TEXT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    price NUMERIC(10,2) CHECK (price >= 0),
+    is_available BOOLEAN,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (vendor_id, menu_item_id)
+);
+
+CREATE INDEX idx_outlet_menu_overrides_item ON outlet_menu_overrides(menu_item_id);
+
+CREATE TRIGGER set_updated_at_outlet_menu_overrides
+    BEFORE UPDATE ON outlet_menu_overrides
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- VIEW: vendor_menu_items
+-- =========================
+-- The menu each vendor actually sells, shaped like menu_items: its own items
+-- plus, for outlets, the brand's master items with the outlet's overrides
+-- applied and vendor_id set to the outlet.
+
+CREATE VIEW vendor_menu_items AS
+SELECT mi.id, mi.vendor_id, mi.category_id, mi.name, mi.description,
+       mi.base_price, mi.old_price, mi.image, mi.is_available,
+       mi.is_vegetarian, mi.is_vegan, mi.is_popular, mi.is_gluten_free,
+       mi.spicy_level, mi.most_liked_rank, mi.additional_service_charge,
+       mi.tags, mi.portion_size, mi.keywords, mi.discount_amount,
+       mi.created_at, mi.updated_at, mi.brand_id
+FROM menu_items mi
+WHERE mi.vendor_id IS NOT NULL
+UNION ALL
+SELECT mi.id, v.id AS vendor_id, mi.category_id, mi.name, mi.description,
+       COALESCE(o.price, mi.base_price) AS base_price, mi.old_price, mi.image,
+       (COALESCE(mi.is_available, FALSE) AND COALESCE(o.is_available, TRUE)) AS is_available,
+       mi.is_vegetarian, mi.is_vegan, mi.is_popular, mi.is_gluten_free,
+       mi.spicy_level, mi.most_liked_rank, mi.additional_service_charge,
+       mi.tags, mi.portion_size, mi.keywords, mi.discount_amount,
+       mi.created_at, GREATEST(mi.updated_at, o.updated_at) AS updated_at, mi.brand_id
+FROM menu_items mi
+JOIN vendors v ON v.brand_id = mi.brand_id
+LEFT JOIN outlet_menu_overrides o ON o.vendor_id = v.id AND o.menu_item_id = mi.id
+WHERE mi.brand_id IS NOT NULL;
+
+
+
+-- =========================
+-- HELPER FUNCTION: distance_km(lat1, lon1, lat2, lon2)
+-- =========================
+-- Great-circle distance, for delivery zones and nearest outlets.
+
+CREATE OR REPLACE FUNCTION distance_km(lat1 NUMERIC, lon1 NUMERIC, lat2 NUMERIC, lon2 NUMERIC)
+RETURNS NUMERIC LANGUAGE sql IMMUTABLE AS $$
+    SELECT (6371 * 2 * ASIN(SQRT(
+        POWER(SIN(RADIANS(lat2 - lat1) / 2), 2) +
+        COS(RADIANS(lat1)) * COS(RADIANS(lat2)) *
+        POWER(SIN(RADIANS(lon2 - lon1) / 2), 2)
+    )))::NUMERIC
+$$;
+
+
+
+-- =========================
+-- HELPER FUNCTION: accrue_vendor_earning(order_vendor_id)
+-- =========================
+-- As in 011, but an outlet's own payout account takes precedence over the
+-- owner's default.
+
+CREATE OR REPLACE FUNCTION accrue_vendor_earning(p_order_vendor_id TEXT)
+RETURNS VOID LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO vendor_earnings (
+        vendor_id, vendor_user_id, payout_account_id, order_id,
+        entry_type, gross_amount, commission_rate, commission, description
+    )
+    SELECT
+        v.id,
+        v.vendor_user_id,
+        COALESCE(v.payout_account_id, (
+            SELECT pa.id FROM payout_accounts pa
+            WHERE pa.owner_id = v.vendor_user_id AND pa.owner_type = 'vendor'
+            ORDER BY pa.is_default DESC, pa.created_at
+            LIMIT 1
+        )),
+        ov.id,
+        'order',
+        ov.total,
+        v.commission_rate,
+        ROUND(ov.total * v.commission_rate / 100, 2),
+        'Order ' || ov.id
+    FROM order_vendors ov
+    JOIN vendors v ON v.id = ov.vendor_id
+    WHERE ov.id = p_order_vendor_id
+    ON CONFLICT DO NOTHING;
+END;
+$$;
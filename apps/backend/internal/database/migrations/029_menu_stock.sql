@@ -0,0 +1,94 @@
+-- =========================
+-- MENU ITEM STOCK
+-- =========================
+-- Optional stock counts per vendor and menu item; items without a row are
+-- not tracked and stay available until the vendor says otherwise. Keyed by
+-- the vendor so each outlet of a brand counts its own stock of the master
+-- items.
+--
+-- daily_quantity refills quantity every day at reset_time (business local
+-- time); NULL keeps the count until the vendor changes it. counted_at is when
+-- quantity was last set outright, by the vendor or the daily reset; stock
+-- reserved before it is not given back on release, it was already recounted.
+
+CREATE TABLE menu_item_stock (
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    menu_item_id TEXT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    daily_quantity INT CHECK (daily_quantity >= 0),
+    reset_time TIME NOT NULL DEFAULT '06:00',
+    low_stock_threshold INT NOT NULL DEFAULT 0 CHECK (low_stock_threshold >= 0),
+    low_stock_alerted BOOLEAN NOT NULL DEFAULT FALSE,   -- the vendor was told; cleared once stock is back above the threshold
+    counted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (vendor_id, menu_item_id)
+);
+
+CREATE INDEX idx_menu_item_stock_item ON menu_item_stock(menu_item_id);
+CREATE INDEX idx_menu_item_stock_daily ON menu_item_stock(reset_time) WHERE daily_quantity IS NOT NULL;
+
+CREATE TRIGGER set_updated_at_menu_item_stock
+    BEFORE UPDATE ON menu_item_stock
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+
+-- =========================
+-- STOCK RESERVATIONS
+-- =========================
+-- Stock an order holds, taken when the order is created. A reservation is
+-- released (and the stock given back) when the order is cancelled, its
+-- payment fails or it is left unpaid; paid orders keep theirs.
+
+CREATE TABLE stock_reservations (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    order_vendor_id TEXT NOT NULL REFERENCES order_vendors(id) ON DELETE CASCADE,
+    vendor_id TEXT NOT NULL,
+    menu_item_id TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    released_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (vendor_id, menu_item_id) REFERENCES menu_item_stock(vendor_id, menu_item_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_stock_reservations_held ON stock_reservations(order_vendor_id, menu_item_id) WHERE released_at IS NULL;
+CREATE INDEX idx_stock_reservations_item ON stock_reservations(vendor_id, menu_item_id);
+
+
+
+-- =========================
+-- VIEW: vendor_menu_items
+-- =========================
+-- As in 028, but a tracked item that has run out is unavailable until it is
+-- restocked, whatever its manual flag says.
+
+CREATE OR REPLACE VIEW vendor_menu_items AS
+SELECT mi.id, mi.vendor_id, mi.category_id, mi.name, mi.description,
+       mi.base_price, mi.old_price, mi.image,
+       (mi.is_available AND COALESCE(st.quantity > 0, TRUE)) AS is_available,
+       mi.is_vegetarian, mi.is_vegan, mi.is_popular, mi.is_gluten_free,
+       mi.spicy_level, mi.most_liked_rank, mi.additional_service_charge,
+       mi.tags, mi.portion_size, mi.keywords, mi.discount_amount,
+       mi.created_at, mi.updated_at, mi.brand_id
+FROM menu_items mi
+LEFT JOIN menu_item_stock st ON st.vendor_id = mi.vendor_id AND st.menu_item_id = mi.id
+WHERE mi.vendor_id IS NOT NULL
+UNION ALL
+SELECT mi.id, v.id AS vendor_id, mi.category_id, mi.name, mi.description,
+       COALESCE(o.price, mi.base_price) AS base_price, mi.old_price, mi.image,
+       (COALESCE(mi.is_available, FALSE) AND COALESCE(o.is_available, TRUE) AND COALESCE(st.quantity > 0, TRUE)) AS is_available,
+       mi.is_vegetarian, mi.is_vegan, mi.is_popular, mi.is_gluten_free,
+       mi.spicy_level, mi.most_liked_rank, mi.additional_service_charge,
+       mi.tags, mi.portion_size, mi.keywords, mi.discount_amount,
+       mi.created_at, GREATEST(mi.updated_at, o.updated_at) AS updated_at, mi.brand_id
+FROM menu_items mi
+JOIN vendors v ON v.brand_id = mi.brand_id
+LEFT JOIN outlet_menu_overrides o ON o.vendor_id = v.id AND o.menu_item_id = mi.id
+LEFT JOIN menu_item_stock st ON st.vendor_id = v.id AND st.menu_item_id = mi.id
+WHERE mi.brand_id IS NOT NULL;
@@ -0,0 +1,44 @@
+-- =========================
+-- MENU IMPORTS
+-- =========================
+-- Menu files (CSV, XLSX or a FoodMandu export) a vendor uploaded to create
+-- or update its menu items in bulk. The file is kept in the upload bucket
+-- and applied by a background job, which records its progress here; report
+-- holds the counts and the rows that did not validate. A dry run validates
+-- and reports without writing.
+--
+-- Rows are matched to the vendor's items by name, ignoring case and
+-- surrounding spaces: a match is updated, anything else is created.
+
+CREATE TABLE menu_imports (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,   -- who uploaded it
+    format VARCHAR(20) NOT NULL CHECK (format IN ('csv', 'xlsx', 'foodmandu')),
+    file_name TEXT NOT NULL,
+    file_key TEXT NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    create_categories BOOLEAN NOT NULL DEFAULT FALSE,                  -- unknown categories are created instead of rejected
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    report JSONB,
+    error TEXT,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_menu_imports_vendor ON menu_imports(vendor_id, created_at DESC);
+
+CREATE TRIGGER set_updated_at_menu_imports
+    BEFORE UPDATE ON menu_imports
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- matching imported rows to the vendor's items
+CREATE INDEX idx_menu_items_vendor_name ON menu_items(vendor_id, lower(btrim(name))) WHERE vendor_id IS NOT NULL;
+CREATE INDEX idx_menu_categories_name ON menu_categories(lower(btrim(name)));
@@ -0,0 +1,50 @@
+-- =========================
+-- IMAGES
+-- =========================
+-- Vendor and menu photos, stored as resized WebP and JPEG variants (see
+-- internal/lib/imaging). Variants are keyed by the SHA-256 of the upload,
+-- images/<hash>/<variant>.<ext>, so uploading the same file twice reuses the
+-- same objects.
+--
+-- Menu items, vendors and brands point at a variant by URL. An image nobody
+-- points at any more (it was replaced, or its item deleted) is removed with
+-- its objects by the image cleanup job, once every upload of it is older
+-- than the grace period clients get to save the URL they were given.
+
+CREATE TABLE images (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT REFERENCES vendors(id) ON DELETE SET NULL,
+    menu_item_id TEXT REFERENCES menu_items(id) ON DELETE SET NULL,
+    purpose VARCHAR(20) NOT NULL
+        CHECK (purpose IN ('upload', 'menu_item', 'vendor_listing', 'vendor_logo')),
+    hash CHAR(64) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,          -- of the upload
+    width INT NOT NULL,
+    height INT NOT NULL,
+    variants JSONB NOT NULL DEFAULT '[]',       -- [{name, format, key, url, width, height, size}]
+    url TEXT NOT NULL,                          -- the variant attached, or the JPEG card of plain uploads
+    uploaded_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_images_hash ON images(hash);
+CREATE INDEX idx_images_vendor ON images(vendor_id);
+CREATE INDEX idx_images_menu_item ON images(menu_item_id);
+
+CREATE TRIGGER set_updated_at_images
+    BEFORE UPDATE ON images
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+
+-- the image a URL points at; NULL for URLs that are not image variants
+CREATE FUNCTION image_hash(url TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE
+    AS $$ SELECT substring(url FROM 'images/([0-9a-f]{64})/') $$;
+
+CREATE INDEX idx_menu_items_image_hash ON menu_items(image_hash(image));
+CREATE INDEX idx_vendors_listing_image_hash ON vendors(image_hash(vendor_listing_image_name));
+CREATE INDEX idx_vendors_logo_image_hash ON vendors(image_hash(vendor_logo_image_name));
+CREATE INDEX idx_brands_logo_image_hash ON brands(image_hash(logo_image_name));
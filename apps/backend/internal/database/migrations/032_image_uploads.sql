@@ -0,0 +1,37 @@
+-- =========================
+-- IMAGE UPLOADS
+-- =========================
+-- Direct uploads: the client gets a presigned PUT or POST and sends the file
+-- straight to S3 under a private staging key, then calls back to complete
+-- the upload. Completing checks the object with HeadObject, runs it through
+-- the image pipeline like any other upload (see images) and deletes the
+-- staging object. Uploads not completed in time are deleted, with their
+-- staging object, by the image cleanup job.
+
+CREATE TABLE image_uploads (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::TEXT,
+    vendor_id TEXT NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    menu_item_id TEXT REFERENCES menu_items(id) ON DELETE SET NULL,
+    purpose VARCHAR(20) NOT NULL
+        CHECK (purpose IN ('upload', 'menu_item', 'vendor_listing', 'vendor_logo')),
+    object_key TEXT NOT NULL UNIQUE,            -- staging key in the upload bucket
+    content_type VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,                       -- exact for PUT, the limit for POST
+    method VARCHAR(4) NOT NULL CHECK (method IN ('PUT', 'POST')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed')),
+    image_id TEXT REFERENCES images(id) ON DELETE SET NULL,
+    uploaded_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,            -- of the presigned request
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_image_uploads_vendor ON image_uploads(vendor_id);
+CREATE INDEX idx_image_uploads_pending ON image_uploads(expires_at) WHERE status = 'pending';
+
+CREATE TRIGGER set_updated_at_image_uploads
+    BEFORE UPDATE ON image_uploads
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,15 @@
+-- =========================
+-- SETTLEMENT PAYOUT AMOUNT
+-- =========================
+-- Part of a settlement's earnings already sits in the vendor's connected
+-- Stripe account: single orders are destination charges and group splits
+-- are transferred at checkout. payout_amount is what is left to send when
+-- the settlement is claimed for payout; net_amount stays the statement total.
+
+ALTER TABLE vendor_settlements
+    ADD COLUMN payout_amount NUMERIC(12,2);
+
+-- a payout that is retried reuses its payouts row
+CREATE UNIQUE INDEX idx_vendor_settlements_payout_id
+    ON vendor_settlements(payout_id)
+    WHERE payout_id IS NOT NULL;
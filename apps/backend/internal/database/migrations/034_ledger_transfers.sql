@@ -0,0 +1,22 @@
+-- =========================
+-- LEDGER: STRIPE CONNECT TRANSFERS
+-- =========================
+-- A vendor's share of a Stripe order reaches its connected account at
+-- checkout (destination charge or group transfer), and a refund pulls it
+-- back with a transfer reversal. Both move money out of or into the
+-- platform's Stripe balance without a settlement payout.
+
+ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_entry_type_check;
+
+ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_entry_type_check CHECK (
+    entry_type IN (
+        'payment_capture',   -- customer paid through a gateway
+        'commission',        -- platform keeps its cut of an order
+        'refund',            -- order refunded, owed back to the customer
+        'refund_disbursed',  -- refund sent back through the gateway
+        'payout',            -- vendor settlement paid out
+        'adjustment',        -- manual correction by an admin
+        'transfer',          -- vendor share sent to its connected account
+        'transfer_reversal'  -- vendor share pulled back for a refund
+    )
+);
@@ -0,0 +1,15 @@
+-- =========================
+-- MENU IMPORT ROWS
+-- =========================
+-- The rows of an import saved so far, written with the items they saved. An
+-- import retried after a failure skips them instead of starting over, so
+-- the items it created are still reported as created.
+
+CREATE TABLE menu_import_rows (
+    import_id TEXT NOT NULL REFERENCES menu_imports(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    menu_item_id TEXT REFERENCES menu_items(id) ON DELETE SET NULL,
+    created BOOLEAN NOT NULL,
+
+    PRIMARY KEY (import_id, row_number)
+);
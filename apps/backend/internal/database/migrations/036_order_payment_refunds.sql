@@ -0,0 +1,21 @@
+-- =========================
+-- ORDER PAYMENT REFUNDS
+-- =========================
+-- A paid order outside a group that its vendor rejects is refunded like a
+-- group split: Stripe refunds it straight away, reversing the destination
+-- charge's transfer and application fee, while a Khalti refund is issued by
+-- hand and stays refund_pending until then.
+
+ALTER TABLE order_payments DROP CONSTRAINT order_payments_status_check;
+
+ALTER TABLE order_payments ADD CONSTRAINT order_payments_status_check CHECK (
+    status IN (
+        'initiated',
+        'success',
+        'failed',
+        'refund_pending',  -- vendor rejected, refund must be issued manually
+        'refunded'         -- vendor rejected and customer refunded
+    )
+);
+
+ALTER TABLE order_payments ADD COLUMN refund_ref TEXT;
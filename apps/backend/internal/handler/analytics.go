@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/analytics"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type AnalyticsHandler struct {
+	Handler
+	AnalyticsService *service.AnalyticsService
+}
+
+func NewAnalyticsHandler(s *server.Server, as *service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		Handler:          NewHandler(s),
+		AnalyticsService: as,
+	}
+}
+
+// =========================================================
+// VENDOR ANALYTICS
+// =========================================================
+
+// GetMyAnalytics serves the analytics of the vendor the caller works at.
+// GET /vendor-analytics/me?from=2025-01-01&to=2025-01-31&granularity=day
+func (h *AnalyticsHandler) GetMyAnalytics(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *analytics.GetAnalyticsQuery) (*analytics.VendorAnalytics, error) {
+			return h.AnalyticsService.GetMyAnalytics(c, middleware.GetVendorID(c), query)
+		},
+		http.StatusOK,
+		&analytics.GetAnalyticsQuery{},
+	)(c)
+}
+
+func (h *AnalyticsHandler) GetVendorAnalytics(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *analytics.GetVendorAnalyticsQuery) (*analytics.VendorAnalytics, error) {
+			return h.AnalyticsService.GetVendorAnalytics(c, query)
+		},
+		http.StatusOK,
+		&analytics.GetVendorAnalyticsQuery{},
+	)(c)
+}
+
+// GetBrandAnalytics serves the analytics of all outlets of a brand the caller
+// owns, with each outlet's share.
+// GET /vendor-analytics/brands/:brandId?from=2025-01-01&to=2025-01-31
+func (h *AnalyticsHandler) GetBrandAnalytics(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *analytics.GetBrandAnalyticsQuery) (*analytics.BrandAnalytics, error) {
+			return h.AnalyticsService.GetBrandAnalytics(c, middleware.GetUserID(c), query)
+		},
+		http.StatusOK,
+		&analytics.GetBrandAnalyticsQuery{},
+	)(c)
+}
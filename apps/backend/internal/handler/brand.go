@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type BrandHandler struct {
+	Handler
+	BrandService *service.BrandService
+}
+
+func NewBrandHandler(s *server.Server, bs *service.BrandService) *BrandHandler {
+	return &BrandHandler{
+		Handler:      NewHandler(s),
+		BrandService: bs,
+	}
+}
+
+type EmptyBrandPayload struct{}
+
+func (p *EmptyBrandPayload) Validate() error {
+	return nil
+}
+
+// =========================================================
+// BRANDS
+// =========================================================
+
+func (h *BrandHandler) CreateBrand(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CreateBrandPayload) (*vendor.Brand, error) {
+			return h.BrandService.CreateBrand(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&vendor.CreateBrandPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) GetMyBrands(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyBrandPayload) ([]vendor.PopulatedBrand, error) {
+			return h.BrandService.GetMyBrands(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&EmptyBrandPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) GetBrand(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetBrandPayload) (*vendor.PopulatedBrand, error) {
+			return h.BrandService.GetBrand(c, middleware.GetUserID(c), payload.ID)
+		},
+		http.StatusOK,
+		&vendor.GetBrandPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) UpdateBrand(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UpdateBrandPayload) (*vendor.Brand, error) {
+			return h.BrandService.UpdateBrand(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.UpdateBrandPayload{},
+	)(c)
+}
+
+// =========================================================
+// OUTLETS
+// =========================================================
+
+func (h *BrandHandler) CreateOutlet(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CreateOutletPayload) (*vendor.Outlet, error) {
+			return h.BrandService.CreateOutlet(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&vendor.CreateOutletPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) AttachOutlet(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.AttachOutletPayload) (*vendor.Outlet, error) {
+			return h.BrandService.AttachOutlet(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.AttachOutletPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) UpdateOutletSettings(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UpdateOutletSettingsPayload) (*vendor.Vendor, error) {
+			return h.BrandService.UpdateOutletSettings(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.UpdateOutletSettingsPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) SetOutletPayoutAccount(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.SetOutletPayoutAccountPayload) (*vendor.Outlet, error) {
+			return h.BrandService.SetOutletPayoutAccount(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.SetOutletPayoutAccountPayload{},
+	)(c)
+}
+
+// =========================================================
+// MASTER MENU
+// =========================================================
+
+func (h *BrandHandler) GetMasterMenu(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetBrandPayload) ([]vendor.MenuItem, error) {
+			return h.BrandService.GetMasterMenu(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.GetBrandPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) CreateMasterMenuItem(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CreateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+			return h.BrandService.CreateMasterMenuItem(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&vendor.CreateBrandMenuItemPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) UpdateMasterMenuItem(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UpdateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+			return h.BrandService.UpdateMasterMenuItem(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.UpdateBrandMenuItemPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) DeleteMasterMenuItem(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.DeleteBrandMenuItemPayload) error {
+			return h.BrandService.DeleteMasterMenuItem(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.DeleteBrandMenuItemPayload{},
+	)(c)
+}
+
+// =========================================================
+// OUTLET MENU OVERRIDES
+// =========================================================
+
+func (h *BrandHandler) GetMenuOverrides(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyBrandPayload) ([]vendor.PopulatedOutletMenuOverride, error) {
+			return h.BrandService.GetMenuOverrides(c, middleware.GetVendorID(c))
+		},
+		http.StatusOK,
+		&EmptyBrandPayload{},
+	)(c)
+}
+
+func (h *BrandHandler) SetMenuOverride(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.SetMenuOverridePayload) (*vendor.OutletMenuOverride, error) {
+			return h.BrandService.SetMenuOverride(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.SetMenuOverridePayload{},
+	)(c)
+}
+
+func (h *BrandHandler) DeleteMenuOverride(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.DeleteMenuOverridePayload) error {
+			return h.BrandService.DeleteMenuOverride(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.DeleteMenuOverridePayload{},
+	)(c)
+}
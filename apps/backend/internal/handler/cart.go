@@ -266,4 +266,16 @@ func (h *CartHandler) ApplyCoupon(c echo.Context) error {
 		http.StatusCreated,
 		&coupon.ApplyCouponPayload{},
 	)(c)
-}
\ No newline at end of file
+}
+
+
+func (h *CartHandler) ApplyLoyaltyPoints(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *cart.ApplyLoyaltyPointsPayload) (*cart.CartVendor, error) {
+			return h.CartService.ApplyLoyaltyPoints(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&cart.ApplyLoyaltyPointsPayload{},
+	)(c)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EmailPreviewHandler renders the email templates with email.PreviewData.
+// It is only routed in the local environment.
+type EmailPreviewHandler struct {
+	Handler
+}
+
+func NewEmailPreviewHandler(s *server.Server) *EmailPreviewHandler {
+	return &EmailPreviewHandler{
+		Handler: NewHandler(s),
+	}
+}
+
+// ListTemplates links to the preview of every template.
+func (h *EmailPreviewHandler) ListTemplates(c echo.Context) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html><html><head><title>Email previews</title></head><body style=\"font-family:sans-serif\"><h1>Email previews</h1><ul>")
+	for _, t := range email.Templates {
+		name := html.EscapeString(string(t))
+		fmt.Fprintf(&b, `<li>%s: <a href="/dev/emails/%s">html</a> · <a href="/dev/emails/%s?format=text">text</a></li>`, name, name, name)
+	}
+	b.WriteString("</ul></body></html>")
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	return c.HTML(http.StatusOK, b.String())
+}
+
+// PreviewTemplate renders one template, as HTML or with ?format=text as
+// its plain-text alternative. Templates are read from disk on every request,
+// so edits show up on reload.
+func (h *EmailPreviewHandler) PreviewTemplate(c echo.Context) error {
+	name := c.Param("template")
+	data, ok := email.PreviewData[name]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "no preview data for template "+name)
+	}
+
+	body, text, err := email.Render(email.Template(name), data)
+	if err != nil {
+		return fmt.Errorf("failed to render email preview: %w", err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	if c.QueryParam("format") == "text" {
+		if text == "" {
+			return echo.NewHTTPError(http.StatusNotFound, "template "+name+" has no plain-text version")
+		}
+		return c.String(http.StatusOK, text)
+	}
+	return c.HTML(http.StatusOK, body)
+}
@@ -9,6 +9,7 @@ import (
 type Handlers struct {
 	Health   *HealthHandler
 	OpenAPI  *OpenAPIHandler
+	EmailPreview *EmailPreviewHandler
 	User     *UserHandler
 	Vendor   *VendorHandler
 	Search   *SearchHandler
@@ -16,18 +17,47 @@ type Handlers struct {
 	Order    *OrderHandler
 	Payment  *PaymentHandler
 	Webhooks *WebhookHandler
+	Settlement *SettlementHandler
+	Ledger     *LedgerHandler
+	Invoice    *InvoiceHandler
+	Loyalty    *LoyaltyHandler
+	Referral   *ReferralHandler
+	Recommendation *RecommendationHandler
+	Notification   *NotificationHandler
+	VendorVerification *VendorVerificationHandler
+	Analytics          *AnalyticsHandler
+	VendorMember       *VendorMemberHandler
+	Brand              *BrandHandler
+	Stock              *StockHandler
+	MenuImport         *MenuImportHandler
+	Image              *ImageHandler
 }
 
 func NewHandlers(s *server.Server, services *service.Services, userRepo *repository.UserRepository) *Handlers {
 	return &Handlers{
 		Health:   NewHealthHandler(s),
 		OpenAPI:  NewOpenAPIHandler(s),
+		EmailPreview: NewEmailPreviewHandler(s),
 		User:     NewUserHandler(s, services.User),
 		Vendor:   NewVendorHandler(s, services.Vendor),
 		Search:   NewSearchHandler(s, services.Search),
 		Cart:     NewCartHandler(s, services.Cart),
-		Webhooks: NewWebhookHandler(s, services.User),
-		Order: NewOrderHandler(s,services.Order),
+		Webhooks: NewWebhookHandler(s, services.User, services.Referral, services.Email),
+		Order: NewOrderHandler(s,services.Order, services.Payment),
 		Payment: NewPaymentHandler(s,services.Payment,userRepo),
+		Settlement: NewSettlementHandler(s, services.Settlement),
+		Ledger:     NewLedgerHandler(s, services.Ledger),
+		Invoice:    NewInvoiceHandler(s, services.Invoice),
+		Loyalty:    NewLoyaltyHandler(s, services.Loyalty),
+		Referral:   NewReferralHandler(s, services.Referral),
+		Recommendation: NewRecommendationHandler(s, services.Recommendation),
+		Notification:   NewNotificationHandler(s, services.Notification),
+		VendorVerification: NewVendorVerificationHandler(s, services.VendorVerification),
+		Analytics:          NewAnalyticsHandler(s, services.Analytics),
+		VendorMember:       NewVendorMemberHandler(s, services.VendorMember),
+		Brand:              NewBrandHandler(s, services.Brand),
+		Stock:              NewStockHandler(s, services.Stock),
+		MenuImport:         NewMenuImportHandler(s, services.MenuImport),
+		Image:              NewImageHandler(s, services.Image),
 	}
 }
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/errs"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type ImageHandler struct {
+	Handler
+	ImageService *service.ImageService
+}
+
+func NewImageHandler(s *server.Server, is *service.ImageService) *ImageHandler {
+	return &ImageHandler{
+		Handler:      NewHandler(s),
+		ImageService: is,
+	}
+}
+
+// =========================================================
+// IMAGES
+// =========================================================
+
+// UploadMenuItemImage takes a multipart form with the image in "file" and
+// makes it the menu item's image.
+func (h *ImageHandler) UploadMenuItemImage(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UploadMenuItemImagePayload) (*vendor.Image, error) {
+			file, err := c.FormFile("file")
+			if err != nil {
+				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
+			}
+			return h.ImageService.UploadMenuItemImage(c, middleware.GetVendorID(c), middleware.GetUserID(c), payload, file)
+		},
+		http.StatusCreated,
+		&vendor.UploadMenuItemImagePayload{},
+	)(c)
+}
+
+// UploadVendorImage takes a multipart form with the image in "file" and
+// makes it the vendor's listing image or logo.
+func (h *ImageHandler) UploadVendorImage(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UploadVendorImagePayload) (*vendor.Image, error) {
+			file, err := c.FormFile("file")
+			if err != nil {
+				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
+			}
+			return h.ImageService.UploadVendorImage(c, middleware.GetVendorID(c), middleware.GetUserID(c), payload, file)
+		},
+		http.StatusCreated,
+		&vendor.UploadVendorImagePayload{},
+	)(c)
+}
+
+// ------------------- DIRECT UPLOADS -------------------
+
+// CreateUpload presigns a direct upload of an image not attached to
+// anything.
+func (h *ImageHandler) CreateUpload(c echo.Context) error {
+	return h.createUpload(c, vendor.ImagePurposeUpload)
+}
+
+func (h *ImageHandler) CreateMenuItemUpload(c echo.Context) error {
+	return h.createUpload(c, vendor.ImagePurposeMenuItem)
+}
+
+func (h *ImageHandler) CreateVendorUpload(c echo.Context) error {
+	return h.createUpload(c, "")
+}
+
+func (h *ImageHandler) createUpload(c echo.Context, purpose string) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CreateImageUploadPayload) (*vendor.ImageUploadResponse, error) {
+			p := purpose
+			if p == "" {
+				p = vendor.ImagePurposeVendorListing
+				if payload.Slot == "logo" {
+					p = vendor.ImagePurposeVendorLogo
+				}
+			}
+			return h.ImageService.CreateUpload(c, middleware.GetVendorID(c), middleware.GetUserID(c), p, payload)
+		},
+		http.StatusCreated,
+		&vendor.CreateImageUploadPayload{},
+	)(c)
+}
+
+// CompleteUpload is called once the file was sent to S3.
+func (h *ImageHandler) CompleteUpload(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CompleteImageUploadPayload) (*vendor.Image, error) {
+			return h.ImageService.CompleteUpload(c, middleware.GetVendorID(c), middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&vendor.CompleteImageUploadPayload{},
+	)(c)
+}
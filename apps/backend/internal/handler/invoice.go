@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/invoice"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type InvoiceHandler struct {
+	Handler
+	InvoiceService *service.InvoiceService
+}
+
+func NewInvoiceHandler(s *server.Server, is *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		Handler:        NewHandler(s),
+		InvoiceService: is,
+	}
+}
+
+func (h *InvoiceHandler) GetInvoice(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *invoice.GetInvoicePayload) (*invoice.Invoice, error) {
+			return h.InvoiceService.GetInvoice(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&invoice.GetInvoicePayload{},
+	)(c)
+}
+
+func (h *InvoiceHandler) DownloadInvoicePDF(c echo.Context) error {
+	return HandleFile(
+		h.Handler,
+		func(c echo.Context, payload *invoice.GetInvoicePayload) ([]byte, error) {
+			return h.InvoiceService.GetInvoicePDF(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&invoice.GetInvoicePayload{},
+		"invoice.pdf",
+		"application/pdf",
+	)(c)
+}
+
+func (h *InvoiceHandler) DownloadInvoiceHTML(c echo.Context) error {
+	return HandleFile(
+		h.Handler,
+		func(c echo.Context, payload *invoice.GetInvoicePayload) ([]byte, error) {
+			return h.InvoiceService.GetInvoiceHTML(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&invoice.GetInvoicePayload{},
+		"invoice.html",
+		"text/html; charset=utf-8",
+	)(c)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/ledger"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type LedgerHandler struct {
+	Handler
+	LedgerService *service.LedgerService
+}
+
+func NewLedgerHandler(s *server.Server, ls *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		Handler:       NewHandler(s),
+		LedgerService: ls,
+	}
+}
+
+// =========================================================
+// FINANCE REPORTS
+// =========================================================
+
+func (h *LedgerHandler) GetTrialBalance(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *ledger.GetTrialBalanceQuery) (*ledger.TrialBalance, error) {
+			return h.LedgerService.GetTrialBalance(c, query)
+		},
+		http.StatusOK,
+		&ledger.GetTrialBalanceQuery{},
+	)(c)
+}
+
+func (h *LedgerHandler) GetAccountBalances(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *ledger.GetAccountBalancesQuery) ([]ledger.AccountBalance, error) {
+			return h.LedgerService.GetAccountBalances(c, query)
+		},
+		http.StatusOK,
+		&ledger.GetAccountBalancesQuery{},
+	)(c)
+}
+
+func (h *LedgerHandler) GetVendorBalance(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *ledger.GetVendorBalancePayload) (*ledger.VendorBalanceResponse, error) {
+			return h.LedgerService.GetVendorBalance(c, payload.VendorUserID)
+		},
+		http.StatusOK,
+		&ledger.GetVendorBalancePayload{},
+	)(c)
+}
+
+type CheckLedgerInvariantsPayload struct{}
+
+func (p *CheckLedgerInvariantsPayload) Validate() error {
+	return nil
+}
+
+func (h *LedgerHandler) CheckInvariants(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *CheckLedgerInvariantsPayload) (*ledger.InvariantReport, error) {
+			return h.LedgerService.CheckInvariants(c)
+		},
+		http.StatusOK,
+		&CheckLedgerInvariantsPayload{},
+	)(c)
+}
+
+// =========================================================
+// VENDOR: WHAT THE PLATFORM OWES ME
+// =========================================================
+
+type GetMyLedgerBalancePayload struct{}
+
+func (p *GetMyLedgerBalancePayload) Validate() error {
+	return nil
+}
+
+func (h *LedgerHandler) GetMyBalance(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetMyLedgerBalancePayload) (*ledger.VendorBalanceResponse, error) {
+			return h.LedgerService.GetVendorBalance(c, middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&GetMyLedgerBalancePayload{},
+	)(c)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/loyalty"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type LoyaltyHandler struct {
+	Handler
+	LoyaltyService *service.LoyaltyService
+}
+
+func NewLoyaltyHandler(s *server.Server, ls *service.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		Handler:        NewHandler(s),
+		LoyaltyService: ls,
+	}
+}
+
+// =========================================================
+// CUSTOMER
+// =========================================================
+
+type GetLoyaltyStatusPayload struct{}
+
+func (p *GetLoyaltyStatusPayload) Validate() error {
+	return nil
+}
+
+func (h *LoyaltyHandler) GetMyStatus(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetLoyaltyStatusPayload) (*loyalty.Status, error) {
+			return h.LoyaltyService.GetStatus(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&GetLoyaltyStatusPayload{},
+	)(c)
+}
+
+type GetLoyaltyTiersPayload struct{}
+
+func (p *GetLoyaltyTiersPayload) Validate() error {
+	return nil
+}
+
+func (h *LoyaltyHandler) GetTiers(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetLoyaltyTiersPayload) ([]loyalty.Tier, error) {
+			return h.LoyaltyService.GetTiers(c)
+		},
+		http.StatusOK,
+		&GetLoyaltyTiersPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) GetActivePromotions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *loyalty.GetPromotionsQuery) ([]loyalty.Promotion, error) {
+			return h.LoyaltyService.GetPromotions(c, true)
+		},
+		http.StatusOK,
+		&loyalty.GetPromotionsQuery{},
+	)(c)
+}
+
+// =========================================================
+// ADMIN: EARNING RULES
+// =========================================================
+
+type GetLoyaltySettingsPayload struct{}
+
+func (p *GetLoyaltySettingsPayload) Validate() error {
+	return nil
+}
+
+func (h *LoyaltyHandler) GetSettings(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetLoyaltySettingsPayload) (*loyalty.Settings, error) {
+			return h.LoyaltyService.GetSettings(c)
+		},
+		http.StatusOK,
+		&GetLoyaltySettingsPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) UpdateSettings(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *loyalty.UpdateSettingsPayload) (*loyalty.Settings, error) {
+			return h.LoyaltyService.UpdateSettings(c, payload)
+		},
+		http.StatusOK,
+		&loyalty.UpdateSettingsPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) GetPromotions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *loyalty.GetPromotionsQuery) ([]loyalty.Promotion, error) {
+			return h.LoyaltyService.GetPromotions(c, query.ActiveOnly != nil && *query.ActiveOnly)
+		},
+		http.StatusOK,
+		&loyalty.GetPromotionsQuery{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) CreatePromotion(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *loyalty.CreatePromotionPayload) (*loyalty.Promotion, error) {
+			return h.LoyaltyService.CreatePromotion(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&loyalty.CreatePromotionPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) UpdatePromotion(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *loyalty.UpdatePromotionPayload) (*loyalty.Promotion, error) {
+			return h.LoyaltyService.UpdatePromotion(c, payload)
+		},
+		http.StatusOK,
+		&loyalty.UpdatePromotionPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) SetVendorMultiplier(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *loyalty.SetVendorMultiplierPayload) (interface{}, error) {
+			if err := h.LoyaltyService.SetVendorMultiplier(c, payload); err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"vendorId":   payload.VendorID,
+				"multiplier": payload.Multiplier,
+			}, nil
+		},
+		http.StatusOK,
+		&loyalty.SetVendorMultiplierPayload{},
+	)(c)
+}
+
+func (h *LoyaltyHandler) AwardOrderPoints(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *loyalty.AwardOrderPointsPayload) (*user.LoyaltyPointsLedger, error) {
+			return h.LoyaltyService.AwardOrderPoints(c, payload)
+		},
+		http.StatusOK,
+		&loyalty.AwardOrderPointsPayload{},
+	)(c)
+}
+
+type ExpireLoyaltyPointsPayload struct{}
+
+func (p *ExpireLoyaltyPointsPayload) Validate() error {
+	return nil
+}
+
+func (h *LoyaltyHandler) ExpirePoints(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *ExpireLoyaltyPointsPayload) (*loyalty.ExpiryResult, error) {
+			return h.LoyaltyService.ExpirePoints(c)
+		},
+		http.StatusOK,
+		&ExpireLoyaltyPointsPayload{},
+	)(c)
+}
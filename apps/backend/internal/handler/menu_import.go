@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/errs"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type MenuImportHandler struct {
+	Handler
+	MenuImportService *service.MenuImportService
+}
+
+func NewMenuImportHandler(s *server.Server, mis *service.MenuImportService) *MenuImportHandler {
+	return &MenuImportHandler{
+		Handler:           NewHandler(s),
+		MenuImportService: mis,
+	}
+}
+
+type EmptyMenuImportPayload struct{}
+
+func (p *EmptyMenuImportPayload) Validate() error {
+	return nil
+}
+
+// =========================================================
+// MENU IMPORTS
+// =========================================================
+
+// CreateImport takes a multipart form with the menu file in "file". The
+// import runs in the background; poll GetImport for its progress.
+func (h *MenuImportHandler) CreateImport(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.CreateMenuImportPayload) (*vendor.MenuImport, error) {
+			file, err := c.FormFile("file")
+			if err != nil {
+				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
+			}
+			return h.MenuImportService.CreateImport(c, middleware.GetVendorID(c), middleware.GetUserID(c), payload, file)
+		},
+		http.StatusAccepted,
+		&vendor.CreateMenuImportPayload{},
+	)(c)
+}
+
+func (h *MenuImportHandler) GetImports(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyMenuImportPayload) ([]vendor.MenuImport, error) {
+			return h.MenuImportService.GetImports(c, middleware.GetVendorID(c))
+		},
+		http.StatusOK,
+		&EmptyMenuImportPayload{},
+	)(c)
+}
+
+func (h *MenuImportHandler) GetImport(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetMenuImportPayload) (*vendor.MenuImport, error) {
+			return h.MenuImportService.GetImport(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.GetMenuImportPayload{},
+	)(c)
+}
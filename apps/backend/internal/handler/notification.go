@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/notification"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type NotificationHandler struct {
+	Handler
+	NotificationService *service.NotificationService
+}
+
+func NewNotificationHandler(s *server.Server, ns *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		Handler:             NewHandler(s),
+		NotificationService: ns,
+	}
+}
+
+type EmptyNotificationPayload struct{}
+
+func (p *EmptyNotificationPayload) Validate() error {
+	return nil
+}
+
+// ==================================================
+// INBOX
+// ==================================================
+
+func (h *NotificationHandler) GetInbox(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *notification.GetInboxQuery) (*model.PaginatedResponse[notification.Notification], error) {
+			return h.NotificationService.GetInbox(c, middleware.GetUserID(c), query)
+		},
+		http.StatusOK,
+		&notification.GetInboxQuery{},
+	)(c)
+}
+
+func (h *NotificationHandler) GetInboxSummary(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyNotificationPayload) (*notification.InboxSummary, error) {
+			return h.NotificationService.GetInboxSummary(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&EmptyNotificationPayload{},
+	)(c)
+}
+
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *notification.MarkReadPayload) (*notification.Notification, error) {
+			return h.NotificationService.MarkRead(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&notification.MarkReadPayload{},
+	)(c)
+}
+
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyNotificationPayload) (*notification.InboxSummary, error) {
+			return h.NotificationService.MarkAllRead(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&EmptyNotificationPayload{},
+	)(c)
+}
+
+// ==================================================
+// PREFERENCES
+// ==================================================
+
+func (h *NotificationHandler) GetPreferences(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyNotificationPayload) (*notification.Preferences, error) {
+			return h.NotificationService.GetPreferences(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&EmptyNotificationPayload{},
+	)(c)
+}
+
+func (h *NotificationHandler) UpdatePreferences(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *notification.UpdatePreferencesPayload) (*notification.Preferences, error) {
+			return h.NotificationService.UpdatePreferences(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&notification.UpdatePreferencesPayload{},
+	)(c)
+}
+
+// ==================================================
+// WEB PUSH
+// ==================================================
+
+func (h *NotificationHandler) GetPushPublicKey(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyNotificationPayload) (map[string]string, error) {
+			return h.NotificationService.GetPushPublicKey(c)
+		},
+		http.StatusOK,
+		&EmptyNotificationPayload{},
+	)(c)
+}
+
+func (h *NotificationHandler) SubscribePush(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *notification.SubscribePushPayload) (*notification.PushSubscription, error) {
+			return h.NotificationService.SubscribePush(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&notification.SubscribePushPayload{},
+	)(c)
+}
+
+func (h *NotificationHandler) UnsubscribePush(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *notification.DeletePushSubscriptionPayload) error {
+			return h.NotificationService.UnsubscribePush(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusNoContent,
+		&notification.DeletePushSubscriptionPayload{},
+	)(c)
+}
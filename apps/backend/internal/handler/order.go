@@ -13,15 +13,16 @@ import (
 
 type OrderHandler struct {
 	Handler
-	OrderService *service.OrderService
-
+	OrderService   *service.OrderService
+	PaymentService *service.PaymentService
 }
 
 
-func NewOrderHandler(s *server.Server, os *service.OrderService) *OrderHandler {
+func NewOrderHandler(s *server.Server, os *service.OrderService, ps *service.PaymentService) *OrderHandler {
 	return &OrderHandler{
-		Handler:     NewHandler(s),
-		OrderService: os,
+		Handler:        NewHandler(s),
+		OrderService:   os,
+		PaymentService: ps,
 	}
 }
 
@@ -104,4 +105,95 @@ func (h *OrderHandler) GetOrderById(c echo.Context) error {
 		http.StatusOK,
 		&order.GetOrderByIDPayload{},
 	)(c)
-}
\ No newline at end of file
+}
+
+
+
+// =========================================================
+// CHECKOUT WHOLE CART INTO AN ORDER GROUP
+// =========================================================
+
+
+func (h *OrderHandler) CheckoutCart(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *order.CheckoutCartPayload) (*order.PopulatedOrderGroup, error) {
+			return h.OrderService.CheckoutCart(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&order.CheckoutCartPayload{},
+	)(c)
+}
+
+
+func (h *OrderHandler) GetOrderGroupById(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *order.GetOrderGroupByIDPayload) (*order.PopulatedOrderGroup, error) {
+			group, err := h.OrderService.GetOrderGroupByID(c, payload)
+			if err != nil {
+				return nil, err
+			}
+			if group.UserID != middleware.GetUserID(c) {
+				return nil, echo.NewHTTPError(http.StatusNotFound, "order group not found")
+			}
+			return group, nil
+		},
+		http.StatusOK,
+		&order.GetOrderGroupByIDPayload{},
+	)(c)
+}
+
+
+
+// =========================================================
+// VENDOR REJECTS ITS PART OF AN ORDER
+// =========================================================
+
+
+func (h *OrderHandler) UpdateOrderStatus(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *order.UpdateOrderStatusPayload) (*order.GroupOrderVendor, error) {
+			return h.OrderService.UpdateVendorOrderStatus(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&order.UpdateOrderStatusPayload{},
+	)(c)
+}
+
+func (h *OrderHandler) RejectOrder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *order.RejectOrderPayload) (interface{}, error) {
+			if err := h.PaymentService.RejectVendorOrder(c, middleware.GetVendorID(c), payload); err != nil {
+				return nil, err
+			}
+			return map[string]string{
+				"message": "Order rejected",
+				"orderId": payload.ID,
+			}, nil
+		},
+		http.StatusOK,
+		&order.RejectOrderPayload{},
+	)(c)
+}
+
+
+
+// =========================================================
+// REBUILD CART FROM A PAST ORDER
+// =========================================================
+
+
+func (h *OrderHandler) Reorder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *order.ReorderPayload) (*order.ReorderResult, error) {
+			userID := middleware.GetUserID(c)
+			return h.OrderService.Reorder(c, userID, payload)
+		},
+		http.StatusOK,
+		&order.ReorderPayload{},
+	)(c)
+}
@@ -120,6 +120,32 @@ func (h *PaymentHandler) StripePayment(c echo.Context) error {
 
 }
 
+func (h *PaymentHandler) StripeGroupPayment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *payment.GroupPaymentPayload) (*payment.StripePaymentResponse, error) {
+
+			return h.PaymentService.ProcessStripeGroupCheckout(c, payload)
+		},
+		http.StatusCreated,
+		&payment.GroupPaymentPayload{},
+	)(c)
+
+}
+
+func (h *PaymentHandler) KhaltiGroupPayment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *payment.GroupPaymentPayload) (*payment.KhaltiPaymentResponse, error) {
+
+			return h.PaymentService.ProcessKhaltiGroupPayment(c, payload)
+		},
+		http.StatusCreated,
+		&payment.GroupPaymentPayload{},
+	)(c)
+
+}
+
 func (h *PaymentHandler) VerifyStripePayment(c echo.Context) error {
 
 	payload := &payment.StripeVerifyPayload{
@@ -169,7 +195,7 @@ func (h *PaymentHandler) OnboardingStripeConnectAccount(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *payment.OnboardingPayload) (*payment.OnboardingResponse, error) {
-
+			payload.VendorUserId = middleware.GetVendorOwnerID(c)
 			return h.PaymentService.CreateOnboardingAccountWithLink(c, payload)
 		},
 		http.StatusCreated,
@@ -182,7 +208,7 @@ func (h *PaymentHandler) CreateOnboardingAccountLink(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *payment.OnboardingAccountLinkPayload) (*payment.OnboardingResponse, error) {
-			userVendorID := middleware.GetUserID(c)
+			userVendorID := middleware.GetVendorOwnerID(c)
 			url, err := h.PaymentService.CreateOnboardingLink(c.Request().Context(), payload.AccountId, userVendorID)
 			if err != nil {
 				return nil, fmt.Errorf("err creating link%v", err)
@@ -288,6 +314,24 @@ func (ph *PaymentHandler) HandleStripeWebhook(c echo.Context) error {
 		}
 		orderID := session.Metadata["purchase_order_id"]
 
+		// Combined checkout of a multi-vendor cart
+		if groupID := session.Metadata["order_group_id"]; groupID != "" {
+			logger := middleware.GetLogger(c)
+			groupID, status, err := ph.PaymentService.VerifyAndUpdateStripeGroupPayment(ctx, session.ID)
+			if err != nil {
+				logger.Error().Err(err).Str("session_id", session.ID).Msg("failed to update group payment")
+				return c.String(http.StatusInternalServerError, "Failed to update payment")
+			}
+			logger.Info().
+				Str("order_group_id", groupID).
+				Str("session_id", session.ID).
+				Str("status", status).
+				Msg("Stripe group payment processed")
+			return c.JSON(http.StatusOK, map[string]string{
+				"Messages": "successfully processed",
+			})
+		}
+
 		// 🚀 Call your Verify & Update function
 		orderID, status, err := ph.PaymentService.VerifyAndUpdateStripePayment(ctx, &payment.StripeVerifyPayload{
 			SessionID: session.ID,
@@ -331,7 +375,7 @@ func (ph *PaymentHandler) HandleStripeWebhook(c echo.Context) error {
 		orderID := ch.Metadata["purchase_order_id"]
 		vendorUserId := ch.Metadata["vendor_user_id"]
 
-		payoutAccId, err := ph.PaymentService.GetPayoutAccountID(ctx, vendorUserId)
+		payoutAccId, err := ph.PaymentService.GetOrderPayoutAccountID(ctx, orderID)
 		if err != nil {
 			return c.String(http.StatusInternalServerError, "Failed to get payout account")
 		}
@@ -365,6 +409,11 @@ func (ph *PaymentHandler) HandleStripeWebhook(c echo.Context) error {
 			return c.String(http.StatusInternalServerError, "Failed to update payout status")
 		}
 		return c.NoContent(http.StatusOK)
+	case "transfer.created":
+		if err := ph.PaymentService.HandleSettlementTransfer(ctx, event); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to update settlement status")
+		}
+		return c.NoContent(http.StatusOK)
 
 	default:
 		// Ignore other events
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/recommendation"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type RecommendationHandler struct {
+	Handler
+	RecommendationService *service.RecommendationService
+}
+
+func NewRecommendationHandler(s *server.Server, rs *service.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{
+		Handler:               NewHandler(s),
+		RecommendationService: rs,
+	}
+}
+
+type GetRecommendationsPayload struct{}
+
+func (p *GetRecommendationsPayload) Validate() error {
+	return nil
+}
+
+func (h *RecommendationHandler) GetRecommendations(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetRecommendationsPayload) (*recommendation.Recommendations, error) {
+			return h.RecommendationService.GetRecommendations(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&GetRecommendationsPayload{},
+	)(c)
+}
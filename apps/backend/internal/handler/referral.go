@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/referral"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type ReferralHandler struct {
+	Handler
+	ReferralService *service.ReferralService
+}
+
+func NewReferralHandler(s *server.Server, rs *service.ReferralService) *ReferralHandler {
+	return &ReferralHandler{
+		Handler:         NewHandler(s),
+		ReferralService: rs,
+	}
+}
+
+// =========================================================
+// CUSTOMER
+// =========================================================
+
+type GetMyReferralsPayload struct{}
+
+func (p *GetMyReferralsPayload) Validate() error {
+	return nil
+}
+
+func (h *ReferralHandler) GetMyReferrals(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetMyReferralsPayload) (*referral.MyReferrals, error) {
+			return h.ReferralService.GetMyReferrals(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&GetMyReferralsPayload{},
+	)(c)
+}
+
+// =========================================================
+// ADMIN
+// =========================================================
+
+type GetReferralSettingsPayload struct{}
+
+func (p *GetReferralSettingsPayload) Validate() error {
+	return nil
+}
+
+func (h *ReferralHandler) GetSettings(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetReferralSettingsPayload) (*referral.Settings, error) {
+			return h.ReferralService.GetSettings(c)
+		},
+		http.StatusOK,
+		&GetReferralSettingsPayload{},
+	)(c)
+}
+
+func (h *ReferralHandler) UpdateSettings(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *referral.UpdateSettingsPayload) (*referral.Settings, error) {
+			return h.ReferralService.UpdateSettings(c, payload)
+		},
+		http.StatusOK,
+		&referral.UpdateSettingsPayload{},
+	)(c)
+}
+
+type GetReferralStatsPayload struct{}
+
+func (p *GetReferralStatsPayload) Validate() error {
+	return nil
+}
+
+func (h *ReferralHandler) GetProgramStats(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetReferralStatsPayload) (*referral.ProgramStats, error) {
+			return h.ReferralService.GetProgramStats(c)
+		},
+		http.StatusOK,
+		&GetReferralStatsPayload{},
+	)(c)
+}
+
+func (h *ReferralHandler) GetReferrals(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *referral.GetReferralsQuery) (*model.PaginatedResponse[referral.Referral], error) {
+			return h.ReferralService.GetReferrals(c, query)
+		},
+		http.StatusOK,
+		&referral.GetReferralsQuery{},
+	)(c)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/settlement"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type SettlementHandler struct {
+	Handler
+	SettlementService *service.SettlementService
+}
+
+func NewSettlementHandler(s *server.Server, ss *service.SettlementService) *SettlementHandler {
+	return &SettlementHandler{
+		Handler:           NewHandler(s),
+		SettlementService: ss,
+	}
+}
+
+// =========================================================
+// VENDOR: SETTLEMENTS & STATEMENTS
+// =========================================================
+
+func (h *SettlementHandler) GetMySettlements(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *settlement.GetSettlementsQuery) (*model.PaginatedResponse[settlement.VendorSettlement], error) {
+			vendorUserID := middleware.GetVendorOwnerID(c)
+			return h.SettlementService.GetSettlements(c, &vendorUserID, query)
+		},
+		http.StatusOK,
+		&settlement.GetSettlementsQuery{},
+	)(c)
+}
+
+type GetUnsettledEntriesPayload struct{}
+
+func (p *GetUnsettledEntriesPayload) Validate() error {
+	return nil
+}
+
+func (h *SettlementHandler) GetMyUnsettledEntries(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *GetUnsettledEntriesPayload) ([]settlement.VendorEarning, error) {
+			return h.SettlementService.GetUnsettledEntries(c, middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&GetUnsettledEntriesPayload{},
+	)(c)
+}
+
+func (h *SettlementHandler) GetStatement(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *settlement.GetSettlementByIDPayload) (*settlement.SettlementStatement, error) {
+			return h.SettlementService.GetStatement(c, middleware.GetVendorOwnerID(c), payload)
+		},
+		http.StatusOK,
+		&settlement.GetSettlementByIDPayload{},
+	)(c)
+}
+
+// =========================================================
+// ADMIN: ADJUSTMENTS, RUNS & MANUAL PAYOUTS
+// =========================================================
+
+func (h *SettlementHandler) GetSettlements(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *settlement.GetSettlementsQuery) (*model.PaginatedResponse[settlement.VendorSettlement], error) {
+			return h.SettlementService.GetSettlements(c, nil, query)
+		},
+		http.StatusOK,
+		&settlement.GetSettlementsQuery{},
+	)(c)
+}
+
+func (h *SettlementHandler) CreateAdjustment(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *settlement.CreateAdjustmentPayload) (*settlement.VendorEarning, error) {
+			return h.SettlementService.CreateAdjustment(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&settlement.CreateAdjustmentPayload{},
+	)(c)
+}
+
+func (h *SettlementHandler) RunSettlement(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *settlement.RunSettlementPayload) (*settlement.RunSettlementResponse, error) {
+			return h.SettlementService.RunSettlement(c, payload)
+		},
+		http.StatusOK,
+		&settlement.RunSettlementPayload{},
+	)(c)
+}
+
+func (h *SettlementHandler) MarkSettlementPaid(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *settlement.MarkSettlementPaidPayload) (*settlement.VendorSettlement, error) {
+			return h.SettlementService.MarkSettlementPaid(c, payload)
+		},
+		http.StatusOK,
+		&settlement.MarkSettlementPaidPayload{},
+	)(c)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type StockHandler struct {
+	Handler
+	StockService *service.StockService
+}
+
+func NewStockHandler(s *server.Server, ss *service.StockService) *StockHandler {
+	return &StockHandler{
+		Handler:      NewHandler(s),
+		StockService: ss,
+	}
+}
+
+type EmptyStockPayload struct{}
+
+func (p *EmptyStockPayload) Validate() error {
+	return nil
+}
+
+// =========================================================
+// MENU STOCK
+// =========================================================
+
+func (h *StockHandler) GetStock(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyStockPayload) ([]vendor.PopulatedMenuItemStock, error) {
+			return h.StockService.GetStock(c, middleware.GetVendorID(c))
+		},
+		http.StatusOK,
+		&EmptyStockPayload{},
+	)(c)
+}
+
+func (h *StockHandler) SetStock(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.SetMenuItemStockPayload) (*vendor.PopulatedMenuItemStock, error) {
+			return h.StockService.SetStock(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.SetMenuItemStockPayload{},
+	)(c)
+}
+
+func (h *StockHandler) DeleteStock(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.DeleteMenuItemStockPayload) error {
+			return h.StockService.DeleteStock(c, middleware.GetVendorID(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.DeleteMenuItemStockPayload{},
+	)(c)
+}
@@ -110,16 +110,63 @@ func (h *UserHandler) GetUsers(c echo.Context) error {
 	)(c)
 }
 
-// ------------------- DELETE USER -------------------
+// ------------------- ACCOUNT DELETION -------------------
+type accountDeletionPayload struct{}
+
+func (p *accountDeletionPayload) Validate() error {
+	return nil
+}
+
+// DeleteUser schedules the account for anonymization; it can be cancelled
+// during the grace period.
 func (h *UserHandler) DeleteUser(c echo.Context) error {
 	return Handle(
 		h.Handler,
-		func(c echo.Context, payload *user.DeleteUserAddressPayload) (interface{}, error) {
+		func(c echo.Context, _ *accountDeletionPayload) (*user.DeletionStatus, error) {
 			userID := middleware.GetUserID(c)
-			return nil, h.UserService.DeleteUser(c, userID)
+			return h.UserService.RequestDeletion(c, userID)
 		},
-		http.StatusNoContent,
-		&user.DeleteUserAddressPayload{},
+		http.StatusAccepted,
+		&accountDeletionPayload{},
+	)(c)
+}
+
+func (h *UserHandler) GetDeletionStatus(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *accountDeletionPayload) (*user.DeletionStatus, error) {
+			userID := middleware.GetUserID(c)
+			return h.UserService.GetDeletionStatus(c, userID)
+		},
+		http.StatusOK,
+		&accountDeletionPayload{},
+	)(c)
+}
+
+func (h *UserHandler) CancelDeletion(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *accountDeletionPayload) (*user.DeletionStatus, error) {
+			userID := middleware.GetUserID(c)
+			return h.UserService.CancelDeletion(c, userID)
+		},
+		http.StatusOK,
+		&accountDeletionPayload{},
+	)(c)
+}
+
+// ------------------- EXPORT DATA -------------------
+func (h *UserHandler) ExportData(c echo.Context) error {
+	return HandleFile(
+		h.Handler,
+		func(c echo.Context, _ *accountDeletionPayload) ([]byte, error) {
+			userID := middleware.GetUserID(c)
+			return h.UserService.ExportData(c, userID)
+		},
+		http.StatusOK,
+		&accountDeletionPayload{},
+		"khajaride-data.zip",
+		"application/zip",
 	)(c)
 }
 
@@ -264,3 +311,28 @@ func (h *UserHandler) AdjustPoints(c echo.Context) error {
 		&user.AdjustPointsPayload{},
 	)(c)
 }
+
+// ------------------- PHONE VERIFICATION -------------------
+func (h *UserHandler) RequestPhoneOTP(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *user.RequestPhoneOTPPayload) (*user.PhoneOTPIssued, error) {
+			userID := middleware.GetUserID(c)
+			return h.UserService.RequestPhoneOTP(c, userID, payload)
+		},
+		http.StatusAccepted,
+		&user.RequestPhoneOTPPayload{},
+	)(c)
+}
+
+func (h *UserHandler) VerifyPhoneOTP(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *user.VerifyPhoneOTPPayload) (*user.User, error) {
+			userID := middleware.GetUserID(c)
+			return h.UserService.VerifyPhoneOTP(c, userID, payload)
+		},
+		http.StatusOK,
+		&user.VerifyPhoneOTPPayload{},
+	)(c)
+}
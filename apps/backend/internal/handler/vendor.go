@@ -49,6 +49,7 @@ func (h *VendorHandler) CreateVendor(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *vendor.CreateVendorPayload) (*vendor.Vendor, error) {
+			payload.VendorUserID = middleware.GetUserID(c)
 			return h.VendorService.CreateVendor(c, payload)
 		},
 		http.StatusCreated,
@@ -63,6 +64,7 @@ func (h *VendorHandler) CreateVendorAddress(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *vendor.CreateVendorAddressPayload) (*vendor.VendorAddress, error) {
+			payload.VendorID = middleware.GetVendorID(c)
 			return h.VendorService.CreateVendorAddress(c, payload)
 		},
 		http.StatusCreated,
@@ -93,7 +95,7 @@ func (h *VendorHandler) UploadImages(c echo.Context) error {
 				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
 			}
 
-			return h.VendorService.UploadImages(c,files)
+			return h.VendorService.UploadImages(c, middleware.GetVendorID(c), middleware.GetUserID(c), files)
 		},
 		http.StatusCreated,
 		&UploadImagesPayload{},
@@ -205,16 +207,52 @@ func (p *GetVendorByUserIDPayload) Validate() error {
 }
 
 
+// GetVendorByUserID returns the vendor the signed-in user works at.
 func (h *VendorHandler) GetVendorByUserID(c echo.Context) error {
-	
-
 	return Handle(
 		h.Handler,
 		func(c echo.Context, p *GetVendorByUserIDPayload) (*vendor.VendorWithAddress, error) {
-			vendorUserId := middleware.GetUserID(c)
-			return h.VendorService.GetVendorByUserID(c, vendorUserId)
+			return h.VendorService.GetMyVendor(c, middleware.GetVendorID(c))
 		},
 		http.StatusOK,
 		&GetVendorByUserIDPayload{},
 	)(c)
 }
+
+
+// ------------------- FAVORITES -------------------
+func (h *VendorHandler) AddFavorite(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.AddFavoritePayload) (*vendor.FavoriteState, error) {
+			userID := middleware.GetUserID(c)
+			return h.VendorService.AddFavorite(c, userID, payload)
+		},
+		http.StatusOK,
+		&vendor.AddFavoritePayload{},
+	)(c)
+}
+
+func (h *VendorHandler) RemoveFavorite(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.RemoveFavoritePayload) (*vendor.FavoriteState, error) {
+			userID := middleware.GetUserID(c)
+			return h.VendorService.RemoveFavorite(c, userID, payload)
+		},
+		http.StatusOK,
+		&vendor.RemoveFavoritePayload{},
+	)(c)
+}
+
+func (h *VendorHandler) GetFavorites(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetFavoritesQuery) (*model.PaginatedResponse[vendor.PopulatedFavorite], error) {
+			userID := middleware.GetUserID(c)
+			return h.VendorService.GetFavorites(c, userID, payload)
+		},
+		http.StatusOK,
+		&vendor.GetFavoritesQuery{},
+	)(c)
+}
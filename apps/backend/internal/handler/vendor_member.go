@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type VendorMemberHandler struct {
+	Handler
+	MemberService *service.VendorMemberService
+}
+
+func NewVendorMemberHandler(s *server.Server, ms *service.VendorMemberService) *VendorMemberHandler {
+	return &VendorMemberHandler{
+		Handler:       NewHandler(s),
+		MemberService: ms,
+	}
+}
+
+type EmptyMemberPayload struct{}
+
+func (p *EmptyMemberPayload) Validate() error {
+	return nil
+}
+
+// =========================================================
+// MEMBERSHIPS
+// =========================================================
+
+func (h *VendorMemberHandler) GetMyMemberships(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyMemberPayload) ([]vendor.Membership, error) {
+			return h.MemberService.GetMyMemberships(c, middleware.GetUserID(c))
+		},
+		http.StatusOK,
+		&EmptyMemberPayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) GetMembers(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyMemberPayload) ([]vendor.PopulatedVendorMember, error) {
+			return h.MemberService.GetMembers(c, middleware.GetVendorID(c))
+		},
+		http.StatusOK,
+		&EmptyMemberPayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) UpdateMemberRole(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UpdateMemberRolePayload) (*vendor.VendorMember, error) {
+			return h.MemberService.UpdateMemberRole(c, middleware.GetVendorID(c), middleware.GetVendorRole(c), payload)
+		},
+		http.StatusOK,
+		&vendor.UpdateMemberRolePayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) RemoveMember(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.RemoveMemberPayload) error {
+			return h.MemberService.RemoveMember(c, middleware.GetVendorID(c), middleware.GetVendorRole(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.RemoveMemberPayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) LeaveVendor(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, _ *EmptyMemberPayload) error {
+			return h.MemberService.LeaveVendor(c, middleware.GetVendorID(c), middleware.GetUserID(c), middleware.GetVendorRole(c))
+		},
+		http.StatusNoContent,
+		&EmptyMemberPayload{},
+	)(c)
+}
+
+// =========================================================
+// INVITATIONS
+// =========================================================
+
+func (h *VendorMemberHandler) InviteMember(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.InviteMemberPayload) (*vendor.VendorInvitation, error) {
+			return h.MemberService.InviteMember(c, middleware.GetVendorID(c), middleware.GetVendorRole(c), middleware.GetUserID(c), payload)
+		},
+		http.StatusCreated,
+		&vendor.InviteMemberPayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) GetInvitations(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyMemberPayload) ([]vendor.VendorInvitation, error) {
+			return h.MemberService.GetInvitations(c, middleware.GetVendorID(c))
+		},
+		http.StatusOK,
+		&EmptyMemberPayload{},
+	)(c)
+}
+
+func (h *VendorMemberHandler) RevokeInvitation(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.RevokeInvitationPayload) error {
+			return h.MemberService.RevokeInvitation(c, middleware.GetVendorID(c), middleware.GetVendorRole(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.RevokeInvitationPayload{},
+	)(c)
+}
+
+// AcceptInvitation takes the token from the invitation email; the user must
+// be signed in with the address it was sent to.
+func (h *VendorMemberHandler) AcceptInvitation(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.AcceptInvitationPayload) (*vendor.VendorMember, error) {
+			return h.MemberService.AcceptInvitation(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.AcceptInvitationPayload{},
+	)(c)
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/errs"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/gitSanje/khajaride/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type VendorVerificationHandler struct {
+	Handler
+	VerificationService *service.VendorVerificationService
+}
+
+func NewVendorVerificationHandler(s *server.Server, vs *service.VendorVerificationService) *VendorVerificationHandler {
+	return &VendorVerificationHandler{
+		Handler:             NewHandler(s),
+		VerificationService: vs,
+	}
+}
+
+type EmptyVerificationPayload struct{}
+
+func (p *EmptyVerificationPayload) Validate() error {
+	return nil
+}
+
+// =========================================================
+// VENDOR: DOCUMENTS & SUBMISSION
+// =========================================================
+
+func (h *VendorVerificationHandler) GetMyVerification(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyVerificationPayload) (*vendor.Verification, error) {
+			return h.VerificationService.GetMyVerification(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&EmptyVerificationPayload{},
+	)(c)
+}
+
+// UploadDocument takes a multipart form with the document in "file".
+func (h *VendorVerificationHandler) UploadDocument(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.UploadDocumentPayload) (*vendor.VendorDocument, error) {
+			file, err := c.FormFile("file")
+			if err != nil {
+				return nil, errs.NewBadRequestError("no file found", false, nil, nil, nil)
+			}
+			return h.VerificationService.UploadDocument(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c), payload, file)
+		},
+		http.StatusCreated,
+		&vendor.UploadDocumentPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) DeleteDocument(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *vendor.DeleteDocumentPayload) error {
+			return h.VerificationService.DeleteDocument(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c), payload)
+		},
+		http.StatusNoContent,
+		&vendor.DeleteDocumentPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) Submit(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyVerificationPayload) (*vendor.Verification, error) {
+			return h.VerificationService.Submit(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&EmptyVerificationPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) Activate(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyVerificationPayload) (*vendor.Verification, error) {
+			return h.VerificationService.Activate(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&EmptyVerificationPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) GetMyCompliance(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, _ *EmptyVerificationPayload) (*vendor.Compliance, error) {
+			return h.VerificationService.GetMyCompliance(c, middleware.GetVendorID(c), middleware.GetVendorOwnerID(c))
+		},
+		http.StatusOK,
+		&EmptyVerificationPayload{},
+	)(c)
+}
+
+// =========================================================
+// ADMIN: REVIEW QUEUE & DECISIONS
+// =========================================================
+
+func (h *VendorVerificationHandler) GetQueue(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *vendor.GetVerificationQueueQuery) (*model.PaginatedResponse[vendor.VerificationQueueItem], error) {
+			return h.VerificationService.GetQueue(c, query)
+		},
+		http.StatusOK,
+		&vendor.GetVerificationQueueQuery{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) GetVerification(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetVerificationPayload) (*vendor.Verification, error) {
+			return h.VerificationService.GetVerification(c, payload)
+		},
+		http.StatusOK,
+		&vendor.GetVerificationPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) GetCompliance(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.GetVerificationPayload) (*vendor.Compliance, error) {
+			return h.VerificationService.GetCompliance(c, payload)
+		},
+		http.StatusOK,
+		&vendor.GetVerificationPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) Approve(c echo.Context) error {
+	return h.review(c, h.VerificationService.Approve)
+}
+
+func (h *VendorVerificationHandler) Reject(c echo.Context) error {
+	return h.review(c, h.VerificationService.Reject)
+}
+
+func (h *VendorVerificationHandler) RequestChanges(c echo.Context) error {
+	return h.review(c, h.VerificationService.RequestChanges)
+}
+
+func (h *VendorVerificationHandler) Suspend(c echo.Context) error {
+	return h.review(c, h.VerificationService.Suspend)
+}
+
+func (h *VendorVerificationHandler) Reinstate(c echo.Context) error {
+	return h.review(c, h.VerificationService.Reinstate)
+}
+
+func (h *VendorVerificationHandler) review(c echo.Context, decide func(echo.Context, string, *vendor.ReviewVendorPayload) (*vendor.Verification, error)) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+			return decide(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.ReviewVendorPayload{},
+	)(c)
+}
+
+func (h *VendorVerificationHandler) ReviewDocument(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *vendor.ReviewDocumentPayload) (*vendor.VendorDocument, error) {
+			return h.VerificationService.ReviewDocument(c, middleware.GetUserID(c), payload)
+		},
+		http.StatusOK,
+		&vendor.ReviewDocumentPayload{},
+	)(c)
+}
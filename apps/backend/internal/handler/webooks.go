@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/gitSanje/khajaride/internal/lib/job"
 	"github.com/gitSanje/khajaride/internal/lib/utils"
 	"github.com/gitSanje/khajaride/internal/middleware"
+	emailmodel "github.com/gitSanje/khajaride/internal/model/email"
+	"github.com/gitSanje/khajaride/internal/model/user"
 	"github.com/gitSanje/khajaride/internal/server"
 	"github.com/gitSanje/khajaride/internal/service"
     "github.com/svix/svix-webhooks/go"
@@ -18,12 +23,16 @@ type WebhookHandler struct {
 	Handler
 	server     *server.Server
 	UserService *service.UserService
+	ReferralService *service.ReferralService
+	EmailService    *service.EmailService
 }
 
-func NewWebhookHandler(s *server.Server, us *service.UserService) *WebhookHandler {
+func NewWebhookHandler(s *server.Server, us *service.UserService, rs *service.ReferralService, es *service.EmailService) *WebhookHandler {
 	return &WebhookHandler{
 		server:     s,
 		UserService: us,
+		ReferralService: rs,
+		EmailService:    es,
 	}
 }
 
@@ -46,27 +55,153 @@ func (h *WebhookHandler) HandleClerkWebhook(c echo.Context) error {
     }
 
     var event struct {
-        Type string `json:"type"`
-		Data json.RawMessage `json:"data"`
+        Type      string          `json:"type"`
+        Data      json.RawMessage `json:"data"`
+        Timestamp int64           `json:"timestamp"`
     }
     if err := json.Unmarshal(body, &event); err != nil {
         return echo.NewHTTPError(http.StatusBadRequest, "invalid json")
     }
-   
+
+    // Svix retries deliveries; a processed one is acknowledged again
+    eventID := c.Request().Header.Get("svix-id")
+    processed, err := h.UserService.IsClerkEventProcessed(c, eventID)
+    if err != nil {
+        return err
+    }
+    if processed {
+        return c.JSON(http.StatusOK, map[string]string{"status": "duplicate"})
+    }
+
+    var objectID string
     switch event.Type {
-    case "user.created":
-        userPayload, err := utils.MapClerkUserToCreateUser(event.Data)
+    case "user.created", "user.updated":
+        var clerkUser clerk.User
+        if err := json.Unmarshal(event.Data, &clerkUser); err != nil {
+            return echo.NewHTTPError(http.StatusBadRequest, "invalid user payload")
+        }
+        objectID = clerkUser.ID
+
+        synced, inserted, err := h.UserService.SyncClerkUser(c, utils.MapClerkUser(&clerkUser))
         if err != nil {
             return err
         }
-        _, err = h.UserService.CreateUser(c, userPayload)
-        if err != nil {
+
+        // Referral attribution must not fail the webhook, Clerk would
+        // retry and the user already exists
+        if synced != nil && inserted {
+            attribution, err := utils.MapClerkReferralAttribution(&clerkUser)
+            if err != nil {
+                logger.Error().Err(err).Str("user_id", synced.ID).Msg("failed to read referral attribution")
+            }
+            if _, err := h.ReferralService.RegisterSignup(c, synced, attribution); err != nil {
+                logger.Error().Err(err).Str("user_id", synced.ID).Msg("failed to register referral signup")
+            }
+        }
+
+    case "user.deleted":
+        var deleted clerk.DeletedResource
+        if err := json.Unmarshal(event.Data, &deleted); err != nil || deleted.ID == "" {
+            return echo.NewHTTPError(http.StatusBadRequest, "invalid user payload")
+        }
+        objectID = deleted.ID
+
+        if err := h.UserService.DeleteClerkUser(c, deleted.ID, event.Timestamp); err != nil {
             return err
         }
+
+    case "session.created":
+        var session clerk.Session
+        if err := json.Unmarshal(event.Data, &session); err != nil {
+            return echo.NewHTTPError(http.StatusBadRequest, "invalid session payload")
+        }
+        objectID = session.UserID
+
+        if err := h.UserService.RecordClerkSignIn(c, session.UserID, time.UnixMilli(session.CreatedAt)); err != nil {
+            return err
+        }
+
+    case "email.created":
+        var clerkEmail struct {
+            ID               string `json:"id"`
+            ToEmailAddress   string `json:"to_email_address"`
+            Subject          string `json:"subject"`
+            Body             string `json:"body"`
+            DeliveredByClerk bool   `json:"delivered_by_clerk"`
+        }
+        if err := json.Unmarshal(event.Data, &clerkEmail); err != nil {
+            return echo.NewHTTPError(http.StatusBadRequest, "invalid email payload")
+        }
+        objectID = clerkEmail.ID
+
+        // Clerk only hands over the emails it is configured not to send
+        if !clerkEmail.DeliveredByClerk {
+            err := h.UserService.SendClerkEmail(c, job.ClerkEmailPayload{
+                EmailID: clerkEmail.ID,
+                To:      clerkEmail.ToEmailAddress,
+                Subject: clerkEmail.Subject,
+                HTML:    clerkEmail.Body,
+            })
+            if err != nil {
+                return err
+            }
+        }
+
     default:
         return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
     }
 
+    if eventID != "" {
+        err := h.UserService.RecordClerkEvent(c, &user.ClerkEvent{
+            ID:             eventID,
+            Type:           event.Type,
+            ObjectID:       &objectID,
+            EventTimestamp: event.Timestamp,
+        })
+        if err != nil {
+            return err
+        }
+    }
+
+    return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleResendWebhook tracks delivery of the emails sent through Resend and
+// suppresses addresses that bounce or complain. Resend signs with Svix too.
+func (h *WebhookHandler) HandleResendWebhook(c echo.Context) error {
+    logger := middleware.GetLogger(c)
+
+    if h.server.Config.Email == nil || h.server.Config.Email.WebhookSecret == "" {
+        return echo.NewHTTPError(http.StatusNotFound, "resend webhooks are not configured")
+    }
+
+    body, err := io.ReadAll(c.Request().Body)
+    if err != nil {
+        logger.Error().Err(err).Msg("failed to read body")
+        return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+    }
+
+    if err := verifyWebhook(body, c.Request().Header, h.server.Config.Email.WebhookSecret); err != nil {
+        logger.Error().Err(err).Msg("resend webhook signature verification failed")
+        return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+    }
+
+    var event emailmodel.ResendEvent
+    if err := json.Unmarshal(body, &event); err != nil || event.Data.EmailID == "" {
+        return echo.NewHTTPError(http.StatusBadRequest, "invalid json")
+    }
+
+    // applying an event twice leaves the same state, so retries need no
+    // bookkeeping
+    handled, err := h.EmailService.HandleResendEvent(c.Request().Context(), &event)
+    if err != nil {
+        logger.Error().Err(err).Str("type", event.Type).Msg("failed to handle resend event")
+        return echo.NewHTTPError(http.StatusInternalServerError, "failed to handle event")
+    }
+    if !handled {
+        return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
+    }
+
     return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
 
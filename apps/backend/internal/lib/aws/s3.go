@@ -3,16 +3,20 @@ package aws
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gitSanje/khajaride/internal/server"
 )
 
+var ErrObjectNotFound = errors.New("object not found")
 
 type S3Client struct {
 	server *server.Server
@@ -124,4 +128,178 @@ func (s *S3Client) DeleteObject(ctx context.Context, bucket string, key string)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// PutObject stores data under an exact key, overwriting what is there.
+func (s *S3Client) PutObject(ctx context.Context, bucket string, key string, contentType string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3Client) GetObject(ctx context.Context, bucket string, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from S3: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from S3: %w", key, err)
+	}
+	return data, nil
+}
+
+// PutPublicObject stores data under an exact key, readable by anyone. Keys
+// whose content never changes can be cached for good.
+func (s *S3Client) PutPublicObject(ctx context.Context, bucket string, key string, contentType string, data []byte, immutable bool) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+		ACL:         "public-read",
+	}
+	if immutable {
+		input.CacheControl = aws.String("public, max-age=31536000, immutable")
+	}
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+// PublicURL is the address of a public object, as UploadPublicFile returns.
+func (s *S3Client) PublicURL(bucket string, key string) string {
+	return fmt.Sprintf("%s/%s/%s", s.server.Config.AWS.EndpointURL, bucket, key)
+}
+
+// EnsureBucket creates a bucket unless it exists.
+func (s *S3Client) EnsureBucket(ctx context.Context, bucket string) error {
+	if _, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err == nil {
+		return nil
+	}
+	if _, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// PresignedUpload lets a client upload a file straight to S3. A PUT sends
+// the file as the body with Headers; a POST sends Fields and then the file
+// as a multipart form.
+type PresignedUpload struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// ObjectInfo is what HeadObject tells about a stored object.
+type ObjectInfo struct {
+	ContentType string
+	Size        int64
+	ETag        string
+}
+
+// PresignPutObject signs a PUT of a file of exactly size bytes and the given
+// content type; S3 rejects any other.
+func (s *S3Client) PresignPutObject(ctx context.Context, bucket string, key string, contentType string, size int64, expires time.Duration) (*PresignedUpload, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	req, err := presignClient.PresignPutObject(ctx,
+		&s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			ContentType:   aws.String(contentType),
+			ContentLength: aws.Int64(size),
+		},
+		s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload of %s: %w", key, err)
+	}
+
+	// the client sets Host itself
+	headers := make(map[string]string, len(req.SignedHeader))
+	for name, values := range req.SignedHeader {
+		if !strings.EqualFold(name, "Host") && len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+	return &PresignedUpload{
+		Method:    http.MethodPut,
+		URL:       req.URL,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// PresignPostObject signs a browser form upload of a file of at most maxSize
+// bytes and the given content type.
+func (s *S3Client) PresignPostObject(ctx context.Context, bucket string, key string, contentType string, maxSize int64, expires time.Duration) (*PresignedUpload, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	req, err := presignClient.PresignPostObject(ctx,
+		&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		},
+		func(o *s3.PresignPostOptions) {
+			o.Expires = expires
+			o.Conditions = []interface{}{
+				[]interface{}{"content-length-range", 1, maxSize},
+				map[string]string{"Content-Type": contentType},
+			}
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload of %s: %w", key, err)
+	}
+
+	// the SDK leaves the URL empty with a custom endpoint; forms post to the
+	// bucket
+	url := req.URL
+	if url == "" {
+		url = fmt.Sprintf("%s/%s", s.server.Config.AWS.EndpointURL, bucket)
+	}
+
+	req.Values["Content-Type"] = contentType
+	return &PresignedUpload{
+		Method:    http.MethodPost,
+		URL:       url,
+		Fields:    req.Values,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// HeadObject reads the size and type of an object without downloading it.
+func (s *S3Client) HeadObject(ctx context.Context, bucket string, key string) (*ObjectInfo, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to read %s from S3: %w", key, err)
+	}
+
+	return &ObjectInfo{
+		ContentType: aws.ToString(out.ContentType),
+		Size:        aws.ToInt64(out.ContentLength),
+		ETag:        strings.Trim(aws.ToString(out.ETag), `"`),
+	}, nil
+}
@@ -0,0 +1,182 @@
+package aws_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/config"
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/server"
+	testingpkg "github.com/gitSanje/khajaride/internal/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupS3(t *testing.T) (*aws.S3Client, string) {
+	t.Helper()
+
+	s := &server.Server{Config: &config.Config{}}
+	awsClient := testingpkg.SetupTestS3(t, s)
+	return awsClient.S3, s.Config.AWS.UploadBucket
+}
+
+// putPresigned sends body to a presigned PUT with the signed headers,
+// overriding the content type with contentType.
+func putPresigned(t *testing.T, upload *aws.PresignedUpload, contentType string, body []byte) int {
+	t.Helper()
+
+	req, err := http.NewRequest(upload.Method, upload.URL, bytes.NewReader(body))
+	require.NoError(t, err)
+	for name, value := range upload.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+// postPresigned sends body as a form upload with the presigned fields,
+// overriding the content type with contentType.
+func postPresigned(t *testing.T, upload *aws.PresignedUpload, contentType string, body []byte) int {
+	t.Helper()
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	for name, value := range upload.Fields {
+		if name == "Content-Type" {
+			value = contentType
+		}
+		require.NoError(t, w.WriteField(name, value))
+	}
+	// S3 ignores fields after the file
+	part, err := w.CreateFormFile("file", "image")
+	require.NoError(t, err)
+	_, err = part.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	resp, err := http.Post(upload.URL, w.FormDataContentType(), &form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPresignPutObject(t *testing.T) {
+	s3Client, bucket := setupS3(t)
+	ctx := context.Background()
+	body := []byte("not really a png")
+
+	t.Run("accepts the declared file", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPutObject(ctx, bucket, key, "image/png", int64(len(body)), time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, upload.Method)
+
+		status := putPresigned(t, upload, "image/png", body)
+		require.Equal(t, http.StatusOK, status)
+
+		info, err := s3Client.HeadObject(ctx, bucket, key)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(body)), info.Size)
+		assert.Equal(t, "image/png", info.ContentType)
+		assert.NotEmpty(t, info.ETag)
+	})
+
+	t.Run("rejects a file of another size", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPutObject(ctx, bucket, key, "image/png", int64(len(body))-1, time.Minute)
+		require.NoError(t, err)
+
+		status := putPresigned(t, upload, "image/png", body)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		_, err = s3Client.HeadObject(ctx, bucket, key)
+		assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	})
+
+	t.Run("rejects a file of another type", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPutObject(ctx, bucket, key, "image/png", int64(len(body)), time.Minute)
+		require.NoError(t, err)
+
+		status := putPresigned(t, upload, "text/html", body)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		_, err = s3Client.HeadObject(ctx, bucket, key)
+		assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	})
+}
+
+func TestPresignPostObject(t *testing.T) {
+	s3Client, bucket := setupS3(t)
+	ctx := context.Background()
+	body := []byte("not really a jpeg")
+
+	t.Run("accepts a file within the limit", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPostObject(ctx, bucket, key, "image/jpeg", int64(len(body))+10, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, upload.Method)
+		assert.Equal(t, "image/jpeg", upload.Fields["Content-Type"])
+
+		status := postPresigned(t, upload, "image/jpeg", body)
+		require.Equal(t, http.StatusNoContent, status)
+
+		info, err := s3Client.HeadObject(ctx, bucket, key)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(body)), info.Size)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+	})
+
+	t.Run("rejects a file over the limit", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPostObject(ctx, bucket, key, "image/jpeg", int64(len(body))-1, time.Minute)
+		require.NoError(t, err)
+
+		status := postPresigned(t, upload, "image/jpeg", body)
+		assert.GreaterOrEqual(t, status, http.StatusBadRequest)
+
+		_, err = s3Client.HeadObject(ctx, bucket, key)
+		assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	})
+
+	t.Run("rejects a file of another type", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		upload, err := s3Client.PresignPostObject(ctx, bucket, key, "image/jpeg", int64(len(body)), time.Minute)
+		require.NoError(t, err)
+
+		status := postPresigned(t, upload, "text/html", body)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		_, err = s3Client.HeadObject(ctx, bucket, key)
+		assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	})
+}
+
+func TestHeadObject(t *testing.T) {
+	s3Client, bucket := setupS3(t)
+	ctx := context.Background()
+
+	t.Run("reads size and type", func(t *testing.T) {
+		key := "uploads/" + uuid.NewString()
+		require.NoError(t, s3Client.PutObject(ctx, bucket, key, "image/webp", []byte("webp")))
+
+		info, err := s3Client.HeadObject(ctx, bucket, key)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), info.Size)
+		assert.Equal(t, "image/webp", info.ContentType)
+	})
+
+	t.Run("reports a missing object", func(t *testing.T) {
+		_, err := s3Client.HeadObject(ctx, bucket, "uploads/"+uuid.NewString())
+		assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	})
+}
@@ -0,0 +1,175 @@
+package clerksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	clerkuser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/gitSanje/khajaride/internal/config"
+)
+
+// ErrUserNotFound is returned by GetUser when Clerk has no such user.
+var ErrUserNotFound = errors.New("clerk user not found")
+
+// Directory pages through the users Clerk knows about, oldest first, and
+// removes them once their account is deleted here.
+//
+// ListUsers returns users created after createdAfter and before
+// createdBefore (Unix milliseconds, 0 for no bound). Paging on created_at
+// instead of an offset means users created or deleted during a run do not
+// shift later pages.
+type Directory interface {
+	ListUsers(ctx context.Context, createdAfter, createdBefore int64, limit int) ([]*clerk.User, error)
+	GetUser(ctx context.Context, id string) (*clerk.User, error)
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// NewDirectory returns the Clerk API, or the local stand-in when
+// auth.clerk_users_file is set.
+func NewDirectory(cfg *config.AuthConfig) Directory {
+	if cfg.ClerkUsersFile != "" {
+		return &fileDirectory{path: cfg.ClerkUsersFile}
+	}
+	return &apiDirectory{
+		client: clerkuser.NewClient(&clerk.ClientConfig{
+			BackendConfig: clerk.BackendConfig{Key: &cfg.SecretKey},
+		}),
+	}
+}
+
+// ---------------- CLERK API ----------------
+
+type apiDirectory struct {
+	client *clerkuser.Client
+}
+
+func (d *apiDirectory) ListUsers(ctx context.Context, createdAfter, createdBefore int64, limit int) ([]*clerk.User, error) {
+	orderBy := "+created_at"
+	params := &clerkuser.ListParams{OrderBy: &orderBy}
+	if createdAfter > 0 {
+		params.CreatedAtAfter = clerk.Int64(createdAfter)
+	}
+	if createdBefore > 0 {
+		params.CreatedAtBefore = clerk.Int64(createdBefore)
+	}
+	params.Limit = clerk.Int64(int64(limit))
+
+	list, err := d.client.List(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clerk users: %w", err)
+	}
+	return list.Users, nil
+}
+
+func (d *apiDirectory) GetUser(ctx context.Context, id string) (*clerk.User, error) {
+	u, err := d.client.Get(ctx, id)
+	if err != nil {
+		var apiErr *clerk.APIErrorResponse
+		if errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get clerk user %s: %w", id, err)
+	}
+	return u, nil
+}
+
+func (d *apiDirectory) DeleteUser(ctx context.Context, id string) error {
+	if _, err := d.client.Delete(ctx, id); err != nil {
+		var apiErr *clerk.APIErrorResponse
+		if errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to delete clerk user %s: %w", id, err)
+	}
+	return nil
+}
+
+// ---------------- LOCAL STAND-IN ----------------
+
+// fileDirectory serves users from a JSON file in the shape of GET /v1/users
+// (an array of Clerk users, or {"data": [...]}), for local runs without a
+// Clerk instance.
+type fileDirectory struct {
+	path  string
+	once  sync.Once
+	mu    sync.Mutex
+	users []*clerk.User
+	err   error
+}
+
+func (d *fileDirectory) ListUsers(ctx context.Context, createdAfter, createdBefore int64, limit int) ([]*clerk.User, error) {
+	d.once.Do(d.load)
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var page []*clerk.User
+	for _, u := range d.users {
+		if u.CreatedAt <= createdAfter || (createdBefore > 0 && u.CreatedAt >= createdBefore) {
+			continue
+		}
+		page = append(page, u)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+func (d *fileDirectory) GetUser(ctx context.Context, id string) (*clerk.User, error) {
+	d.once.Do(d.load)
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, u := range d.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// DeleteUser only drops the user from memory; the file is left as it is.
+func (d *fileDirectory) DeleteUser(ctx context.Context, id string) error {
+	d.once.Do(d.load)
+	if d.err != nil {
+		return d.err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users = slices.DeleteFunc(d.users, func(u *clerk.User) bool { return u.ID == id })
+	return nil
+}
+
+func (d *fileDirectory) load() {
+	raw, err := os.ReadFile(d.path)
+	if err != nil {
+		d.err = fmt.Errorf("failed to read clerk users file: %w", err)
+		return
+	}
+
+	if err := json.Unmarshal(raw, &d.users); err != nil {
+		var list clerk.UserList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			d.err = fmt.Errorf("failed to parse clerk users file: %w", err)
+			return
+		}
+		d.users = list.Users
+	}
+
+	sort.SliceStable(d.users, func(i, j int) bool {
+		return d.users[i].CreatedAt < d.users[j].CreatedAt
+	})
+}
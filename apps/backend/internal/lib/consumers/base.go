@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
 	"github.com/gitSanje/khajaride/internal/config"
 	"github.com/gitSanje/khajaride/internal/database"
+	"github.com/gitSanje/khajaride/internal/lib/job"
 	"github.com/gitSanje/khajaride/internal/logger"
 	"github.com/gitSanje/khajaride/internal/repository"
 	"github.com/gitSanje/khajaride/internal/server"
@@ -48,12 +50,26 @@ func NewJobContext() (*JobContext, error) {
 		DB:       0,
 	})
 
+	// jobs that change what customers can find keep the search index in step
+	var esClient *elasticsearch.Client
+	if cfg.Elasticsearch != nil {
+		esClient, err = elasticsearch.NewClient(elasticsearch.Config{
+			Addresses: []string{cfg.Elasticsearch.Address},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Elasticsearch client: %w", err)
+		}
+	}
+
 	srv := &server.Server{
 		Config:        cfg,
 		Logger:        &loggerInstance,
 		LoggerService: loggerService,
 		DB:            db,
 		Redis:         redisClient,
+		Elasticsearch: esClient,
+		// only the client is used: emails are enqueued for the API's job server
+		Job: job.NewJobService(&loggerInstance, cfg),
 	}
 
 
@@ -75,6 +91,9 @@ func (c *JobContext) Close() {
 	if c.Server != nil && c.Server.Redis != nil {
 		c.Server.Redis.Close()
 	}
+	if c.Server != nil && c.Server.Job != nil {
+		c.Server.Job.Client.Close()
+	}
 	if c.LoggerService != nil {
 		c.LoggerService.Shutdown()
 	}
@@ -2,62 +2,852 @@ package consumers
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gitSanje/khajaride/internal/lib/events"
-	"github.com/segmentio/kafka-go"
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/lib/clerksync"
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/lib/utils"
+	"github.com/gitSanje/khajaride/internal/model/recommendation"
+	"github.com/gitSanje/khajaride/internal/model/referral"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/hibiken/asynq"
 )
 
+// VendorSettlementJob batches vendor earnings and pays them out. It ticks
+// hourly: daily accounts are settled once a day has closed, weekly accounts
+// once a week (Monday to Sunday) has closed, and failed payouts are retried
+// on every tick once their backoff has passed.
+type VendorSettlementJob struct {
+	Interval time.Duration
+}
+
+func NewVendorSettlementJob(interval time.Duration) *VendorSettlementJob {
+	return &VendorSettlementJob{
+		Interval: interval,
+	}
+}
 
+func (j *VendorSettlementJob) Name() string {
+	return "vendor_settlement_worker"
+}
 
-type KafkaPayoutJob struct {
-	Reader *kafka.Reader
+func (j *VendorSettlementJob) Description() string {
+	return "Batches vendor earnings into daily/weekly settlements and pays them out"
 }
 
+func (j *VendorSettlementJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	logger := jobCtx.Server.Logger
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, frequency := range []string{"daily", "weekly"} {
+			res, err := jobCtx.Repositories.Settlement.RunSettlementCycle(ctx, frequency, time.Now())
+			if err != nil {
+				logger.Error().Err(err).Str("frequency", frequency).Msg("settlement cycle failed")
+				continue
+			}
+			logger.Info().
+				Str("frequency", frequency).
+				Int("created", res.Created).
+				Int("paid", res.Paid).
+				Int("failed", res.Failed).
+				Msg("settlement cycle completed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
 
-func NewKafkaPayoutJob(brokers []string, topic, groupID string) *KafkaPayoutJob {
-	return &KafkaPayoutJob{
-		Reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers: brokers,
-			Topic:   topic,
-			GroupID: groupID,
-		}),
+// nextDailyRun is the next time of day at, an offset from local midnight,
+// after now. The nightly jobs (LoyaltyJob, RecommendationJob,
+// VendorComplianceJob) run once at start-up and then at their RunAt.
+func nextDailyRun(now time.Time, at time.Duration) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
 	}
+	return next
 }
 
+// LoyaltyJob is the nightly loyalty maintenance run: it writes off expired
+// points (FIFO lots), awards delivered orders and referral rewards that were
+// missed when their status changed, and moves customers between tiers as
+// their rolling spend changes.
+type LoyaltyJob struct {
+	RunAt     time.Duration // offset from local midnight
+	BatchSize int
+}
+
+func NewLoyaltyJob(runAt time.Duration) *LoyaltyJob {
+	return &LoyaltyJob{
+		RunAt:     runAt,
+		BatchSize: 500,
+	}
+}
 
-func (k *KafkaPayoutJob) Name() string {
-	return "kafka_payout_worker"
+func (j *LoyaltyJob) Name() string {
+	return "loyalty_worker"
 }
 
-func (k *KafkaPayoutJob) Description() string {
-	return "Consumes payout_requested events from Kafka and executes payouts"
+func (j *LoyaltyJob) Description() string {
+	return "Expires loyalty points, awards missed delivered orders and referrals and refreshes customer tiers nightly"
 }
 
-func (k *KafkaPayoutJob) Run(ctx context.Context, jobCtx *JobContext) error {
-	fmt.Println("✅ Payout Worker Running...")
+func (j *LoyaltyJob) Run(ctx context.Context, jobCtx *JobContext) error {
 	for {
-		msg, err := k.Reader.ReadMessage(context.Background())
+		j.runOnce(ctx, jobCtx)
+
+		timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), j.RunAt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (j *LoyaltyJob) runOnce(ctx context.Context, jobCtx *JobContext) {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.Loyalty
+
+	expired, err := repo.ExpirePoints(ctx, time.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("loyalty expiry failed")
+	} else {
+		logger.Info().
+			Int("users", expired.Users).
+			Float64("points", expired.Points).
+			Msg("loyalty points expired")
+	}
+
+	orderIDs, err := repo.ListUnawardedOrders(ctx, j.BatchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list unawarded orders")
+	}
+	awarded := 0
+	for _, id := range orderIDs {
+		entry, err := repo.AwardOrderPoints(ctx, id)
 		if err != nil {
-			fmt.Println("Error reading message:", err)
+			logger.Error().Err(err).Str("order_id", id).Msg("failed to award loyalty points")
 			continue
 		}
+		if entry != nil {
+			awarded++
+		}
+	}
+	logger.Info().Int("orders", awarded).Msg("loyalty points awarded")
 
-		var e events.PayoutRequestedEvent
-		if err := json.Unmarshal(msg.Value, &e); err != nil {
-			fmt.Println("Invalid message format:", err)
+	pending, err := jobCtx.Repositories.Referral.ListPendingRewards(ctx, j.BatchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list pending referral rewards")
+	}
+	rewarded := 0
+	for _, p := range pending {
+		ref, err := jobCtx.Repositories.Referral.RewardFirstDelivery(ctx, p.RefereeID, p.OrderID)
+		if err != nil {
+			logger.Error().Err(err).Str("referee_id", p.RefereeID).Msg("failed to issue referral reward")
 			continue
 		}
+		if ref != nil && ref.Status == referral.StatusRewarded {
+			rewarded++
+		}
+	}
+	logger.Info().Int("referrals", rewarded).Msg("referral rewards issued")
+
+	changed, err := repo.RefreshTiers(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("loyalty tier refresh failed")
+		return
+	}
+	logger.Info().Int("changed", changed).Msg("loyalty tiers refreshed")
+}
+
+// ClerkReconcileJob repairs drift between Clerk and the users table: it
+// pages through every Clerk user created before the run started, applies
+// their state with the same ordered upsert as the webhooks, and deactivates
+// local users Clerk no longer has. It runs once and exits.
+type ClerkReconcileJob struct {
+	PageSize int
+}
+
+func NewClerkReconcileJob(pageSize int) *ClerkReconcileJob {
+	return &ClerkReconcileJob{
+		PageSize: pageSize,
+	}
+}
+
+func (j *ClerkReconcileJob) Name() string {
+	return "clerk_reconcile"
+}
+
+func (j *ClerkReconcileJob) Description() string {
+	return "Reconciles users with Clerk (missed webhooks, deleted users)"
+}
+
+func (j *ClerkReconcileJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.User
+	directory := clerksync.NewDirectory(&jobCtx.Config.Auth)
+
+	var res user.ClerkReconcileResult
+	seen := make(map[string]bool)
+
+	// Users signing up or leaving during the run do not move the pages: the
+	// listing is cut off at the start and paged on created_at
+	started := time.Now()
+	var after int64
+	for {
+		page, err := directory.ListUsers(ctx, after, started.UnixMilli(), j.PageSize)
+		if err != nil {
+			// nothing is deleted from a partial listing
+			return err
+		}
+
+		fresh := 0
+		for _, cu := range page {
+			if seen[cu.ID] {
+				continue
+			}
+			seen[cu.ID] = true
+			fresh++
+			res.Seen++
+
+			synced, inserted, err := repo.UpsertClerkUser(ctx, utils.MapClerkUser(cu))
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", cu.ID).Msg("failed to reconcile clerk user")
+				continue
+			}
+			switch {
+			case synced == nil:
+				res.Unchanged++
+			case inserted:
+				res.Created++
+			default:
+				res.Updated++
+			}
+		}
+
+		if len(page) < j.PageSize {
+			break
+		}
+		// The next page starts a millisecond before the last user so users
+		// created in the same millisecond are not skipped; a page with
+		// nothing new moves past that millisecond
+		after = page[len(page)-1].CreatedAt - 1
+		if fresh == 0 {
+			after++
+		}
+	}
+
+	// An empty listing is more likely a wrong key or file than an empty
+	// Clerk instance
+	if res.Seen == 0 {
+		logger.Warn().Msg("clerk returned no users, skipping deletions")
+	} else {
+		// Only users that existed before the listing started can be missing
+		// from it, and each one is checked with Clerk before it goes
+		ids, err := repo.ListActiveClerkUserIDs(ctx, started)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UnixMilli()
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			if _, err := directory.GetUser(ctx, id); !errors.Is(err, clerksync.ErrUserNotFound) {
+				if err != nil {
+					logger.Error().Err(err).Str("user_id", id).Msg("failed to check user missing from clerk listing")
+				}
+				continue
+			}
+			deleted, err := repo.DeleteClerkUser(ctx, id, now)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", id).Msg("failed to deactivate user missing from clerk")
+				continue
+			}
+			if deleted {
+				res.Deleted++
+			}
+		}
+	}
+
+	logger.Info().
+		Int("seen", res.Seen).
+		Int("created", res.Created).
+		Int("updated", res.Updated).
+		Int("unchanged", res.Unchanged).
+		Int("deleted", res.Deleted).
+		Msg("clerk reconciliation completed")
+	return nil
+}
+
+// AccountDeletionJob anonymizes accounts whose deletion grace period is over
+// and removes them from Clerk so they can no longer sign in. Accounts with
+// an order in flight are pushed back by the repository. It ticks hourly.
+type AccountDeletionJob struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewAccountDeletionJob(interval time.Duration) *AccountDeletionJob {
+	return &AccountDeletionJob{
+		Interval:  interval,
+		BatchSize: 100,
+	}
+}
+
+func (j *AccountDeletionJob) Name() string {
+	return "account_deletion_worker"
+}
+
+func (j *AccountDeletionJob) Description() string {
+	return "Anonymizes deleted accounts once their grace period is over"
+}
+
+func (j *AccountDeletionJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.User
+	directory := clerksync.NewDirectory(&jobCtx.Config.Auth)
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := repo.ListDueDeletions(ctx, j.BatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to list due account deletions")
+		}
+
+		anonymized := 0
+		for _, id := range ids {
+			done, err := repo.AnonymizeUser(ctx, id)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", id).Msg("failed to anonymize user")
+				continue
+			}
+			if !done {
+				logger.Info().Str("user_id", id).Msg("account deletion postponed")
+				continue
+			}
+			anonymized++
+
+			// the local row stays deactivated even if this fails
+			if strings.HasPrefix(id, "user_") {
+				if err := directory.DeleteUser(ctx, id); err != nil {
+					logger.Error().Err(err).Str("user_id", id).Msg("failed to delete clerk user")
+				}
+			}
+		}
+		logger.Info().Int("accounts", anonymized).Msg("accounts anonymized")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecommendationJob precomputes recommendations nightly: it rebuilds the
+// order aggregates of menu_item_stats from delivered orders, then computes
+// every eligible user's lists and stores them in Redis for
+// GET /users/me/recommendations.
+type RecommendationJob struct {
+	RunAt     time.Duration // offset from local midnight
+	BatchSize int
+}
+
+func NewRecommendationJob(runAt time.Duration) *RecommendationJob {
+	return &RecommendationJob{
+		RunAt:     runAt,
+		BatchSize: 200,
+	}
+}
+
+func (j *RecommendationJob) Name() string {
+	return "recommendation_worker"
+}
+
+func (j *RecommendationJob) Description() string {
+	return "Refreshes menu item stats and precomputes user recommendations into Redis nightly"
+}
+
+func (j *RecommendationJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	for {
+		j.runOnce(ctx, jobCtx)
+
+		timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), j.RunAt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (j *RecommendationJob) runOnce(ctx context.Context, jobCtx *JobContext) {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.Recommendation
+
+	var res recommendation.RefreshResult
+	items, err := repo.RefreshMenuItemStats(ctx)
+	if err != nil {
+		// lists still build from yesterday's stats
+		logger.Error().Err(err).Msg("menu item stats refresh failed")
+	}
+	res.Items = items
 
-		fmt.Println("🎯 Processing payout:", e)
+	afterID := ""
+	for {
+		ids, err := repo.ListRecommendationUserIDs(ctx, afterID, j.BatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to list recommendation users")
+			break
+		}
+		for _, id := range ids {
+			recs, err := repo.BuildRecommendations(ctx, id)
+			if err == nil {
+				err = repo.CacheRecommendations(ctx, id, recs)
+			}
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", id).Msg("failed to precompute recommendations")
+				res.Failed++
+				continue
+			}
+			res.Users++
+		}
+		if len(ids) < j.BatchSize {
+			break
+		}
+		afterID = ids[len(ids)-1]
+	}
 
-		if _, err := jobCtx.Repositories.Payment.PerformStripePayout(context.Background(), &e); err != nil {
-			fmt.Println("❌ Payout failed:", err)
+	logger.Info().
+		Int64("items", res.Items).
+		Int("users", res.Users).
+		Int("failed", res.Failed).
+		Msg("recommendations precomputed")
+}
+
+// VendorComplianceJob watches document expiry dates daily: it reminds
+// vendors 30, 7 and 1 days before a document expires and suspends trading
+// vendors whose compliance documents have lapsed, taking them out of
+// search. They are reinstated when an admin approves the renewals.
+type VendorComplianceJob struct {
+	RunAt time.Duration // offset from local midnight
+}
+
+func NewVendorComplianceJob(runAt time.Duration) *VendorComplianceJob {
+	return &VendorComplianceJob{
+		RunAt: runAt,
+	}
+}
+
+func (j *VendorComplianceJob) Name() string {
+	return "vendor_compliance_worker"
+}
+
+func (j *VendorComplianceJob) Description() string {
+	return "Warns vendors of expiring documents and suspends vendors whose required documents have lapsed"
+}
+
+func (j *VendorComplianceJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	for {
+		j.runOnce(ctx, jobCtx)
+
+		timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), j.RunAt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (j *VendorComplianceJob) runOnce(ctx context.Context, jobCtx *JobContext) {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.VendorVerification
+
+	var res vendor.ComplianceResult
+
+	// 1️⃣ Expiry reminders; each is recorded only once it is queued, so a
+	// failed enqueue is retried on the next run. The task ID keeps a rerun
+	// that failed to record it from sending it twice.
+	docs, err := repo.ListDueExpiryWarnings(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list document expiry warnings")
+	}
+	for _, d := range docs {
+		if d.OwnerEmail != nil {
+			if err := j.enqueueExpiryWarning(jobCtx, d); err != nil {
+				logger.Error().Err(err).Str("document_id", d.DocumentID).Msg("failed to enqueue document expiry warning")
+				res.Failed++
+				continue
+			}
+			res.Warned++
+		}
+		if err := repo.RecordExpiryWarning(ctx, d); err != nil {
+			logger.Error().Err(err).Str("document_id", d.DocumentID).Msg("failed to record document expiry warning")
+		}
+	}
+
+	// 2️⃣ Suspend vendors with lapsed documents
+	lapsed, err := repo.ListLapsedVendors(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list vendors with lapsed documents")
+	}
+	for _, lv := range lapsed {
+		names := make([]string, len(lv.LapsedDocuments))
+		for i, t := range lv.LapsedDocuments {
+			names[i] = vendor.DocumentName(t)
+		}
+		reason := "your " + joinNames(names) + " expired"
+		remarks := "Lapsed documents: " + strings.Join(names, ", ")
+
+		updated, err := repo.Transition(ctx, &vendor.Transition{
+			VendorID:         lv.VendorID,
+			Action:           vendor.ActionSuspended,
+			Remarks:          &remarks,
+			SuspensionReason: vendor.SuspensionCompliance,
+		})
+		if errors.Is(err, repository.ErrInvalidVendorTransition) {
+			// renewed or suspended since it was listed
+			continue
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("vendor_id", lv.VendorID).Msg("failed to suspend vendor with lapsed documents")
+			res.Failed++
 			continue
 		}
+		res.Suspended++
+
+		if err := jobCtx.Repositories.Search.UpdateVendorStatus(ctx, updated.ID, updated.Status); err != nil {
+			logger.Error().Err(err).Str("vendor_id", updated.ID).Msg("failed to index vendor status")
+		}
+		if err := j.enqueueSuspensionEmail(ctx, jobCtx, updated, reason); err != nil {
+			logger.Error().Err(err).Str("vendor_id", updated.ID).Msg("failed to enqueue vendor suspension email")
+		}
+	}
+
+	logger.Info().
+		Int("warned", res.Warned).
+		Int("suspended", res.Suspended).
+		Int("failed", res.Failed).
+		Msg("vendor compliance checked")
+}
+
+func (j *VendorComplianceJob) enqueueExpiryWarning(jobCtx *JobContext, d vendor.ExpiringDocument) error {
+	data := map[string]string{
+		"VendorName":   d.VendorName,
+		"DocumentName": vendor.DocumentName(d.DocumentType),
+		"ExpiryDate":   d.ExpiryDate.Format("2 Jan 2006"),
+		"DaysLeft":     strconv.Itoa(d.DaysLeft),
+	}
+	if vendor.IsComplianceDocument(d.DocumentType) {
+		data["Suspends"] = "true"
+	}
+
+	task, err := job.NewVendorDocumentExpiryEmailTask(job.VendorDocumentExpiryEmailPayload{
+		DocumentID: d.DocumentID,
+		ExpiryDate: d.ExpiryDate.Format("2006-01-02"),
+		Warning:    d.DaysBefore,
+		To:         *d.OwnerEmail,
+		Data:       data,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := jobCtx.Server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+	return nil
+}
+
+func (j *VendorComplianceJob) enqueueSuspensionEmail(ctx context.Context, jobCtx *JobContext, v *vendor.Vendor, reason string) error {
+	to, err := jobCtx.Repositories.VendorVerification.GetOwnerEmail(ctx, v.ID)
+	if err != nil || to == "" {
+		return err
+	}
+
+	task, err := job.NewVendorVerificationResultEmailTask(job.VendorVerificationResultEmailPayload{
+		To:         to,
+		VendorName: v.Name,
+		Outcome:    email.VerificationSuspended,
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = jobCtx.Server.Job.Client.Enqueue(task)
+	return err
+}
+
+// joinNames lists names for a sentence: "a", "a and b", "a, b and c".
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// VendorAnalyticsJob keeps the vendor analytics rollups current: every tick
+// it recomputes the sales hours and earning days touched by orders and
+// earnings changed since the last refresh. The first run builds the rollups
+// from all history.
+type VendorAnalyticsJob struct {
+	Interval time.Duration
+}
+
+func NewVendorAnalyticsJob(interval time.Duration) *VendorAnalyticsJob {
+	return &VendorAnalyticsJob{Interval: interval}
+}
+
+func (j *VendorAnalyticsJob) Name() string {
+	return "vendor_analytics_worker"
+}
+
+func (j *VendorAnalyticsJob) Description() string {
+	return "Refreshes the vendor sales analytics rollups"
+}
+
+func (j *VendorAnalyticsJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	logger := jobCtx.Server.Logger
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := jobCtx.Repositories.Analytics.Refresh(ctx, jobCtx.Config.Primary.Timezone)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to refresh vendor analytics")
+		} else {
+			logger.Info().
+				Int64("sales_buckets", res.SalesBuckets).
+				Int64("earning_days", res.EarningDays).
+				Msg("vendor analytics refreshed")
+		}
 
-		fmt.Println("✅ Payout completed for order:", e.OrderID)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
+}
+
+// MenuStockJob keeps tracked menu stock current: every tick it refills the
+// items whose daily reset time has passed and gives back the stock of orders
+// left unpaid for longer than vendor.ReservationHold. Items that come back
+// into stock are made available in search again.
+type MenuStockJob struct {
+	Interval time.Duration
+}
+
+func NewMenuStockJob(interval time.Duration) *MenuStockJob {
+	return &MenuStockJob{Interval: interval}
+}
 
-}
\ No newline at end of file
+func (j *MenuStockJob) Name() string {
+	return "menu_stock_worker"
+}
+
+func (j *MenuStockJob) Description() string {
+	return "Resets daily menu stock and releases stock held by abandoned orders"
+}
+
+func (j *MenuStockJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx, jobCtx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *MenuStockJob) runOnce(ctx context.Context, jobCtx *JobContext) {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.Stock
+
+	var res vendor.StockResetResult
+
+	// 1️⃣ Daily refills
+	reset, err := repo.ResetDailyStock(ctx, jobCtx.Config.Primary.Timezone)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to reset daily stock")
+	}
+	res.Reset = len(reset)
+
+	// 2️⃣ Stock held by checkouts that were never paid
+	released, orders, err := repo.ReleaseAbandonedReservations(ctx, vendor.ReservationHold)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to release abandoned stock reservations")
+	}
+	res.Released = orders
+
+	for _, c := range append(reset, released...) {
+		if !c.SoldOut() && !c.Restocked() {
+			continue
+		}
+		state, err := repo.GetMenuState(ctx, c.VendorID, c.MenuItemID)
+		if err == nil {
+			err = jobCtx.Repositories.Search.UpdateOutletMenuItem(ctx, state.VendorID, state.MenuItemID, state.BasePrice, state.IsAvailable)
+		}
+		if err != nil {
+			logger.Error().Err(err).
+				Str("vendor_id", c.VendorID).
+				Str("menu_item_id", c.MenuItemID).
+				Msg("failed to index menu item availability")
+			res.Failed++
+		}
+	}
+
+	logger.Info().
+		Int("reset", res.Reset).
+		Int("released_orders", res.Released).
+		Int("failed", res.Failed).
+		Msg("menu stock refreshed")
+}
+
+// ImageCleanupJob deletes uploaded images nothing points at any more, with
+// their objects in S3, once every upload of them is older than
+// vendor.ImageUploadGrace. It also deletes direct uploads that were never
+// completed, with their staging objects.
+type ImageCleanupJob struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewImageCleanupJob(interval time.Duration, batchSize int) *ImageCleanupJob {
+	return &ImageCleanupJob{Interval: interval, BatchSize: batchSize}
+}
+
+func (j *ImageCleanupJob) Name() string {
+	return "image_cleanup_worker"
+}
+
+func (j *ImageCleanupJob) Description() string {
+	return "Deletes uploaded images that are no longer used"
+}
+
+func (j *ImageCleanupJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	awsClient, err := aws.NewAWS(jobCtx.Server)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx, jobCtx, awsClient)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *ImageCleanupJob) runOnce(ctx context.Context, jobCtx *JobContext, awsClient *aws.AWS) {
+	logger := jobCtx.Server.Logger
+	repo := jobCtx.Repositories.Image
+	bucket := jobCtx.Config.AWS.UploadBucket
+
+	images, err := repo.GetUnusedImages(ctx, vendor.ImageUploadGrace, j.BatchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list unused images")
+		return
+	}
+
+	var res vendor.ImageCleanupResult
+	for _, img := range images {
+		if err := j.deleteImage(ctx, jobCtx, awsClient, bucket, img); err != nil {
+			logger.Error().Err(err).Str("hash", img.Hash).Msg("failed to delete unused image")
+			res.Failed++
+			continue
+		}
+		res.Deleted++
+	}
+
+	// direct uploads never completed
+	uploads, err := repo.GetExpiredUploads(ctx, vendor.ImageUploadCompleteWindow, j.BatchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list expired image uploads")
+	}
+	for _, upload := range uploads {
+		if err := j.deleteUpload(ctx, jobCtx, awsClient, bucket, upload); err != nil {
+			logger.Error().Err(err).Str("upload_id", upload.ID).Msg("failed to delete expired image upload")
+			res.Failed++
+			continue
+		}
+		res.ExpiredUploads++
+	}
+
+	logger.Info().
+		Int("deleted", res.Deleted).
+		Int("expired_uploads", res.ExpiredUploads).
+		Int("failed", res.Failed).
+		Msg("unused images cleaned up")
+}
+
+// deleteImage deletes the objects while holding the image's lock, so an
+// upload of the same file waits and stores them again afterwards.
+func (j *ImageCleanupJob) deleteImage(ctx context.Context, jobCtx *JobContext, awsClient *aws.AWS, bucket string, img vendor.UnusedImage) error {
+	tx, err := jobCtx.Server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	deleted, err := jobCtx.Repositories.Image.DeleteUnusedImage(ctx, tx, img.Hash, vendor.ImageUploadGrace)
+	if err != nil || !deleted {
+		return err
+	}
+	for _, key := range img.Keys {
+		if err := awsClient.S3.DeleteObject(ctx, bucket, key); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteUpload deletes the staging object of an expired direct upload; a
+// completion running meanwhile finds the upload gone.
+func (j *ImageCleanupJob) deleteUpload(ctx context.Context, jobCtx *JobContext, awsClient *aws.AWS, bucket string, upload vendor.ExpiredImageUpload) error {
+	tx, err := jobCtx.Server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	deleted, err := jobCtx.Repositories.Image.DeleteExpiredUpload(ctx, tx, upload.ID, vendor.ImageUploadCompleteWindow)
+	if err != nil || !deleted {
+		return err
+	}
+	if err := awsClient.S3.DeleteObject(ctx, bucket, upload.ObjectKey); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -1,6 +1,9 @@
 package consumers
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type JobRegistry struct {
 	jobs map[string]Job
@@ -10,8 +13,24 @@ func NewJobRegistry(brokers []string) *JobRegistry {
 	registry := &JobRegistry{
 		jobs: make(map[string]Job),
 	}
-	// Register Kafka payout job
-	registry.Register(NewKafkaPayoutJob(brokers, "payout_requested", "payout_workers_group"))
+	// Register vendor settlement job
+	registry.Register(NewVendorSettlementJob(time.Hour))
+	// Register nightly vendor document compliance check (01:00 local time)
+	registry.Register(NewVendorComplianceJob(1 * time.Hour))
+	// Register vendor analytics rollup refresh
+	registry.Register(NewVendorAnalyticsJob(15 * time.Minute))
+	// Register menu stock daily resets and abandoned reservation release
+	registry.Register(NewMenuStockJob(5 * time.Minute))
+	// Register nightly loyalty job (02:00 local time)
+	registry.Register(NewLoyaltyJob(2 * time.Hour))
+	// Register nightly recommendation precompute (03:00 local time, after loyalty)
+	registry.Register(NewRecommendationJob(3 * time.Hour))
+	// Register cleanup of uploaded images no longer used
+	registry.Register(NewImageCleanupJob(6*time.Hour, 500))
+	// Register account deletion job (anonymizes accounts past the grace period)
+	registry.Register(NewAccountDeletionJob(time.Hour))
+	// Register one-off Clerk reconciliation (run on demand)
+	registry.Register(NewClerkReconcileJob(100))
 
 	return registry
 }
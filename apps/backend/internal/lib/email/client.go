@@ -2,51 +2,198 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
+	"os"
+	texttemplate "text/template"
 
 	"github.com/gitSanje/khajaride/internal/config"
 	"github.com/pkg/errors"
-	"github.com/resend/resend-go/v2"
 	"github.com/rs/zerolog"
 )
 
+const templateDir = "templates/emails"
+
+// defaultSender is Resend's test sender, used until a domain is configured
+var defaultSender = fmt.Sprintf("%s <%s>", "Khajaride", "onboarding@resend.dev")
+
+// Email log statuses; the delivery ones come from provider webhooks.
+const (
+	StatusSent            = "sent"
+	StatusFailed          = "failed"
+	StatusSuppressed      = "suppressed"
+	StatusDelivered       = "delivered"
+	StatusDeliveryDelayed = "delivery_delayed"
+	StatusBounced         = "bounced"
+	StatusComplained      = "complained"
+)
+
+// LogEntry is one outbound email in the email log.
+type LogEntry struct {
+	Provider          string
+	ProviderMessageID string
+	Template          string
+	To                string
+	Subject           string
+	Status            string
+	Error             string
+}
+
+// Recorder keeps the email log and the suppression list. The job server
+// starts before the repositories exist, so it is set afterwards; until then
+// emails are sent unrecorded.
+type Recorder interface {
+	IsSuppressed(ctx context.Context, address string) (bool, error)
+	RecordEmail(ctx context.Context, entry *LogEntry) error
+}
+
 type Client struct {
-	client *resend.Client
-	logger *zerolog.Logger
+	mailer   Mailer
+	logger   *zerolog.Logger
+	sender   string
+	replyTo  string
+	recorder Recorder
 }
 
 func NewClient(cfg *config.Config, logger *zerolog.Logger) *Client {
-	return &Client{
-		client: resend.NewClient(cfg.Integration.ResendAPIKey),
+	c := &Client{
+		mailer: NewMailer(cfg, logger),
 		logger: logger,
+		sender: defaultSender,
+	}
+	if cfg.Email != nil {
+		if cfg.Email.FromAddress != "" {
+			name := cfg.Email.FromName
+			if name == "" {
+				name = "Khajaride"
+			}
+			c.sender = fmt.Sprintf("%s <%s>", name, cfg.Email.FromAddress)
+		}
+		c.replyTo = cfg.Email.ReplyTo
 	}
+	return c
 }
 
-func (c *Client) SendEmail(to, subject string, templateName Template, data map[string]string) error {
-	tmplPath := fmt.Sprintf("%s/%s.html", "templates/emails", templateName)
+func (c *Client) SetRecorder(r Recorder) {
+	c.recorder = r
+}
 
-	tmpl, err := template.ParseFiles(tmplPath)
+// Attachment is a file sent along with an email, e.g. an invoice PDF.
+type Attachment struct {
+	Filename string `json:"filename"`
+	Content  []byte `json:"content"`
+}
+
+func (c *Client) SendEmail(ctx context.Context, to, subject string, templateName Template, data map[string]string) error {
+	return c.SendEmailWithAttachments(ctx, to, subject, templateName, data, nil)
+}
+
+func (c *Client) SendEmailWithAttachments(ctx context.Context, to, subject string, templateName Template, data map[string]string, attachments []Attachment) error {
+	html, text, err := Render(templateName, data)
 	if err != nil {
-		return errors.Wrapf(err, "failed to parse email template %s", templateName)
+		return err
 	}
 
-	var body bytes.Buffer
-	if err := tmpl.Execute(&body, data); err != nil {
-		return errors.Wrapf(err, "failed to execute email template %s", templateName)
-	}
+	return c.deliver(ctx, string(templateName), &Message{
+		From:        c.sender,
+		To:          to,
+		ReplyTo:     c.replyTo,
+		Subject:     subject,
+		HTML:        html,
+		Text:        text,
+		Attachments: attachments,
+	})
+}
 
-	params := &resend.SendEmailRequest{
-		From:    fmt.Sprintf("%s <%s>", "Boilerplate", "onboarding@resend.dev"),
-		To:      []string{to},
+// SendHTMLEmail sends a body that was rendered elsewhere, e.g. an email Clerk
+// hands over to us instead of delivering it itself.
+func (c *Client) SendHTMLEmail(ctx context.Context, to, subject, html string) error {
+	return c.deliver(ctx, "", &Message{
+		From:    c.sender,
+		To:      to,
+		ReplyTo: c.replyTo,
 		Subject: subject,
-		Html:    body.String(),
+		HTML:    html,
+	})
+}
+
+// deliver skips suppressed addresses, sends and logs the outcome. A
+// suppressed address is not an error: retrying would not change it.
+func (c *Client) deliver(ctx context.Context, templateName string, msg *Message) error {
+	entry := &LogEntry{
+		Provider: c.mailer.Name(),
+		Template: templateName,
+		To:       msg.To,
+		Subject:  msg.Subject,
 	}
 
-	_, err = c.client.Emails.Send(params)
+	if c.recorder != nil {
+		suppressed, err := c.recorder.IsSuppressed(ctx, msg.To)
+		if err != nil {
+			return fmt.Errorf("failed to check email suppression: %w", err)
+		}
+		if suppressed {
+			entry.Status = StatusSuppressed
+			c.record(ctx, entry)
+			c.logger.Warn().Str("to", msg.To).Str("template", templateName).Msg("Email to suppressed address skipped")
+			return nil
+		}
+	}
+
+	id, err := c.mailer.Send(ctx, msg)
 	if err != nil {
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+		c.record(ctx, entry)
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
+	entry.Status = StatusSent
+	entry.ProviderMessageID = id
+	c.record(ctx, entry)
 	return nil
 }
+
+// record logs an email; the log is bookkeeping and never fails a send.
+func (c *Client) record(ctx context.Context, entry *LogEntry) {
+	if c.recorder == nil {
+		return
+	}
+	if err := c.recorder.RecordEmail(ctx, entry); err != nil {
+		c.logger.Error().Err(err).Str("to", entry.To).Msg("failed to record email")
+	}
+}
+
+// Render executes a template's HTML and, when the template has a .txt
+// alongside it, its plain-text alternative. text is empty otherwise.
+func Render(templateName Template, data map[string]string) (html string, text string, err error) {
+	tmpl, err := template.ParseFiles(fmt.Sprintf("%s/%s.html", templateDir, templateName))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to parse email template %s", templateName)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", "", errors.Wrapf(err, "failed to execute email template %s", templateName)
+	}
+
+	textPath := fmt.Sprintf("%s/%s.txt", templateDir, templateName)
+	if _, err := os.Stat(textPath); err != nil {
+		if os.IsNotExist(err) {
+			return body.String(), "", nil
+		}
+		return "", "", errors.Wrapf(err, "failed to read text template %s", templateName)
+	}
+
+	textTmpl, err := texttemplate.ParseFiles(textPath)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to parse text template %s", templateName)
+	}
+	var plain bytes.Buffer
+	if err := textTmpl.Execute(&plain, data); err != nil {
+		return "", "", errors.Wrapf(err, "failed to execute text template %s", templateName)
+	}
+
+	return body.String(), plain.String(), nil
+}
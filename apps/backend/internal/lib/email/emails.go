@@ -1,14 +1,171 @@
 package email
 
-func (c *Client) SendWelcomeEmail(to, firstName string) error {
+import "context"
+
+func (c *Client) SendWelcomeEmail(ctx context.Context, to, firstName string) error {
 	data := map[string]string{
 		"UserFirstName": firstName,
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		"Welcome to Boilerplate!",
 		TemplateWelcome,
 		data,
 	)
 }
+
+func (c *Client) SendOrderConfirmationEmail(ctx context.Context, to, customerName, orderRef, total string, invoices []Attachment) error {
+	data := map[string]string{
+		"CustomerName": customerName,
+		"OrderRef":     orderRef,
+		"Total":        total,
+	}
+
+	return c.SendEmailWithAttachments(
+		ctx,
+		to,
+		"Your Khajaride order "+orderRef+" is confirmed",
+		TemplateOrderConfirmation,
+		data,
+		invoices,
+	)
+}
+
+// SendNotificationEmail sends a rendered notification (see
+// internal/lib/notification) in the generic layout.
+func (c *Client) SendNotificationEmail(ctx context.Context, to, title, body string) error {
+	data := map[string]string{
+		"Title": title,
+		"Body":  body,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		title,
+		TemplateNotification,
+		data,
+	)
+}
+
+// SendPaymentReceiptEmail sends the receipt of a delivered order. data holds
+// the keys of payment_receipt (CustomerName, VendorName, OrderRef, amounts,
+// PaymentMethod, TransactionRef, DeliveredAt).
+func (c *Client) SendPaymentReceiptEmail(ctx context.Context, to string, data map[string]string) error {
+	return c.SendEmail(
+		ctx,
+		to,
+		"Your receipt for Khajaride order "+data["OrderRef"],
+		TemplatePaymentReceipt,
+		data,
+	)
+}
+
+// SendOrderCancelledEmail tells the customer a vendor cancelled the order and
+// whether it was refunded (RefundAmount) or is still to be (Paid).
+func (c *Client) SendOrderCancelledEmail(ctx context.Context, to string, data map[string]string) error {
+	subject := "Your Khajaride order " + data["OrderRef"] + " was cancelled"
+	if data["RefundAmount"] != "" {
+		subject = "Your Khajaride order " + data["OrderRef"] + " was cancelled and refunded"
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		subject,
+		TemplateOrderCancelled,
+		data,
+	)
+}
+
+func (c *Client) SendVendorNewOrderEmail(ctx context.Context, to string, data map[string]string) error {
+	return c.SendEmail(
+		ctx,
+		to,
+		"New order "+data["OrderRef"]+" for "+data["VendorName"],
+		TemplateVendorNewOrder,
+		data,
+	)
+}
+
+// Vendor verification outcomes, as used by the vendor_verification_result
+// template.
+const (
+	VerificationApproved         = "approved"
+	VerificationChangesRequested = "changes_requested"
+	VerificationRejected         = "rejected"
+	VerificationSuspended        = "suspended"
+	VerificationReinstated       = "reinstated"
+)
+
+func (c *Client) SendVendorVerificationResultEmail(ctx context.Context, to, vendorName, outcome, reason string) error {
+	subject := "Update on the verification of " + vendorName
+	switch outcome {
+	case VerificationApproved:
+		subject = vendorName + " is verified on Khajaride"
+	case VerificationChangesRequested:
+		subject = "Changes needed to verify " + vendorName
+	case VerificationSuspended:
+		subject = vendorName + " has been taken offline"
+	case VerificationReinstated:
+		subject = vendorName + " is back online"
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		subject,
+		TemplateVendorVerificationResult,
+		map[string]string{
+			"VendorName": vendorName,
+			"Outcome":    outcome,
+			"Reason":     reason,
+		},
+	)
+}
+
+func (c *Client) SendPayoutSentEmail(ctx context.Context, to string, data map[string]string) error {
+	return c.SendEmail(
+		ctx,
+		to,
+		"We paid out "+data["Amount"]+" to "+data["VendorName"],
+		TemplatePayoutSent,
+		data,
+	)
+}
+
+func (c *Client) SendVendorDocumentExpiryEmail(ctx context.Context, to string, data map[string]string) error {
+	return c.SendEmail(
+		ctx,
+		to,
+		"Your "+data["DocumentName"]+" expires on "+data["ExpiryDate"],
+		TemplateVendorDocumentExpiry,
+		data,
+	)
+}
+
+func (c *Client) SendVendorInvitationEmail(ctx context.Context, to string, data map[string]string) error {
+	return c.SendEmail(
+		ctx,
+		to,
+		"You're invited to join "+data["VendorName"]+" on Khajaride",
+		TemplateVendorInvitation,
+		data,
+	)
+}
+
+func (c *Client) SendVendorLowStockEmail(ctx context.Context, to string, data map[string]string) error {
+	subject := data["ItemName"] + " is running low at " + data["VendorName"]
+	if data["Quantity"] == "0" {
+		subject = data["ItemName"] + " has sold out at " + data["VendorName"]
+	}
+	return c.SendEmail(
+		ctx,
+		to,
+		subject,
+		TemplateVendorLowStock,
+		data,
+	)
+}
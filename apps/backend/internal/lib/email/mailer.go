@@ -0,0 +1,213 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/config"
+	"github.com/resend/resend-go/v2"
+	"github.com/rs/zerolog"
+)
+
+// Message is a rendered email ready to hand to a transport.
+type Message struct {
+	From        string
+	To          string
+	ReplyTo     string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []Attachment
+}
+
+// Mailer delivers a message and returns the id the transport gave it, which
+// delivery webhooks refer back to.
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// NewMailer returns the transport picked in the email config; without one
+// emails go through Resend.
+func NewMailer(cfg *config.Config, logger *zerolog.Logger) Mailer {
+	provider := "resend"
+	if cfg.Email != nil && cfg.Email.Provider != "" {
+		provider = cfg.Email.Provider
+	}
+
+	switch provider {
+	case "smtp":
+		return &smtpMailer{cfg: cfg.Email.SMTP}
+	case "file":
+		dir := cfg.Email.FileDir
+		if dir == "" {
+			dir = "tmp/emails"
+		}
+		return &fileMailer{dir: dir, logger: logger}
+	case "memory":
+		return NewMemoryMailer()
+	default:
+		return &resendMailer{client: resend.NewClient(cfg.Integration.ResendAPIKey)}
+	}
+}
+
+// ---------------- RESEND ----------------
+
+type resendMailer struct {
+	client *resend.Client
+}
+
+func (m *resendMailer) Name() string { return "resend" }
+
+func (m *resendMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	params := &resend.SendEmailRequest{
+		From:    msg.From,
+		To:      []string{msg.To},
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+		ReplyTo: msg.ReplyTo,
+	}
+	for _, a := range msg.Attachments {
+		params.Attachments = append(params.Attachments, &resend.Attachment{
+			Filename: a.Filename,
+			Content:  a.Content,
+		})
+	}
+
+	resp, err := m.client.Emails.SendWithContext(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("resend: %w", err)
+	}
+	return resp.Id, nil
+}
+
+// ---------------- SMTP ----------------
+
+// smtpMailer sends through any SMTP server, upgrading to TLS when the server
+// offers STARTTLS.
+type smtpMailer struct {
+	cfg *config.SMTPConfig
+}
+
+func (m *smtpMailer) Name() string { return "smtp" }
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", fmt.Errorf("smtp: invalid sender: %w", err)
+	}
+	raw, messageID, err := buildMIME(msg, from.Address)
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	// net/smtp has no context support; run it aside so a cancelled task
+	// does not wait on a hanging server
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{msg.To}, raw)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("smtp: %w", err)
+		}
+		return messageID, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// ---------------- FILE ----------------
+
+// fileMailer writes every message to an .eml file instead of sending it, so
+// local development never emails anyone. Open them with any mail client.
+type fileMailer struct {
+	dir    string
+	logger *zerolog.Logger
+}
+
+func (m *fileMailer) Name() string { return "file" }
+
+func (m *fileMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", fmt.Errorf("file mailer: invalid sender: %w", err)
+	}
+	raw, messageID, err := buildMIME(msg, from.Address)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return "", fmt.Errorf("file mailer: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000"), sanitizeFilename(msg.To))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return "", fmt.Errorf("file mailer: %w", err)
+	}
+
+	m.logger.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("file", path).Msg("Email written to file")
+	return messageID, nil
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// ---------------- MEMORY ----------------
+
+// MemoryMailer keeps sent messages in memory, for tests and tooling that
+// want to look at what would have been sent.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Name() string { return "memory" }
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return fmt.Sprintf("memory-%d", len(m.messages)), nil
+}
+
+// Messages returns a copy of everything sent so far.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
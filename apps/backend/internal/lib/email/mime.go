@@ -0,0 +1,136 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// buildMIME renders a message as RFC 5322 text: a multipart/alternative of
+// the plain-text and HTML bodies, wrapped in multipart/mixed when there are
+// attachments. It returns the message with the Message-ID it was given.
+func buildMIME(msg *Message, fromAddress string) ([]byte, string, error) {
+	messageID, err := newMessageID(fromAddress)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", msg.From)
+	header("To", msg.To)
+	if msg.ReplyTo != "" {
+		header("Reply-To", msg.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	var body bytes.Buffer
+	alt := multipart.NewWriter(&body)
+	if msg.Text != "" {
+		if err := writeQuotedPart(alt, "text/plain; charset=utf-8", msg.Text); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writeQuotedPart(alt, "text/html; charset=utf-8", msg.HTML); err != nil {
+		return nil, "", err
+	}
+	if err := alt.Close(); err != nil {
+		return nil, "", err
+	}
+
+	if len(msg.Attachments) == 0 {
+		header("Content-Type", "multipart/alternative; boundary="+alt.Boundary())
+		buf.WriteString("\r\n")
+		buf.Write(body.Bytes())
+		return buf.Bytes(), messageID, nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(body.Bytes()); err != nil {
+		return nil, "", err
+	}
+
+	for _, a := range msg.Attachments {
+		contentType := mime.TypeByExtension(filepath.Ext(a.Filename))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(wrapBase64(a.Content)); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), messageID, nil
+}
+
+func writeQuotedPart(w *multipart.Writer, contentType, content string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// wrapBase64 encodes in 76 character lines, as RFC 2045 requires.
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var out bytes.Buffer
+	for len(encoded) > 76 {
+		out.WriteString(encoded[:76])
+		out.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	out.WriteString(encoded)
+	out.WriteString("\r\n")
+	return out.Bytes()
+}
+
+func newMessageID(fromAddress string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	domain := "khajaride.local"
+	if i := strings.LastIndex(fromAddress, "@"); i >= 0 {
+		domain = fromAddress[i+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
@@ -1,7 +1,85 @@
 package email
 
+// PreviewData is sample data for every template, rendered by the dev-only
+// preview route (GET /dev/emails/:template).
 var PreviewData = map[string]map[string]string{
 	"welcome": {
 		"UserFirstName": "John",
 	},
+	"order_confirmation": {
+		"CustomerName": "John",
+		"OrderRef":     "9f1c2a7e",
+		"Total":        "USD 24.50",
+	},
+	"notification": {
+		"Title": "Order accepted",
+		"Body":  "Momo Hut accepted your order #9f1c2a7e.",
+	},
+	"payment_receipt": {
+		"CustomerName":   "John",
+		"VendorName":     "Momo Hut",
+		"OrderRef":       "9f1c2a7e",
+		"DeliveredAt":    "12 Oct 2025, 19:42",
+		"Subtotal":       "NPR 1,150.00",
+		"DeliveryCharge": "NPR 100.00",
+		"Fees":           "NPR 162.50",
+		"Discount":       "NPR 50.00",
+		"Total":          "NPR 1,362.50",
+		"PaymentMethod":  "Khalti",
+		"TransactionRef": "bZQLD9wRVWo4CdESSfuSsB",
+	},
+	"order_cancelled": {
+		"CustomerName": "John",
+		"VendorName":   "Momo Hut",
+		"OrderRef":     "9f1c2a7e",
+		"Reason":       "out of chicken momo tonight",
+		"Total":        "NPR 1,362.50",
+		"Paid":         "true",
+		"RefundAmount": "NPR 1,362.50",
+	},
+	"vendor_new_order": {
+		"VendorName":   "Momo Hut",
+		"OrderRef":     "9f1c2a7e",
+		"CustomerName": "John",
+		"ItemCount":    "3",
+		"Total":        "NPR 1,362.50",
+		"Instructions": "Extra chutney, please",
+	},
+	"vendor_verification_result": {
+		"VendorName": "Momo Hut",
+		"Outcome":    "changes_requested",
+		"Reason":     "the PAN certificate is not readable",
+	},
+	"payout_sent": {
+		"VendorName":     "Momo Hut",
+		"PeriodStart":    "6 Oct 2025",
+		"PeriodEnd":      "12 Oct 2025",
+		"Method":         "bank transfer",
+		"Gross":          "NPR 48,200.00",
+		"Commission":     "NPR 7,230.00",
+		"Refunds":        "NPR 1,362.50",
+		"Adjustments":    "NPR 0.00",
+		"Amount":         "NPR 39,607.50",
+		"TransactionRef": "NIBL-20251013-0042",
+	},
+	"vendor_document_expiry": {
+		"VendorName":   "Momo Hut",
+		"DocumentName": "hygiene certificate",
+		"ExpiryDate":   "19 Nov 2025",
+		"DaysLeft":     "7",
+		"Suspends":     "true",
+	},
+	"vendor_invitation": {
+		"VendorName":  "Momo Hut",
+		"InviterName": "ramesh",
+		"Role":        "cashier",
+		"AcceptURL":   "http://localhost:3000/vendor/invitations/accept?token=preview",
+		"ExpiresAt":   "26 Oct 2025",
+	},
+	"vendor_low_stock": {
+		"VendorName": "Momo Hut",
+		"ItemName":   "Chicken Momo",
+		"Quantity":   "3",
+		"DailyReset": "06:00",
+	},
 }
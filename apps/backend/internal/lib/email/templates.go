@@ -3,5 +3,30 @@ package email
 type Template string
 
 const (
-	TemplateWelcome Template = "welcome"
+	TemplateWelcome                  Template = "welcome"
+	TemplateOrderConfirmation        Template = "order_confirmation"
+	TemplateNotification             Template = "notification"
+	TemplatePaymentReceipt           Template = "payment_receipt"
+	TemplateOrderCancelled           Template = "order_cancelled"
+	TemplateVendorNewOrder           Template = "vendor_new_order"
+	TemplateVendorVerificationResult Template = "vendor_verification_result"
+	TemplatePayoutSent               Template = "payout_sent"
+	TemplateVendorDocumentExpiry     Template = "vendor_document_expiry"
+	TemplateVendorInvitation         Template = "vendor_invitation"
+	TemplateVendorLowStock           Template = "vendor_low_stock"
 )
+
+// Templates lists every template, e.g. for the preview index.
+var Templates = []Template{
+	TemplateWelcome,
+	TemplateOrderConfirmation,
+	TemplateNotification,
+	TemplatePaymentReceipt,
+	TemplateOrderCancelled,
+	TemplateVendorNewOrder,
+	TemplateVendorVerificationResult,
+	TemplatePayoutSent,
+	TemplateVendorDocumentExpiry,
+	TemplateVendorInvitation,
+	TemplateVendorLowStock,
+}
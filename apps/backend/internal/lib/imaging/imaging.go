@@ -0,0 +1,226 @@
+// Package imaging checks uploaded images and renders the sizes they are
+// served at. Renditions are re-encoded from the decoded pixels, so no EXIF
+// or other metadata of the upload survives; the EXIF orientation of JPEGs is
+// applied first.
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/chai2010/webp"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// MaxFileSize is the largest upload accepted
+	MaxFileSize = 10 << 20
+	// MinSide and MaxSide bound the width and height of uploads
+	MinSide = 200
+	MaxSide = 8000
+	// maxPixels keeps decoding within memory, about 100 MB per image
+	maxPixels = 25_000_000
+
+	jpegQuality = 82
+	webpQuality = 80
+)
+
+var (
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or WebP file")
+	ErrFileTooLarge    = errors.New("image must be 10 MB or smaller")
+	ErrTooSmall        = fmt.Errorf("image must be at least %dx%d pixels", MinSide, MinSide)
+	ErrTooLarge        = fmt.Errorf("image must be at most %dx%d pixels and 25 megapixels", MaxSide, MaxSide)
+	ErrCorrupt         = errors.New("image could not be read")
+)
+
+// Variant names.
+const (
+	VariantThumbnail = "thumbnail"
+	VariantCard      = "card"
+	VariantHero      = "hero"
+)
+
+// Formats every variant is rendered in.
+const (
+	FormatWebP = "webp"
+	FormatJPEG = "jpeg"
+)
+
+// Variant is a size images are served at. Cropped variants fill their box
+// from the centre of the image; the others fit inside it. Images are never
+// enlarged.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+var Variants = []Variant{
+	{Name: VariantThumbnail, Width: 200, Height: 200, Crop: true},
+	{Name: VariantCard, Width: 640, Height: 480, Crop: true},
+	{Name: VariantHero, Width: 1600, Height: 1600},
+}
+
+// Rendition is a variant in one format.
+type Rendition struct {
+	Variant     string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Result is a processed upload.
+type Result struct {
+	Hash        string // SHA-256 of the upload, hex
+	ContentType string // of the upload
+	Width       int    // upright
+	Height      int
+	Renditions  []Rendition
+}
+
+// Key is where a rendition of an upload is stored. Keys only depend on the
+// upload's content, so the same file always lands on the same objects.
+func Key(hash, variant, format string) string {
+	ext := "jpg"
+	if format == FormatWebP {
+		ext = "webp"
+	}
+	return fmt.Sprintf("images/%s/%s.%s", hash, variant, ext)
+}
+
+// Process checks an upload and renders all variants in all formats.
+func Process(data []byte) (*Result, error) {
+	if len(data) > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// 1️⃣ Check the type and size before decoding the pixels; the declared
+	// type is not trusted
+	contentType := http.DetectContentType(data)
+	var decode func([]byte) (image.Image, error)
+	var decodeConfig func([]byte) (image.Config, error)
+	switch contentType {
+	case "image/jpeg":
+		decode = func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) }
+	case "image/png":
+		decode = func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) }
+	case "image/webp":
+		decode = func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return webp.DecodeConfig(bytes.NewReader(b)) }
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, err := decodeConfig(data)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if cfg.Width < MinSide || cfg.Height < MinSide {
+		return nil, ErrTooSmall
+	}
+	if cfg.Width > MaxSide || cfg.Height > MaxSide || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	// 2️⃣ Decode and turn upright
+	img, err := decode(data)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	src := toRGBA(img)
+	if contentType == "image/jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+
+	// 3️⃣ Render
+	sum := sha256.Sum256(data)
+	res := &Result{
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: contentType,
+		Width:       src.Bounds().Dx(),
+		Height:      src.Bounds().Dy(),
+	}
+	for _, v := range Variants {
+		resized := resize(src, v)
+		size := resized.Bounds().Size()
+
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, resized, &webp.Options{Quality: webpQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s webp: %w", v.Name, err)
+		}
+		res.Renditions = append(res.Renditions, Rendition{
+			Variant: v.Name, Format: FormatWebP, ContentType: "image/webp",
+			Width: size.X, Height: size.Y, Data: buf.Bytes(),
+		})
+
+		buf = bytes.Buffer{}
+		if err := jpeg.Encode(&buf, flatten(resized), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s jpeg: %w", v.Name, err)
+		}
+		res.Renditions = append(res.Renditions, Rendition{
+			Variant: v.Name, Format: FormatJPEG, ContentType: "image/jpeg",
+			Width: size.X, Height: size.Y, Data: buf.Bytes(),
+		})
+	}
+	return res, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+func resize(src *image.RGBA, v Variant) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	crop := src.Bounds()
+	var dw, dh int
+
+	if v.Crop {
+		// the largest centred part of the image with the box's aspect ratio
+		cw, ch := sw, sh
+		if sw*v.Height > sh*v.Width {
+			cw = sh * v.Width / v.Height
+		} else {
+			ch = sw * v.Height / v.Width
+		}
+		x0, y0 := (sw-cw)/2, (sh-ch)/2
+		crop = image.Rect(x0, y0, x0+cw, y0+ch)
+		dw = min(v.Width, cw)
+		dh = max(1, dw*v.Height/v.Width)
+	} else {
+		scale := min(1, float64(v.Width)/float64(sw), float64(v.Height)/float64(sh))
+		dw = max(1, int(float64(sw)*scale+0.5))
+		dh = max(1, int(float64(sh)*scale+0.5))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, xdraw.Src, nil)
+	return dst
+}
+
+// flatten puts transparent images on white for JPEG.
+func flatten(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,133 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/gitSanje/khajaride/internal/model/invoice"
+	"github.com/go-pdf/fpdf"
+	"github.com/pkg/errors"
+)
+
+const templatePath = "templates/invoices/invoice.html"
+
+var funcs = template.FuncMap{
+	"money": money,
+}
+
+func money(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+// RenderHTML renders the invoice with the HTML template, the same layout the
+// PDF follows.
+func RenderHTML(doc *invoice.InvoiceDocument) ([]byte, error) {
+	tmpl, err := template.New("invoice.html").Funcs(funcs).ParseFiles(templatePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse invoice template")
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, doc); err != nil {
+		return nil, errors.Wrap(err, "failed to execute invoice template")
+	}
+	return body.Bytes(), nil
+}
+
+// RenderPDF draws the invoice as an A4 PDF.
+func RenderPDF(doc *invoice.InvoiceDocument) ([]byte, error) {
+	inv := doc.Invoice
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	// Header
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, "TAX INVOICE", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 5, "Invoice No: "+inv.InvoiceNumber, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, "Date: "+inv.IssuedAt.Format("2006-01-02 15:04"), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, "Order: "+inv.OrderID, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	// Seller / buyer
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(90, 6, "Seller", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Billed to", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(90, 5, tr(inv.VendorName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 5, tr(deref(inv.CustomerName)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(90, 5, tr(deref(inv.VendorAddress)), "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 5, tr(deref(inv.CustomerEmail)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(90, 5, "PAN/VAT: "+orDash(inv.VendorPanVat), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	// Line items
+	widths := []float64{80, 20, 25, 25, 30}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(240, 240, 240)
+	for i, h := range []string{"Item", "Qty", "Unit price", "Discount", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, l := range doc.Lines {
+		pdf.CellFormat(widths[0], 6, tr(l.Name), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, fmt.Sprintf("%d", l.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, money(l.UnitPrice), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, money(l.DiscountAmount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, money(l.Subtotal), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Tax breakdown
+	totals := [][2]string{
+		{"Subtotal", money(inv.Subtotal)},
+		{fmt.Sprintf("Service charge (%s%%)", money(inv.ServiceChargeRate)), money(inv.ServiceCharge)},
+		{fmt.Sprintf("VAT (%s%%)", money(inv.VatRate)), money(inv.Vat)},
+		{"Delivery charge", money(inv.DeliveryCharge)},
+		{"Discount", "-" + money(inv.Discount)},
+	}
+	if inv.LoyaltyDiscount > 0 {
+		totals = append(totals, [2]string{"Loyalty points", "-" + money(inv.LoyaltyDiscount)})
+	}
+	for _, t := range totals {
+		pdf.CellFormat(150, 6, t[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, t[1], "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(150, 8, "Total ("+inv.Currency+")", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 8, money(inv.Total), "T", 1, "R", false, 0, "")
+
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.CellFormat(0, 4, "This is a computer generated invoice issued through Khajaride.", "", 1, "L", false, 0, "")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, errors.Wrap(err, "failed to render invoice pdf")
+	}
+	return out.Bytes(), nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func orDash(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+	return *s
+}
@@ -1,14 +1,27 @@
 package job
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/gitSanje/khajaride/internal/lib/email"
 	"github.com/hibiken/asynq"
 )
 
 const (
-	TaskWelcome = "email:welcome"
+	TaskWelcome                  = "email:welcome"
+	TaskOrderConfirmation        = "email:order_confirmation"
+	TaskClerkEmail               = "email:clerk"
+	TaskPaymentReceipt           = "email:payment_receipt"
+	TaskOrderCancelled           = "email:order_cancelled"
+	TaskVendorNewOrder           = "email:vendor_new_order"
+	TaskVendorVerificationResult = "email:vendor_verification_result"
+	TaskPayoutSent               = "email:payout_sent"
+	TaskVendorDocumentExpiry     = "email:vendor_document_expiry"
+	TaskVendorInvitation         = "email:vendor_invitation"
+	TaskVendorLowStock           = "email:vendor_low_stock"
 )
 
 type WelcomeEmailPayload struct {
@@ -30,3 +43,224 @@ func NewWelcomeEmailTask(to, firstName string) (*asynq.Task, error) {
 		asynq.Queue("default"),
 		asynq.Timeout(30*time.Second)), nil
 }
+
+// OrderConfirmationEmailPayload names the purchase and its orders; the
+// worker issues the invoices (or fetches them from S3) when it sends the
+// email, so no PDF passes through the queue.
+type OrderConfirmationEmailPayload struct {
+	OrderRef string   `json:"order_ref"`
+	OrderIDs []string `json:"order_ids"`
+}
+
+// OrderConfirmation is what the confirmation email of a purchase says, with
+// the invoice of every order attached.
+type OrderConfirmation struct {
+	To           string
+	CustomerName string
+	Total        string
+	Invoices     []email.Attachment
+}
+
+// InvoiceIssuer issues the invoices of paid orders.
+type InvoiceIssuer interface {
+	PrepareOrderConfirmation(ctx context.Context, orderRef string, orderIDs []string) (*OrderConfirmation, error)
+}
+
+// SetInvoiceIssuer wires the invoice service in once the services exist;
+// confirmations picked up before that are retried.
+func (j *JobService) SetInvoiceIssuer(i InvoiceIssuer) {
+	j.invoiceIssuer = i
+}
+
+func NewOrderConfirmationEmailTask(p OrderConfirmationEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// The task id makes a second payment confirmation (redirect + webhook)
+	// for the same order a no-op while the first task is retained.
+	return asynq.NewTask(TaskOrderConfirmation, payload,
+		asynq.TaskID(TaskOrderConfirmation+":"+p.OrderRef),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(2*time.Minute)), nil
+}
+
+// ClerkEmailPayload is an email Clerk rendered but left for us to deliver
+// (delivered_by_clerk = false), e.g. verification codes sent through our
+// own domain.
+type ClerkEmailPayload struct {
+	EmailID string `json:"email_id"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+}
+
+func NewClerkEmailTask(p ClerkEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clerk retries the webhook; the task id keeps it to one email
+	return asynq.NewTask(TaskClerkEmail, payload,
+		asynq.TaskID(TaskClerkEmail+":"+p.EmailID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+// OrderEmailPayload is an order email whose template data was put together
+// by the service (see email.PreviewData for the keys of each template).
+type OrderEmailPayload struct {
+	OrderID string            `json:"order_id"`
+	To      string            `json:"to"`
+	Data    map[string]string `json:"data"`
+}
+
+func NewPaymentReceiptEmailTask(p OrderEmailPayload) (*asynq.Task, error) {
+	return newOrderEmailTask(TaskPaymentReceipt, p, "default")
+}
+
+func NewOrderCancelledEmailTask(p OrderEmailPayload) (*asynq.Task, error) {
+	return newOrderEmailTask(TaskOrderCancelled, p, "critical")
+}
+
+func NewVendorNewOrderEmailTask(p OrderEmailPayload) (*asynq.Task, error) {
+	return newOrderEmailTask(TaskVendorNewOrder, p, "critical")
+}
+
+// newOrderEmailTask sends each kind of order email once per order, however
+// often the event that triggers it is replayed.
+func newOrderEmailTask(taskType string, p OrderEmailPayload, queue string) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(taskType, payload,
+		asynq.TaskID(taskType+":"+p.OrderID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue(queue),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+type VendorVerificationResultEmailPayload struct {
+	To         string `json:"to"`
+	VendorName string `json:"vendor_name"`
+	Outcome    string `json:"outcome"` // email.Verification*
+	Reason     string `json:"reason,omitempty"`
+}
+
+func NewVendorVerificationResultEmailTask(p VendorVerificationResultEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskVendorVerificationResult, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+type PayoutSentEmailPayload struct {
+	SettlementID string            `json:"settlement_id"`
+	To           string            `json:"to"`
+	Data         map[string]string `json:"data"`
+}
+
+func NewPayoutSentEmailTask(p PayoutSentEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stripe's payout.paid webhook can arrive more than once
+	return asynq.NewTask(TaskPayoutSent, payload,
+		asynq.TaskID(TaskPayoutSent+":"+p.SettlementID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+// VendorDocumentExpiryEmailPayload is an expiry reminder; Warning is the
+// reminder it is (30, 7 or 1 days before) and keys the task with the
+// document and its expiry date.
+type VendorDocumentExpiryEmailPayload struct {
+	DocumentID string            `json:"document_id"`
+	ExpiryDate string            `json:"expiry_date"`
+	Warning    int               `json:"warning"`
+	To         string            `json:"to"`
+	Data       map[string]string `json:"data"`
+}
+
+func NewVendorDocumentExpiryEmailTask(p VendorDocumentExpiryEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskVendorDocumentExpiry, payload,
+		asynq.TaskID(fmt.Sprintf("%s:%s:%s:%d", TaskVendorDocumentExpiry, p.DocumentID, p.ExpiryDate, p.Warning)),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+// VendorInvitationEmailPayload carries the invitation link, so the task is
+// keyed by the invitation: sending it again replaces the invitation.
+type VendorInvitationEmailPayload struct {
+	InvitationID string            `json:"invitation_id"`
+	To           string            `json:"to"`
+	Data         map[string]string `json:"data"`
+}
+
+func NewVendorInvitationEmailTask(p VendorInvitationEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskVendorInvitation, payload,
+		asynq.TaskID(TaskVendorInvitation+":"+p.InvitationID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+// SetEmailRecorder wires the email log and suppression list in once the
+// repositories exist.
+func (j *JobService) SetEmailRecorder(r email.Recorder) {
+	if emailClient == nil {
+		return
+	}
+	emailClient.SetRecorder(r)
+}
+
+// VendorLowStockEmailPayload tells a vendor an item is running low. The
+// stock row is flagged when the alert is handed out, so it is sent once per
+// dip below the threshold.
+type VendorLowStockEmailPayload struct {
+	To   string            `json:"to"`
+	Data map[string]string `json:"data"`
+}
+
+func NewVendorLowStockEmailTask(p VendorLowStockEmailPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskVendorLowStock, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
@@ -3,18 +3,31 @@ package job
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/gitSanje/khajaride/internal/config"
 	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/lib/notification"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
 
-var emailClient *email.Client
+var (
+	emailClient *email.Client
+	notifier    *notification.Dispatcher
+)
 
 func (j *JobService) InitHandlers(config *config.Config, logger *zerolog.Logger) {
 	emailClient = email.NewClient(config, logger)
+	notifier = notification.NewDispatcher(config, logger, emailClient, func(ctx context.Context, endpoint string) {
+		if j.notificationRecorder == nil {
+			return
+		}
+		if err := j.notificationRecorder.DeletePushSubscription(ctx, endpoint); err != nil {
+			logger.Error().Err(err).Msg("Failed to delete expired push subscription")
+		}
+	})
 }
 
 func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task) error {
@@ -29,6 +42,7 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		Msg("Processing welcome email task")
 
 	err := emailClient.SendWelcomeEmail(
+		ctx,
 		p.To,
 		p.FirstName,
 	)
@@ -47,3 +61,182 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		Msg("Successfully sent welcome email")
 	return nil
 }
+
+func (j *JobService) handleOrderConfirmationEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p OrderConfirmationEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal order confirmation email payload: %w", err)
+	}
+	if j.invoiceIssuer == nil {
+		return errors.New("invoice issuer not set")
+	}
+
+	j.logger.Info().
+		Str("type", "order_confirmation").
+		Str("order_ref", p.OrderRef).
+		Msg("Processing order confirmation email task")
+
+	conf, err := j.invoiceIssuer.PrepareOrderConfirmation(ctx, p.OrderRef, p.OrderIDs)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "order_confirmation").
+			Str("order_ref", p.OrderRef).
+			Err(err).
+			Msg("Failed to issue invoices for order confirmation email")
+		return err
+	}
+
+	err = emailClient.SendOrderConfirmationEmail(
+		ctx,
+		conf.To,
+		conf.CustomerName,
+		p.OrderRef,
+		conf.Total,
+		conf.Invoices,
+	)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "order_confirmation").
+			Str("to", conf.To).
+			Err(err).
+			Msg("Failed to send order confirmation email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "order_confirmation").
+		Str("to", conf.To).
+		Msg("Successfully sent order confirmation email")
+	return nil
+}
+
+func (j *JobService) handleClerkEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p ClerkEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal clerk email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "clerk").
+		Str("to", p.To).
+		Str("email_id", p.EmailID).
+		Msg("Processing clerk email task")
+
+	if err := emailClient.SendHTMLEmail(ctx, p.To, p.Subject, p.HTML); err != nil {
+		j.logger.Error().
+			Str("type", "clerk").
+			Str("to", p.To).
+			Err(err).
+			Msg("Failed to send clerk email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "clerk").
+		Str("to", p.To).
+		Msg("Successfully sent clerk email")
+	return nil
+}
+
+// sendEmail logs an email task the way the handlers above do.
+func (j *JobService) sendEmail(kind, to string, send func() error) error {
+	j.logger.Info().
+		Str("type", kind).
+		Str("to", to).
+		Msg("Processing " + kind + " email task")
+
+	if err := send(); err != nil {
+		j.logger.Error().
+			Str("type", kind).
+			Str("to", to).
+			Err(err).
+			Msg("Failed to send " + kind + " email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", kind).
+		Str("to", to).
+		Msg("Successfully sent " + kind + " email")
+	return nil
+}
+
+func (j *JobService) handlePaymentReceiptEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p OrderEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal payment receipt email payload: %w", err)
+	}
+	return j.sendEmail("payment_receipt", p.To, func() error {
+		return emailClient.SendPaymentReceiptEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleOrderCancelledEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p OrderEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal order cancelled email payload: %w", err)
+	}
+	return j.sendEmail("order_cancelled", p.To, func() error {
+		return emailClient.SendOrderCancelledEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleVendorNewOrderEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p OrderEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal vendor new order email payload: %w", err)
+	}
+	return j.sendEmail("vendor_new_order", p.To, func() error {
+		return emailClient.SendVendorNewOrderEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleVendorVerificationResultEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p VendorVerificationResultEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal vendor verification result email payload: %w", err)
+	}
+	return j.sendEmail("vendor_verification_result", p.To, func() error {
+		return emailClient.SendVendorVerificationResultEmail(ctx, p.To, p.VendorName, p.Outcome, p.Reason)
+	})
+}
+
+func (j *JobService) handlePayoutSentEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p PayoutSentEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal payout sent email payload: %w", err)
+	}
+	return j.sendEmail("payout_sent", p.To, func() error {
+		return emailClient.SendPayoutSentEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleVendorDocumentExpiryEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p VendorDocumentExpiryEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal vendor document expiry email payload: %w", err)
+	}
+	return j.sendEmail("vendor_document_expiry", p.To, func() error {
+		return emailClient.SendVendorDocumentExpiryEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleVendorInvitationEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p VendorInvitationEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal vendor invitation email payload: %w", err)
+	}
+	return j.sendEmail("vendor_invitation", p.To, func() error {
+		return emailClient.SendVendorInvitationEmail(ctx, p.To, p.Data)
+	})
+}
+
+func (j *JobService) handleVendorLowStockEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p VendorLowStockEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal vendor low stock email payload: %w", err)
+	}
+	return j.sendEmail("vendor_low_stock", p.To, func() error {
+		return emailClient.SendVendorLowStockEmail(ctx, p.To, p.Data)
+	})
+}
@@ -10,6 +10,10 @@ type JobService struct {
 	Client *asynq.Client
 	server *asynq.Server
 	logger *zerolog.Logger
+
+	notificationRecorder NotificationRecorder
+	menuImportRunner     MenuImportRunner
+	invoiceIssuer        InvoiceIssuer
 }
 
 func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
@@ -42,6 +46,18 @@ func (j *JobService) Start() error {
 	// Register task handlers
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	mux.HandleFunc(TaskOrderConfirmation, j.handleOrderConfirmationEmailTask)
+	mux.HandleFunc(TaskClerkEmail, j.handleClerkEmailTask)
+	mux.HandleFunc(TaskPaymentReceipt, j.handlePaymentReceiptEmailTask)
+	mux.HandleFunc(TaskOrderCancelled, j.handleOrderCancelledEmailTask)
+	mux.HandleFunc(TaskVendorNewOrder, j.handleVendorNewOrderEmailTask)
+	mux.HandleFunc(TaskVendorVerificationResult, j.handleVendorVerificationResultEmailTask)
+	mux.HandleFunc(TaskPayoutSent, j.handlePayoutSentEmailTask)
+	mux.HandleFunc(TaskVendorDocumentExpiry, j.handleVendorDocumentExpiryEmailTask)
+	mux.HandleFunc(TaskVendorInvitation, j.handleVendorInvitationEmailTask)
+	mux.HandleFunc(TaskVendorLowStock, j.handleVendorLowStockEmailTask)
+	mux.HandleFunc(TaskNotification, j.handleNotificationTask)
+	mux.HandleFunc(TaskMenuImport, j.handleMenuImportTask)
 
 	j.logger.Info().Msg("Starting background job server")
 	if err := j.server.Start(mux); err != nil {
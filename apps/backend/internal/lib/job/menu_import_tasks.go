@@ -0,0 +1,80 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const TaskMenuImport = "menu_import:run"
+
+// menuImportMaxRetry covers S3 and database hiccups; a file that cannot be
+// read fails the import on the first attempt.
+const menuImportMaxRetry = 3
+
+// MenuImportRunner applies uploaded menu files and records their progress.
+type MenuImportRunner interface {
+	RunMenuImport(ctx context.Context, importID string) error
+	FailMenuImport(ctx context.Context, importID, reason string) error
+}
+
+// SetMenuImportRunner wires the importer in once the services exist; imports
+// picked up before that are retried.
+func (j *JobService) SetMenuImportRunner(r MenuImportRunner) {
+	j.menuImportRunner = r
+}
+
+type MenuImportPayload struct {
+	ImportID string `json:"importId"`
+}
+
+func NewMenuImportTask(importID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(MenuImportPayload{ImportID: importID})
+	if err != nil {
+		return nil, err
+	}
+
+	// one task per menu_imports row
+	return asynq.NewTask(TaskMenuImport, payload,
+		asynq.TaskID(TaskMenuImport+":"+importID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(menuImportMaxRetry),
+		asynq.Queue("low"),
+		asynq.Timeout(15*time.Minute)), nil
+}
+
+func (j *JobService) handleMenuImportTask(ctx context.Context, t *asynq.Task) error {
+	var p MenuImportPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal menu import payload: %w", err)
+	}
+	if j.menuImportRunner == nil {
+		return errors.New("menu import runner not set")
+	}
+
+	err := j.menuImportRunner.RunMenuImport(ctx, p.ImportID)
+	if err == nil {
+		return nil
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+	j.logger.Error().
+		Err(err).
+		Str("import_id", p.ImportID).
+		Int("attempt", retried+1).
+		Bool("final", final).
+		Msg("Failed to run menu import")
+
+	if final {
+		if failErr := j.menuImportRunner.FailMenuImport(ctx, p.ImportID, "the import stopped on an internal error"); failErr != nil {
+			j.logger.Error().Err(failErr).Str("import_id", p.ImportID).Msg("Failed to record failed menu import")
+		}
+	}
+	return err
+}
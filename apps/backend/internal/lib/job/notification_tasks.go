@@ -0,0 +1,85 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/notification"
+	"github.com/hibiken/asynq"
+)
+
+const TaskNotification = "notification:deliver"
+
+// notificationMaxRetry spreads a failing delivery over about an hour with
+// asynq's default backoff
+const notificationMaxRetry = 6
+
+// NotificationRecorder keeps order_notifications in step with deliveries.
+type NotificationRecorder interface {
+	MarkNotificationSent(ctx context.Context, id string, attempts int) error
+	MarkNotificationFailed(ctx context.Context, id string, attempts int, reason string, final bool) error
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+}
+
+// SetNotificationRecorder wires the delivery status store in once the
+// repositories exist; deliveries picked up before that are retried.
+func (j *JobService) SetNotificationRecorder(r NotificationRecorder) {
+	j.notificationRecorder = r
+}
+
+func NewNotificationTask(msg notification.Message) (*asynq.Task, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// one task per order_notifications row
+	return asynq.NewTask(TaskNotification, payload,
+		asynq.TaskID(TaskNotification+":"+msg.NotificationID),
+		asynq.Retention(24*time.Hour),
+		asynq.MaxRetry(notificationMaxRetry),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+func (j *JobService) handleNotificationTask(ctx context.Context, t *asynq.Task) error {
+	var msg notification.Message
+	if err := json.Unmarshal(t.Payload(), &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal notification payload: %w", err)
+	}
+	if j.notificationRecorder == nil {
+		return errors.New("notification recorder not set")
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	attempts := retried + 1
+
+	err := notifier.Send(ctx, &msg)
+	if err == nil {
+		if err := j.notificationRecorder.MarkNotificationSent(ctx, msg.NotificationID, attempts); err != nil {
+			j.logger.Error().Err(err).Str("notification_id", msg.NotificationID).Msg("Failed to record sent notification")
+		}
+		return nil
+	}
+
+	final := retried >= maxRetry || errors.Is(err, notification.ErrUndeliverable)
+	j.logger.Error().
+		Err(err).
+		Str("notification_id", msg.NotificationID).
+		Str("channel", msg.Channel).
+		Int("attempt", attempts).
+		Bool("final", final).
+		Msg("Failed to deliver notification")
+
+	if recErr := j.notificationRecorder.MarkNotificationFailed(ctx, msg.NotificationID, attempts, err.Error(), final); recErr != nil {
+		j.logger.Error().Err(recErr).Str("notification_id", msg.NotificationID).Msg("Failed to record failed notification")
+	}
+	if errors.Is(err, notification.ErrUndeliverable) {
+		return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+	}
+	return err
+}
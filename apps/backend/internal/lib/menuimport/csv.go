@@ -0,0 +1,46 @@
+package menuimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type csvSource struct{}
+
+func (csvSource) Read(data []byte) (*Table, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = csvDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	// blank lines are skipped by the reader, so rows are numbered by the
+	// line they start on
+	var rows [][]string
+	var lines []int
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	return readTable(rows, lines)
+}
+
+// csvDelimiter picks ";" for files saved by spreadsheets set to separate
+// lists with it, "," otherwise.
+func csvDelimiter(data []byte) rune {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
@@ -0,0 +1,90 @@
+package menuimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// foodManduSource reads the menu export of one FoodMandu vendor, the shape
+// TransformFoodManduMenuItems loads for many: the vendor's categories with
+// their items, either bare or keyed by the FoodMandu vendor id.
+type foodManduSource struct{}
+
+type foodManduCategory struct {
+	Category string          `json:"category"`
+	Items    []foodManduItem `json:"items"`
+}
+
+type foodManduItem struct {
+	Name           string `json:"name"`
+	ProductDesc    string `json:"productDesc"`
+	ProductImage   string `json:"ProductImage"`
+	Price          any    `json:"price"`
+	OldPrice       any    `json:"oldprice"`
+	Keyword        string `json:"Keyword"`
+	ItemDisplayTag string `json:"itemDisplayTag"`
+}
+
+func (foodManduSource) Read(data []byte) (*Table, error) {
+	var categories []foodManduCategory
+	if err := json.Unmarshal(data, &categories); err != nil {
+		var byVendor map[string][]foodManduCategory
+		if err := json.Unmarshal(data, &byVendor); err != nil {
+			return nil, fmt.Errorf("invalid FoodMandu menu file: %w", err)
+		}
+		if len(byVendor) != 1 {
+			return nil, errors.New("FoodMandu menu file must hold the menu of one vendor")
+		}
+		for _, c := range byVendor {
+			categories = c
+		}
+	}
+
+	t := &Table{}
+	row := 0
+	for _, c := range categories {
+		for _, item := range c.Items {
+			row++
+			if row > MaxRows {
+				return nil, ErrTooManyRows
+			}
+
+			fields := make(map[string]string)
+			set := func(column, value string) {
+				if v := strings.TrimSpace(value); v != "" {
+					fields[column] = v
+				}
+			}
+			set(ColumnName, item.Name)
+			set(ColumnCategory, c.Category)
+			set(ColumnPrice, foodManduNumber(item.Price))
+			set(ColumnOldPrice, foodManduNumber(item.OldPrice))
+			set(ColumnDescription, item.ProductDesc)
+			set(ColumnImage, item.ProductImage)
+			set(ColumnKeywords, item.Keyword)
+			// display tags are "/"-separated there
+			set(ColumnTags, strings.ReplaceAll(item.ItemDisplayTag, "/", ","))
+
+			t.Records = append(t.Records, Record{Row: row, Fields: fields})
+		}
+	}
+	if len(t.Records) == 0 {
+		return nil, ErrNoRows
+	}
+	return t, nil
+}
+
+// foodManduNumber returns prices as text for validation; the export has
+// them as numbers or strings.
+func foodManduNumber(v any) string {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case string:
+		return n
+	}
+	return ""
+}
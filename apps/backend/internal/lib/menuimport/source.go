@@ -0,0 +1,174 @@
+// Package menuimport reads vendor menus from uploaded files. A Source turns
+// one file format into records of column → cell text, Validate checks the
+// records and builds the menu items to upsert.
+package menuimport
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// File formats, stored with each import.
+const (
+	FormatCSV       = "csv"
+	FormatXLSX      = "xlsx"
+	FormatFoodMandu = "foodmandu"
+)
+
+// MaxRows caps the items of one file.
+const MaxRows = 5000
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported menu file format")
+	ErrNoRows            = errors.New("menu file has no items")
+	ErrTooManyRows       = fmt.Errorf("menu file has more than %d items", MaxRows)
+	ErrMissingColumns    = errors.New(`menu file needs a "name" and a "price" column`)
+)
+
+// Columns of a menu file. Spreadsheets may use any of the headers in
+// columnAliases.
+const (
+	ColumnName         = "name"
+	ColumnCategory     = "category"
+	ColumnPrice        = "price"
+	ColumnOldPrice     = "old_price"
+	ColumnDescription  = "description"
+	ColumnImage        = "image"
+	ColumnTags         = "tags"
+	ColumnKeywords     = "keywords"
+	ColumnPortionSize  = "portion_size"
+	ColumnSpicyLevel   = "spicy_level"
+	ColumnIsVegetarian = "is_vegetarian"
+	ColumnIsVegan      = "is_vegan"
+	ColumnIsGlutenFree = "is_gluten_free"
+	ColumnIsAvailable  = "is_available"
+)
+
+// columnAliases maps the headers seen in vendor spreadsheets to columns.
+// Headers are compared lower-cased with spaces and dashes as underscores.
+var columnAliases = map[string]string{
+	"name": ColumnName, "item": ColumnName, "item_name": ColumnName, "product": ColumnName,
+	"category": ColumnCategory, "category_name": ColumnCategory,
+	"price": ColumnPrice, "base_price": ColumnPrice, "selling_price": ColumnPrice,
+	"old_price": ColumnOldPrice, "original_price": ColumnOldPrice,
+	"description": ColumnDescription, "desc": ColumnDescription,
+	"image": ColumnImage, "image_url": ColumnImage, "photo": ColumnImage,
+	"tags": ColumnTags, "tag": ColumnTags,
+	"keywords": ColumnKeywords, "keyword": ColumnKeywords,
+	"portion_size": ColumnPortionSize, "portion": ColumnPortionSize,
+	"spicy_level": ColumnSpicyLevel, "spicy": ColumnSpicyLevel,
+	"is_vegetarian": ColumnIsVegetarian, "vegetarian": ColumnIsVegetarian, "veg": ColumnIsVegetarian,
+	"is_vegan": ColumnIsVegan, "vegan": ColumnIsVegan,
+	"is_gluten_free": ColumnIsGlutenFree, "gluten_free": ColumnIsGlutenFree,
+	"is_available": ColumnIsAvailable, "available": ColumnIsAvailable,
+}
+
+// Record is one menu item as read from a file. Row is where it is in the
+// file: the spreadsheet row (the header is row 1) or, for FoodMandu files,
+// the position of the item.
+type Record struct {
+	Row    int
+	Fields map[string]string // trimmed, empty cells left out
+}
+
+// Table is what a Source read. Columns it did not recognise are ignored and
+// reported.
+type Table struct {
+	Records        []Record
+	IgnoredColumns []string
+}
+
+// Source reads the menu items of one file format.
+type Source interface {
+	Read(data []byte) (*Table, error)
+}
+
+// NewSource returns the reader of a format.
+func NewSource(format string) (Source, error) {
+	switch format {
+	case FormatCSV:
+		return csvSource{}, nil
+	case FormatXLSX:
+		return xlsxSource{}, nil
+	case FormatFoodMandu:
+		return foodManduSource{}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// DetectFormat guesses the format of a file from its name; "" when unknown.
+func DetectFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	case ".json":
+		return FormatFoodMandu
+	}
+	return ""
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+}
+
+// readTable turns the rows of a spreadsheet, the first being the header,
+// into records. lines numbers the rows when they are not consecutive.
+// Blank rows are skipped.
+func readTable(rows [][]string, lines []int) (*Table, error) {
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+
+	t := &Table{}
+	columns := make([]string, len(rows[0]))
+	seen := make(map[string]bool)
+	for i, h := range rows[0] {
+		col, ok := columnAliases[normalizeHeader(h)]
+		if !ok || seen[col] {
+			if h = strings.TrimSpace(h); h != "" {
+				t.IgnoredColumns = append(t.IgnoredColumns, h)
+			}
+			continue
+		}
+		seen[col] = true
+		columns[i] = col
+	}
+	if !seen[ColumnName] || !seen[ColumnPrice] {
+		return nil, ErrMissingColumns
+	}
+
+	for n, row := range rows {
+		if n == 0 {
+			continue
+		}
+		fields := make(map[string]string)
+		for i, cell := range row {
+			if i >= len(columns) || columns[i] == "" {
+				continue
+			}
+			if v := strings.TrimSpace(cell); v != "" {
+				fields[columns[i]] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if len(t.Records) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		line := n + 1
+		if lines != nil {
+			line = lines[n]
+		}
+		t.Records = append(t.Records, Record{Row: line, Fields: fields})
+	}
+	if len(t.Records) == 0 {
+		return nil, ErrNoRows
+	}
+	return t, nil
+}
@@ -0,0 +1,202 @@
+package menuimport
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+)
+
+// maxPrice fits menu_items' NUMERIC(10,2)
+const maxPrice = 99999999.99
+
+// Catalog is what the vendor's menu already has, for matching rows.
+type Catalog struct {
+	Categories map[string]string // NameKey(name) → category id
+	Items      map[string]bool   // NameKey(name) of the vendor's items
+}
+
+// Result is the validated file. Invalid rows are in Errors, the others in
+// Items in file order.
+type Result struct {
+	Items         []vendor.MenuImportItem
+	Errors        []vendor.MenuImportRowError
+	InvalidRows   int
+	NewCategories []string // unknown categories the import may create
+}
+
+// NameKey is how item and category names are matched: case and
+// surrounding spaces do not matter.
+func NameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Validate checks the records against the vendor's menu. Unknown categories
+// are errors unless createCategories is set; a name repeated in the file is
+// an error on all but its first row.
+func Validate(records []Record, catalog Catalog, createCategories bool) *Result {
+	res := &Result{}
+	firstRow := make(map[string]int)
+	newCategories := make(map[string]bool)
+
+	for _, rec := range records {
+		v := &rowValidator{rec: rec}
+		item := vendor.MenuImportItem{Row: rec.Row}
+
+		// 1️⃣ Name, unique within the file
+		item.Name = rec.Fields[ColumnName]
+		key := NameKey(item.Name)
+		switch {
+		case item.Name == "":
+			v.fail(ColumnName, "name is required")
+		case len(item.Name) > 150:
+			v.fail(ColumnName, "name must be 150 characters or fewer")
+		case firstRow[key] != 0:
+			v.fail(ColumnName, fmt.Sprintf("duplicate of row %d", firstRow[key]))
+		default:
+			firstRow[key] = rec.Row
+			item.Exists = catalog.Items[key]
+		}
+
+		// 2️⃣ Prices
+		if raw, ok := rec.Fields[ColumnPrice]; !ok {
+			v.fail(ColumnPrice, "price is required")
+		} else if p, ok := parsePrice(raw); !ok || p <= 0 {
+			v.fail(ColumnPrice, "price must be a number greater than 0")
+		} else {
+			item.Price = p
+		}
+		if raw, ok := rec.Fields[ColumnOldPrice]; ok {
+			if p, ok := parsePrice(raw); !ok || p < 0 {
+				v.fail(ColumnOldPrice, "old price must be a number of 0 or more")
+			} else {
+				item.OldPrice = &p
+			}
+		}
+
+		// 3️⃣ Category
+		item.Category = rec.Fields[ColumnCategory]
+		if item.Category != "" {
+			if id, ok := catalog.Categories[NameKey(item.Category)]; ok {
+				item.CategoryID = &id
+			} else if !createCategories {
+				v.fail(ColumnCategory, "unknown category")
+			} else if len(item.Category) > 150 {
+				v.fail(ColumnCategory, "category must be 150 characters or fewer")
+			}
+		} else if !item.Exists {
+			v.fail(ColumnCategory, "category is required for new items")
+		}
+
+		// 4️⃣ The rest
+		item.Description = v.text(ColumnDescription, 0)
+		item.Image = v.text(ColumnImage, 0)
+		item.Keywords = v.text(ColumnKeywords, 0)
+		item.PortionSize = v.text(ColumnPortionSize, 50)
+		item.Tags = splitTags(rec.Fields[ColumnTags])
+		item.SpicyLevel = v.spicyLevel()
+		item.IsVegetarian = v.boolean(ColumnIsVegetarian)
+		item.IsVegan = v.boolean(ColumnIsVegan)
+		item.IsGlutenFree = v.boolean(ColumnIsGlutenFree)
+		item.IsAvailable = v.boolean(ColumnIsAvailable)
+
+		if len(v.errs) > 0 {
+			res.Errors = append(res.Errors, v.errs...)
+			res.InvalidRows++
+			continue
+		}
+		if item.Category != "" && item.CategoryID == nil && !newCategories[NameKey(item.Category)] {
+			newCategories[NameKey(item.Category)] = true
+			res.NewCategories = append(res.NewCategories, item.Category)
+		}
+		res.Items = append(res.Items, item)
+	}
+	return res
+}
+
+type rowValidator struct {
+	rec  Record
+	errs []vendor.MenuImportRowError
+}
+
+func (v *rowValidator) fail(column, message string) {
+	v.errs = append(v.errs, vendor.MenuImportRowError{
+		Row:     v.rec.Row,
+		Column:  column,
+		Value:   v.rec.Fields[column],
+		Message: message,
+	})
+}
+
+func (v *rowValidator) text(column string, maxLen int) *string {
+	s, ok := v.rec.Fields[column]
+	if !ok {
+		return nil
+	}
+	if maxLen > 0 && len(s) > maxLen {
+		v.fail(column, fmt.Sprintf("must be %d characters or fewer", maxLen))
+		return nil
+	}
+	return &s
+}
+
+func (v *rowValidator) boolean(column string) *bool {
+	s, ok := v.rec.Fields[column]
+	if !ok {
+		return nil
+	}
+	var b bool
+	switch strings.ToLower(s) {
+	case "true", "yes", "y", "1":
+		b = true
+	case "false", "no", "n", "0":
+		b = false
+	default:
+		v.fail(column, "must be yes or no")
+		return nil
+	}
+	return &b
+}
+
+func (v *rowValidator) spicyLevel() *int {
+	s, ok := v.rec.Fields[ColumnSpicyLevel]
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 5 {
+		v.fail(ColumnSpicyLevel, "spicy level must be a whole number from 0 to 5")
+		return nil
+	}
+	return &n
+}
+
+// parsePrice reads prices as vendors write them: "1,200", "Rs. 250",
+// "NPR 99.50". Prices are rounded to paisa.
+func parsePrice(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range []string{"npr", "rs.", "rs"} {
+		s = strings.TrimSpace(strings.TrimPrefix(s, prefix))
+	}
+	s = strings.ReplaceAll(s, ",", "")
+
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(p) || math.IsInf(p, 0) || p > maxPrice {
+		return 0, false
+	}
+	return math.Round(p*100) / 100, true
+}
+
+// splitTags splits a tags cell on commas; nil when there are none, which
+// keeps the tags of an existing item.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,36 @@
+package menuimport
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxUnzipLimit bounds what a workbook may unzip to, well above any real
+// menu.
+const xlsxUnzipLimit = 64 << 20
+
+type xlsxSource struct{}
+
+// Read takes the items from the first sheet of the workbook.
+func (xlsxSource) Read(data []byte) (*Table, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{
+		UnzipSizeLimit:    xlsxUnzipLimit,
+		UnzipXMLSizeLimit: xlsxUnzipLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrNoRows
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheets[0], err)
+	}
+	return readTable(rows, nil)
+}
@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/config"
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/lib/sms"
+	"github.com/gitSanje/khajaride/internal/lib/webpush"
+	model "github.com/gitSanje/khajaride/internal/model/notification"
+	"github.com/rs/zerolog"
+)
+
+// ErrUndeliverable is a failure retrying will not fix, e.g. a channel that
+// is not configured or a recipient with no push subscription left.
+var ErrUndeliverable = errors.New("notification cannot be delivered")
+
+// pushTTL is how long push services hold a message for an offline browser
+const pushTTL = 24 * time.Hour
+
+// Message is a rendered notification on its way out over one channel. To
+// is the email address or phone number; push messages carry the
+// recipient's subscriptions instead.
+type Message struct {
+	NotificationID string                 `json:"notification_id"`
+	Channel        string                 `json:"channel"`
+	To             string                 `json:"to,omitempty"`
+	Title          string                 `json:"title"`
+	Body           string                 `json:"body"`
+	Data           map[string]string      `json:"data,omitempty"`
+	Push           []webpush.Subscription `json:"push,omitempty"`
+}
+
+// Adapter delivers messages over one channel.
+type Adapter interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Dispatcher routes a message to its channel's adapter. The in-app inbox has
+// no adapter: its rows are delivered as soon as they are written.
+type Dispatcher struct {
+	adapters map[string]Adapter
+}
+
+// NewDispatcher sets up every configured channel. Email goes through the
+// shared client so it shares the email log and suppression list. pushGone
+// is told about push subscriptions the push service has dropped, so they
+// can be deleted.
+func NewDispatcher(cfg *config.Config, logger *zerolog.Logger, emailClient *email.Client, pushGone func(ctx context.Context, endpoint string)) *Dispatcher {
+	d := &Dispatcher{
+		adapters: map[string]Adapter{
+			model.ChannelEmail: &emailAdapter{client: emailClient},
+			model.ChannelSMS:   &smsAdapter{sender: sms.NewSender(cfg.SMS, logger)},
+		},
+	}
+
+	push, err := webpush.NewClient(cfg.Push)
+	if err != nil {
+		logger.Error().Err(err).Msg("web push disabled")
+	}
+	if push != nil {
+		d.adapters[model.ChannelPush] = &pushAdapter{client: push, gone: pushGone}
+	}
+	return d
+}
+
+func (d *Dispatcher) Send(ctx context.Context, msg *Message) error {
+	adapter, ok := d.adapters[msg.Channel]
+	if !ok {
+		return fmt.Errorf("%w: channel %s is not configured", ErrUndeliverable, msg.Channel)
+	}
+	return adapter.Send(ctx, msg)
+}
+
+// ---------------- EMAIL ----------------
+
+type emailAdapter struct {
+	client *email.Client
+}
+
+func (a *emailAdapter) Send(ctx context.Context, msg *Message) error {
+	return a.client.SendNotificationEmail(ctx, msg.To, msg.Title, msg.Body)
+}
+
+// ---------------- SMS ----------------
+
+type smsAdapter struct {
+	sender sms.Sender
+}
+
+func (a *smsAdapter) Send(ctx context.Context, msg *Message) error {
+	return a.sender.Send(ctx, msg.To, "Khajaride: "+msg.Body)
+}
+
+// ---------------- WEB PUSH ----------------
+
+type pushAdapter struct {
+	client *webpush.Client
+	gone   func(ctx context.Context, endpoint string)
+}
+
+// Send pushes to every browser of the recipient; one is enough for the
+// message to count as sent.
+func (a *pushAdapter) Send(ctx context.Context, msg *Message) error {
+	payload, err := json.Marshal(map[string]any{
+		"title": msg.Title,
+		"body":  msg.Body,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	sent := 0
+	for _, sub := range msg.Push {
+		err := a.client.Send(ctx, sub, payload, pushTTL)
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, webpush.ErrSubscriptionGone):
+			if a.gone != nil {
+				a.gone(ctx, sub.Endpoint)
+			}
+		default:
+			lastErr = err
+		}
+	}
+
+	switch {
+	case sent > 0:
+		return nil
+	case lastErr != nil:
+		return lastErr
+	default:
+		return fmt.Errorf("%w: no push subscription", ErrUndeliverable)
+	}
+}
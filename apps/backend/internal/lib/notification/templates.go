@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	model "github.com/gitSanje/khajaride/internal/model/notification"
+)
+
+// Template is the message of one event for one kind of recipient, and the
+// channels it goes out on. Title and Body are text/templates over the event
+// data (OrderRef, VendorName, CustomerName, Total, ItemCount, Reason).
+type Template struct {
+	Title    string
+	Body     string
+	Channels []string
+}
+
+var (
+	pushAndInbox = []string{model.ChannelPush, model.ChannelInApp}
+	allButEmail  = []string{model.ChannelSMS, model.ChannelPush, model.ChannelInApp}
+)
+
+// templates by event, then recipient type. Paid orders are confirmed by the
+// invoice email, and new and cancelled orders have their own emails (see
+// internal/lib/email), so those templates do not email.
+var templates = map[string]map[string]Template{
+	model.EventPaymentSucceeded: {
+		model.RecipientUser: {
+			Title:    "Order confirmed",
+			Body:     "We received your payment of {{.Total}} for order #{{.OrderRef}} from {{.VendorName}}. We'll let you know when the restaurant accepts it.",
+			Channels: allButEmail,
+		},
+	},
+	model.EventPaymentFailed: {
+		model.RecipientUser: {
+			Title:    "Payment failed",
+			Body:     "Your payment for order #{{.OrderRef}} from {{.VendorName}} did not go through. Your cart is saved, so you can try again.",
+			Channels: []string{model.ChannelEmail, model.ChannelPush, model.ChannelInApp},
+		},
+	},
+	model.EventOrderNew: {
+		model.RecipientVendor: {
+			Title:    "New order #{{.OrderRef}}",
+			Body:     "New paid order #{{.OrderRef}}: {{.ItemCount}} item(s), {{.Total}}. Accept it in your dashboard.",
+			Channels: allButEmail,
+		},
+	},
+	model.OrderStatusEvent("accepted"): {
+		model.RecipientUser: {
+			Title:    "Order accepted",
+			Body:     "{{.VendorName}} accepted your order #{{.OrderRef}}.",
+			Channels: pushAndInbox,
+		},
+	},
+	model.OrderStatusEvent("preparing"): {
+		model.RecipientUser: {
+			Title:    "Being prepared",
+			Body:     "{{.VendorName}} is preparing your order #{{.OrderRef}}.",
+			Channels: []string{model.ChannelInApp},
+		},
+	},
+	model.OrderStatusEvent("ready_for_pickup"): {
+		model.RecipientUser: {
+			Title:    "Order ready",
+			Body:     "Your order #{{.OrderRef}} from {{.VendorName}} is ready.",
+			Channels: pushAndInbox,
+		},
+	},
+	model.OrderStatusEvent("assigned"): {
+		model.RecipientUser: {
+			Title:    "Rider assigned",
+			Body:     "A rider has been assigned to your order #{{.OrderRef}}.",
+			Channels: pushAndInbox,
+		},
+	},
+	model.OrderStatusEvent("picked_up"): {
+		model.RecipientUser: {
+			Title:    "On the way",
+			Body:     "Your order #{{.OrderRef}} from {{.VendorName}} is on the way.",
+			Channels: allButEmail,
+		},
+	},
+	model.OrderStatusEvent("delivered"): {
+		model.RecipientUser: {
+			Title:    "Delivered",
+			Body:     "Your order #{{.OrderRef}} from {{.VendorName}} was delivered. Enjoy your meal!",
+			Channels: pushAndInbox,
+		},
+	},
+	model.EventOrderCancelled: {
+		model.RecipientUser: {
+			Title:    "Order cancelled",
+			Body:     "{{.VendorName}} could not take your order #{{.OrderRef}}{{if .Reason}} ({{.Reason}}){{end}}. Any payment will be refunded.",
+			Channels: allButEmail,
+		},
+	},
+}
+
+// Lookup returns the template of an event for a recipient type.
+func Lookup(event, recipientType string) (Template, bool) {
+	t, ok := templates[event][recipientType]
+	return t, ok
+}
+
+// Render fills in the title and body.
+func (t Template) Render(data map[string]string) (string, string, error) {
+	title, err := execute(t.Title, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := execute(t.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return title, body, nil
+}
+
+func execute(text string, data map[string]string) (string, error) {
+	tmpl, err := template.New("notification").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse notification template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute notification template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -33,6 +33,8 @@ func (i *Indexes) CreateIndexes() error {
       "is_gluten_free": { "type": "boolean" },
       "spicy_level": { "type": "integer" },
       "portion_size": { "type": "keyword" },
+      "favorite_count": { "type": "integer" },
+      "chain_key": { "type": "keyword" },
       "category": {
         "properties": {
           "id": { "type": "keyword" },
@@ -46,11 +48,14 @@ func (i *Indexes) CreateIndexes() error {
       "vendor": {
         "properties": {
           "id": { "type": "keyword" },
+          "brand_id": { "type": "keyword" },
           "name": { "type": "text", "analyzer": "english" },
+          "outlet_name": { "type": "text", "analyzer": "english" },
           "about": { "type": "text", "analyzer": "english" },
           "cuisine": { "type": "text", "analyzer": "english" },
           "cuisine_tags": { "type": "text" },
           "vendor_type": { "type": "keyword" },
+          "status": { "type": "keyword" },
           "rating": { "type": "float" },
           "favorite_count": { "type": "integer" },
           "is_open": { "type": "boolean" },
@@ -59,6 +64,7 @@ func (i *Indexes) CreateIndexes() error {
           "pickup_available": { "type": "boolean" },
           "delivery_fee": { "type": "float" },
           "min_order_amount": { "type": "float" },
+          "delivery_radius_km": { "type": "float" },
           "promo_text": { "type": "text", "analyzer": "english" },
           "vendor_notice": { "type": "text", "analyzer": "english" },
           "location": { "type": "geo_point" },
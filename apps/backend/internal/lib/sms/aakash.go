@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const aakashURL = "https://sms.aakashsms.com/sms/v3/send"
+
+// aakashSender sends through Aakash SMS (aakashsms.com).
+type aakashSender struct {
+	client *http.Client
+	token  string
+}
+
+type aakashResponse struct {
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+}
+
+func (s *aakashSender) Send(ctx context.Context, to, message string) error {
+	form := url.Values{
+		"auth_token": {s.token},
+		"to":         {localNumber(to)},
+		"text":       {message},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, aakashURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("aakash sms: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var res aakashResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("aakash sms: failed to decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || res.Error {
+		return fmt.Errorf("aakash sms: %s", res.Message)
+	}
+	return nil
+}
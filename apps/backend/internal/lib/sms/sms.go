@@ -0,0 +1,55 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// Sender delivers a text message to a Nepali mobile number in E.164 form
+// (+9779XXXXXXXXX).
+type Sender interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// NewSender returns the gateway picked in the SMS config; without one the
+// messages only go to the log.
+func NewSender(cfg *config.SMSConfig, logger *zerolog.Logger) Sender {
+	if cfg == nil {
+		return &logSender{logger: logger}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Provider {
+	case "sparrow":
+		return &sparrowSender{client: client, token: cfg.Token, from: cfg.From}
+	case "aakash":
+		return &aakashSender{client: client, token: cfg.Token}
+	default:
+		return &logSender{logger: logger}
+	}
+}
+
+// localNumber strips the country code; the Nepali gateways take the
+// 10-digit number.
+func localNumber(to string) string {
+	return strings.TrimPrefix(to, "+977")
+}
+
+// ---------------- LOCAL STAND-IN ----------------
+
+type logSender struct {
+	logger *zerolog.Logger
+}
+
+func (s *logSender) Send(ctx context.Context, to, message string) error {
+	s.logger.Info().
+		Str("to", to).
+		Str("message", message).
+		Msg("sms (log sink)")
+	return nil
+}
@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const sparrowURL = "https://api.sparrowsms.com/v2/sms/"
+
+// sparrowSender sends through Sparrow SMS (sparrowsms.com).
+type sparrowSender struct {
+	client *http.Client
+	token  string
+	from   string
+}
+
+type sparrowResponse struct {
+	ResponseCode int    `json:"response_code"`
+	Response     string `json:"response"`
+}
+
+func (s *sparrowSender) Send(ctx context.Context, to, message string) error {
+	form := url.Values{
+		"token": {s.token},
+		"from":  {s.from},
+		"to":    {localNumber(to)},
+		"text":  {message},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sparrowURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sparrow sms: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var res sparrowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("sparrow sms: failed to decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || res.ResponseCode != http.StatusOK {
+		return fmt.Errorf("sparrow sms: %d %s", res.ResponseCode, res.Response)
+	}
+	return nil
+}
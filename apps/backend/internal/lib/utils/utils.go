@@ -9,7 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/gitSanje/khajaride/internal/model/coupon"
+	"github.com/gitSanje/khajaride/internal/model/referral"
 	"github.com/gitSanje/khajaride/internal/model/user"
 	"github.com/gitSanje/khajaride/internal/model/vendor"
 )
@@ -83,67 +85,77 @@ func getInt(m map[string]interface{}, key string) int {
 	return 0
 }
 
-// ---------- Interfaces & Structs ----------
-type HasValue interface {
-	GetValue() string
-	GetID() string
-}
-
-type ClerkEmail struct {
-	ID           string `json:"id"`
-	EmailAddress string `json:"email_address"`
-}
+// MapClerkUser maps a Clerk user (webhook "data" or API result) to the
+// fields mirrored in users. Instances without usernames get one derived
+// from the email, made unique with the end of the Clerk id.
+func MapClerkUser(u *clerk.User) *user.ClerkUserSync {
+	var email, phoneNumber string
+	for _, e := range u.EmailAddresses {
+		if u.PrimaryEmailAddressID != nil && e.ID == *u.PrimaryEmailAddressID {
+			email = e.EmailAddress
+		}
+	}
+	for _, p := range u.PhoneNumbers {
+		if u.PrimaryPhoneNumberID != nil && p.ID == *u.PrimaryPhoneNumberID {
+			phoneNumber = p.PhoneNumber
+		}
+	}
 
-func (c ClerkEmail) GetValue() string { return c.EmailAddress }
-func (c ClerkEmail) GetID() string    { return c.ID }
+	username := ""
+	if u.Username != nil {
+		username = *u.Username
+	}
+	if username == "" {
+		local, _, _ := strings.Cut(email, "@")
+		username = fmt.Sprintf("%s_%s", local, strings.ToLower(u.ID[max(0, len(u.ID)-6):]))
+	}
 
-type ClerkPhoneNumber struct {
-	ID          string `json:"id"`
-	PhoneNumber string `json:"phone_number"`
-}
+	var lastSignInAt *time.Time
+	if u.LastSignInAt != nil {
+		t := time.UnixMilli(*u.LastSignInAt)
+		lastSignInAt = &t
+	}
 
-func (c ClerkPhoneNumber) GetValue() string { return c.PhoneNumber }
-func (c ClerkPhoneNumber) GetID() string    { return c.ID }
+	var profilePicture *string
+	if u.HasImage {
+		profilePicture = u.ImageURL
+	}
 
-// ---------- Generic Helper ----------
-func findPrimaryValue[T HasValue](data []T, primaryID string) string {
-	for _, item := range data {
-		if item.GetID() == primaryID {
-			return item.GetValue()
-		}
+	return &user.ClerkUserSync{
+		ID:             u.ID,
+		Email:          email,
+		Username:       username,
+		PhoneNumber:    strPtr(phoneNumber),
+		ProfilePicture: profilePicture,
+		LastSignInAt:   lastSignInAt,
+		ClerkUpdatedAt: u.UpdatedAt,
 	}
-	return ""
 }
 
-// MapClerkUserToCreateUser maps the "data" field from a Clerk webhook payload to CreateUserPayload
-func MapClerkUserToCreateUser(data json.RawMessage) (*user.CreateUserPayload, error) {
+// MapClerkReferralAttribution reads the referral code (and the device id used
+// for fraud checks) that the sign-up form stores in Clerk's unsafe_metadata.
+// It returns nil when the user did not sign up with a code.
+func MapClerkReferralAttribution(u *clerk.User) (*referral.SignupAttribution, error) {
 
-	var clerkUser struct {
-		ID                    *string            `json:"id"`
-		EmailAddresses        []ClerkEmail       `json:"email_addresses"`
-		PhoneNumbers          []ClerkPhoneNumber `json:"phone_numbers"`
-		ProfileImage          *string            `json:"image_url"`
-		Username              string             `json:"username"`
-		PrimaryEmailAddressID string             `json:"primary_email_address_id"`
-		PrimaryPhoneNumberID  string             `json:"primary_phone_number_id"`
+	var metadata struct {
+		ReferralCode string `json:"referralCode"`
+		DeviceID     string `json:"deviceId"`
 	}
-
-	// Unmarshal only the data portion
-	if err := json.Unmarshal(data, &clerkUser); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Clerk user data: %w", err)
+	if len(u.UnsafeMetadata) > 0 {
+		if err := json.Unmarshal(u.UnsafeMetadata, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Clerk unsafe_metadata: %w", err)
+		}
 	}
 
-	email := findPrimaryValue(clerkUser.EmailAddresses, clerkUser.PrimaryEmailAddressID)
-	phoneNumber := findPrimaryValue(clerkUser.PhoneNumbers, clerkUser.PrimaryPhoneNumberID)
+	code := strings.TrimSpace(metadata.ReferralCode)
+	if code == "" {
+		return nil, nil
+	}
 
-	return &user.CreateUserPayload{
-		ID:             clerkUser.ID,
-		Email:          email,
-		Username:       clerkUser.Username,
-		PhoneNumber:    strPtr(phoneNumber),
-		Password:       nil,
-		Role:           "user",
-		ProfilePicture: clerkUser.ProfileImage,
+	return &referral.SignupAttribution{
+		Code:     code,
+		Phone:    MapClerkUser(u).PhoneNumber,
+		DeviceID: strPtr(strings.TrimSpace(metadata.DeviceID)),
 	}, nil
 }
 
@@ -277,7 +289,7 @@ func TransformFoodManduMenuItems(flatJSON []byte) ([]vendor.MenuItem, []vendor.M
 					mItem := vendor.MenuItem{
 						ID:          strconv.Itoa(itemId),
 						Name:        getString(itemMap, "name"),
-						VendorID:    vendorId,
+						VendorID:    &vendorId,
 						Description: getString(itemMap, "productDesc"),
 						Image:       getString(itemMap, "ProductImage"),
 						BasePrice:   getFloat(itemMap, "price"),
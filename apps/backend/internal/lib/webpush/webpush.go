@@ -0,0 +1,103 @@
+package webpush
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	webpushgo "github.com/SherClockHolmes/webpush-go"
+	"github.com/gitSanje/khajaride/internal/config"
+)
+
+// ErrSubscriptionGone is returned when the push service no longer knows the
+// subscription (404/410); it should be deleted.
+var ErrSubscriptionGone = errors.New("push subscription is gone")
+
+// Subscription is a browser's PushSubscription: the endpoint and its
+// base64url keys.
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	P256dh   string `json:"p256dh"`
+	Auth     string `json:"auth"`
+}
+
+// Client sends Web Push messages (RFC 8030). Encryption (RFC 8291) and VAPID
+// signing (RFC 8292) are done by webpush-go.
+type Client struct {
+	http       *http.Client
+	publicKey  string
+	privateKey string
+	subject    string
+}
+
+// NewClient returns nil when push is not configured.
+func NewClient(cfg *config.PushConfig) (*Client, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	pub, err := decodeKey(cfg.VAPIDPublicKey)
+	if err != nil || len(pub) != 65 || pub[0] != 0x04 {
+		return nil, fmt.Errorf("invalid vapid public key")
+	}
+	priv, err := decodeKey(cfg.VAPIDPrivateKey)
+	if err != nil || len(priv) != 32 {
+		return nil, fmt.Errorf("invalid vapid private key")
+	}
+
+	return &Client{
+		http:       &http.Client{Timeout: 10 * time.Second},
+		publicKey:  cfg.VAPIDPublicKey,
+		privateKey: cfg.VAPIDPrivateKey,
+		subject:    cfg.Subject,
+	}, nil
+}
+
+// PublicKey is the application server key browsers subscribe with.
+func (c *Client) PublicKey() string {
+	return c.publicKey
+}
+
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, ttl time.Duration) error {
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" {
+		return fmt.Errorf("invalid push endpoint")
+	}
+
+	resp, err := webpushgo.SendNotificationWithContext(ctx, payload, &webpushgo.Subscription{
+		Endpoint: sub.Endpoint,
+		Keys:     webpushgo.Keys{P256dh: sub.P256dh, Auth: sub.Auth},
+	}, &webpushgo.Options{
+		HTTPClient:      c.http,
+		Subscriber:      c.subject,
+		TTL:             int(ttl.Seconds()),
+		Urgency:         webpushgo.UrgencyHigh,
+		VAPIDPublicKey:  c.publicKey,
+		VAPIDPrivateKey: c.privateKey,
+	})
+	if err != nil {
+		return fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// decodeKey accepts base64url with or without padding, as browsers and key
+// generators differ.
+func decodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/clerk/clerk-sdk-go/v2"
 	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
 	"github.com/gitSanje/khajaride/internal/errs"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/gitSanje/khajaride/internal/server"
 	"github.com/labstack/echo/v4"
 )
@@ -72,3 +73,102 @@ func (auth *AuthMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc
 		return next(c)
 	})
 }
+
+// RequireAdmin must run after RequireAuth. It checks the role stored on the
+// user's row rather than the Clerk organization role.
+func (auth *AuthMiddleware) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := GetUserID(c)
+
+		var role string
+		err := auth.server.DB.Pool.QueryRow(c.Request().Context(),
+			`SELECT role FROM users WHERE id = $1`, userID,
+		).Scan(&role)
+		if err != nil || role != "admin" {
+			auth.server.Logger.Warn().
+				Str("function", "RequireAdmin").
+				Str("user_id", userID).
+				Str("request_id", GetRequestID(c)).
+				Msg("admin access denied")
+			return errs.NewForbiddenError("Admin access required", false)
+		}
+
+		return next(c)
+	}
+}
+
+// VendorHeader selects the vendor to act for when the user is a member of
+// more than one.
+const VendorHeader = "X-Vendor-ID"
+
+// RequireVendorPermission must run after RequireAuth. It resolves the vendor
+// the user acts for through their membership and checks that their role
+// there grants permission; an empty permission admits any member. The
+// vendor is then available through GetVendorID.
+func (auth *AuthMiddleware) RequireVendorPermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID := GetUserID(c)
+			vendorID := c.Request().Header.Get(VendorHeader)
+
+			rows, err := auth.server.DB.Pool.Query(c.Request().Context(), `
+				SELECT m.vendor_id, m.role, v.vendor_user_id
+				FROM vendor_members m
+				JOIN vendors v ON v.id = m.vendor_id
+				WHERE m.user_id = $1 AND ($2 = '' OR m.vendor_id = $2)
+				ORDER BY m.created_at
+				LIMIT 2
+			`, userID, vendorID)
+			if err != nil {
+				return err
+			}
+			type membership struct {
+				vendorID, role, ownerID string
+			}
+			var found []membership
+			for rows.Next() {
+				var m membership
+				if err := rows.Scan(&m.vendorID, &m.role, &m.ownerID); err != nil {
+					rows.Close()
+					return err
+				}
+				found = append(found, m)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			if len(found) == 0 {
+				auth.server.Logger.Warn().
+					Str("function", "RequireVendorPermission").
+					Str("user_id", userID).
+					Str("vendor_id", vendorID).
+					Str("request_id", GetRequestID(c)).
+					Msg("vendor access denied")
+				return errs.NewForbiddenError("Vendor access required", false)
+			}
+			if len(found) > 1 {
+				return errs.NewBadRequestError("You work at more than one vendor; choose one with the "+VendorHeader+" header", false, nil, nil, nil)
+			}
+
+			m := found[0]
+			if permission != "" && !vendor.HasPermission(m.role, permission) {
+				auth.server.Logger.Warn().
+					Str("function", "RequireVendorPermission").
+					Str("user_id", userID).
+					Str("vendor_id", m.vendorID).
+					Str("vendor_role", m.role).
+					Str("permission", permission).
+					Str("request_id", GetRequestID(c)).
+					Msg("vendor permission denied")
+				return errs.NewForbiddenError("Your role at this vendor does not allow this", false)
+			}
+
+			c.Set(VendorIDKey, m.vendorID)
+			c.Set(VendorRoleKey, m.role)
+			c.Set(VendorOwnerIDKey, m.ownerID)
+			return next(c)
+		}
+	}
+}
@@ -11,9 +11,12 @@ import (
 )
 
 const (
-	UserIDKey   = "user_id"
-	UserRoleKey = "user_role"
-	LoggerKey   = "logger"
+	UserIDKey        = "user_id"
+	UserRoleKey      = "user_role"
+	LoggerKey        = "logger"
+	VendorIDKey      = "vendor_id"
+	VendorRoleKey    = "vendor_role"
+	VendorOwnerIDKey = "vendor_owner_id"
 )
 
 type ContextEnhancer struct {
@@ -87,6 +90,30 @@ func GetUserID(c echo.Context) string {
 	return ""
 }
 
+// GetVendorID returns the vendor resolved by RequireVendorPermission.
+func GetVendorID(c echo.Context) string {
+	if vendorID, ok := c.Get(VendorIDKey).(string); ok {
+		return vendorID
+	}
+	return ""
+}
+
+func GetVendorRole(c echo.Context) string {
+	if role, ok := c.Get(VendorRoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// GetVendorOwnerID returns the owner account of the resolved vendor, which
+// payouts, earnings and verification documents are keyed to.
+func GetVendorOwnerID(c echo.Context) string {
+	if ownerID, ok := c.Get(VendorOwnerIDKey).(string); ok {
+		return ownerID
+	}
+	return ""
+}
+
 func GetLogger(c echo.Context) *zerolog.Logger {
 	if logger, ok := c.Get(LoggerKey).(*zerolog.Logger); ok {
 		return logger
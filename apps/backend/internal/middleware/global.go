@@ -38,6 +38,7 @@ func (global *GlobalMiddlewares) CORS() echo.MiddlewareFunc {
       echo.HeaderContentType,
       echo.HeaderAccept,
       echo.HeaderAuthorization,
+      VendorHeader,
     },
     AllowCredentials: true,
 	})
@@ -0,0 +1,140 @@
+package analytics
+
+import "time"
+
+// Granularities of the sales timeseries
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// =========================
+// Limits
+// =========================
+const (
+	DefaultRangeDays = 30
+	MaxRangeDays     = 366
+	// hourly series are only served for short ranges
+	MaxHourlyRangeDays = 31
+	TopItemsLimit      = 10
+
+	// RefreshName keys the rollups' high-water mark in analytics_refresh_state
+	RefreshName = "vendor_analytics"
+	// RefreshOverlap re-reads changes this far behind the mark, for
+	// transactions that committed after a refresh had already started
+	RefreshOverlap = 10 * time.Minute
+)
+
+// Summary is a vendor's sales over a range. Revenue, the average order value
+// and items sold cover delivered orders; the cancellation rate is over all
+// orders placed. A returning customer had ordered before the range or
+// ordered more than once in it.
+type Summary struct {
+	Orders             int     `json:"orders" db:"orders"`
+	DeliveredOrders    int     `json:"deliveredOrders" db:"delivered_orders"`
+	CancelledOrders    int     `json:"cancelledOrders" db:"cancelled_orders"`
+	Revenue            float64 `json:"revenue" db:"revenue"`
+	ItemsSold          int     `json:"itemsSold" db:"items_sold"`
+	AverageOrderValue  float64 `json:"averageOrderValue" db:"-"`
+	CancellationRate   float64 `json:"cancellationRate" db:"-"`
+	Customers          int     `json:"customers" db:"-"`
+	ReturningCustomers int     `json:"returningCustomers" db:"-"`
+	RepeatCustomerRate float64 `json:"repeatCustomerRate" db:"-"`
+}
+
+// Point is one period of the timeseries, labelled by its local start
+// ("2006-01-02" or "2006-01-02 15:00" for hours).
+type Point struct {
+	Period            string  `json:"period" db:"period"`
+	Orders            int     `json:"orders" db:"orders"`
+	DeliveredOrders   int     `json:"deliveredOrders" db:"delivered_orders"`
+	CancelledOrders   int     `json:"cancelledOrders" db:"cancelled_orders"`
+	Revenue           float64 `json:"revenue" db:"revenue"`
+	AverageOrderValue float64 `json:"averageOrderValue" db:"average_order_value"`
+}
+
+type TopItem struct {
+	MenuItemID string  `json:"menuItemId" db:"menu_item_id"`
+	Name       string  `json:"name" db:"name"`
+	Image      *string `json:"image" db:"image"`
+	Orders     int     `json:"orders" db:"orders"`
+	Quantity   int     `json:"quantity" db:"quantity"`
+	Revenue    float64 `json:"revenue" db:"revenue"`
+}
+
+// HeatmapCell is the orders placed in one local hour of one weekday over the
+// range (Weekday 1 = Monday ... 7 = Sunday). Empty cells are left out.
+type HeatmapCell struct {
+	Weekday int     `json:"weekday" db:"weekday"`
+	Hour    int     `json:"hour" db:"hour"`
+	Orders  int     `json:"orders" db:"orders"`
+	Revenue float64 `json:"revenue" db:"revenue"`
+}
+
+// Payouts is what the vendor earned over the range after commission and
+// refunds, what was paid out to them in it, and what is earned but not yet
+// batched into a settlement.
+type Payouts struct {
+	Gross      float64 `json:"gross" db:"gross"`
+	Commission float64 `json:"commission" db:"commission"`
+	Refunds    float64 `json:"refunds" db:"refunds"`
+	Net        float64 `json:"net" db:"net"`
+	PaidOut    float64 `json:"paidOut" db:"paid_out"`
+	Unsettled  float64 `json:"unsettled" db:"unsettled"`
+}
+
+// Report is the analytics of one or more vendors over a range.
+type Report struct {
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Granularity string        `json:"granularity"`
+	Timezone    string        `json:"timezone"`
+	Summary     Summary       `json:"summary"`
+	Timeseries  []Point       `json:"timeseries"`
+	TopItems    []TopItem     `json:"topItems"`
+	Heatmap     []HeatmapCell `json:"heatmap"`
+	Payouts     Payouts       `json:"payouts"`
+	// RefreshedThrough is how fresh the rollups are; nil before the first
+	// refresh
+	RefreshedThrough *time.Time `json:"refreshedThrough"`
+}
+
+type VendorAnalytics struct {
+	VendorID string `json:"vendorId"`
+	Report
+}
+
+// OutletSales is one outlet's share of its brand's sales over the range.
+type OutletSales struct {
+	VendorID        string  `json:"vendorId" db:"vendor_id"`
+	OutletName      *string `json:"outletName" db:"outlet_name"`
+	Orders          int     `json:"orders" db:"orders"`
+	DeliveredOrders int     `json:"deliveredOrders" db:"delivered_orders"`
+	Revenue         float64 `json:"revenue" db:"revenue"`
+	Net             float64 `json:"net" db:"net"`
+}
+
+// BrandAnalytics rolls the analytics of all of a brand's outlets up into one
+// report; a customer of several outlets counts once.
+type BrandAnalytics struct {
+	BrandID string `json:"brandId"`
+	Report
+	Outlets []OutletSales `json:"outlets"`
+}
+
+// Range is a validated analytics request: local dates, inclusive.
+type Range struct {
+	From        string
+	To          string
+	Granularity string
+	Timezone    string
+}
+
+// RefreshResult summarizes a rollup refresh: how many changed order hours
+// and earning days were recomputed.
+type RefreshResult struct {
+	SalesBuckets int64
+	EarningDays  int64
+}
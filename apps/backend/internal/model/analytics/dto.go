@@ -0,0 +1,51 @@
+package analytics
+
+import "github.com/go-playground/validator/v10"
+
+// GetAnalyticsQuery selects the range (local dates, inclusive) and the
+// timeseries granularity. Without dates it covers the last
+// DefaultRangeDays days.
+type GetAnalyticsQuery struct {
+	From        *string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To          *string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	Granularity *string `query:"granularity" validate:"omitempty,oneof=hour day week month"`
+}
+
+func (q *GetAnalyticsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Granularity == nil {
+		defaultGranularity := GranularityDay
+		q.Granularity = &defaultGranularity
+	}
+	return nil
+}
+
+type GetVendorAnalyticsQuery struct {
+	VendorID string `param:"vendorId" validate:"required"`
+	GetAnalyticsQuery
+}
+
+func (q *GetVendorAnalyticsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+	return q.GetAnalyticsQuery.Validate()
+}
+
+type GetBrandAnalyticsQuery struct {
+	BrandID string `param:"brandId" validate:"required"`
+	GetAnalyticsQuery
+}
+
+func (q *GetBrandAnalyticsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+	return q.GetAnalyticsQuery.Validate()
+}
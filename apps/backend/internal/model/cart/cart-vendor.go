@@ -13,6 +13,8 @@ type CartVendor struct {
 	VendorServiceCharge float64  `json:"vendorServiceCharge" db:"vendor_service_charge"`
 	VAT                 float64  `json:"vat" db:"vat"`
 	VendorDiscount      float64  `json:"vendorDiscount" db:"vendor_discount"`
+	LoyaltyPointsUsed   float64  `json:"loyaltyPointsUsed" db:"loyalty_points_used"`
+	LoyaltyDiscount     float64  `json:"loyaltyDiscount" db:"loyalty_discount"`
 	Total               *float64  `json:"total" db:"total"`
 	AppliedCouponCode *string `json:"appliedCouponCode,omitempty" db:"applied_coupon_code"`
 	
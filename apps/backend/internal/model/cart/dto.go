@@ -303,9 +303,27 @@ type GetCartTotalsResponse struct {
 	VAT                    float64 `json:"vat"`
 	VendorDiscount         float64 `json:"vendorDiscount"`
 	CouponDiscount         float64 `json:"couponDiscount"`
+	LoyaltyPointsUsed      float64 `json:"loyaltyPointsUsed"`
+	LoyaltyDiscount        float64 `json:"loyaltyDiscount"`
 	Total                  float64 `json:"total"`
 	EstimatedDeliveryTime  string  `json:"estimatedDeliveryTime"`
 	Currency               string  `json:"currency"`
 	AppliedCouponCode      *string  `json:"appliedCouponCode,omitempty"`
 
-}
\ No newline at end of file
+}
+
+
+
+//-- ==================================================
+//-- APPLY LOYALTY POINTS
+//-- ==================================================
+
+type ApplyLoyaltyPointsPayload struct {
+	CartVendorID string  `param:"id" validate:"required"`
+	Points       float64 `json:"points" validate:"gte=0"` // 0 removes the points
+}
+
+func (p *ApplyLoyaltyPointsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -6,6 +6,7 @@ type Coupon struct {
 	ID                string     `db:"id" json:"id"`
 	Code              string     `db:"code" json:"code"`
 	VendorID          *string    `db:"vendor_id" json:"vendorId,omitempty"` // NULL = global
+	UserID            *string    `db:"user_id" json:"userId,omitempty"`     // NULL = anyone
 	Description       *string    `db:"description" json:"description,omitempty"`
 	DiscountType      string     `db:"discount_type" json:"discountType"`   // "percent" or "flat"
 	DiscountValue     float64    `db:"discount_value" json:"discountValue"` // e.g., 20 or 100
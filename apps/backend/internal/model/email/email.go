@@ -0,0 +1,33 @@
+package email
+
+// ResendEvent is a Resend webhook delivery. Only the fields used to track
+// delivery and suppress bad addresses are read.
+type ResendEvent struct {
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"created_at"`
+	Data      ResendEventData `json:"data"`
+}
+
+type ResendEventData struct {
+	EmailID string        `json:"email_id"`
+	To      []string      `json:"to"`
+	Subject string        `json:"subject"`
+	Bounce  *ResendBounce `json:"bounce,omitempty"`
+}
+
+// ResendBounce.Type is Permanent, Transient or Undetermined; only a
+// permanent bounce means the address is bad.
+type ResendBounce struct {
+	Type    string `json:"type"`
+	SubType string `json:"subType"`
+	Message string `json:"message"`
+}
+
+const BouncePermanent = "Permanent"
+
+// Suppression reasons
+const (
+	SuppressionBounce    = "bounce"
+	SuppressionComplaint = "complaint"
+	SuppressionManual    = "manual"
+)
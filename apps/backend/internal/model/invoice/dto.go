@@ -0,0 +1,12 @@
+package invoice
+
+import "github.com/go-playground/validator/v10"
+
+type GetInvoicePayload struct {
+	OrderID string `param:"id" validate:"required"`
+}
+
+func (p *GetInvoicePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,47 @@
+package invoice
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
+
+type Invoice struct {
+	model.Base
+	OrderID           string    `json:"orderId" db:"order_id"`
+	VendorID          string    `json:"vendorId" db:"vendor_id"`
+	UserID            string    `json:"userId" db:"user_id"`
+	SequenceNumber    int       `json:"sequenceNumber" db:"sequence_number"`
+	InvoiceNumber     string    `json:"invoiceNumber" db:"invoice_number"`
+	VendorName        string    `json:"vendorName" db:"vendor_name"`
+	VendorPanVat      *string   `json:"vendorPanVat,omitempty" db:"vendor_pan_vat"`
+	VendorAddress     *string   `json:"vendorAddress,omitempty" db:"vendor_address"`
+	CustomerName      *string   `json:"customerName,omitempty" db:"customer_name"`
+	CustomerEmail     *string   `json:"customerEmail,omitempty" db:"customer_email"`
+	Subtotal          float64   `json:"subtotal" db:"subtotal"`
+	DeliveryCharge    float64   `json:"deliveryCharge" db:"delivery_charge"`
+	ServiceCharge     float64   `json:"serviceCharge" db:"service_charge"`
+	ServiceChargeRate float64   `json:"serviceChargeRate" db:"service_charge_rate"`
+	Vat               float64   `json:"vat" db:"vat"`
+	VatRate           float64   `json:"vatRate" db:"vat_rate"`
+	Discount          float64   `json:"discount" db:"discount"`
+	LoyaltyDiscount   float64   `json:"loyaltyDiscount" db:"loyalty_discount"`
+	Total             float64   `json:"total" db:"total"`
+	Currency          string    `json:"currency" db:"currency"`
+	FileKey           *string   `json:"-" db:"file_key"`
+	IssuedAt          time.Time `json:"issuedAt" db:"issued_at"`
+}
+
+type InvoiceLine struct {
+	Name           string  `json:"name" db:"name"`
+	Quantity       int     `json:"quantity" db:"quantity"`
+	UnitPrice      float64 `json:"unitPrice" db:"unit_price"`
+	DiscountAmount float64 `json:"discountAmount" db:"discount_amount"`
+	Subtotal       float64 `json:"subtotal" db:"subtotal"`
+}
+
+// InvoiceDocument is everything needed to render an invoice.
+type InvoiceDocument struct {
+	Invoice Invoice       `json:"invoice"`
+	Lines   []InvoiceLine `json:"lines"`
+}
@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type GetTrialBalanceQuery struct {
+	AsOf *string `query:"asOf" validate:"omitempty,datetime=2006-01-02"` // inclusive, defaults to now
+}
+
+func (q *GetTrialBalanceQuery) Validate() error {
+	validate := validator.New()
+	return validate.Struct(q)
+}
+
+// Cutoff returns the moment the trial balance is taken at: the end of the
+// asOf day, or now.
+func (q *GetTrialBalanceQuery) Cutoff() time.Time {
+	if q.AsOf == nil {
+		return time.Now()
+	}
+	day, _ := time.Parse("2006-01-02", *q.AsOf)
+	return day.AddDate(0, 0, 1)
+}
+
+type GetAccountBalancesQuery struct {
+	OwnerType *string `query:"ownerType" validate:"omitempty,oneof=platform vendor customer gateway"`
+	OwnerID   *string `query:"ownerId"`
+}
+
+func (q *GetAccountBalancesQuery) Validate() error {
+	validate := validator.New()
+	return validate.Struct(q)
+}
+
+type GetVendorBalancePayload struct {
+	VendorUserID string `param:"vendorUserId" validate:"required"`
+}
+
+func (p *GetVendorBalancePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type VendorBalanceResponse struct {
+	VendorUserID string  `json:"vendorUserId"`
+	Payable      float64 `json:"payable"` // what the platform owes the vendor right now
+}
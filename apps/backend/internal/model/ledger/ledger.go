@@ -0,0 +1,143 @@
+package ledger
+
+import "time"
+
+// =========================
+// Account types & owners
+// =========================
+const (
+	AccountTypeAsset     = "asset"
+	AccountTypeLiability = "liability"
+	AccountTypeRevenue   = "revenue"
+	AccountTypeExpense   = "expense"
+	AccountTypeEquity    = "equity"
+)
+
+const (
+	EntryPaymentCapture   = "payment_capture"
+	EntryCommission       = "commission"
+	EntryRefund           = "refund"
+	EntryRefundDisbursed  = "refund_disbursed"
+	EntryPayout           = "payout"
+	EntryAdjustment       = "adjustment"
+	EntryTransfer         = "transfer"
+	EntryTransferReversal = "transfer_reversal"
+)
+
+type Account struct {
+	ID          string    `json:"id" db:"id"`
+	Code        string    `json:"code" db:"code"`
+	Name        string    `json:"name" db:"name"`
+	AccountType string    `json:"accountType" db:"account_type"` // asset, liability, revenue, expense, equity
+	OwnerType   string    `json:"ownerType" db:"owner_type"`     // platform, vendor, customer, gateway
+	OwnerID     *string   `json:"ownerId,omitempty" db:"owner_id"`
+	Currency    string    `json:"currency" db:"currency"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+// DebitNormal reports whether the account grows with debits (assets and
+// expenses) rather than credits (liabilities, revenue and equity).
+func (a Account) DebitNormal() bool {
+	return a.AccountType == AccountTypeAsset || a.AccountType == AccountTypeExpense
+}
+
+// PlatformRevenueAccount holds the commission the platform has earned.
+func PlatformRevenueAccount() Account {
+	return Account{Code: "platform:revenue", Name: "Platform commission revenue", AccountType: AccountTypeRevenue, OwnerType: "platform"}
+}
+
+// PlatformAdjustmentsAccount is the expense side of manual vendor adjustments.
+func PlatformAdjustmentsAccount() Account {
+	return Account{Code: "platform:adjustments", Name: "Vendor adjustments", AccountType: AccountTypeExpense, OwnerType: "platform"}
+}
+
+// GatewayClearingAccount is the money sitting with a payment gateway.
+func GatewayClearingAccount(gateway string) Account {
+	return Account{Code: "gateway_clearing:" + gateway, Name: "Gateway clearing (" + gateway + ")", AccountType: AccountTypeAsset, OwnerType: "gateway", OwnerID: &gateway}
+}
+
+// VendorPayableAccount is what the platform owes a vendor.
+func VendorPayableAccount(vendorUserID string) Account {
+	return Account{Code: "vendor_payable:" + vendorUserID, Name: "Vendor payable", AccountType: AccountTypeLiability, OwnerType: "vendor", OwnerID: &vendorUserID}
+}
+
+// CustomerWalletAccount is what the platform owes a customer, e.g. a refund
+// that has not been sent back yet.
+func CustomerWalletAccount(userID string) Account {
+	return Account{Code: "customer_wallet:" + userID, Name: "Customer wallet", AccountType: AccountTypeLiability, OwnerType: "customer", OwnerID: &userID}
+}
+
+// =========================
+// Journal
+// =========================
+
+type JournalEntry struct {
+	ID             string        `json:"id" db:"id"`
+	EntryType      string        `json:"entryType" db:"entry_type"`
+	Description    *string       `json:"description,omitempty" db:"description"`
+	ReferenceType  *string       `json:"referenceType,omitempty" db:"reference_type"`
+	ReferenceID    *string       `json:"referenceId,omitempty" db:"reference_id"`
+	IdempotencyKey string        `json:"idempotencyKey" db:"idempotency_key"`
+	Currency       string        `json:"currency" db:"currency"`
+	CreatedAt      time.Time     `json:"createdAt" db:"created_at"`
+	Lines          []JournalLine `json:"lines,omitempty" db:"-"`
+}
+
+type JournalLine struct {
+	ID        string    `json:"id" db:"id"`
+	EntryID   string    `json:"entryId" db:"entry_id"`
+	AccountID string    `json:"accountId" db:"account_id"`
+	Debit     float64   `json:"debit" db:"debit"`
+	Credit    float64   `json:"credit" db:"credit"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Posting is one side of an entry before it is written: the account is
+// created on first use.
+type Posting struct {
+	Account Account
+	Debit   float64
+	Credit  float64
+}
+
+type NewEntry struct {
+	EntryType      string
+	Description    string
+	ReferenceType  string
+	ReferenceID    string
+	IdempotencyKey string
+	Currency       string
+	Postings       []Posting
+}
+
+// =========================
+// Reports
+// =========================
+
+type AccountBalance struct {
+	Account
+	Debit   float64 `json:"debit" db:"debit"`
+	Credit  float64 `json:"credit" db:"credit"`
+	Balance float64 `json:"balance" db:"-"` // in the account's normal direction
+}
+
+type TrialBalance struct {
+	AsOf        time.Time        `json:"asOf"`
+	Accounts    []AccountBalance `json:"accounts"`
+	TotalDebit  float64          `json:"totalDebit"`
+	TotalCredit float64          `json:"totalCredit"`
+	Balanced    bool             `json:"balanced"`
+}
+
+type UnbalancedEntry struct {
+	EntryID string  `json:"entryId" db:"entry_id"`
+	Debit   float64 `json:"debit" db:"debit"`
+	Credit  float64 `json:"credit" db:"credit"`
+}
+
+type InvariantReport struct {
+	EntriesChecked    int               `json:"entriesChecked"`
+	UnbalancedEntries []UnbalancedEntry `json:"unbalancedEntries"`
+	EmptyEntries      []string          `json:"emptyEntries"`
+	Ok                bool              `json:"ok"`
+}
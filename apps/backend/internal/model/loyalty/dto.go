@@ -0,0 +1,82 @@
+package loyalty
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type UpdateSettingsPayload struct {
+	PointsPerNPR     *float64 `json:"pointsPerNpr" validate:"omitempty,gte=0"`
+	ExpiryDays       *int     `json:"expiryDays" validate:"omitempty,min=1"`
+	TierWindowDays   *int     `json:"tierWindowDays" validate:"omitempty,min=1"`
+	NPRPerPoint      *float64 `json:"nprPerPoint" validate:"omitempty,gt=0"`
+	MaxRedeemPercent *float64 `json:"maxRedeemPercent" validate:"omitempty,gte=0,lte=100"`
+}
+
+func (p *UpdateSettingsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type CreatePromotionPayload struct {
+	Name       string    `json:"name" validate:"required,min=3,max=255"`
+	Multiplier float64   `json:"multiplier" validate:"required,gt=0,lte=10"`
+	VendorID   *string   `json:"vendorId"`
+	StartsAt   time.Time `json:"startsAt" validate:"required"`
+	EndsAt     time.Time `json:"endsAt" validate:"required"`
+}
+
+func (p *CreatePromotionPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+	if !p.EndsAt.After(p.StartsAt) {
+		return errors.New("endsAt must be after startsAt")
+	}
+	return nil
+}
+
+type UpdatePromotionPayload struct {
+	ID         string     `param:"id" validate:"required"`
+	Name       *string    `json:"name" validate:"omitempty,min=3,max=255"`
+	Multiplier *float64   `json:"multiplier" validate:"omitempty,gt=0,lte=10"`
+	StartsAt   *time.Time `json:"startsAt"`
+	EndsAt     *time.Time `json:"endsAt"`
+	IsActive   *bool      `json:"isActive"`
+}
+
+func (p *UpdatePromotionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetPromotionsQuery struct {
+	ActiveOnly *bool `query:"activeOnly"`
+}
+
+func (q *GetPromotionsQuery) Validate() error {
+	validate := validator.New()
+	return validate.Struct(q)
+}
+
+type SetVendorMultiplierPayload struct {
+	VendorID   string  `param:"vendorId" validate:"required"`
+	Multiplier float64 `json:"multiplier" validate:"required,gt=0,lte=10"`
+}
+
+func (p *SetVendorMultiplierPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type AwardOrderPointsPayload struct {
+	OrderID string `param:"id" validate:"required"`
+}
+
+func (p *AwardOrderPointsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,81 @@
+package loyalty
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
+
+// =========================
+// Ledger transaction & reference types
+// =========================
+const (
+	TransactionEarn   = "EARN"
+	TransactionRedeem = "REDEEM"
+	TransactionAdjust = "ADJUST"
+	TransactionExpire = "EXPIRE"
+
+	ReferenceTypeOrder         = "ORDER"
+	ReferenceTypeOrderReversal = "ORDER_REVERSAL"
+)
+
+// Settings are the program-wide earning rules.
+type Settings struct {
+	PointsPerNPR   float64 `json:"pointsPerNpr" db:"points_per_npr"`
+	ExpiryDays     int     `json:"expiryDays" db:"expiry_days"`
+	TierWindowDays int     `json:"tierWindowDays" db:"tier_window_days"`
+	// NPRPerPoint is what one point is worth at checkout
+	NPRPerPoint      float64   `json:"nprPerPoint" db:"npr_per_point"`
+	MaxRedeemPercent float64   `json:"maxRedeemPercent" db:"max_redeem_percent"` // of the subtotal
+	UpdatedAt        time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type Tier struct {
+	Code           string  `json:"code" db:"code"`
+	Name           string  `json:"name" db:"name"`
+	MinSpend       float64 `json:"minSpend" db:"min_spend"`
+	EarnMultiplier float64 `json:"earnMultiplier" db:"earn_multiplier"`
+}
+
+// Promotion multiplies the points earned on orders delivered inside its
+// window, for one vendor or, without a vendor, for all of them.
+type Promotion struct {
+	model.Base
+	Name       string    `json:"name" db:"name"`
+	Multiplier float64   `json:"multiplier" db:"multiplier"`
+	VendorID   *string   `json:"vendorId,omitempty" db:"vendor_id"`
+	StartsAt   time.Time `json:"startsAt" db:"starts_at"`
+	EndsAt     time.Time `json:"endsAt" db:"ends_at"`
+	IsActive   bool      `json:"isActive" db:"is_active"`
+	CreatedBy  *string   `json:"createdBy,omitempty" db:"created_by"`
+}
+
+// EarnRule is how the points of one order were worked out.
+type EarnRule struct {
+	OrderTotal          float64 `json:"orderTotal"`
+	PointsPerNPR        float64 `json:"pointsPerNpr"`
+	VendorMultiplier    float64 `json:"vendorMultiplier"`
+	TierMultiplier      float64 `json:"tierMultiplier"`
+	PromotionMultiplier float64 `json:"promotionMultiplier"`
+}
+
+// Points is the total rounded down to whole points.
+func (r EarnRule) Points() float64 {
+	points := r.OrderTotal * r.PointsPerNPR * r.VendorMultiplier * r.TierMultiplier * r.PromotionMultiplier
+	return float64(int64(points))
+}
+
+type Status struct {
+	Balance         float64    `json:"balance"`
+	Tier            Tier       `json:"tier"`
+	NextTier        *Tier      `json:"nextTier,omitempty"`
+	RollingSpend    float64    `json:"rollingSpend"`
+	SpendToNextTier float64    `json:"spendToNextTier"`
+	ExpiringPoints  float64    `json:"expiringPoints"` // within the next 30 days
+	NextExpiryAt    *time.Time `json:"nextExpiryAt,omitempty"`
+}
+
+type ExpiryResult struct {
+	Users  int     `json:"users"`
+	Points float64 `json:"points"`
+}
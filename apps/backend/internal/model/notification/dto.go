@@ -0,0 +1,68 @@
+package notification
+
+import "github.com/go-playground/validator/v10"
+
+type GetInboxQuery struct {
+	Page   *int  `query:"page" validate:"omitempty,min=1"`
+	Limit  *int  `query:"limit" validate:"omitempty,min=1,max=100"`
+	Unread *bool `query:"unread"`
+}
+
+func (q *GetInboxQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+	return nil
+}
+
+type MarkReadPayload struct {
+	ID string `param:"id" validate:"required"`
+}
+
+func (p *MarkReadPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type UpdatePreferencesPayload struct {
+	EmailEnabled *bool `json:"emailEnabled"`
+	SMSEnabled   *bool `json:"smsEnabled"`
+	PushEnabled  *bool `json:"pushEnabled"`
+}
+
+func (p *UpdatePreferencesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// SubscribePushPayload is the browser's PushSubscription.toJSON().
+type SubscribePushPayload struct {
+	Endpoint string `json:"endpoint" validate:"required,url,startswith=https://"`
+	Keys     struct {
+		P256dh string `json:"p256dh" validate:"required"`
+		Auth   string `json:"auth" validate:"required"`
+	} `json:"keys"`
+}
+
+func (p *SubscribePushPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type DeletePushSubscriptionPayload struct {
+	ID string `param:"id" validate:"required"`
+}
+
+func (p *DeletePushSubscriptionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
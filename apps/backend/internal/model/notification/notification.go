@@ -0,0 +1,145 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
+
+// =========================
+// Channels, recipients & statuses
+// =========================
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+	ChannelInApp = "in_app"
+
+	RecipientUser   = "user"
+	RecipientVendor = "vendor"
+
+	StatusPending   = "pending"
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// =========================
+// Events
+// =========================
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+	EventOrderNew         = "order.new" // to the vendor, once the order is paid
+	EventOrderCancelled   = "order.cancelled"
+)
+
+// OrderStatusEvent is the event of an order moving to status, e.g.
+// order.accepted.
+func OrderStatusEvent(status string) string {
+	return "order." + status
+}
+
+// Notification is an order_notifications row: one message to one
+// recipient over one channel.
+type Notification struct {
+	model.Base
+	OrderID        string            `json:"orderId" db:"order_id"`
+	RecipientType  string            `json:"recipientType" db:"recipient_type"`
+	RecipientID    *string           `json:"-" db:"recipient_id"`
+	EventType      string            `json:"eventType" db:"event_type"`
+	Channel        string            `json:"channel" db:"channel"`
+	Title          *string           `json:"title" db:"title"`
+	Message        string            `json:"message" db:"message"`
+	Data           map[string]string `json:"data" db:"data"`
+	DeliveryStatus string            `json:"deliveryStatus" db:"delivery_status"`
+	Attempts       int               `json:"-" db:"attempts"`
+	LastError      *string           `json:"-" db:"last_error"`
+	SentAt         *time.Time        `json:"sentAt,omitempty" db:"sent_at"`
+	ReadAt         *time.Time        `json:"readAt,omitempty" db:"read_at"`
+}
+
+// Preferences are a user's channel switches. The in-app inbox is always on.
+type Preferences struct {
+	EmailEnabled bool `json:"emailEnabled" db:"email_enabled"`
+	SMSEnabled   bool `json:"smsEnabled" db:"sms_enabled"`
+	PushEnabled  bool `json:"pushEnabled" db:"push_enabled"`
+}
+
+func DefaultPreferences() Preferences {
+	return Preferences{EmailEnabled: true, SMSEnabled: true, PushEnabled: true}
+}
+
+func (p Preferences) Allows(channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return p.EmailEnabled
+	case ChannelSMS:
+		return p.SMSEnabled
+	case ChannelPush:
+		return p.PushEnabled
+	}
+	return true
+}
+
+type PushSubscription struct {
+	model.Base
+	UserID    string  `json:"-" db:"user_id"`
+	Endpoint  string  `json:"endpoint" db:"endpoint"`
+	P256dh    string  `json:"-" db:"p256dh"`
+	Auth      string  `json:"-" db:"auth"`
+	UserAgent *string `json:"userAgent,omitempty" db:"user_agent"`
+}
+
+// Recipient is where one party of an order is reached on each channel.
+// Empty addresses leave the channel out.
+type Recipient struct {
+	Type        string
+	UserID      string
+	Name        string
+	Email       string
+	Phone       string
+	Preferences Preferences
+}
+
+// OrderContext is what the templates and recipients of an order event are
+// built from.
+type OrderContext struct {
+	OrderID       string  `db:"order_id"`
+	Total         float64 `db:"total"`
+	Currency      string  `db:"currency"`
+	VendorName    string  `db:"vendor_name"`
+	UserID        string  `db:"user_id"`
+	CustomerName  string  `db:"customer_name"`
+	CustomerEmail *string `db:"customer_email"`
+	CustomerPhone *string `db:"customer_phone"`
+	VendorUserID  *string `db:"vendor_user_id"`
+	VendorEmail   *string `db:"vendor_email"`
+	VendorPhone   *string `db:"vendor_phone"`
+	ItemCount     int     `db:"item_count"`
+
+	PaymentStatus        string  `db:"payment_status"`
+	DeliveryInstructions *string `db:"delivery_instructions"`
+}
+
+// OrderReceipt is what the payment receipt of a delivered order shows. The
+// payment is the order's own or, for a multi-vendor cart, the group's.
+type OrderReceipt struct {
+	OrderID        string     `db:"order_id"`
+	CustomerName   string     `db:"customer_name"`
+	CustomerEmail  *string    `db:"customer_email"`
+	VendorName     string     `db:"vendor_name"`
+	Subtotal       float64    `db:"subtotal"`
+	DeliveryCharge float64    `db:"delivery_charge"`
+	Fees           float64    `db:"fees"`
+	Discount       float64    `db:"discount"`
+	Total          float64    `db:"total"`
+	Currency       string     `db:"currency"`
+	PaymentMethod  *string    `db:"payment_method"`
+	TransactionRef *string    `db:"transaction_ref"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
+
+type InboxSummary struct {
+	Unread int `json:"unread"`
+}
@@ -19,6 +19,7 @@ type CreateOrderPayload struct {
 	DeliveryAddressId    string         `json:"deliveryAddressId" validate:"required"`
 	DeliveryInstructions string         `json:"deliveryInstructions"`
 	ExpectedDeliveryTime string         `json:"expectedDeliveryTime"`
+	ContactPhone         *string        `json:"-"` // the user's verified number, set by the service
 }
 
 func (p *CreateOrderPayload) Validate() error {
@@ -36,3 +37,47 @@ func (p *GetOrderByIDPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
 }
+
+
+type CheckoutCartPayload struct {
+	DeliveryAddressId    string  `json:"deliveryAddressId" validate:"required"`
+	DeliveryInstructions string  `json:"deliveryInstructions"`
+	ExpectedDeliveryTime string  `json:"expectedDeliveryTime"`
+}
+
+func (p *CheckoutCartPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+
+type GetOrderGroupByIDPayload struct {
+	ID string `param:"id" validate:"required"`
+}
+
+func (p *GetOrderGroupByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+
+type UpdateOrderStatusPayload struct {
+	ID     string `param:"id" validate:"required"`
+	Status string `json:"status" validate:"required,oneof=accepted preparing ready_for_pickup assigned picked_up delivered"`
+}
+
+func (p *UpdateOrderStatusPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+
+type RejectOrderPayload struct {
+	ID     string  `param:"id" validate:"required"`
+	Reason *string `json:"reason"`
+}
+
+func (p *RejectOrderPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
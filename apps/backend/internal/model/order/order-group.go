@@ -9,4 +9,20 @@ type OrderGroup struct {
 	Total         float64 `json:"total" db:"total"`
 	Currency      string  `json:"currency" db:"currency"`
 	PaymentStatus string  `json:"paymentStatus" db:"payment_status"`
+	CartSessionID *string `json:"cartSessionId,omitempty" db:"cart_session_id"`
+}
+
+// GroupOrderVendor is an order_vendors row of a group with the vendor
+// details needed to pay the vendor out.
+type GroupOrderVendor struct {
+	OrderVendor
+	VendorName   string  `json:"vendorName" db:"vendor_name"`
+	VendorUserID *string `json:"vendorUserId,omitempty" db:"vendor_user_id"`
+	// CommissionRate is the vendor's platform commission in percent
+	CommissionRate float64 `json:"-" db:"commission_rate"`
+}
+
+type PopulatedOrderGroup struct {
+	OrderGroup
+	Orders []GroupOrderVendor `json:"orders"`
 }
@@ -12,6 +12,7 @@ type OrderVendor struct {
 	model.Base
 
 	UserID               string   `json:"userId" db:"user_id"`
+	OrderGroupID         *string  `json:"orderGroupId,omitempty" db:"order_group_id"`
 	VendorCartID         *string  `json:"vendorCartId,omitempty" db:"vendor_cart_id"`
 	VendorID             string   `json:"vendorId" db:"vendor_id"`
 	Status               string   `json:"status" db:"status"`
@@ -20,10 +21,13 @@ type OrderVendor struct {
 	VendorServiceCharge  float64  `json:"vendorServiceCharge" db:"vendor_service_charge"`
 	Vat                  float64  `json:"vat" db:"vat"`
 	VendorDiscount       float64  `json:"vendorDiscount" db:"vendor_discount"`
+	LoyaltyPointsUsed    float64  `json:"loyaltyPointsUsed" db:"loyalty_points_used"`
+	LoyaltyDiscount      float64  `json:"loyaltyDiscount" db:"loyalty_discount"`
 	Total                float64  `json:"total" db:"total"`
 	Currency             string   `json:"currency" db:"currency"`
 	PaymentStatus        string   `json:"paymentStatus" db:"payment_status"`
 	FulfillmentType      string   `json:"fulfillmentType" db:"fulfillment_type"`
+	ContactPhone         *string  `json:"contactPhone,omitempty" db:"contact_phone"`
 	DeliveryAddressID    *string  `json:"deliveryAddressId,omitempty" db:"delivery_address_id"`
 	DeliveryInstructions *string  `json:"deliveryInstructions,omitempty" db:"delivery_instructions"`
 
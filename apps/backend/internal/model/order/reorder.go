@@ -0,0 +1,49 @@
+package order
+
+import "github.com/go-playground/validator/v10"
+
+type ReorderPayload struct {
+	ID string `param:"id" validate:"required"`
+}
+
+func (p *ReorderPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ReorderSource is the past order being repeated with the current state of
+// its vendor.
+type ReorderSource struct {
+	OrderVendorID string `db:"order_vendor_id"`
+	VendorID      string `db:"vendor_id"`
+	VendorName    string `db:"vendor_name"`
+	VendorIsOpen  bool   `db:"vendor_is_open"`
+}
+
+// ReorderItem is an item of the past order next to the menu item as it is
+// now. UnitPrice is what was paid, CurrentPrice what the cart is charged.
+type ReorderItem struct {
+	MenuItemID          string  `json:"menuItemId" db:"menu_item_id"`
+	Name                string  `json:"name" db:"name"`
+	Quantity            int     `json:"quantity" db:"quantity"`
+	UnitPrice           float64 `json:"unitPrice" db:"unit_price"`
+	CurrentPrice        float64 `json:"currentPrice" db:"current_price"`
+	IsAvailable         bool    `json:"-" db:"is_available"`
+	SpecialInstructions *string `json:"specialInstructions,omitempty" db:"special_instructions"`
+}
+
+// ReorderResult is the diff between the past order and the cart rebuilt from
+// it, for the customer to confirm before checking out. Nothing is added to
+// the cart when the vendor is closed.
+type ReorderResult struct {
+	OrderID          string        `json:"orderId"`
+	VendorID         string        `json:"vendorId"`
+	VendorName       string        `json:"vendorName"`
+	VendorClosed     bool          `json:"vendorClosed"`
+	CartVendorID     *string       `json:"cartVendorId,omitempty"`
+	Added            []ReorderItem `json:"added"`
+	Unavailable      []ReorderItem `json:"unavailable"`
+	PriceChanged     []ReorderItem `json:"priceChanged"`
+	OriginalSubtotal float64       `json:"originalSubtotal"`
+	NewSubtotal      float64       `json:"newSubtotal"`
+}
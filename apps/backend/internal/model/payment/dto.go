@@ -68,9 +68,10 @@ type KhaltiPaymentResponse struct {
 type StripePaymentPayload struct {
 	PurchaseOrderID   string  `json:"purchase_order_id" validate:"required"`
 	PurchaseOrderName string  `json:"purchase_order_name" validate:"required"`
-	Amount            float64 `json:"amount" validate:"required"`
+	// Amount and VendorUserId are ignored: the charge is read from the order
+	Amount            float64 `json:"amount"`
 	Currency          *string  `json:"currency"`
-	VendorUserId       string   `json:"vendorUserId" validate:"required"`
+	VendorUserId       string   `json:"vendorUserId"`
 	
 }
 
@@ -100,7 +101,9 @@ func (p *StripeVerifyPayload) Validate() error {
 
 
 type OnboardingPayload struct {
-	VendorUserId   string  `json:"vendorUserId"  validate:"required"`
+	// VendorUserId is the owner account of the caller's vendor, which the
+	// payout account belongs to; it is not read from the request
+	VendorUserId   string  `json:"-"`
 }
 func (p *OnboardingPayload) Validate() error {
 	validate := validator.New()
@@ -121,3 +124,13 @@ type OnboardingResponse struct {
 }
 
 
+
+
+type GroupPaymentPayload struct {
+	OrderGroupID string `json:"orderGroupId" validate:"required"`
+}
+
+func (p *GroupPaymentPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
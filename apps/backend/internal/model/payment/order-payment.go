@@ -12,9 +12,50 @@ type OrderPayment struct {
     PaymentGateway string     `json:"paymentGateway,omitempty" db:"payment_gateway"`
     TransactionID  string     `json:"transactionId,omitempty" db:"transaction_id"`
     Amount         float64    `json:"amount" db:"amount"`
-    Status         string     `json:"status" db:"status"` // 'initiated', 'success', 'failed', 'refunded'
+    Status         string     `json:"status" db:"status"` // 'initiated', 'success', 'failed', 'refund_pending', 'refunded'
     Method         string     `json:"method" db:"method"` // 'esewa', 'khalti', 'card', 'cod'
     PaidAt         *time.Time `json:"paidAt,omitempty" db:"paid_at"`
+    RefundRef      *string    `json:"refundRef,omitempty" db:"refund_ref"`
 }
 
+// OrderCharge is what the platform charges for an order outside a group,
+// read from the order and its vendor rather than from the checkout request.
+type OrderCharge struct {
+    OrderID        string  `db:"order_id"`
+    Total          float64 `db:"total"`
+    PaymentStatus  string  `db:"payment_status"`
+    VendorUserID   string  `db:"vendor_user_id"`
+    CommissionRate float64 `db:"commission_rate"` // percent
+}
+
+
+
 
+
+
+type OrderGroupPayment struct {
+    model.Base
+    OrderGroupID   string     `json:"orderGroupId" db:"order_group_id"`
+    PaymentGateway string     `json:"paymentGateway,omitempty" db:"payment_gateway"`
+    TransactionID  string     `json:"transactionId,omitempty" db:"transaction_id"`
+    ChargeRef      *string    `json:"chargeRef,omitempty" db:"charge_ref"`
+    Amount         float64    `json:"amount" db:"amount"`
+    Status         string     `json:"status" db:"status"` // 'initiated', 'success', 'failed', 'refunded', 'partially_refunded'
+    Method         string     `json:"method" db:"method"`
+    PaidAt         *time.Time `json:"paidAt,omitempty" db:"paid_at"`
+}
+
+type OrderVendorSplit struct {
+    model.Base
+    OrderGroupID    string  `json:"orderGroupId" db:"order_group_id"`
+    OrderVendorID   string  `json:"orderVendorId" db:"order_vendor_id"`
+    VendorUserID    *string `json:"vendorUserId,omitempty" db:"vendor_user_id"`
+    StripeAccountID *string `json:"stripeAccountId,omitempty" db:"stripe_account_id"`
+    Amount          float64 `json:"amount" db:"amount"`
+    Commission      float64 `json:"commission" db:"commission"`
+    VendorShare     float64 `json:"vendorShare" db:"vendor_share"`
+    Status          string  `json:"status" db:"status"` // pending, transferred, transfer_failed, refund_pending, refunded
+    TransferRef     *string `json:"transferRef,omitempty" db:"transfer_ref"`
+    RefundRef       *string `json:"refundRef,omitempty" db:"refund_ref"`
+    Remarks         *string `json:"remarks,omitempty" db:"remarks"`
+}
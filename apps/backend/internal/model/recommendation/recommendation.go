@@ -0,0 +1,53 @@
+package recommendation
+
+import "time"
+
+// =========================
+// Limits
+// =========================
+const (
+	UsualsLimit      = 10
+	PopularLimit     = 20
+	AlsoOrderedLimit = 10
+
+	// an item is a usual once it was in this many delivered orders
+	UsualMinOrders = 2
+	// order history considered for usuals and co-occurrence
+	HistoryWindow = 180 * 24 * time.Hour
+	// vendors within this distance of the default address are "near you"
+	NearbyRadiusKm = 5.0
+
+	// recommendations are rebuilt nightly; the TTL outlives a missed run
+	CacheTTL = 48 * time.Hour
+)
+
+func CacheKey(userID string) string {
+	return "recommendations:" + userID
+}
+
+// Item is a recommended menu item with its vendor. Score is the count the
+// list is ranked by: the user's orders for usuals, delivered orders for popular
+// items and shared baskets for co-occurrence.
+type Item struct {
+	MenuItemID string  `json:"menuItemId" db:"menu_item_id"`
+	Name       string  `json:"name" db:"name"`
+	Image      *string `json:"image" db:"image"`
+	BasePrice  float64 `json:"basePrice" db:"base_price"`
+	VendorID   string  `json:"vendorId" db:"vendor_id"`
+	VendorName string  `json:"vendorName" db:"vendor_name"`
+	Score      float64 `json:"score" db:"score"`
+}
+
+type Recommendations struct {
+	Usuals         []Item    `json:"usuals"`
+	PopularNearYou []Item    `json:"popularNearYou"`
+	AlsoOrdered    []Item    `json:"alsoOrdered"`
+	GeneratedAt    time.Time `json:"generatedAt"`
+}
+
+// RefreshResult summarizes a nightly precompute run.
+type RefreshResult struct {
+	Items  int64
+	Users  int
+	Failed int
+}
@@ -0,0 +1,49 @@
+package referral
+
+import "github.com/go-playground/validator/v10"
+
+// SignupAttribution is what the sign-up form passes to Clerk in
+// unsafe_metadata; it arrives with the user.created webhook.
+type SignupAttribution struct {
+	Code     string
+	Phone    *string
+	DeviceID *string
+}
+
+type UpdateSettingsPayload struct {
+	IsActive              *bool    `json:"isActive"`
+	RewardType            *string  `json:"rewardType" validate:"omitempty,oneof=points coupon"`
+	ReferrerPoints        *float64 `json:"referrerPoints" validate:"omitempty,gte=0"`
+	RefereePoints         *float64 `json:"refereePoints" validate:"omitempty,gte=0"`
+	CouponValue           *float64 `json:"couponValue" validate:"omitempty,gt=0"`
+	CouponMinOrderAmount  *float64 `json:"couponMinOrderAmount" validate:"omitempty,gte=0"`
+	CouponValidDays       *int     `json:"couponValidDays" validate:"omitempty,min=1,max=365"`
+	MaxRewardsPerReferrer *int     `json:"maxRewardsPerReferrer" validate:"omitempty,min=1"`
+}
+
+func (p *UpdateSettingsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetReferralsQuery struct {
+	Page   *int    `query:"page" validate:"omitempty,min=1"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status *string `query:"status" validate:"omitempty,oneof=pending rewarded rejected"`
+}
+
+func (q *GetReferralsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+	return nil
+}
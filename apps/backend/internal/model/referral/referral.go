@@ -0,0 +1,111 @@
+package referral
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
+
+// =========================
+// Statuses, rewards & rejection reasons
+// =========================
+const (
+	StatusPending  = "pending"
+	StatusRewarded = "rewarded"
+	StatusRejected = "rejected"
+
+	RewardPoints = "points"
+	RewardCoupon = "coupon"
+
+	RejectSelfReferral = "self_referral"
+	RejectSamePhone    = "same_phone"
+	RejectSameDevice   = "same_device"
+	RejectLimitReached = "limit_reached"
+	RejectInactive     = "program_inactive"
+
+	// loyalty ledger references for referral points
+	ReferenceTypeReferral        = "REFERRAL"
+	ReferenceTypeReferralWelcome = "REFERRAL_WELCOME"
+)
+
+// Settings are the program-wide referral rules.
+type Settings struct {
+	IsActive              bool      `json:"isActive" db:"is_active"`
+	RewardType            string    `json:"rewardType" db:"reward_type"` // what the referrer gets
+	ReferrerPoints        float64   `json:"referrerPoints" db:"referrer_points"`
+	RefereePoints         float64   `json:"refereePoints" db:"referee_points"` // welcome bonus, 0 = none
+	CouponValue           float64   `json:"couponValue" db:"coupon_value"`     // flat NPR off
+	CouponMinOrderAmount  float64   `json:"couponMinOrderAmount" db:"coupon_min_order_amount"`
+	CouponValidDays       int       `json:"couponValidDays" db:"coupon_valid_days"`
+	MaxRewardsPerReferrer int       `json:"maxRewardsPerReferrer" db:"max_rewards_per_referrer"`
+	UpdatedAt             time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type Code struct {
+	UserID    string    `json:"userId" db:"user_id"`
+	Code      string    `json:"code" db:"code"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type Referral struct {
+	model.Base
+	ReferrerID        string     `json:"referrerId" db:"referrer_id"`
+	RefereeID         string     `json:"refereeId" db:"referee_id"`
+	Code              string     `json:"code" db:"code"`
+	Status            string     `json:"status" db:"status"`
+	RejectionReason   *string    `json:"rejectionReason,omitempty" db:"rejection_reason"`
+	SignupPhone       *string    `json:"-" db:"signup_phone"`
+	SignupDeviceID    *string    `json:"-" db:"signup_device_id"`
+	QualifyingOrderID *string    `json:"qualifyingOrderId,omitempty" db:"qualifying_order_id"`
+	RewardType        *string    `json:"rewardType,omitempty" db:"reward_type"`
+	CouponID          *string    `json:"couponId,omitempty" db:"coupon_id"`
+	RewardedAt        *time.Time `json:"rewardedAt,omitempty" db:"rewarded_at"`
+}
+
+// ReferredUser is one row of a referrer's own list; the referee is only
+// shown by username.
+type ReferredUser struct {
+	RefereeUsername string     `json:"refereeUsername" db:"referee_username"`
+	Status          string     `json:"status" db:"status"`
+	RewardType      *string    `json:"rewardType,omitempty" db:"reward_type"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+	RewardedAt      *time.Time `json:"rewardedAt,omitempty" db:"rewarded_at"`
+}
+
+// PendingReward is a pending referral whose referee already has a delivered,
+// paid order.
+type PendingReward struct {
+	RefereeID string `db:"referee_id"`
+	OrderID   string `db:"order_id"`
+}
+
+type Stats struct {
+	Invited       int     `json:"invited" db:"invited"`
+	Pending       int     `json:"pending" db:"pending"`
+	Rewarded      int     `json:"rewarded" db:"rewarded"`
+	Rejected      int     `json:"rejected" db:"rejected"`
+	PointsEarned  float64 `json:"pointsEarned" db:"points_earned"`
+	CouponsIssued int     `json:"couponsIssued" db:"coupons_issued"`
+}
+
+// MyReferrals is the referral page of a user: their code, how it performed
+// and who signed up with it.
+type MyReferrals struct {
+	Code      string         `json:"code"`
+	Stats     Stats          `json:"stats"`
+	Referrals []ReferredUser `json:"referrals"`
+}
+
+type TopReferrer struct {
+	UserID   string `json:"userId" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Invited  int    `json:"invited" db:"invited"`
+	Rewarded int    `json:"rewarded" db:"rewarded"`
+}
+
+// ProgramStats is the admin view over every referral.
+type ProgramStats struct {
+	Stats
+	RejectedByReason map[string]int `json:"rejectedByReason"`
+	TopReferrers     []TopReferrer  `json:"topReferrers"`
+}
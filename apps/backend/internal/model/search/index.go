@@ -46,6 +46,7 @@ type MenuIndex struct {
 	IsGlutenFree bool     `json:"is_gluten_free"` // boolean
 	SpicyLevel   int      `json:"spicy_level"`    // integer
 	PortionSize  string   `json:"portion_size"`   // keyword
+	FavoriteCount int     `json:"favorite_count"` // integer
 	Category     Category `json:"category"`       // nested object
 }
 
@@ -0,0 +1,71 @@
+package settlement
+
+import "github.com/go-playground/validator/v10"
+
+type CreateAdjustmentPayload struct {
+	VendorID    string  `json:"vendorId" validate:"required"`
+	Amount      float64 `json:"amount" validate:"required"` // negative to deduct
+	Description string  `json:"description" validate:"required,min=3"`
+}
+
+func (p *CreateAdjustmentPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type GetSettlementsQuery struct {
+	Page   *int    `query:"page" validate:"omitempty,min=1"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status *string `query:"status" validate:"omitempty,oneof=pending processing paid failed"`
+}
+
+func (q *GetSettlementsQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+	return nil
+}
+
+type GetSettlementByIDPayload struct {
+	ID string `param:"id" validate:"required"`
+}
+
+func (p *GetSettlementByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type RunSettlementPayload struct {
+	Frequency string `json:"frequency" validate:"required,oneof=daily weekly"`
+}
+
+func (p *RunSettlementPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type MarkSettlementPaidPayload struct {
+	ID             string `param:"id" validate:"required"`
+	TransactionRef string `json:"transactionRef" validate:"required"`
+}
+
+func (p *MarkSettlementPaidPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type RunSettlementResponse struct {
+	Created int `json:"created"`
+	Paid    int `json:"paid"`
+	Failed  int `json:"failed"`
+}
@@ -0,0 +1,69 @@
+package settlement
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
+
+type VendorEarning struct {
+	ID              string    `json:"id" db:"id"`
+	VendorID        string    `json:"vendorId" db:"vendor_id"`
+	VendorUserID    *string   `json:"vendorUserId,omitempty" db:"vendor_user_id"`
+	PayoutAccountID *string   `json:"payoutAccountId,omitempty" db:"payout_account_id"`
+	OrderID         *string   `json:"orderId,omitempty" db:"order_id"`
+	EntryType       string    `json:"entryType" db:"entry_type"` // order, refund, adjustment
+	GrossAmount     float64   `json:"grossAmount" db:"gross_amount"`
+	CommissionRate  float64   `json:"commissionRate" db:"commission_rate"`
+	Commission      float64   `json:"commission" db:"commission"`
+	NetAmount       float64   `json:"netAmount" db:"net_amount"`
+	Description     *string   `json:"description,omitempty" db:"description"`
+	CreatedBy       *string   `json:"createdBy,omitempty" db:"created_by"`
+	SettlementID    *string   `json:"settlementId,omitempty" db:"settlement_id"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+}
+
+type VendorSettlement struct {
+	model.Base
+	PayoutAccountID  string     `json:"payoutAccountId" db:"payout_account_id"`
+	VendorUserID     *string    `json:"vendorUserId,omitempty" db:"vendor_user_id"`
+	Frequency        string     `json:"frequency" db:"frequency"` // daily, weekly, manual
+	PeriodStart      time.Time  `json:"periodStart" db:"period_start"`
+	PeriodEnd        time.Time  `json:"periodEnd" db:"period_end"`
+	GrossAmount      float64    `json:"grossAmount" db:"gross_amount"`
+	CommissionAmount float64    `json:"commissionAmount" db:"commission_amount"`
+	RefundAmount     float64    `json:"refundAmount" db:"refund_amount"`
+	AdjustmentAmount float64    `json:"adjustmentAmount" db:"adjustment_amount"`
+	NetAmount        float64    `json:"netAmount" db:"net_amount"`
+	PayoutAmount     *float64   `json:"payoutAmount,omitempty" db:"payout_amount"` // net_amount minus what already reached the connected account
+	Currency         string     `json:"currency" db:"currency"`
+	Status           string     `json:"status" db:"status"` // pending, processing, paid, failed
+	Attempts         int        `json:"attempts" db:"attempts"`
+	LastError        *string    `json:"lastError,omitempty" db:"last_error"`
+	NextRetryAt      *time.Time `json:"nextRetryAt,omitempty" db:"next_retry_at"`
+	PayoutID         *string    `json:"payoutId,omitempty" db:"payout_id"`
+	TransactionRef   *string    `json:"transactionRef,omitempty" db:"transaction_ref"`
+	PaidAt           *time.Time `json:"paidAt,omitempty" db:"paid_at"`
+}
+
+// SettlementStatement is what a vendor sees for one batch: the totals and
+// every earning, refund and adjustment that went into it.
+type SettlementStatement struct {
+	Settlement VendorSettlement `json:"settlement"`
+	Entries    []VendorEarning  `json:"entries"`
+}
+
+// PayoutRecipient is who is told about a paid settlement, and how it was
+// paid.
+type PayoutRecipient struct {
+	Email  *string `db:"email"`
+	Name   string  `db:"name"`
+	Method string  `db:"method"`
+}
+
+// SettlementPayoutTarget is the payout account a settlement is paid into.
+type SettlementPayoutTarget struct {
+	Method          string  `db:"method"`
+	StripeAccountID *string `db:"stripe_account_id"`
+	Currency        string  `db:"currency"`
+}
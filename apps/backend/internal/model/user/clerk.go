@@ -0,0 +1,33 @@
+package user
+
+import "time"
+
+// ClerkUserSync is the part of a Clerk user that is mirrored in users.
+// ClerkUpdatedAt (ms) orders the writes: an older state never overwrites a
+// newer one, whichever order the webhooks arrive in.
+type ClerkUserSync struct {
+	ID             string
+	Email          string
+	Username       string
+	PhoneNumber    *string
+	ProfilePicture *string
+	LastSignInAt   *time.Time
+	ClerkUpdatedAt int64
+}
+
+// ClerkEvent is a processed Clerk webhook delivery.
+type ClerkEvent struct {
+	ID             string  `json:"id" db:"id"`
+	Type           string  `json:"type" db:"type"`
+	ObjectID       *string `json:"objectId,omitempty" db:"object_id"`
+	EventTimestamp int64   `json:"eventTimestamp" db:"event_timestamp"`
+}
+
+// ClerkReconcileResult counts what a reconciliation run changed.
+type ClerkReconcileResult struct {
+	Seen      int
+	Created   int
+	Updated   int
+	Unchanged int
+	Deleted   int
+}
@@ -0,0 +1,22 @@
+package user
+
+import "time"
+
+// DeletionGracePeriod is how long a deletion request can be cancelled
+// before the account is anonymized.
+const DeletionGracePeriod = 30 * 24 * time.Hour
+
+// DeletionStatus is the state of a user's deletion request.
+type DeletionStatus struct {
+	Pending     bool       `json:"pending"`
+	RequestedAt *time.Time `json:"requestedAt,omitempty"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"` // anonymized from then on
+}
+
+func NewDeletionStatus(u *User) *DeletionStatus {
+	return &DeletionStatus{
+		Pending:     u.DeletionScheduledAt != nil,
+		RequestedAt: u.DeletionRequestedAt,
+		ScheduledAt: u.DeletionScheduledAt,
+	}
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"time"
 
+	"github.com/gitSanje/khajaride/internal/validation"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
@@ -195,7 +196,7 @@ func (p *SetDefaultAddressPayload) Validate() error {
 type GetLoyaltyQuery struct {
 	Page  *int `query:"page" validate:"omitempty,min=1"`
 	Limit *int `query:"limit" validate:"omitempty,min=1,max=100"`
-	TransactionType *string   `query:"transactionType" validate:"omitempty,oneof=EARN REDEEM ADJUST"`
+	TransactionType *string   `query:"transactionType" validate:"omitempty,oneof=EARN REDEEM ADJUST EXPIRE"`
 	FromDate       *time.Time `query:"fromDate"`
 	ToDate         *time.Time `query:"toDate"`
 }
@@ -271,4 +272,37 @@ func (p *VendorOnboardingTrackPayload) Validate() error {
 type VendorOnboardingTrackResponse  struct{
     Completed     bool `json:"completed"`
     CurrentStep   string `json:"currentStep"`
-}
\ No newline at end of file
+}
+
+// ------------------------------------------------------------
+// Phone verification
+
+type RequestPhoneOTPPayload struct {
+	PhoneNumber string `json:"phoneNumber" validate:"required"`
+}
+
+// Validate also normalizes the number to E.164.
+func (p *RequestPhoneOTPPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+	phone, ok := NormalizePhoneNumber(p.PhoneNumber)
+	if !ok {
+		return validation.CustomValidationErrors{{
+			Field:   "phoneNumber",
+			Message: "must be a Nepali mobile number",
+		}}
+	}
+	p.PhoneNumber = phone
+	return nil
+}
+
+type VerifyPhoneOTPPayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+func (p *VerifyPhoneOTPPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
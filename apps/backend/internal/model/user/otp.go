@@ -0,0 +1,55 @@
+package user
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Phone OTP rules. The resend limits are enforced in Redis, the attempt
+// limit on the token row.
+const (
+	TokenTypePhoneOTP = "phone_otp"
+
+	OTPLength         = 6
+	OTPTTL            = 10 * time.Minute
+	OTPMaxAttempts    = 5
+	OTPResendCooldown = time.Minute
+	OTPMaxPerHour     = 5
+)
+
+// TwoFactorToken is a user_2fa_tokens row.
+type TwoFactorToken struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"userId" db:"user_id"`
+	Token       string    `json:"-" db:"token"` // HMAC of the code
+	TokenType   string    `json:"tokenType" db:"token_type"`
+	PhoneNumber *string   `json:"phoneNumber,omitempty" db:"phone_number"`
+	Attempts    int       `json:"attempts" db:"attempts"`
+	ExpiresAt   time.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	Used        bool      `json:"used" db:"used"`
+}
+
+// PhoneOTPIssued tells the client where the code went and when it may ask
+// for another one.
+type PhoneOTPIssued struct {
+	PhoneNumber string    `json:"phoneNumber"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	ResendAfter int       `json:"resendAfter"` // seconds
+}
+
+var nepaliMobile = regexp.MustCompile(`^9[678]\d{8}$`)
+
+// NormalizePhoneNumber turns a Nepali mobile number, with or without the
+// +977 prefix, into E.164 (+9779XXXXXXXXX). It reports false for anything
+// that is not a Nepali mobile number.
+func NormalizePhoneNumber(raw string) (string, bool) {
+	n := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(raw))
+	n = strings.TrimPrefix(n, "+977")
+	n = strings.TrimPrefix(n, "00977")
+	if !nepaliMobile.MatchString(n) {
+		return "", false
+	}
+	return "+977" + n, true
+}
@@ -1,6 +1,10 @@
 package user
 
-import "github.com/gitSanje/khajaride/internal/model"
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+)
 
 type User struct {
 	model.BaseWithCreatedAt
@@ -14,8 +18,15 @@ type User struct {
 	IsVerified                  bool    `json:"isVerified" db:"is_verified"`
 	IsActive                    bool    `json:"isActive" db:"is_active"`
 	LoyaltyPoints               int     `json:"loyaltyPoints" db:"loyalty_points"`
+	LoyaltyTier                 string  `json:"loyaltyTier" db:"loyalty_tier"`
 	ProfilePicture              *string `json:"profilePicture,omitempty" db:"profile_picture"`
 	TwoFactorEnabled            bool    `json:"twoFactorEnabled" db:"two_factor_enabled"`
 	CurrentOnboardingStep       string    `json:"currentOnboardingStep" db:"current_onboarding_step"`
 	IsVendorOnboardingCompleted bool    `json:"isVendorOnboardingCompleted" db:"is_vendor_onboarding_completed"`
+	ClerkUpdatedAt              int64      `json:"-" db:"clerk_updated_at"`
+	LastSignInAt                *time.Time `json:"lastSignInAt,omitempty" db:"last_sign_in_at"`
+	DeletedAt                   *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	DeletionRequestedAt         *time.Time `json:"deletionRequestedAt,omitempty" db:"deletion_requested_at"`
+	DeletionScheduledAt         *time.Time `json:"deletionScheduledAt,omitempty" db:"deletion_scheduled_at"`
+	AnonymizedAt                *time.Time `json:"-" db:"anonymized_at"`
 }
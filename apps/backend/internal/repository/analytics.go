@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/analytics"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------- ANALYTICS REPOSITORY ----------------
+
+type AnalyticsRepository struct {
+	server *server.Server
+}
+
+func NewAnalyticsRepository(s *server.Server) *AnalyticsRepository {
+	return &AnalyticsRepository{server: s}
+}
+
+//-- ==================================================
+//-- ROLLUP REFRESH
+//-- ==================================================
+
+// Refresh brings the vendor analytics rollups up to date with the orders and
+// earnings changed since the last refresh. Every bucket a changed row falls
+// in is recomputed from scratch, so re-reading a change is harmless and an
+// order that moves on (delivered, cancelled) moves its bucket with it.
+// Buckets are local to tz.
+func (r *AnalyticsRepository) Refresh(ctx context.Context, tz string) (*analytics.RefreshResult, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ Take the mark; the row lock keeps refreshes from overlapping
+	var mark time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO analytics_refresh_state (name, refreshed_through)
+		VALUES ($1, 'epoch')
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING refreshed_through
+	`, analytics.RefreshName).Scan(&mark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock analytics refresh state: %w", err)
+	}
+
+	args := pgx.NamedArgs{
+		"since": mark.Add(-analytics.RefreshOverlap),
+		"tz":    tz,
+	}
+	res := &analytics.RefreshResult{}
+
+	// 2️⃣ Hourly sales of the hours changed orders were placed in
+	tag, err := tx.Exec(ctx, `
+		WITH changed AS (
+			SELECT DISTINCT vendor_id, date_trunc('hour', created_at AT TIME ZONE @tz::TEXT) AS bucket
+			FROM order_vendors
+			WHERE updated_at > @since
+		), fresh AS (
+			SELECT c.vendor_id, c.bucket,
+			       (COUNT(*) FILTER (WHERE ov.status <> 'failed'))::INT AS orders,
+			       (COUNT(*) FILTER (WHERE ov.status = 'delivered'))::INT AS delivered_orders,
+			       (COUNT(*) FILTER (WHERE ov.status = 'cancelled'))::INT AS cancelled_orders,
+			       COALESCE(SUM(ov.total) FILTER (WHERE ov.status = 'delivered'), 0) AS revenue,
+			       COALESCE(SUM(i.quantity) FILTER (WHERE ov.status = 'delivered'), 0)::INT AS items_sold
+			FROM changed c
+			JOIN order_vendors ov ON ov.vendor_id = c.vendor_id
+			 AND ov.created_at >= c.bucket AT TIME ZONE @tz::TEXT
+			 AND ov.created_at < (c.bucket + INTERVAL '1 hour') AT TIME ZONE @tz::TEXT
+			LEFT JOIN LATERAL (
+				SELECT SUM(oi.quantity) AS quantity FROM order_items oi WHERE oi.order_vendor_id = ov.id
+			) i ON TRUE
+			GROUP BY c.vendor_id, c.bucket
+		), removed AS (
+			DELETE FROM vendor_sales_hourly h
+			USING fresh f
+			WHERE h.vendor_id = f.vendor_id AND h.bucket = f.bucket AND f.orders = 0
+		)
+		INSERT INTO vendor_sales_hourly (vendor_id, bucket, orders, delivered_orders, cancelled_orders, revenue, items_sold)
+		SELECT vendor_id, bucket, orders, delivered_orders, cancelled_orders, revenue, items_sold
+		FROM fresh
+		WHERE orders > 0
+		ON CONFLICT (vendor_id, bucket) DO UPDATE SET
+			orders = EXCLUDED.orders,
+			delivered_orders = EXCLUDED.delivered_orders,
+			cancelled_orders = EXCLUDED.cancelled_orders,
+			revenue = EXCLUDED.revenue,
+			items_sold = EXCLUDED.items_sold
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh vendor sales: %w", err)
+	}
+	res.SalesBuckets = tag.RowsAffected()
+
+	// 3️⃣ Daily item sales and customers of the days changed orders were
+	// placed on
+	_, err = tx.Exec(ctx, `
+		WITH changed AS (
+			SELECT DISTINCT vendor_id, (created_at AT TIME ZONE @tz::TEXT)::DATE AS day
+			FROM order_vendors
+			WHERE updated_at > @since
+		), fresh AS (
+			SELECT c.vendor_id, c.day, oi.menu_item_id,
+			       COUNT(DISTINCT ov.id)::INT AS orders,
+			       SUM(oi.quantity)::INT AS quantity,
+			       SUM(oi.subtotal) AS revenue
+			FROM changed c
+			JOIN order_vendors ov ON ov.vendor_id = c.vendor_id
+			 AND ov.created_at >= c.day::TIMESTAMP AT TIME ZONE @tz::TEXT
+			 AND ov.created_at < (c.day + 1)::TIMESTAMP AT TIME ZONE @tz::TEXT
+			 AND ov.status = 'delivered'
+			JOIN order_items oi ON oi.order_vendor_id = ov.id
+			GROUP BY c.vendor_id, c.day, oi.menu_item_id
+		), removed AS (
+			DELETE FROM vendor_item_sales_daily d
+			USING changed c
+			WHERE d.vendor_id = c.vendor_id AND d.day = c.day
+			  AND NOT EXISTS (
+				SELECT 1 FROM fresh f
+				WHERE f.vendor_id = d.vendor_id AND f.day = d.day AND f.menu_item_id = d.menu_item_id
+			  )
+		)
+		INSERT INTO vendor_item_sales_daily (vendor_id, day, menu_item_id, orders, quantity, revenue)
+		SELECT vendor_id, day, menu_item_id, orders, quantity, revenue
+		FROM fresh
+		ON CONFLICT (vendor_id, day, menu_item_id) DO UPDATE SET
+			orders = EXCLUDED.orders,
+			quantity = EXCLUDED.quantity,
+			revenue = EXCLUDED.revenue
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh vendor item sales: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		WITH changed AS (
+			SELECT DISTINCT vendor_id, (created_at AT TIME ZONE @tz::TEXT)::DATE AS day
+			FROM order_vendors
+			WHERE updated_at > @since
+		), fresh AS (
+			SELECT c.vendor_id, c.day, ov.user_id, COUNT(*)::INT AS orders
+			FROM changed c
+			JOIN order_vendors ov ON ov.vendor_id = c.vendor_id
+			 AND ov.created_at >= c.day::TIMESTAMP AT TIME ZONE @tz::TEXT
+			 AND ov.created_at < (c.day + 1)::TIMESTAMP AT TIME ZONE @tz::TEXT
+			 AND ov.status = 'delivered'
+			GROUP BY c.vendor_id, c.day, ov.user_id
+		), removed AS (
+			DELETE FROM vendor_customer_daily d
+			USING changed c
+			WHERE d.vendor_id = c.vendor_id AND d.day = c.day
+			  AND NOT EXISTS (
+				SELECT 1 FROM fresh f
+				WHERE f.vendor_id = d.vendor_id AND f.day = d.day AND f.user_id = d.user_id
+			  )
+		)
+		INSERT INTO vendor_customer_daily (vendor_id, day, user_id, orders)
+		SELECT vendor_id, day, user_id, orders
+		FROM fresh
+		ON CONFLICT (vendor_id, day, user_id) DO UPDATE SET
+			orders = EXCLUDED.orders
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh vendor customers: %w", err)
+	}
+
+	// 4️⃣ Daily earnings; earning rows are only ever inserted
+	tag, err = tx.Exec(ctx, `
+		WITH changed AS (
+			SELECT DISTINCT vendor_id, (created_at AT TIME ZONE @tz::TEXT)::DATE AS day
+			FROM vendor_earnings
+			WHERE created_at > @since
+		), fresh AS (
+			SELECT c.vendor_id, c.day,
+			       COALESCE(SUM(e.gross_amount) FILTER (WHERE e.entry_type <> 'refund'), 0) AS gross_amount,
+			       COALESCE(SUM(e.commission), 0) AS commission,
+			       COALESCE(SUM(e.gross_amount) FILTER (WHERE e.entry_type = 'refund'), 0) AS refund_amount,
+			       COALESCE(SUM(e.net_amount), 0) AS net_amount
+			FROM changed c
+			JOIN vendor_earnings e ON e.vendor_id = c.vendor_id
+			 AND e.created_at >= c.day::TIMESTAMP AT TIME ZONE @tz::TEXT
+			 AND e.created_at < (c.day + 1)::TIMESTAMP AT TIME ZONE @tz::TEXT
+			GROUP BY c.vendor_id, c.day
+		)
+		INSERT INTO vendor_earnings_daily (vendor_id, day, gross_amount, commission, refund_amount, net_amount)
+		SELECT vendor_id, day, gross_amount, commission, refund_amount, net_amount
+		FROM fresh
+		ON CONFLICT (vendor_id, day) DO UPDATE SET
+			gross_amount = EXCLUDED.gross_amount,
+			commission = EXCLUDED.commission,
+			refund_amount = EXCLUDED.refund_amount,
+			net_amount = EXCLUDED.net_amount
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh vendor earnings: %w", err)
+	}
+	res.EarningDays = tag.RowsAffected()
+
+	// 5️⃣ Move the mark to when this refresh's snapshot was taken
+	_, err = tx.Exec(ctx, `
+		UPDATE analytics_refresh_state SET refreshed_through = NOW() WHERE name = $1
+	`, analytics.RefreshName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update analytics refresh state: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit analytics refresh: %w", err)
+	}
+	return res, nil
+}
+
+//-- ==================================================
+//-- VENDOR ANALYTICS
+//-- ==================================================
+
+func (r *AnalyticsRepository) VendorExists(ctx context.Context, vendorID string) (bool, error) {
+	var exists bool
+	err := r.server.DB.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM vendors WHERE id = $1)`, vendorID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check vendor: %w", err)
+	}
+	return exists, nil
+}
+
+// GetOwnedBrandOutlets returns the ids of the outlets of a brand ownerID
+// owns.
+func (r *AnalyticsRepository) GetOwnedBrandOutlets(ctx context.Context, brandID, ownerID string) ([]string, error) {
+	var exists bool
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM brands WHERE id = $1 AND owner_user_id = $2)
+	`, brandID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check brand: %w", err)
+	}
+	if !exists {
+		return nil, ErrBrandNotFound
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT id FROM vendors WHERE brand_id = $1`, brandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get brand outlets: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect brand outlets: %w", err)
+	}
+	return ids, nil
+}
+
+// GetVendorAnalytics reads a vendor's analytics over rng from the rollups.
+func (r *AnalyticsRepository) GetVendorAnalytics(ctx context.Context, vendorID string, rng *analytics.Range) (*analytics.VendorAnalytics, error) {
+	report, err := r.getReport(ctx, []string{vendorID}, rng)
+	if err != nil {
+		return nil, err
+	}
+	return &analytics.VendorAnalytics{VendorID: vendorID, Report: *report}, nil
+}
+
+// GetBrandAnalytics reads the analytics of a brand's outlets over rng as one
+// report, with each outlet's share of the sales.
+func (r *AnalyticsRepository) GetBrandAnalytics(ctx context.Context, brandID string, outletIDs []string, rng *analytics.Range) (*analytics.BrandAnalytics, error) {
+	report, err := r.getReport(ctx, outletIDs, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT v.id AS vendor_id, v.outlet_name,
+		       COALESCE(h.orders, 0)::INT AS orders,
+		       COALESCE(h.delivered_orders, 0)::INT AS delivered_orders,
+		       COALESCE(h.revenue, 0) AS revenue,
+		       COALESCE(e.net, 0) AS net
+		FROM vendors v
+		LEFT JOIN LATERAL (
+			SELECT SUM(orders) AS orders, SUM(delivered_orders) AS delivered_orders, SUM(revenue) AS revenue
+			FROM vendor_sales_hourly
+			WHERE vendor_id = v.id AND bucket >= @from::DATE AND bucket < @to::DATE + 1
+		) h ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT SUM(net_amount) AS net
+			FROM vendor_earnings_daily
+			WHERE vendor_id = v.id AND day BETWEEN @from::DATE AND @to::DATE
+		) e ON TRUE
+		WHERE v.id = ANY(@vendorIds)
+		ORDER BY revenue DESC, v.outlet_name
+	`, pgx.NamedArgs{
+		"vendorIds": outletIDs,
+		"from":      rng.From,
+		"to":        rng.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet sales: %w", err)
+	}
+	outlets, err := pgx.CollectRows(rows, pgx.RowToStructByName[analytics.OutletSales])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect outlet sales: %w", err)
+	}
+
+	return &analytics.BrandAnalytics{BrandID: brandID, Report: *report, Outlets: outlets}, nil
+}
+
+// getReport reads the analytics of vendorIDs over rng from the rollups, summed
+// across the vendors. Only the unsettled payout total is read live, from the
+// (small) set of earnings not yet batched.
+func (r *AnalyticsRepository) getReport(ctx context.Context, vendorIDs []string, rng *analytics.Range) (*analytics.Report, error) {
+	format := "YYYY-MM-DD"
+	if rng.Granularity == analytics.GranularityHour {
+		format = "YYYY-MM-DD HH24:00"
+	}
+	args := pgx.NamedArgs{
+		"vendorIds":   vendorIDs,
+		"from":        rng.From,
+		"to":          rng.To,
+		"tz":          rng.Timezone,
+		"granularity": rng.Granularity,
+		"format":      format,
+		"limit":       analytics.TopItemsLimit,
+	}
+
+	res := &analytics.Report{
+		From:        rng.From,
+		To:          rng.To,
+		Granularity: rng.Granularity,
+		Timezone:    rng.Timezone,
+	}
+
+	// 1️⃣ Summary
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT COALESCE(SUM(orders), 0)::INT AS orders,
+		       COALESCE(SUM(delivered_orders), 0)::INT AS delivered_orders,
+		       COALESCE(SUM(cancelled_orders), 0)::INT AS cancelled_orders,
+		       COALESCE(SUM(revenue), 0) AS revenue,
+		       COALESCE(SUM(items_sold), 0)::INT AS items_sold
+		FROM vendor_sales_hourly
+		WHERE vendor_id = ANY(@vendorIds) AND bucket >= @from::DATE AND bucket < @to::DATE + 1
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales summary: %w", err)
+	}
+	summary, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[analytics.Summary])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sales summary: %w", err)
+	}
+
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		WITH in_range AS (
+			SELECT user_id, SUM(orders) AS orders
+			FROM vendor_customer_daily
+			WHERE vendor_id = ANY(@vendorIds) AND day BETWEEN @from::DATE AND @to::DATE
+			GROUP BY user_id
+		)
+		SELECT COUNT(*)::INT,
+		       (COUNT(*) FILTER (
+				WHERE r.orders > 1 OR EXISTS (
+					SELECT 1 FROM vendor_customer_daily p
+					WHERE p.vendor_id = ANY(@vendorIds) AND p.user_id = r.user_id AND p.day < @from::DATE
+				)
+		       ))::INT
+		FROM in_range r
+	`, args).Scan(&summary.Customers, &summary.ReturningCustomers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count vendor customers: %w", err)
+	}
+	res.Summary = summary
+
+	// 2️⃣ Timeseries, with empty periods filled in
+	rows, err = r.server.DB.Pool.Query(ctx, `
+		WITH series AS (
+			SELECT generate_series(
+				date_trunc(@granularity::TEXT, @from::DATE::TIMESTAMP),
+				date_trunc(@granularity::TEXT, (@to::DATE + 1)::TIMESTAMP - INTERVAL '1 hour'),
+				('1 ' || @granularity::TEXT)::INTERVAL
+			) AS period
+		), agg AS (
+			SELECT date_trunc(@granularity::TEXT, bucket) AS period,
+			       SUM(orders) AS orders,
+			       SUM(delivered_orders) AS delivered_orders,
+			       SUM(cancelled_orders) AS cancelled_orders,
+			       SUM(revenue) AS revenue
+			FROM vendor_sales_hourly
+			WHERE vendor_id = ANY(@vendorIds) AND bucket >= @from::DATE AND bucket < @to::DATE + 1
+			GROUP BY 1
+		)
+		SELECT to_char(s.period, @format::TEXT) AS period,
+		       COALESCE(a.orders, 0)::INT AS orders,
+		       COALESCE(a.delivered_orders, 0)::INT AS delivered_orders,
+		       COALESCE(a.cancelled_orders, 0)::INT AS cancelled_orders,
+		       COALESCE(a.revenue, 0) AS revenue,
+		       CASE WHEN a.delivered_orders > 0 THEN ROUND(a.revenue / a.delivered_orders, 2) ELSE 0 END AS average_order_value
+		FROM series s
+		LEFT JOIN agg a ON a.period = s.period
+		ORDER BY s.period
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales timeseries: %w", err)
+	}
+	res.Timeseries, err = pgx.CollectRows(rows, pgx.RowToStructByName[analytics.Point])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sales timeseries: %w", err)
+	}
+
+	// 3️⃣ Top items by revenue
+	rows, err = r.server.DB.Pool.Query(ctx, `
+		SELECT s.menu_item_id, mi.name, mi.image,
+		       SUM(s.orders)::INT AS orders,
+		       SUM(s.quantity)::INT AS quantity,
+		       SUM(s.revenue) AS revenue
+		FROM vendor_item_sales_daily s
+		JOIN menu_items mi ON mi.id = s.menu_item_id
+		WHERE s.vendor_id = ANY(@vendorIds) AND s.day BETWEEN @from::DATE AND @to::DATE
+		GROUP BY s.menu_item_id, mi.name, mi.image
+		ORDER BY revenue DESC, quantity DESC
+		LIMIT @limit
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top items: %w", err)
+	}
+	res.TopItems, err = pgx.CollectRows(rows, pgx.RowToStructByName[analytics.TopItem])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect top items: %w", err)
+	}
+
+	// 4️⃣ Hour of day x weekday
+	rows, err = r.server.DB.Pool.Query(ctx, `
+		SELECT EXTRACT(ISODOW FROM bucket)::INT AS weekday,
+		       EXTRACT(HOUR FROM bucket)::INT AS hour,
+		       SUM(orders)::INT AS orders,
+		       SUM(revenue) AS revenue
+		FROM vendor_sales_hourly
+		WHERE vendor_id = ANY(@vendorIds) AND bucket >= @from::DATE AND bucket < @to::DATE + 1
+		GROUP BY 1, 2
+		HAVING SUM(orders) > 0
+		ORDER BY 1, 2
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order heatmap: %w", err)
+	}
+	res.Heatmap, err = pgx.CollectRows(rows, pgx.RowToStructByName[analytics.HeatmapCell])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect order heatmap: %w", err)
+	}
+
+	// 5️⃣ Payouts
+	rows, err = r.server.DB.Pool.Query(ctx, `
+		SELECT COALESCE(SUM(e.gross_amount), 0) AS gross,
+		       COALESCE(SUM(e.commission), 0) AS commission,
+		       COALESCE(SUM(e.refund_amount), 0) AS refunds,
+		       COALESCE(SUM(e.net_amount), 0) AS net,
+		       (
+				SELECT COALESCE(SUM(p.net_amount), 0)
+				FROM vendor_earnings p
+				JOIN vendor_settlements s ON s.id = p.settlement_id
+				WHERE p.vendor_id = ANY(@vendorIds) AND s.status = 'paid'
+				  AND (s.paid_at AT TIME ZONE @tz::TEXT)::DATE BETWEEN @from::DATE AND @to::DATE
+		       ) AS paid_out,
+		       (
+				SELECT COALESCE(SUM(u.net_amount), 0)
+				FROM vendor_earnings u
+				WHERE u.vendor_id = ANY(@vendorIds) AND u.settlement_id IS NULL
+		       ) AS unsettled
+		FROM vendor_earnings_daily e
+		WHERE e.vendor_id = ANY(@vendorIds) AND e.day BETWEEN @from::DATE AND @to::DATE
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout totals: %w", err)
+	}
+	res.Payouts, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[analytics.Payouts])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect payout totals: %w", err)
+	}
+
+	// 6️⃣ How fresh all of the above is
+	var refreshed time.Time
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		SELECT refreshed_through FROM analytics_refresh_state WHERE name = $1
+	`, analytics.RefreshName).Scan(&refreshed)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get analytics refresh state: %w", err)
+	}
+	if err == nil {
+		res.RefreshedThrough = &refreshed
+	}
+
+	return res, nil
+}
@@ -0,0 +1,670 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrBrandNotFound         = errors.New("brand not found")
+	ErrOutletNotAttachable   = errors.New("vendor cannot be attached to this brand")
+	ErrBrandMenuItemNotFound = errors.New("brand menu item not found")
+	ErrPayoutAccountNotOwned = errors.New("payout account does not belong to the vendor owner")
+	ErrMenuOverrideNotFound  = errors.New("menu override not found")
+)
+
+// ---------------- BRAND REPOSITORY ----------------
+
+type BrandRepository struct {
+	server *server.Server
+}
+
+func NewBrandRepository(s *server.Server) *BrandRepository {
+	return &BrandRepository{server: s}
+}
+
+// outletColumns selects a vendor.Outlet from vendors v with its first
+// address va.
+const outletColumns = `
+	v.id, v.name, v.outlet_name, v.status, v.is_open, v.opening_hours,
+	v.delivery_radius_km, v.payout_account_id, va.city, va.street_address`
+
+//-- ==================================================
+//-- BRANDS
+//-- ==================================================
+
+func (r *BrandRepository) CreateBrand(ctx context.Context, ownerID string, payload *vendor.CreateBrandPayload) (*vendor.Brand, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO brands (owner_user_id, name, about, cuisine, cuisine_tags, logo_image_name)
+		VALUES (@ownerId, @name, @about, @cuisine, @cuisineTags, @logo)
+		RETURNING *
+	`, pgx.NamedArgs{
+		"ownerId":     ownerID,
+		"name":        payload.Name,
+		"about":       payload.About,
+		"cuisine":     payload.Cuisine,
+		"cuisineTags": payload.CuisineTags,
+		"logo":        payload.LogoImageName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create brand: %w", err)
+	}
+	brand, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Brand])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect brand: %w", err)
+	}
+	return &brand, nil
+}
+
+// UpdateBrand changes the brand of ownerID. A new name is carried over to
+// all of its outlets, which are listed under the brand name.
+func (r *BrandRepository) UpdateBrand(ctx context.Context, ownerID string, payload *vendor.UpdateBrandPayload) (*vendor.Brand, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE brands SET
+			name = COALESCE(@name, name),
+			about = COALESCE(@about, about),
+			cuisine = COALESCE(@cuisine, cuisine),
+			cuisine_tags = COALESCE(@cuisineTags, cuisine_tags),
+			logo_image_name = COALESCE(@logo, logo_image_name)
+		WHERE id = @id AND owner_user_id = @ownerId
+		RETURNING *
+	`, pgx.NamedArgs{
+		"id":          payload.ID,
+		"ownerId":     ownerID,
+		"name":        payload.Name,
+		"about":       payload.About,
+		"cuisine":     payload.Cuisine,
+		"cuisineTags": payload.CuisineTags,
+		"logo":        payload.LogoImageName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update brand: %w", err)
+	}
+	brand, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Brand])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect brand: %w", err)
+	}
+
+	if payload.Name != nil {
+		if _, err := tx.Exec(ctx, `UPDATE vendors SET name = $1 WHERE brand_id = $2`, brand.Name, brand.ID); err != nil {
+			return nil, fmt.Errorf("failed to rename outlets: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+// GetOwnedBrands lists the user's brands with their outlets.
+func (r *BrandRepository) GetOwnedBrands(ctx context.Context, ownerID string) ([]vendor.PopulatedBrand, error) {
+	return r.queryBrands(ctx, `b.owner_user_id = @ownerId`, pgx.NamedArgs{"ownerId": ownerID})
+}
+
+func (r *BrandRepository) GetOwnedBrand(ctx context.Context, brandID, ownerID string) (*vendor.PopulatedBrand, error) {
+	brands, err := r.queryBrands(ctx, `b.id = @id AND b.owner_user_id = @ownerId`, pgx.NamedArgs{
+		"id":      brandID,
+		"ownerId": ownerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(brands) == 0 {
+		return nil, ErrBrandNotFound
+	}
+	return &brands[0], nil
+}
+
+func (r *BrandRepository) queryBrands(ctx context.Context, condition string, args pgx.NamedArgs) ([]vendor.PopulatedBrand, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT b.*,
+		       COALESCE((
+				SELECT jsonb_agg(camel(to_jsonb(o)) ORDER BY o.outlet_name)
+				FROM (
+					SELECT `+outletColumns+`
+					FROM vendors v
+					LEFT JOIN LATERAL (
+						SELECT city, street_address FROM vendor_addresses
+						WHERE vendor_id = v.id
+						ORDER BY created_at
+						LIMIT 1
+					) va ON TRUE
+					WHERE v.brand_id = b.id
+				) o
+		       ), '[]'::jsonb) AS outlets
+		FROM brands b
+		WHERE `+condition+`
+		ORDER BY b.created_at
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get brands: %w", err)
+	}
+	brands, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.PopulatedBrand])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect brands: %w", err)
+	}
+	return brands, nil
+}
+
+//-- ==================================================
+//-- OUTLETS
+//-- ==================================================
+
+// CreateOutlet adds a vendor for the brand, owned by the brand owner and
+// listed under the brand's name, together with its address.
+func (r *BrandRepository) CreateOutlet(ctx context.Context, ownerID string, payload *vendor.CreateOutletPayload) (*vendor.Outlet, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ The vendor, carrying the brand's identity
+	var vendorID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO vendors (
+			vendor_user_id, brand_id, name, about, cuisine, cuisine_tags, vendor_logo_image_name,
+			outlet_name, phone, opening_hours, delivery_radius_km,
+			delivery_fee, min_order_amount, delivery_time_estimate
+		)
+		SELECT b.owner_user_id, b.id, b.name, b.about, COALESCE(b.cuisine, ''), b.cuisine_tags, b.logo_image_name,
+		       @outletName, @phone, @openingHours, @deliveryRadiusKm,
+		       COALESCE(@deliveryFee, 0), COALESCE(@minOrderAmount, 0), COALESCE(@deliveryTimeEstimate, '')
+		FROM brands b
+		WHERE b.id = @brandId AND b.owner_user_id = @ownerId
+		RETURNING id
+	`, pgx.NamedArgs{
+		"brandId":              payload.BrandID,
+		"ownerId":              ownerID,
+		"outletName":           payload.OutletName,
+		"phone":                payload.Phone,
+		"openingHours":         payload.OpeningHours,
+		"deliveryRadiusKm":     payload.DeliveryRadiusKm,
+		"deliveryFee":          payload.DeliveryFee,
+		"minOrderAmount":       payload.MinOrderAmount,
+		"deliveryTimeEstimate": payload.DeliveryTimeEstimate,
+	}).Scan(&vendorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outlet: %w", err)
+	}
+
+	// 2️⃣ Its address
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vendor_addresses (vendor_id, street_address, city, state, zipcode, latitude, longitude)
+		VALUES (@vendorId, @street, @city, @state, @zipcode, @lat, @lng)
+	`, pgx.NamedArgs{
+		"vendorId": vendorID,
+		"street":   payload.StreetAddress,
+		"city":     payload.City,
+		"state":    payload.State,
+		"zipcode":  payload.Zipcode,
+		"lat":      payload.Latitude,
+		"lng":      payload.Longitude,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outlet address: %w", err)
+	}
+
+	outlet, err := getOutlet(ctx, tx, vendorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return outlet, nil
+}
+
+// AttachOutlet makes a standalone vendor of the brand owner an outlet of the
+// brand. Its own menu stays; the master menu is added to it.
+func (r *BrandRepository) AttachOutlet(ctx context.Context, ownerID string, payload *vendor.AttachOutletPayload) (*vendor.Outlet, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM brands WHERE id = $1 AND owner_user_id = $2)
+	`, payload.BrandID, ownerID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check brand: %w", err)
+	}
+	if !exists {
+		return nil, ErrBrandNotFound
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE vendors v
+		SET brand_id = b.id,
+		    name = b.name,
+		    outlet_name = COALESCE(@outletName, v.outlet_name)
+		FROM brands b
+		WHERE b.id = @brandId AND v.id = @vendorId
+		  AND v.vendor_user_id = @ownerId AND v.brand_id IS NULL
+	`, pgx.NamedArgs{
+		"brandId":    payload.BrandID,
+		"vendorId":   payload.VendorID,
+		"ownerId":    ownerID,
+		"outletName": payload.OutletName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach outlet: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrOutletNotAttachable
+	}
+
+	outlet, err := getOutlet(ctx, tx, payload.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return outlet, nil
+}
+
+func getOutlet(ctx context.Context, tx pgx.Tx, vendorID string) (*vendor.Outlet, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT `+outletColumns+`
+		FROM vendors v
+		LEFT JOIN LATERAL (
+			SELECT city, street_address FROM vendor_addresses
+			WHERE vendor_id = v.id
+			ORDER BY created_at
+			LIMIT 1
+		) va ON TRUE
+		WHERE v.id = $1
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet: %w", err)
+	}
+	outlet, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Outlet])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect outlet: %w", err)
+	}
+	return &outlet, nil
+}
+
+// UpdateOutletSettings changes the outlet's own listing details: its name
+// within the brand, hours and delivery zone.
+func (r *BrandRepository) UpdateOutletSettings(ctx context.Context, vendorID string, payload *vendor.UpdateOutletSettingsPayload) (*vendor.Vendor, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		UPDATE vendors SET
+			outlet_name = COALESCE(@outletName, outlet_name),
+			is_open = COALESCE(@isOpen, is_open),
+			opening_hours = COALESCE(@openingHours, opening_hours),
+			delivery_radius_km = COALESCE(@deliveryRadiusKm, delivery_radius_km),
+			delivery_fee = COALESCE(@deliveryFee, delivery_fee),
+			min_order_amount = COALESCE(@minOrderAmount, min_order_amount),
+			delivery_time_estimate = COALESCE(@deliveryTimeEstimate, delivery_time_estimate)
+		WHERE id = @id
+		RETURNING *
+	`, pgx.NamedArgs{
+		"id":                   vendorID,
+		"outletName":           payload.OutletName,
+		"isOpen":               payload.IsOpen,
+		"openingHours":         payload.OpeningHours,
+		"deliveryRadiusKm":     payload.DeliveryRadiusKm,
+		"deliveryFee":          payload.DeliveryFee,
+		"minOrderAmount":       payload.MinOrderAmount,
+		"deliveryTimeEstimate": payload.DeliveryTimeEstimate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update outlet settings: %w", err)
+	}
+	v, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Vendor])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor: %w", err)
+	}
+	return &v, nil
+}
+
+// SetOutletPayoutAccount routes the vendor's future earnings to accountID,
+// which must be one of the owner's accounts; nil goes back to the owner's
+// default. Earnings already accrued keep their account.
+func (r *BrandRepository) SetOutletPayoutAccount(ctx context.Context, vendorID string, accountID *string) (*vendor.Outlet, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE vendors v SET payout_account_id = @accountId
+		WHERE v.id = @vendorId
+		  AND (@accountId::TEXT IS NULL OR EXISTS (
+			SELECT 1 FROM payout_accounts pa
+			WHERE pa.id = @accountId AND pa.owner_type = 'vendor' AND pa.owner_id = v.vendor_user_id
+		  ))
+	`, pgx.NamedArgs{"vendorId": vendorID, "accountId": accountID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set outlet payout account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrPayoutAccountNotOwned
+	}
+
+	outlet, err := getOutlet(ctx, tx, vendorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return outlet, nil
+}
+
+//-- ==================================================
+//-- MASTER MENU
+//-- ==================================================
+
+func (r *BrandRepository) GetMasterMenu(ctx context.Context, brandID, ownerID string) ([]vendor.MenuItem, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT mi.*
+		FROM menu_items mi
+		JOIN brands b ON b.id = mi.brand_id
+		WHERE b.id = $1 AND b.owner_user_id = $2
+		ORDER BY mi.category_id, mi.name
+	`, brandID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master menu: %w", err)
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.MenuItem])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect master menu: %w", err)
+	}
+	return items, nil
+}
+
+// CreateMasterMenuItem adds an item to the brand's master menu and lists its
+// category for the brand.
+func (r *BrandRepository) CreateMasterMenuItem(ctx context.Context, ownerID string, payload *vendor.CreateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO menu_items (
+			brand_id, category_id, name, description, base_price, old_price, image,
+			is_available, is_vegetarian, is_vegan, is_popular, is_gluten_free,
+			spicy_level, most_liked_rank, tags, portion_size, keywords
+		)
+		SELECT b.id, @categoryId, @name, COALESCE(@description, ''), @basePrice, COALESCE(@oldPrice, 0),
+		       COALESCE(@image, ''), COALESCE(@isAvailable, TRUE), COALESCE(@isVegetarian, FALSE),
+		       COALESCE(@isVegan, FALSE), COALESCE(@isPopular, FALSE), COALESCE(@isGlutenFree, FALSE),
+		       COALESCE(@spicyLevel, 0), 0, @tags, COALESCE(@portionSize, ''), COALESCE(@keywords, '')
+		FROM brands b
+		WHERE b.id = @brandId AND b.owner_user_id = @ownerId
+		RETURNING *
+	`, pgx.NamedArgs{
+		"brandId":      payload.BrandID,
+		"ownerId":      ownerID,
+		"categoryId":   payload.CategoryID,
+		"name":         payload.Name,
+		"description":  payload.Description,
+		"basePrice":    payload.BasePrice,
+		"oldPrice":     payload.OldPrice,
+		"image":        payload.Image,
+		"isAvailable":  payload.IsAvailable,
+		"isVegetarian": payload.IsVegetarian,
+		"isVegan":      payload.IsVegan,
+		"isPopular":    payload.IsPopular,
+		"isGlutenFree": payload.IsGlutenFree,
+		"spicyLevel":   payload.SpicyLevel,
+		"tags":         payload.Tags,
+		"portionSize":  payload.PortionSize,
+		"keywords":     payload.Keywords,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create master menu item: %w", err)
+	}
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.MenuItem])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect master menu item: %w", err)
+	}
+
+	if err := linkBrandCategory(ctx, tx, payload.BrandID, item.CategoryID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *BrandRepository) UpdateMasterMenuItem(ctx context.Context, ownerID string, payload *vendor.UpdateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE menu_items mi SET
+			category_id = COALESCE(@categoryId, mi.category_id),
+			name = COALESCE(@name, mi.name),
+			description = COALESCE(@description, mi.description),
+			base_price = COALESCE(@basePrice, mi.base_price),
+			old_price = COALESCE(@oldPrice, mi.old_price),
+			image = COALESCE(@image, mi.image),
+			is_available = COALESCE(@isAvailable, mi.is_available),
+			is_vegetarian = COALESCE(@isVegetarian, mi.is_vegetarian),
+			is_vegan = COALESCE(@isVegan, mi.is_vegan),
+			is_popular = COALESCE(@isPopular, mi.is_popular),
+			is_gluten_free = COALESCE(@isGlutenFree, mi.is_gluten_free),
+			spicy_level = COALESCE(@spicyLevel, mi.spicy_level),
+			tags = COALESCE(@tags, mi.tags),
+			portion_size = COALESCE(@portionSize, mi.portion_size),
+			keywords = COALESCE(@keywords, mi.keywords)
+		FROM brands b
+		WHERE mi.id = @itemId AND mi.brand_id = b.id
+		  AND b.id = @brandId AND b.owner_user_id = @ownerId
+		RETURNING mi.*
+	`, pgx.NamedArgs{
+		"brandId":      payload.BrandID,
+		"itemId":       payload.ItemID,
+		"ownerId":      ownerID,
+		"categoryId":   payload.CategoryID,
+		"name":         payload.Name,
+		"description":  payload.Description,
+		"basePrice":    payload.BasePrice,
+		"oldPrice":     payload.OldPrice,
+		"image":        payload.Image,
+		"isAvailable":  payload.IsAvailable,
+		"isVegetarian": payload.IsVegetarian,
+		"isVegan":      payload.IsVegan,
+		"isPopular":    payload.IsPopular,
+		"isGlutenFree": payload.IsGlutenFree,
+		"spicyLevel":   payload.SpicyLevel,
+		"tags":         payload.Tags,
+		"portionSize":  payload.PortionSize,
+		"keywords":     payload.Keywords,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update master menu item: %w", err)
+	}
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.MenuItem])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandMenuItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect master menu item: %w", err)
+	}
+
+	if payload.CategoryID != nil {
+		if err := linkBrandCategory(ctx, tx, payload.BrandID, item.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *BrandRepository) DeleteMasterMenuItem(ctx context.Context, ownerID string, payload *vendor.DeleteBrandMenuItemPayload) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM menu_items mi
+		USING brands b
+		WHERE mi.id = @itemId AND mi.brand_id = b.id
+		  AND b.id = @brandId AND b.owner_user_id = @ownerId
+	`, pgx.NamedArgs{
+		"brandId": payload.BrandID,
+		"itemId":  payload.ItemID,
+		"ownerId": ownerID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete master menu item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBrandMenuItemNotFound
+	}
+	return nil
+}
+
+func linkBrandCategory(ctx context.Context, tx pgx.Tx, brandID, categoryID string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO brand_menu_categories (brand_id, category_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, brandID, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to link brand menu category: %w", err)
+	}
+	return nil
+}
+
+// GetOutletMenuStates returns how each outlet of the item's brand sells a
+// master menu item after its overrides.
+func (r *BrandRepository) GetOutletMenuStates(ctx context.Context, menuItemID string) ([]vendor.OutletMenuState, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT vendor_id, id, base_price, is_available
+		FROM vendor_menu_items
+		WHERE id = $1 AND brand_id IS NOT NULL
+	`, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet menu states: %w", err)
+	}
+	states, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.OutletMenuState])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect outlet menu states: %w", err)
+	}
+	return states, nil
+}
+
+//-- ==================================================
+//-- OUTLET MENU OVERRIDES
+//-- ==================================================
+
+func (r *BrandRepository) GetMenuOverrides(ctx context.Context, vendorID string) ([]vendor.PopulatedOutletMenuOverride, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT o.*, mi.name, mi.base_price AS master_price,
+		       COALESCE(mi.is_available, FALSE) AS master_is_available
+		FROM outlet_menu_overrides o
+		JOIN menu_items mi ON mi.id = o.menu_item_id
+		WHERE o.vendor_id = $1
+		ORDER BY mi.name
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu overrides: %w", err)
+	}
+	overrides, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.PopulatedOutletMenuOverride])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// SetMenuOverride sets the outlet's price and availability of a master menu
+// item of its brand.
+func (r *BrandRepository) SetMenuOverride(ctx context.Context, vendorID string, payload *vendor.SetMenuOverridePayload) (*vendor.OutletMenuOverride, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO outlet_menu_overrides (vendor_id, menu_item_id, price, is_available)
+		SELECT v.id, mi.id, @price, @isAvailable
+		FROM vendors v
+		JOIN menu_items mi ON mi.brand_id = v.brand_id
+		WHERE v.id = @vendorId AND mi.id = @menuItemId
+		ON CONFLICT (vendor_id, menu_item_id) DO UPDATE
+		SET price = EXCLUDED.price,
+		    is_available = EXCLUDED.is_available
+		RETURNING *
+	`, pgx.NamedArgs{
+		"vendorId":    vendorID,
+		"menuItemId":  payload.MenuItemID,
+		"price":       payload.Price,
+		"isAvailable": payload.IsAvailable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set menu override: %w", err)
+	}
+	override, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.OutletMenuOverride])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandMenuItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu override: %w", err)
+	}
+	return &override, nil
+}
+
+func (r *BrandRepository) DeleteMenuOverride(ctx context.Context, vendorID, menuItemID string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM outlet_menu_overrides WHERE vendor_id = $1 AND menu_item_id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return fmt.Errorf("failed to delete menu override: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMenuOverrideNotFound
+	}
+	return nil
+}
+
+// GetOutletMenuState returns how the vendor now sells a menu item.
+func (r *BrandRepository) GetOutletMenuState(ctx context.Context, vendorID, menuItemID string) (*vendor.OutletMenuState, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT vendor_id, id, base_price, is_available
+		FROM vendor_menu_items
+		WHERE vendor_id = $1 AND id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet menu state: %w", err)
+	}
+	state, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.OutletMenuState])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBrandMenuItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect outlet menu state: %w", err)
+	}
+	return &state, nil
+}
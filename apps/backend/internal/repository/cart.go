@@ -156,7 +156,7 @@ func (r *CartRepository) GetActiveCartsByUserID(ctx context.Context, userID stri
 					)
 				) AS cart_items
 			FROM cart_items ci
-			JOIN menu_items mi ON mi.id = ci.menu_item_id
+			JOIN vendor_menu_items mi ON mi.id = ci.menu_item_id AND mi.vendor_id = cv.vendor_id
 			WHERE ci.cart_vendor_id = cv.id
 		) ci ON TRUE
 		WHERE cv.cart_session_id = @cartSessionId 
@@ -437,6 +437,44 @@ func (r *CartRepository) GetCartVendor(ctx context.Context, tx pgx.Tx, sessionID
 }
 
 
+// ------------------- VENDOR ORDERABLE -------------------
+// IsVendorOrderable reports whether the vendor is active; only active vendors
+// take orders. An unknown vendor is not orderable.
+func (r *CartRepository) IsVendorOrderable(ctx context.Context, tx pgx.Tx, vendorID string) (bool, error) {
+	var orderable bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM vendors WHERE id = $1 AND status = 'active')
+	`, vendorID).Scan(&orderable)
+	if err != nil {
+		return false, fmt.Errorf("failed to check vendor status: %w", err)
+	}
+	return orderable, nil
+}
+
+// ------------------- VENDOR DELIVERS TO -------------------
+// IsWithinDeliveryZone reports whether the delivery address is within the
+// vendor's delivery radius. Vendors without a radius, and addresses or vendors
+// without coordinates, are not limited.
+func (r *CartRepository) IsWithinDeliveryZone(ctx context.Context, tx pgx.Tx, vendorID, addressID string) (bool, error) {
+	var outside bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM vendors v
+			JOIN vendor_addresses va ON va.vendor_id = v.id
+			JOIN user_addresses ua ON ua.id = $2
+			WHERE v.id = $1 AND v.delivery_radius_km IS NOT NULL
+			  AND va.latitude IS NOT NULL AND va.longitude IS NOT NULL
+			  AND ua.latitude IS NOT NULL AND ua.longitude IS NOT NULL
+			  AND distance_km(va.latitude, va.longitude, ua.latitude, ua.longitude) > v.delivery_radius_km
+		)
+	`, vendorID, addressID).Scan(&outside)
+	if err != nil {
+		return false, fmt.Errorf("failed to check delivery zone: %w", err)
+	}
+	return !outside, nil
+}
+
 // ------------------- GET CART VENDOR -------------------
 func (r *CartRepository) GetCartVendorByID(ctx context.Context, tx pgx.Tx, id string) (*cart.CartVendor, error) {
 	stmt := `SELECT * FROM cart_vendors WHERE id = $1 LIMIT 1`
@@ -910,6 +948,8 @@ func (r *CartRepository) GetCartTotals(
 			cv.vendor_service_charge,
 			cv.vat,
 			cv.vendor_discount,
+			cv.loyalty_points_used,
+			cv.loyalty_discount,
 			cv.delivery_charge,
 			va.latitude,
 			va.longitude
@@ -920,6 +960,7 @@ func (r *CartRepository) GetCartTotals(
 
 	var (
 		total, subtotal, vendorServiceCharge, vat, vendorDiscount float64
+		loyaltyPointsUsed, loyaltyDiscount                        float64
 		vendorLat, vendorLng                                      float64
 		deliveryCharge                                            sql.NullFloat64
 	)
@@ -933,6 +974,8 @@ func (r *CartRepository) GetCartTotals(
 		&vendorServiceCharge,
 		&vat,
 		&vendorDiscount,
+		&loyaltyPointsUsed,
+		&loyaltyDiscount,
 		&deliveryCharge,
 		&vendorLat,
 		&vendorLng,
@@ -985,6 +1028,8 @@ func (r *CartRepository) GetCartTotals(
 		VendorServiceCharge:   vendorServiceCharge,
 		VAT:                   vat,
 		VendorDiscount:        vendorDiscount,
+		LoyaltyPointsUsed:     loyaltyPointsUsed,
+		LoyaltyDiscount:       loyaltyDiscount,
 		DeliveryFee:           calculatedFee,
 		EstimatedDeliveryTime: estimatedTime,
 		Total:                 total,
@@ -1016,6 +1061,9 @@ func (r *CartRepository) ApplyCoupon(ctx context.Context, payload *coupon.ApplyC
     if c.VendorID != nil && *c.VendorID != payload.VendorID {
         return nil, fmt.Errorf("coupon not valid for this vendor")
     }
+    if c.UserID != nil && *c.UserID != payload.UserID {
+        return nil, fmt.Errorf("coupon not valid for this account")
+    }
 
     // 3️⃣ Check validity window
     now := time.Now()
@@ -1067,3 +1115,84 @@ func (r *CartRepository) ApplyCoupon(ctx context.Context, payload *coupon.ApplyC
     return &discount, nil
 }
 
+
+
+//-- ==================================================
+//-- APPLY LOYALTY POINTS
+//-- ==================================================
+
+// ApplyLoyaltyPoints sets how many points the user spends on one vendor of
+// the cart. The request is capped at the spendable balance (minus points held
+// by the user's other carts) and at max_redeem_percent of the subtotal. The
+// points are only reserved here; they are written to the ledger when the
+// order is paid. Applying 0 removes the points.
+func (r *CartRepository) ApplyLoyaltyPoints(ctx context.Context, userID string, payload *cart.ApplyLoyaltyPointsPayload) (*cart.CartVendor, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ Lock the cart vendor, it must be an active cart of this user
+	var subtotal float64
+	err = tx.QueryRow(ctx, `
+		SELECT cv.subtotal
+		FROM cart_vendors cv
+		JOIN cart_sessions cs ON cs.id = cv.cart_session_id
+		WHERE cv.id = $1 AND cs.user_id = $2 AND cv.status = 'active'
+		FOR UPDATE OF cv
+	`, payload.CartVendorID, userID).Scan(&subtotal)
+	if err != nil {
+		return nil, err
+	}
+
+	points := math.Floor(payload.Points)
+	if points > 0 {
+		// 2️⃣ Spendable balance, less what other carts hold
+		balance, err := lockLoyaltyBalance(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		reserved, err := reservedLoyaltyPoints(ctx, tx, userID, payload.CartVendorID)
+		if err != nil {
+			return nil, err
+		}
+		if available := balance - reserved; points > available {
+			return nil, ErrInsufficientPoints
+		}
+
+		// 3️⃣ Cap at the share of the subtotal points may pay for
+		var nprPerPoint, maxPercent float64
+		if err := tx.QueryRow(ctx, `SELECT npr_per_point, max_redeem_percent FROM loyalty_settings`).Scan(&nprPerPoint, &maxPercent); err != nil {
+			return nil, fmt.Errorf("failed to load loyalty settings: %w", err)
+		}
+		points = math.Min(points, math.Floor(subtotal*maxPercent/100/nprPerPoint))
+
+		_, err = tx.Exec(ctx, `
+			UPDATE cart_vendors
+			SET loyalty_points_used = @points,
+			    loyalty_discount = ROUND(@points * @nprPerPoint, 2)
+			WHERE id = @id
+		`, pgx.NamedArgs{"id": payload.CartVendorID, "points": points, "nprPerPoint": nprPerPoint})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply loyalty points: %w", err)
+		}
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE cart_vendors SET loyalty_points_used = 0, loyalty_discount = 0 WHERE id = $1`,
+			payload.CartVendorID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove loyalty points: %w", err)
+		}
+	}
+
+	cv, err := r.GetCartVendorByID(ctx, tx, payload.CartVendorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return cv, nil
+}
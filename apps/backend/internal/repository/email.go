@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------- EMAIL REPOSITORY ----------------
+
+type EmailRepository struct {
+	server *server.Server
+}
+
+func NewEmailRepository(s *server.Server) *EmailRepository {
+	return &EmailRepository{server: s}
+}
+
+//-- ==================================================
+//-- EMAIL LOG
+//-- ==================================================
+
+func (r *EmailRepository) RecordEmail(ctx context.Context, entry *email.LogEntry) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		INSERT INTO email_log (provider, provider_message_id, template, to_address, subject, status, error, sent_at)
+		VALUES (@provider, NULLIF(@message_id, ''), NULLIF(@template, ''), @to, @subject, @status, NULLIF(@error, ''),
+		        CASE WHEN @status = 'sent' THEN NOW() END)
+	`, pgx.NamedArgs{
+		"provider":   entry.Provider,
+		"message_id": entry.ProviderMessageID,
+		"template":   entry.Template,
+		"to":         entry.To,
+		"subject":    entry.Subject,
+		"status":     entry.Status,
+		"error":      entry.Error,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record email: %w", err)
+	}
+	return nil
+}
+
+// ApplyDeliveryEvent moves a logged email to the status the provider
+// reported. Webhooks can arrive out of order, so a bounce or complaint is
+// never overwritten and a delivered email does not fall back to delayed.
+// It returns false when no email has the message id.
+func (r *EmailRepository) ApplyDeliveryEvent(ctx context.Context, messageID, status, detail string) (bool, error) {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE email_log
+		SET status = @status,
+		    error = COALESCE(NULLIF(@detail, ''), error),
+		    delivered_at = CASE WHEN @status = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE provider_message_id = @message_id
+		  AND status NOT IN ('bounced', 'complained')
+		  AND NOT (status = 'delivered' AND @status = 'delivery_delayed')
+	`, pgx.NamedArgs{"message_id": messageID, "status": status, "detail": detail})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply email delivery event: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	var exists bool
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM email_log WHERE provider_message_id = $1)
+	`, messageID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up email: %w", err)
+	}
+	return exists, nil
+}
+
+//-- ==================================================
+//-- SUPPRESSIONS
+//-- ==================================================
+
+func (r *EmailRepository) IsSuppressed(ctx context.Context, address string) (bool, error) {
+	var reason string
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT reason FROM email_suppressions WHERE address = $1
+	`, strings.ToLower(strings.TrimSpace(address))).Scan(&reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check email suppression: %w", err)
+	}
+	return true, nil
+}
+
+// Suppress stops further emails to an address. The first reason is kept.
+func (r *EmailRepository) Suppress(ctx context.Context, address, reason, detail, messageID string) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		INSERT INTO email_suppressions (address, reason, detail, provider_message_id)
+		VALUES (@address, @reason, NULLIF(@detail, ''), NULLIF(@message_id, ''))
+		ON CONFLICT (address) DO NOTHING
+	`, pgx.NamedArgs{
+		"address":    strings.ToLower(strings.TrimSpace(address)),
+		"reason":     reason,
+		"detail":     detail,
+		"message_id": messageID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to suppress email address: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrImageMenuItemNotFound = errors.New("menu item not found on the vendor's menu")
+	ErrImageUploadNotFound   = errors.New("image upload not found")
+	ErrImageUploadNotPending = errors.New("image upload already completed or expired")
+)
+
+// ---------------- IMAGE REPOSITORY ----------------
+
+type ImageRepository struct {
+	server *server.Server
+}
+
+func NewImageRepository(s *server.Server) *ImageRepository {
+	return &ImageRepository{server: s}
+}
+
+// imageInUse is true while a menu item, vendor or brand points at a variant
+// of the image i.
+const imageInUse = `(
+	EXISTS (SELECT 1 FROM menu_items WHERE image_hash(image) = i.hash)
+	OR EXISTS (SELECT 1 FROM vendors WHERE image_hash(vendor_listing_image_name) = i.hash)
+	OR EXISTS (SELECT 1 FROM vendors WHERE image_hash(vendor_logo_image_name) = i.hash)
+	OR EXISTS (SELECT 1 FROM brands WHERE image_hash(logo_image_name) = i.hash)
+)`
+
+//-- ==================================================
+//-- UPLOADS
+//-- ==================================================
+
+// LockImage serializes the uploads and the cleanup of a file for the rest
+// of the transaction, so the cleanup never deletes objects just stored again.
+func (r *ImageRepository) LockImage(ctx context.Context, tx pgx.Tx, hash string) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('image:' || $1))`, hash); err != nil {
+		return fmt.Errorf("failed to lock image %s: %w", hash, err)
+	}
+	return nil
+}
+
+func (r *ImageRepository) CreateImage(ctx context.Context, tx pgx.Tx, img *vendor.Image) (*vendor.Image, error) {
+	rows, err := tx.Query(ctx, `
+		INSERT INTO images (
+			vendor_id, menu_item_id, purpose, hash, content_type, width, height, variants, url, uploaded_by
+		)
+		VALUES (@vendorId, @menuItemId, @purpose, @hash, @contentType, @width, @height, @variants, @url, @uploadedBy)
+		RETURNING *
+	`, pgx.NamedArgs{
+		"vendorId":    img.VendorID,
+		"menuItemId":  img.MenuItemID,
+		"purpose":     img.Purpose,
+		"hash":        img.Hash,
+		"contentType": img.ContentType,
+		"width":       img.Width,
+		"height":      img.Height,
+		"variants":    img.Variants,
+		"url":         img.URL,
+		"uploadedBy":  img.UploadedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image: %w", err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Image])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect image: %w", err)
+	}
+	return &created, nil
+}
+
+// MenuItemExists reports whether the vendor sells its own menu item of that
+// id. Master items of a brand get their images through the brand.
+func (r *ImageRepository) MenuItemExists(ctx context.Context, vendorID, menuItemID string) (bool, error) {
+	var exists bool
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM menu_items WHERE id = $1 AND vendor_id = $2)
+	`, menuItemID, vendorID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check menu item: %w", err)
+	}
+	return exists, nil
+}
+
+// AttachMenuItemImage shows an image on a menu item, replacing its previous
+// image.
+func (r *ImageRepository) AttachMenuItemImage(ctx context.Context, tx pgx.Tx, vendorID, menuItemID, url string) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE menu_items SET image = $3 WHERE id = $2 AND vendor_id = $1
+	`, vendorID, menuItemID, url)
+	if err != nil {
+		return fmt.Errorf("failed to attach menu item image: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrImageMenuItemNotFound
+	}
+	return nil
+}
+
+// AttachVendorImage sets the listing image or logo of a vendor.
+func (r *ImageRepository) AttachVendorImage(ctx context.Context, tx pgx.Tx, vendorID, purpose, url string) error {
+	column := "vendor_listing_image_name"
+	if purpose == vendor.ImagePurposeVendorLogo {
+		column = "vendor_logo_image_name"
+	}
+	tag, err := tx.Exec(ctx, `UPDATE vendors SET `+column+` = $2 WHERE id = $1`, vendorID, url)
+	if err != nil {
+		return fmt.Errorf("failed to attach vendor image: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVendorNotFound
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- DIRECT UPLOADS
+//-- ==================================================
+
+func (r *ImageRepository) CreateUpload(ctx context.Context, upload *vendor.ImageUpload) (*vendor.ImageUpload, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO image_uploads (
+			vendor_id, menu_item_id, purpose, object_key, content_type, size, method, uploaded_by, expires_at
+		)
+		VALUES (@vendorId, @menuItemId, @purpose, @objectKey, @contentType, @size, @method, @uploadedBy, @expiresAt)
+		RETURNING *
+	`, pgx.NamedArgs{
+		"vendorId":    upload.VendorID,
+		"menuItemId":  upload.MenuItemID,
+		"purpose":     upload.Purpose,
+		"objectKey":   upload.ObjectKey,
+		"contentType": upload.ContentType,
+		"size":        upload.Size,
+		"method":      upload.Method,
+		"uploadedBy":  upload.UploadedBy,
+		"expiresAt":   upload.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image upload: %w", err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.ImageUpload])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect image upload: %w", err)
+	}
+	return &created, nil
+}
+
+// GetUpload returns a direct upload the user started for the vendor.
+func (r *ImageRepository) GetUpload(ctx context.Context, vendorID, userID, id string) (*vendor.ImageUpload, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM image_uploads
+		WHERE id = $1 AND vendor_id = $2 AND uploaded_by = $3
+	`, id, vendorID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image upload: %w", err)
+	}
+	upload, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.ImageUpload])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrImageUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to collect image upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// CompleteUpload records the image a direct upload became. It fails with
+// ErrImageUploadNotPending if the upload was completed meanwhile or the
+// cleanup got to it first.
+func (r *ImageRepository) CompleteUpload(ctx context.Context, tx pgx.Tx, id, imageID string, window time.Duration) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE image_uploads
+		SET status = 'completed', image_id = @imageId
+		WHERE id = @id
+		  AND status = 'pending'
+		  AND expires_at > NOW() - make_interval(secs => @window)
+	`, pgx.NamedArgs{"id": id, "imageId": imageID, "window": window.Seconds()})
+	if err != nil {
+		return fmt.Errorf("failed to complete image upload: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrImageUploadNotPending
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- CLEANUP
+//-- ==================================================
+
+// GetExpiredUploads returns direct uploads that can no longer be completed.
+func (r *ImageRepository) GetExpiredUploads(ctx context.Context, window time.Duration, limit int) ([]vendor.ExpiredImageUpload, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT id, object_key
+		FROM image_uploads
+		WHERE status = 'pending'
+		  AND expires_at <= NOW() - make_interval(secs => @window)
+		ORDER BY expires_at
+		LIMIT @limit
+	`, pgx.NamedArgs{"window": window.Seconds(), "limit": limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired image uploads: %w", err)
+	}
+	uploads, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.ExpiredImageUpload])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect expired image uploads: %w", err)
+	}
+	return uploads, nil
+}
+
+// DeleteExpiredUpload removes a direct upload if it is still pending. The
+// caller deletes the staging object before committing.
+func (r *ImageRepository) DeleteExpiredUpload(ctx context.Context, tx pgx.Tx, id string, window time.Duration) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM image_uploads
+		WHERE id = @id
+		  AND status = 'pending'
+		  AND expires_at <= NOW() - make_interval(secs => @window)
+	`, pgx.NamedArgs{"id": id, "window": window.Seconds()})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image upload %s: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetUnusedImages returns files no longer shown anywhere whose uploads are
+// all older than grace, with the objects of their variants.
+func (r *ImageRepository) GetUnusedImages(ctx context.Context, grace time.Duration, limit int) ([]vendor.UnusedImage, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT i.hash, array_agg(DISTINCT v->>'key') AS keys
+		FROM images i
+		CROSS JOIN LATERAL jsonb_array_elements(i.variants) v
+		GROUP BY i.hash
+		HAVING MAX(i.created_at) < NOW() - make_interval(secs => @grace)
+		   AND NOT `+imageInUse+`
+		LIMIT @limit
+	`, pgx.NamedArgs{"grace": grace.Seconds(), "limit": limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unused images: %w", err)
+	}
+	images, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.UnusedImage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect unused images: %w", err)
+	}
+	return images, nil
+}
+
+// DeleteUnusedImage removes the records of a file if it is still unused and
+// was not uploaded again since GetUnusedImages. The caller deletes the
+// objects before committing.
+func (r *ImageRepository) DeleteUnusedImage(ctx context.Context, tx pgx.Tx, hash string, grace time.Duration) (bool, error) {
+	if err := r.LockImage(ctx, tx, hash); err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM images
+		WHERE hash = @hash
+		  AND NOT EXISTS (
+		      SELECT 1 FROM images i
+		      WHERE i.hash = @hash
+		        AND (i.created_at >= NOW() - make_interval(secs => @grace) OR `+imageInUse+`)
+		  )
+	`, pgx.NamedArgs{"hash": hash, "grace": grace.Seconds()})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image %s: %w", hash, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gitSanje/khajaride/internal/model/invoice"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrOrderNotPaid = errors.New("order is not paid")
+
+// ---------------- INVOICE REPOSITORY ----------------
+
+type InvoiceRepository struct {
+	server *server.Server
+}
+
+func NewInvoiceRepository(s *server.Server) *InvoiceRepository {
+	return &InvoiceRepository{server: s}
+}
+
+func (r *InvoiceRepository) GetInvoiceByOrderID(ctx context.Context, orderID string) (*invoice.Invoice, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM invoices WHERE order_id = @orderId`, pgx.NamedArgs{"orderId": orderID})
+	if err != nil {
+		return nil, err
+	}
+	inv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[invoice.Invoice])
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// CreateInvoice issues the invoice of a paid order with the vendor's next
+// invoice number. The counter row is locked for the duration of the
+// transaction, so numbers are sequential and, because a failed insert rolls
+// the counter back, gapless.
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, orderID string) (*invoice.Invoice, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var vendorID, paymentStatus string
+	err = tx.QueryRow(ctx,
+		`SELECT vendor_id, payment_status FROM order_vendors WHERE id = $1 FOR UPDATE`, orderID,
+	).Scan(&vendorID, &paymentStatus)
+	if err != nil {
+		return nil, err
+	}
+	if paymentStatus == "unpaid" {
+		return nil, ErrOrderNotPaid
+	}
+
+	// Another request may have issued it while we waited for the lock
+	existing, err := tx.Query(ctx, `SELECT * FROM invoices WHERE order_id = @orderId`, pgx.NamedArgs{"orderId": orderID})
+	if err != nil {
+		return nil, err
+	}
+	if inv, err := pgx.CollectOneRow(existing, pgx.RowToStructByName[invoice.Invoice]); err == nil {
+		return &inv, nil
+	} else if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	var seq int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO vendor_invoice_sequences (vendor_id, last_number)
+		VALUES ($1, 1)
+		ON CONFLICT (vendor_id) DO UPDATE SET last_number = vendor_invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, vendorID).Scan(&seq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate invoice number: %w", err)
+	}
+
+	query := `
+		INSERT INTO invoices (
+			order_id, vendor_id, user_id, sequence_number, invoice_number,
+			vendor_name, vendor_pan_vat, vendor_address, customer_name, customer_email,
+			subtotal, delivery_charge, service_charge, service_charge_rate,
+			vat, vat_rate, discount, loyalty_discount, total, currency
+		)
+		SELECT
+			ov.id,
+			v.id,
+			ov.user_id,
+			@seq,
+			'INV-' || UPPER(LEFT(REPLACE(v.id, '-', ''), 6)) || '-' || LPAD(@seq::TEXT, 6, '0'),
+			v.name,
+			(
+				SELECT vd.document_number FROM vendor_documents vd
+				WHERE vd.vendor_user_id = v.vendor_user_id
+				  AND vd.document_type = 'pan_vat_registration'
+				  AND vd.status = 'approved'
+				ORDER BY vd.reviewed_at DESC NULLS LAST
+				LIMIT 1
+			),
+			(
+				SELECT CONCAT_WS(', ', va.street_address, va.city, va.state) FROM vendor_addresses va
+				WHERE va.vendor_id = v.id
+				ORDER BY va.created_at
+				LIMIT 1
+			),
+			u.username,
+			u.email,
+			ov.subtotal,
+			COALESCE(ov.delivery_charge, 0),
+			COALESCE(ov.vendor_service_charge, 0),
+			COALESCE(v.vendor_service_charge, 0),
+			COALESCE(ov.vat, 0),
+			COALESCE(v.vat, 0),
+			COALESCE(ov.vendor_discount, 0),
+			ov.loyalty_discount,
+			ov.total,
+			COALESCE(ov.currency, 'USD')
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		JOIN users u ON u.id = ov.user_id
+		WHERE ov.id = @orderId
+		RETURNING *
+	`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"orderId": orderID, "seq": seq})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+	inv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[invoice.Invoice])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invoice: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *InvoiceRepository) ListInvoiceLines(ctx context.Context, orderID string) ([]invoice.InvoiceLine, error) {
+	query := `
+		SELECT
+			mi.name,
+			oi.quantity,
+			oi.unit_price,
+			COALESCE(oi.discount_amount, 0) AS discount_amount,
+			oi.subtotal
+		FROM order_items oi
+		JOIN menu_items mi ON mi.id = oi.menu_item_id
+		WHERE oi.order_vendor_id = @orderId
+		ORDER BY oi.created_at
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"orderId": orderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoice lines: %w", err)
+	}
+	lines, err := pgx.CollectRows(rows, pgx.RowToStructByName[invoice.InvoiceLine])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invoice lines: %w", err)
+	}
+	return lines, nil
+}
+
+func (r *InvoiceRepository) SetInvoiceFileKey(ctx context.Context, invoiceID, fileKey string) error {
+	_, err := r.server.DB.Pool.Exec(ctx,
+		`UPDATE invoices SET file_key = @fileKey WHERE id = @id`,
+		pgx.NamedArgs{"id": invoiceID, "fileKey": fileKey},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store invoice file key: %w", err)
+	}
+	return nil
+}
+
+// GetInvoiceParties returns the customer of an order and userID's role at
+// the vendor that fulfils it ("" when they are not a member), which decide
+// who may download its invoice.
+func (r *InvoiceRepository) GetInvoiceParties(ctx context.Context, orderID, userID string) (customerID string, vendorRole string, err error) {
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		SELECT ov.user_id,
+		       COALESCE((
+				SELECT m.role FROM vendor_members m
+				WHERE m.vendor_id = ov.vendor_id AND m.user_id = $2
+		       ), '')
+		FROM order_vendors ov
+		WHERE ov.id = $1
+	`, orderID, userID).Scan(&customerID, &vendorRole)
+	return customerID, vendorRole, err
+}
@@ -0,0 +1,524 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/ledger"
+	"github.com/gitSanje/khajaride/internal/model/settlement"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry debits and credits do not match")
+
+// ---------------- LEDGER REPOSITORY ----------------
+
+type LedgerRepository struct {
+	server *server.Server
+}
+
+func NewLedgerRepository(s *server.Server) *LedgerRepository {
+	return &LedgerRepository{server: s}
+}
+
+func cents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+//-- ==================================================
+//-- POSTING
+//-- ==================================================
+
+// PostEntry writes a balanced journal entry in the caller's transaction, so
+// it commits or rolls back with the money movement it records. Posting the
+// same idempotency key twice returns the entry that is already there.
+func (r *LedgerRepository) PostEntry(ctx context.Context, tx pgx.Tx, e *ledger.NewEntry) (*ledger.JournalEntry, error) {
+	var debit, credit float64
+	postings := make([]ledger.Posting, 0, len(e.Postings))
+	for _, p := range e.Postings {
+		p.Debit, p.Credit = cents(p.Debit), cents(p.Credit)
+		if p.Debit == 0 && p.Credit == 0 {
+			continue
+		}
+		if p.Debit < 0 || p.Credit < 0 || (p.Debit != 0 && p.Credit != 0) {
+			return nil, fmt.Errorf("%w: invalid posting on %s", ErrUnbalancedEntry, p.Account.Code)
+		}
+		debit += p.Debit
+		credit += p.Credit
+		postings = append(postings, p)
+	}
+	if len(postings) < 2 || cents(debit) != cents(credit) {
+		return nil, fmt.Errorf("%w: debit %.2f credit %.2f", ErrUnbalancedEntry, debit, credit)
+	}
+
+	currency := e.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO journal_entries (entry_type, description, reference_type, reference_id, idempotency_key, currency)
+		VALUES (@entryType, NULLIF(@description, ''), NULLIF(@referenceType, ''), NULLIF(@referenceId, ''), @idempotencyKey, @currency)
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING *
+	`, pgx.NamedArgs{
+		"entryType":      e.EntryType,
+		"description":    e.Description,
+		"referenceType":  e.ReferenceType,
+		"referenceId":    e.ReferenceID,
+		"idempotencyKey": e.IdempotencyKey,
+		"currency":       currency,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ledger.JournalEntry])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			// already posted
+			return getEntryByIdempotencyKey(ctx, tx, e.IdempotencyKey)
+		}
+		return nil, fmt.Errorf("failed to collect journal entry: %w", err)
+	}
+
+	for _, p := range postings {
+		accountID, err := r.ensureAccount(ctx, tx, p.Account, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := tx.Query(ctx, `
+			INSERT INTO journal_lines (entry_id, account_id, debit, credit)
+			VALUES (@entryId, @accountId, @debit, @credit)
+			RETURNING *
+		`, pgx.NamedArgs{
+			"entryId":   entry.ID,
+			"accountId": accountID,
+			"debit":     p.Debit,
+			"credit":    p.Credit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert journal line: %w", err)
+		}
+		line, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ledger.JournalLine])
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect journal line: %w", err)
+		}
+		entry.Lines = append(entry.Lines, line)
+	}
+	return &entry, nil
+}
+
+func (r *LedgerRepository) ensureAccount(ctx context.Context, tx pgx.Tx, a ledger.Account, currency string) (string, error) {
+	var id string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO ledger_accounts (code, name, account_type, owner_type, owner_id, currency)
+		VALUES (@code, @name, @accountType, @ownerType, @ownerId, @currency)
+		ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+		RETURNING id
+	`, pgx.NamedArgs{
+		"code":        a.Code,
+		"name":        a.Name,
+		"accountType": a.AccountType,
+		"ownerType":   a.OwnerType,
+		"ownerId":     a.OwnerID,
+		"currency":    currency,
+	}).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to ensure ledger account %s: %w", a.Code, err)
+	}
+	return id, nil
+}
+
+func getEntryByIdempotencyKey(ctx context.Context, tx pgx.Tx, key string) (*ledger.JournalEntry, error) {
+	rows, err := tx.Query(ctx, `SELECT * FROM journal_entries WHERE idempotency_key = @key`, pgx.NamedArgs{"key": key})
+	if err != nil {
+		return nil, err
+	}
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ledger.JournalEntry])
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+//-- ==================================================
+//-- BUSINESS POSTINGS
+//-- ==================================================
+
+// orderEarning is the accrued earning row an order's postings are derived
+// from, so the ledger and the settlement always agree on the amounts.
+type orderEarning struct {
+	OrderID      string  `db:"order_id"`
+	CustomerID   string  `db:"customer_id"`
+	VendorUserID *string `db:"vendor_user_id"`
+	GrossAmount  float64 `db:"gross_amount"`
+	Commission   float64 `db:"commission"`
+	Currency     string  `db:"currency"`
+}
+
+func (r *LedgerRepository) getOrderEarning(ctx context.Context, tx pgx.Tx, orderVendorID, entryType string) (*orderEarning, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT e.order_id, ov.user_id AS customer_id, e.vendor_user_id, e.gross_amount, e.commission,
+		       COALESCE(ov.currency, 'USD') AS currency
+		FROM vendor_earnings e
+		JOIN order_vendors ov ON ov.id = e.order_id
+		WHERE e.order_id = @orderId AND e.entry_type = @entryType
+	`, pgx.NamedArgs{"orderId": orderVendorID, "entryType": entryType})
+	if err != nil {
+		return nil, err
+	}
+	earning, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[orderEarning])
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s earning of order %s: %w", entryType, orderVendorID, err)
+	}
+	if earning.VendorUserID == nil {
+		return nil, fmt.Errorf("order %s has no vendor user", orderVendorID)
+	}
+	return &earning, nil
+}
+
+// RecordOrderCapture posts a paid order: the gateway holds the money, the
+// vendor is owed it, and the platform takes its commission out of that.
+func (r *LedgerRepository) RecordOrderCapture(ctx context.Context, tx pgx.Tx, orderVendorID, gateway string) error {
+	e, err := r.getOrderEarning(ctx, tx, orderVendorID, "order")
+	if err != nil {
+		return err
+	}
+	vendor := ledger.VendorPayableAccount(*e.VendorUserID)
+
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryPaymentCapture,
+		Description:    "Payment for order " + orderVendorID,
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "capture:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.GatewayClearingAccount(gateway), Debit: e.GrossAmount},
+			{Account: vendor, Credit: e.GrossAmount},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.Commission == 0 {
+		return nil
+	}
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryCommission,
+		Description:    "Commission on order " + orderVendorID,
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "commission:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: vendor, Debit: e.Commission},
+			{Account: ledger.PlatformRevenueAccount(), Credit: e.Commission},
+		},
+	})
+	return err
+}
+
+// RecordOrderGroupCapture posts every order of a paid order group.
+func (r *LedgerRepository) RecordOrderGroupCapture(ctx context.Context, tx pgx.Tx, orderGroupID, gateway string) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM order_vendors WHERE order_group_id = $1`, orderGroupID)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := r.RecordOrderCapture(ctx, tx, id, gateway); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordRefund moves a refunded order from the vendor (and the commission
+// from the platform) to the customer, who is owed it until it is disbursed.
+func (r *LedgerRepository) RecordRefund(ctx context.Context, tx pgx.Tx, orderVendorID string) error {
+	e, err := r.getOrderEarning(ctx, tx, orderVendorID, "refund")
+	if err != nil {
+		return err
+	}
+	// refund earnings are negative
+	amount, commission := -e.GrossAmount, -e.Commission
+
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryRefund,
+		Description:    "Refund of order " + orderVendorID,
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "refund:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.VendorPayableAccount(*e.VendorUserID), Debit: amount - commission},
+			{Account: ledger.PlatformRevenueAccount(), Debit: commission},
+			{Account: ledger.CustomerWalletAccount(e.CustomerID), Credit: amount},
+		},
+	})
+	return err
+}
+
+// RecordRefundDisbursed posts a refund that has been sent back through the gateway.
+func (r *LedgerRepository) RecordRefundDisbursed(ctx context.Context, tx pgx.Tx, orderVendorID, gateway, refundRef string) error {
+	e, err := r.getOrderEarning(ctx, tx, orderVendorID, "refund")
+	if err != nil {
+		return err
+	}
+
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryRefundDisbursed,
+		Description:    "Refund " + refundRef + " sent via " + gateway,
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "refund_disbursed:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.CustomerWalletAccount(e.CustomerID), Debit: -e.GrossAmount},
+			{Account: ledger.GatewayClearingAccount(gateway), Credit: -e.GrossAmount},
+		},
+	})
+	return err
+}
+
+// RecordTransfer posts a vendor's share of an order moving to its connected
+// Stripe account, either as a destination charge or as a group transfer: the
+// vendor is no longer owed it and it has left the platform's Stripe balance.
+func (r *LedgerRepository) RecordTransfer(ctx context.Context, tx pgx.Tx, orderVendorID string) error {
+	e, err := r.getOrderEarning(ctx, tx, orderVendorID, "order")
+	if err != nil {
+		return err
+	}
+
+	share := e.GrossAmount - e.Commission
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryTransfer,
+		Description:    "Transfer of order " + orderVendorID + " to the connected account",
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "transfer:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.VendorPayableAccount(*e.VendorUserID), Debit: share},
+			{Account: ledger.GatewayClearingAccount("stripe"), Credit: share},
+		},
+	})
+	return err
+}
+
+// RecordTransferReversal posts the vendor's share of a refund being pulled
+// back from its connected account.
+func (r *LedgerRepository) RecordTransferReversal(ctx context.Context, tx pgx.Tx, orderVendorID string) error {
+	e, err := r.getOrderEarning(ctx, tx, orderVendorID, "refund")
+	if err != nil {
+		return err
+	}
+
+	// refund earnings are negative
+	share := -(e.GrossAmount - e.Commission)
+	_, err = r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryTransferReversal,
+		Description:    "Transfer reversal of order " + orderVendorID,
+		ReferenceType:  "order_vendor",
+		ReferenceID:    orderVendorID,
+		IdempotencyKey: "transfer_reversal:" + orderVendorID,
+		Currency:       e.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.GatewayClearingAccount("stripe"), Debit: share},
+			{Account: ledger.VendorPayableAccount(*e.VendorUserID), Credit: share},
+		},
+	})
+	return err
+}
+
+// RecordSettlementPayout posts a paid settlement: the vendor is no longer
+// owed the amount and it has left through the payout method. Only the part
+// that was actually sent is posted.
+func (r *LedgerRepository) RecordSettlementPayout(ctx context.Context, tx pgx.Tx, s *settlement.VendorSettlement, method string) error {
+	if s.VendorUserID == nil {
+		return fmt.Errorf("settlement %s has no vendor user", s.ID)
+	}
+	amount := s.NetAmount
+	if s.PayoutAmount != nil {
+		amount = *s.PayoutAmount
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	_, err := r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryPayout,
+		Description:    "Settlement payout " + s.ID,
+		ReferenceType:  "vendor_settlement",
+		ReferenceID:    s.ID,
+		IdempotencyKey: "payout:" + s.ID,
+		Currency:       s.Currency,
+		Postings: []ledger.Posting{
+			{Account: ledger.VendorPayableAccount(*s.VendorUserID), Debit: amount},
+			{Account: ledger.GatewayClearingAccount(method), Credit: amount},
+		},
+	})
+	return err
+}
+
+// RecordAdjustment posts a manual credit (or, when negative, a deduction) to
+// a vendor against the platform's adjustments expense.
+func (r *LedgerRepository) RecordAdjustment(ctx context.Context, tx pgx.Tx, e *settlement.VendorEarning) error {
+	if e.VendorUserID == nil {
+		return fmt.Errorf("adjustment %s has no vendor user", e.ID)
+	}
+
+	vendor := ledger.VendorPayableAccount(*e.VendorUserID)
+	expense := ledger.PlatformAdjustmentsAccount()
+	postings := []ledger.Posting{
+		{Account: expense, Debit: e.GrossAmount},
+		{Account: vendor, Credit: e.GrossAmount},
+	}
+	if e.GrossAmount < 0 {
+		postings = []ledger.Posting{
+			{Account: vendor, Debit: -e.GrossAmount},
+			{Account: expense, Credit: -e.GrossAmount},
+		}
+	}
+
+	description := "Adjustment"
+	if e.Description != nil {
+		description = *e.Description
+	}
+
+	_, err := r.PostEntry(ctx, tx, &ledger.NewEntry{
+		EntryType:      ledger.EntryAdjustment,
+		Description:    description,
+		ReferenceType:  "vendor_earning",
+		ReferenceID:    e.ID,
+		IdempotencyKey: "adjustment:" + e.ID,
+		Postings:       postings,
+	})
+	return err
+}
+
+//-- ==================================================
+//-- BALANCES & REPORTS
+//-- ==================================================
+
+// GetAccountBalances returns every account with its totals up to cutoff,
+// optionally limited to one owner.
+func (r *LedgerRepository) GetAccountBalances(ctx context.Context, ownerType, ownerID *string, cutoff time.Time) ([]ledger.AccountBalance, error) {
+	query := `
+		SELECT
+			a.*,
+			COALESCE(SUM(l.debit), 0) AS debit,
+			COALESCE(SUM(l.credit), 0) AS credit
+		FROM ledger_accounts a
+		LEFT JOIN journal_lines l ON l.account_id = a.id AND l.created_at < @cutoff
+		WHERE (@ownerType::TEXT IS NULL OR a.owner_type = @ownerType)
+		  AND (@ownerId::TEXT IS NULL OR a.owner_id = @ownerId)
+		GROUP BY a.id
+		ORDER BY a.code
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{
+		"ownerType": ownerType,
+		"ownerId":   ownerID,
+		"cutoff":    cutoff,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account balances: %w", err)
+	}
+	balances, err := pgx.CollectRows(rows, pgx.RowToStructByName[ledger.AccountBalance])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect account balances: %w", err)
+	}
+
+	for i := range balances {
+		b := &balances[i]
+		if b.DebitNormal() {
+			b.Balance = cents(b.Debit - b.Credit)
+		} else {
+			b.Balance = cents(b.Credit - b.Debit)
+		}
+	}
+	return balances, nil
+}
+
+// GetVendorPayable returns what the platform owes a vendor right now.
+func (r *LedgerRepository) GetVendorPayable(ctx context.Context, vendorUserID string) (float64, error) {
+	var payable float64
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(l.credit - l.debit), 0)
+		FROM journal_lines l
+		JOIN ledger_accounts a ON a.id = l.account_id
+		WHERE a.code = $1
+	`, ledger.VendorPayableAccount(vendorUserID).Code).Scan(&payable)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch vendor payable: %w", err)
+	}
+	return cents(payable), nil
+}
+
+func (r *LedgerRepository) GetTrialBalance(ctx context.Context, cutoff time.Time) (*ledger.TrialBalance, error) {
+	balances, err := r.GetAccountBalances(ctx, nil, nil, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	tb := &ledger.TrialBalance{AsOf: cutoff, Accounts: balances}
+	for _, b := range balances {
+		tb.TotalDebit += b.Debit
+		tb.TotalCredit += b.Credit
+	}
+	tb.TotalDebit, tb.TotalCredit = cents(tb.TotalDebit), cents(tb.TotalCredit)
+	tb.Balanced = tb.TotalDebit == tb.TotalCredit
+	return tb, nil
+}
+
+// CheckInvariants verifies that every entry has lines and that each entry's
+// debits equal its credits.
+func (r *LedgerRepository) CheckInvariants(ctx context.Context) (*ledger.InvariantReport, error) {
+	report := &ledger.InvariantReport{
+		UnbalancedEntries: []ledger.UnbalancedEntry{},
+		EmptyEntries:      []string{},
+	}
+
+	if err := r.server.DB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM journal_entries`).Scan(&report.EntriesChecked); err != nil {
+		return nil, fmt.Errorf("failed to count journal entries: %w", err)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT l.entry_id, SUM(l.debit) AS debit, SUM(l.credit) AS credit
+		FROM journal_lines l
+		GROUP BY l.entry_id
+		HAVING SUM(l.debit) <> SUM(l.credit)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check entry balances: %w", err)
+	}
+	report.UnbalancedEntries, err = pgx.CollectRows(rows, pgx.RowToStructByName[ledger.UnbalancedEntry])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect unbalanced entries: %w", err)
+	}
+
+	rows, err = r.server.DB.Pool.Query(ctx, `
+		SELECT e.id FROM journal_entries e
+		WHERE NOT EXISTS (SELECT 1 FROM journal_lines l WHERE l.entry_id = e.id)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check empty entries: %w", err)
+	}
+	report.EmptyEntries, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect empty entries: %w", err)
+	}
+
+	report.Ok = len(report.UnbalancedEntries) == 0 && len(report.EmptyEntries) == 0
+	return report, nil
+}
@@ -0,0 +1,810 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/loyalty"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+// ---------------- LOYALTY REPOSITORY ----------------
+
+type LoyaltyRepository struct {
+	server *server.Server
+}
+
+func NewLoyaltyRepository(s *server.Server) *LoyaltyRepository {
+	return &LoyaltyRepository{server: s}
+}
+
+//-- ==================================================
+//-- SETTINGS, TIERS & PROMOTIONS
+//-- ==================================================
+
+func (r *LoyaltyRepository) GetSettings(ctx context.Context) (*loyalty.Settings, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT points_per_npr, expiry_days, tier_window_days, npr_per_point, max_redeem_percent, updated_at FROM loyalty_settings`)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[loyalty.Settings])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loyalty settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *LoyaltyRepository) UpdateSettings(ctx context.Context, payload *loyalty.UpdateSettingsPayload) (*loyalty.Settings, error) {
+	query := `
+		UPDATE loyalty_settings
+		SET points_per_npr = COALESCE(@pointsPerNpr, points_per_npr),
+		    expiry_days = COALESCE(@expiryDays, expiry_days),
+		    tier_window_days = COALESCE(@tierWindowDays, tier_window_days),
+		    npr_per_point = COALESCE(@nprPerPoint, npr_per_point),
+		    max_redeem_percent = COALESCE(@maxRedeemPercent, max_redeem_percent)
+		RETURNING points_per_npr, expiry_days, tier_window_days, npr_per_point, max_redeem_percent, updated_at
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{
+		"pointsPerNpr":     payload.PointsPerNPR,
+		"expiryDays":       payload.ExpiryDays,
+		"tierWindowDays":   payload.TierWindowDays,
+		"nprPerPoint":      payload.NPRPerPoint,
+		"maxRedeemPercent": payload.MaxRedeemPercent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update loyalty settings: %w", err)
+	}
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[loyalty.Settings])
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *LoyaltyRepository) ListTiers(ctx context.Context) ([]loyalty.Tier, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM loyalty_tiers ORDER BY min_spend`)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := pgx.CollectRows(rows, pgx.RowToStructByName[loyalty.Tier])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect loyalty tiers: %w", err)
+	}
+	return tiers, nil
+}
+
+func (r *LoyaltyRepository) CreatePromotion(ctx context.Context, payload *loyalty.CreatePromotionPayload, createdBy string) (*loyalty.Promotion, error) {
+	query := `
+		INSERT INTO loyalty_promotions (name, multiplier, vendor_id, starts_at, ends_at, created_by)
+		VALUES (@name, @multiplier, @vendorId, @startsAt, @endsAt, @createdBy)
+		RETURNING *
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{
+		"name":       payload.Name,
+		"multiplier": payload.Multiplier,
+		"vendorId":   payload.VendorID,
+		"startsAt":   payload.StartsAt,
+		"endsAt":     payload.EndsAt,
+		"createdBy":  createdBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loyalty promotion: %w", err)
+	}
+	promo, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[loyalty.Promotion])
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *LoyaltyRepository) UpdatePromotion(ctx context.Context, payload *loyalty.UpdatePromotionPayload) (*loyalty.Promotion, error) {
+	query := `
+		UPDATE loyalty_promotions
+		SET name = COALESCE(@name, name),
+		    multiplier = COALESCE(@multiplier, multiplier),
+		    starts_at = COALESCE(@startsAt, starts_at),
+		    ends_at = COALESCE(@endsAt, ends_at),
+		    is_active = COALESCE(@isActive, is_active)
+		WHERE id = @id
+		RETURNING *
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{
+		"id":         payload.ID,
+		"name":       payload.Name,
+		"multiplier": payload.Multiplier,
+		"startsAt":   payload.StartsAt,
+		"endsAt":     payload.EndsAt,
+		"isActive":   payload.IsActive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update loyalty promotion: %w", err)
+	}
+	promo, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[loyalty.Promotion])
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *LoyaltyRepository) ListPromotions(ctx context.Context, activeOnly bool) ([]loyalty.Promotion, error) {
+	query := `SELECT * FROM loyalty_promotions`
+	if activeOnly {
+		query += ` WHERE is_active AND NOW() >= starts_at AND NOW() < ends_at`
+	}
+	query += ` ORDER BY starts_at DESC`
+
+	rows, err := r.server.DB.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	promos, err := pgx.CollectRows(rows, pgx.RowToStructByName[loyalty.Promotion])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect loyalty promotions: %w", err)
+	}
+	return promos, nil
+}
+
+func (r *LoyaltyRepository) SetVendorMultiplier(ctx context.Context, vendorID string, multiplier float64) error {
+	ct, err := r.server.DB.Pool.Exec(ctx,
+		`UPDATE vendors SET loyalty_multiplier = $1 WHERE id = $2`, multiplier, vendorID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set vendor loyalty multiplier: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- EARNING
+//-- ==================================================
+
+// AwardOrderPoints credits the customer of a delivered, paid order with the
+// points the earning rules give it. It returns nil when the order earns
+// nothing (not delivered, not paid, or zero points). Awarding is idempotent:
+// an order that already earned returns its existing entry.
+func (r *LoyaltyRepository) AwardOrderPoints(ctx context.Context, orderID string) (*user.LoyaltyPointsLedger, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		userID, vendorID, status, paymentStatus string
+		deliveredAt                             time.Time
+		rule                                    loyalty.EarnRule
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT ov.user_id, ov.vendor_id, ov.status, ov.payment_status,
+		       COALESCE(ov.delivered_at, NOW()), ov.total, v.loyalty_multiplier
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		WHERE ov.id = $1
+		FOR UPDATE OF ov
+	`, orderID).Scan(&userID, &vendorID, &status, &paymentStatus, &deliveredAt, &rule.OrderTotal, &rule.VendorMultiplier)
+	if err != nil {
+		return nil, err
+	}
+	if status != "delivered" || paymentStatus != "paid" {
+		return nil, nil
+	}
+
+	existing, err := getOrderEarnEntry(ctx, tx, orderID)
+	if err == nil {
+		return existing, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	if _, err := lockLoyaltyBalance(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	var expiryDays int
+	err = tx.QueryRow(ctx, `
+		SELECT s.points_per_npr, s.expiry_days, t.earn_multiplier
+		FROM loyalty_settings s, users u
+		JOIN loyalty_tiers t ON t.code = u.loyalty_tier
+		WHERE u.id = $1
+	`, userID).Scan(&rule.PointsPerNPR, &expiryDays, &rule.TierMultiplier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load earning rules: %w", err)
+	}
+
+	// Promotions do not stack, the best one applies
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(multiplier), 1)
+		FROM loyalty_promotions
+		WHERE is_active
+		  AND (vendor_id IS NULL OR vendor_id = $1)
+		  AND $2 >= starts_at AND $2 < ends_at
+	`, vendorID, deliveredAt).Scan(&rule.PromotionMultiplier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loyalty promotions: %w", err)
+	}
+
+	points := rule.Points()
+	if points <= 0 {
+		return nil, nil
+	}
+
+	referenceType := loyalty.ReferenceTypeOrder
+	reason := fmt.Sprintf("Order #%s", strings.ToUpper(orderID[:min(8, len(orderID))]))
+	expiresAt := time.Now().AddDate(0, 0, expiryDays)
+	entry, err := insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: loyalty.TransactionEarn,
+		PointsChange:    points,
+		Reason:          &reason,
+		ReferenceID:     &orderID,
+		ReferenceType:   &referenceType,
+		PerformedBy:     userID,
+		ExpiresAt:       &expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := refreshUserTier(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListUnawardedOrders returns delivered, paid orders that have not earned
+// points yet, oldest first. Orders delivered before the points would already
+// have expired are left out.
+func (r *LoyaltyRepository) ListUnawardedOrders(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		SELECT ov.id
+		FROM order_vendors ov
+		CROSS JOIN loyalty_settings s
+		WHERE ov.status = 'delivered'
+		  AND ov.payment_status = 'paid'
+		  AND COALESCE(ov.delivered_at, ov.updated_at) > NOW() - s.expiry_days * INTERVAL '1 day'
+		  AND NOT EXISTS (
+			SELECT 1 FROM loyalty_points_ledger l
+			WHERE l.transaction_type = 'EARN'
+			  AND l.reference_type = 'ORDER'
+			  AND l.reference_id = ov.id
+		  )
+		ORDER BY COALESCE(ov.delivered_at, ov.updated_at)
+		LIMIT @limit
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"limit": limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unawarded orders: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+//-- ==================================================
+//-- REDEMPTION
+//-- ==================================================
+
+// redeemOrderPoints writes the points an order was discounted with to the
+// customer's ledger. It runs in the transaction that marks the order paid, so
+// points applied to a cart are only ever spent by a successful payment. If the
+// customer spent the points elsewhere in the meantime only what is left is
+// taken; the discount was already granted.
+func redeemOrderPoints(ctx context.Context, tx pgx.Tx, orderID string) error {
+	var (
+		userID string
+		points float64
+	)
+	err := tx.QueryRow(ctx,
+		`SELECT user_id, loyalty_points_used FROM order_vendors WHERE id = $1`, orderID,
+	).Scan(&userID, &points)
+	if err != nil {
+		return fmt.Errorf("failed to load order points: %w", err)
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	balance, err := lockLoyaltyBalance(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM loyalty_points_ledger
+			WHERE transaction_type = 'REDEEM' AND reference_type = 'ORDER' AND reference_id = $1
+		)
+	`, orderID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	points = math.Min(points, math.Max(balance, 0))
+	if points == 0 {
+		return nil
+	}
+
+	referenceType := loyalty.ReferenceTypeOrder
+	reason := fmt.Sprintf("Redeemed on order #%s", strings.ToUpper(orderID[:min(8, len(orderID))]))
+	_, err = insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: loyalty.TransactionRedeem,
+		PointsChange:    -points,
+		Reason:          &reason,
+		ReferenceID:     &orderID,
+		ReferenceType:   &referenceType,
+		PerformedBy:     userID,
+	})
+	return err
+}
+
+// ReverseOrderRedemption gives back the points an order redeemed once the
+// order is refunded, cancelled or its payment failed. The points come back as
+// a new lot with a full expiry period. It returns nil when there is nothing
+// to reverse.
+func (r *LoyaltyRepository) ReverseOrderRedemption(ctx context.Context, orderID string) (*user.LoyaltyPointsLedger, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status, paymentStatus string
+	err = tx.QueryRow(ctx,
+		`SELECT status, payment_status FROM order_vendors WHERE id = $1 FOR UPDATE`, orderID,
+	).Scan(&status, &paymentStatus)
+	if err != nil {
+		return nil, err
+	}
+	// A paid, live order keeps its redemption
+	if paymentStatus == "paid" && status != "cancelled" && status != "failed" {
+		return nil, nil
+	}
+
+	redeemed, err := tx.Query(ctx, `
+		SELECT * FROM loyalty_points_ledger
+		WHERE transaction_type = 'REDEEM' AND reference_type = 'ORDER' AND reference_id = $1
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := pgx.CollectOneRow(redeemed, pgx.RowToStructByName[user.LoyaltyPointsLedger])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if _, err := lockLoyaltyBalance(ctx, tx, entry.UserID); err != nil {
+		return nil, err
+	}
+
+	var reversed bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM loyalty_points_ledger
+			WHERE reference_type = 'ORDER_REVERSAL' AND reference_id = $1
+		)
+	`, orderID).Scan(&reversed)
+	if err != nil {
+		return nil, err
+	}
+	if reversed {
+		return nil, nil
+	}
+
+	referenceType := loyalty.ReferenceTypeOrderReversal
+	reason := fmt.Sprintf("Points returned for order #%s", strings.ToUpper(orderID[:min(8, len(orderID))]))
+	reversal, err := insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          entry.UserID,
+		TransactionType: loyalty.TransactionAdjust,
+		PointsChange:    -entry.PointsChange,
+		Reason:          &reason,
+		ReferenceID:     &orderID,
+		ReferenceType:   &referenceType,
+		PerformedBy:     entry.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// reservedLoyaltyPoints is what the customer has applied to active carts other than
+// excludeCartVendorID; those points are not spendable elsewhere.
+func reservedLoyaltyPoints(ctx context.Context, tx pgx.Tx, userID, excludeCartVendorID string) (float64, error) {
+	var reserved float64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(cv.loyalty_points_used), 0)
+		FROM cart_vendors cv
+		JOIN cart_sessions cs ON cs.id = cv.cart_session_id
+		WHERE cs.user_id = $1
+		  AND cv.status = 'active'
+		  AND cv.id <> $2
+	`, userID, excludeCartVendorID).Scan(&reserved)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reserved loyalty points: %w", err)
+	}
+	return reserved, nil
+}
+
+//-- ==================================================
+//-- TIERS
+//-- ==================================================
+
+// RefreshTiers recomputes every customer's tier from the rolling spend, so
+// customers whose old orders leave the window move down. It returns how many
+// tiers changed.
+func (r *LoyaltyRepository) RefreshTiers(ctx context.Context) (int, error) {
+	ct, err := r.server.DB.Pool.Exec(ctx, `
+		WITH spend AS (
+			SELECT u.id AS user_id, COALESCE(SUM(ov.total), 0) AS total
+			FROM users u
+			CROSS JOIN loyalty_settings s
+			LEFT JOIN order_vendors ov
+			  ON ov.user_id = u.id
+			 AND ov.status = 'delivered'
+			 AND ov.payment_status = 'paid'
+			 AND COALESCE(ov.delivered_at, ov.updated_at) > NOW() - s.tier_window_days * INTERVAL '1 day'
+			GROUP BY u.id
+		),
+		target AS (
+			SELECT sp.user_id, (
+				SELECT t.code FROM loyalty_tiers t
+				WHERE t.min_spend <= sp.total
+				ORDER BY t.min_spend DESC
+				LIMIT 1
+			) AS tier
+			FROM spend sp
+		)
+		UPDATE users u
+		SET loyalty_tier = target.tier
+		FROM target
+		WHERE u.id = target.user_id
+		  AND target.tier IS NOT NULL
+		  AND u.loyalty_tier <> target.tier
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh loyalty tiers: %w", err)
+	}
+	return int(ct.RowsAffected()), nil
+}
+
+func (r *LoyaltyRepository) GetStatus(ctx context.Context, userID string) (*loyalty.Status, error) {
+	tiers, err := r.ListTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		st       loyalty.Status
+		tierCode string
+	)
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		SELECT
+			u.loyalty_tier,
+			(SELECT COALESCE(SUM(points_change), 0) FROM loyalty_points_ledger WHERE user_id = u.id),
+			(
+				SELECT COALESCE(SUM(ov.total), 0) FROM order_vendors ov, loyalty_settings s
+				WHERE ov.user_id = u.id
+				  AND ov.status = 'delivered'
+				  AND ov.payment_status = 'paid'
+				  AND COALESCE(ov.delivered_at, ov.updated_at) > NOW() - s.tier_window_days * INTERVAL '1 day'
+			),
+			(
+				SELECT COALESCE(SUM(remaining_points), 0) FROM loyalty_points_ledger
+				WHERE user_id = u.id AND remaining_points > 0
+				  AND expires_at <= NOW() + INTERVAL '30 days'
+			),
+			(
+				SELECT MIN(expires_at) FROM loyalty_points_ledger
+				WHERE user_id = u.id AND remaining_points > 0
+			)
+		FROM users u
+		WHERE u.id = $1
+	`, userID).Scan(&tierCode, &st.Balance, &st.RollingSpend, &st.ExpiringPoints, &st.NextExpiryAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range tiers {
+		if t.Code != tierCode {
+			continue
+		}
+		st.Tier = t
+		if i+1 < len(tiers) {
+			next := tiers[i+1]
+			st.NextTier = &next
+			st.SpendToNextTier = math.Max(0, next.MinSpend-st.RollingSpend)
+		}
+		break
+	}
+	return &st, nil
+}
+
+//-- ==================================================
+//-- EXPIRY
+//-- ==================================================
+
+// ExpirePoints writes off what is left of every lot that expired before now,
+// one EXPIRE entry per customer.
+func (r *LoyaltyRepository) ExpirePoints(ctx context.Context, now time.Time) (*loyalty.ExpiryResult, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT DISTINCT user_id FROM loyalty_points_ledger
+		WHERE remaining_points > 0 AND expires_at <= $1
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring points: %w", err)
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	res := &loyalty.ExpiryResult{}
+	for _, userID := range userIDs {
+		expired, err := r.expireUserPoints(ctx, userID, now)
+		if err != nil {
+			r.server.Logger.Error().Err(err).Str("user_id", userID).Msg("failed to expire loyalty points")
+			continue
+		}
+		if expired > 0 {
+			res.Users++
+			res.Points += expired
+		}
+	}
+	return res, nil
+}
+
+func (r *LoyaltyRepository) expireUserPoints(ctx context.Context, userID string, now time.Time) (float64, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, err
+	}
+	expired, err := expireLoyaltyLots(ctx, tx, userID, now)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+//-- ==================================================
+//-- LEDGER HELPERS (shared with UserRepository)
+//-- ==================================================
+
+// lockLoyaltyBalance locks the customer's balance for the rest of the
+// transaction, writes off expired lots and returns the spendable balance.
+func lockLoyaltyBalance(ctx context.Context, tx pgx.Tx, userID string) (float64, error) {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, fmt.Errorf("failed to lock loyalty balance: %w", err)
+	}
+	if _, err := expireLoyaltyLots(ctx, tx, userID, time.Now()); err != nil {
+		return 0, err
+	}
+	return loyaltyBalance(ctx, tx, userID)
+}
+
+func loyaltyBalance(ctx context.Context, tx pgx.Tx, userID string) (float64, error) {
+	var balance float64
+	err := tx.QueryRow(ctx, `SELECT COALESCE(SUM(points_change), 0) FROM loyalty_points_ledger WHERE user_id = $1`, userID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get current balance: %w", err)
+	}
+	return balance, nil
+}
+
+func expireLoyaltyLots(ctx context.Context, tx pgx.Tx, userID string, now time.Time) (float64, error) {
+	var expired float64
+	err := tx.QueryRow(ctx, `
+		WITH lots AS (
+			UPDATE loyalty_points_ledger l
+			SET remaining_points = 0
+			FROM (
+				SELECT id, remaining_points FROM loyalty_points_ledger
+				WHERE user_id = $1 AND remaining_points > 0 AND expires_at <= $2
+				FOR UPDATE
+			) e
+			WHERE l.id = e.id
+			RETURNING e.remaining_points
+		)
+		SELECT COALESCE(SUM(remaining_points), 0) FROM lots
+	`, userID, now).Scan(&expired)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire loyalty lots: %w", err)
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+
+	reason := "Points expired"
+	_, err = insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: loyalty.TransactionExpire,
+		PointsChange:    -expired,
+		Reason:          &reason,
+		PerformedBy:     userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// consumeLoyaltyLots takes points from the oldest unexpired lots first.
+func consumeLoyaltyLots(ctx context.Context, tx pgx.Tx, userID string, points float64) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id, remaining_points FROM loyalty_points_ledger
+		WHERE user_id = $1 AND remaining_points > 0
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY performed_at, id
+		FOR UPDATE
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load loyalty lots: %w", err)
+	}
+
+	type lot struct {
+		ID        string  `db:"id"`
+		Remaining float64 `db:"remaining_points"`
+	}
+	lots, err := pgx.CollectRows(rows, pgx.RowToStructByName[lot])
+	if err != nil {
+		return fmt.Errorf("failed to collect loyalty lots: %w", err)
+	}
+
+	for _, l := range lots {
+		if points <= 0 {
+			break
+		}
+		take := math.Min(points, l.Remaining)
+		if _, err := tx.Exec(ctx,
+			`UPDATE loyalty_points_ledger SET remaining_points = remaining_points - $1 WHERE id = $2`,
+			take, l.ID,
+		); err != nil {
+			return fmt.Errorf("failed to consume loyalty lot: %w", err)
+		}
+		points -= take
+	}
+	return nil
+}
+
+// insertLoyaltyEntry appends an entry to the customer's ledger. Credits become
+// lots, debits consume lots, and users.loyalty_points follows the balance.
+// The caller must hold the balance lock.
+func insertLoyaltyEntry(ctx context.Context, tx pgx.Tx, e *user.LoyaltyPointsLedger) (*user.LoyaltyPointsLedger, error) {
+	balance, err := loyaltyBalance(ctx, tx, e.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := 0.0
+	if e.PointsChange > 0 {
+		remaining = e.PointsChange
+	} else if e.TransactionType != loyalty.TransactionExpire {
+		if err := consumeLoyaltyLots(ctx, tx, e.UserID, -e.PointsChange); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		INSERT INTO loyalty_points_ledger (
+			user_id,
+			transaction_type,
+			points_change,
+			balance_after,
+			reason,
+			reference_id,
+			reference_type,
+			performed_by,
+			performed_at,
+			remaining_points,
+			expires_at
+		) VALUES (
+			@userId, @transactionType, @pointsChange, @balanceAfter, @reason,
+			@referenceId, @referenceType, @performedBy, NOW(), @remaining,
+			CASE WHEN @remaining::NUMERIC > 0
+				THEN COALESCE(@expiresAt::TIMESTAMP, NOW() + (SELECT expiry_days FROM loyalty_settings) * INTERVAL '1 day')
+			END
+		)
+		RETURNING *
+	`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{
+		"userId":          e.UserID,
+		"transactionType": e.TransactionType,
+		"pointsChange":    e.PointsChange,
+		"balanceAfter":    balance + e.PointsChange,
+		"reason":          e.Reason,
+		"referenceId":     e.ReferenceID,
+		"referenceType":   e.ReferenceType,
+		"performedBy":     e.PerformedBy,
+		"remaining":       remaining,
+		"expiresAt":       e.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert loyalty entry: %w", err)
+	}
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.LoyaltyPointsLedger])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect loyalty entry: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE users SET loyalty_points = FLOOR($1) WHERE id = $2`,
+		entry.BalanceAfter, e.UserID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update loyalty points: %w", err)
+	}
+	return &entry, nil
+}
+
+func getOrderEarnEntry(ctx context.Context, tx pgx.Tx, orderID string) (*user.LoyaltyPointsLedger, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT * FROM loyalty_points_ledger
+		WHERE transaction_type = 'EARN' AND reference_type = 'ORDER' AND reference_id = $1
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.LoyaltyPointsLedger])
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// refreshUserTier moves one customer to the tier their rolling spend earns.
+func refreshUserTier(ctx context.Context, tx pgx.Tx, userID string) (string, error) {
+	var tier string
+	err := tx.QueryRow(ctx, `
+		UPDATE users u
+		SET loyalty_tier = COALESCE((
+			SELECT t.code FROM loyalty_tiers t
+			WHERE t.min_spend <= (
+				SELECT COALESCE(SUM(ov.total), 0) FROM order_vendors ov, loyalty_settings s
+				WHERE ov.user_id = u.id
+				  AND ov.status = 'delivered'
+				  AND ov.payment_status = 'paid'
+				  AND COALESCE(ov.delivered_at, ov.updated_at) > NOW() - s.tier_window_days * INTERVAL '1 day'
+			)
+			ORDER BY t.min_spend DESC
+			LIMIT 1
+		), u.loyalty_tier)
+		WHERE u.id = $1
+		RETURNING loyalty_tier
+	`, userID).Scan(&tier)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh loyalty tier: %w", err)
+	}
+	return tier, nil
+}
@@ -0,0 +1,425 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gitSanje/khajaride/internal/lib/menuimport"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrMenuImportNotFound = errors.New("menu import not found")
+	ErrMenuImportFinished = errors.New("menu import has already finished")
+)
+
+// ---------------- MENU IMPORT REPOSITORY ----------------
+
+type MenuImportRepository struct {
+	server *server.Server
+}
+
+func NewMenuImportRepository(s *server.Server) *MenuImportRepository {
+	return &MenuImportRepository{server: s}
+}
+
+//-- ==================================================
+//-- IMPORTS
+//-- ==================================================
+
+func (r *MenuImportRepository) CreateImport(ctx context.Context, imp *vendor.MenuImport) (*vendor.MenuImport, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO menu_imports (vendor_id, user_id, format, file_name, file_key, dry_run, create_categories)
+		VALUES (@vendorId, @userId, @format, @fileName, @fileKey, @dryRun, @createCategories)
+		RETURNING *
+	`, pgx.NamedArgs{
+		"vendorId":         imp.VendorID,
+		"userId":           imp.UserID,
+		"format":           imp.Format,
+		"fileName":         imp.FileName,
+		"fileKey":          imp.FileKey,
+		"dryRun":           imp.DryRun,
+		"createCategories": imp.CreateCategories,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create menu import: %w", err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.MenuImport])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu import: %w", err)
+	}
+	return &created, nil
+}
+
+// GetImports returns the vendor's latest imports, newest first.
+func (r *MenuImportRepository) GetImports(ctx context.Context, vendorID string) ([]vendor.MenuImport, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM menu_imports
+		WHERE vendor_id = $1
+		ORDER BY created_at DESC
+		LIMIT 50
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu imports: %w", err)
+	}
+	imports, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.MenuImport])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu imports: %w", err)
+	}
+	return imports, nil
+}
+
+func (r *MenuImportRepository) GetImport(ctx context.Context, vendorID, importID string) (*vendor.MenuImport, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM menu_imports WHERE id = $1 AND vendor_id = $2
+	`, importID, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu import: %w", err)
+	}
+	imp, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.MenuImport])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMenuImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu import: %w", err)
+	}
+	return &imp, nil
+}
+
+// StartImport marks an import running. An import interrupted while running
+// is resumed: the rows it saved are skipped (GetSavedRows).
+func (r *MenuImportRepository) StartImport(ctx context.Context, importID string) (*vendor.MenuImport, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		UPDATE menu_imports
+		SET status = 'running', started_at = NOW(), processed_rows = 0, error = NULL
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING *
+	`, importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start menu import: %w", err)
+	}
+	imp, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.MenuImport])
+	if errors.Is(err, pgx.ErrNoRows) {
+		// finished, or the vendor is gone
+		return nil, ErrMenuImportFinished
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu import: %w", err)
+	}
+	return &imp, nil
+}
+
+func (r *MenuImportRepository) UpdateImportProgress(ctx context.Context, importID string, total, processed int) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE menu_imports SET total_rows = $2, processed_rows = $3 WHERE id = $1
+	`, importID, total, processed)
+	if err != nil {
+		return fmt.Errorf("failed to update menu import progress: %w", err)
+	}
+	return nil
+}
+
+func (r *MenuImportRepository) CompleteImport(ctx context.Context, importID string, report *vendor.MenuImportReport) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE menu_imports
+		SET status = 'completed', report = $2, total_rows = $3, processed_rows = $3, finished_at = NOW()
+		WHERE id = $1
+	`, importID, report, report.TotalRows)
+	if err != nil {
+		return fmt.Errorf("failed to complete menu import: %w", err)
+	}
+	return nil
+}
+
+// FailImport stops an import that has not completed. Items saved before the
+// failure stay.
+func (r *MenuImportRepository) FailImport(ctx context.Context, importID, reason string) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE menu_imports
+		SET status = 'failed', error = $2, finished_at = NOW()
+		WHERE id = $1 AND status <> 'completed'
+	`, importID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to fail menu import: %w", err)
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- MENU
+//-- ==================================================
+
+// GetCatalog returns the categories and the vendor's items rows are matched
+// against, an outlet's including its brand's master items. Of categories
+// sharing a name the first in menu order is used.
+func (r *MenuImportRepository) GetCatalog(ctx context.Context, vendorID string) (*menuimport.Catalog, error) {
+	catalog := &menuimport.Catalog{
+		Categories: make(map[string]string),
+		Items:      make(map[string]bool),
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT id, name FROM menu_categories ORDER BY position, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu categories: %w", err)
+	}
+	var id, name string
+	_, err = pgx.ForEachRow(rows, []any{&id, &name}, func() error {
+		if key := menuimport.NameKey(name); catalog.Categories[key] == "" {
+			catalog.Categories[key] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu categories: %w", err)
+	}
+
+	rows, err = r.server.DB.Pool.Query(ctx, `SELECT name FROM vendor_menu_items WHERE vendor_id = $1`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu item names: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu item names: %w", err)
+	}
+	for _, n := range names {
+		catalog.Items[menuimport.NameKey(n)] = true
+	}
+	return catalog, nil
+}
+
+// CreateCategories adds the named categories that do not exist yet and
+// returns the ids of all of them by name key.
+func (r *MenuImportRepository) CreateCategories(ctx context.Context, names []string) (map[string]string, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make(map[string]string, len(names))
+	for _, name := range names {
+		var id string
+		err := tx.QueryRow(ctx, `
+			WITH existing AS (
+				SELECT id FROM menu_categories
+				WHERE lower(btrim(name)) = lower(btrim(@name))
+				ORDER BY position, created_at
+				LIMIT 1
+			), inserted AS (
+				INSERT INTO menu_categories (name)
+				SELECT btrim(@name)
+				WHERE NOT EXISTS (SELECT 1 FROM existing)
+				RETURNING id
+			)
+			SELECT id FROM existing
+			UNION ALL
+			SELECT id FROM inserted
+		`, pgx.NamedArgs{"name": name}).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create menu category %q: %w", name, err)
+		}
+		ids[menuimport.NameKey(name)] = id
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetSavedRows returns the rows an interrupted run of the import saved.
+func (r *MenuImportRepository) GetSavedRows(ctx context.Context, importID string) ([]vendor.MenuImportSaved, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT row_number, COALESCE(menu_item_id, ''), created FROM menu_import_rows
+		WHERE import_id = $1
+		ORDER BY row_number
+	`, importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved menu import rows: %w", err)
+	}
+	var saved []vendor.MenuImportSaved
+	var s vendor.MenuImportSaved
+	_, err = pgx.ForEachRow(rows, []any{&s.Row, &s.MenuItemID, &s.Created}, func() error {
+		saved = append(saved, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect saved menu import rows: %w", err)
+	}
+	return saved, nil
+}
+
+// UpsertMenuItems saves a batch of imported items in one transaction and
+// records the rows against the import: an item the vendor sells with the
+// same name is updated, otherwise one is created. Empty fields keep the
+// current values. A brand's master item only takes the outlet's price and
+// availability, as an outlet override; the brand owns the rest. Rows that
+// can no longer be saved (a new item without a category, as the item it
+// was matched to is gone) come back as row errors.
+func (r *MenuImportRepository) UpsertMenuItems(ctx context.Context, importID, vendorID string, items []vendor.MenuImportItem) ([]vendor.MenuImportSaved, []vendor.MenuImportRowError, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// one import of a vendor writes at a time, so two cannot both create
+	// the same item
+	if _, err := tx.Exec(ctx, `SELECT id FROM vendors WHERE id = $1 FOR UPDATE`, vendorID); err != nil {
+		return nil, nil, fmt.Errorf("failed to lock vendor: %w", err)
+	}
+
+	saved := make([]vendor.MenuImportSaved, 0, len(items))
+	var rowErrs []vendor.MenuImportRowError
+	categoryIDs := make([]string, 0, len(items))
+
+	for _, item := range items {
+		var s vendor.MenuImportSaved
+		var categoryID *string
+		err := tx.QueryRow(ctx, `
+			WITH existing AS (
+				SELECT id, brand_id FROM vendor_menu_items
+				WHERE vendor_id = @vendorId AND lower(btrim(name)) = lower(btrim(@name))
+				ORDER BY created_at
+				LIMIT 1
+			), overridden AS (
+				INSERT INTO outlet_menu_overrides (vendor_id, menu_item_id, price, is_available)
+				SELECT @vendorId, e.id, @price, @isAvailable
+				FROM existing e
+				WHERE e.brand_id IS NOT NULL
+				ON CONFLICT (vendor_id, menu_item_id) DO UPDATE SET
+					price = EXCLUDED.price,
+					is_available = COALESCE(EXCLUDED.is_available, outlet_menu_overrides.is_available)
+				RETURNING menu_item_id AS id, NULL::TEXT AS category_id, FALSE AS created
+			), updated AS (
+				UPDATE menu_items mi SET
+					name = @name,
+					category_id = COALESCE(@categoryId, mi.category_id),
+					base_price = @price,
+					old_price = COALESCE(@oldPrice, mi.old_price),
+					description = COALESCE(@description, mi.description),
+					image = COALESCE(@image, mi.image),
+					keywords = COALESCE(@keywords, mi.keywords),
+					portion_size = COALESCE(@portionSize, mi.portion_size),
+					tags = COALESCE(@tags, mi.tags),
+					spicy_level = COALESCE(@spicyLevel, mi.spicy_level),
+					is_vegetarian = COALESCE(@isVegetarian, mi.is_vegetarian),
+					is_vegan = COALESCE(@isVegan, mi.is_vegan),
+					is_gluten_free = COALESCE(@isGlutenFree, mi.is_gluten_free),
+					is_available = COALESCE(@isAvailable, mi.is_available)
+				FROM existing e
+				WHERE mi.id = e.id AND e.brand_id IS NULL
+				RETURNING mi.id, mi.category_id, FALSE AS created
+			), inserted AS (
+				INSERT INTO menu_items (
+					vendor_id, category_id, name, description, base_price, old_price, image,
+					is_available, is_vegetarian, is_vegan, is_gluten_free, spicy_level,
+					tags, portion_size, keywords
+				)
+				SELECT @vendorId, @categoryId, @name, COALESCE(@description, ''), @price, COALESCE(@oldPrice, 0),
+				       COALESCE(@image, ''), COALESCE(@isAvailable, TRUE), COALESCE(@isVegetarian, FALSE),
+				       COALESCE(@isVegan, FALSE), COALESCE(@isGlutenFree, FALSE), COALESCE(@spicyLevel, 0),
+				       @tags, COALESCE(@portionSize, ''), COALESCE(@keywords, '')
+				WHERE NOT EXISTS (SELECT 1 FROM existing) AND @categoryId::TEXT IS NOT NULL
+				RETURNING id, category_id, TRUE AS created
+			)
+			SELECT id, category_id, created FROM overridden
+			UNION ALL
+			SELECT id, category_id, created FROM updated
+			UNION ALL
+			SELECT id, category_id, created FROM inserted
+		`, pgx.NamedArgs{
+			"vendorId":     vendorID,
+			"name":         item.Name,
+			"categoryId":   item.CategoryID,
+			"price":        item.Price,
+			"oldPrice":     item.OldPrice,
+			"description":  item.Description,
+			"image":        item.Image,
+			"keywords":     item.Keywords,
+			"portionSize":  item.PortionSize,
+			"tags":         item.Tags,
+			"spicyLevel":   item.SpicyLevel,
+			"isVegetarian": item.IsVegetarian,
+			"isVegan":      item.IsVegan,
+			"isGlutenFree": item.IsGlutenFree,
+			"isAvailable":  item.IsAvailable,
+		}).Scan(&s.MenuItemID, &categoryID, &s.Created)
+		if errors.Is(err, pgx.ErrNoRows) {
+			rowErrs = append(rowErrs, vendor.MenuImportRowError{
+				Row:     item.Row,
+				Column:  menuimport.ColumnCategory,
+				Message: "category is required for new items",
+			})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save menu item of row %d: %w", item.Row, err)
+		}
+		s.Row = item.Row
+		saved = append(saved, s)
+		// master items are on the menu through the brand's categories
+		if categoryID != nil {
+			categoryIDs = append(categoryIDs, *categoryID)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO menu_import_rows (import_id, row_number, menu_item_id, created)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (import_id, row_number) DO NOTHING
+		`, importID, s.Row, s.MenuItemID, s.Created)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to record menu import row %d: %w", item.Row, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vendor_menu_categories (vendor_id, category_id)
+		SELECT $1, unnest($2::TEXT[])
+		ON CONFLICT DO NOTHING
+	`, vendorID, categoryIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to link vendor menu categories: %w", err)
+	}
+
+	// price and availability as sold, after stock
+	ids := make([]string, len(saved))
+	for i, s := range saved {
+		ids[i] = s.MenuItemID
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id, base_price, COALESCE(is_available, FALSE) FROM vendor_menu_items
+		WHERE vendor_id = $1 AND id = ANY($2)
+	`, vendorID, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get saved menu items: %w", err)
+	}
+	type state struct {
+		price     float64
+		available bool
+	}
+	states := make(map[string]state, len(ids))
+	var id string
+	var st state
+	_, err = pgx.ForEachRow(rows, []any{&id, &st.price, &st.available}, func() error {
+		states[id] = st
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect saved menu items: %w", err)
+	}
+	for i := range saved {
+		saved[i].BasePrice = states[saved[i].MenuItemID].price
+		saved[i].IsAvailable = states[saved[i].MenuItemID].available
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return saved, rowErrs, nil
+}
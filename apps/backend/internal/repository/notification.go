@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/notification"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotificationNotFound     = errors.New("notification not found")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+)
+
+// ---------------- NOTIFICATION REPOSITORY ----------------
+
+type NotificationRepository struct {
+	server *server.Server
+}
+
+func NewNotificationRepository(s *server.Server) *NotificationRepository {
+	return &NotificationRepository{server: s}
+}
+
+//-- ==================================================
+//-- ORDER CONTEXT
+//-- ==================================================
+
+// GetOrderContext loads the order, its customer and its vendor for
+// notifying them. Only a verified phone number is used for the customer:
+// the order's contact phone, or the account's once verified. Anonymized
+// customers have no email.
+func (r *NotificationRepository) GetOrderContext(ctx context.Context, orderID string) (*notification.OrderContext, error) {
+	query := `
+		SELECT ov.id AS order_id, ov.total, COALESCE(ov.currency, 'NPR') AS currency,
+		       v.name AS vendor_name, ov.user_id,
+		       COALESCE(u.username, '') AS customer_name,
+		       CASE WHEN u.anonymized_at IS NULL THEN u.email END AS customer_email,
+		       COALESCE(ov.contact_phone, CASE WHEN u.is_verified THEN u.phone_number END) AS customer_phone,
+		       v.vendor_user_id, vu.email AS vendor_email, v.phone AS vendor_phone,
+		       (SELECT COALESCE(SUM(oi.quantity), 0)::INT FROM order_items oi WHERE oi.order_vendor_id = ov.id) AS item_count,
+		       COALESCE(ov.payment_status, 'unpaid') AS payment_status, ov.delivery_instructions
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		JOIN users u ON u.id = ov.user_id
+		LEFT JOIN users vu ON vu.id = v.vendor_user_id
+		WHERE ov.id = $1
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order context: %w", err)
+	}
+	oc, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.OrderContext])
+	if err != nil {
+		return nil, err
+	}
+	return &oc, nil
+}
+
+// GetOrderReceipt loads a delivered order with the payment that paid for it.
+func (r *NotificationRepository) GetOrderReceipt(ctx context.Context, orderID string) (*notification.OrderReceipt, error) {
+	query := `
+		SELECT ov.id AS order_id,
+		       COALESCE(u.username, '') AS customer_name,
+		       CASE WHEN u.anonymized_at IS NULL THEN u.email END AS customer_email,
+		       v.name AS vendor_name,
+		       COALESCE(ov.subtotal, 0) AS subtotal,
+		       COALESCE(ov.delivery_charge, 0) AS delivery_charge,
+		       COALESCE(ov.vat, 0) + COALESCE(ov.vendor_service_charge, 0) AS fees,
+		       COALESCE(ov.vendor_discount, 0) + ov.loyalty_discount AS discount,
+		       ov.total, COALESCE(ov.currency, 'NPR') AS currency,
+		       COALESCE(op.method, op.payment_gateway, ogp.method, ogp.payment_gateway) AS payment_method,
+		       COALESCE(op.transaction_id, ogp.transaction_id) AS transaction_ref,
+		       COALESCE(ov.delivered_at, ov.updated_at) AS delivered_at
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		JOIN users u ON u.id = ov.user_id
+		LEFT JOIN LATERAL (
+			SELECT method, payment_gateway, transaction_id
+			FROM order_payments
+			WHERE order_id = ov.id AND status = 'success'
+			ORDER BY paid_at DESC NULLS LAST
+			LIMIT 1
+		) op ON TRUE
+		LEFT JOIN order_group_payments ogp
+		       ON ogp.order_group_id = ov.order_group_id AND ogp.status IN ('success', 'partially_refunded')
+		WHERE ov.id = $1
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order receipt: %w", err)
+	}
+	receipt, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.OrderReceipt])
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+//-- ==================================================
+//-- NOTIFICATIONS
+//-- ==================================================
+
+// CreateNotification stores a notification. It returns nil when the same
+// event was already notified to this recipient over this channel.
+func (r *NotificationRepository) CreateNotification(ctx context.Context, n *notification.Notification) (*notification.Notification, error) {
+	stmt := `
+		INSERT INTO order_notifications (
+			order_id, recipient_type, recipient_id, event_type, channel,
+			title, message, data, delivery_status, sent_at
+		)
+		VALUES (
+			@orderId, @recipientType, @recipientId, @eventType, @channel,
+			@title, @message, @data, @status,
+			CASE WHEN @status::TEXT = 'delivered' THEN NOW() END
+		)
+		ON CONFLICT (order_id, event_type, recipient_type, recipient_id, channel) DO NOTHING
+		RETURNING *
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"orderId":       n.OrderID,
+		"recipientType": n.RecipientType,
+		"recipientId":   n.RecipientID,
+		"eventType":     n.EventType,
+		"channel":       n.Channel,
+		"title":         n.Title,
+		"message":       n.Message,
+		"data":          n.Data,
+		"status":        n.DeliveryStatus,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.Notification])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect notification: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *NotificationRepository) MarkNotificationSent(ctx context.Context, id string, attempts int) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE order_notifications
+		SET delivery_status = 'sent', sent_at = NOW(), attempts = @attempts, last_error = NULL
+		WHERE id = @id
+	`, pgx.NamedArgs{"id": id, "attempts": attempts})
+	if err != nil {
+		return fmt.Errorf("failed to mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkNotificationFailed records a failed attempt. The notification stays
+// pending while it is retried and is failed after the last attempt.
+func (r *NotificationRepository) MarkNotificationFailed(ctx context.Context, id string, attempts int, reason string, final bool) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE order_notifications
+		SET delivery_status = CASE WHEN @final THEN 'failed' ELSE delivery_status END,
+		    attempts = @attempts, last_error = @reason
+		WHERE id = @id AND delivery_status = 'pending'
+	`, pgx.NamedArgs{"id": id, "attempts": attempts, "reason": reason, "final": final})
+	if err != nil {
+		return fmt.Errorf("failed to mark notification failed: %w", err)
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- INBOX
+//-- ==================================================
+
+func (r *NotificationRepository) GetInbox(ctx context.Context, userID string, query *notification.GetInboxQuery) (*model.PaginatedResponse[notification.Notification], error) {
+	args := pgx.NamedArgs{
+		"userId": userID,
+		"limit":  *query.Limit,
+		"offset": (*query.Page - 1) * (*query.Limit),
+	}
+	where := ` WHERE recipient_id = @userId AND channel = 'in_app'`
+	if query.Unread != nil && *query.Unread {
+		where += ` AND read_at IS NULL`
+	}
+
+	var total int
+	if err := r.server.DB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM order_notifications`+where, args).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM order_notifications`+where+` ORDER BY created_at DESC LIMIT @limit OFFSET @offset`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[notification.Notification])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect notifications: %w", err)
+	}
+
+	return &model.PaginatedResponse[notification.Notification]{
+		Data:       notifications,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	var unread int
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM order_notifications
+		WHERE recipient_id = $1 AND channel = 'in_app' AND read_at IS NULL
+	`, userID).Scan(&unread)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return unread, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id string) (*notification.Notification, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		UPDATE order_notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = @id AND recipient_id = @userId AND channel = 'in_app'
+		RETURNING *
+	`, pgx.NamedArgs{"id": id, "userId": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	n, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.Notification])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, fmt.Errorf("failed to collect notification: %w", err)
+	}
+	return &n, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE order_notifications
+		SET read_at = NOW()
+		WHERE recipient_id = $1 AND channel = 'in_app' AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+//-- ==================================================
+//-- PREFERENCES
+//-- ==================================================
+
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) (notification.Preferences, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT email_enabled, sms_enabled, push_enabled
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return notification.Preferences{}, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	prefs, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.Preferences])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notification.DefaultPreferences(), nil
+		}
+		return notification.Preferences{}, fmt.Errorf("failed to collect notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+func (r *NotificationRepository) UpdatePreferences(ctx context.Context, userID string, payload *notification.UpdatePreferencesPayload) (*notification.Preferences, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO notification_preferences (user_id, email_enabled, sms_enabled, push_enabled)
+		VALUES (@userId, COALESCE(@email, TRUE), COALESCE(@sms, TRUE), COALESCE(@push, TRUE))
+		ON CONFLICT (user_id) DO UPDATE SET
+			email_enabled = COALESCE(@email, notification_preferences.email_enabled),
+			sms_enabled = COALESCE(@sms, notification_preferences.sms_enabled),
+			push_enabled = COALESCE(@push, notification_preferences.push_enabled)
+		RETURNING email_enabled, sms_enabled, push_enabled
+	`, pgx.NamedArgs{
+		"userId": userID,
+		"email":  payload.EmailEnabled,
+		"sms":    payload.SMSEnabled,
+		"push":   payload.PushEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+	prefs, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.Preferences])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect notification preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+//-- ==================================================
+//-- PUSH SUBSCRIPTIONS
+//-- ==================================================
+
+// UpsertPushSubscription registers a browser. An endpoint belongs to one
+// browser, so a re-subscription (or another user signing in on it) takes
+// the row over.
+func (r *NotificationRepository) UpsertPushSubscription(ctx context.Context, userID string, payload *notification.SubscribePushPayload, userAgent *string) (*notification.PushSubscription, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES (@userId, @endpoint, @p256dh, @auth, @userAgent)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING *
+	`, pgx.NamedArgs{
+		"userId":    userID,
+		"endpoint":  payload.Endpoint,
+		"p256dh":    payload.Keys.P256dh,
+		"auth":      payload.Keys.Auth,
+		"userAgent": userAgent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save push subscription: %w", err)
+	}
+	sub, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[notification.PushSubscription])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect push subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+func (r *NotificationRepository) ListPushSubscriptions(ctx context.Context, userID string) ([]notification.PushSubscription, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM push_subscriptions WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push subscriptions: %w", err)
+	}
+	subs, err := pgx.CollectRows(rows, pgx.RowToStructByName[notification.PushSubscription])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect push subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *NotificationRepository) DeleteUserPushSubscription(ctx context.Context, userID, id string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// DeletePushSubscription drops a subscription the push service no longer
+// accepts.
+func (r *NotificationRepository) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	if _, err := r.server.DB.Pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE endpoint = $1`, endpoint); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
@@ -9,33 +9,37 @@ import (
 	"github.com/gitSanje/khajaride/internal/model/cart"
 	"github.com/gitSanje/khajaride/internal/model/order"
 	"github.com/gitSanje/khajaride/internal/model/payout"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/gitSanje/khajaride/internal/server"
 	"github.com/jackc/pgx/v5"
 )
 
+var ErrOrderStatusChanged = errors.New("order status changed")
+
 // ---------------- ORDER REPOSITORY ----------------
 
 type OrderRepository struct {
 	server *server.Server
+	ledger *LedgerRepository
 }
 
-func NewOrderRepository(s *server.Server) *OrderRepository {
-	return &OrderRepository{server: s}
+func NewOrderRepository(s *server.Server, ledger *LedgerRepository) *OrderRepository {
+	return &OrderRepository{server: s, ledger: ledger}
 }
 
 //-- ==================================================
 //-- CREATE ORDER GROUP
 //-- ==================================================
 
-func (r *OrderRepository) CreateOrderGroup(ctx context.Context, tx pgx.Tx, userID string) (*order.OrderGroup, error) {
+func (r *OrderRepository) CreateOrderGroup(ctx context.Context, tx pgx.Tx, userID string, cartSessionID string) (*order.OrderGroup, error) {
 
 	query := `
-		INSERT INTO order_groups (user_id, payment_status)
-		VALUES (@userId, 'unpaid')
+		INSERT INTO order_groups (user_id, cart_session_id, payment_status)
+		VALUES (@userId, @cartSessionId, 'unpaid')
 		RETURNING *
 	`
 
-	row, err := tx.Query(ctx, query, pgx.NamedArgs{"userId": userID})
+	row, err := tx.Query(ctx, query, pgx.NamedArgs{"userId": userID, "cartSessionId": cartSessionID})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create order group: %w", err)
 	}
@@ -68,9 +72,12 @@ func (r *OrderRepository) CreateOrderVendor(
 			vendor_service_charge,
 			vat,
 			vendor_discount,
+			loyalty_points_used,
+			loyalty_discount,
 			expected_delivery_time,
 			delivery_instructions,
 			delivery_address_id,
+			contact_phone,
 			payment_status,
 			status
 		)
@@ -83,9 +90,12 @@ func (r *OrderRepository) CreateOrderVendor(
 			@vendor_service_charge,
 			@vat,
 			@vendor_discount,
+			@loyalty_points_used,
+			@loyalty_discount,
 			@expected_delivery_time,
 			@delivery_instructions,
 			@delivery_address_id,
+			@contact_phone,
 			'unpaid',
 			'pending'
 		)
@@ -101,9 +111,12 @@ func (r *OrderRepository) CreateOrderVendor(
 		"vendor_service_charge": vCart.VendorServiceCharge,
 		"vat":                   vCart.VAT,
 		"vendor_discount":       vCart.VendorDiscount,
+		"loyalty_points_used":   vCart.LoyaltyPointsUsed,
+		"loyalty_discount":      vCart.LoyaltyDiscount,
 		"expected_delivery_time": payload.ExpectedDeliveryTime,
 		"delivery_instructions": payload.DeliveryInstructions,
 		"delivery_address_id":    payload.DeliveryAddressId,
+		"contact_phone":          payload.ContactPhone,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create order_vendor: %w", err)
@@ -137,7 +150,10 @@ func (r *OrderRepository) UpdateOrderVendor(
 			delivery_instructions = COALESCE(@delivery_instructions, delivery_instructions),
 			subtotal = COALESCE(@subtotal, subtotal),
 			vendor_discount = COALESCE(@vendor_discount, vendor_discount),
-			delivery_charge = COALESCE(@delivery_charge, delivery_charge)
+			loyalty_points_used = COALESCE(@loyalty_points_used, loyalty_points_used),
+			loyalty_discount = COALESCE(@loyalty_discount, loyalty_discount),
+			delivery_charge = COALESCE(@delivery_charge, delivery_charge),
+			contact_phone = COALESCE(@contact_phone, contact_phone)
 		WHERE id = @id
 		RETURNING *
 	`
@@ -430,7 +446,12 @@ func (pr *OrderRepository) MarkOrder(ctx context.Context,   orderID string, stat
 	}
 	return nil
 }
-func (pr *OrderRepository) MarkOrderPaidAndCheckout(ctx context.Context, orderID string) error {
+// MarkOrderPaidAndCheckout marks an order paid, accrues the vendor's earning
+// and posts the payment to the ledger in one transaction. Stripe orders are
+// destination charges, so the vendor's share is posted as transferred too.
+// Stock released while the payment was pending is taken back; the changes
+// are returned for publishing.
+func (pr *OrderRepository) MarkOrderPaidAndCheckout(ctx context.Context, orderID, gateway string) ([]vendor.StockChange, error) {
 	query := `
 		WITH updated_cart AS (
 			UPDATE cart_vendors cv
@@ -444,10 +465,366 @@ func (pr *OrderRepository) MarkOrderPaidAndCheckout(ctx context.Context, orderID
 		WHERE id IN (SELECT id FROM updated_cart)
 	`
 
-	_, err := pr.server.DB.Pool.Exec(ctx, query, orderID)
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, query, orderID); err != nil {
+		return nil, fmt.Errorf("update order and cart status: %w", err)
+	}
+
+	changes, err := reclaimReleasedStock(ctx, tx, []string{orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	// accrue the vendor's earning for the next settlement batch
+	if _, err := tx.Exec(ctx, `SELECT accrue_vendor_earning($1)`, orderID); err != nil {
+		return nil, fmt.Errorf("accrue vendor earning: %w", err)
+	}
+
+	// points applied at checkout are spent only now that the order is paid
+	if err := redeemOrderPoints(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+
+	if err := pr.ledger.RecordOrderCapture(ctx, tx, orderID, gateway); err != nil {
+		return nil, fmt.Errorf("post payment to ledger: %w", err)
+	}
+	if gateway == "stripe" {
+		if err := pr.ledger.RecordTransfer(ctx, tx, orderID); err != nil {
+			return nil, fmt.Errorf("post transfer to ledger: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+
+
+//-- ==================================================
+//-- ORDER GROUPS
+//-- ==================================================
+
+// GetUnpaidOrderGroupByCartSession returns the open group of a cart session so
+// that checking out the same cart twice reuses it instead of creating another.
+func (r *OrderRepository) GetUnpaidOrderGroupByCartSession(ctx context.Context, tx pgx.Tx, cartSessionID string) (*order.OrderGroup, error) {
+	query := `
+		SELECT * FROM order_groups
+		WHERE cart_session_id = @cartSessionId AND payment_status = 'unpaid'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	row, err := tx.Query(ctx, query, pgx.NamedArgs{"cartSessionId": cartSessionID})
 	if err != nil {
-		return fmt.Errorf("update order and cart status: %w", err)
+		return nil, err
 	}
+	og, err := pgx.CollectOneRow(row, pgx.RowToStructByName[order.OrderGroup])
+	if err != nil {
+		return nil, err
+	}
+	return &og, nil
+}
 
+func (r *OrderRepository) AttachOrderVendorToGroup(ctx context.Context, tx pgx.Tx, orderVendorID, orderGroupID string) error {
+	query := `UPDATE order_vendors SET order_group_id = @orderGroupId WHERE id = @id`
+	_, err := tx.Exec(ctx, query, pgx.NamedArgs{"id": orderVendorID, "orderGroupId": orderGroupID})
+	if err != nil {
+		return fmt.Errorf("failed to attach order_vendor to group: %w", err)
+	}
 	return nil
 }
+
+// DetachUnlistedOrderVendors drops unpaid orders of a group whose cart vendor is
+// no longer part of the checkout (e.g. the vendor was removed from the cart).
+func (r *OrderRepository) DetachUnlistedOrderVendors(ctx context.Context, tx pgx.Tx, orderGroupID string, keepIDs []string) ([]string, error) {
+	query := `
+		UPDATE order_vendors
+		SET order_group_id = NULL
+		WHERE order_group_id = @orderGroupId
+		  AND payment_status = 'unpaid'
+		  AND NOT (id = ANY(@keepIds))
+		RETURNING id
+	`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"orderGroupId": orderGroupID, "keepIds": keepIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detach order_vendors from group: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect detached order_vendors: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *OrderRepository) DeleteOrderItems(ctx context.Context, tx pgx.Tx, orderVendorID string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM order_items WHERE order_vendor_id = @id`, pgx.NamedArgs{"id": orderVendorID})
+	if err != nil {
+		return fmt.Errorf("failed to delete order_items: %w", err)
+	}
+	return nil
+}
+
+// RefreshOrderGroupTotal recomputes the group total from its child orders.
+func (r *OrderRepository) RefreshOrderGroupTotal(ctx context.Context, tx pgx.Tx, orderGroupID string) (*order.OrderGroup, error) {
+	query := `
+		UPDATE order_groups og
+		SET total = COALESCE((
+			SELECT SUM(ov.total) FROM order_vendors ov WHERE ov.order_group_id = og.id
+		), 0)
+		WHERE og.id = @id
+		RETURNING *
+	`
+	row, err := tx.Query(ctx, query, pgx.NamedArgs{"id": orderGroupID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh order group total: %w", err)
+	}
+	og, err := pgx.CollectOneRow(row, pgx.RowToStructByName[order.OrderGroup])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect order group: %w", err)
+	}
+	return &og, nil
+}
+
+func (r *OrderRepository) GetOrderGroupByID(ctx context.Context, orderGroupID string) (*order.OrderGroup, error) {
+	row, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM order_groups WHERE id = @id`, pgx.NamedArgs{"id": orderGroupID})
+	if err != nil {
+		return nil, err
+	}
+	og, err := pgx.CollectOneRow(row, pgx.RowToStructByName[order.OrderGroup])
+	if err != nil {
+		return nil, err
+	}
+	return &og, nil
+}
+
+func (r *OrderRepository) ListGroupOrderVendors(ctx context.Context, orderGroupID string) ([]order.GroupOrderVendor, error) {
+	query := `
+		SELECT ov.*, v.name AS vendor_name, v.vendor_user_id, v.commission_rate
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		WHERE ov.order_group_id = @orderGroupId
+		ORDER BY ov.created_at
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"orderGroupId": orderGroupID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group orders: %w", err)
+	}
+	orders, err := pgx.CollectRows(rows, pgx.RowToStructByName[order.GroupOrderVendor])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect group orders: %w", err)
+	}
+	return orders, nil
+}
+
+// MarkOrderGroupPaidAndCheckout marks the group and all its orders paid,
+// checks out the cart vendors and the cart session they came from, and posts
+// the payment to the ledger. It reports false when the group was no longer
+// unpaid. Stock released while the payment was pending is taken back; the
+// changes are returned for publishing.
+func (r *OrderRepository) MarkOrderGroupPaidAndCheckout(ctx context.Context, orderGroupID, gateway string) (bool, []vendor.StockChange, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// a redelivered payment event finds the group already paid, or already
+	// partially refunded, and changes nothing
+	tag, err := tx.Exec(ctx, `UPDATE order_groups SET payment_status = 'paid' WHERE id = @id AND payment_status = 'unpaid'`,
+		pgx.NamedArgs{"id": orderGroupID})
+	if err != nil {
+		return false, nil, fmt.Errorf("update order group payment status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil, nil
+	}
+
+	queries := []string{
+		`UPDATE order_vendors SET payment_status = 'paid' WHERE order_group_id = @id AND payment_status = 'unpaid'`,
+		`UPDATE cart_vendors cv
+		 SET status = 'checked_out'
+		 FROM order_vendors ov
+		 WHERE ov.order_group_id = @id AND ov.vendor_cart_id = cv.id`,
+		`UPDATE cart_sessions cs
+		 SET status = 'checked_out'
+		 FROM order_groups og
+		 WHERE og.id = @id AND og.cart_session_id = cs.id`,
+		// accrue every vendor's earning for the next settlement batch
+		`SELECT accrue_vendor_earning(id) FROM order_vendors WHERE order_group_id = @id`,
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(ctx, q, pgx.NamedArgs{"id": orderGroupID}); err != nil {
+			return false, nil, fmt.Errorf("update order group and cart status: %w", err)
+		}
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id FROM order_vendors WHERE order_group_id = $1 ORDER BY id`, orderGroupID)
+	if err != nil {
+		return false, nil, err
+	}
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return false, nil, err
+	}
+	changes, err := reclaimReleasedStock(ctx, tx, orderIDs)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// points applied at checkout are spent only now that the orders are paid
+	for _, id := range orderIDs {
+		if err := redeemOrderPoints(ctx, tx, id); err != nil {
+			return false, nil, err
+		}
+	}
+
+	if err := r.ledger.RecordOrderGroupCapture(ctx, tx, orderGroupID, gateway); err != nil {
+		return false, nil, fmt.Errorf("post group payment to ledger: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, nil, err
+	}
+	return true, changes, nil
+}
+
+func (r *OrderRepository) MarkOrderGroup(ctx context.Context, orderGroupID string, status string) error {
+	_, err := r.server.DB.Pool.Exec(ctx,
+		`UPDATE order_groups SET payment_status = @status WHERE id = @id`,
+		pgx.NamedArgs{"id": orderGroupID, "status": status},
+	)
+	if err != nil {
+		return fmt.Errorf("update order group payment status: %w", err)
+	}
+	return nil
+}
+
+// RefreshOrderGroupPaymentStatus derives the group payment status from its
+// orders once one of them has been refunded.
+func (r *OrderRepository) RefreshOrderGroupPaymentStatus(ctx context.Context, orderGroupID string) (string, error) {
+	query := `
+		UPDATE order_groups og
+		SET payment_status = CASE
+			WHEN s.refunded = s.total THEN 'refunded'
+			WHEN s.refunded > 0 THEN 'partially_refunded'
+			ELSE og.payment_status
+		END
+		FROM (
+			SELECT
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE payment_status = 'refunded') AS refunded
+			FROM order_vendors
+			WHERE order_group_id = @id
+		) s
+		WHERE og.id = @id
+		RETURNING og.payment_status
+	`
+	var status string
+	if err := r.server.DB.Pool.QueryRow(ctx, query, pgx.NamedArgs{"id": orderGroupID}).Scan(&status); err != nil {
+		return "", fmt.Errorf("refresh order group payment status: %w", err)
+	}
+	return status, nil
+}
+
+//-- ==================================================
+//-- ORDER VENDOR STATUS
+//-- ==================================================
+
+// GetVendorOrder returns an order only if it belongs to vendorID.
+func (r *OrderRepository) GetVendorOrder(ctx context.Context, orderVendorID, vendorID string) (*order.GroupOrderVendor, error) {
+	query := `
+		SELECT ov.*, v.name AS vendor_name, v.vendor_user_id, v.commission_rate
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		WHERE ov.id = @id AND ov.vendor_id = @vendorId
+	`
+	row, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"id": orderVendorID, "vendorId": vendorID})
+	if err != nil {
+		return nil, err
+	}
+	ov, err := pgx.CollectOneRow(row, pgx.RowToStructByName[order.GroupOrderVendor])
+	if err != nil {
+		return nil, err
+	}
+	return &ov, nil
+}
+
+// TransitionOrderVendorStatus moves an order from one status to the next only
+// if it is still in the status the caller checked, so two concurrent updates
+// cannot both apply. Delivery also needs the order to still be paid. It
+// returns ErrOrderStatusChanged when the order moved on in the meantime.
+func (r *OrderRepository) TransitionOrderVendorStatus(ctx context.Context, orderVendorID, from, to string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE order_vendors
+		SET status = @to,
+		    restaurant_accepted_at = CASE WHEN @to = 'accepted' THEN NOW() ELSE restaurant_accepted_at END,
+		    delivered_at = CASE WHEN @to = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE id = @id
+		  AND status = @from
+		  AND (@to <> 'delivered' OR payment_status = 'paid')
+	`,
+		pgx.NamedArgs{"id": orderVendorID, "from": from, "to": to},
+	)
+	if err != nil {
+		return fmt.Errorf("update order_vendor status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- REORDER
+//-- ==================================================
+
+func (r *OrderRepository) GetReorderSource(ctx context.Context, orderVendorID, userID string) (*order.ReorderSource, error) {
+	query := `
+		SELECT ov.id AS order_vendor_id, ov.vendor_id, v.name AS vendor_name,
+		       (COALESCE(v.is_open, FALSE) AND v.status = 'active') AS vendor_is_open
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		WHERE ov.id = @id AND ov.user_id = @userId
+	`
+	row, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"id": orderVendorID, "userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	src, err := pgx.CollectOneRow(row, pgx.RowToStructByName[order.ReorderSource])
+	if err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+// ListReorderItems returns the items of a past order with the current price
+// and availability of each menu item at the vendor, after outlet overrides.
+// Items the vendor has since moved off its menu count as unavailable.
+func (r *OrderRepository) ListReorderItems(ctx context.Context, orderVendorID string) ([]order.ReorderItem, error) {
+	query := `
+		SELECT oi.menu_item_id, mi.name, oi.quantity, oi.unit_price, oi.special_instructions,
+		       COALESCE(vmi.base_price, mi.base_price) AS current_price,
+		       COALESCE(vmi.is_available, FALSE) AS is_available
+		FROM order_items oi
+		JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+		JOIN menu_items mi ON mi.id = oi.menu_item_id
+		LEFT JOIN vendor_menu_items vmi ON vmi.id = oi.menu_item_id AND vmi.vendor_id = ov.vendor_id
+		WHERE oi.order_vendor_id = $1
+		ORDER BY oi.created_at
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, orderVendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reorder items: %w", err)
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[order.ReorderItem])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect reorder items: %w", err)
+	}
+	return items, nil
+}
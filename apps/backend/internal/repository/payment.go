@@ -4,26 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
-	"github.com/gitSanje/khajaride/internal/lib/events"
 	"github.com/gitSanje/khajaride/internal/model/payment"
 	"github.com/gitSanje/khajaride/internal/model/payout"
 	"github.com/gitSanje/khajaride/internal/server"
 	"github.com/jackc/pgx/v5"
 	"github.com/stripe/stripe-go/v83"
 	"github.com/stripe/stripe-go/v83/balance"
-	stripepayout "github.com/stripe/stripe-go/v83/payout"
 )
 
 // ---------------- PAYMENT REPOSITORY ----------------
 
 type PaymentRepository struct {
 	server *server.Server
+	ledger *LedgerRepository
 }
 
-func NewPaymentRepository(s *server.Server) *PaymentRepository {
-	return &PaymentRepository{server: s}
+func NewPaymentRepository(s *server.Server, ledger *LedgerRepository) *PaymentRepository {
+	return &PaymentRepository{server: s, ledger: ledger}
 }
 
 func (pr *PaymentRepository) CreateOrUpdateOrderPayment(ctx context.Context, p *payment.OrderPayment) error {
@@ -139,6 +137,75 @@ func (pr *PaymentRepository) UpdatePaymentStatus(ctx context.Context, transactio
 
 
 
+// FailOrderPayment marks the order's payment failed unless it has already
+// been settled one way or the other.
+func (pr *PaymentRepository) FailOrderPayment(ctx context.Context, orderID string) error {
+	_, err := pr.server.DB.Pool.Exec(ctx, `
+		UPDATE order_payments
+		SET status = 'failed'
+		WHERE order_id = $1
+		  AND status = 'initiated'
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to mark order payment failed: %w", err)
+	}
+	return nil
+}
+
+// MarkOrderPaymentRefundPending records a paid order whose refund has to be
+// sent by hand and takes it out of the vendor's next settlement in the same
+// transaction.
+func (pr *PaymentRepository) MarkOrderPaymentRefundPending(ctx context.Context, p *payment.OrderPayment, amount float64) error {
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE order_payments SET status = 'refund_pending' WHERE id = $1`, p.ID); err != nil {
+		return fmt.Errorf("update order payment: %w", err)
+	}
+	if err := accrueRefund(ctx, tx, pr.ledger, p.OrderID, amount); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkOrderPaymentRefunded records a refunded order, takes it out of the
+// vendor's next settlement and posts the reversal of its destination charge
+// transfer and the refund sent back through the gateway in the same
+// transaction.
+func (pr *PaymentRepository) MarkOrderPaymentRefunded(ctx context.Context, p *payment.OrderPayment, amount float64, refundRef string) error {
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE order_payments SET status = 'refunded', refund_ref = @refundRef WHERE id = @id`,
+		pgx.NamedArgs{"id": p.ID, "refundRef": refundRef},
+	)
+	if err != nil {
+		return fmt.Errorf("update order payment: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE order_vendors SET payment_status = 'refunded' WHERE id = $1`, p.OrderID); err != nil {
+		return fmt.Errorf("update order payment status: %w", err)
+	}
+	if err := accrueRefund(ctx, tx, pr.ledger, p.OrderID, amount); err != nil {
+		return err
+	}
+	if p.PaymentGateway == "stripe" {
+		if err := pr.ledger.RecordTransferReversal(ctx, tx, p.OrderID); err != nil {
+			return fmt.Errorf("post transfer reversal to ledger: %w", err)
+		}
+	}
+	if err := pr.ledger.RecordRefundDisbursed(ctx, tx, p.OrderID, p.PaymentGateway, refundRef); err != nil {
+		return fmt.Errorf("post refund to ledger: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
 func (r *PaymentRepository) CreatePayoutAccount(ctx context.Context, acc *payout.PayoutAccount) error {
 	query := `
 		INSERT INTO payout_accounts (
@@ -230,27 +297,61 @@ func (r *PaymentRepository) GetStripeAccountID(ctx context.Context, vendorUserID
 }
 
 
-func (r *PaymentRepository) GetPayoutAccountID(ctx context.Context, vendorUserID string) (string, error) {
+// GetOrderPayoutAccount returns the Stripe payout account of the vendor
+// that fulfils an order: the outlet's own account when it has one, else the
+// owner's Stripe account. Both are "" when there is none.
+func (r *PaymentRepository) GetOrderPayoutAccount(ctx context.Context, orderVendorID string) (accountID string, stripeAccountID string, err error) {
 	query := `
-		SELECT id
-		FROM payout_accounts
-		WHERE owner_id = $1
-		  AND owner_type = 'vendor'
-		  AND method = 'stripe'
-		LIMIT 1;
+		SELECT pa.id, COALESCE(pa.stripe_account_id, '')
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		JOIN payout_accounts pa ON pa.id = COALESCE(v.payout_account_id, (
+			SELECT own.id FROM payout_accounts own
+			WHERE own.owner_id = v.vendor_user_id
+			  AND own.owner_type = 'vendor'
+			  AND own.method = 'stripe'
+			ORDER BY own.is_default DESC, own.created_at
+			LIMIT 1
+		))
+		WHERE ov.id = $1
+		  AND pa.method = 'stripe'
 	`
 
-	var ID string
-	err := r.server.DB.Pool.QueryRow(ctx, query, vendorUserID).Scan(&ID)
+	err = r.server.DB.Pool.QueryRow(ctx, query, orderVendorID).Scan(&accountID, &stripeAccountID)
 	if err != nil {
-		// No row found → return empty string, nil (not an error)
+		// No row found → return empty strings, nil (not an error)
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", nil
+			return "", "", nil
 		}
-		return "", fmt.Errorf("failed to fetch stripe account id: %w", err)
+		return "", "", fmt.Errorf("failed to fetch order payout account: %w", err)
 	}
 
-	return ID, nil
+	return accountID, stripeAccountID, nil
+}
+
+// GetOrderCharge returns the total of an order outside a group together with
+// its vendor's commission rate.
+func (r *PaymentRepository) GetOrderCharge(ctx context.Context, orderVendorID string) (*payment.OrderCharge, error) {
+	query := `
+		SELECT ov.id AS order_id, ov.total, COALESCE(ov.payment_status, 'unpaid') AS payment_status,
+		       v.vendor_user_id, v.commission_rate
+		FROM order_vendors ov
+		JOIN vendors v ON v.id = ov.vendor_id
+		WHERE ov.id = $1
+		  AND ov.order_group_id IS NULL
+	`
+
+	rows, err := r.server.DB.Pool.Query(ctx, query, orderVendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order charge: %w", err)
+	}
+
+	charge, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[payment.OrderCharge])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect order charge: %w", err)
+	}
+
+	return &charge, nil
 }
 
 func (r *PaymentRepository) UpdateStripePayoutAccount(ctx context.Context, payload *payout.PayoutAccountUpdatePayload) error {
@@ -342,71 +443,6 @@ func (r *PaymentRepository) UpdatePayoutStatus(ctx context.Context, pId string,s
 
 
 
-func (r *PaymentRepository) PerformStripePayout(ctx context.Context, payload *events.PayoutRequestedEvent) (*stripe.Payout, error) {
-	// Stripe uses cents
-	amt := payload.Amount * 100
-	fee:= payload.Amount *100*0.03
-    vendorShare := amt - fee
-    
-	 // ✅ Check balance
-    availableUSD, err := r.CheckConnectedAccountBalance(payload.StripeConnectAccId, "usd",true)
-	if err != nil {
-		return nil,fmt.Errorf("error:%s", err)
-	}
-
-	if availableUSD*100 < vendorShare {
-        log.Printf("Balance:%f, VendorShare:%f", availableUSD, float64(vendorShare)/100)
-        return nil, fmt.Errorf("insufficient funds for payout")
-    }
-
-	log.Printf("Balance:%f,VendorShare:%f", availableUSD,vendorShare / 100)
-
-	stripe.Key = r.server.Config.Stripe.SecretKey
-	
-	params := &stripe.PayoutParams{
-		Amount:   stripe.Int64(int64(vendorShare)),
-		Currency: stripe.String("usd"),
-		
-		 Method: stripe.String("instant"),
-	}
-    
-	payoutPayload := &payout.Payout{
-			VendorUserID:   &payload.VendorUserID,
-			OrderID:        &payload.OrderID,
-			AccountID:      &payload.PayoutAccId,
-			Sender:         "platform",
-			PayoutType:     "vendor_payout",
-			Method:         "stripe",
-			Amount:          vendorShare / 100,
-			Status:         "pending",
-			TransactionRef: &payload.SessionId, 
-    }
-	pid, err := r.CreatePayout(ctx, payoutPayload); 
-	
-	if err != nil {
-	  return nil, fmt.Errorf("create payout: %w", err)
-	}
-
-	// Send payout to connected account
-	params.SetStripeAccount(payload.StripeConnectAccId)
-
-	// ✅ Embed metadata for traceability
-	params.AddMetadata("vendor_id", payload.VendorUserID)
-	params.AddMetadata("order_id", payload.OrderID)
-	params.AddMetadata("payout_account_id", payload.PayoutAccId)
-	params.AddMetadata("payout_type", "vendor_payout")
-	params.AddMetadata("payout_id",pid)
-
-
-	p, err := stripepayout.New(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create payout: %w", err)
-	}
-	fmt.Println("✅ Payout sent:", p.ID)
-	
-	return p, nil
-}
-
 func (r *PaymentRepository) CheckConnectedAccountBalance(stripeAccountID string, currency string, instant bool) (float64, error) {
 	stripe.Key = r.server.Config.Stripe.SecretKey
     params := &stripe.BalanceParams{}
@@ -430,3 +466,277 @@ func (r *PaymentRepository) CheckConnectedAccountBalance(stripeAccountID string,
 
     return 0, fmt.Errorf("no balance available in currency %s", currency)
 }
+
+
+
+//-- ==================================================
+//-- ORDER GROUP PAYMENTS
+//-- ==================================================
+
+func (pr *PaymentRepository) CreateOrUpdateOrderGroupPayment(ctx context.Context, p *payment.OrderGroupPayment) error {
+	query := `
+		INSERT INTO order_group_payments (
+			order_group_id, payment_gateway, amount, status, transaction_id, method
+		)
+		VALUES (@orderGroupId, @paymentGateway, @amount, @status, @transactionId, @method)
+		ON CONFLICT (order_group_id) DO UPDATE
+		SET payment_gateway = EXCLUDED.payment_gateway,
+		    amount = EXCLUDED.amount,
+		    status = EXCLUDED.status,
+		    transaction_id = EXCLUDED.transaction_id,
+		    method = EXCLUDED.method
+	`
+	_, err := pr.server.DB.Pool.Exec(ctx, query, pgx.NamedArgs{
+		"orderGroupId":   p.OrderGroupID,
+		"paymentGateway": p.PaymentGateway,
+		"amount":         p.Amount,
+		"status":         p.Status,
+		"transactionId":  p.TransactionID,
+		"method":         p.Method,
+	})
+	if err != nil {
+		return fmt.Errorf("upsert order group payment: %w", err)
+	}
+	return nil
+}
+
+func (pr *PaymentRepository) GetOrderGroupPaymentByGroupID(ctx context.Context, orderGroupID string) (*payment.OrderGroupPayment, error) {
+	row, err := pr.server.DB.Pool.Query(ctx,
+		`SELECT * FROM order_group_payments WHERE order_group_id = @orderGroupId`,
+		pgx.NamedArgs{"orderGroupId": orderGroupID},
+	)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pgx.CollectOneRow(row, pgx.RowToStructByName[payment.OrderGroupPayment])
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (pr *PaymentRepository) GetOrderGroupPaymentByTransactionID(ctx context.Context, transactionID string) (*payment.OrderGroupPayment, error) {
+	row, err := pr.server.DB.Pool.Query(ctx,
+		`SELECT * FROM order_group_payments WHERE transaction_id = @transactionId LIMIT 1`,
+		pgx.NamedArgs{"transactionId": transactionID},
+	)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pgx.CollectOneRow(row, pgx.RowToStructByName[payment.OrderGroupPayment])
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (pr *PaymentRepository) UpdateOrderGroupPaymentStatus(ctx context.Context, transactionID, status string, chargeRef *string) (string, error) {
+	query := `
+		UPDATE order_group_payments
+		SET status = @status,
+		    charge_ref = COALESCE(@chargeRef, charge_ref),
+		    paid_at = CASE WHEN @status = 'success' THEN NOW() ELSE paid_at END
+		WHERE transaction_id = @transactionId
+		RETURNING order_group_id
+	`
+	var orderGroupID string
+	err := pr.server.DB.Pool.QueryRow(ctx, query, pgx.NamedArgs{
+		"transactionId": transactionID,
+		"status":        status,
+		"chargeRef":     chargeRef,
+	}).Scan(&orderGroupID)
+	if err != nil {
+		return "", err
+	}
+	return orderGroupID, nil
+}
+
+//-- ==================================================
+//-- ORDER VENDOR SPLITS
+//-- ==================================================
+
+func (pr *PaymentRepository) UpsertOrderVendorSplit(ctx context.Context, s *payment.OrderVendorSplit) error {
+	query := `
+		INSERT INTO order_vendor_splits (
+			order_group_id, order_vendor_id, vendor_user_id, stripe_account_id, amount, commission, status
+		)
+		VALUES (@orderGroupId, @orderVendorId, @vendorUserId, @stripeAccountId, @amount, @commission, 'pending')
+		ON CONFLICT (order_vendor_id) DO UPDATE
+		SET order_group_id = EXCLUDED.order_group_id,
+		    stripe_account_id = EXCLUDED.stripe_account_id,
+		    amount = EXCLUDED.amount,
+		    commission = EXCLUDED.commission
+		WHERE order_vendor_splits.status = 'pending'
+	`
+	_, err := pr.server.DB.Pool.Exec(ctx, query, pgx.NamedArgs{
+		"orderGroupId":    s.OrderGroupID,
+		"orderVendorId":   s.OrderVendorID,
+		"vendorUserId":    s.VendorUserID,
+		"stripeAccountId": s.StripeAccountID,
+		"amount":          s.Amount,
+		"commission":      s.Commission,
+	})
+	if err != nil {
+		return fmt.Errorf("upsert order vendor split: %w", err)
+	}
+	return nil
+}
+
+func (pr *PaymentRepository) ListOrderVendorSplits(ctx context.Context, orderGroupID string) ([]payment.OrderVendorSplit, error) {
+	rows, err := pr.server.DB.Pool.Query(ctx,
+		`SELECT * FROM order_vendor_splits WHERE order_group_id = @orderGroupId ORDER BY created_at`,
+		pgx.NamedArgs{"orderGroupId": orderGroupID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list order vendor splits: %w", err)
+	}
+	splits, err := pgx.CollectRows(rows, pgx.RowToStructByName[payment.OrderVendorSplit])
+	if err != nil {
+		return nil, fmt.Errorf("collect order vendor splits: %w", err)
+	}
+	return splits, nil
+}
+
+func (pr *PaymentRepository) GetOrderVendorSplit(ctx context.Context, orderVendorID string) (*payment.OrderVendorSplit, error) {
+	row, err := pr.server.DB.Pool.Query(ctx,
+		`SELECT * FROM order_vendor_splits WHERE order_vendor_id = @orderVendorId`,
+		pgx.NamedArgs{"orderVendorId": orderVendorID},
+	)
+	if err != nil {
+		return nil, err
+	}
+	s, err := pgx.CollectOneRow(row, pgx.RowToStructByName[payment.OrderVendorSplit])
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (pr *PaymentRepository) UpdateOrderVendorSplit(ctx context.Context, id, status string, transferRef, refundRef, remarks *string) error {
+	query := `
+		UPDATE order_vendor_splits
+		SET status = @status,
+		    transfer_ref = COALESCE(@transferRef, transfer_ref),
+		    refund_ref = COALESCE(@refundRef, refund_ref),
+		    remarks = COALESCE(@remarks, remarks)
+		WHERE id = @id
+	`
+	_, err := pr.server.DB.Pool.Exec(ctx, query, pgx.NamedArgs{
+		"id":          id,
+		"status":      status,
+		"transferRef": transferRef,
+		"refundRef":   refundRef,
+		"remarks":     remarks,
+	})
+	if err != nil {
+		return fmt.Errorf("update order vendor split: %w", err)
+	}
+	return nil
+}
+
+// MarkOrderVendorSplitTransferred records a vendor's group transfer and posts
+// it to the ledger in the same transaction.
+func (pr *PaymentRepository) MarkOrderVendorSplitTransferred(ctx context.Context, sp *payment.OrderVendorSplit, transferRef string) error {
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE order_vendor_splits SET status = 'transferred', transfer_ref = @transferRef WHERE id = @id`,
+		pgx.NamedArgs{"id": sp.ID, "transferRef": transferRef},
+	)
+	if err != nil {
+		return fmt.Errorf("update order vendor split: %w", err)
+	}
+	if err := pr.ledger.RecordTransfer(ctx, tx, sp.OrderVendorID); err != nil {
+		return fmt.Errorf("post transfer to ledger: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkOrderVendorSplitRefundPending records a split whose refund has to be
+// sent by hand and takes it out of the vendor's next settlement in the same
+// transaction.
+func (pr *PaymentRepository) MarkOrderVendorSplitRefundPending(ctx context.Context, sp *payment.OrderVendorSplit, remarks *string) error {
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE order_vendor_splits SET status = 'refund_pending', remarks = COALESCE(@remarks, remarks) WHERE id = @id`,
+		pgx.NamedArgs{"id": sp.ID, "remarks": remarks},
+	)
+	if err != nil {
+		return fmt.Errorf("update order vendor split: %w", err)
+	}
+	if err := accrueRefund(ctx, tx, pr.ledger, sp.OrderVendorID, sp.Amount); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkOrderVendorSplitRefunded records a refunded split, takes it out of the
+// vendor's next settlement and posts the reversal of its transfer, if it had
+// one, and the refund sent back through the gateway in the same transaction.
+func (pr *PaymentRepository) MarkOrderVendorSplitRefunded(ctx context.Context, sp *payment.OrderVendorSplit, gateway, refundRef string, remarks *string) error {
+	tx, err := pr.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE order_vendor_splits
+		SET status = 'refunded',
+		    refund_ref = @refundRef,
+		    remarks = COALESCE(@remarks, remarks)
+		WHERE id = @id
+	`, pgx.NamedArgs{"id": sp.ID, "refundRef": refundRef, "remarks": remarks})
+	if err != nil {
+		return fmt.Errorf("update order vendor split: %w", err)
+	}
+	if err := accrueRefund(ctx, tx, pr.ledger, sp.OrderVendorID, sp.Amount); err != nil {
+		return err
+	}
+	if sp.TransferRef != nil {
+		if err := pr.ledger.RecordTransferReversal(ctx, tx, sp.OrderVendorID); err != nil {
+			return fmt.Errorf("post transfer reversal to ledger: %w", err)
+		}
+	}
+	if err := pr.ledger.RecordRefundDisbursed(ctx, tx, sp.OrderVendorID, gateway, refundRef); err != nil {
+		return fmt.Errorf("post refund to ledger: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (pr *PaymentRepository) ListUserOrderPayments(ctx context.Context, userID string) ([]payment.OrderPayment, error) {
+	query := `
+		SELECT op.* FROM order_payments op
+		JOIN order_vendors ov ON ov.id = op.order_id
+		WHERE ov.user_id = @userId
+		ORDER BY op.created_at DESC
+	`
+	rows, err := pr.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"userId": userID})
+	if err != nil {
+		return nil, fmt.Errorf("list user order payments: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[payment.OrderPayment])
+}
+
+func (pr *PaymentRepository) ListUserOrderGroupPayments(ctx context.Context, userID string) ([]payment.OrderGroupPayment, error) {
+	query := `
+		SELECT gp.* FROM order_group_payments gp
+		JOIN order_groups og ON og.id = gp.order_group_id
+		WHERE og.user_id = @userId
+		ORDER BY gp.created_at DESC
+	`
+	rows, err := pr.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"userId": userID})
+	if err != nil {
+		return nil, fmt.Errorf("list user order group payments: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[payment.OrderGroupPayment])
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/recommendation"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ---------------- RECOMMENDATION REPOSITORY ----------------
+
+type RecommendationRepository struct {
+	server *server.Server
+}
+
+func NewRecommendationRepository(s *server.Server) *RecommendationRepository {
+	return &RecommendationRepository{server: s}
+}
+
+// recommendedItemColumns selects a recommendation.Item from vendor_menu_items
+// mi joined with vendors v. Queries keep to active vendors' available items.
+const recommendedItemColumns = `
+	mi.id AS menu_item_id, mi.name, mi.image, mi.base_price,
+	v.id AS vendor_id, v.name AS vendor_name`
+
+//-- ==================================================
+//-- MENU ITEM STATS
+//-- ==================================================
+
+// RefreshMenuItemStats rebuilds the order aggregates of menu_item_stats from
+// delivered orders. A customer counts towards reorder_count once they have
+// ordered the item in more than one order.
+func (r *RecommendationRepository) RefreshMenuItemStats(ctx context.Context) (int64, error) {
+	stmt := `
+		WITH per_customer AS (
+			SELECT oi.menu_item_id, ov.user_id,
+			       COUNT(DISTINCT ov.id) AS orders,
+			       MAX(COALESCE(ov.delivered_at, ov.created_at)) AS last_ordered
+			FROM order_items oi
+			JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+			WHERE ov.status = 'delivered'
+			GROUP BY oi.menu_item_id, ov.user_id
+		), agg AS (
+			SELECT menu_item_id,
+			       SUM(orders)::INT AS order_count,
+			       COUNT(*)::INT AS unique_customers,
+			       (COUNT(*) FILTER (WHERE orders > 1))::INT AS reorder_count,
+			       MAX(last_ordered) AS last_ordered
+			FROM per_customer
+			GROUP BY menu_item_id
+		)
+		INSERT INTO menu_item_stats (menu_item_id, order_count, unique_customers, reorder_count, reorder_rate, last_ordered)
+		SELECT menu_item_id, order_count, unique_customers, reorder_count,
+		       ROUND(reorder_count::NUMERIC / unique_customers, 4), last_ordered
+		FROM agg
+		ON CONFLICT (menu_item_id) DO UPDATE SET
+			order_count = EXCLUDED.order_count,
+			unique_customers = EXCLUDED.unique_customers,
+			reorder_count = EXCLUDED.reorder_count,
+			reorder_rate = EXCLUDED.reorder_rate,
+			last_ordered = EXCLUDED.last_ordered
+		WHERE (menu_item_stats.order_count, menu_item_stats.unique_customers, menu_item_stats.reorder_count, menu_item_stats.last_ordered)
+		      IS DISTINCT FROM (EXCLUDED.order_count, EXCLUDED.unique_customers, EXCLUDED.reorder_count, EXCLUDED.last_ordered)
+	`
+	tag, err := r.server.DB.Pool.Exec(ctx, stmt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh menu item stats: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+//-- ==================================================
+//-- RECOMMENDATION LISTS
+//-- ==================================================
+
+// ListRecommendationUserIDs pages (by id) through the active users there is
+// something to recommend to: a recent delivered order or a default address.
+func (r *RecommendationRepository) ListRecommendationUserIDs(ctx context.Context, afterID string, limit int) ([]string, error) {
+	stmt := `
+		SELECT u.id
+		FROM users u
+		WHERE u.id > @afterId
+		  AND COALESCE(u.is_active, TRUE) AND u.anonymized_at IS NULL
+		  AND (
+			EXISTS (
+				SELECT 1 FROM order_vendors ov
+				WHERE ov.user_id = u.id AND ov.status = 'delivered'
+				  AND ov.created_at > NOW() - make_interval(secs => @window)
+			)
+			OR EXISTS (SELECT 1 FROM user_addresses ua WHERE ua.user_id = u.id AND ua.is_default)
+		  )
+		ORDER BY u.id
+		LIMIT @limit
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+		"afterId": afterID,
+		"window":  recommendation.HistoryWindow.Seconds(),
+		"limit":   limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recommendation users: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect recommendation users: %w", err)
+	}
+	return ids, nil
+}
+
+// BuildRecommendations computes all lists for a user from the database.
+func (r *RecommendationRepository) BuildRecommendations(ctx context.Context, userID string) (*recommendation.Recommendations, error) {
+	usuals, err := r.getUsuals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	popular, err := r.getPopularNearUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	alsoOrdered, err := r.getAlsoOrdered(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recommendation.Recommendations{
+		Usuals:         usuals,
+		PopularNearYou: popular,
+		AlsoOrdered:    alsoOrdered,
+		GeneratedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// getUsuals returns the items the user keeps coming back to, most ordered
+// first.
+func (r *RecommendationRepository) getUsuals(ctx context.Context, userID string) ([]recommendation.Item, error) {
+	stmt := `
+		SELECT ` + recommendedItemColumns + `, COUNT(DISTINCT ov.id)::FLOAT8 AS score
+		FROM order_items oi
+		JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+		JOIN vendor_menu_items mi ON mi.id = oi.menu_item_id AND mi.vendor_id = ov.vendor_id
+		JOIN vendors v ON v.id = mi.vendor_id
+		WHERE ov.user_id = @userId AND ov.status = 'delivered'
+		  AND ov.created_at > NOW() - make_interval(secs => @window)
+		  AND mi.is_available AND v.status = 'active'
+		GROUP BY mi.id, v.id
+		HAVING COUNT(DISTINCT ov.id) >= @minOrders
+		ORDER BY score DESC, MAX(ov.created_at) DESC
+		LIMIT @limit
+	`
+	return r.queryItems(ctx, "usuals", stmt, pgx.NamedArgs{
+		"userId":    userID,
+		"window":    recommendation.HistoryWindow.Seconds(),
+		"minOrders": recommendation.UsualMinOrders,
+		"limit":     recommendation.UsualsLimit,
+	})
+}
+
+// getPopularNearUser returns the most ordered items of vendors within
+// NearbyRadiusKm (great-circle distance) of the user's default address and
+// their own delivery zone. A brand's master item is offered from its nearest
+// outlet. Users without a default address get an empty list.
+func (r *RecommendationRepository) getPopularNearUser(ctx context.Context, userID string) ([]recommendation.Item, error) {
+	stmt := `
+		WITH home AS (
+			SELECT latitude, longitude FROM user_addresses
+			WHERE user_id = @userId AND is_default
+			LIMIT 1
+		)
+		SELECT menu_item_id, name, image, base_price, vendor_id, vendor_name, score
+		FROM (
+			SELECT DISTINCT ON (mi.id) ` + recommendedItemColumns + `,
+			       s.order_count::FLOAT8 AS score, s.reorder_rate
+			FROM home h
+			CROSS JOIN menu_item_stats s
+			JOIN vendor_menu_items mi ON mi.id = s.menu_item_id
+			JOIN vendors v ON v.id = mi.vendor_id
+			JOIN vendor_addresses va ON va.vendor_id = v.id
+			CROSS JOIN LATERAL (
+				SELECT distance_km(h.latitude, h.longitude, va.latitude, va.longitude) AS km
+			) d
+			WHERE s.order_count > 0 AND mi.is_available AND v.status = 'active'
+			  AND va.latitude IS NOT NULL AND va.longitude IS NOT NULL
+			  AND d.km <= @radiusKm
+			  AND (v.delivery_radius_km IS NULL OR d.km <= v.delivery_radius_km)
+			ORDER BY mi.id, d.km
+		) nearest
+		ORDER BY score DESC, reorder_rate DESC, menu_item_id
+		LIMIT @limit
+	`
+	return r.queryItems(ctx, "popular near you", stmt, pgx.NamedArgs{
+		"userId":   userID,
+		"radiusKm": recommendation.NearbyRadiusKm,
+		"limit":    recommendation.PopularLimit,
+	})
+}
+
+// getAlsoOrdered returns items that other customers' delivered orders had
+// together with items the user ordered, ranked by the number of such orders.
+// Items the user already ordered are left out.
+func (r *RecommendationRepository) getAlsoOrdered(ctx context.Context, userID string) ([]recommendation.Item, error) {
+	stmt := `
+		WITH seed AS (
+			SELECT DISTINCT oi.menu_item_id
+			FROM order_items oi
+			JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+			WHERE ov.user_id = @userId AND ov.status = 'delivered'
+			  AND ov.created_at > NOW() - make_interval(secs => @window)
+		), baskets AS (
+			SELECT DISTINCT oi.order_vendor_id
+			FROM order_items oi
+			JOIN seed USING (menu_item_id)
+			JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+			WHERE ov.user_id <> @userId AND ov.status = 'delivered'
+			  AND ov.created_at > NOW() - make_interval(secs => @window)
+		)
+		SELECT ` + recommendedItemColumns + `, COUNT(DISTINCT oi.order_vendor_id)::FLOAT8 AS score
+		FROM order_items oi
+		JOIN baskets b USING (order_vendor_id)
+		JOIN order_vendors ov ON ov.id = oi.order_vendor_id
+		JOIN vendor_menu_items mi ON mi.id = oi.menu_item_id AND mi.vendor_id = ov.vendor_id
+		JOIN vendors v ON v.id = mi.vendor_id
+		WHERE mi.is_available AND v.status = 'active'
+		  AND oi.menu_item_id NOT IN (SELECT menu_item_id FROM seed)
+		GROUP BY mi.id, v.id
+		ORDER BY score DESC, mi.id
+		LIMIT @limit
+	`
+	return r.queryItems(ctx, "also ordered", stmt, pgx.NamedArgs{
+		"userId": userID,
+		"window": recommendation.HistoryWindow.Seconds(),
+		"limit":  recommendation.AlsoOrderedLimit,
+	})
+}
+
+func (r *RecommendationRepository) queryItems(ctx context.Context, list, stmt string, args pgx.NamedArgs) ([]recommendation.Item, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", list, err)
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[recommendation.Item])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect %s: %w", list, err)
+	}
+	return items, nil
+}
+
+//-- ==================================================
+//-- CACHE
+//-- ==================================================
+
+// GetCachedRecommendations returns the precomputed lists, or nil when there
+// are none.
+func (r *RecommendationRepository) GetCachedRecommendations(ctx context.Context, userID string) (*recommendation.Recommendations, error) {
+	raw, err := r.server.Redis.Get(ctx, recommendation.CacheKey(userID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cached recommendations: %w", err)
+	}
+
+	var recs recommendation.Recommendations
+	if err := json.Unmarshal(raw, &recs); err != nil {
+		return nil, fmt.Errorf("failed to decode cached recommendations: %w", err)
+	}
+	return &recs, nil
+}
+
+func (r *RecommendationRepository) CacheRecommendations(ctx context.Context, userID string, recs *recommendation.Recommendations) error {
+	raw, err := json.Marshal(recs)
+	if err != nil {
+		return fmt.Errorf("failed to encode recommendations: %w", err)
+	}
+	if err := r.server.Redis.Set(ctx, recommendation.CacheKey(userID), raw, recommendation.CacheTTL).Err(); err != nil {
+		return fmt.Errorf("failed to cache recommendations: %w", err)
+	}
+	return nil
+}
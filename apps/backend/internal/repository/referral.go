@@ -0,0 +1,653 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/loyalty"
+	"github.com/gitSanje/khajaride/internal/model/referral"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrReferralCodeNotFound = errors.New("referral code not found")
+
+// referral and coupon codes avoid characters that are easy to misread (0/O, 1/I)
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const referralCodeLength = 8
+
+// ---------------- REFERRAL REPOSITORY ----------------
+
+type ReferralRepository struct {
+	server *server.Server
+}
+
+func NewReferralRepository(s *server.Server) *ReferralRepository {
+	return &ReferralRepository{server: s}
+}
+
+//-- ==================================================
+//-- SETTINGS
+//-- ==================================================
+
+const referralSettingsColumns = `is_active, reward_type, referrer_points, referee_points, coupon_value,
+	coupon_min_order_amount, coupon_valid_days, max_rewards_per_referrer, updated_at`
+
+func (r *ReferralRepository) GetSettings(ctx context.Context) (*referral.Settings, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT `+referralSettingsColumns+` FROM referral_settings`)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Settings])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *ReferralRepository) UpdateSettings(ctx context.Context, payload *referral.UpdateSettingsPayload) (*referral.Settings, error) {
+	query := `
+		UPDATE referral_settings
+		SET is_active = COALESCE(@isActive, is_active),
+		    reward_type = COALESCE(@rewardType, reward_type),
+		    referrer_points = COALESCE(@referrerPoints, referrer_points),
+		    referee_points = COALESCE(@refereePoints, referee_points),
+		    coupon_value = COALESCE(@couponValue, coupon_value),
+		    coupon_min_order_amount = COALESCE(@couponMinOrderAmount, coupon_min_order_amount),
+		    coupon_valid_days = COALESCE(@couponValidDays, coupon_valid_days),
+		    max_rewards_per_referrer = COALESCE(@maxRewardsPerReferrer, max_rewards_per_referrer)
+		RETURNING ` + referralSettingsColumns
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{
+		"isActive":              payload.IsActive,
+		"rewardType":            payload.RewardType,
+		"referrerPoints":        payload.ReferrerPoints,
+		"refereePoints":         payload.RefereePoints,
+		"couponValue":           payload.CouponValue,
+		"couponMinOrderAmount":  payload.CouponMinOrderAmount,
+		"couponValidDays":       payload.CouponValidDays,
+		"maxRewardsPerReferrer": payload.MaxRewardsPerReferrer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update referral settings: %w", err)
+	}
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Settings])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referral settings: %w", err)
+	}
+	return &settings, nil
+}
+
+//-- ==================================================
+//-- CODES
+//-- ==================================================
+
+// GetOrCreateCode returns the user's referral code, generating one the first
+// time it is asked for.
+func (r *ReferralRepository) GetOrCreateCode(ctx context.Context, userID string) (*referral.Code, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := randomReferralCode(referralCodeLength)
+		if err != nil {
+			return nil, err
+		}
+
+		// ON CONFLICT covers both an existing code for the user and a
+		// clash with someone else's code
+		rows, err := r.server.DB.Pool.Query(ctx, `
+			WITH inserted AS (
+				INSERT INTO referral_codes (user_id, code)
+				VALUES ($1, $2)
+				ON CONFLICT DO NOTHING
+				RETURNING *
+			)
+			SELECT * FROM inserted
+			UNION ALL
+			SELECT * FROM referral_codes WHERE user_id = $1
+			LIMIT 1
+		`, userID, code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create referral code: %w", err)
+		}
+		c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Code])
+		if err == nil {
+			return &c, nil
+		}
+		if err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("failed to collect referral code: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to generate a unique referral code for user %s", userID)
+}
+
+//-- ==================================================
+//-- ATTRIBUTION
+//-- ==================================================
+
+// AttributeSignup records that refereeID signed up with someone's referral
+// code. Suspicious sign-ups are still recorded, as rejected with the reason,
+// so they show up in the stats; they never earn a reward. A user is only
+// ever attributed once, a second call returns the existing referral.
+func (r *ReferralRepository) AttributeSignup(ctx context.Context, refereeID string, attr *referral.SignupAttribution) (*referral.Referral, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	code := strings.ToUpper(strings.TrimSpace(attr.Code))
+
+	var (
+		referrerID, referrerEmail, refereeEmail string
+		referrerPhone                           *string
+		isActive                                bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT rc.user_id, u.email, u.phone_number, s.is_active
+		FROM referral_codes rc
+		JOIN users u ON u.id = rc.user_id
+		CROSS JOIN referral_settings s
+		WHERE rc.code = $1
+	`, code).Scan(&referrerID, &referrerEmail, &referrerPhone, &isActive)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrReferralCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to look up referral code: %w", err)
+	}
+	// the referrals table cannot hold a user referring themselves
+	if referrerID == refereeID {
+		return nil, fmt.Errorf("user %s cannot refer themselves", refereeID)
+	}
+	if err := tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, refereeID).Scan(&refereeEmail); err != nil {
+		return nil, err
+	}
+
+	// 1️⃣ Fraud checks
+	var reason *string
+	reject := func(r string) {
+		if reason == nil {
+			reason = &r
+		}
+	}
+
+	if !isActive {
+		reject(referral.RejectInactive)
+	}
+	if normalizeEmail(referrerEmail) == normalizeEmail(refereeEmail) {
+		reject(referral.RejectSelfReferral)
+	}
+	if attr.Phone != nil && *attr.Phone != "" {
+		if referrerPhone != nil && *referrerPhone == *attr.Phone {
+			reject(referral.RejectSamePhone)
+		}
+		var used bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM referrals WHERE signup_phone = $1)
+			    OR EXISTS (SELECT 1 FROM users WHERE phone_number = $1 AND id <> $2)
+		`, *attr.Phone, refereeID).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check referral phone: %w", err)
+		}
+		if used {
+			reject(referral.RejectSamePhone)
+		}
+	}
+	if attr.DeviceID != nil && *attr.DeviceID != "" {
+		var used bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM referrals WHERE signup_device_id = $1)`, *attr.DeviceID).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check referral device: %w", err)
+		}
+		if used {
+			reject(referral.RejectSameDevice)
+		}
+	}
+
+	// 2️⃣ Record the referral
+	status := referral.StatusPending
+	if reason != nil {
+		status = referral.StatusRejected
+	}
+	rows, err := tx.Query(ctx, `
+		WITH inserted AS (
+			INSERT INTO referrals (referrer_id, referee_id, code, status, rejection_reason, signup_phone, signup_device_id)
+			VALUES (@referrerId, @refereeId, @code, @status, @reason, @phone, @deviceId)
+			ON CONFLICT (referee_id) DO NOTHING
+			RETURNING *
+		)
+		SELECT * FROM inserted
+		UNION ALL
+		SELECT * FROM referrals WHERE referee_id = @refereeId
+		LIMIT 1
+	`, pgx.NamedArgs{
+		"referrerId": referrerID,
+		"refereeId":  refereeID,
+		"code":       code,
+		"status":     status,
+		"reason":     reason,
+		"phone":      attr.Phone,
+		"deviceId":   attr.DeviceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record referral: %w", err)
+	}
+	ref, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Referral])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referral: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+//-- ==================================================
+//-- REWARDS
+//-- ==================================================
+
+// RewardFirstDelivery issues the referral reward once the referee's order is
+// delivered and paid. It returns nil when there is nothing to reward: the
+// user was not referred, the referral is no longer pending, the order does
+// not qualify yet or the program is switched off. The phone check is
+// repeated because a phone number can be added after sign-up.
+func (r *ReferralRepository) RewardFirstDelivery(ctx context.Context, refereeID, orderID string) (*referral.Referral, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT * FROM referrals WHERE referee_id = $1 FOR UPDATE`, refereeID)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Referral])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect referral: %w", err)
+	}
+	if ref.Status != referral.StatusPending {
+		return nil, nil
+	}
+
+	var qualifies bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM order_vendors
+			WHERE id = $1 AND user_id = $2 AND status = 'delivered' AND payment_status = 'paid'
+		)
+	`, orderID, refereeID).Scan(&qualifies)
+	if err != nil {
+		return nil, err
+	}
+	if !qualifies {
+		return nil, nil
+	}
+
+	settings, err := r.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.IsActive {
+		return nil, nil
+	}
+
+	// 1️⃣ Fraud & limit checks
+	var samePhone bool
+	var refereeUsername string
+	err = tx.QueryRow(ctx, `
+		SELECT ee.username,
+		       ee.phone_number IS NOT NULL AND ee.phone_number <> '' AND ee.phone_number = er.phone_number
+		FROM users ee, users er
+		WHERE ee.id = $1 AND er.id = $2
+	`, ref.RefereeID, ref.ReferrerID).Scan(&refereeUsername, &samePhone)
+	if err != nil {
+		return nil, err
+	}
+	var rewarded int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND status = 'rewarded'`, ref.ReferrerID).Scan(&rewarded)
+	if err != nil {
+		return nil, err
+	}
+
+	if samePhone || rewarded >= settings.MaxRewardsPerReferrer {
+		reason := referral.RejectSamePhone
+		if !samePhone {
+			reason = referral.RejectLimitReached
+		}
+		if err := rejectReferral(ctx, tx, &ref, reason); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return &ref, nil
+	}
+
+	// 2️⃣ Reward the referrer
+	rewardType := settings.RewardType
+	var couponID *string
+	switch rewardType {
+	case referral.RewardCoupon:
+		id, err := issueReferralCoupon(ctx, tx, ref.ReferrerID, settings)
+		if err != nil {
+			return nil, err
+		}
+		couponID = &id
+	default:
+		if settings.ReferrerPoints > 0 {
+			reason := fmt.Sprintf("Referral reward: %s", refereeUsername)
+			if err := creditReferralPoints(ctx, tx, ref.ReferrerID, settings.ReferrerPoints, reason, referral.ReferenceTypeReferral, ref.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 3️⃣ Welcome bonus for the referee
+	if settings.RefereePoints > 0 {
+		if err := creditReferralPoints(ctx, tx, ref.RefereeID, settings.RefereePoints, "Referral welcome bonus", referral.ReferenceTypeReferralWelcome, ref.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err = tx.Query(ctx, `
+		UPDATE referrals
+		SET status = 'rewarded',
+		    qualifying_order_id = @orderId,
+		    reward_type = @rewardType,
+		    coupon_id = @couponId,
+		    rewarded_at = NOW()
+		WHERE id = @id
+		RETURNING *
+	`, pgx.NamedArgs{
+		"id":         ref.ID,
+		"orderId":    orderID,
+		"rewardType": rewardType,
+		"couponId":   couponID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark referral rewarded: %w", err)
+	}
+	ref, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Referral])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referral: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// ListPendingRewards returns pending referrals whose referee already has a
+// delivered, paid order, with the earliest such order.
+func (r *ReferralRepository) ListPendingRewards(ctx context.Context, limit int) ([]referral.PendingReward, error) {
+	query := `
+		SELECT rf.referee_id, first_order.id AS order_id
+		FROM referrals rf
+		CROSS JOIN LATERAL (
+			SELECT ov.id
+			FROM order_vendors ov
+			WHERE ov.user_id = rf.referee_id
+			  AND ov.status = 'delivered'
+			  AND ov.payment_status = 'paid'
+			ORDER BY COALESCE(ov.delivered_at, ov.updated_at)
+			LIMIT 1
+		) first_order
+		WHERE rf.status = 'pending'
+		ORDER BY rf.created_at
+		LIMIT @limit
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"limit": limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending referral rewards: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[referral.PendingReward])
+}
+
+func rejectReferral(ctx context.Context, tx pgx.Tx, ref *referral.Referral, reason string) error {
+	_, err := tx.Exec(ctx, `UPDATE referrals SET status = 'rejected', rejection_reason = $2 WHERE id = $1`, ref.ID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to reject referral: %w", err)
+	}
+	ref.Status = referral.StatusRejected
+	ref.RejectionReason = &reason
+	return nil
+}
+
+func creditReferralPoints(ctx context.Context, tx pgx.Tx, userID string, points float64, reason, referenceType, referralID string) error {
+	if _, err := lockLoyaltyBalance(ctx, tx, userID); err != nil {
+		return err
+	}
+	_, err := insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: loyalty.TransactionEarn,
+		PointsChange:    points,
+		Reason:          &reason,
+		ReferenceID:     &referralID,
+		ReferenceType:   &referenceType,
+		PerformedBy:     userID,
+	})
+	return err
+}
+
+// issueReferralCoupon creates a flat, single-use coupon that only the
+// referrer can apply.
+func issueReferralCoupon(ctx context.Context, tx pgx.Tx, userID string, settings *referral.Settings) (string, error) {
+	code, err := randomReferralCode(referralCodeLength)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO coupons (
+			code, description, discount_type, discount_value, min_order_amount,
+			usage_limit, per_user_limit, start_date, end_date, user_id
+		) VALUES (
+			@code, @description, 'flat', @value, @minOrder,
+			1, 1, NOW(), NOW() + @validDays * INTERVAL '1 day', @userId
+		)
+		RETURNING id
+	`, pgx.NamedArgs{
+		"code":        "REF-" + code,
+		"description": fmt.Sprintf("Referral reward: NPR %.0f off", settings.CouponValue),
+		"value":       settings.CouponValue,
+		"minOrder":    settings.CouponMinOrderAmount,
+		"validDays":   settings.CouponValidDays,
+		"userId":      userID,
+	}).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to issue referral coupon: %w", err)
+	}
+	return id, nil
+}
+
+//-- ==================================================
+//-- STATS
+//-- ==================================================
+
+const referralStatsColumns = `
+	COUNT(*)::INT AS invited,
+	COUNT(*) FILTER (WHERE rf.status = 'pending')::INT AS pending,
+	COUNT(*) FILTER (WHERE rf.status = 'rewarded')::INT AS rewarded,
+	COUNT(*) FILTER (WHERE rf.status = 'rejected')::INT AS rejected,
+	COUNT(*) FILTER (WHERE rf.status = 'rewarded' AND rf.reward_type = 'coupon')::INT AS coupons_issued`
+
+func (r *ReferralRepository) GetUserStats(ctx context.Context, userID string) (*referral.Stats, error) {
+	query := `
+		SELECT ` + referralStatsColumns + `,
+		       COALESCE((
+				SELECT SUM(points_change) FROM loyalty_points_ledger
+				WHERE user_id = @userId AND transaction_type = 'EARN' AND reference_type = 'REFERRAL'
+		       ), 0) AS points_earned
+		FROM referrals rf
+		WHERE rf.referrer_id = @userId
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"userId": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral stats: %w", err)
+	}
+	stats, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Stats])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referral stats: %w", err)
+	}
+	return &stats, nil
+}
+
+func (r *ReferralRepository) ListReferredUsers(ctx context.Context, userID string) ([]referral.ReferredUser, error) {
+	query := `
+		SELECT u.username AS referee_username, rf.status, rf.reward_type, rf.created_at, rf.rewarded_at
+		FROM referrals rf
+		JOIN users u ON u.id = rf.referee_id
+		WHERE rf.referrer_id = $1
+		ORDER BY rf.created_at DESC
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referred users: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[referral.ReferredUser])
+}
+
+func (r *ReferralRepository) GetProgramStats(ctx context.Context) (*referral.ProgramStats, error) {
+	query := `
+		SELECT ` + referralStatsColumns + `,
+		       COALESCE((
+				SELECT SUM(points_change) FROM loyalty_points_ledger
+				WHERE transaction_type = 'EARN' AND reference_type IN ('REFERRAL', 'REFERRAL_WELCOME')
+		       ), 0) AS points_earned
+		FROM referrals rf
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral program stats: %w", err)
+	}
+	stats, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[referral.Stats])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referral program stats: %w", err)
+	}
+	res := &referral.ProgramStats{
+		Stats:            stats,
+		RejectedByReason: map[string]int{},
+	}
+
+	reasonRows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT rejection_reason, COUNT(*)::INT
+		FROM referrals
+		WHERE status = 'rejected' AND rejection_reason IS NOT NULL
+		GROUP BY rejection_reason
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rejected referrals: %w", err)
+	}
+	defer reasonRows.Close()
+	for reasonRows.Next() {
+		var reason string
+		var count int
+		if err := reasonRows.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		res.RejectedByReason[reason] = count
+	}
+	if err := reasonRows.Err(); err != nil {
+		return nil, err
+	}
+
+	topRows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT u.id AS user_id, u.username,
+		       COUNT(*)::INT AS invited,
+		       COUNT(*) FILTER (WHERE rf.status = 'rewarded')::INT AS rewarded
+		FROM referrals rf
+		JOIN users u ON u.id = rf.referrer_id
+		GROUP BY u.id, u.username
+		ORDER BY rewarded DESC, invited DESC
+		LIMIT 10
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top referrers: %w", err)
+	}
+	res.TopReferrers, err = pgx.CollectRows(topRows, pgx.RowToStructByName[referral.TopReferrer])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect top referrers: %w", err)
+	}
+	return res, nil
+}
+
+func (r *ReferralRepository) GetReferrals(ctx context.Context, query *referral.GetReferralsQuery) (*model.PaginatedResponse[referral.Referral], error) {
+	args := pgx.NamedArgs{
+		"limit":  *query.Limit,
+		"offset": (*query.Page - 1) * (*query.Limit),
+	}
+	where := ""
+	if query.Status != nil {
+		where = " WHERE status = @status"
+		args["status"] = *query.Status
+	}
+
+	var total int
+	if err := r.server.DB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM referrals`+where, args).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count referrals: %w", err)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM referrals`+where+` ORDER BY created_at DESC LIMIT @limit OFFSET @offset`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	referrals, err := pgx.CollectRows(rows, pgx.RowToStructByName[referral.Referral])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect referrals: %w", err)
+	}
+
+	return &model.PaginatedResponse[referral.Referral]{
+		Data:       referrals,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+//-- ==================================================
+//-- HELPERS
+//-- ==================================================
+
+func randomReferralCode(n int) (string, error) {
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate referral code: %w", err)
+		}
+		b[i] = referralCodeAlphabet[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// normalizeEmail folds the aliases one mailbox can sign up with
+// ("+tag" suffixes, and dots for Gmail) so a second account of the same
+// person is caught as a self-referral.
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	if i := strings.Index(local, "+"); i >= 0 {
+		local = local[:i]
+	}
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
@@ -9,15 +9,47 @@ type Repositories struct{
 	Cart    *CartRepository
 	Order   *OrderRepository
 	Payment *PaymentRepository
+	Settlement *SettlementRepository
+	Ledger     *LedgerRepository
+	Invoice    *InvoiceRepository
+	Loyalty    *LoyaltyRepository
+	Referral   *ReferralRepository
+	Recommendation *RecommendationRepository
+	Notification   *NotificationRepository
+	Email          *EmailRepository
+	VendorVerification *VendorVerificationRepository
+	Analytics          *AnalyticsRepository
+	VendorMember       *VendorMemberRepository
+	Brand              *BrandRepository
+	Stock              *StockRepository
+	MenuImport         *MenuImportRepository
+	Image              *ImageRepository
 }
 
 func NewRepositories(s *server.Server) *Repositories {
+	ledgerRepo := NewLedgerRepository(s)
+
 	return &Repositories{
 		User: NewUserRepository(s),
 		Vendor: NewVendorRepository(s),
 		Search: NewSearchRepository(s),
 		Cart:   NewCartRepository(s),
-		Order:  NewOrderRepository(s),
-		Payment: NewPaymentRepository(s),
+		Order:  NewOrderRepository(s, ledgerRepo),
+		Payment: NewPaymentRepository(s, ledgerRepo),
+		Settlement: NewSettlementRepository(s, ledgerRepo),
+		Ledger:     ledgerRepo,
+		Invoice:    NewInvoiceRepository(s),
+		Loyalty:    NewLoyaltyRepository(s),
+		Referral:   NewReferralRepository(s),
+		Recommendation: NewRecommendationRepository(s),
+		Notification:   NewNotificationRepository(s),
+		Email:          NewEmailRepository(s),
+		VendorVerification: NewVendorVerificationRepository(s),
+		Analytics:          NewAnalyticsRepository(s),
+		VendorMember:       NewVendorMemberRepository(s),
+		Brand:              NewBrandRepository(s),
+		Stock:              NewStockRepository(s),
+		MenuImport:         NewMenuImportRepository(s),
+		Image:              NewImageRepository(s),
 	}
 }
@@ -10,7 +10,9 @@ import (
 	"sync"
 
 	"github.com/gitSanje/khajaride/internal/model/search"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
 )
 
 // ---------------- Vendor Repository ----------------
@@ -148,6 +150,10 @@ func (r *SearchRepository) FullTextSearch(ctx context.Context, payload *search.S
 	// Join filters
 	filterClause := strings.Join(filters, ",")
 
+	// Only active vendors are searchable. Documents indexed before statuses
+	// were pushed to the index have none, so the other statuses are excluded
+	// instead of requiring "active".
+
 	// --------------------- Build Base Query ---------------------
 	query := fmt.Sprintf(`
 	{
@@ -172,6 +178,9 @@ func (r *SearchRepository) FullTextSearch(ctx context.Context, payload *search.S
 	        }
 	      ],
 	      "filter": [%s],
+	      "must_not": [
+	        { "terms": { "vendor.status": ["draft", "pending_verification", "verified", "suspended", "rejected"] } }
+	      ],
 	      "should": [
 	        { "term": { "is_popular": true } },
 	        { "range": { "vendor.rating": { "gte": 4.2, "boost": 2 } } }
@@ -236,9 +245,19 @@ func (r *SearchRepository) FullTextSearch(ctx context.Context, payload *search.S
 	hitsData := raw["hits"].(map[string]interface{})
 	hitsArray := hitsData["hits"].([]interface{})
 	results := make([]map[string]interface{}, 0, len(hitsArray))
+	// Outlets of a chain each have a document per master menu item, sharing a
+	// chain_key. They score alike, so the geo sort puts the nearest outlet
+	// first and the others are dropped.
+	seenChains := map[string]bool{}
 	for _, h := range hitsArray {
 		hit := h.(map[string]interface{})
 		source := hit["_source"].(map[string]interface{})
+		if key, ok := source["chain_key"].(string); ok && key != "" {
+			if seenChains[key] {
+				continue
+			}
+			seenChains[key] = true
+		}
 		sortVal := hit["sort"]
 		source["sort"] = sortVal
 		results = append(results, source)
@@ -267,3 +286,219 @@ func (r *SearchRepository) FullTextSearch(ctx context.Context, payload *search.S
 	return response, nil
 
 }
+
+// UpdateVendorFavoriteCount sets the favorite count on every document of the
+// vendor in the denormalized vendor_menu index.
+func (r *SearchRepository) UpdateVendorFavoriteCount(ctx context.Context, vendorID string, count int) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"term": map[string]interface{}{"vendor.id": vendorID}},
+		"ctx._source.vendor.favorite_count = params.count",
+		map[string]interface{}{"count": count},
+	)
+}
+
+// UpdateMenuItemFavoriteCount sets the favorite count on a menu item's
+// document in the vendor_menu index.
+func (r *SearchRepository) UpdateMenuItemFavoriteCount(ctx context.Context, menuItemID string, count int) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"term": map[string]interface{}{"menu_id": menuItemID}},
+		"ctx._source.favorite_count = params.count",
+		map[string]interface{}{"count": count},
+	)
+}
+
+// UpdateVendorStatus sets the vendor's status on every document of the
+// vendor in the vendor_menu index; search hides vendors that are not active.
+func (r *SearchRepository) UpdateVendorStatus(ctx context.Context, vendorID string, status string) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"term": map[string]interface{}{"vendor.id": vendorID}},
+		"ctx._source.vendor.status = params.status",
+		map[string]interface{}{"status": status},
+	)
+}
+
+// UpdateOutletMenuItem sets the price and availability a vendor sells a
+// menu item at, after its outlet overrides, on that vendor's document.
+func (r *SearchRepository) UpdateOutletMenuItem(ctx context.Context, vendorID, menuItemID string, price float64, available bool) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+			map[string]interface{}{"term": map[string]interface{}{"vendor.id": vendorID}},
+			map[string]interface{}{"term": map[string]interface{}{"menu_id": menuItemID}},
+		}}},
+		"ctx._source.base_price = params.price; ctx._source.is_available = params.available",
+		map[string]interface{}{"price": price, "available": available},
+	)
+}
+
+// IndexOutletMenuItems writes the vendor_menu documents of items the vendor
+// sells, e.g. items just created by a menu import; writing one again
+// replaces it.
+func (r *SearchRepository) IndexOutletMenuItems(ctx context.Context, vendorID string, menuItemIDs []string) error {
+	if r.server.Elasticsearch == nil || len(menuItemIDs) == 0 {
+		return nil
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT mi.vendor_id || ':' || mi.id, jsonb_build_object(
+			'menu_id', mi.id,
+			'menu_name', mi.name,
+			'menu_description', mi.description,
+			'tags', mi.tags,
+			'keywords', mi.keywords,
+			'base_price', mi.base_price,
+			'is_available', COALESCE(mi.is_available, FALSE),
+			'is_popular', mi.is_popular,
+			'is_vegetarian', mi.is_vegetarian,
+			'is_vegan', mi.is_vegan,
+			'is_gluten_free', mi.is_gluten_free,
+			'spicy_level', mi.spicy_level,
+			'portion_size', mi.portion_size,
+			'favorite_count', COALESCE(st.favorite_count, 0),
+			'category', jsonb_build_object('id', mc.id, 'name', mc.name),
+			'vendor', jsonb_build_object(
+				'id', v.id,
+				'brand_id', v.brand_id,
+				'name', COALESCE(b.name, v.name),
+				'outlet_name', v.outlet_name,
+				'about', v.about,
+				'cuisine', v.cuisine,
+				'cuisine_tags', v.cuisine_tags,
+				'vendor_type', v.vendor_type,
+				'status', v.status,
+				'rating', v.rating,
+				'favorite_count', v.favorite_count,
+				'is_open', v.is_open,
+				'is_featured', v.is_featured,
+				'delivery_available', v.delivery_available,
+				'pickup_available', v.pickup_available,
+				'delivery_fee', v.delivery_fee,
+				'min_order_amount', v.min_order_amount,
+				'delivery_radius_km', v.delivery_radius_km,
+				'promo_text', v.promo_text,
+				'vendor_notice', v.vendor_notice,
+				'location', CASE WHEN va.latitude IS NOT NULL AND va.longitude IS NOT NULL
+					THEN jsonb_build_object('lat', va.latitude, 'lon', va.longitude) END,
+				'street_address', va.street_address,
+				'city', va.city,
+				'state', va.state,
+				'zip_code', va.zipcode,
+				'opening_hours', v.opening_hours,
+				'vendor_listing_image_name', v.vendor_listing_image_name,
+				'vendor_logo_image_name', v.vendor_logo_image_name
+			)
+		)
+		FROM vendor_menu_items mi
+		JOIN vendors v ON v.id = mi.vendor_id
+		LEFT JOIN brands b ON b.id = v.brand_id
+		LEFT JOIN menu_categories mc ON mc.id = mi.category_id
+		LEFT JOIN menu_item_stats st ON st.menu_item_id = mi.id
+		LEFT JOIN LATERAL (
+			SELECT * FROM vendor_addresses WHERE vendor_id = v.id ORDER BY created_at LIMIT 1
+		) va ON TRUE
+		WHERE mi.vendor_id = $1 AND mi.id = ANY($2)
+	`, vendorID, menuItemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load menu item documents: %w", err)
+	}
+
+	var buf bytes.Buffer
+	var id string
+	var doc json.RawMessage
+	_, err = pgx.ForEachRow(rows, []any{&id, &doc}, func() error {
+		meta, err := json.Marshal(map[string]map[string]string{
+			"index": {"_index": "vendor_menu", "_id": id},
+		})
+		if err != nil {
+			return err
+		}
+		buf.Write(meta)
+		buf.WriteByte('\n')
+		buf.Write(doc)
+		buf.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build menu item documents: %w", err)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	res, err := r.server.Elasticsearch.Bulk(bytes.NewReader(buf.Bytes()), r.server.Elasticsearch.Bulk.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("bulk index failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk index returned error: %s", res.String())
+	}
+	return nil
+}
+
+// UpdateOutletListing sets the outlet's listing details on every document of
+// the vendor in the vendor_menu index.
+func (r *SearchRepository) UpdateOutletListing(ctx context.Context, v *vendor.Vendor) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"term": map[string]interface{}{"vendor.id": v.ID}},
+		`ctx._source.vendor.outlet_name = params.outlet_name;
+		ctx._source.vendor.is_open = params.is_open;
+		ctx._source.vendor.opening_hours = params.opening_hours;
+		ctx._source.vendor.delivery_radius_km = params.delivery_radius_km;
+		ctx._source.vendor.delivery_fee = params.delivery_fee;
+		ctx._source.vendor.min_order_amount = params.min_order_amount`,
+		map[string]interface{}{
+			"outlet_name":        v.OutletName,
+			"is_open":            v.IsOpen,
+			"opening_hours":      v.OpeningHours,
+			"delivery_radius_km": v.DeliveryRadiusKm,
+			"delivery_fee":       v.DeliveryFee,
+			"min_order_amount":   v.MinOrderAmount,
+		},
+	)
+}
+
+// UpdateBrandName sets the vendor name on every document of the brand's
+// outlets, which are listed under the brand name.
+func (r *SearchRepository) UpdateBrandName(ctx context.Context, brandID, name string) error {
+	return r.updateByQuery(ctx, "vendor_menu",
+		map[string]interface{}{"term": map[string]interface{}{"vendor.brand_id": brandID}},
+		"ctx._source.vendor.name = params.name",
+		map[string]interface{}{"name": name},
+	)
+}
+
+func (r *SearchRepository) updateByQuery(ctx context.Context, indexName string, query map[string]interface{}, script string, params map[string]interface{}) error {
+	if r.server.Elasticsearch == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"script": map[string]interface{}{
+			"source": script,
+			"lang":   "painless",
+			"params": params,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	es := r.server.Elasticsearch
+	res, err := es.UpdateByQuery(
+		[]string{indexName},
+		es.UpdateByQuery.WithContext(ctx),
+		es.UpdateByQuery.WithBody(bytes.NewReader(body)),
+		es.UpdateByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		return fmt.Errorf("update by query failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("update by query returned error: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,625 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/settlement"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/stripe/stripe-go/v83"
+	"github.com/stripe/stripe-go/v83/transfer"
+)
+
+// maxSettlementAttempts is how many failed payouts a settlement may have before it is left for an admin.
+const maxSettlementAttempts = 5
+
+// ---------------- SETTLEMENT REPOSITORY ----------------
+
+type SettlementRepository struct {
+	server *server.Server
+	ledger *LedgerRepository
+}
+
+func NewSettlementRepository(s *server.Server, ledger *LedgerRepository) *SettlementRepository {
+	return &SettlementRepository{server: s, ledger: ledger}
+}
+
+//-- ==================================================
+//-- ACCRUALS
+//-- ==================================================
+
+func (r *SettlementRepository) AccrueOrderEarning(ctx context.Context, orderID string) error {
+	if _, err := r.server.DB.Pool.Exec(ctx, `SELECT accrue_vendor_earning($1)`, orderID); err != nil {
+		return fmt.Errorf("failed to accrue vendor earning: %w", err)
+	}
+	return nil
+}
+
+// accrueRefund takes a refund out of the vendor's next settlement and posts
+// it to the ledger in the caller's transaction, so it only happens together
+// with the refund it records.
+func accrueRefund(ctx context.Context, tx pgx.Tx, ledger *LedgerRepository, orderVendorID string, amount float64) error {
+	if _, err := tx.Exec(ctx, `SELECT accrue_vendor_refund($1, $2)`, orderVendorID, amount); err != nil {
+		return fmt.Errorf("failed to accrue vendor refund: %w", err)
+	}
+	if err := ledger.RecordRefund(ctx, tx, orderVendorID); err != nil {
+		return fmt.Errorf("failed to post refund to ledger: %w", err)
+	}
+	return nil
+}
+
+func (r *SettlementRepository) CreateAdjustment(ctx context.Context, payload *settlement.CreateAdjustmentPayload, createdBy string) (*settlement.VendorEarning, error) {
+	query := `
+		INSERT INTO vendor_earnings (
+			vendor_id, vendor_user_id, payout_account_id, entry_type, gross_amount, description, created_by
+		)
+		SELECT
+			v.id,
+			v.vendor_user_id,
+			COALESCE(v.payout_account_id, (
+				SELECT pa.id FROM payout_accounts pa
+				WHERE pa.owner_id = v.vendor_user_id AND pa.owner_type = 'vendor'
+				ORDER BY pa.is_default DESC, pa.created_at
+				LIMIT 1
+			)),
+			'adjustment',
+			@amount,
+			@description,
+			@createdBy
+		FROM vendors v
+		WHERE v.id = @vendorId
+		RETURNING *
+	`
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{
+		"vendorId":    payload.VendorID,
+		"amount":      payload.Amount,
+		"description": payload.Description,
+		"createdBy":   createdBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create adjustment: %w", err)
+	}
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.VendorEarning])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect adjustment: %w", err)
+	}
+	if err := r.ledger.RecordAdjustment(ctx, tx, &entry); err != nil {
+		return nil, fmt.Errorf("failed to post adjustment to ledger: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+//-- ==================================================
+//-- SETTLEMENT BATCHES
+//-- ==================================================
+
+// SettlementPeriodEnd returns the cut-off for a batch: everything accrued
+// before the start of today (daily) or of this week's Monday (weekly).
+func SettlementPeriodEnd(frequency string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if frequency == "weekly" {
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// CreateSettlementBatches groups every unsettled earning accrued before
+// periodEnd into one settlement per payout account. Accounts whose net is not
+// positive (e.g. refunds exceed earnings) are carried forward to the next run.
+func (r *SettlementRepository) CreateSettlementBatches(ctx context.Context, frequency string, periodEnd time.Time) ([]settlement.VendorSettlement, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Earnings accrued before the vendor finished payout onboarding
+	backfill := `
+		UPDATE vendor_earnings e
+		SET payout_account_id = COALESCE(
+			(SELECT v.payout_account_id FROM vendors v WHERE v.id = e.vendor_id),
+			(
+				SELECT pa.id FROM payout_accounts pa
+				WHERE pa.owner_id = e.vendor_user_id AND pa.owner_type = 'vendor'
+				ORDER BY pa.is_default DESC, pa.created_at
+				LIMIT 1
+			)
+		)
+		WHERE e.payout_account_id IS NULL AND e.settlement_id IS NULL
+	`
+	if _, err := tx.Exec(ctx, backfill); err != nil {
+		return nil, fmt.Errorf("failed to backfill payout accounts: %w", err)
+	}
+
+	insert := `
+		INSERT INTO vendor_settlements (
+			payout_account_id, vendor_user_id, frequency, period_start, period_end,
+			gross_amount, commission_amount, refund_amount, adjustment_amount, net_amount, currency
+		)
+		SELECT
+			e.payout_account_id,
+			MIN(e.vendor_user_id),
+			@frequency,
+			MIN(e.created_at),
+			@periodEnd,
+			COALESCE(SUM(e.gross_amount) FILTER (WHERE e.entry_type = 'order'), 0),
+			COALESCE(SUM(e.commission), 0),
+			COALESCE(-SUM(e.gross_amount) FILTER (WHERE e.entry_type = 'refund'), 0),
+			COALESCE(SUM(e.gross_amount) FILTER (WHERE e.entry_type = 'adjustment'), 0),
+			SUM(e.net_amount),
+			COALESCE(pa.currency, 'USD')
+		FROM vendor_earnings e
+		JOIN payout_accounts pa ON pa.id = e.payout_account_id
+		WHERE e.settlement_id IS NULL
+		  AND e.created_at < @periodEnd
+		  AND pa.settlement_frequency = @frequency
+		GROUP BY e.payout_account_id, pa.currency
+		HAVING SUM(e.net_amount) > 0
+		RETURNING *
+	`
+	rows, err := tx.Query(ctx, insert, pgx.NamedArgs{"frequency": frequency, "periodEnd": periodEnd})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create settlements: %w", err)
+	}
+	settlements, err := pgx.CollectRows(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect settlements: %w", err)
+	}
+
+	attach := `
+		UPDATE vendor_earnings
+		SET settlement_id = @settlementId
+		WHERE settlement_id IS NULL
+		  AND payout_account_id = @payoutAccountId
+		  AND created_at < @periodEnd
+	`
+	for _, s := range settlements {
+		_, err := tx.Exec(ctx, attach, pgx.NamedArgs{
+			"settlementId":    s.ID,
+			"payoutAccountId": s.PayoutAccountID,
+			"periodEnd":       periodEnd,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach earnings to settlement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return settlements, nil
+}
+
+// dueSettlementCondition matches settlements that still need a payout: new
+// ones, failed ones whose retry time has come, and Stripe payouts whose run
+// stopped between the claim and the transfer (the idempotency key makes
+// sending them again safe).
+const dueSettlementCondition = `
+	status = 'pending'
+	OR (status = 'failed' AND attempts < @maxAttempts AND COALESCE(next_retry_at, NOW()) <= NOW())
+	OR (status = 'processing' AND transaction_ref IS NULL AND updated_at < NOW() - INTERVAL '1 hour'
+	    AND EXISTS (
+	        SELECT 1 FROM payout_accounts pa
+	        WHERE pa.id = vendor_settlements.payout_account_id AND pa.method = 'stripe'
+	    ))
+`
+
+// ListDueSettlements returns settlements that still need a payout.
+func (r *SettlementRepository) ListDueSettlements(ctx context.Context) ([]settlement.VendorSettlement, error) {
+	query := `
+		SELECT * FROM vendor_settlements
+		WHERE ` + dueSettlementCondition + `
+		ORDER BY created_at
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"maxAttempts": maxSettlementAttempts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due settlements: %w", err)
+	}
+	settlements, err := pgx.CollectRows(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect due settlements: %w", err)
+	}
+	return settlements, nil
+}
+
+// claimSettlement moves a due settlement to processing and works out what is
+// left to pay. Only one caller wins the claim, so the scheduled run and an
+// admin run never pay the same settlement twice; the loser gets nil.
+//
+// Earnings that already reached the vendor's connected Stripe account are
+// left out: group splits with a transfer and single Stripe orders, which
+// are destination charges. A refund of either reverses the transfer, so it
+// is left out too.
+func (r *SettlementRepository) claimSettlement(ctx context.Context, id string) (*settlement.VendorSettlement, error) {
+	query := `
+		UPDATE vendor_settlements
+		SET status = 'processing',
+		    last_error = NULL,
+		    next_retry_at = NULL,
+		    payout_amount = (
+		        SELECT COALESCE(SUM(e.net_amount), 0)
+		        FROM vendor_earnings e
+		        WHERE e.settlement_id = vendor_settlements.id
+		          AND NOT EXISTS (
+		              SELECT 1 FROM order_vendor_splits sp
+		              WHERE sp.order_vendor_id = e.order_id AND sp.transfer_ref IS NOT NULL
+		          )
+		          AND NOT EXISTS (
+		              SELECT 1 FROM order_payments op
+		              WHERE op.order_id = e.order_id
+		                AND op.payment_gateway = 'stripe'
+		                AND op.status IN ('success', 'refunded')
+		          )
+		    )
+		WHERE id = @id AND (` + dueSettlementCondition + `)
+		RETURNING *
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, query, pgx.NamedArgs{"id": id, "maxAttempts": maxSettlementAttempts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim settlement: %w", err)
+	}
+	s, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim settlement: %w", err)
+	}
+	return &s, nil
+}
+
+// ExecuteSettlementPayout claims one settlement and sends what is left to
+// pay. Stripe accounts get it as a transfer to the connected account, which
+// Stripe then pays out on the account's schedule; the settlement is marked
+// paid when the transfer.created webhook arrives. Other methods are left
+// processing until an admin confirms the manual transfer.
+func (r *SettlementRepository) ExecuteSettlementPayout(ctx context.Context, due *settlement.VendorSettlement) error {
+	s, err := r.claimSettlement(ctx, due.ID)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		// another run claimed it first
+		return nil
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx,
+		`SELECT method, stripe_account_id, COALESCE(currency, 'USD') AS currency FROM payout_accounts WHERE id = @id`,
+		pgx.NamedArgs{"id": s.PayoutAccountID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to fetch payout account: %w", err)
+	}
+	target, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.SettlementPayoutTarget])
+	if err != nil {
+		return fmt.Errorf("failed to fetch payout account: %w", err)
+	}
+
+	amount := *s.PayoutAmount
+	payoutID, err := r.upsertSettlementPayout(ctx, s, target.Method, amount)
+	if err != nil {
+		return err
+	}
+
+	// Everything already reached the vendor (or refunds ate the rest)
+	if amount <= 0 {
+		_, err := r.MarkSettlementPaid(ctx, s.ID, nil)
+		return err
+	}
+
+	if target.Method != "stripe" {
+		return r.MarkSettlementProcessing(ctx, s.ID, payoutID, nil)
+	}
+
+	if target.StripeAccountID == nil || *target.StripeAccountID == "" {
+		return r.MarkSettlementFailed(ctx, s.ID, payoutID, "payout account has no stripe account")
+	}
+
+	stripe.Key = r.server.Config.Stripe.SecretKey
+	params := &stripe.TransferParams{
+		Amount:        stripe.Int64(int64(math.Round(amount * 100))),
+		Currency:      stripe.String(strings.ToLower(target.Currency)),
+		Destination:   target.StripeAccountID,
+		TransferGroup: stripe.String("settlement-" + s.ID),
+	}
+	params.AddMetadata("settlement_id", s.ID)
+	params.AddMetadata("payout_id", payoutID)
+	params.AddMetadata("payout_type", "vendor_payout")
+	// one transfer per settlement, however often it is retried
+	params.SetIdempotencyKey("settlement-" + s.ID)
+
+	t, err := transfer.New(params)
+	if err != nil {
+		return r.MarkSettlementFailed(ctx, s.ID, payoutID, err.Error())
+	}
+
+	return r.MarkSettlementProcessing(ctx, s.ID, payoutID, stripe.String(t.ID))
+}
+
+// upsertSettlementPayout returns the payouts row of a settlement, creating
+// it on the first attempt and resetting it on a retry.
+func (r *SettlementRepository) upsertSettlementPayout(ctx context.Context, s *settlement.VendorSettlement, method string, amount float64) (string, error) {
+	args := pgx.NamedArgs{
+		"settlementId": s.ID,
+		"payoutId":     s.PayoutID,
+		"vendorUserId": s.VendorUserID,
+		"accountId":    s.PayoutAccountID,
+		"method":       method,
+		"amount":       amount,
+		"remarks":      "settlement " + s.ID,
+	}
+
+	var id string
+	if s.PayoutID != nil {
+		err := r.server.DB.Pool.QueryRow(ctx, `
+			UPDATE payouts
+			SET method = @method, amount = @amount, status = 'pending', remarks = @remarks
+			WHERE id = @payoutId
+			RETURNING id
+		`, args).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if err != pgx.ErrNoRows {
+			return "", fmt.Errorf("failed to reset settlement payout: %w", err)
+		}
+	}
+
+	// The row is linked to the settlement straight away, so a run that stops
+	// before the transfer still finds it on the next attempt
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		WITH p AS (
+			INSERT INTO payouts (
+				vendor_user_id, account_id, sender, payout_type, method, amount, status, remarks
+			) VALUES (
+				@vendorUserId, @accountId, 'platform', 'vendor_payout', @method, @amount, 'pending', @remarks
+			)
+			RETURNING id
+		)
+		UPDATE vendor_settlements SET payout_id = (SELECT id FROM p)
+		WHERE id = @settlementId
+		RETURNING payout_id
+	`, args).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to create settlement payout: %w", err)
+	}
+	return id, nil
+}
+
+// MarkSettlementProcessing records the sent payout. The transfer webhook may
+// already have marked the settlement paid, which is left alone.
+func (r *SettlementRepository) MarkSettlementProcessing(ctx context.Context, id, payoutID string, transactionRef *string) error {
+	query := `
+		UPDATE vendor_settlements
+		SET status = 'processing',
+		    payout_id = @payoutId,
+		    transaction_ref = @transactionRef,
+		    last_error = NULL,
+		    next_retry_at = NULL
+		WHERE id = @id AND status <> 'paid'
+	`
+	_, err := r.server.DB.Pool.Exec(ctx, query, pgx.NamedArgs{"id": id, "payoutId": payoutID, "transactionRef": transactionRef})
+	if err != nil {
+		return fmt.Errorf("failed to mark settlement processing: %w", err)
+	}
+	if transactionRef != nil {
+		_, err = r.server.DB.Pool.Exec(ctx, `UPDATE payouts SET transaction_ref = $1 WHERE id = $2`, *transactionRef, payoutID)
+	}
+	return err
+}
+
+// MarkSettlementFailed records a failed attempt and schedules the next retry
+// with a quadratic backoff (1h, 4h, 9h, ...).
+func (r *SettlementRepository) MarkSettlementFailed(ctx context.Context, id, payoutID, reason string) error {
+	query := `
+		UPDATE vendor_settlements
+		SET status = 'failed',
+		    attempts = attempts + 1,
+		    payout_id = COALESCE(NULLIF(@payoutId, ''), payout_id),
+		    last_error = @reason,
+		    next_retry_at = NOW() + make_interval(hours => (attempts + 1) * (attempts + 1))
+		WHERE id = @id AND status <> 'paid'
+	`
+	_, err := r.server.DB.Pool.Exec(ctx, query, pgx.NamedArgs{"id": id, "payoutId": payoutID, "reason": reason})
+	if err != nil {
+		return fmt.Errorf("failed to mark settlement failed: %w", err)
+	}
+	if payoutID != "" {
+		_, err = r.server.DB.Pool.Exec(ctx, `UPDATE payouts SET status = 'failed', remarks = $1 WHERE id = $2`, reason, payoutID)
+	}
+	return err
+}
+
+func (r *SettlementRepository) MarkSettlementPaid(ctx context.Context, id string, transactionRef *string) (*settlement.VendorSettlement, error) {
+	query := `
+		UPDATE vendor_settlements
+		SET status = 'paid',
+		    paid_at = NOW(),
+		    transaction_ref = COALESCE(@transactionRef, transaction_ref),
+		    last_error = NULL,
+		    next_retry_at = NULL
+		WHERE id = @id AND status IN ('processing', 'pending', 'failed')
+		RETURNING *
+	`
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"id": id, "transactionRef": transactionRef})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark settlement paid: %w", err)
+	}
+	s, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		return nil, err
+	}
+	method := "bank_transfer"
+	if s.PayoutID != nil {
+		err = tx.QueryRow(ctx,
+			`UPDATE payouts SET status = 'completed', transaction_ref = COALESCE($1, transaction_ref) WHERE id = $2 RETURNING method`,
+			transactionRef, *s.PayoutID,
+		).Scan(&method)
+		if err != nil {
+			return nil, fmt.Errorf("failed to complete settlement payout: %w", err)
+		}
+	}
+	if err := r.ledger.RecordSettlementPayout(ctx, tx, &s, method); err != nil {
+		return nil, fmt.Errorf("failed to post settlement payout to ledger: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RunSettlementCycle batches the period's earnings and pays out everything
+// that is due, including retries of earlier failures.
+func (r *SettlementRepository) RunSettlementCycle(ctx context.Context, frequency string, now time.Time) (*settlement.RunSettlementResponse, error) {
+	created, err := r.CreateSettlementBatches(ctx, frequency, SettlementPeriodEnd(frequency, now))
+	if err != nil {
+		return nil, err
+	}
+
+	due, err := r.ListDueSettlements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &settlement.RunSettlementResponse{Created: len(created)}
+	for i := range due {
+		if err := r.ExecuteSettlementPayout(ctx, &due[i]); err != nil {
+			r.server.Logger.Error().Err(err).Str("settlement_id", due[i].ID).Msg("settlement payout failed")
+			res.Failed++
+			continue
+		}
+		res.Paid++
+	}
+	return res, nil
+}
+
+//-- ==================================================
+//-- STATEMENTS
+//-- ==================================================
+
+func (r *SettlementRepository) GetSettlementByID(ctx context.Context, id string) (*settlement.VendorSettlement, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM vendor_settlements WHERE id = @id`, pgx.NamedArgs{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	s, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetPayoutRecipient returns the vendor user of a settlement, named after
+// their (first) restaurant.
+func (r *SettlementRepository) GetPayoutRecipient(ctx context.Context, settlementID string) (*settlement.PayoutRecipient, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT u.email,
+		       COALESCE(
+		           (SELECT v.name FROM vendors v WHERE v.vendor_user_id = u.id ORDER BY v.created_at LIMIT 1),
+		           u.username, ''
+		       ) AS name,
+		       COALESCE(pa.method, 'bank_transfer') AS method
+		FROM vendor_settlements s
+		JOIN users u ON u.id = s.vendor_user_id
+		LEFT JOIN payout_accounts pa ON pa.id = s.payout_account_id
+		WHERE s.id = @id
+	`, pgx.NamedArgs{"id": settlementID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout recipient: %w", err)
+	}
+	rcpt, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[settlement.PayoutRecipient])
+	if err != nil {
+		return nil, err
+	}
+	return &rcpt, nil
+}
+
+func (r *SettlementRepository) ListSettlementEntries(ctx context.Context, settlementID string) ([]settlement.VendorEarning, error) {
+	rows, err := r.server.DB.Pool.Query(ctx,
+		`SELECT * FROM vendor_earnings WHERE settlement_id = @id ORDER BY created_at`,
+		pgx.NamedArgs{"id": settlementID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlement entries: %w", err)
+	}
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[settlement.VendorEarning])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect settlement entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *SettlementRepository) ListUnsettledEntries(ctx context.Context, vendorUserID string) ([]settlement.VendorEarning, error) {
+	rows, err := r.server.DB.Pool.Query(ctx,
+		`SELECT * FROM vendor_earnings WHERE vendor_user_id = @vendorUserId AND settlement_id IS NULL ORDER BY created_at`,
+		pgx.NamedArgs{"vendorUserId": vendorUserID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsettled entries: %w", err)
+	}
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[settlement.VendorEarning])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect unsettled entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *SettlementRepository) GetSettlements(ctx context.Context, vendorUserID *string, query *settlement.GetSettlementsQuery) (*model.PaginatedResponse[settlement.VendorSettlement], error) {
+	args := pgx.NamedArgs{
+		"vendorUserId": vendorUserID,
+		"status":       query.Status,
+		"limit":        *query.Limit,
+		"offset":       (*query.Page - 1) * *query.Limit,
+	}
+	where := `
+		WHERE (@vendorUserId::TEXT IS NULL OR vendor_user_id = @vendorUserId)
+		  AND (@status::TEXT IS NULL OR status = @status)
+	`
+
+	var total int
+	if err := r.server.DB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM vendor_settlements `+where, args).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count settlements: %w", err)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx,
+		`SELECT * FROM vendor_settlements `+where+` ORDER BY created_at DESC LIMIT @limit OFFSET @offset`,
+		args,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlements: %w", err)
+	}
+	settlements, err := pgx.CollectRows(rows, pgx.RowToStructByName[settlement.VendorSettlement])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect settlements: %w", err)
+	}
+
+	return &model.PaginatedResponse[settlement.VendorSettlement]{
+		Data:       settlements,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
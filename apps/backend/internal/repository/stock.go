@@ -0,0 +1,579 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrStockMenuItemNotFound = errors.New("menu item not found on the vendor's menu")
+	ErrStockNotTracked       = errors.New("stock is not tracked for this menu item")
+	ErrStockQuantityRequired = errors.New("a quantity or daily quantity is needed to start tracking stock")
+	ErrInsufficientStock     = errors.New("not enough stock")
+)
+
+// ---------------- STOCK REPOSITORY ----------------
+
+type StockRepository struct {
+	server *server.Server
+}
+
+func NewStockRepository(s *server.Server) *StockRepository {
+	return &StockRepository{server: s}
+}
+
+// stockColumns selects a vendor.MenuItemStock from menu_item_stock s.
+const stockColumns = `
+	s.vendor_id, s.menu_item_id, s.quantity, s.daily_quantity,
+	to_char(s.reset_time, 'HH24:MI') AS reset_time, s.low_stock_threshold,
+	s.low_stock_alerted, s.counted_at, s.created_at, s.updated_at`
+
+//-- ==================================================
+//-- VENDOR STOCK
+//-- ==================================================
+
+func (r *StockRepository) GetStock(ctx context.Context, vendorID string) ([]vendor.PopulatedMenuItemStock, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT `+stockColumns+`, vmi.name, vmi.is_available
+		FROM menu_item_stock s
+		JOIN vendor_menu_items vmi ON vmi.vendor_id = s.vendor_id AND vmi.id = s.menu_item_id
+		WHERE s.vendor_id = $1
+		ORDER BY s.quantity, vmi.name
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	stock, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.PopulatedMenuItemStock])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect stock: %w", err)
+	}
+	return stock, nil
+}
+
+func (r *StockRepository) getMenuItemStock(ctx context.Context, vendorID, menuItemID string) (*vendor.PopulatedMenuItemStock, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT `+stockColumns+`, vmi.name, vmi.is_available
+		FROM menu_item_stock s
+		JOIN vendor_menu_items vmi ON vmi.vendor_id = s.vendor_id AND vmi.id = s.menu_item_id
+		WHERE s.vendor_id = $1 AND s.menu_item_id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu item stock: %w", err)
+	}
+	stock, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.PopulatedMenuItemStock])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStockNotTracked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu item stock: %w", err)
+	}
+	return &stock, nil
+}
+
+// SetStock starts tracking the stock of an item the vendor sells or replaces
+// its settings. Setting a quantity is a recount: reservations made before it
+// are not given back when released. A count at or below the threshold does
+// not alert, the vendor set it.
+func (r *StockRepository) SetStock(ctx context.Context, vendorID string, payload *vendor.SetMenuItemStockPayload) (*vendor.PopulatedMenuItemStock, error) {
+	var sold bool
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM vendor_menu_items WHERE vendor_id = $1 AND id = $2)
+	`, vendorID, payload.MenuItemID).Scan(&sold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check menu item: %w", err)
+	}
+	if !sold {
+		return nil, ErrStockMenuItemNotFound
+	}
+
+	args := pgx.NamedArgs{
+		"vendorId":      vendorID,
+		"menuItemId":    payload.MenuItemID,
+		"quantity":      payload.Quantity,
+		"dailyQuantity": payload.DailyQuantity,
+		"resetTime":     payload.ResetTime,
+		"threshold":     payload.LowStockThreshold,
+	}
+
+	// without a quantity the count is kept; a new item starts at its daily
+	// quantity
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		INSERT INTO menu_item_stock (
+			vendor_id, menu_item_id, quantity, daily_quantity, reset_time,
+			low_stock_threshold, low_stock_alerted
+		)
+		SELECT @vendorId, @menuItemId, q.quantity, @dailyQuantity,
+		       COALESCE(@resetTime::TIME, '06:00'), COALESCE(@threshold::INT, 0),
+		       q.quantity <= COALESCE(@threshold::INT, 0)
+		FROM (
+			SELECT COALESCE(
+				@quantity::INT,
+				(SELECT quantity FROM menu_item_stock WHERE vendor_id = @vendorId AND menu_item_id = @menuItemId),
+				@dailyQuantity::INT
+			) AS quantity
+		) q
+		WHERE q.quantity IS NOT NULL
+		ON CONFLICT (vendor_id, menu_item_id) DO UPDATE SET
+			quantity = EXCLUDED.quantity,
+			daily_quantity = EXCLUDED.daily_quantity,
+			reset_time = EXCLUDED.reset_time,
+			low_stock_threshold = EXCLUDED.low_stock_threshold,
+			low_stock_alerted = EXCLUDED.low_stock_alerted,
+			counted_at = CASE WHEN @quantity::INT IS NULL THEN menu_item_stock.counted_at ELSE NOW() END
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set stock: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrStockQuantityRequired
+	}
+
+	return r.getMenuItemStock(ctx, vendorID, payload.MenuItemID)
+}
+
+// DeleteStock stops tracking the stock of an item; it is available again
+// unless the vendor marked it otherwise.
+func (r *StockRepository) DeleteStock(ctx context.Context, vendorID, menuItemID string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM menu_item_stock WHERE vendor_id = $1 AND menu_item_id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return fmt.Errorf("failed to delete stock: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStockNotTracked
+	}
+	return nil
+}
+
+// GetMenuState is the item as the vendor now sells it, after its stock.
+func (r *StockRepository) GetMenuState(ctx context.Context, vendorID, menuItemID string) (*vendor.OutletMenuState, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT vendor_id, id, base_price, is_available
+		FROM vendor_menu_items
+		WHERE vendor_id = $1 AND id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu state: %w", err)
+	}
+	state, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.OutletMenuState])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStockMenuItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect menu state: %w", err)
+	}
+	return &state, nil
+}
+
+// GetLowStockAlert is what the vendor's owner is told about a low item.
+func (r *StockRepository) GetLowStockAlert(ctx context.Context, vendorID, menuItemID string) (*vendor.LowStockAlert, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT s.vendor_id, COALESCE(v.name || ' ' || v.outlet_name, v.name) AS vendor_name,
+		       s.menu_item_id, mi.name AS item_name, s.quantity,
+		       CASE WHEN s.daily_quantity IS NOT NULL THEN to_char(s.reset_time, 'HH24:MI') END AS daily_reset,
+		       CASE WHEN u.anonymized_at IS NULL THEN u.email END AS owner_email
+		FROM menu_item_stock s
+		JOIN vendors v ON v.id = s.vendor_id
+		JOIN users u ON u.id = v.vendor_user_id
+		JOIN menu_items mi ON mi.id = s.menu_item_id
+		WHERE s.vendor_id = $1 AND s.menu_item_id = $2
+	`, vendorID, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get low stock alert: %w", err)
+	}
+	alert, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.LowStockAlert])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStockNotTracked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect low stock alert: %w", err)
+	}
+	return &alert, nil
+}
+
+//-- ==================================================
+//-- RESERVATIONS
+//-- ==================================================
+
+// ReserveStock takes the stock of the tracked items an order has, quantities
+// keyed by menu item, replacing what the order held before (a re-checkout).
+// The stock rows are locked for the rest of the transaction, in item order so
+// concurrent orders cannot deadlock; an item without enough left fails the
+// whole order with ErrInsufficientStock.
+func (r *StockRepository) ReserveStock(ctx context.Context, tx pgx.Tx, orderVendorID, vendorID string, quantities map[string]int) ([]vendor.StockChange, error) {
+	released, err := r.releaseStock(ctx, tx, []string{orderVendorID})
+	if err != nil {
+		return nil, err
+	}
+
+	itemIDs := make([]string, 0, len(quantities))
+	for id := range quantities {
+		itemIDs = append(itemIDs, id)
+	}
+	sort.Strings(itemIDs)
+
+	// 1️⃣ Lock the tracked items and check there is enough of each
+	rows, err := tx.Query(ctx, `
+		SELECT s.menu_item_id, s.quantity, mi.name
+		FROM menu_item_stock s
+		JOIN menu_items mi ON mi.id = s.menu_item_id
+		WHERE s.vendor_id = @vendorId AND s.menu_item_id = ANY(@itemIds)
+		ORDER BY s.menu_item_id
+		FOR UPDATE OF s
+	`, pgx.NamedArgs{"vendorId": vendorID, "itemIds": itemIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stock: %w", err)
+	}
+	type level struct {
+		MenuItemID string `db:"menu_item_id"`
+		Quantity   int    `db:"quantity"`
+		Name       string `db:"name"`
+	}
+	levels, err := pgx.CollectRows(rows, pgx.RowToStructByName[level])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect stock: %w", err)
+	}
+	if len(levels) == 0 {
+		return released, nil
+	}
+
+	tracked := make([]string, len(levels))
+	amounts := make([]int, len(levels))
+	for i, l := range levels {
+		want := quantities[l.MenuItemID]
+		if l.Quantity < want {
+			if l.Quantity == 0 {
+				return nil, fmt.Errorf("%w: %s is sold out", ErrInsufficientStock, l.Name)
+			}
+			return nil, fmt.Errorf("%w: only %d %s left", ErrInsufficientStock, l.Quantity, l.Name)
+		}
+		tracked[i] = l.MenuItemID
+		amounts[i] = want
+	}
+
+	// 2️⃣ Take it; reaching the threshold flags the alert once
+	args := pgx.NamedArgs{
+		"orderVendorId": orderVendorID,
+		"vendorId":      vendorID,
+		"itemIds":       tracked,
+		"quantities":    amounts,
+	}
+	rows, err = tx.Query(ctx, `
+		WITH want AS (
+			SELECT * FROM unnest(@itemIds::TEXT[], @quantities::INT[]) AS w(menu_item_id, quantity)
+		), old AS (
+			SELECT s.menu_item_id, s.quantity, s.low_stock_alerted
+			FROM menu_item_stock s
+			JOIN want w USING (menu_item_id)
+			WHERE s.vendor_id = @vendorId
+		)
+		UPDATE menu_item_stock s
+		SET quantity = s.quantity - w.quantity,
+		    low_stock_alerted = s.low_stock_alerted OR s.quantity - w.quantity <= s.low_stock_threshold
+		FROM want w
+		JOIN old o USING (menu_item_id)
+		WHERE s.vendor_id = @vendorId AND s.menu_item_id = w.menu_item_id
+		RETURNING s.vendor_id, s.menu_item_id, s.quantity, o.quantity AS previous_quantity,
+		          (s.low_stock_alerted AND NOT o.low_stock_alerted) AS low_stock
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	reserved, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.StockChange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect reserved stock: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO stock_reservations (order_vendor_id, vendor_id, menu_item_id, quantity)
+		SELECT @orderVendorId, @vendorId, w.menu_item_id, w.quantity
+		FROM unnest(@itemIds::TEXT[], @quantities::INT[]) AS w(menu_item_id, quantity)
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record stock reservations: %w", err)
+	}
+
+	return mergeStockChanges(released, reserved), nil
+}
+
+// ReleaseStock gives back the stock the orders still hold.
+func (r *StockRepository) ReleaseStock(ctx context.Context, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	changes, err := r.releaseStock(ctx, tx, orderVendorIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ReleaseStockTx is ReleaseStock within the caller's transaction.
+func (r *StockRepository) ReleaseStockTx(ctx context.Context, tx pgx.Tx, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	return r.releaseStock(ctx, tx, orderVendorIDs)
+}
+
+// ReleaseUnpaidStock is ReleaseStock for orders that may still be paid. Like
+// ReleaseAbandonedReservations it only touches orders that are not paid and
+// skips those being paid right now.
+func (r *StockRepository) ReleaseUnpaidStock(ctx context.Context, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT ov.id
+		FROM order_vendors ov
+		WHERE ov.id = ANY(@orderVendorIds)
+		  AND ov.payment_status <> 'paid'
+		FOR UPDATE OF ov SKIP LOCKED
+	`, pgx.NamedArgs{"orderVendorIds": orderVendorIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock unpaid orders: %w", err)
+	}
+	unpaid, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect unpaid orders: %w", err)
+	}
+
+	changes, err := r.releaseStock(ctx, tx, unpaid)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// releaseStock marks the orders' reservations released and adds them back
+// to the stock, except those made before the item was last counted.
+func (r *StockRepository) releaseStock(ctx context.Context, tx pgx.Tx, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	if len(orderVendorIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH released AS (
+			UPDATE stock_reservations
+			SET released_at = NOW()
+			WHERE order_vendor_id = ANY(@orderVendorIds) AND released_at IS NULL
+			RETURNING vendor_id, menu_item_id, quantity, created_at
+		), restock AS (
+			SELECT rs.vendor_id, rs.menu_item_id, SUM(rs.quantity)::INT AS quantity
+			FROM released rs
+			JOIN menu_item_stock s USING (vendor_id, menu_item_id)
+			WHERE rs.created_at >= s.counted_at
+			GROUP BY rs.vendor_id, rs.menu_item_id
+		)
+		UPDATE menu_item_stock s
+		SET quantity = s.quantity + rs.quantity,
+		    low_stock_alerted = s.low_stock_alerted AND s.quantity + rs.quantity <= s.low_stock_threshold
+		FROM restock rs
+		WHERE s.vendor_id = rs.vendor_id AND s.menu_item_id = rs.menu_item_id
+		RETURNING s.vendor_id, s.menu_item_id, s.quantity, s.quantity - rs.quantity AS previous_quantity,
+		          FALSE AS low_stock
+	`, pgx.NamedArgs{"orderVendorIds": orderVendorIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to release stock: %w", err)
+	}
+	changes, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.StockChange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect released stock: %w", err)
+	}
+	return changes, nil
+}
+
+// reclaimReleasedStock takes back, within the transaction marking the orders
+// paid, the stock of orders whose reservations were released before the
+// payment arrived (abandoned, or a failed attempt). The customer has paid,
+// so the order stands: an item that sold out meanwhile is taken down to
+// zero and the vendor fills the rest. Only what the release gave back, or a
+// recount since has counted, is taken again.
+func reclaimReleasedStock(ctx context.Context, tx pgx.Tx, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	if len(orderVendorIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT r.order_vendor_id, r.vendor_id, r.menu_item_id, r.quantity
+		FROM stock_reservations r
+		JOIN menu_item_stock s USING (vendor_id, menu_item_id)
+		WHERE r.order_vendor_id = ANY(@orderVendorIds)
+		  AND NOT EXISTS (
+			SELECT 1 FROM stock_reservations h
+			WHERE h.order_vendor_id = r.order_vendor_id AND h.released_at IS NULL
+		  )
+		  AND r.released_at = (
+			SELECT MAX(l.released_at) FROM stock_reservations l
+			WHERE l.order_vendor_id = r.order_vendor_id
+		  )
+		  AND (r.created_at >= s.counted_at OR r.released_at <= s.counted_at)
+		ORDER BY r.vendor_id, r.menu_item_id
+		FOR UPDATE OF s
+	`, pgx.NamedArgs{"orderVendorIds": orderVendorIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock released stock: %w", err)
+	}
+	type lapsed struct {
+		OrderVendorID string `db:"order_vendor_id"`
+		VendorID      string `db:"vendor_id"`
+		MenuItemID    string `db:"menu_item_id"`
+		Quantity      int    `db:"quantity"`
+	}
+	reservations, err := pgx.CollectRows(rows, pgx.RowToStructByName[lapsed])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect released stock: %w", err)
+	}
+
+	var changes []vendor.StockChange
+	for _, l := range reservations {
+		rows, err := tx.Query(ctx, `
+			WITH old AS (
+				SELECT quantity, low_stock_alerted FROM menu_item_stock
+				WHERE vendor_id = @vendorId AND menu_item_id = @menuItemId
+			)
+			UPDATE menu_item_stock s
+			SET quantity = s.quantity - LEAST(s.quantity, @quantity),
+			    low_stock_alerted = s.low_stock_alerted OR s.quantity - LEAST(s.quantity, @quantity) <= s.low_stock_threshold
+			FROM old o
+			WHERE s.vendor_id = @vendorId AND s.menu_item_id = @menuItemId
+			RETURNING s.vendor_id, s.menu_item_id, s.quantity, o.quantity AS previous_quantity,
+			          (s.low_stock_alerted AND NOT o.low_stock_alerted) AS low_stock
+		`, pgx.NamedArgs{"vendorId": l.VendorID, "menuItemId": l.MenuItemID, "quantity": l.Quantity})
+		if err != nil {
+			return nil, fmt.Errorf("failed to reclaim stock: %w", err)
+		}
+		c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.StockChange])
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect reclaimed stock: %w", err)
+		}
+
+		if taken := c.PreviousQuantity - c.Quantity; taken > 0 {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO stock_reservations (order_vendor_id, vendor_id, menu_item_id, quantity)
+				VALUES ($1, $2, $3, $4)
+			`, l.OrderVendorID, l.VendorID, l.MenuItemID, taken)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record stock reservation: %w", err)
+			}
+		}
+		changes = mergeStockChanges(changes, []vendor.StockChange{c})
+	}
+	return changes, nil
+}
+
+// mergeStockChanges folds a release and the reservation that followed it
+// into one change per item, from the level before both.
+func mergeStockChanges(first, then []vendor.StockChange) []vendor.StockChange {
+	merged := make([]vendor.StockChange, 0, len(first)+len(then))
+	index := make(map[string]int, len(first))
+	for _, c := range first {
+		index[c.VendorID+":"+c.MenuItemID] = len(merged)
+		merged = append(merged, c)
+	}
+	for _, c := range then {
+		i, ok := index[c.VendorID+":"+c.MenuItemID]
+		if !ok {
+			merged = append(merged, c)
+			continue
+		}
+		c.PreviousQuantity = merged[i].PreviousQuantity
+		merged[i] = c
+	}
+	return merged
+}
+
+//-- ==================================================
+//-- STOCK WORKER
+//-- ==================================================
+
+// ReleaseAbandonedReservations gives back the stock of orders left unpaid
+// for longer than hold.
+func (r *StockRepository) ReleaseAbandonedReservations(ctx context.Context, hold time.Duration) ([]vendor.StockChange, int, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// the order rows stay locked until the release commits, so a payment
+	// landing meanwhile waits and then takes the stock back
+	// (reclaimReleasedStock); orders being paid right now are skipped
+	rows, err := tx.Query(ctx, `
+		SELECT ov.id
+		FROM order_vendors ov
+		WHERE ov.payment_status <> 'paid'
+		  AND EXISTS (
+			SELECT 1 FROM stock_reservations r
+			WHERE r.order_vendor_id = ov.id
+			  AND r.released_at IS NULL
+			  AND r.created_at < NOW() - make_interval(secs => @hold)
+		  )
+		FOR UPDATE OF ov SKIP LOCKED
+	`, pgx.NamedArgs{"hold": hold.Seconds()})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list abandoned reservations: %w", err)
+	}
+	orderIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect abandoned reservations: %w", err)
+	}
+
+	changes, err := r.releaseStock(ctx, tx, orderIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+	return changes, len(orderIDs), nil
+}
+
+// ResetDailyStock refills the items whose reset time has passed today (in
+// tz) and that were last counted before it. It also catches up after the
+// worker was down.
+func (r *StockRepository) ResetDailyStock(ctx context.Context, tz string) ([]vendor.StockChange, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		WITH due AS (
+			SELECT vendor_id, menu_item_id, quantity
+			FROM menu_item_stock
+			WHERE daily_quantity IS NOT NULL
+			  AND (NOW() AT TIME ZONE @tz)::TIME >= reset_time
+			  AND (counted_at AT TIME ZONE @tz) < (NOW() AT TIME ZONE @tz)::DATE + reset_time
+			FOR UPDATE
+		)
+		UPDATE menu_item_stock s
+		SET quantity = s.daily_quantity,
+		    low_stock_alerted = s.daily_quantity <= s.low_stock_threshold,
+		    counted_at = NOW()
+		FROM due d
+		WHERE s.vendor_id = d.vendor_id AND s.menu_item_id = d.menu_item_id
+		RETURNING s.vendor_id, s.menu_item_id, s.quantity, d.quantity AS previous_quantity,
+		          FALSE AS low_stock
+	`, pgx.NamedArgs{"tz": tz})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset daily stock: %w", err)
+	}
+	changes, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.StockChange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect reset stock: %w", err)
+	}
+	return changes, nil
+}
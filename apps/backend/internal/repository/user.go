@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"strings"
+	"time"
 
 	"fmt"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/gitSanje/khajaride/internal/server"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 
@@ -178,7 +181,9 @@ func (r *UserRepository) UpdateUser(ctx context.Context, userID string, payload
         args["username"] = *payload.Username
     }
     if payload.PhoneNumber != nil {
-        setClauses = append(setClauses, "phone_number = @phone_number")
+        // a new number has to be verified again
+        setClauses = append(setClauses, "phone_number = @phone_number",
+            "is_verified = is_verified AND phone_number IS NOT DISTINCT FROM @phone_number")
         args["phone_number"] = *payload.PhoneNumber
     }
     if payload.Password != nil {
@@ -218,29 +223,6 @@ func (r *UserRepository) UpdateUser(ctx context.Context, userID string, payload
     return &updatedUser, nil
 }
 
-// ------------------- DELETE USER (SOFT DELETE) -------------------
-
-func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
-    stmt := `
-        DELETE FROM users
-        WHERE id = @id 
-    `
-
-    tag, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
-        "id": id,
-    })
-    if err != nil {
-        return fmt.Errorf("failed to delete user id=%s: %w", id, err)
-    }
-
-    if tag.RowsAffected() == 0 {
-        return fmt.Errorf("no user found to delete with id=%s", id)
-    }
-
-    return nil
-}
-
-
 // ------------------- GET  USERS (Query) -------------------
 func (r *UserRepository) GetUsers(ctx context.Context, query *user.GetUsersQuery) (*model.PaginatedResponse[user.User], error) {
 
@@ -534,7 +516,7 @@ func (r *UserRepository) GetUserLoyaltyPoints(ctx context.Context, userID string
 		WHERE user_id = @user_id
 	`
 
-	args := pgx.NamedArgs{"UserID": userID}
+	args := pgx.NamedArgs{"user_id": userID}
 
 	conditions := [] string{}
 
@@ -612,31 +594,23 @@ func (r *UserRepository) RedeemPoints(ctx context.Context, userID string, payloa
 
 	// check if user has enough points
 
-	var currentBalance float64
-
-	err = tx.QueryRow(ctx, "SELECT COALESCE(SUM(points_change),0) FROM loyalty_points_ledger WHERE user_id = $1", userID).Scan(&currentBalance)
+	currentBalance, err := lockLoyaltyBalance(ctx, tx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get current balance: %w", err)
+		return err
 	}
 
 	if currentBalance < payload.Points {
-		return errors.New("insufficient loyalty points")
+		return ErrInsufficientPoints
 	}
 
-	// insert redeem transaction
-	_, err = tx.Exec(ctx, `
-		INSERT INTO loyalty_points_ledger (
-			user_id,
-			transaction_type,
-			points_change,
-			balance_after,
-			reason,
-			performed_by,
-			performed_at
-		) VALUES (
-			$1, 'REDEEM', $2, $3, $4, $1, NOW()
-		)
-	`, userID, -payload.Points, currentBalance-payload.Points, payload.Reason)
+	// insert redeem transaction, spending the oldest points first
+	_, err = insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: "REDEEM",
+		PointsChange:    -payload.Points,
+		Reason:          &payload.Reason,
+		PerformedBy:     userID,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to insert redeem points: %w", err)
 	}
@@ -655,31 +629,33 @@ func (r *UserRepository) AdjustPoints(ctx context.Context, payload *user.AdjustP
 	}
 	defer tx.Rollback(ctx)
 
+	userID := payload.UserID.String()
+
 	// get current balance
-	var balance float64
-	err = tx.QueryRow(ctx, "SELECT COALESCE(SUM(points_change),0) FROM loyalty_points_ledger WHERE user_id = $1", payload.UserID).Scan(&balance)
+	balance, err := lockLoyaltyBalance(ctx, tx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get current balance: %w", err)
+		return err
+	}
+	if balance+payload.PointsChange < 0 {
+		return ErrInsufficientPoints
 	}
 
-	newBalance := balance + payload.PointsChange
+	var referenceID *string
+	if payload.ReferenceID != nil {
+		id := payload.ReferenceID.String()
+		referenceID = &id
+	}
 
 	// insert ledger row
-	_, err = tx.Exec(ctx, `
-		INSERT INTO loyalty_points_ledger (
-			user_id,
-			transaction_type,
-			points_change,
-			balance_after,
-			reason,
-			reference_id,
-			reference_type,
-			performed_by,
-			performed_at
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, NOW()
-		)
-	`, payload.UserID, payload.TransactionType, payload.PointsChange, newBalance, payload.Reason, payload.ReferenceID, payload.ReferenceType, performedBy)
+	_, err = insertLoyaltyEntry(ctx, tx, &user.LoyaltyPointsLedger{
+		UserID:          userID,
+		TransactionType: payload.TransactionType,
+		PointsChange:    payload.PointsChange,
+		Reason:          &payload.Reason,
+		ReferenceID:     referenceID,
+		ReferenceType:   payload.ReferenceType,
+		PerformedBy:     performedBy,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to insert adjust points: %w", err)
 	}
@@ -703,3 +679,549 @@ func (r *UserRepository) GetCurrentBalance(ctx context.Context, userID string) (
     }
     return balance, nil
 }
+
+
+
+//-- ==================================================
+//--  CLERK SYNC
+//-- ==================================================
+
+// UpsertClerkUser writes a Clerk user's state to users. Writes are ordered by
+// Clerk's updated_at, so a late, older webhook is dropped instead of undoing
+// a newer one; a deleted user is never revived, even when its user.deleted
+// arrived before the user.created. It returns nil when nothing was written,
+// and whether the row was inserted.
+func (r *UserRepository) UpsertClerkUser(ctx context.Context, payload *user.ClerkUserSync) (*user.User, bool, error) {
+    stmt := `
+        INSERT INTO users (id, email, username, phone_number, profile_picture, last_sign_in_at, clerk_updated_at)
+        SELECT @id, @email, @username, @phoneNumber, @profilePicture, @lastSignInAt, @clerkUpdatedAt
+        WHERE NOT EXISTS (
+            SELECT 1 FROM clerk_webhook_events
+            WHERE object_id = @id AND type = 'user.deleted'
+        )
+        ON CONFLICT (id) DO UPDATE SET
+            email = EXCLUDED.email,
+            username = EXCLUDED.username,
+            -- a number verified by OTP here wins over Clerk's
+            phone_number = CASE WHEN users.is_verified THEN users.phone_number ELSE EXCLUDED.phone_number END,
+            profile_picture = EXCLUDED.profile_picture,
+            last_sign_in_at = GREATEST(users.last_sign_in_at, EXCLUDED.last_sign_in_at),
+            clerk_updated_at = EXCLUDED.clerk_updated_at
+        WHERE users.deleted_at IS NULL
+          AND users.clerk_updated_at <= EXCLUDED.clerk_updated_at
+        RETURNING *, (xmax = 0) AS inserted
+    `
+
+    rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+        "id":             payload.ID,
+        "email":          payload.Email,
+        "username":       payload.Username,
+        "phoneNumber":    payload.PhoneNumber,
+        "profilePicture": payload.ProfilePicture,
+        "lastSignInAt":   payload.LastSignInAt,
+        "clerkUpdatedAt": payload.ClerkUpdatedAt,
+    })
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to upsert clerk user %s: %w", payload.ID, err)
+    }
+
+    type upsertedUser struct {
+        user.User
+        Inserted bool `db:"inserted"`
+    }
+    row, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[upsertedUser])
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, false, nil
+        }
+        return nil, false, fmt.Errorf("failed to collect clerk user %s: %w", payload.ID, err)
+    }
+
+    return &row.User, row.Inserted, nil
+}
+
+// DeleteClerkUser deactivates a user deleted in Clerk and queues the account
+// for anonymization. The row is kept for the orders, payments and ledger
+// entries that point at it.
+func (r *UserRepository) DeleteClerkUser(ctx context.Context, id string, clerkTimestamp int64) (bool, error) {
+    stmt := `
+        UPDATE users
+        SET is_active = FALSE,
+            deleted_at = NOW(),
+            deletion_scheduled_at = COALESCE(deletion_scheduled_at, NOW()),
+            clerk_updated_at = GREATEST(clerk_updated_at, @clerkTimestamp)
+        WHERE id = @id AND deleted_at IS NULL
+    `
+    tag, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+        "id":             id,
+        "clerkTimestamp": clerkTimestamp,
+    })
+    if err != nil {
+        return false, fmt.Errorf("failed to delete clerk user %s: %w", id, err)
+    }
+    return tag.RowsAffected() > 0, nil
+}
+
+func (r *UserRepository) RecordClerkSignIn(ctx context.Context, id string, at time.Time) error {
+    _, err := r.server.DB.Pool.Exec(ctx, `
+        UPDATE users
+        SET last_sign_in_at = GREATEST(last_sign_in_at, @at)
+        WHERE id = @id
+    `, pgx.NamedArgs{"id": id, "at": at})
+    if err != nil {
+        return fmt.Errorf("failed to record sign in for user %s: %w", id, err)
+    }
+    return nil
+}
+
+// ListActiveClerkUserIDs returns the ids of users that came from Clerk, are
+// not deleted yet and were created before createdBefore.
+func (r *UserRepository) ListActiveClerkUserIDs(ctx context.Context, createdBefore time.Time) ([]string, error) {
+    rows, err := r.server.DB.Pool.Query(ctx, `
+        SELECT id FROM users
+        WHERE id LIKE 'user\_%' AND deleted_at IS NULL AND created_at < @createdBefore
+        ORDER BY created_at, id
+    `, pgx.NamedArgs{"createdBefore": createdBefore})
+    if err != nil {
+        return nil, fmt.Errorf("failed to list clerk users: %w", err)
+    }
+    return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (r *UserRepository) IsClerkEventProcessed(ctx context.Context, id string) (bool, error) {
+    var processed bool
+    err := r.server.DB.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM clerk_webhook_events WHERE id = $1)`, id).Scan(&processed)
+    if err != nil {
+        return false, fmt.Errorf("failed to check clerk event %s: %w", id, err)
+    }
+    return processed, nil
+}
+
+func (r *UserRepository) RecordClerkEvent(ctx context.Context, e *user.ClerkEvent) error {
+    _, err := r.server.DB.Pool.Exec(ctx, `
+        INSERT INTO clerk_webhook_events (id, type, object_id, event_timestamp)
+        VALUES (@id, @type, @objectId, @eventTimestamp)
+        ON CONFLICT (id) DO NOTHING
+    `, pgx.NamedArgs{
+        "id":             e.ID,
+        "type":           e.Type,
+        "objectId":       e.ObjectID,
+        "eventTimestamp": e.EventTimestamp,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to record clerk event %s: %w", e.ID, err)
+    }
+    return nil
+}
+
+
+
+//-- ==================================================
+//--  ACCOUNT DELETION
+//-- ==================================================
+
+var (
+	ErrVendorAccountDeletion = errors.New("vendor accounts cannot be deleted while they own a store")
+	ErrNoDeletionPending     = errors.New("no account deletion is pending")
+)
+
+// activeOrderCondition matches the order states a deleted account must not be in:
+// the vendor and rider still need the contact details.
+const activeOrderCondition = `status NOT IN ('delivered', 'cancelled', 'failed')`
+
+// RequestDeletion schedules the account for anonymization once the grace
+// period is over. Asking again keeps the original schedule.
+func (r *UserRepository) RequestDeletion(ctx context.Context, userID string, gracePeriod time.Duration) (*user.User, error) {
+    var ownsStore bool
+    err := r.server.DB.Pool.QueryRow(ctx,
+        `SELECT EXISTS (SELECT 1 FROM vendors WHERE vendor_user_id = $1)`, userID,
+    ).Scan(&ownsStore)
+    if err != nil {
+        return nil, fmt.Errorf("failed to check vendor ownership: %w", err)
+    }
+    if ownsStore {
+        return nil, ErrVendorAccountDeletion
+    }
+
+    stmt := `
+        UPDATE users
+        SET deletion_requested_at = COALESCE(deletion_requested_at, NOW()),
+            deletion_scheduled_at = COALESCE(deletion_scheduled_at, NOW() + make_interval(secs => @gracePeriod))
+        WHERE id = @id AND anonymized_at IS NULL
+        RETURNING *
+    `
+    rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+        "id":          userID,
+        "gracePeriod": gracePeriod.Seconds(),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to request deletion of user %s: %w", userID, err)
+    }
+
+    u, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.User])
+    if err != nil {
+        return nil, err
+    }
+    return &u, nil
+}
+
+// CancelDeletion withdraws a pending deletion request.
+func (r *UserRepository) CancelDeletion(ctx context.Context, userID string) (*user.User, error) {
+    stmt := `
+        UPDATE users
+        SET deletion_requested_at = NULL,
+            deletion_scheduled_at = NULL
+        WHERE id = @id
+          AND deletion_scheduled_at IS NOT NULL
+          AND deleted_at IS NULL
+        RETURNING *
+    `
+    rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{"id": userID})
+    if err != nil {
+        return nil, fmt.Errorf("failed to cancel deletion of user %s: %w", userID, err)
+    }
+
+    u, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.User])
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, ErrNoDeletionPending
+        }
+        return nil, err
+    }
+    return &u, nil
+}
+
+// ListDueDeletions returns the accounts whose grace period is over.
+func (r *UserRepository) ListDueDeletions(ctx context.Context, limit int) ([]string, error) {
+    rows, err := r.server.DB.Pool.Query(ctx, `
+        SELECT id FROM users
+        WHERE deletion_scheduled_at <= NOW() AND anonymized_at IS NULL
+        ORDER BY deletion_scheduled_at
+        LIMIT $1
+    `, limit)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list due deletions: %w", err)
+    }
+    return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// AnonymizeUser strips the personal data from an account whose deletion is
+// due. Orders, payments and ledger entries stay, pointing at the anonymized
+// row. While the user still has an order in flight the deletion is pushed
+// back a day. It returns whether the account was anonymized.
+func (r *UserRepository) AnonymizeUser(ctx context.Context, userID string) (bool, error) {
+    tx, err := r.server.DB.Pool.Begin(ctx)
+    if err != nil {
+        return false, fmt.Errorf("failed to start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    // 1️⃣ Lock the user; the request may have been cancelled meanwhile
+    rows, err := tx.Query(ctx, `SELECT * FROM users WHERE id = $1 FOR UPDATE`, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to lock user %s: %w", userID, err)
+    }
+    u, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.User])
+    if err != nil {
+        return false, fmt.Errorf("failed to collect user %s: %w", userID, err)
+    }
+    if u.AnonymizedAt != nil || u.DeletionScheduledAt == nil || u.DeletionScheduledAt.After(time.Now()) {
+        return false, nil
+    }
+
+    // 2️⃣ Wait for orders in flight
+    var active bool
+    err = tx.QueryRow(ctx,
+        `SELECT EXISTS (SELECT 1 FROM order_vendors WHERE user_id = $1 AND `+activeOrderCondition+`)`, userID,
+    ).Scan(&active)
+    if err != nil {
+        return false, fmt.Errorf("failed to check active orders: %w", err)
+    }
+    if active {
+        _, err = tx.Exec(ctx, `
+            UPDATE users SET deletion_scheduled_at = NOW() + INTERVAL '1 day' WHERE id = $1
+        `, userID)
+        if err != nil {
+            return false, fmt.Errorf("failed to postpone deletion: %w", err)
+        }
+        return false, tx.Commit(ctx)
+    }
+
+    // 3️⃣ Profile
+    _, err = tx.Exec(ctx, `
+        UPDATE users
+        SET email = 'deleted-' || id || '@deleted.invalid',
+            username = 'deleted-' || id,
+            phone_number = NULL,
+            is_verified = FALSE,
+            password = NULL,
+            profile_picture = NULL,
+            last_sign_in_at = NULL,
+            two_factor_enabled = FALSE,
+            is_active = FALSE,
+            deleted_at = COALESCE(deleted_at, NOW()),
+            anonymized_at = NOW()
+        WHERE id = $1
+    `, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to anonymize user: %w", err)
+    }
+
+    // 4️⃣ Addresses: past orders still point at them, so they are kept with
+    // the name and phone removed and the location coarsened to ~1 km
+    _, err = tx.Exec(ctx, `
+        UPDATE user_addresses
+        SET first_name = 'Deleted',
+            last_name = 'User',
+            phone_number = NULL,
+            detail_address_direction = NULL,
+            latitude = ROUND(latitude, 2),
+            longitude = ROUND(longitude, 2),
+            is_default = FALSE
+        WHERE user_id = $1
+    `, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to anonymize addresses: %w", err)
+    }
+
+    // 5️⃣ Free text and contact details on orders
+    _, err = tx.Exec(ctx, `
+        UPDATE order_vendors
+        SET contact_phone = NULL,
+            delivery_instructions = NULL
+        WHERE user_id = $1
+    `, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to anonymize orders: %w", err)
+    }
+    _, err = tx.Exec(ctx, `
+        UPDATE order_items oi
+        SET special_instructions = NULL
+        FROM order_vendors ov
+        WHERE oi.order_vendor_id = ov.id AND ov.user_id = $1
+    `, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to anonymize order items: %w", err)
+    }
+    _, err = tx.Exec(ctx, `
+        UPDATE referrals
+        SET signup_phone = NULL,
+            signup_device_id = NULL
+        WHERE referee_id = $1
+    `, userID)
+    if err != nil {
+        return false, fmt.Errorf("failed to anonymize referral: %w", err)
+    }
+
+    // 6️⃣ Data with no record-keeping value; favorites are taken off the
+    // counters they were part of
+    for _, stmt := range []string{
+        `UPDATE vendors SET favorite_count = GREATEST(COALESCE(favorite_count, 0) - 1, 0)
+         WHERE id IN (SELECT entity_id FROM favorites WHERE user_id = $1 AND entity_type = 'restaurant')`,
+        `UPDATE menu_item_stats SET favorite_count = GREATEST(COALESCE(favorite_count, 0) - 1, 0)
+         WHERE menu_item_id IN (SELECT entity_id FROM favorites WHERE user_id = $1 AND entity_type = 'menu_item')`,
+        `DELETE FROM favorites WHERE user_id = $1`,
+        `DELETE FROM cart_sessions WHERE user_id = $1`,
+        `DELETE FROM user_2fa_tokens WHERE user_id = $1`,
+        `DELETE FROM referral_codes WHERE user_id = $1`,
+        `DELETE FROM order_notifications WHERE recipient_type = 'user' AND recipient_id = $1`,
+        `DELETE FROM notification_preferences WHERE user_id = $1`,
+        `DELETE FROM push_subscriptions WHERE user_id = $1`,
+        `DELETE FROM vendor_members WHERE user_id = $1`,
+    } {
+        if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+            return false, fmt.Errorf("failed to delete personal data: %w", err)
+        }
+    }
+
+    // the email log is keyed by address; a suppression is kept so a bounced
+    // or complaining address is not emailed again if it signs up anew
+    if _, err := tx.Exec(ctx, `DELETE FROM email_log WHERE LOWER(to_address) = LOWER($1)`, u.Email); err != nil {
+        return false, fmt.Errorf("failed to delete email log: %w", err)
+    }
+
+    // invoices keep the customer's name and email: they are tax records
+    // and must be retained as issued
+
+    if err := tx.Commit(ctx); err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+// GetLoyaltyHistory returns every loyalty ledger entry of a user, newest
+// first.
+func (r *UserRepository) GetLoyaltyHistory(ctx context.Context, userID string) ([]user.LoyaltyPointsLedger, error) {
+    rows, err := r.server.DB.Pool.Query(ctx, `
+        SELECT * FROM loyalty_points_ledger
+        WHERE user_id = $1
+        ORDER BY performed_at DESC
+    `, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query loyalty history: %w", err)
+    }
+    return pgx.CollectRows(rows, pgx.RowToStructByName[user.LoyaltyPointsLedger])
+}
+
+
+
+//-- ==================================================
+//--  PHONE VERIFICATION
+//-- ==================================================
+
+var (
+	ErrOTPNotFound        = errors.New("no verification code was requested")
+	ErrOTPExpired         = errors.New("verification code expired")
+	ErrOTPInvalid         = errors.New("verification code is wrong")
+	ErrOTPTooManyAttempts = errors.New("too many wrong verification codes")
+	ErrPhoneNumberTaken   = errors.New("phone number belongs to another account")
+)
+
+func (r *UserRepository) IsPhoneNumberTaken(ctx context.Context, userID, phone string) (bool, error) {
+    var taken bool
+    err := r.server.DB.Pool.QueryRow(ctx,
+        `SELECT EXISTS (SELECT 1 FROM users WHERE phone_number = $1 AND id <> $2)`, phone, userID,
+    ).Scan(&taken)
+    if err != nil {
+        return false, fmt.Errorf("failed to check phone number: %w", err)
+    }
+    return taken, nil
+}
+
+// IssuePhoneOTP stores a new code for the user; codes issued before it stop
+// working.
+func (r *UserRepository) IssuePhoneOTP(ctx context.Context, userID, phone, codeHash string, ttl time.Duration) (*user.TwoFactorToken, error) {
+    tx, err := r.server.DB.Pool.Begin(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    _, err = tx.Exec(ctx, `
+        UPDATE user_2fa_tokens SET used = TRUE
+        WHERE user_id = @userId AND token_type = @tokenType AND used = FALSE
+    `, pgx.NamedArgs{"userId": userID, "tokenType": user.TokenTypePhoneOTP})
+    if err != nil {
+        return nil, fmt.Errorf("failed to revoke previous codes: %w", err)
+    }
+
+    rows, err := tx.Query(ctx, `
+        INSERT INTO user_2fa_tokens (user_id, token, token_type, phone_number, expires_at)
+        VALUES (@userId, @token, @tokenType, @phone, @expiresAt)
+        RETURNING *
+    `, pgx.NamedArgs{
+        "userId":    userID,
+        "token":     codeHash,
+        "tokenType": user.TokenTypePhoneOTP,
+        "phone":     phone,
+        "expiresAt": time.Now().Add(ttl),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to insert code: %w", err)
+    }
+    token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.TwoFactorToken])
+    if err != nil {
+        return nil, fmt.Errorf("failed to collect code: %w", err)
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return nil, err
+    }
+    return &token, nil
+}
+
+// VerifyPhoneOTP checks a code against the user's latest one. A wrong code
+// counts against the attempt limit; the right one marks the number the code
+// was sent to as the user's verified number.
+func (r *UserRepository) VerifyPhoneOTP(ctx context.Context, userID, codeHash string, maxAttempts int) (*user.User, error) {
+    tx, err := r.server.DB.Pool.Begin(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    // 1️⃣ Latest open code, locked against concurrent guesses
+    rows, err := tx.Query(ctx, `
+        SELECT * FROM user_2fa_tokens
+        WHERE user_id = @userId AND token_type = @tokenType AND used = FALSE
+        ORDER BY created_at DESC
+        LIMIT 1
+        FOR UPDATE
+    `, pgx.NamedArgs{"userId": userID, "tokenType": user.TokenTypePhoneOTP})
+    if err != nil {
+        return nil, fmt.Errorf("failed to query code: %w", err)
+    }
+    token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.TwoFactorToken])
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, ErrOTPNotFound
+        }
+        return nil, fmt.Errorf("failed to collect code: %w", err)
+    }
+
+    // 2️⃣ Expiry and attempt limit
+    if time.Now().After(token.ExpiresAt) {
+        return nil, ErrOTPExpired
+    }
+    if token.Attempts >= maxAttempts {
+        return nil, ErrOTPTooManyAttempts
+    }
+
+    // 3️⃣ Compare; a miss is committed so it counts
+    if subtle.ConstantTimeCompare([]byte(token.Token), []byte(codeHash)) != 1 {
+        if _, err := tx.Exec(ctx, `UPDATE user_2fa_tokens SET attempts = attempts + 1 WHERE id = $1`, token.ID); err != nil {
+            return nil, fmt.Errorf("failed to count attempt: %w", err)
+        }
+        if err := tx.Commit(ctx); err != nil {
+            return nil, err
+        }
+        if token.Attempts+1 >= maxAttempts {
+            return nil, ErrOTPTooManyAttempts
+        }
+        return nil, ErrOTPInvalid
+    }
+
+    // 4️⃣ Use the code and take over the number
+    if _, err := tx.Exec(ctx, `UPDATE user_2fa_tokens SET used = TRUE WHERE id = $1`, token.ID); err != nil {
+        return nil, fmt.Errorf("failed to use code: %w", err)
+    }
+
+    rows, err = tx.Query(ctx, `
+        UPDATE users
+        SET phone_number = @phone,
+            is_verified = TRUE
+        WHERE id = @userId
+        RETURNING *
+    `, pgx.NamedArgs{"userId": userID, "phone": token.PhoneNumber})
+    if err != nil {
+        return nil, fmt.Errorf("failed to verify phone number: %w", err)
+    }
+    u, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[user.User])
+    if err != nil {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+            return nil, ErrPhoneNumberTaken
+        }
+        return nil, fmt.Errorf("failed to collect user: %w", err)
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return nil, err
+    }
+    return &u, nil
+}
+
+// GetVerifiedPhoneNumber returns the user's number if it was verified.
+func (r *UserRepository) GetVerifiedPhoneNumber(ctx context.Context, userID string) (*string, error) {
+    var phone *string
+    err := r.server.DB.Pool.QueryRow(ctx,
+        `SELECT phone_number FROM users WHERE id = $1 AND is_verified = TRUE`, userID,
+    ).Scan(&phone)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, nil
+        }
+        return nil, fmt.Errorf("failed to get verified phone number: %w", err)
+    }
+    return phone, nil
+}
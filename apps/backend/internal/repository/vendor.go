@@ -148,7 +148,9 @@ func (r *VendorRepository) UpdateVendor(ctx context.Context, payload *vendor.Upd
 	return &updatedVendor, nil
 }
 
-func (r *VendorRepository) GetVendorByUserID(ctx context.Context, vendorUserID string) (*vendor.VendorWithAddress, error) {
+// GetVendorWithAddress returns a vendor and its first address, nil when the
+// vendor does not exist.
+func (r *VendorRepository) GetVendorWithAddress(ctx context.Context, vendorID string) (*vendor.VendorWithAddress, error) {
 
 	var result vendor.VendorWithAddress
 
@@ -156,10 +158,10 @@ func (r *VendorRepository) GetVendorByUserID(ctx context.Context, vendorUserID s
 	queryVendor := `
 		SELECT *
 		FROM vendors
-		WHERE vendor_user_id = $1
+		WHERE id = $1
 	`
 
-	vendorRow,err := r.server.DB.Pool.Query(ctx, queryVendor, vendorUserID)
+	vendorRow,err := r.server.DB.Pool.Query(ctx, queryVendor, vendorID)
     if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -228,7 +230,8 @@ func (r *VendorRepository) GetVendors(ctx context.Context, query *vendor.GetVend
 	stmt := `SELECT * FROM vendors`
 	args := pgx.NamedArgs{}
 
-	conditions := []string{}
+	// only active vendors are listed
+	conditions := []string{"status = 'active'"}
 
 	 if query.Search != nil {
 		conditions = append(conditions, "(name ILIKE @search OR about ILIKE @search OR cuisine ILIKE @search)")
@@ -344,11 +347,15 @@ func (r *VendorRepository) GetVendorByID(ctx context.Context, payload *vendor.Ge
 		) AS categories
 		FROM vendors v
 		LEFT JOIN vendor_addresses va ON v.id = va.vendor_id
-		LEFT JOIN vendor_menu_categories vmc ON vmc.vendor_id = v.id
+		LEFT JOIN LATERAL (
+			SELECT category_id FROM vendor_menu_categories WHERE vendor_id = v.id
+			UNION
+			SELECT category_id FROM brand_menu_categories WHERE brand_id = v.brand_id
+		) vmc ON true
 		LEFT JOIN menu_categories mc ON mc.id = vmc.category_id
 		LEFT JOIN LATERAL (
 			SELECT jsonb_agg(to_jsonb(camel(mi.*))) AS menu_items
-			FROM menu_items mi
+			FROM vendor_menu_items mi
 			WHERE mi.category_id = mc.id AND mi.vendor_id =  @VendorID
 		) mi ON true
 		WHERE v.id = @VendorID
@@ -586,15 +593,6 @@ func (r *VendorRepository) BulkInsertMenuData(
 // ---------------- Vendor Address ----------------
 
 func (r *VendorRepository) CreateVendorAddress(ctx context.Context, payload *vendor.CreateVendorAddressPayload) (*vendor.VendorAddress, error) {
-    var vendorID string
-	err := r.server.DB.Pool.QueryRow(ctx, `
-        SELECT id FROM vendors WHERE vendor_user_id = $1
-    `, payload.VendorUserID).Scan(&vendorID)
-    if err != nil {
-        return nil, fmt.Errorf("failed to lookup vendor by user id: %w", err)
-    }
-
-
 	stmt := `
 		INSERT INTO vendor_addresses (
 			id, vendor_id, street_address, city, state, zipcode, latitude, longitude
@@ -608,7 +606,7 @@ func (r *VendorRepository) CreateVendorAddress(ctx context.Context, payload *ven
 
 	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
 		"ID":           nil,
-		"VendorID":    vendorID,
+		"VendorID":    payload.VendorID,
 		"StreetAddress": payload.StreetAddress,
 		"City":         payload.City,
 		"State":        payload.State,
@@ -824,70 +822,213 @@ func (r *VendorRepository) GetMenuItemByID(ctx context.Context, id string) (*ven
 //-- ==================================================
 
 
-// CreateFavorite
-func (r *VendorRepository) CreateFavorite(ctx context.Context, payload *vendor.CreateFavoritePayload) (*vendor.Favorite, error) {
-	stmt := `
-		INSERT INTO favorites (id, user_id, entity_type, entity_id)
-		VALUES (COALESCE(@ID, gen_random_uuid()), @UserID, @EntityType, @EntityID)
-		RETURNING *
-	`
+var ErrFavoriteEntityNotFound = errors.New("favorited entity not found")
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"ID":         nil,
-		"UserID":     payload.UserID,
-		"EntityType": payload.EntityType,
-		"EntityID":   payload.EntityID,
+// AddFavorite favorites a vendor or menu item for the user and bumps its
+// favorite_count in the same transaction. Favoriting twice is a no-op.
+func (r *VendorRepository) AddFavorite(ctx context.Context, userID string, payload *vendor.AddFavoritePayload) (*vendor.FavoriteState, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ The entity must exist; favorites has no foreign key to check it
+	exists, err := favoriteEntityExists(ctx, tx, payload.EntityType, payload.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrFavoriteEntityNotFound
+	}
+
+	// 2️⃣ Insert; the unique key makes a repeat a no-op
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO favorites (user_id, entity_type, entity_id)
+		VALUES (@userId, @entityType, @entityId)
+		ON CONFLICT (user_id, entity_type, entity_id) DO NOTHING
+	`, pgx.NamedArgs{
+		"userId":     userID,
+		"entityType": payload.EntityType,
+		"entityId":   payload.EntityID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create favorite: %w", err)
 	}
 
-	fav, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Favorite])
+	// 3️⃣ Count it only when it was new
+	delta := 0
+	if tag.RowsAffected() > 0 {
+		delta = 1
+	}
+	count, err := adjustFavoriteCount(ctx, tx, payload.EntityType, payload.EntityID, delta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect favorite row: %w", err)
+		return nil, err
 	}
 
-	return &fav, nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &vendor.FavoriteState{
+		EntityType:    payload.EntityType,
+		EntityID:      payload.EntityID,
+		IsFavorite:    true,
+		FavoriteCount: count,
+	}, nil
 }
 
-// UpdateFavorite
-func (r *VendorRepository) UpdateFavorite(ctx context.Context, payload *vendor.UpdateFavoritePayload) (*vendor.Favorite, error) {
-	stmt := `
-		UPDATE favorites SET
-			user_id = COALESCE(@UserID, user_id),
-			entity_type = COALESCE(@EntityType, entity_type),
-			entity_id = COALESCE(@EntityID, entity_id),
-			updated_at = NOW()
-		WHERE id = @ID
-		RETURNING *
-	`
+// RemoveFavorite unfavorites a vendor or menu item and lowers its
+// favorite_count in the same transaction. Removing a missing favorite is a
+// no-op.
+func (r *VendorRepository) RemoveFavorite(ctx context.Context, userID string, payload *vendor.RemoveFavoritePayload) (*vendor.FavoriteState, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"ID":         payload.ID,
-		"UserID":     payload.UserID,
-		"EntityType": payload.EntityType,
-		"EntityID":   payload.EntityID,
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM favorites
+		WHERE user_id = @userId AND entity_type = @entityType AND entity_id = @entityId
+	`, pgx.NamedArgs{
+		"userId":     userID,
+		"entityType": payload.EntityType,
+		"entityId":   payload.EntityID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update favorite: %w", err)
+		return nil, fmt.Errorf("failed to delete favorite: %w", err)
+	}
+
+	delta := 0
+	if tag.RowsAffected() > 0 {
+		delta = -1
+	}
+	count, err := adjustFavoriteCount(ctx, tx, payload.EntityType, payload.EntityID, delta)
+	if err != nil {
+		return nil, err
 	}
 
-	fav, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Favorite])
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &vendor.FavoriteState{
+		EntityType:    payload.EntityType,
+		EntityID:      payload.EntityID,
+		IsFavorite:    false,
+		FavoriteCount: count,
+	}, nil
+}
+
+// GetFavorites lists the user's favorites, newest first, with the entity
+// each one points at.
+func (r *VendorRepository) GetFavorites(ctx context.Context, userID string, query *vendor.GetFavoritesQuery) (*model.PaginatedResponse[vendor.PopulatedFavorite], error) {
+	args := pgx.NamedArgs{
+		"userId": userID,
+		"limit":  *query.Limit,
+		"offset": (*query.Page - 1) * (*query.Limit),
+	}
+	condition := ""
+	if query.EntityType != nil {
+		condition = " AND f.entity_type = @entityType"
+		args["entityType"] = *query.EntityType
+	}
+
+	var total int
+	countStmt := `SELECT COUNT(*) FROM favorites f WHERE f.user_id = @userId` + condition
+	if err := r.server.DB.Pool.QueryRow(ctx, countStmt, args).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count favorites: %w", err)
+	}
+
+	stmt := `
+		SELECT
+			f.*,
+			CASE WHEN v.id IS NULL THEN NULL ELSE camel(to_jsonb(v.*)) END AS vendor,
+			CASE
+				WHEN mi.id IS NOT NULL THEN camel(to_jsonb(mi.*))
+				WHEN base.id IS NOT NULL THEN camel(to_jsonb(base.*))
+			END AS menu_item
+		FROM favorites f
+		LEFT JOIN menu_items base
+			ON f.entity_type = 'menu_item' AND base.id = f.entity_id
+		-- a brand's master item has no vendor_id; show it as its first
+		-- outlet sells it
+		LEFT JOIN LATERAL (
+			SELECT vmi.*
+			FROM vendor_menu_items vmi
+			JOIN vendors ov ON ov.id = vmi.vendor_id
+			WHERE vmi.id = base.id
+			ORDER BY ov.created_at
+			LIMIT 1
+		) mi ON TRUE
+		LEFT JOIN vendors v
+			ON v.id = CASE WHEN f.entity_type = 'restaurant' THEN f.entity_id ELSE mi.vendor_id END
+		WHERE f.user_id = @userId` + condition + `
+		ORDER BY f.created_at DESC
+		LIMIT @limit OFFSET @offset
+	`
+	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect favorite row: %w", err)
+		return nil, fmt.Errorf("failed to query favorites: %w", err)
+	}
+	favorites, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.PopulatedFavorite])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect favorites: %w", err)
 	}
 
-	return &fav, nil
+	return &model.PaginatedResponse[vendor.PopulatedFavorite]{
+		Data:       favorites,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
 }
 
-// DeleteFavorite
-func (r *VendorRepository ) DeleteFavorite(ctx context.Context, payload *vendor.DeleteFavoritePayload) error {
-	stmt := `DELETE FROM favorites WHERE id = @ID`
-	_, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{"ID": payload.ID})
+func favoriteEntityExists(ctx context.Context, tx pgx.Tx, entityType, entityID string) (bool, error) {
+	table := "vendors"
+	if entityType == vendor.FavoriteMenuItem {
+		table = "menu_items"
+	}
+	var exists bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, entityID).Scan(&exists)
 	if err != nil {
-		return fmt.Errorf("failed to delete favorite %s: %w", payload.ID, err)
+		return false, fmt.Errorf("failed to check %s %s: %w", entityType, entityID, err)
 	}
-	return nil
+	return exists, nil
+}
+
+// adjustFavoriteCount moves an entity's favorite_count by delta (0 just
+// reads it) and returns the new value. The row lock it takes serializes
+// concurrent (un)favorites of the same entity.
+func adjustFavoriteCount(ctx context.Context, tx pgx.Tx, entityType, entityID string, delta int) (int, error) {
+	var stmt string
+	if entityType == vendor.FavoriteMenuItem {
+		stmt = `
+			INSERT INTO menu_item_stats (menu_item_id, favorite_count)
+			VALUES (@id, GREATEST(@delta, 0))
+			ON CONFLICT (menu_item_id) DO UPDATE
+			SET favorite_count = GREATEST(COALESCE(menu_item_stats.favorite_count, 0) + @delta, 0)
+			RETURNING favorite_count
+		`
+	} else {
+		stmt = `
+			UPDATE vendors
+			SET favorite_count = GREATEST(COALESCE(favorite_count, 0) + @delta, 0)
+			WHERE id = @id
+			RETURNING favorite_count
+		`
+	}
+
+	var count int
+	err := tx.QueryRow(ctx, stmt, pgx.NamedArgs{"id": entityID, "delta": delta}).Scan(&count)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the vendor is gone; nothing left to count
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to update favorite count: %w", err)
+	}
+	return count, nil
 }
 
 
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrVendorMemberNotFound    = errors.New("vendor member not found")
+	ErrAlreadyVendorMember     = errors.New("user is already a member of this vendor")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationExpired       = errors.New("invitation expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to another email address")
+)
+
+// ---------------- VENDOR MEMBER REPOSITORY ----------------
+
+type VendorMemberRepository struct {
+	server *server.Server
+}
+
+func NewVendorMemberRepository(s *server.Server) *VendorMemberRepository {
+	return &VendorMemberRepository{server: s}
+}
+
+//-- ==================================================
+//-- MEMBERS
+//-- ==================================================
+
+// ListMemberships returns the vendors a user works at, the owned one first.
+func (r *VendorMemberRepository) ListMemberships(ctx context.Context, userID string) ([]vendor.Membership, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT m.vendor_id, v.name AS vendor_name, v.status AS vendor_status,
+		       v.vendor_user_id AS owner_id, m.role
+		FROM vendor_members m
+		JOIN vendors v ON v.id = m.vendor_id
+		WHERE m.user_id = $1
+		ORDER BY m.role = 'owner' DESC, m.created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+	memberships, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.Membership])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect memberships: %w", err)
+	}
+	for i := range memberships {
+		memberships[i].Permissions = vendor.RolePermissions(memberships[i].Role)
+	}
+	return memberships, nil
+}
+
+func (r *VendorMemberRepository) ListMembers(ctx context.Context, vendorID string) ([]vendor.PopulatedVendorMember, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT m.*, u.email, u.username, u.profile_picture
+		FROM vendor_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.vendor_id = $1
+		ORDER BY m.role = 'owner' DESC, m.created_at
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vendor members: %w", err)
+	}
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.PopulatedVendorMember])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor members: %w", err)
+	}
+	return members, nil
+}
+
+func (r *VendorMemberRepository) GetMember(ctx context.Context, vendorID, memberID string) (*vendor.VendorMember, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM vendor_members WHERE id = $1 AND vendor_id = $2
+	`, memberID, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendor member: %w", err)
+	}
+	m, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorMember])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor member: %w", err)
+	}
+	return &m, nil
+}
+
+// UpdateMemberRole changes a staff member's role; the owner's role is fixed.
+func (r *VendorMemberRepository) UpdateMemberRole(ctx context.Context, vendorID, memberID, role string) (*vendor.VendorMember, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		UPDATE vendor_members SET role = $3
+		WHERE id = $1 AND vendor_id = $2 AND role <> 'owner'
+		RETURNING *
+	`, memberID, vendorID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update vendor member: %w", err)
+	}
+	m, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorMember])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor member: %w", err)
+	}
+	return &m, nil
+}
+
+// RemoveMember takes a staff member off the vendor; the owner cannot be
+// removed.
+func (r *VendorMemberRepository) RemoveMember(ctx context.Context, vendorID, memberID string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM vendor_members WHERE id = $1 AND vendor_id = $2 AND role <> 'owner'
+	`, memberID, vendorID)
+	if err != nil {
+		return fmt.Errorf("failed to remove vendor member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVendorMemberNotFound
+	}
+	return nil
+}
+
+// LeaveVendor removes the user's own staff membership.
+func (r *VendorMemberRepository) LeaveVendor(ctx context.Context, vendorID, userID string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		DELETE FROM vendor_members WHERE vendor_id = $1 AND user_id = $2 AND role <> 'owner'
+	`, vendorID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to leave vendor: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVendorMemberNotFound
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- INVITATIONS
+//-- ==================================================
+
+// CreateInvitation invites an email address to the vendor, replacing any
+// invitation still pending for it.
+func (r *VendorMemberRepository) CreateInvitation(ctx context.Context, inv *vendor.VendorInvitation) (*vendor.VendorInvitation, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var isMember bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM vendor_members m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.vendor_id = $1 AND LOWER(u.email) = LOWER($2)
+		)
+	`, inv.VendorID, inv.Email).Scan(&isMember)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check vendor membership: %w", err)
+	}
+	if isMember {
+		return nil, ErrAlreadyVendorMember
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE vendor_invitations SET status = 'revoked'
+		WHERE vendor_id = $1 AND LOWER(email) = LOWER($2) AND status = 'pending'
+	`, inv.VendorID, inv.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace pending invitation: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO vendor_invitations (vendor_id, email, role, token_hash, invited_by, expires_at)
+		VALUES (@vendorId, @email, @role, @tokenHash, @invitedBy, @expiresAt)
+		RETURNING *
+	`, pgx.NamedArgs{
+		"vendorId":  inv.VendorID,
+		"email":     inv.Email,
+		"role":      inv.Role,
+		"tokenHash": inv.TokenHash,
+		"invitedBy": inv.InvitedBy,
+		"expiresAt": inv.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorInvitation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invitation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListInvitations returns the vendor's pending invitations, newest first.
+func (r *VendorMemberRepository) ListInvitations(ctx context.Context, vendorID string) ([]vendor.VendorInvitation, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM vendor_invitations
+		WHERE vendor_id = $1 AND status = 'pending'
+		ORDER BY created_at DESC
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	invitations, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.VendorInvitation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (r *VendorMemberRepository) GetInvitation(ctx context.Context, vendorID, invitationID string) (*vendor.VendorInvitation, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT * FROM vendor_invitations WHERE id = $1 AND vendor_id = $2
+	`, invitationID, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	inv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorInvitation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invitation: %w", err)
+	}
+	return &inv, nil
+}
+
+func (r *VendorMemberRepository) RevokeInvitation(ctx context.Context, vendorID, invitationID string) error {
+	tag, err := r.server.DB.Pool.Exec(ctx, `
+		UPDATE vendor_invitations SET status = 'revoked'
+		WHERE id = $1 AND vendor_id = $2 AND status = 'pending'
+	`, invitationID, vendorID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes the user a member with the invited role. The
+// invitation must be pending, unexpired and addressed to the user's email.
+func (r *VendorMemberRepository) AcceptInvitation(ctx context.Context, tokenHash, userID string) (*vendor.VendorMember, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ Lock the invitation
+	rows, err := tx.Query(ctx, `
+		SELECT * FROM vendor_invitations WHERE token_hash = $1 FOR UPDATE
+	`, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	inv, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorInvitation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect invitation: %w", err)
+	}
+	if inv.Status != vendor.InvitationPending {
+		return nil, ErrInvitationNotFound
+	}
+	if time.Now().After(inv.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+
+	// 2️⃣ Check it was sent to this user
+	var matches bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND LOWER(email) = LOWER($2))
+	`, userID, inv.Email).Scan(&matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check invitation email: %w", err)
+	}
+	if !matches {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	// 3️⃣ Join the vendor
+	rows, err = tx.Query(ctx, `
+		INSERT INTO vendor_members (vendor_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (vendor_id, user_id) DO NOTHING
+		RETURNING *
+	`, inv.VendorID, userID, inv.Role, inv.InvitedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add vendor member: %w", err)
+	}
+	m, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorMember])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadyVendorMember
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor member: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE vendor_invitations
+		SET status = 'accepted', accepted_by = $2, accepted_at = NOW()
+		WHERE id = $1
+	`, inv.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark invitation accepted: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetInvitationContext returns what the invitation email needs: the
+// vendor's name and who sent it.
+func (r *VendorMemberRepository) GetInvitationContext(ctx context.Context, vendorID, inviterID string) (vendorName, inviterName string, err error) {
+	err = r.server.DB.Pool.QueryRow(ctx, `
+		SELECT v.name, COALESCE((SELECT username FROM users WHERE id = $2), '')
+		FROM vendors v WHERE v.id = $1
+	`, vendorID, inviterID).Scan(&vendorName, &inviterName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", ErrVendorNotFound
+	}
+	return vendorName, inviterName, err
+}
@@ -0,0 +1,524 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrVendorNotFound          = errors.New("vendor not found")
+	ErrVendorDocumentNotFound  = errors.New("vendor document not found")
+	ErrInvalidVendorTransition = errors.New("vendor status does not allow this action")
+	ErrMissingVendorDocuments  = errors.New("required vendor documents are missing or rejected")
+	ErrVendorMenuEmpty         = errors.New("vendor has no available menu items")
+)
+
+// ---------------- VENDOR VERIFICATION REPOSITORY ----------------
+
+type VendorVerificationRepository struct {
+	server *server.Server
+}
+
+func NewVendorVerificationRepository(s *server.Server) *VendorVerificationRepository {
+	return &VendorVerificationRepository{server: s}
+}
+
+//-- ==================================================
+//-- VENDORS
+//-- ==================================================
+
+func (r *VendorVerificationRepository) GetVendor(ctx context.Context, vendorID string) (*vendor.Vendor, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM vendors WHERE id = $1`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendor: %w", err)
+	}
+	v, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Vendor])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor: %w", err)
+	}
+	return &v, nil
+}
+
+// GetOwnedVendor returns the vendor if vendorUserID owns it. An owner of a
+// brand owns several vendors, one per outlet, and shares documents across them.
+func (r *VendorVerificationRepository) GetOwnedVendor(ctx context.Context, vendorID, vendorUserID string) (*vendor.Vendor, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `SELECT * FROM vendors WHERE id = $1 AND vendor_user_id = $2`, vendorID, vendorUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendor: %w", err)
+	}
+	v, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Vendor])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor: %w", err)
+	}
+	return &v, nil
+}
+
+// GetOwnerEmail returns where verification emails go; empty when the owner
+// has been anonymized.
+func (r *VendorVerificationRepository) GetOwnerEmail(ctx context.Context, vendorID string) (string, error) {
+	var email *string
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		SELECT CASE WHEN u.anonymized_at IS NULL THEN u.email END
+		FROM vendors v
+		JOIN users u ON u.id = v.vendor_user_id
+		WHERE v.id = $1
+	`, vendorID).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrVendorNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get vendor owner email: %w", err)
+	}
+	if email == nil {
+		return "", nil
+	}
+	return *email, nil
+}
+
+//-- ==================================================
+//-- STATUS TRANSITIONS
+//-- ==================================================
+
+// Transition moves a vendor along the verification state machine and logs
+// the change, under a lock on the vendor so concurrent reviews cannot both
+// apply. Submitting needs every required document uploaded and none
+// rejected; going live needs an available menu item. It returns the
+// vendor as updated.
+func (r *VendorVerificationRepository) Transition(ctx context.Context, t *vendor.Transition) (*vendor.Vendor, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1️⃣ Lock the vendor and check the move is allowed from its status
+	var from, vendorUserID string
+	err = tx.QueryRow(ctx, `
+		SELECT status, vendor_user_id FROM vendors WHERE id = $1 FOR UPDATE
+	`, t.VendorID).Scan(&from, &vendorUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock vendor: %w", err)
+	}
+
+	to, ok := vendor.NextStatus(t.Action, from)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot %s a vendor that is %s", ErrInvalidVendorTransition, t.Action, from)
+	}
+
+	// 2️⃣ Preconditions and document side effects
+	switch t.Action {
+	case vendor.ActionSubmitted:
+		var ready int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(DISTINCT document_type)
+			FROM vendor_documents
+			WHERE vendor_user_id = $1 AND document_type = ANY($2) AND status <> 'rejected'
+		`, vendorUserID, vendor.RequiredDocuments).Scan(&ready)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check vendor documents: %w", err)
+		}
+		if ready < len(vendor.RequiredDocuments) {
+			return nil, ErrMissingVendorDocuments
+		}
+
+	case vendor.ActionApproved:
+		_, err := tx.Exec(ctx, `
+			UPDATE vendor_documents
+			SET status = 'approved', reviewed_at = NOW(), reviewed_by = @adminId
+			WHERE vendor_user_id = @vendorUserId AND status = 'pending'
+		`, pgx.NamedArgs{"vendorUserId": vendorUserID, "adminId": t.AdminID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to approve vendor documents: %w", err)
+		}
+
+	case vendor.ActionRequestedChanges:
+		if len(t.RejectDocumentIDs) > 0 {
+			tag, err := tx.Exec(ctx, `
+				UPDATE vendor_documents
+				SET status = 'rejected', remarks = @remarks, reviewed_at = NOW(), reviewed_by = @adminId
+				WHERE vendor_user_id = @vendorUserId AND id = ANY(@ids)
+			`, pgx.NamedArgs{
+				"vendorUserId": vendorUserID,
+				"ids":          t.RejectDocumentIDs,
+				"remarks":      t.Remarks,
+				"adminId":      t.AdminID,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to reject vendor documents: %w", err)
+			}
+			if int(tag.RowsAffected()) != len(t.RejectDocumentIDs) {
+				return nil, ErrVendorDocumentNotFound
+			}
+		}
+
+	case vendor.ActionSuspended:
+		// the daily check may race a renewal; only suspend what is still lapsed
+		if t.SuspensionReason == vendor.SuspensionCompliance {
+			lapsed, err := countLapsedDocuments(ctx, tx, vendorUserID)
+			if err != nil {
+				return nil, err
+			}
+			if lapsed == 0 {
+				return nil, fmt.Errorf("%w: vendor has no lapsed documents", ErrInvalidVendorTransition)
+			}
+		}
+
+	case vendor.ActionActivated:
+		var hasMenu bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM vendor_menu_items WHERE vendor_id = $1 AND is_available)
+		`, t.VendorID).Scan(&hasMenu)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check vendor menu: %w", err)
+		}
+		if !hasMenu {
+			return nil, ErrVendorMenuEmpty
+		}
+	}
+
+	// 3️⃣ Move the vendor and log it
+	rows, err := tx.Query(ctx, `
+		UPDATE vendors
+		SET status = @status,
+		    submitted_at = CASE WHEN @action = 'submitted' THEN NOW() ELSE submitted_at END,
+		    suspension_reason = CASE WHEN @action = 'suspended' THEN NULLIF(@reason, '') END
+		WHERE id = @id
+		RETURNING *
+	`, pgx.NamedArgs{"id": t.VendorID, "status": to, "action": t.Action, "reason": t.SuspensionReason})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update vendor status: %w", err)
+	}
+	updated, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.Vendor])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vendor_verification_logs (vendor_id, admin_id, action, from_status, to_status, remarks)
+		VALUES (@vendorId, @adminId, @action, @from, @to, @remarks)
+	`, pgx.NamedArgs{
+		"vendorId": t.VendorID,
+		"adminId":  t.AdminID,
+		"action":   t.Action,
+		"from":     from,
+		"to":       to,
+		"remarks":  t.Remarks,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to log vendor verification: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit vendor transition: %w", err)
+	}
+	return &updated, nil
+}
+
+func (r *VendorVerificationRepository) ListLogs(ctx context.Context, vendorID string) ([]vendor.VerificationLog, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT id, vendor_id, admin_id, action, from_status, to_status, remarks, created_at
+		FROM vendor_verification_logs
+		WHERE vendor_id = $1
+		ORDER BY created_at DESC
+	`, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verification logs: %w", err)
+	}
+	logs, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.VerificationLog])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect verification logs: %w", err)
+	}
+	return logs, nil
+}
+
+// GetVerificationQueue lists vendors in a status, the longest waiting first.
+func (r *VendorVerificationRepository) GetVerificationQueue(ctx context.Context, query *vendor.GetVerificationQueueQuery) (*model.PaginatedResponse[vendor.VerificationQueueItem], error) {
+	args := pgx.NamedArgs{
+		"status": *query.Status,
+		"limit":  *query.Limit,
+		"offset": (*query.Page - 1) * *query.Limit,
+	}
+
+	var total int
+	err := r.server.DB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM vendors WHERE status = @status`, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count verification queue: %w", err)
+	}
+
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT v.id AS vendor_id, v.name AS vendor_name, v.vendor_user_id,
+		       u.email AS owner_email, v.status, v.submitted_at,
+		       COUNT(d.id)::INT AS document_count,
+		       COUNT(d.id) FILTER (WHERE d.status = 'pending')::INT AS pending_documents,
+		       COUNT(d.id) FILTER (WHERE d.status = 'rejected')::INT AS rejected_documents
+		FROM vendors v
+		JOIN users u ON u.id = v.vendor_user_id
+		LEFT JOIN vendor_documents d ON d.vendor_user_id = v.vendor_user_id
+		WHERE v.status = @status
+		GROUP BY v.id, u.email
+		ORDER BY v.submitted_at ASC NULLS LAST, v.created_at ASC
+		LIMIT @limit OFFSET @offset
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification queue: %w", err)
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.VerificationQueueItem])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect verification queue: %w", err)
+	}
+
+	return &model.PaginatedResponse[vendor.VerificationQueueItem]{
+		Data:       items,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+//-- ==================================================
+//-- DOCUMENTS
+//-- ==================================================
+
+const documentColumns = `
+	id, vendor_user_id, document_type, file_key, content_type, file_size, document_number,
+	expiry_date, status, remarks, submitted_at, reviewed_at, reviewed_by, created_at, updated_at
+`
+
+func (r *VendorVerificationRepository) ListDocuments(ctx context.Context, vendorUserID string) ([]vendor.VendorDocument, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT `+documentColumns+`
+		FROM vendor_documents
+		WHERE vendor_user_id = $1
+		ORDER BY document_type
+	`, vendorUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vendor documents: %w", err)
+	}
+	docs, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.VendorDocument])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor documents: %w", err)
+	}
+	return docs, nil
+}
+
+// UpsertDocument stores the vendor's document of a type, replacing the
+// previous one and sending it back to review. It returns the new row and
+// the file key of the replaced document, if any, for the caller to delete.
+func (r *VendorVerificationRepository) UpsertDocument(ctx context.Context, doc *vendor.VendorDocument) (*vendor.VendorDocument, *string, error) {
+	tx, err := r.server.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previousKey *string
+	err = tx.QueryRow(ctx, `
+		SELECT file_key FROM vendor_documents
+		WHERE vendor_user_id = $1 AND document_type = $2
+		FOR UPDATE
+	`, doc.VendorUserID, doc.DocumentType).Scan(&previousKey)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, fmt.Errorf("failed to lock vendor document: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO vendor_documents (
+			vendor_user_id, document_type, file_key, content_type, file_size,
+			document_number, expiry_date, status, submitted_at
+		)
+		VALUES (
+			@vendorUserId, @documentType, @fileKey, @contentType, @fileSize,
+			@documentNumber, @expiryDate, 'pending', NOW()
+		)
+		ON CONFLICT (vendor_user_id, document_type) DO UPDATE
+		SET file_key = EXCLUDED.file_key,
+		    content_type = EXCLUDED.content_type,
+		    file_size = EXCLUDED.file_size,
+		    document_number = EXCLUDED.document_number,
+		    expiry_date = EXCLUDED.expiry_date,
+		    status = 'pending',
+		    remarks = NULL,
+		    submitted_at = NOW(),
+		    reviewed_at = NULL,
+		    reviewed_by = NULL
+		RETURNING `+documentColumns,
+		pgx.NamedArgs{
+			"vendorUserId":   doc.VendorUserID,
+			"documentType":   doc.DocumentType,
+			"fileKey":        doc.FileKey,
+			"contentType":    doc.ContentType,
+			"fileSize":       doc.FileSize,
+			"documentNumber": doc.DocumentNumber,
+			"expiryDate":     doc.ExpiryDate,
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save vendor document: %w", err)
+	}
+	saved, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorDocument])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect vendor document: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit vendor document: %w", err)
+	}
+	return &saved, previousKey, nil
+}
+
+// DeleteDocument removes a document and returns its file key.
+func (r *VendorVerificationRepository) DeleteDocument(ctx context.Context, vendorUserID, id string) (string, error) {
+	var fileKey string
+	err := r.server.DB.Pool.QueryRow(ctx, `
+		DELETE FROM vendor_documents WHERE id = $1 AND vendor_user_id = $2
+		RETURNING file_key
+	`, id, vendorUserID).Scan(&fileKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrVendorDocumentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to delete vendor document: %w", err)
+	}
+	return fileKey, nil
+}
+
+// ReviewDocument records an admin's decision on a single document.
+func (r *VendorVerificationRepository) ReviewDocument(ctx context.Context, vendorUserID, id, status string, remarks *string, adminID string) (*vendor.VendorDocument, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		UPDATE vendor_documents
+		SET status = @status, remarks = @remarks, reviewed_at = NOW(), reviewed_by = @adminId
+		WHERE id = @id AND vendor_user_id = @vendorUserId
+		RETURNING `+documentColumns,
+		pgx.NamedArgs{
+			"id":           id,
+			"vendorUserId": vendorUserID,
+			"status":       status,
+			"remarks":      remarks,
+			"adminId":      adminID,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to review vendor document: %w", err)
+	}
+	doc, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[vendor.VendorDocument])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVendorDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect vendor document: %w", err)
+	}
+	return &doc, nil
+}
+
+//-- ==================================================
+//-- COMPLIANCE
+//-- ==================================================
+
+func countLapsedDocuments(ctx context.Context, tx pgx.Tx, vendorUserID string) (int, error) {
+	var lapsed int
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM vendor_documents
+		WHERE vendor_user_id = $1 AND document_type = ANY($2) AND expiry_date < CURRENT_DATE
+	`, vendorUserID, vendor.ComplianceDocuments).Scan(&lapsed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check lapsed vendor documents: %w", err)
+	}
+	return lapsed, nil
+}
+
+// ListDueExpiryWarnings finds the documents of trading vendors due an expiry
+// reminder. A document is due the nearest warning (vendor.ExpiryWarningDays)
+// it is within and has not had yet; RecordExpiryWarning marks it sent once
+// the reminder is handed to the queue.
+func (r *VendorVerificationRepository) ListDueExpiryWarnings(ctx context.Context) ([]vendor.ExpiringDocument, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		WITH due AS (
+			-- outlets of a brand share the owner's documents; remind once
+			SELECT DISTINCT ON (d.id) d.id AS document_id, d.document_type, d.expiry_date,
+			       w.days_before, (d.expiry_date - CURRENT_DATE)::INT AS days_left,
+			       v.id AS vendor_id, v.name AS vendor_name,
+			       CASE WHEN u.anonymized_at IS NULL THEN u.email END AS owner_email
+			FROM vendor_documents d
+			JOIN vendors v ON v.vendor_user_id = d.vendor_user_id
+			JOIN users u ON u.id = v.vendor_user_id
+			CROSS JOIN LATERAL (
+				SELECT MIN(days) AS days_before
+				FROM unnest(@warningDays::INT[]) AS days
+				WHERE days >= d.expiry_date - CURRENT_DATE
+			) w
+			WHERE d.expiry_date >= CURRENT_DATE
+			  AND w.days_before IS NOT NULL
+			  AND d.status <> 'rejected'
+			  AND v.status IN ('verified', 'active')
+			ORDER BY d.id, v.created_at
+		)
+		SELECT due.*
+		FROM due
+		WHERE NOT EXISTS (
+			SELECT 1 FROM vendor_document_expiry_warnings ew
+			WHERE ew.document_id = due.document_id
+			  AND ew.expiry_date = due.expiry_date
+			  AND ew.days_before = due.days_before
+		)
+		ORDER BY due.vendor_id, due.document_type
+	`, pgx.NamedArgs{"warningDays": vendor.ExpiryWarningDays})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiry warnings: %w", err)
+	}
+	docs, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.ExpiringDocument])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect expiring documents: %w", err)
+	}
+	return docs, nil
+}
+
+// RecordExpiryWarning marks a reminder as sent so later runs skip it.
+func (r *VendorVerificationRepository) RecordExpiryWarning(ctx context.Context, d vendor.ExpiringDocument) error {
+	_, err := r.server.DB.Pool.Exec(ctx, `
+		INSERT INTO vendor_document_expiry_warnings (document_id, expiry_date, days_before)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (document_id, expiry_date, days_before) DO NOTHING
+	`, d.DocumentID, d.ExpiryDate, d.DaysBefore)
+	if err != nil {
+		return fmt.Errorf("failed to record expiry warning: %w", err)
+	}
+	return nil
+}
+
+// ListLapsedVendors returns the trading vendors with a compliance document
+// past its expiry date.
+func (r *VendorVerificationRepository) ListLapsedVendors(ctx context.Context) ([]vendor.LapsedVendor, error) {
+	rows, err := r.server.DB.Pool.Query(ctx, `
+		SELECT v.id AS vendor_id,
+		       array_agg(d.document_type ORDER BY d.document_type) AS lapsed_documents
+		FROM vendors v
+		JOIN vendor_documents d ON d.vendor_user_id = v.vendor_user_id
+		WHERE v.status IN ('verified', 'active')
+		  AND d.document_type = ANY($1)
+		  AND d.expiry_date < CURRENT_DATE
+		GROUP BY v.id
+	`, vendor.ComplianceDocuments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lapsed vendors: %w", err)
+	}
+	vendors, err := pgx.CollectRows(rows, pgx.RowToStructByName[vendor.LapsedVendor])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect lapsed vendors: %w", err)
+	}
+	return vendors, nil
+}
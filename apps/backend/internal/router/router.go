@@ -52,10 +52,11 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 	)
 
 	// register system routes
-	registerSystemRoutes(router, h)
+	registerSystemRoutes(router, s, h)
     // register webhook routes
 	webhookRoutes := router.Group("/api/webhooks")
 	webhookRoutes.POST("", h.Webhooks.HandleClerkWebhook) // POST /api/webhooks
+	webhookRoutes.POST("/resend", h.Webhooks.HandleResendWebhook) // POST /api/webhooks/resend
 	// register versioned routes
 	v1Router := router.Group("/api/v1")
 	v1.RegisterV1Routes(v1Router, h, middlewares)
@@ -2,14 +2,21 @@ package router
 
 import (
 	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/server"
 
 	"github.com/labstack/echo/v4"
 )
 
-func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
+func registerSystemRoutes(r *echo.Echo, s *server.Server, h *handler.Handlers) {
 	r.GET("/status", h.Health.CheckHealth)
 
 	r.Static("/static", "static")
 
 	r.GET("/docs", h.OpenAPI.ServeOpenAPIUI)
+
+	// email template previews, never in deployed environments
+	if s.Config.Primary.Env == "local" {
+		r.GET("/dev/emails", h.EmailPreview.ListTemplates)
+		r.GET("/dev/emails/:template", h.EmailPreview.PreviewTemplate)
+	}
 }
@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerAnalyticsRoutes(r *echo.Group, h *handler.AnalyticsHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Vendor -------------------
+	a := r.Group("/vendor-analytics")
+	a.Use(auth.RequireAuth)
+	a.GET("/me", h.GetMyAnalytics, auth.RequireVendorPermission(vendor.PermissionAnalyticsView)) // ?from=&to=&granularity=hour|day|week|month
+	a.GET("/brands/:brandId", h.GetBrandAnalytics)                                               // brand owner only
+
+	// ------------------- Admin -------------------
+	admin := a.Group("", auth.RequireAdmin)
+	admin.GET("/:vendorId", h.GetVendorAnalytics)
+}
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerBrandRoutes(r *echo.Group, h *handler.BrandHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Brands (owner only) -------------------
+	brands := r.Group("/brands")
+	brands.Use(auth.RequireAuth)
+	brands.POST("", h.CreateBrand)
+	brands.GET("/me", h.GetMyBrands)
+	brands.GET("/:id", h.GetBrand)
+	brands.PATCH("/:id", h.UpdateBrand)
+
+	// ------------------- Outlets -------------------
+	brands.POST("/:id/outlets", h.CreateOutlet)
+	brands.POST("/:id/outlets/attach", h.AttachOutlet) // body: vendorId of a standalone vendor
+
+	// ------------------- Master menu -------------------
+	brands.GET("/:id/menu-items", h.GetMasterMenu)
+	brands.POST("/:id/menu-items", h.CreateMasterMenuItem)
+	brands.PATCH("/:id/menu-items/:itemId", h.UpdateMasterMenuItem)
+	brands.DELETE("/:id/menu-items/:itemId", h.DeleteMasterMenuItem)
+
+	// ------------------- Outlet (X-Vendor-ID selects the outlet) -------------------
+	outlet := r.Group("/outlets/me")
+	outlet.Use(auth.RequireAuth)
+	outlet.PATCH("/settings", h.UpdateOutletSettings, auth.RequireVendorPermission(vendor.PermissionStoreEdit))
+	outlet.PUT("/payout-account", h.SetOutletPayoutAccount, auth.RequireVendorPermission(vendor.PermissionPayoutsManage))
+
+	overrides := outlet.Group("/menu-overrides", auth.RequireVendorPermission(vendor.PermissionMenuEdit))
+	overrides.GET("", h.GetMenuOverrides)
+	overrides.PUT("/:menuItemId", h.SetMenuOverride)
+	overrides.DELETE("/:menuItemId", h.DeleteMenuOverride)
+}
@@ -20,6 +20,7 @@ func registerCartRoutes(r *echo.Group, h *handler.CartHandler, auth *middleware.
 
 	carts.GET("/totals",h.GetCartTotals)
 	carts.POST("/apply-coupon",h.ApplyCoupon)
+	carts.PUT("/vendors/:id/loyalty-points", h.ApplyLoyaltyPoints)
 
 
 
@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerImageRoutes(r *echo.Group, h *handler.ImageHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Images (X-Vendor-ID selects the vendor) -------------------
+	images := r.Group("/images", auth.RequireAuth)
+	images.POST("/menu-items/:menuItemId", h.UploadMenuItemImage, auth.RequireVendorPermission(vendor.PermissionMenuEdit)) // multipart: file
+	images.POST("/vendor/:slot", h.UploadVendorImage, auth.RequireVendorPermission(vendor.PermissionStoreEdit))            // slot: listing | logo
+
+	// direct uploads: presign, the client sends the file to S3, then completes
+	images.POST("/uploads", h.CreateUpload, auth.RequireVendorPermission(vendor.PermissionMenuEdit))
+	images.POST("/menu-items/:menuItemId/uploads", h.CreateMenuItemUpload, auth.RequireVendorPermission(vendor.PermissionMenuEdit))
+	images.POST("/vendor/:slot/uploads", h.CreateVendorUpload, auth.RequireVendorPermission(vendor.PermissionStoreEdit))
+	images.POST("/uploads/:id/complete", h.CompleteUpload, auth.RequireVendorPermission(""))
+}
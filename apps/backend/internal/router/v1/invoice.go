@@ -0,0 +1,17 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerInvoiceRoutes(r *echo.Group, h *handler.InvoiceHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Invoices -------------------
+	invoices := r.Group("/invoices")
+	invoices.Use(auth.RequireAuth)
+	invoices.GET("/orders/:id", h.GetInvoice)
+	invoices.GET("/orders/:id/pdf", h.DownloadInvoicePDF)
+	invoices.GET("/orders/:id/html", h.DownloadInvoiceHTML)
+}
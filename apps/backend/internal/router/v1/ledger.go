@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerLedgerRoutes(r *echo.Group, h *handler.LedgerHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Ledger -------------------
+	ledger := r.Group("/ledger")
+	ledger.Use(auth.RequireAuth)
+	ledger.GET("/me/balance", h.GetMyBalance, auth.RequireVendorPermission(vendor.PermissionPayoutsView))
+
+	// ------------------- Finance (admin) -------------------
+	finance := ledger.Group("", auth.RequireAdmin)
+	finance.GET("/trial-balance", h.GetTrialBalance)
+	finance.GET("/accounts", h.GetAccountBalances)
+	finance.GET("/vendors/:vendorUserId/balance", h.GetVendorBalance)
+	finance.GET("/invariants", h.CheckInvariants)
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerLoyaltyRoutes(r *echo.Group, h *handler.LoyaltyHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Loyalty program -------------------
+	program := r.Group("/loyalty/program")
+	program.Use(auth.RequireAuth)
+	program.GET("/me", h.GetMyStatus) // GET /loyalty/program/me (tier, balance, expiring points)
+	program.GET("/tiers", h.GetTiers)
+	program.GET("/promotions", h.GetActivePromotions)
+
+	// ------------------- Earning rules (admin) -------------------
+	admin := program.Group("/admin", auth.RequireAdmin)
+	admin.GET("/settings", h.GetSettings)
+	admin.PATCH("/settings", h.UpdateSettings)
+	admin.GET("/promotions", h.GetPromotions)
+	admin.POST("/promotions", h.CreatePromotion)
+	admin.PATCH("/promotions/:id", h.UpdatePromotion)
+	admin.PUT("/vendors/:vendorId/multiplier", h.SetVendorMultiplier)
+	admin.POST("/orders/:id/award", h.AwardOrderPoints)
+	admin.POST("/expire", h.ExpirePoints)
+}
@@ -0,0 +1,18 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerMenuImportRoutes(r *echo.Group, h *handler.MenuImportHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Menu imports (X-Vendor-ID selects the vendor) -------------------
+	imports := r.Group("/menu-imports")
+	imports.Use(auth.RequireAuth, auth.RequireVendorPermission(vendor.PermissionMenuEdit))
+	imports.POST("", h.CreateImport) // multipart: file, format, dryRun, createCategories
+	imports.GET("", h.GetImports)
+	imports.GET("/:id", h.GetImport)
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerNotificationRoutes(r *echo.Group, h *handler.NotificationHandler, auth *middleware.AuthMiddleware) {
+
+	r.GET("/notifications/push/public-key", h.GetPushPublicKey) // VAPID key for PushManager.subscribe
+
+	notifications := r.Group("/users/me/notifications", auth.RequireAuth)
+
+	// ------------------- Inbox -------------------
+	notifications.GET("", h.GetInbox)                // GET /users/me/notifications?unread=true
+	notifications.GET("/summary", h.GetInboxSummary) // GET /users/me/notifications/summary (unread count)
+	notifications.POST("/:id/read", h.MarkRead)      // POST /users/me/notifications/:id/read
+	notifications.POST("/read-all", h.MarkAllRead)   // POST /users/me/notifications/read-all
+
+	// ------------------- Preferences -------------------
+	notifications.GET("/preferences", h.GetPreferences)      // GET /users/me/notifications/preferences
+	notifications.PATCH("/preferences", h.UpdatePreferences) // PATCH /users/me/notifications/preferences
+
+	// ------------------- Web Push -------------------
+	notifications.POST("/push-subscriptions", h.SubscribePush)         // POST /users/me/notifications/push-subscriptions
+	notifications.DELETE("/push-subscriptions/:id", h.UnsubscribePush) // DELETE /users/me/notifications/push-subscriptions/:id
+}
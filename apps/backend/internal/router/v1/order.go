@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/gitSanje/khajaride/internal/handler"
 	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,4 +18,9 @@ func registerOrderRoutes(r *echo.Group, h *handler.OrderHandler, auth *middlewar
 	order.POST("/create-order", h.CreateOrderFromCart)
 	order.GET("/me/get-order",h.GetOrdersByUserId )
 	order.GET("/get-order/:id",h.GetOrderById )
+	order.POST("/checkout", h.CheckoutCart)
+	order.GET("/groups/:id", h.GetOrderGroupById)
+	order.PATCH("/:id/status", h.UpdateOrderStatus, auth.RequireVendorPermission(vendor.PermissionOrdersHandle))
+	order.PATCH("/:id/reject", h.RejectOrder, auth.RequireVendorPermission(vendor.PermissionOrdersReject))
+	order.POST("/:id/reorder", h.Reorder)
 }
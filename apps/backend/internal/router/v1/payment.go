@@ -5,6 +5,7 @@ package v1
 import (
 	"github.com/gitSanje/khajaride/internal/handler"
 	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/labstack/echo/v4"
 )
 
@@ -24,10 +25,12 @@ func registerPaymentRoutes(r *echo.Group, h *handler.PaymentHandler, auth *middl
 	payment.GET("/stripe/onboarding/return", h.StripeOnboardingReturn)
 
 	payment.Use(auth.RequireAuth)
-    payment.POST("/stripe/connect-onboard", h.OnboardingStripeConnectAccount)
-	payment.POST("/stripe/create-account-link", h.CreateOnboardingAccountLink)
+    payment.POST("/stripe/connect-onboard", h.OnboardingStripeConnectAccount, auth.RequireVendorPermission(vendor.PermissionPayoutsManage))
+	payment.POST("/stripe/create-account-link", h.CreateOnboardingAccountLink, auth.RequireVendorPermission(vendor.PermissionPayoutsManage))
 	payment.POST("/stripe/initiate", h.StripePayment)
 	payment.POST("/khalti/initiate", h.KhaltiPayment)
+	payment.POST("/stripe/initiate-group", h.StripeGroupPayment)
+	payment.POST("/khalti/initiate-group", h.KhaltiGroupPayment)
 
 
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerRecommendationRoutes(r *echo.Group, h *handler.RecommendationHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Recommendations -------------------
+	r.GET("/users/me/recommendations", h.GetRecommendations, auth.RequireAuth) // usuals, popular near you, people also ordered
+}
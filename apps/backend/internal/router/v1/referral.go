@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func registerReferralRoutes(r *echo.Group, h *handler.ReferralHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Referrals -------------------
+	referrals := r.Group("/referrals")
+	referrals.Use(auth.RequireAuth)
+	referrals.GET("/me", h.GetMyReferrals) // GET /referrals/me (code, stats, referred users)
+
+	// ------------------- Program (admin) -------------------
+	admin := referrals.Group("/admin", auth.RequireAdmin)
+	admin.GET("/settings", h.GetSettings)
+	admin.PATCH("/settings", h.UpdateSettings)
+	admin.GET("/stats", h.GetProgramStats)
+	admin.GET("", h.GetReferrals)
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerSettlementRoutes(r *echo.Group, h *handler.SettlementHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Vendor Settlements -------------------
+	settlements := r.Group("/settlements")
+	settlements.Use(auth.RequireAuth)
+	payouts := settlements.Group("", auth.RequireVendorPermission(vendor.PermissionPayoutsView))
+	payouts.GET("/me", h.GetMySettlements)
+	payouts.GET("/me/unsettled", h.GetMyUnsettledEntries)
+	payouts.GET("/:id/statement", h.GetStatement)
+
+	// ------------------- Admin -------------------
+	admin := settlements.Group("", auth.RequireAdmin)
+	admin.GET("", h.GetSettlements)
+	admin.POST("/adjustments", h.CreateAdjustment)
+	admin.POST("/run", h.RunSettlement)
+	admin.POST("/:id/mark-paid", h.MarkSettlementPaid)
+}
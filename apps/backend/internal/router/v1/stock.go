@@ -0,0 +1,18 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerStockRoutes(r *echo.Group, h *handler.StockHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Menu stock (X-Vendor-ID selects the vendor) -------------------
+	stock := r.Group("/menu-stock")
+	stock.Use(auth.RequireAuth, auth.RequireVendorPermission(vendor.PermissionMenuEdit))
+	stock.GET("", h.GetStock)
+	stock.PUT("/:menuItemId", h.SetStock)
+	stock.DELETE("/:menuItemId", h.DeleteStock) // stop tracking
+}
@@ -22,9 +22,16 @@ func registerUserRoutes(r *echo.Group, h *handler.UserHandler, auth *middleware.
 	
 	user.GET("/me", h.GetUserByID)   // GET /users/me
 	user.PATCH("/me", h.UpdateUser)  // PATCH /users/me
-	user.DELETE("/me", h.DeleteUser) // DELETE /users/me
+	user.DELETE("/me", h.DeleteUser) // DELETE /users/me (scheduled, see /me/deletion)
+	user.GET("/me/deletion", h.GetDeletionStatus)      // GET /users/me/deletion
+	user.POST("/me/deletion/cancel", h.CancelDeletion) // POST /users/me/deletion/cancel
+	user.GET("/me/export", h.ExportData)               // GET /users/me/export (ZIP)
 	user.GET("/list", h.GetUsers)      // GET /users/list
 
+	// ------------------- Phone verification -------------------
+	user.POST("/me/phone/otp", h.RequestPhoneOTP)       // POST /users/me/phone/otp
+	user.POST("/me/phone/verify", h.VerifyPhoneOTP)     // POST /users/me/phone/verify
+
 	// ------------------- Addresses -------------------
 	address := user.Group("/me/addresses")
 	address.POST("", h.CreateAddress)                    // POST /users/me/addresses
@@ -14,4 +14,18 @@ func RegisterV1Routes(router *echo.Group, handlers *handler.Handlers, middleware
 	registerCartRoutes(router, handlers.Cart, middleware.Auth)
 	registerOrderRoutes(router, handlers.Order, middleware.Auth)
 	registerPaymentRoutes(router, handlers.Payment, middleware.Auth)
+	registerSettlementRoutes(router, handlers.Settlement, middleware.Auth)
+	registerLedgerRoutes(router, handlers.Ledger, middleware.Auth)
+	registerInvoiceRoutes(router, handlers.Invoice, middleware.Auth)
+	registerLoyaltyRoutes(router, handlers.Loyalty, middleware.Auth)
+	registerReferralRoutes(router, handlers.Referral, middleware.Auth)
+	registerRecommendationRoutes(router, handlers.Recommendation, middleware.Auth)
+	registerNotificationRoutes(router, handlers.Notification, middleware.Auth)
+	registerVendorVerificationRoutes(router, handlers.VendorVerification, middleware.Auth)
+	registerAnalyticsRoutes(router, handlers.Analytics, middleware.Auth)
+	registerVendorMemberRoutes(router, handlers.VendorMember, middleware.Auth)
+	registerBrandRoutes(router, handlers.Brand, middleware.Auth)
+	registerStockRoutes(router, handlers.Stock, middleware.Auth)
+	registerMenuImportRoutes(router, handlers.MenuImport, middleware.Auth)
+	registerImageRoutes(router, handlers.Image, middleware.Auth)
 }
\ No newline at end of file
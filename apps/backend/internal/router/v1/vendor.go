@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/gitSanje/khajaride/internal/handler"
 	"github.com/gitSanje/khajaride/internal/middleware"
+	vendorModel "github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/labstack/echo/v4"
 )
 
@@ -12,18 +13,24 @@ func registerVendorRoutes(r *echo.Group, h *handler.VendorHandler, auth *middlew
 
 	// ------------------- Vendors -------------------
 	vendor := r.Group("/vendors")
-	vendor.POST("/bulk", h.CreateVendors)
-	vendor.POST("", h.CreateVendor)
-	vendor.POST("/menuItemsWithCategory", h.CreateMenuItemsWithCategory)     
-    
     vendor.GET("",h.GetVendors)
 	vendor.GET("/:id", h.GetVendorByID)
 
-	vendor.POST("/upload-images",h.UploadImages)
 	vendor.Use(auth.RequireAuth)
-	vendor.GET("/vendorByUserId",h.GetVendorByUserID)
+	vendor.POST("", h.CreateVendor) // the caller becomes the owner
+	// the imports write many vendors' data from one file
+	vendor.POST("/bulk", h.CreateVendors, auth.RequireAdmin)
+	vendor.POST("/menuItemsWithCategory", h.CreateMenuItemsWithCategory, auth.RequireAdmin)
+	vendor.POST("/upload-images", h.UploadImages, auth.RequireVendorPermission(vendorModel.PermissionMenuEdit))
+	vendor.GET("/vendorByUserId",h.GetVendorByUserID, auth.RequireVendorPermission(""))
 	//------------------- Vendor Address -------------------
-	vendor.POST("/addresses",h.CreateVendorAddress)
+	vendor.POST("/addresses",h.CreateVendorAddress, auth.RequireVendorPermission(vendorModel.PermissionStoreEdit))
+
+	// ------------------- Favorites -------------------
+	favorite := r.Group("/favorites", auth.RequireAuth)
+	favorite.GET("", h.GetFavorites)                              // GET /favorites?type=restaurant|menu_item
+	favorite.POST("", h.AddFavorite)                              // POST /favorites
+	favorite.DELETE("/:entityType/:entityId", h.RemoveFavorite)   // DELETE /favorites/:entityType/:entityId
     
 
 	
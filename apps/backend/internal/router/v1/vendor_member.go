@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerVendorMemberRoutes(r *echo.Group, h *handler.VendorMemberHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Memberships -------------------
+	members := r.Group("/vendor-members")
+	members.Use(auth.RequireAuth)
+	members.GET("/me", h.GetMyMemberships)
+	members.POST("/invitations/accept", h.AcceptInvitation) // body: token from the invitation email
+
+	// ------------------- Team (X-Vendor-ID selects the vendor) -------------------
+	team := members.Group("", auth.RequireVendorPermission(""))
+	team.GET("", h.GetMembers)
+	team.POST("/leave", h.LeaveVendor)
+
+	// ------------------- Staff management -------------------
+	staff := members.Group("", auth.RequireVendorPermission(vendor.PermissionStaffManage))
+	staff.GET("/invitations", h.GetInvitations)
+	staff.POST("/invitations", h.InviteMember)
+	staff.DELETE("/invitations/:id", h.RevokeInvitation)
+	staff.PATCH("/:id", h.UpdateMemberRole)
+	staff.DELETE("/:id", h.RemoveMember)
+}
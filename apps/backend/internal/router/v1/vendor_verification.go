@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"github.com/gitSanje/khajaride/internal/handler"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/labstack/echo/v4"
+)
+
+func registerVendorVerificationRoutes(r *echo.Group, h *handler.VendorVerificationHandler, auth *middleware.AuthMiddleware) {
+
+	// ------------------- Vendor -------------------
+	verification := r.Group("/vendor-verification")
+	verification.Use(auth.RequireAuth)
+	me := verification.Group("/me", auth.RequireVendorPermission(vendor.PermissionVerificationManage))
+	me.GET("", h.GetMyVerification)
+	me.POST("/documents", h.UploadDocument) // multipart: file, documentType, documentNumber, expiryDate
+	me.DELETE("/documents/:id", h.DeleteDocument)
+	me.POST("/submit", h.Submit)
+	me.POST("/activate", h.Activate)
+	me.GET("/compliance", h.GetMyCompliance)
+
+	// ------------------- Admin -------------------
+	admin := verification.Group("", auth.RequireAdmin)
+	admin.GET("", h.GetQueue) // GET /vendor-verification?status=pending_verification
+	admin.GET("/:vendorId", h.GetVerification)
+	admin.GET("/:vendorId/compliance", h.GetCompliance)
+	admin.POST("/:vendorId/approve", h.Approve)
+	admin.POST("/:vendorId/reject", h.Reject)
+	admin.POST("/:vendorId/request-changes", h.RequestChanges)
+	admin.POST("/:vendorId/suspend", h.Suspend)
+	admin.POST("/:vendorId/reinstate", h.Reinstate)
+	admin.POST("/:vendorId/documents/:documentId/review", h.ReviewDocument)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/analytics"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type AnalyticsService struct {
+	server        *server.Server
+	analyticsRepo *repository.AnalyticsRepository
+}
+
+func NewAnalyticsService(s *server.Server, analyticsRepo *repository.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{
+		server:        s,
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+// GetMyAnalytics serves the analytics of the vendor the user works at.
+func (s *AnalyticsService) GetMyAnalytics(ctx echo.Context, vendorID string, query *analytics.GetAnalyticsQuery) (*analytics.VendorAnalytics, error) {
+	return s.getAnalytics(ctx, vendorID, query)
+}
+
+func (s *AnalyticsService) GetVendorAnalytics(ctx echo.Context, query *analytics.GetVendorAnalyticsQuery) (*analytics.VendorAnalytics, error) {
+	exists, err := s.analyticsRepo.VendorExists(ctx.Request().Context(), query.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+	}
+	return s.getAnalytics(ctx, query.VendorID, &query.GetAnalyticsQuery)
+}
+
+// GetBrandAnalytics rolls the analytics of all outlets of a brand the user
+// owns up into one report.
+func (s *AnalyticsService) GetBrandAnalytics(ctx echo.Context, ownerID string, query *analytics.GetBrandAnalyticsQuery) (*analytics.BrandAnalytics, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	outletIDs, err := s.analyticsRepo.GetOwnedBrandOutlets(ctxx, query.BrandID, ownerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBrandNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "brand not found")
+		}
+		return nil, err
+	}
+
+	rng, err := s.resolveRange(&query.GetAnalyticsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.analyticsRepo.GetBrandAnalytics(ctxx, query.BrandID, outletIDs, rng)
+	if err != nil {
+		logger.Error().Err(err).Str("brand_id", query.BrandID).Msg("Failed to get brand analytics")
+		return nil, err
+	}
+	completeSummary(&res.Summary)
+	return res, nil
+}
+
+func (s *AnalyticsService) getAnalytics(ctx echo.Context, vendorID string, query *analytics.GetAnalyticsQuery) (*analytics.VendorAnalytics, error) {
+	logger := middleware.GetLogger(ctx)
+
+	rng, err := s.resolveRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.analyticsRepo.GetVendorAnalytics(ctx.Request().Context(), vendorID, rng)
+	if err != nil {
+		logger.Error().Err(err).Str("vendor_id", vendorID).Msg("Failed to get vendor analytics")
+		return nil, err
+	}
+
+	completeSummary(&res.Summary)
+	return res, nil
+}
+
+// completeSummary derives the rates of a summary from its totals.
+func completeSummary(sm *analytics.Summary) {
+	sm.AverageOrderValue = ratio(sm.Revenue, sm.DeliveredOrders, 2)
+	sm.CancellationRate = ratio(float64(sm.CancelledOrders), sm.Orders, 4)
+	sm.RepeatCustomerRate = ratio(float64(sm.ReturningCustomers), sm.Customers, 4)
+}
+
+// resolveRange applies the defaults to a query in the platform timezone:
+// the range ends today and covers DefaultRangeDays days.
+func (s *AnalyticsService) resolveRange(query *analytics.GetAnalyticsQuery) (*analytics.Range, error) {
+	tz := s.server.Config.Primary.Timezone
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if query.To != nil {
+		to, _ = time.Parse("2006-01-02", *query.To)
+	}
+	from := to.AddDate(0, 0, -(analytics.DefaultRangeDays - 1))
+	if query.From != nil {
+		from, _ = time.Parse("2006-01-02", *query.From)
+	}
+
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days < 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "from must not be after to")
+	}
+	if days > analytics.MaxRangeDays {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "the range can be at most a year")
+	}
+	if *query.Granularity == analytics.GranularityHour && days > analytics.MaxHourlyRangeDays {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "hourly granularity is limited to 31 days")
+	}
+
+	return &analytics.Range{
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Granularity: *query.Granularity,
+		Timezone:    tz,
+	}, nil
+}
+
+// ratio is num/den rounded to places, 0 when there is nothing to divide by.
+func ratio(num float64, den int, places int) float64 {
+	if den == 0 {
+		return 0
+	}
+	p := math.Pow(10, float64(places))
+	return math.Round(num/float64(den)*p) / p
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type BrandService struct {
+	server     *server.Server
+	brandRepo  *repository.BrandRepository
+	searchRepo *repository.SearchRepository
+}
+
+func NewBrandService(s *server.Server, brandRepo *repository.BrandRepository, searchRepo *repository.SearchRepository) *BrandService {
+	return &BrandService{
+		server:     s,
+		brandRepo:  brandRepo,
+		searchRepo: searchRepo,
+	}
+}
+
+//-- ==================================================
+//-- BRANDS
+//-- ==================================================
+
+func (s *BrandService) CreateBrand(ctx echo.Context, ownerID string, payload *vendor.CreateBrandPayload) (*vendor.Brand, error) {
+	logger := middleware.GetLogger(ctx)
+
+	brand, err := s.brandRepo.CreateBrand(ctx.Request().Context(), ownerID, payload)
+	if err != nil {
+		logger.Error().Err(err).Str("owner_id", ownerID).Msg("Failed to create brand")
+		return nil, err
+	}
+	return brand, nil
+}
+
+func (s *BrandService) UpdateBrand(ctx echo.Context, ownerID string, payload *vendor.UpdateBrandPayload) (*vendor.Brand, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	brand, err := s.brandRepo.UpdateBrand(ctxx, ownerID, payload)
+	if err != nil {
+		return nil, brandError(err)
+	}
+
+	if payload.Name != nil {
+		if err := s.searchRepo.UpdateBrandName(ctxx, brand.ID, brand.Name); err != nil {
+			logger.Error().Err(err).Str("brand_id", brand.ID).Msg("Failed to index brand name")
+		}
+	}
+	return brand, nil
+}
+
+func (s *BrandService) GetMyBrands(ctx echo.Context, ownerID string) ([]vendor.PopulatedBrand, error) {
+	return s.brandRepo.GetOwnedBrands(ctx.Request().Context(), ownerID)
+}
+
+func (s *BrandService) GetBrand(ctx echo.Context, ownerID string, brandID string) (*vendor.PopulatedBrand, error) {
+	brand, err := s.brandRepo.GetOwnedBrand(ctx.Request().Context(), brandID, ownerID)
+	if err != nil {
+		return nil, brandError(err)
+	}
+	return brand, nil
+}
+
+//-- ==================================================
+//-- OUTLETS
+//-- ==================================================
+
+// CreateOutlet opens a new outlet of the brand. It starts as a draft vendor
+// and is verified on its own before it is listed.
+func (s *BrandService) CreateOutlet(ctx echo.Context, ownerID string, payload *vendor.CreateOutletPayload) (*vendor.Outlet, error) {
+	logger := middleware.GetLogger(ctx)
+
+	outlet, err := s.brandRepo.CreateOutlet(ctx.Request().Context(), ownerID, payload)
+	if err != nil {
+		if !errors.Is(err, repository.ErrBrandNotFound) {
+			logger.Error().Err(err).Str("brand_id", payload.BrandID).Msg("Failed to create outlet")
+		}
+		return nil, brandError(err)
+	}
+	return outlet, nil
+}
+
+func (s *BrandService) AttachOutlet(ctx echo.Context, ownerID string, payload *vendor.AttachOutletPayload) (*vendor.Outlet, error) {
+	outlet, err := s.brandRepo.AttachOutlet(ctx.Request().Context(), ownerID, payload)
+	if err != nil {
+		return nil, brandError(err)
+	}
+	return outlet, nil
+}
+
+func (s *BrandService) UpdateOutletSettings(ctx echo.Context, vendorID string, payload *vendor.UpdateOutletSettingsPayload) (*vendor.Vendor, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	v, err := s.brandRepo.UpdateOutletSettings(ctxx, vendorID, payload)
+	if err != nil {
+		return nil, brandError(err)
+	}
+
+	if err := s.searchRepo.UpdateOutletListing(ctxx, v); err != nil {
+		logger.Error().Err(err).Str("vendor_id", vendorID).Msg("Failed to index outlet settings")
+	}
+	return v, nil
+}
+
+// SetOutletPayoutAccount routes the vendor's future earnings to one of the
+// owner's payout accounts, or back to the owner's default.
+func (s *BrandService) SetOutletPayoutAccount(ctx echo.Context, vendorID string, payload *vendor.SetOutletPayoutAccountPayload) (*vendor.Outlet, error) {
+	accountID := payload.PayoutAccountID
+	if accountID != nil && *accountID == "" {
+		accountID = nil
+	}
+
+	outlet, err := s.brandRepo.SetOutletPayoutAccount(ctx.Request().Context(), vendorID, accountID)
+	if err != nil {
+		return nil, brandError(err)
+	}
+	return outlet, nil
+}
+
+//-- ==================================================
+//-- MASTER MENU
+//-- ==================================================
+
+func (s *BrandService) GetMasterMenu(ctx echo.Context, ownerID string, payload *vendor.GetBrandPayload) ([]vendor.MenuItem, error) {
+	ctxx := ctx.Request().Context()
+
+	if _, err := s.brandRepo.GetOwnedBrand(ctxx, payload.ID, ownerID); err != nil {
+		return nil, brandError(err)
+	}
+	return s.brandRepo.GetMasterMenu(ctxx, payload.ID, ownerID)
+}
+
+// CreateMasterMenuItem adds an item to the brand's master menu, sold by all
+// of its outlets. Their search documents are built by the indexer.
+func (s *BrandService) CreateMasterMenuItem(ctx echo.Context, ownerID string, payload *vendor.CreateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+	logger := middleware.GetLogger(ctx)
+
+	item, err := s.brandRepo.CreateMasterMenuItem(ctx.Request().Context(), ownerID, payload)
+	if err != nil {
+		if !errors.Is(err, repository.ErrBrandNotFound) {
+			logger.Error().Err(err).Str("brand_id", payload.BrandID).Msg("Failed to create master menu item")
+		}
+		return nil, brandError(err)
+	}
+	return item, nil
+}
+
+// UpdateMasterMenuItem changes a master menu item for all outlets. A new
+// price or availability is pushed to each outlet's search document with the
+// outlet's overrides applied.
+func (s *BrandService) UpdateMasterMenuItem(ctx echo.Context, ownerID string, payload *vendor.UpdateBrandMenuItemPayload) (*vendor.MenuItem, error) {
+	item, err := s.brandRepo.UpdateMasterMenuItem(ctx.Request().Context(), ownerID, payload)
+	if err != nil {
+		return nil, brandError(err)
+	}
+
+	if payload.BasePrice != nil || payload.IsAvailable != nil {
+		s.indexMasterMenuItem(ctx, item.ID)
+	}
+	return item, nil
+}
+
+func (s *BrandService) DeleteMasterMenuItem(ctx echo.Context, ownerID string, payload *vendor.DeleteBrandMenuItemPayload) error {
+	if err := s.brandRepo.DeleteMasterMenuItem(ctx.Request().Context(), ownerID, payload); err != nil {
+		return brandError(err)
+	}
+	return nil
+}
+
+func (s *BrandService) indexMasterMenuItem(ctx echo.Context, menuItemID string) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	states, err := s.brandRepo.GetOutletMenuStates(ctxx, menuItemID)
+	if err != nil {
+		logger.Error().Err(err).Str("menu_item_id", menuItemID).Msg("Failed to get outlet menu states")
+		return
+	}
+	for _, state := range states {
+		s.indexOutletMenuState(ctx, &state)
+	}
+}
+
+func (s *BrandService) indexOutletMenuState(ctx echo.Context, state *vendor.OutletMenuState) {
+	logger := middleware.GetLogger(ctx)
+
+	err := s.searchRepo.UpdateOutletMenuItem(ctx.Request().Context(), state.VendorID, state.MenuItemID, state.BasePrice, state.IsAvailable)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("vendor_id", state.VendorID).
+			Str("menu_item_id", state.MenuItemID).
+			Msg("Failed to index outlet menu item")
+	}
+}
+
+//-- ==================================================
+//-- OUTLET MENU OVERRIDES
+//-- ==================================================
+
+func (s *BrandService) GetMenuOverrides(ctx echo.Context, vendorID string) ([]vendor.PopulatedOutletMenuOverride, error) {
+	return s.brandRepo.GetMenuOverrides(ctx.Request().Context(), vendorID)
+}
+
+func (s *BrandService) SetMenuOverride(ctx echo.Context, vendorID string, payload *vendor.SetMenuOverridePayload) (*vendor.OutletMenuOverride, error) {
+	override, err := s.brandRepo.SetMenuOverride(ctx.Request().Context(), vendorID, payload)
+	if err != nil {
+		return nil, brandError(err)
+	}
+
+	s.indexOutletMenuItem(ctx, vendorID, payload.MenuItemID)
+	return override, nil
+}
+
+// DeleteMenuOverride drops the outlet's override, so it sells the item at
+// the master price and availability again.
+func (s *BrandService) DeleteMenuOverride(ctx echo.Context, vendorID string, payload *vendor.DeleteMenuOverridePayload) error {
+	if err := s.brandRepo.DeleteMenuOverride(ctx.Request().Context(), vendorID, payload.MenuItemID); err != nil {
+		return brandError(err)
+	}
+
+	s.indexOutletMenuItem(ctx, vendorID, payload.MenuItemID)
+	return nil
+}
+
+func (s *BrandService) indexOutletMenuItem(ctx echo.Context, vendorID, menuItemID string) {
+	logger := middleware.GetLogger(ctx)
+
+	state, err := s.brandRepo.GetOutletMenuState(ctx.Request().Context(), vendorID, menuItemID)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("vendor_id", vendorID).
+			Str("menu_item_id", menuItemID).
+			Msg("Failed to get outlet menu state")
+		return
+	}
+	s.indexOutletMenuState(ctx, state)
+}
+
+func brandError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrBrandNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "brand not found")
+	case errors.Is(err, repository.ErrOutletNotAttachable):
+		return echo.NewHTTPError(http.StatusConflict, "only your own vendors that are not part of a brand can be attached")
+	case errors.Is(err, repository.ErrBrandMenuItemNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "menu item not found in the brand's master menu")
+	case errors.Is(err, repository.ErrPayoutAccountNotOwned):
+		return echo.NewHTTPError(http.StatusBadRequest, "payout account not found")
+	case errors.Is(err, repository.ErrMenuOverrideNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "menu override not found")
+	case errors.Is(err, repository.ErrVendorNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/gitSanje/khajaride/internal/middleware"
 	"github.com/gitSanje/khajaride/internal/model"
@@ -11,6 +12,7 @@ import (
 	"github.com/gitSanje/khajaride/internal/model/coupon"
 	"github.com/gitSanje/khajaride/internal/repository"
 	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -38,6 +40,14 @@ func (s *CartService) AddCartItem(ctx echo.Context, userID string, payload *cart
 	}
 	defer tx.Rollback(ctx.Request().Context())
 
+	orderable, err := s.cartRepo.IsVendorOrderable(ctx.Request().Context(), tx, payload.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if !orderable {
+		return nil, echo.NewHTTPError(http.StatusConflict, "vendor is not accepting orders")
+	}
+
 	// 1️⃣ Ensure active cart session exists
 	session, err := s.cartRepo.GetActiveCartSession(ctx.Request().Context(), tx, userID)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -82,6 +92,33 @@ func (s *CartService) AddCartItem(ctx echo.Context, userID string, payload *cart
 }
 
 
+// ==================================================
+// APPLY LOYALTY POINTS
+// ==================================================
+
+func (s *CartService) ApplyLoyaltyPoints(ctx echo.Context, userID string, payload *cart.ApplyLoyaltyPointsPayload) (*cart.CartVendor, error) {
+
+	logger := middleware.GetLogger(ctx)
+
+	cv, err := s.cartRepo.ApplyLoyaltyPoints(ctx.Request().Context(), userID, payload)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "cart not found")
+		}
+		if errors.Is(err, repository.ErrInsufficientPoints) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "not enough loyalty points")
+		}
+		logger.Error().
+			Err(err).
+			Str("user_id", userID).
+			Msg("Failed to apply loyalty points")
+		return nil, err
+	}
+
+	return cv, nil
+}
+
+
 // ==================================================
 // GET ACTIVE CARTS BY USER ID
 // ==================================================
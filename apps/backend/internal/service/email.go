@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	model "github.com/gitSanje/khajaride/internal/model/email"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+)
+
+type EmailService struct {
+	server    *server.Server
+	emailRepo *repository.EmailRepository
+}
+
+func NewEmailService(s *server.Server, emailRepo *repository.EmailRepository) *EmailService {
+	return &EmailService{
+		server:    s,
+		emailRepo: emailRepo,
+	}
+}
+
+// HandleResendEvent applies a Resend delivery webhook to the email log. A
+// permanent bounce or a spam complaint also suppresses the recipient, so
+// nothing is sent to them again. Unknown event types are ignored and
+// reported as not handled.
+func (s *EmailService) HandleResendEvent(ctx context.Context, event *model.ResendEvent) (bool, error) {
+	var status, detail string
+	suppress := ""
+
+	switch event.Type {
+	case "email.sent":
+		// already logged as sent when Resend accepted it
+		return true, nil
+	case "email.delivered":
+		status = email.StatusDelivered
+	case "email.delivery_delayed":
+		status = email.StatusDeliveryDelayed
+	case "email.bounced":
+		if event.Data.Bounce == nil || event.Data.Bounce.Type != model.BouncePermanent {
+			// soft bounces are retried by the provider and may still arrive
+			status = email.StatusDeliveryDelayed
+			if event.Data.Bounce != nil {
+				detail = event.Data.Bounce.Message
+			}
+			break
+		}
+		status = email.StatusBounced
+		detail = event.Data.Bounce.Message
+		suppress = model.SuppressionBounce
+	case "email.complained":
+		status = email.StatusComplained
+		suppress = model.SuppressionComplaint
+	default:
+		return false, nil
+	}
+
+	found, err := s.emailRepo.ApplyDeliveryEvent(ctx, event.Data.EmailID, status, detail)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		// sent before the email log existed, or from another environment
+		// sharing the Resend account
+		s.server.Logger.Warn().Str("email_id", event.Data.EmailID).Str("type", event.Type).Msg("Delivery event for unknown email")
+	}
+
+	if suppress == "" {
+		return true, nil
+	}
+	for _, to := range event.Data.To {
+		if strings.TrimSpace(to) == "" {
+			continue
+		}
+		if err := s.emailRepo.Suppress(ctx, to, suppress, detail, event.Data.EmailID); err != nil {
+			return false, fmt.Errorf("suppress %s: %w", to, err)
+		}
+		s.server.Logger.Info().Str("to", to).Str("reason", suppress).Msg("Email address suppressed")
+	}
+	return true, nil
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/lib/imaging"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// maxImagesPerUpload caps the files of one plain upload
+const maxImagesPerUpload = 10
+
+// imagePrimaryVariant is the variant attached for each purpose; the others
+// are in the image's variants, or at the same URL with the variant name
+// swapped.
+var imagePrimaryVariant = map[string]string{
+	vendor.ImagePurposeUpload:        imaging.VariantCard,
+	vendor.ImagePurposeMenuItem:      imaging.VariantCard,
+	vendor.ImagePurposeVendorListing: imaging.VariantCard,
+	vendor.ImagePurposeVendorLogo:    imaging.VariantThumbnail,
+}
+
+type ImageService struct {
+	server    *server.Server
+	imageRepo *repository.ImageRepository
+	awsClient *aws.AWS
+}
+
+func NewImageService(s *server.Server, imageRepo *repository.ImageRepository, awsClient *aws.AWS) *ImageService {
+	return &ImageService{
+		server:    s,
+		imageRepo: imageRepo,
+		awsClient: awsClient,
+	}
+}
+
+//-- ==================================================
+//-- VENDOR: UPLOADS
+//-- ==================================================
+
+// UploadImages stores images the client attaches itself, e.g. to a menu
+// item it is about to create.
+func (s *ImageService) UploadImages(ctx echo.Context, vendorID, userID string, files []*multipart.FileHeader) (*vendor.UploadImagesResponse, error) {
+	if len(files) > maxImagesPerUpload {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("upload at most %d images at a time", maxImagesPerUpload))
+	}
+
+	res := &vendor.UploadImagesResponse{
+		UploadedURLs: make([]string, 0, len(files)),
+		Images:       make([]vendor.Image, 0, len(files)),
+	}
+	for _, file := range files {
+		img, err := s.storeFile(ctx, &vendor.Image{
+			VendorID:   &vendorID,
+			Purpose:    vendor.ImagePurposeUpload,
+			UploadedBy: &userID,
+		}, file, nil)
+		if err != nil {
+			return nil, err
+		}
+		res.UploadedURLs = append(res.UploadedURLs, img.URL)
+		res.Images = append(res.Images, *img)
+	}
+	return res, nil
+}
+
+// UploadMenuItemImage replaces the image of one of the vendor's menu items.
+func (s *ImageService) UploadMenuItemImage(ctx echo.Context, vendorID, userID string, payload *vendor.UploadMenuItemImagePayload, file *multipart.FileHeader) (*vendor.Image, error) {
+	exists, err := s.imageRepo.MenuItemExists(ctx.Request().Context(), vendorID, payload.MenuItemID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "menu item not found")
+	}
+
+	return s.storeFile(ctx, &vendor.Image{
+		VendorID:   &vendorID,
+		MenuItemID: &payload.MenuItemID,
+		Purpose:    vendor.ImagePurposeMenuItem,
+		UploadedBy: &userID,
+	}, file, func(ctx context.Context, tx pgx.Tx, img *vendor.Image) error {
+		return s.imageRepo.AttachMenuItemImage(ctx, tx, vendorID, payload.MenuItemID, img.URL)
+	})
+}
+
+// UploadVendorImage replaces the listing image or the logo of the vendor.
+func (s *ImageService) UploadVendorImage(ctx echo.Context, vendorID, userID string, payload *vendor.UploadVendorImagePayload, file *multipart.FileHeader) (*vendor.Image, error) {
+	purpose := vendor.ImagePurposeVendorListing
+	if payload.Slot == "logo" {
+		purpose = vendor.ImagePurposeVendorLogo
+	}
+
+	return s.storeFile(ctx, &vendor.Image{
+		VendorID:   &vendorID,
+		Purpose:    purpose,
+		UploadedBy: &userID,
+	}, file, func(ctx context.Context, tx pgx.Tx, img *vendor.Image) error {
+		return s.imageRepo.AttachVendorImage(ctx, tx, vendorID, purpose, img.URL)
+	})
+}
+
+//-- ==================================================
+//-- VENDOR: DIRECT UPLOADS
+//-- ==================================================
+
+// CreateUpload presigns an upload of one image straight to S3, so large
+// files do not pass through the API. The image is processed and attached
+// like any other once the client completes the upload.
+func (s *ImageService) CreateUpload(ctx echo.Context, vendorID, userID, purpose string, payload *vendor.CreateImageUploadPayload) (*vendor.ImageUploadResponse, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	if payload.Size > imaging.MaxFileSize {
+		return nil, imageError(imaging.ErrFileTooLarge)
+	}
+
+	upload := &vendor.ImageUpload{
+		VendorID:    vendorID,
+		Purpose:     purpose,
+		ObjectKey:   fmt.Sprintf("image-uploads/%s/%s", vendorID, uuid.NewString()),
+		ContentType: payload.ContentType,
+		Size:        payload.Size,
+		Method:      http.MethodPut,
+		UploadedBy:  &userID,
+	}
+	if payload.Method != "" {
+		upload.Method = payload.Method
+	}
+	if purpose == vendor.ImagePurposeMenuItem {
+		exists, err := s.imageRepo.MenuItemExists(ctxx, vendorID, payload.MenuItemID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "menu item not found")
+		}
+		upload.MenuItemID = &payload.MenuItemID
+	}
+
+	// 1️⃣ Presign; a PUT is held to the declared size, a POST to at most it
+	bucket := s.server.Config.AWS.UploadBucket
+	var presigned *aws.PresignedUpload
+	var err error
+	if upload.Method == http.MethodPost {
+		presigned, err = s.awsClient.S3.PresignPostObject(ctxx, bucket, upload.ObjectKey, upload.ContentType, upload.Size, vendor.ImageUploadExpiry)
+	} else {
+		presigned, err = s.awsClient.S3.PresignPutObject(ctxx, bucket, upload.ObjectKey, upload.ContentType, upload.Size, vendor.ImageUploadExpiry)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("vendor_id", vendorID).Msg("Failed to presign image upload")
+		return nil, err
+	}
+
+	// 2️⃣ Record
+	upload.ExpiresAt = presigned.ExpiresAt
+	created, err := s.imageRepo.CreateUpload(ctxx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Str("upload_id", created.ID).
+		Str("purpose", created.Purpose).
+		Str("method", created.Method).
+		Int64("size", created.Size).
+		Msg("Image upload presigned")
+	return &vendor.ImageUploadResponse{
+		Upload:    created,
+		Method:    presigned.Method,
+		URL:       presigned.URL,
+		Headers:   presigned.Headers,
+		Fields:    presigned.Fields,
+		ExpiresAt: presigned.ExpiresAt,
+	}, nil
+}
+
+// CompleteUpload checks the object a client uploaded directly and turns it
+// into an image.
+func (s *ImageService) CompleteUpload(ctx echo.Context, vendorID, userID string, payload *vendor.CompleteImageUploadPayload) (*vendor.Image, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	upload, err := s.imageRepo.GetUpload(ctxx, vendorID, userID, payload.ID)
+	if err != nil {
+		return nil, imageError(err)
+	}
+	if upload.Status != vendor.ImageUploadPending {
+		return nil, imageError(repository.ErrImageUploadNotPending)
+	}
+	if time.Now().After(upload.ExpiresAt.Add(vendor.ImageUploadCompleteWindow)) {
+		return nil, echo.NewHTTPError(http.StatusGone, "image upload expired")
+	}
+
+	// 1️⃣ Check the object before downloading it
+	bucket := s.server.Config.AWS.UploadBucket
+	info, err := s.awsClient.S3.HeadObject(ctxx, bucket, upload.ObjectKey)
+	if errors.Is(err, aws.ErrObjectNotFound) {
+		return nil, echo.NewHTTPError(http.StatusConflict, "the file has not been uploaded yet")
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("upload_id", upload.ID).Msg("Failed to check uploaded image")
+		return nil, err
+	}
+	if info.Size > imaging.MaxFileSize {
+		return nil, imageError(imaging.ErrFileTooLarge)
+	}
+	if info.Size > upload.Size || (upload.Method == http.MethodPut && info.Size != upload.Size) {
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "uploaded file does not have the declared size")
+	}
+	if info.ContentType != upload.ContentType {
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "uploaded file does not have the declared content type")
+	}
+
+	data, err := s.awsClient.S3.GetObject(ctxx, bucket, upload.ObjectKey)
+	if err != nil {
+		logger.Error().Err(err).Str("upload_id", upload.ID).Msg("Failed to download uploaded image")
+		return nil, err
+	}
+
+	// 2️⃣ Process and attach, completing the upload with it
+	img := &vendor.Image{
+		VendorID:   &upload.VendorID,
+		MenuItemID: upload.MenuItemID,
+		Purpose:    upload.Purpose,
+		UploadedBy: &userID,
+	}
+	created, err := s.store(ctx, img, data, func(ctx context.Context, tx pgx.Tx, img *vendor.Image) error {
+		if err := s.imageRepo.CompleteUpload(ctx, tx, upload.ID, img.ID, vendor.ImageUploadCompleteWindow); err != nil {
+			return err
+		}
+		switch upload.Purpose {
+		case vendor.ImagePurposeMenuItem:
+			if upload.MenuItemID == nil {
+				// the item was deleted since
+				return repository.ErrImageMenuItemNotFound
+			}
+			return s.imageRepo.AttachMenuItemImage(ctx, tx, upload.VendorID, *upload.MenuItemID, img.URL)
+		case vendor.ImagePurposeVendorListing, vendor.ImagePurposeVendorLogo:
+			return s.imageRepo.AttachVendorImage(ctx, tx, upload.VendorID, upload.Purpose, img.URL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3️⃣ The staging object is not needed any more; the cleanup does not
+	// see completed uploads, so a failure here only leaves it behind
+	if err := s.awsClient.S3.DeleteObject(ctxx, bucket, upload.ObjectKey); err != nil {
+		logger.Warn().Err(err).Str("upload_id", upload.ID).Msg("Failed to delete staged image upload")
+	}
+
+	logger.Info().
+		Str("upload_id", upload.ID).
+		Str("image_id", created.ID).
+		Msg("Image upload completed")
+	return created, nil
+}
+
+// storeFile runs an uploaded form file through store.
+func (s *ImageService) storeFile(ctx echo.Context, img *vendor.Image, file *multipart.FileHeader, attach imageAttacher) (*vendor.Image, error) {
+	if file.Size > imaging.MaxFileSize {
+		return nil, imageError(imaging.ErrFileTooLarge)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read image")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, imaging.MaxFileSize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read image")
+	}
+	return s.store(ctx, img, data, attach)
+}
+
+// imageAttacher puts a stored image where it was uploaded for, in the
+// transaction recording it.
+type imageAttacher func(ctx context.Context, tx pgx.Tx, img *vendor.Image) error
+
+// store runs a file through the image pipeline, stores its variants and
+// records it, attaching it where it was uploaded for.
+func (s *ImageService) store(ctx echo.Context, img *vendor.Image, data []byte, attach imageAttacher) (*vendor.Image, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	// 1️⃣ Check and render
+	processed, err := imaging.Process(data)
+	if err != nil {
+		return nil, imageError(err)
+	}
+
+	bucket := s.server.Config.AWS.UploadBucket
+	img.Hash = processed.Hash
+	img.ContentType = processed.ContentType
+	img.Width = processed.Width
+	img.Height = processed.Height
+	for _, r := range processed.Renditions {
+		key := imaging.Key(processed.Hash, r.Variant, r.Format)
+		variant := vendor.ImageVariant{
+			Name:   r.Variant,
+			Format: r.Format,
+			Key:    key,
+			URL:    s.awsClient.S3.PublicURL(bucket, key),
+			Width:  r.Width,
+			Height: r.Height,
+			Size:   len(r.Data),
+		}
+		img.Variants = append(img.Variants, variant)
+		if r.Variant == imagePrimaryVariant[img.Purpose] && r.Format == imaging.FormatJPEG {
+			img.URL = variant.URL
+		}
+	}
+
+	// 2️⃣ Record and store under the file's lock, so the cleanup cannot
+	// delete the objects of an earlier upload of it meanwhile
+	tx, err := s.server.DB.Pool.Begin(ctxx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctxx)
+
+	if err := s.imageRepo.LockImage(ctxx, tx, img.Hash); err != nil {
+		return nil, err
+	}
+	created, err := s.imageRepo.CreateImage(ctxx, tx, img)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range processed.Renditions {
+		key := imaging.Key(processed.Hash, r.Variant, r.Format)
+		if err := s.awsClient.S3.PutPublicObject(ctxx, bucket, key, r.ContentType, r.Data, true); err != nil {
+			logger.Error().Err(err).Str("hash", img.Hash).Msg("Failed to upload image variant")
+			return nil, err
+		}
+	}
+
+	// 3️⃣ Attach
+	if attach != nil {
+		if err := attach(ctxx, tx, created); err != nil {
+			return nil, imageError(err)
+		}
+	}
+	if err := tx.Commit(ctxx); err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Str("image_id", created.ID).
+		Str("purpose", created.Purpose).
+		Str("hash", created.Hash).
+		Msg("Image uploaded")
+	return created, nil
+}
+
+func imageError(err error) error {
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, imaging.ErrFileTooLarge):
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, imaging.ErrTooSmall), errors.Is(err, imaging.ErrTooLarge), errors.Is(err, imaging.ErrCorrupt):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, repository.ErrImageMenuItemNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "menu item not found")
+	case errors.Is(err, repository.ErrVendorNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+	case errors.Is(err, repository.ErrImageUploadNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "image upload not found")
+	case errors.Is(err, repository.ErrImageUploadNotPending):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/service"
+	testingpkg "github.com/gitSanje/khajaride/internal/testing"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageServiceCompleteUpload(t *testing.T) {
+	testDB, s, cleanup := testingpkg.SetupTest(t)
+	defer cleanup()
+	awsClient := testingpkg.SetupTestS3(t, s)
+
+	ctx := context.Background()
+	imageRepo := repository.NewImageRepository(s)
+	imageService := service.NewImageService(s, imageRepo, awsClient)
+	bucket := s.Config.AWS.UploadBucket
+
+	var userID, vendorID string
+	err := testDB.Pool.QueryRow(ctx, `
+		INSERT INTO users (email, username) VALUES ('owner@example.com', 'owner')
+		RETURNING id
+	`).Scan(&userID)
+	require.NoError(t, err)
+	err = testDB.Pool.QueryRow(ctx, `
+		INSERT INTO vendors (vendor_user_id, name) VALUES ($1, 'Test Kitchen')
+		RETURNING id
+	`, userID).Scan(&vendorID)
+	require.NoError(t, err)
+
+	// createUpload records a pending PUT upload of a size-byte PNG
+	createUpload := func(t *testing.T, size int64) *vendor.ImageUpload {
+		t.Helper()
+
+		upload, err := imageRepo.CreateUpload(ctx, &vendor.ImageUpload{
+			VendorID:    vendorID,
+			Purpose:     vendor.ImagePurposeUpload,
+			ObjectKey:   "image-uploads/" + vendorID + "/" + uuid.NewString(),
+			ContentType: "image/png",
+			Size:        size,
+			Method:      http.MethodPut,
+			UploadedBy:  &userID,
+			ExpiresAt:   time.Now().Add(vendor.ImageUploadExpiry),
+		})
+		require.NoError(t, err)
+		return upload
+	}
+
+	complete := func(t *testing.T, upload *vendor.ImageUpload) error {
+		t.Helper()
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		_, err := imageService.CompleteUpload(c, vendorID, userID, &vendor.CompleteImageUploadPayload{ID: upload.ID})
+		return err
+	}
+
+	// assertRejected checks the status of the error and that the upload can
+	// still be completed
+	assertRejected := func(t *testing.T, upload *vendor.ImageUpload, err error, status int) {
+		t.Helper()
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, status, httpErr.Code)
+
+		stored, err := imageRepo.GetUpload(ctx, vendorID, userID, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, vendor.ImageUploadPending, stored.Status)
+	}
+
+	t.Run("object missing", func(t *testing.T) {
+		upload := createUpload(t, 8)
+
+		err := complete(t, upload)
+		assertRejected(t, upload, err, http.StatusConflict)
+	})
+
+	t.Run("wrong size", func(t *testing.T) {
+		upload := createUpload(t, 8)
+		require.NoError(t, awsClient.S3.PutObject(ctx, bucket, upload.ObjectKey, "image/png", []byte("tiny")))
+
+		err := complete(t, upload)
+		assertRejected(t, upload, err, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		upload := createUpload(t, 8)
+		require.NoError(t, awsClient.S3.PutObject(ctx, bucket, upload.ObjectKey, "text/html", []byte("<html/>!")))
+
+		err := complete(t, upload)
+		assertRejected(t, upload, err, http.StatusUnprocessableEntity)
+	})
+}
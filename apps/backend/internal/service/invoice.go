@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	invoicerender "github.com/gitSanje/khajaride/internal/lib/invoice"
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/invoice"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type InvoiceService struct {
+	server      *server.Server
+	invoiceRepo *repository.InvoiceRepository
+	awsClient   *aws.AWS
+}
+
+func NewInvoiceService(s *server.Server, invoiceRepo *repository.InvoiceRepository, awsClient *aws.AWS) *InvoiceService {
+	return &InvoiceService{
+		server:      s,
+		invoiceRepo: invoiceRepo,
+		awsClient:   awsClient,
+	}
+}
+
+// IssueInvoice returns the invoice of a paid order and its PDF, issuing and
+// rendering it on first use. The PDF is stored in S3 so it is rendered once
+// and every later download is byte-for-byte the same document.
+func (s *InvoiceService) IssueInvoice(ctx context.Context, orderID string) (*invoice.Invoice, []byte, error) {
+	inv, err := s.invoiceRepo.GetInvoiceByOrderID(ctx, orderID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			return nil, nil, err
+		}
+		if inv, err = s.invoiceRepo.CreateInvoice(ctx, orderID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bucket := s.server.Config.AWS.UploadBucket
+	if inv.FileKey != nil {
+		pdf, err := s.awsClient.S3.GetObject(ctx, bucket, *inv.FileKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return inv, pdf, nil
+	}
+
+	doc, err := s.buildDocument(ctx, inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pdf, err := invoicerender.RenderPDF(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := fmt.Sprintf("invoices/%s/%s.pdf", inv.VendorID, inv.InvoiceNumber)
+	if err := s.awsClient.S3.PutObject(ctx, bucket, key, "application/pdf", pdf); err != nil {
+		return nil, nil, err
+	}
+	if err := s.invoiceRepo.SetInvoiceFileKey(ctx, inv.ID, key); err != nil {
+		return nil, nil, err
+	}
+	inv.FileKey = &key
+
+	return inv, pdf, nil
+}
+
+func (s *InvoiceService) buildDocument(ctx context.Context, inv *invoice.Invoice) (*invoice.InvoiceDocument, error) {
+	lines, err := s.invoiceRepo.ListInvoiceLines(ctx, inv.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice.InvoiceDocument{
+		Invoice: *inv,
+		Lines:   lines,
+	}, nil
+}
+
+// authorize allows the customer who placed the order and the staff of the
+// vendor who fulfils it that handle orders.
+func (s *InvoiceService) authorize(ctx context.Context, userID, orderID string) error {
+	customerID, vendorRole, err := s.invoiceRepo.GetInvoiceParties(ctx, orderID, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		return err
+	}
+	if userID != customerID && !vendor.HasPermission(vendorRole, vendor.PermissionOrdersHandle) {
+		return echo.NewHTTPError(http.StatusNotFound, "order not found")
+	}
+	return nil
+}
+
+func (s *InvoiceService) issueForUser(c echo.Context, userID string, payload *invoice.GetInvoicePayload) (*invoice.Invoice, []byte, error) {
+	logger := middleware.GetLogger(c)
+	ctx := c.Request().Context()
+
+	if err := s.authorize(ctx, userID, payload.OrderID); err != nil {
+		return nil, nil, err
+	}
+
+	inv, pdf, err := s.IssueInvoice(ctx, payload.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotPaid) {
+			return nil, nil, echo.NewHTTPError(http.StatusConflict, "invoice is available once the order is paid")
+		}
+		logger.Error().Err(err).Str("order_id", payload.OrderID).Msg("Failed to issue invoice")
+		return nil, nil, err
+	}
+	return inv, pdf, nil
+}
+
+func (s *InvoiceService) GetInvoice(c echo.Context, userID string, payload *invoice.GetInvoicePayload) (*invoice.Invoice, error) {
+	inv, _, err := s.issueForUser(c, userID, payload)
+	return inv, err
+}
+
+func (s *InvoiceService) GetInvoicePDF(c echo.Context, userID string, payload *invoice.GetInvoicePayload) ([]byte, error) {
+	_, pdf, err := s.issueForUser(c, userID, payload)
+	return pdf, err
+}
+
+func (s *InvoiceService) GetInvoiceHTML(c echo.Context, userID string, payload *invoice.GetInvoicePayload) ([]byte, error) {
+	inv, _, err := s.issueForUser(c, userID, payload)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.buildDocument(c.Request().Context(), inv)
+	if err != nil {
+		return nil, err
+	}
+	return invoicerender.RenderHTML(doc)
+}
+
+// SendOrderConfirmation queues one confirmation email for the paid orders.
+// orderRef identifies the purchase in the email: the order id, or the order
+// group id for a cart with several vendors. The invoices are issued by the
+// email worker, not on the payment path.
+func (s *InvoiceService) SendOrderConfirmation(ctx context.Context, orderRef string, orderIDs []string) error {
+	task, err := job.NewOrderConfirmationEmailTask(job.OrderConfirmationEmailPayload{
+		OrderRef: orderRef,
+		OrderIDs: orderIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("enqueue order confirmation email: %w", err)
+	}
+	return nil
+}
+
+// PrepareOrderConfirmation issues the invoices of the paid orders, or loads
+// the ones already in S3, for the confirmation email.
+func (s *InvoiceService) PrepareOrderConfirmation(ctx context.Context, orderRef string, orderIDs []string) (*job.OrderConfirmation, error) {
+	var (
+		attachments []email.Attachment
+		to          string
+		name        string
+		currency    string
+		total       float64
+	)
+	for _, id := range orderIDs {
+		inv, pdf, err := s.IssueInvoice(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("issue invoice for order %s: %w", id, err)
+		}
+		attachments = append(attachments, email.Attachment{
+			Filename: inv.InvoiceNumber + ".pdf",
+			Content:  pdf,
+		})
+		if inv.CustomerEmail != nil {
+			to = *inv.CustomerEmail
+		}
+		if inv.CustomerName != nil {
+			name = *inv.CustomerName
+		}
+		currency = inv.Currency
+		total += inv.Total
+	}
+	if to == "" {
+		return nil, fmt.Errorf("no customer email for order %s", orderRef)
+	}
+
+	return &job.OrderConfirmation{
+		To:           to,
+		CustomerName: name,
+		Total:        fmt.Sprintf("%s %.2f", currency, total),
+		Invoices:     attachments,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/ledger"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type LedgerService struct {
+	server     *server.Server
+	ledgerRepo *repository.LedgerRepository
+}
+
+func NewLedgerService(s *server.Server, ledgerRepo *repository.LedgerRepository) *LedgerService {
+	return &LedgerService{
+		server:     s,
+		ledgerRepo: ledgerRepo,
+	}
+}
+
+func (s *LedgerService) GetTrialBalance(ctx echo.Context, query *ledger.GetTrialBalanceQuery) (*ledger.TrialBalance, error) {
+	logger := middleware.GetLogger(ctx)
+
+	tb, err := s.ledgerRepo.GetTrialBalance(ctx.Request().Context(), query.Cutoff())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to build trial balance")
+		return nil, err
+	}
+	if !tb.Balanced {
+		logger.Error().
+			Float64("total_debit", tb.TotalDebit).
+			Float64("total_credit", tb.TotalCredit).
+			Msg("Trial balance does not balance")
+	}
+	return tb, nil
+}
+
+func (s *LedgerService) GetAccountBalances(ctx echo.Context, query *ledger.GetAccountBalancesQuery) ([]ledger.AccountBalance, error) {
+	return s.ledgerRepo.GetAccountBalances(ctx.Request().Context(), query.OwnerType, query.OwnerID, time.Now())
+}
+
+func (s *LedgerService) GetVendorBalance(ctx echo.Context, vendorUserID string) (*ledger.VendorBalanceResponse, error) {
+	payable, err := s.ledgerRepo.GetVendorPayable(ctx.Request().Context(), vendorUserID)
+	if err != nil {
+		return nil, err
+	}
+	return &ledger.VendorBalanceResponse{
+		VendorUserID: vendorUserID,
+		Payable:      payable,
+	}, nil
+}
+
+func (s *LedgerService) CheckInvariants(ctx echo.Context) (*ledger.InvariantReport, error) {
+	logger := middleware.GetLogger(ctx)
+
+	report, err := s.ledgerRepo.CheckInvariants(ctx.Request().Context())
+	if err != nil {
+		return nil, err
+	}
+	if !report.Ok {
+		logger.Error().
+			Int("unbalanced", len(report.UnbalancedEntries)).
+			Int("empty", len(report.EmptyEntries)).
+			Msg("Ledger invariant check failed")
+	}
+	return report, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/loyalty"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type LoyaltyService struct {
+	server      *server.Server
+	loyaltyRepo *repository.LoyaltyRepository
+}
+
+func NewLoyaltyService(s *server.Server, loyaltyRepo *repository.LoyaltyRepository) *LoyaltyService {
+	return &LoyaltyService{
+		server:      s,
+		loyaltyRepo: loyaltyRepo,
+	}
+}
+
+func (s *LoyaltyService) GetStatus(ctx echo.Context, userID string) (*loyalty.Status, error) {
+	logger := middleware.GetLogger(ctx)
+
+	st, err := s.loyaltyRepo.GetStatus(ctx.Request().Context(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to fetch loyalty status")
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *LoyaltyService) GetTiers(ctx echo.Context) ([]loyalty.Tier, error) {
+	return s.loyaltyRepo.ListTiers(ctx.Request().Context())
+}
+
+func (s *LoyaltyService) GetPromotions(ctx echo.Context, activeOnly bool) ([]loyalty.Promotion, error) {
+	return s.loyaltyRepo.ListPromotions(ctx.Request().Context(), activeOnly)
+}
+
+//-- ==================================================
+//-- ADMIN
+//-- ==================================================
+
+func (s *LoyaltyService) GetSettings(ctx echo.Context) (*loyalty.Settings, error) {
+	return s.loyaltyRepo.GetSettings(ctx.Request().Context())
+}
+
+func (s *LoyaltyService) UpdateSettings(ctx echo.Context, payload *loyalty.UpdateSettingsPayload) (*loyalty.Settings, error) {
+	logger := middleware.GetLogger(ctx)
+
+	settings, err := s.loyaltyRepo.UpdateSettings(ctx.Request().Context(), payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to update loyalty settings")
+		return nil, err
+	}
+
+	logger.Info().
+		Float64("points_per_npr", settings.PointsPerNPR).
+		Int("expiry_days", settings.ExpiryDays).
+		Int("tier_window_days", settings.TierWindowDays).
+		Msg("Loyalty settings updated")
+	return settings, nil
+}
+
+func (s *LoyaltyService) CreatePromotion(ctx echo.Context, adminID string, payload *loyalty.CreatePromotionPayload) (*loyalty.Promotion, error) {
+	logger := middleware.GetLogger(ctx)
+
+	promo, err := s.loyaltyRepo.CreatePromotion(ctx.Request().Context(), payload, adminID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create loyalty promotion")
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (s *LoyaltyService) UpdatePromotion(ctx echo.Context, payload *loyalty.UpdatePromotionPayload) (*loyalty.Promotion, error) {
+	logger := middleware.GetLogger(ctx)
+
+	promo, err := s.loyaltyRepo.UpdatePromotion(ctx.Request().Context(), payload)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "promotion not found")
+		}
+		logger.Error().Err(err).Str("promotion_id", payload.ID).Msg("Failed to update loyalty promotion")
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (s *LoyaltyService) SetVendorMultiplier(ctx echo.Context, payload *loyalty.SetVendorMultiplierPayload) error {
+	err := s.loyaltyRepo.SetVendorMultiplier(ctx.Request().Context(), payload.VendorID, payload.Multiplier)
+	if err == pgx.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+	}
+	return err
+}
+
+// AwardOrderPoints awards a delivered order by hand, e.g. after the rules
+// were fixed. Orders that already earned are returned unchanged.
+func (s *LoyaltyService) AwardOrderPoints(ctx echo.Context, payload *loyalty.AwardOrderPointsPayload) (*user.LoyaltyPointsLedger, error) {
+	logger := middleware.GetLogger(ctx)
+
+	entry, err := s.loyaltyRepo.AwardOrderPoints(ctx.Request().Context(), payload.OrderID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		logger.Error().Err(err).Str("order_id", payload.OrderID).Msg("Failed to award loyalty points")
+		return nil, err
+	}
+	if entry == nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "order does not earn points")
+	}
+	return entry, nil
+}
+
+func (s *LoyaltyService) ExpirePoints(ctx echo.Context) (*loyalty.ExpiryResult, error) {
+	logger := middleware.GetLogger(ctx)
+
+	res, err := s.loyaltyRepo.ExpirePoints(ctx.Request().Context(), time.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to expire loyalty points")
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/lib/menuimport"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxMenuImportSize is the largest menu file accepted
+const maxMenuImportSize = 5 << 20
+
+// menuImportBatchSize is how many items are saved per transaction, and how
+// often the progress is recorded
+const menuImportBatchSize = 200
+
+// maxMenuImportErrors caps the row errors kept in a report
+const maxMenuImportErrors = 500
+
+// menuImportFiles are the stored extension and content type of each format
+var menuImportFiles = map[string]struct{ ext, contentType string }{
+	menuimport.FormatCSV:       {".csv", "text/csv"},
+	menuimport.FormatXLSX:      {".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	menuimport.FormatFoodMandu: {".json", "application/json"},
+}
+
+type MenuImportService struct {
+	server     *server.Server
+	importRepo *repository.MenuImportRepository
+	searchRepo *repository.SearchRepository
+	awsClient  *aws.AWS
+}
+
+func NewMenuImportService(s *server.Server, importRepo *repository.MenuImportRepository, searchRepo *repository.SearchRepository, awsClient *aws.AWS) *MenuImportService {
+	return &MenuImportService{
+		server:     s,
+		importRepo: importRepo,
+		searchRepo: searchRepo,
+		awsClient:  awsClient,
+	}
+}
+
+//-- ==================================================
+//-- VENDOR: UPLOADS
+//-- ==================================================
+
+// CreateImport stores a menu file and queues it for the import job.
+func (s *MenuImportService) CreateImport(ctx echo.Context, vendorID, userID string, payload *vendor.CreateMenuImportPayload, file *multipart.FileHeader) (*vendor.MenuImport, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	format := menuimport.DetectFormat(file.Filename)
+	if payload.Format != nil {
+		format = *payload.Format
+	}
+	stored, ok := menuImportFiles[format]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, "menu file must be a CSV, XLSX or FoodMandu JSON file")
+	}
+
+	// 1️⃣ Read the file
+	if file.Size > maxMenuImportSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "menu file must be 5 MB or smaller")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read menu file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxMenuImportSize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read menu file")
+	}
+	if len(data) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "menu file is empty")
+	}
+	if len(data) > maxMenuImportSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "menu file must be 5 MB or smaller")
+	}
+
+	// 2️⃣ Keep it for the job
+	bucket := s.server.Config.AWS.UploadBucket
+	key := fmt.Sprintf("vendors/%s/menu-imports/%s%s", vendorID, uuid.NewString(), stored.ext)
+	if err := s.awsClient.S3.PutObject(ctxx, bucket, key, stored.contentType, data); err != nil {
+		logger.Error().Err(err).Str("vendor_id", vendorID).Msg("Failed to upload menu file")
+		return nil, err
+	}
+
+	imp, err := s.importRepo.CreateImport(ctxx, &vendor.MenuImport{
+		VendorID:         vendorID,
+		UserID:           userID,
+		Format:           format,
+		FileName:         filepath.Base(file.Filename),
+		FileKey:          key,
+		DryRun:           payload.DryRun,
+		CreateCategories: payload.CreateCategories,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3️⃣ Queue it
+	task, err := job.NewMenuImportTask(imp.ID)
+	if err == nil {
+		_, err = s.server.Job.Client.Enqueue(task)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("import_id", imp.ID).Msg("Failed to enqueue menu import")
+		if failErr := s.importRepo.FailImport(ctxx, imp.ID, "the import could not be queued"); failErr != nil {
+			logger.Error().Err(failErr).Str("import_id", imp.ID).Msg("Failed to record failed menu import")
+		}
+		return nil, err
+	}
+
+	logger.Info().
+		Str("vendor_id", vendorID).
+		Str("import_id", imp.ID).
+		Str("format", format).
+		Bool("dry_run", imp.DryRun).
+		Msg("Menu import queued")
+	return imp, nil
+}
+
+func (s *MenuImportService) GetImports(ctx echo.Context, vendorID string) ([]vendor.MenuImport, error) {
+	return s.importRepo.GetImports(ctx.Request().Context(), vendorID)
+}
+
+func (s *MenuImportService) GetImport(ctx echo.Context, vendorID string, payload *vendor.GetMenuImportPayload) (*vendor.MenuImport, error) {
+	imp, err := s.importRepo.GetImport(ctx.Request().Context(), vendorID, payload.ID)
+	if errors.Is(err, repository.ErrMenuImportNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "menu import not found")
+	}
+	return imp, err
+}
+
+//-- ==================================================
+//-- JOB
+//-- ==================================================
+
+// RunMenuImport validates an uploaded file and, unless it is a dry run,
+// saves its valid rows in batches. Files that cannot be read fail the
+// import; errors returned are retried by the job.
+func (s *MenuImportService) RunMenuImport(ctx context.Context, importID string) error {
+	logger := s.server.Logger
+
+	imp, err := s.importRepo.StartImport(ctx, importID)
+	if errors.Is(err, repository.ErrMenuImportFinished) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 1️⃣ Read and validate the file
+	data, err := s.awsClient.S3.GetObject(ctx, s.server.Config.AWS.UploadBucket, imp.FileKey)
+	if err != nil {
+		return fmt.Errorf("failed to download menu file: %w", err)
+	}
+	source, err := menuimport.NewSource(imp.Format)
+	if err != nil {
+		return s.FailMenuImport(ctx, imp.ID, err.Error())
+	}
+	table, err := source.Read(data)
+	if err != nil {
+		return s.FailMenuImport(ctx, imp.ID, err.Error())
+	}
+
+	catalog, err := s.importRepo.GetCatalog(ctx, imp.VendorID)
+	if err != nil {
+		return err
+	}
+	result := menuimport.Validate(table.Records, *catalog, imp.CreateCategories)
+
+	report := &vendor.MenuImportReport{
+		TotalRows:      len(table.Records),
+		InvalidRows:    result.InvalidRows,
+		NewCategories:  result.NewCategories,
+		IgnoredColumns: table.IgnoredColumns,
+		Errors:         result.Errors,
+	}
+	processed := result.InvalidRows
+	if err := s.importRepo.UpdateImportProgress(ctx, imp.ID, report.TotalRows, processed); err != nil {
+		return err
+	}
+
+	if imp.DryRun {
+		for _, item := range result.Items {
+			if item.Exists {
+				report.Updated++
+			} else {
+				report.Created++
+			}
+		}
+		report.ValidRows = len(result.Items)
+		return s.completeImport(ctx, imp, report)
+	}
+
+	// 2️⃣ Create the missing categories
+	if len(result.NewCategories) > 0 {
+		ids, err := s.importRepo.CreateCategories(ctx, result.NewCategories)
+		if err != nil {
+			return err
+		}
+		for i := range result.Items {
+			item := &result.Items[i]
+			if item.CategoryID == nil && item.Category != "" {
+				id := ids[menuimport.NameKey(item.Category)]
+				item.CategoryID = &id
+			}
+		}
+	}
+
+	// 3️⃣ Resume after the rows a failed attempt saved
+	prior, err := s.importRepo.GetSavedRows(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+	items := result.Items
+	if len(prior) > 0 {
+		done := make(map[int]bool, len(prior))
+		var created []string
+		for _, p := range prior {
+			done[p.Row] = true
+			if p.Created {
+				report.Created++
+				if p.MenuItemID != "" {
+					created = append(created, p.MenuItemID)
+				}
+			} else {
+				report.Updated++
+			}
+		}
+		report.ValidRows = len(prior)
+		// the attempt may have failed before indexing them
+		s.indexCreatedItems(ctx, imp.VendorID, created)
+
+		items = make([]vendor.MenuImportItem, 0, len(result.Items))
+		for _, item := range result.Items {
+			if !done[item.Row] {
+				items = append(items, item)
+			}
+		}
+		processed += len(result.Items) - len(items)
+	}
+
+	// 4️⃣ Save the items in batches, recording the progress
+	for start := 0; start < len(items); start += menuImportBatchSize {
+		batch := items[start:min(start+menuImportBatchSize, len(items))]
+
+		saved, rowErrs, err := s.importRepo.UpsertMenuItems(ctx, imp.ID, imp.VendorID, batch)
+		if err != nil {
+			return err
+		}
+		var created []string
+		for _, item := range saved {
+			if item.Created {
+				report.Created++
+				created = append(created, item.MenuItemID)
+				continue
+			}
+			report.Updated++
+			err := s.searchRepo.UpdateOutletMenuItem(ctx, imp.VendorID, item.MenuItemID, item.BasePrice, item.IsAvailable)
+			if err != nil {
+				logger.Error().Err(err).
+					Str("vendor_id", imp.VendorID).
+					Str("menu_item_id", item.MenuItemID).
+					Msg("Failed to index imported menu item")
+			}
+		}
+		s.indexCreatedItems(ctx, imp.VendorID, created)
+		report.ValidRows += len(saved)
+		report.InvalidRows += len(rowErrs)
+		report.Errors = append(report.Errors, rowErrs...)
+
+		processed += len(batch)
+		if err := s.importRepo.UpdateImportProgress(ctx, imp.ID, report.TotalRows, processed); err != nil {
+			return err
+		}
+	}
+
+	return s.completeImport(ctx, imp, report)
+}
+
+// indexCreatedItems adds imported items to search. Like the other index
+// updates it never fails the import.
+func (s *MenuImportService) indexCreatedItems(ctx context.Context, vendorID string, menuItemIDs []string) {
+	if err := s.searchRepo.IndexOutletMenuItems(ctx, vendorID, menuItemIDs); err != nil {
+		s.server.Logger.Error().Err(err).
+			Str("vendor_id", vendorID).
+			Strs("menu_item_ids", menuItemIDs).
+			Msg("Failed to index imported menu items")
+	}
+}
+
+// FailMenuImport ends an import that cannot go on.
+func (s *MenuImportService) FailMenuImport(ctx context.Context, importID, reason string) error {
+	s.server.Logger.Warn().Str("import_id", importID).Str("reason", reason).Msg("Menu import failed")
+	return s.importRepo.FailImport(ctx, importID, reason)
+}
+
+func (s *MenuImportService) completeImport(ctx context.Context, imp *vendor.MenuImport, report *vendor.MenuImportReport) error {
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Row < report.Errors[j].Row
+	})
+	if len(report.Errors) > maxMenuImportErrors {
+		report.ErrorsOmitted = len(report.Errors) - maxMenuImportErrors
+		report.Errors = report.Errors[:maxMenuImportErrors]
+	}
+
+	if err := s.importRepo.CompleteImport(ctx, imp.ID, report); err != nil {
+		return err
+	}
+	s.server.Logger.Info().
+		Str("vendor_id", imp.VendorID).
+		Str("import_id", imp.ID).
+		Bool("dry_run", imp.DryRun).
+		Int("created", report.Created).
+		Int("updated", report.Updated).
+		Int("invalid", report.InvalidRows).
+		Msg("Menu import completed")
+	return nil
+}
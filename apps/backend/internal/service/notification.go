@@ -0,0 +1,405 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	notify "github.com/gitSanje/khajaride/internal/lib/notification"
+	"github.com/gitSanje/khajaride/internal/lib/webpush"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/notification"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+)
+
+type NotificationService struct {
+	server           *server.Server
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationService(s *server.Server, notificationRepo *repository.NotificationRepository) *NotificationService {
+	return &NotificationService{
+		server:           s,
+		notificationRepo: notificationRepo,
+	}
+}
+
+//-- ==================================================
+//-- ORDER EVENTS
+//-- ==================================================
+
+// NotifyOrder sends the messages of an order event to the customer and the
+// vendor, on the channels the event's templates list and the recipient has
+// left on. In-app messages are stored straight away, the other channels are
+// queued for the job server. It is safe to call again for the same event.
+//
+// Notifications are a side effect of the order changing: callers log the
+// error and carry on.
+func (s *NotificationService) NotifyOrder(ctx context.Context, orderID, event string, extra map[string]string) error {
+	oc, err := s.notificationRepo.GetOrderContext(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("load order %s for notifications: %w", orderID, err)
+	}
+
+	data := map[string]string{
+		"OrderRef":     shortRef(oc.OrderID),
+		"VendorName":   oc.VendorName,
+		"CustomerName": oc.CustomerName,
+		"Total":        formatMoney(oc.Currency, oc.Total),
+		"ItemCount":    fmt.Sprint(oc.ItemCount),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	var errs []error
+	for _, rcpt := range s.recipients(ctx, oc) {
+		tmpl, ok := notify.Lookup(event, rcpt.Type)
+		if !ok {
+			continue
+		}
+		if err := s.notifyRecipient(ctx, oc.OrderID, event, tmpl, rcpt, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recipients are the customer and, if it has an account, the vendor.
+func (s *NotificationService) recipients(ctx context.Context, oc *notification.OrderContext) []notification.Recipient {
+	customer := notification.Recipient{
+		Type:   notification.RecipientUser,
+		UserID: oc.UserID,
+		Name:   oc.CustomerName,
+		Email:  deref(oc.CustomerEmail),
+		Phone:  deref(oc.CustomerPhone),
+	}
+	rcpts := []notification.Recipient{customer}
+
+	if oc.VendorUserID != nil {
+		vendor := notification.Recipient{
+			Type:   notification.RecipientVendor,
+			UserID: *oc.VendorUserID,
+			Name:   oc.VendorName,
+			Email:  deref(oc.VendorEmail),
+		}
+		// vendors enter their number freely; SMS needs it in +977 form
+		if phone, ok := user.NormalizePhoneNumber(deref(oc.VendorPhone)); ok {
+			vendor.Phone = phone
+		}
+		rcpts = append(rcpts, vendor)
+	}
+
+	for i := range rcpts {
+		prefs, err := s.notificationRepo.GetPreferences(ctx, rcpts[i].UserID)
+		if err != nil {
+			s.server.Logger.Error().Err(err).Str("user_id", rcpts[i].UserID).Msg("failed to load notification preferences, using defaults")
+			prefs = notification.DefaultPreferences()
+		}
+		rcpts[i].Preferences = prefs
+	}
+	return rcpts
+}
+
+func (s *NotificationService) notifyRecipient(ctx context.Context, orderID, event string, tmpl notify.Template, rcpt notification.Recipient, data map[string]string) error {
+	title, body, err := tmpl.Render(data)
+	if err != nil {
+		return err
+	}
+	deepLink := map[string]string{"orderId": orderID, "event": event}
+
+	var errs []error
+	for _, channel := range tmpl.Channels {
+		if !rcpt.Preferences.Allows(channel) {
+			continue
+		}
+
+		msg := notify.Message{
+			Channel: channel,
+			Title:   title,
+			Body:    body,
+			Data:    deepLink,
+		}
+		switch channel {
+		case notification.ChannelEmail:
+			msg.To = rcpt.Email
+		case notification.ChannelSMS:
+			msg.To = rcpt.Phone
+		case notification.ChannelPush:
+			subs, err := s.notificationRepo.ListPushSubscriptions(ctx, rcpt.UserID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, sub := range subs {
+				msg.Push = append(msg.Push, webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth})
+			}
+		}
+		// nowhere to send it
+		if (channel == notification.ChannelEmail || channel == notification.ChannelSMS) && msg.To == "" {
+			continue
+		}
+		if channel == notification.ChannelPush && len(msg.Push) == 0 {
+			continue
+		}
+
+		status := notification.StatusPending
+		if channel == notification.ChannelInApp {
+			status = notification.StatusDelivered
+		}
+		n, err := s.notificationRepo.CreateNotification(ctx, &notification.Notification{
+			OrderID:        orderID,
+			RecipientType:  rcpt.Type,
+			RecipientID:    &rcpt.UserID,
+			EventType:      event,
+			Channel:        channel,
+			Title:          &title,
+			Message:        body,
+			Data:           deepLink,
+			DeliveryStatus: status,
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// already notified, or nothing to deliver
+		if n == nil || channel == notification.ChannelInApp {
+			continue
+		}
+
+		msg.NotificationID = n.ID
+		task, err := job.NewNotificationTask(msg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := s.server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			errs = append(errs, fmt.Errorf("enqueue %s notification %s: %w", channel, n.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shortRef is the order number shown to people: the first block of the id.
+func shortRef(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+//-- ==================================================
+//-- ORDER EMAILS
+//-- ==================================================
+// These events have their own email layout instead of the generic
+// notification email, so their notification templates leave email out.
+
+// SendPaymentReceipt emails the receipt of a delivered order.
+func (s *NotificationService) SendPaymentReceipt(ctx context.Context, orderID string) error {
+	r, err := s.notificationRepo.GetOrderReceipt(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("load receipt of order %s: %w", orderID, err)
+	}
+	if r.CustomerEmail == nil {
+		return nil
+	}
+
+	data := map[string]string{
+		"CustomerName":   r.CustomerName,
+		"VendorName":     r.VendorName,
+		"OrderRef":       shortRef(r.OrderID),
+		"Subtotal":       formatMoney(r.Currency, r.Subtotal),
+		"DeliveryCharge": formatMoney(r.Currency, r.DeliveryCharge),
+		"Fees":           formatMoney(r.Currency, r.Fees),
+		"Discount":       formatMoney(r.Currency, r.Discount),
+		"Total":          formatMoney(r.Currency, r.Total),
+		"PaymentMethod":  "cash on delivery",
+		"TransactionRef": deref(r.TransactionRef),
+	}
+	if r.PaymentMethod != nil {
+		data["PaymentMethod"] = *r.PaymentMethod
+	}
+	if r.DeliveredAt != nil {
+		data["DeliveredAt"] = r.DeliveredAt.Format("2 Jan 2006, 15:04")
+	}
+
+	task, err := job.NewPaymentReceiptEmailTask(job.OrderEmailPayload{OrderID: r.OrderID, To: *r.CustomerEmail, Data: data})
+	if err != nil {
+		return err
+	}
+	return s.enqueue(task, "payment receipt email")
+}
+
+// SendOrderCancelledEmail tells the customer a vendor cancelled the order,
+// and whether the payment was refunded already (Stripe) or will be (Khalti
+// refunds are made by hand).
+func (s *NotificationService) SendOrderCancelledEmail(ctx context.Context, orderID, reason string) error {
+	oc, err := s.notificationRepo.GetOrderContext(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("load order %s for cancellation email: %w", orderID, err)
+	}
+	if oc.CustomerEmail == nil {
+		return nil
+	}
+
+	data := map[string]string{
+		"CustomerName": oc.CustomerName,
+		"VendorName":   oc.VendorName,
+		"OrderRef":     shortRef(oc.OrderID),
+		"Reason":       reason,
+		"Total":        formatMoney(oc.Currency, oc.Total),
+	}
+	switch oc.PaymentStatus {
+	case "refunded":
+		data["Paid"] = "true"
+		data["RefundAmount"] = data["Total"]
+	case "paid":
+		data["Paid"] = "true"
+	}
+
+	task, err := job.NewOrderCancelledEmailTask(job.OrderEmailPayload{OrderID: oc.OrderID, To: *oc.CustomerEmail, Data: data})
+	if err != nil {
+		return err
+	}
+	return s.enqueue(task, "order cancelled email")
+}
+
+// SendVendorNewOrderEmail tells the vendor a paid order is waiting for it.
+func (s *NotificationService) SendVendorNewOrderEmail(ctx context.Context, orderID string) error {
+	oc, err := s.notificationRepo.GetOrderContext(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("load order %s for vendor email: %w", orderID, err)
+	}
+	if oc.VendorEmail == nil {
+		return nil
+	}
+
+	task, err := job.NewVendorNewOrderEmailTask(job.OrderEmailPayload{
+		OrderID: oc.OrderID,
+		To:      *oc.VendorEmail,
+		Data: map[string]string{
+			"VendorName":   oc.VendorName,
+			"OrderRef":     shortRef(oc.OrderID),
+			"CustomerName": oc.CustomerName,
+			"ItemCount":    fmt.Sprint(oc.ItemCount),
+			"Total":        formatMoney(oc.Currency, oc.Total),
+			"Instructions": deref(oc.DeliveryInstructions),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return s.enqueue(task, "vendor new order email")
+}
+
+func (s *NotificationService) enqueue(task *asynq.Task, what string) error {
+	if _, err := s.server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("enqueue %s: %w", what, err)
+	}
+	return nil
+}
+
+func formatMoney(currency string, amount float64) string {
+	return fmt.Sprintf("%s %.2f", currency, amount)
+}
+
+//-- ==================================================
+//-- INBOX
+//-- ==================================================
+
+func (s *NotificationService) GetInbox(ctx echo.Context, userID string, query *notification.GetInboxQuery) (*model.PaginatedResponse[notification.Notification], error) {
+	return s.notificationRepo.GetInbox(ctx.Request().Context(), userID, query)
+}
+
+func (s *NotificationService) GetInboxSummary(ctx echo.Context, userID string) (*notification.InboxSummary, error) {
+	unread, err := s.notificationRepo.CountUnread(ctx.Request().Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return &notification.InboxSummary{Unread: unread}, nil
+}
+
+func (s *NotificationService) MarkRead(ctx echo.Context, userID string, payload *notification.MarkReadPayload) (*notification.Notification, error) {
+	n, err := s.notificationRepo.MarkRead(ctx.Request().Context(), userID, payload.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "notification not found")
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+func (s *NotificationService) MarkAllRead(ctx echo.Context, userID string) (*notification.InboxSummary, error) {
+	if _, err := s.notificationRepo.MarkAllRead(ctx.Request().Context(), userID); err != nil {
+		return nil, err
+	}
+	return &notification.InboxSummary{Unread: 0}, nil
+}
+
+//-- ==================================================
+//-- PREFERENCES & PUSH SUBSCRIPTIONS
+//-- ==================================================
+
+func (s *NotificationService) GetPreferences(ctx echo.Context, userID string) (*notification.Preferences, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx.Request().Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx echo.Context, userID string, payload *notification.UpdatePreferencesPayload) (*notification.Preferences, error) {
+	logger := middleware.GetLogger(ctx)
+
+	prefs, err := s.notificationRepo.UpdatePreferences(ctx.Request().Context(), userID, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Str("user_id", userID).
+		Bool("email", prefs.EmailEnabled).
+		Bool("sms", prefs.SMSEnabled).
+		Bool("push", prefs.PushEnabled).
+		Msg("Notification preferences updated")
+	return prefs, nil
+}
+
+// GetPushPublicKey is the VAPID key the browser subscribes with.
+func (s *NotificationService) GetPushPublicKey(ctx echo.Context) (map[string]string, error) {
+	if s.server.Config.Push == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "web push is not enabled")
+	}
+	return map[string]string{"publicKey": s.server.Config.Push.VAPIDPublicKey}, nil
+}
+
+func (s *NotificationService) SubscribePush(ctx echo.Context, userID string, payload *notification.SubscribePushPayload) (*notification.PushSubscription, error) {
+	var userAgent *string
+	if ua := ctx.Request().UserAgent(); ua != "" {
+		userAgent = &ua
+	}
+	return s.notificationRepo.UpsertPushSubscription(ctx.Request().Context(), userID, payload, userAgent)
+}
+
+func (s *NotificationService) UnsubscribePush(ctx echo.Context, userID string, payload *notification.DeletePushSubscriptionPayload) error {
+	err := s.notificationRepo.DeleteUserPushSubscription(ctx.Request().Context(), userID, payload.ID)
+	if errors.Is(err, repository.ErrPushSubscriptionNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "push subscription not found")
+	}
+	return err
+}
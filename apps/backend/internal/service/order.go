@@ -1,10 +1,16 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
 
 	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/cart"
+	"github.com/gitSanje/khajaride/internal/model/notification"
 	"github.com/gitSanje/khajaride/internal/model/order"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
 	"github.com/jackc/pgx/v5"
 
 	"github.com/gitSanje/khajaride/internal/repository"
@@ -13,24 +19,53 @@ import (
 )
 
 type OrderService struct {
-	server    *server.Server
-	orderRepo *repository.OrderRepository
-	cartRepo  *repository.CartRepository
+	server      *server.Server
+	orderRepo   *repository.OrderRepository
+	cartRepo    *repository.CartRepository
+	loyaltyRepo  *repository.LoyaltyRepository
+	referralRepo *repository.ReferralRepository
+	userRepo     *repository.UserRepository
+	notificationService *NotificationService
+	stockService        *StockService
 }
 
-func NewOrderService(s *server.Server, orderRepo *repository.OrderRepository, cartRepo *repository.CartRepository) *OrderService {
+func NewOrderService(s *server.Server, orderRepo *repository.OrderRepository, cartRepo *repository.CartRepository, loyaltyRepo *repository.LoyaltyRepository, referralRepo *repository.ReferralRepository, userRepo *repository.UserRepository, notificationService *NotificationService, stockService *StockService) *OrderService {
 	return &OrderService{
-		server:    s,
-		orderRepo: orderRepo,
-		cartRepo:  cartRepo,
+		server:       s,
+		orderRepo:    orderRepo,
+		cartRepo:     cartRepo,
+		loyaltyRepo:  loyaltyRepo,
+		referralRepo: referralRepo,
+		userRepo:     userRepo,
+		notificationService: notificationService,
+		stockService:        stockService,
 	}
 }
 
+// verifiedContactPhone is the number riders and vendors call about an
+// order; only a number verified by OTP is accepted.
+func (s *OrderService) verifiedContactPhone(ctx echo.Context, userID string) (*string, error) {
+	phone, err := s.userRepo.GetVerifiedPhoneNumber(ctx.Request().Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if phone == nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "verify your phone number before checking out")
+	}
+	return phone, nil
+}
+
 func (s *OrderService) CreateOrder(ctx echo.Context, userID string, payload *order.CreateOrderPayload) (string, error) {
 
 	logger := middleware.GetLogger(ctx)
 	ctxx := ctx.Request().Context()
 
+	contactPhone, err := s.verifiedContactPhone(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	payload.ContactPhone = contactPhone
+
 	// Begin Transaction
 	tx, err := s.server.DB.Pool.Begin(ctxx)
 	if err != nil {
@@ -71,6 +106,15 @@ func (s *OrderService) CreateOrder(ctx echo.Context, userID string, payload *ord
 			updateArgs["delivery_instructions"] = payload.DeliveryInstructions
 			needsUpdate = true
 		}
+		if existingOrder.ContactPhone == nil || *existingOrder.ContactPhone != *contactPhone {
+			updateArgs["contact_phone"] = contactPhone
+			needsUpdate = true
+		}
+		if existingOrder.LoyaltyPointsUsed != cartVendor.LoyaltyPointsUsed || existingOrder.LoyaltyDiscount != cartVendor.LoyaltyDiscount {
+			updateArgs["loyalty_points_used"] = cartVendor.LoyaltyPointsUsed
+			updateArgs["loyalty_discount"] = cartVendor.LoyaltyDiscount
+			needsUpdate = true
+		}
 		if cartVendor.DeliveryCharge != nil && existingOrder.DeliveryCharge != *cartVendor.DeliveryCharge {
 			updateArgs["delivery_charge"] = cartVendor.DeliveryCharge
 			needsUpdate = true
@@ -83,6 +127,21 @@ func (s *OrderService) CreateOrder(ctx echo.Context, userID string, payload *ord
 			}
 		} else {
 			oVendor = existingOrder
+
+			// a failed payment gave the stock back; hold it again for the retry
+			cartItems, err := s.cartRepo.ListCartItems(ctxx, tx, cartVendor.ID)
+			if err != nil {
+				return "", err
+			}
+			changes, err := s.stockService.Reserve(ctxx, tx, oVendor.ID, cartVendor.VendorID, cartItems)
+			if err != nil {
+				return "", err
+			}
+			if err := tx.Commit(ctxx); err != nil {
+				return "", err
+			}
+			s.stockService.Publish(ctxx, changes)
+
 			logger.Info().
 				Str("event", "existed order").
 				Str("order vendor", oVendor.ID).
@@ -124,9 +183,16 @@ func (s *OrderService) CreateOrder(ctx echo.Context, userID string, payload *ord
 
 	}
 
+	// 5️⃣ Reserve the stock of tracked items; rows stay locked until commit
+	changes, err := s.stockService.Reserve(ctxx, tx, oVendor.ID, cartVendor.VendorID, cartItems)
+	if err != nil {
+		return "", err
+	}
+
 	if err := tx.Commit(ctxx); err != nil {
 		return "", err
 	}
+	s.stockService.Publish(ctxx, changes)
 
 	return oVendor.ID, nil
 }
@@ -156,4 +222,385 @@ func (s *OrderService) GetOrderByID(ctx echo.Context, payload *order.GetOrderByI
 
     logger.Info().Str("order_id", payload.ID).Msg("User fetched successfully")
     return order, nil
-}
\ No newline at end of file
+}
+
+// CheckoutCart turns every vendor of the user's active cart session into a
+// single order group so the whole cart is paid for at once.
+func (s *OrderService) CheckoutCart(ctx echo.Context, userID string, payload *order.CheckoutCartPayload) (*order.PopulatedOrderGroup, error) {
+
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	contactPhone, err := s.verifiedContactPhone(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.server.DB.Pool.Begin(ctxx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctxx)
+
+	// 1️⃣ Fetch the active cart session and its vendors
+	session, err := s.cartRepo.GetActiveCartSession(ctxx, tx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "no active cart found")
+		}
+		return nil, err
+	}
+
+	cartVendors, err := s.cartRepo.ListCartVendors(ctxx, tx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cartVendors) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "cart is empty")
+	}
+
+	// 2️⃣ Reuse the unpaid group of this session or open a new one
+	group, err := s.orderRepo.GetUnpaidOrderGroupByCartSession(ctxx, tx, session.ID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("failed to check existing order group: %w", err)
+		}
+		group, err = s.orderRepo.CreateOrderGroup(ctxx, tx, userID, session.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	orderPayload := &order.CreateOrderPayload{
+		UserID:               &userID,
+		DeliveryAddressId:    payload.DeliveryAddressId,
+		DeliveryInstructions: payload.DeliveryInstructions,
+		ExpectedDeliveryTime: payload.ExpectedDeliveryTime,
+		ContactPhone:         contactPhone,
+	}
+
+	// 3️⃣ One order_vendor per cart vendor, items rebuilt from the cart
+	orderVendorIDs := make([]string, 0, len(cartVendors))
+	var stockChanges []vendor.StockChange
+	for _, cv := range cartVendors {
+		if cv.Status == "checked_out" {
+			continue
+		}
+
+		orderable, err := s.cartRepo.IsVendorOrderable(ctxx, tx, cv.VendorID)
+		if err != nil {
+			return nil, err
+		}
+		if !orderable {
+			return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("vendor %s in the cart is not accepting orders", cv.VendorID))
+		}
+		inZone, err := s.cartRepo.IsWithinDeliveryZone(ctxx, tx, cv.VendorID, payload.DeliveryAddressId)
+		if err != nil {
+			return nil, err
+		}
+		if !inZone {
+			return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("vendor %s in the cart does not deliver to this address", cv.VendorID))
+		}
+
+		var oVendor *order.OrderVendor
+		existing, err := s.orderRepo.GetOrderVendorByCartID(ctxx, tx, cv.ID)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("failed to check existing order_vendor: %w", err)
+		}
+
+		if existing != nil {
+			if existing.PaymentStatus == "paid" {
+				return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("order for cart vendor %s is already paid", cv.ID))
+			}
+			oVendor, err = s.orderRepo.UpdateOrderVendor(ctxx, tx, pgx.NamedArgs{
+				"id":                    existing.ID,
+				"delivery_address_id":   payload.DeliveryAddressId,
+				"delivery_instructions": payload.DeliveryInstructions,
+				"delivery_charge":       cv.DeliveryCharge,
+				"subtotal":              cv.Subtotal,
+				"vendor_discount":       cv.VendorDiscount,
+				"loyalty_points_used":   cv.LoyaltyPointsUsed,
+				"loyalty_discount":      cv.LoyaltyDiscount,
+				"contact_phone":         contactPhone,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := s.orderRepo.DeleteOrderItems(ctxx, tx, oVendor.ID); err != nil {
+				return nil, err
+			}
+		} else {
+			oVendor, err = s.orderRepo.CreateOrderVendor(ctxx, tx, userID, cv, orderPayload)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.orderRepo.AttachOrderVendorToGroup(ctxx, tx, oVendor.ID, group.ID); err != nil {
+			return nil, err
+		}
+
+		cartItems, err := s.cartRepo.ListCartItems(ctxx, tx, cv.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ci := range cartItems {
+			if err := s.orderRepo.CreateOrderItem(ctxx, tx, oVendor.ID, ci); err != nil {
+				return nil, err
+			}
+		}
+
+		changes, err := s.stockService.Reserve(ctxx, tx, oVendor.ID, cv.VendorID, cartItems)
+		if err != nil {
+			return nil, err
+		}
+		stockChanges = append(stockChanges, changes...)
+
+		orderVendorIDs = append(orderVendorIDs, oVendor.ID)
+	}
+
+	if len(orderVendorIDs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "cart has nothing left to check out")
+	}
+
+	// 4️⃣ Drop orders of vendors removed from the cart since the last checkout
+	// and give back their stock
+	detached, err := s.orderRepo.DetachUnlistedOrderVendors(ctxx, tx, group.ID, orderVendorIDs)
+	if err != nil {
+		return nil, err
+	}
+	released, err := s.stockService.ReleaseTx(ctxx, tx, detached)
+	if err != nil {
+		return nil, err
+	}
+	stockChanges = append(stockChanges, released...)
+
+	group, err = s.orderRepo.RefreshOrderGroupTotal(ctxx, tx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctxx); err != nil {
+		return nil, err
+	}
+	s.stockService.Publish(ctxx, stockChanges)
+
+	logger.Info().
+		Str("event", "order_group_created").
+		Str("order_group_id", group.ID).
+		Int("vendors", len(orderVendorIDs)).
+		Msg("Cart checked out into order group")
+
+	return s.GetOrderGroupByID(ctx, &order.GetOrderGroupByIDPayload{ID: group.ID})
+}
+
+func (s *OrderService) GetOrderGroupByID(ctx echo.Context, payload *order.GetOrderGroupByIDPayload) (*order.PopulatedOrderGroup, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	group, err := s.orderRepo.GetOrderGroupByID(ctxx, payload.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "order group not found")
+		}
+		logger.Error().Err(err).Str("order_group_id", payload.ID).Msg("Failed to fetch order group")
+		return nil, err
+	}
+
+	orders, err := s.orderRepo.ListGroupOrderVendors(ctxx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order.PopulatedOrderGroup{
+		OrderGroup: *group,
+		Orders:     orders,
+	}, nil
+}
+
+//-- ==================================================
+//-- ORDER STATUS (VENDOR)
+//-- ==================================================
+
+// orderStatusTransitions lists where an order can move from each status.
+// Pickup orders go straight from ready_for_pickup to delivered; cancelling is
+// done through RejectVendorOrder so the payment is refunded.
+var orderStatusTransitions = map[string][]string{
+	"pending":          {"accepted"},
+	"accepted":         {"preparing"},
+	"preparing":        {"ready_for_pickup"},
+	"ready_for_pickup": {"assigned", "picked_up", "delivered"},
+	"assigned":         {"picked_up"},
+	"picked_up":        {"delivered"},
+}
+
+func (s *OrderService) UpdateVendorOrderStatus(ctx echo.Context, vendorID string, payload *order.UpdateOrderStatusPayload) (*order.GroupOrderVendor, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	ov, err := s.orderRepo.GetVendorOrder(ctxx, payload.ID, vendorID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		return nil, err
+	}
+
+	allowed := false
+	for _, next := range orderStatusTransitions[ov.Status] {
+		if next == payload.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("order cannot move from %s to %s", ov.Status, payload.Status))
+	}
+	if payload.Status == "delivered" && ov.PaymentStatus != "paid" {
+		return nil, echo.NewHTTPError(http.StatusConflict, "order cannot be delivered before it is paid")
+	}
+
+	if err := s.orderRepo.TransitionOrderVendorStatus(ctxx, ov.ID, ov.Status, payload.Status); err != nil {
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "order was updated by someone else, reload and try again")
+		}
+		return nil, err
+	}
+	ov.Status = payload.Status
+
+	// Points and referral rewards are side effects of delivery; the nightly
+	// loyalty job awards anything missed here.
+	if payload.Status == "delivered" {
+		if _, err := s.loyaltyRepo.AwardOrderPoints(ctxx, ov.ID); err != nil {
+			logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to award loyalty points")
+		}
+		if _, err := s.referralRepo.RewardFirstDelivery(ctxx, ov.UserID, ov.ID); err != nil {
+			logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to issue referral reward")
+		}
+		if err := s.notificationService.SendPaymentReceipt(ctxx, ov.ID); err != nil {
+			logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to send payment receipt")
+		}
+	}
+
+	if err := s.notificationService.NotifyOrder(ctxx, ov.ID, notification.OrderStatusEvent(payload.Status), nil); err != nil {
+		logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to send order status notifications")
+	}
+
+	logger.Info().
+		Str("order_id", ov.ID).
+		Str("status", payload.Status).
+		Msg("Order status updated by vendor")
+	return ov, nil
+}
+
+//-- ==================================================
+//-- REORDER
+//-- ==================================================
+
+// Reorder rebuilds the vendor's cart from one of the user's past orders at
+// today's prices and returns what changed, so the UI can confirm before
+// checkout. Items already in that vendor's cart take the old order's
+// quantity.
+func (s *OrderService) Reorder(ctx echo.Context, userID string, payload *order.ReorderPayload) (*order.ReorderResult, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	src, err := s.orderRepo.GetReorderSource(ctxx, payload.ID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		return nil, err
+	}
+
+	items, err := s.orderRepo.ListReorderItems(ctxx, src.OrderVendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &order.ReorderResult{
+		OrderID:      src.OrderVendorID,
+		VendorID:     src.VendorID,
+		VendorName:   src.VendorName,
+		VendorClosed: !src.VendorIsOpen,
+		Added:        []order.ReorderItem{},
+		Unavailable:  []order.ReorderItem{},
+		PriceChanged: []order.ReorderItem{},
+	}
+	for _, it := range items {
+		res.OriginalSubtotal += it.UnitPrice * float64(it.Quantity)
+		if !it.IsAvailable {
+			res.Unavailable = append(res.Unavailable, it)
+			continue
+		}
+		res.Added = append(res.Added, it)
+		res.NewSubtotal += it.CurrentPrice * float64(it.Quantity)
+		if math.Abs(it.CurrentPrice-it.UnitPrice) >= 0.01 {
+			res.PriceChanged = append(res.PriceChanged, it)
+		}
+	}
+	res.OriginalSubtotal = math.Round(res.OriginalSubtotal*100) / 100
+	res.NewSubtotal = math.Round(res.NewSubtotal*100) / 100
+
+	// A closed vendor's cart could not be checked out; report only
+	if res.VendorClosed || len(res.Added) == 0 {
+		return res, nil
+	}
+
+	tx, err := s.server.DB.Pool.Begin(ctxx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctxx)
+
+	// 1️⃣ Ensure active cart session exists
+	session, err := s.cartRepo.GetActiveCartSession(ctxx, tx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		session, err = s.cartRepo.CreateActiveCartSession(ctxx, tx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 2️⃣ Ensure cart_vendor exists for that vendor
+	cartVendor, err := s.cartRepo.GetCartVendor(ctxx, tx, session.ID, src.VendorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		cartVendor, err = s.cartRepo.CreateActiveCartVendor(ctxx, tx, session.ID, src.VendorID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3️⃣ Add or update the items still on the menu
+	for _, it := range res.Added {
+		if _, err := s.cartRepo.UpsertCartItem(ctxx, tx, cartVendor.ID, &cart.AddCartItemPayload{
+			VendorID:            src.VendorID,
+			MenuItemID:          it.MenuItemID,
+			Quantity:            it.Quantity,
+			UnitPrice:           it.CurrentPrice,
+			SpecialInstructions: it.SpecialInstructions,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4️⃣ Recalculate subtotal for that vendor
+	if err := s.cartRepo.UpdateCartVendorSubtotal(ctxx, tx, cartVendor.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctxx); err != nil {
+		return nil, err
+	}
+	res.CartVendorID = &cartVendor.ID
+
+	logger.Info().
+		Str("order_id", src.OrderVendorID).
+		Str("cart_vendor_id", cartVendor.ID).
+		Int("added", len(res.Added)).
+		Int("unavailable", len(res.Unavailable)).
+		Int("price_changed", len(res.PriceChanged)).
+		Msg("Cart rebuilt from past order")
+
+	return res, nil
+}
@@ -4,35 +4,158 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 
 	"net/http"
 	"net/url"
 
-	"github.com/gitSanje/khajaride/internal/lib/events"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/notification"
+	"github.com/gitSanje/khajaride/internal/model/order"
 	"github.com/gitSanje/khajaride/internal/model/payment"
 	"github.com/gitSanje/khajaride/internal/model/payout"
 	"github.com/gitSanje/khajaride/internal/repository"
 	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stripe/stripe-go/v83"
 	"github.com/stripe/stripe-go/v83/account"
 	"github.com/stripe/stripe-go/v83/accountlink"
+	"github.com/stripe/stripe-go/v83/refund"
+	"github.com/stripe/stripe-go/v83/transfer"
+	"github.com/stripe/stripe-go/v83/transferreversal"
 
 	"github.com/stripe/stripe-go/v83/checkout/session"
 )
 
 type PaymentService struct {
-	server      *server.Server
-	paymentRepo *repository.PaymentRepository
-	orderRepo   *repository.OrderRepository
+	server         *server.Server
+	paymentRepo    *repository.PaymentRepository
+	orderRepo      *repository.OrderRepository
+	settlementRepo *repository.SettlementRepository
+	loyaltyRepo    *repository.LoyaltyRepository
+	invoiceService *InvoiceService
+	notificationService *NotificationService
+	stockService        *StockService
 }
 
-func NewPaymentService(s *server.Server, paymentRepo *repository.PaymentRepository, orderRepo *repository.OrderRepository) *PaymentService {
+func NewPaymentService(s *server.Server, paymentRepo *repository.PaymentRepository, orderRepo *repository.OrderRepository, settlementRepo *repository.SettlementRepository, loyaltyRepo *repository.LoyaltyRepository, invoiceService *InvoiceService, notificationService *NotificationService, stockService *StockService) *PaymentService {
 	return &PaymentService{
-		server:      s,
-		paymentRepo: paymentRepo,
-		orderRepo:   orderRepo,
+		server:         s,
+		paymentRepo:    paymentRepo,
+		orderRepo:      orderRepo,
+		settlementRepo: settlementRepo,
+		loyaltyRepo:    loyaltyRepo,
+		invoiceService: invoiceService,
+		notificationService: notificationService,
+		stockService:        stockService,
+	}
+}
+
+// reverseRedemption gives back loyalty points an order redeemed once its
+// payment failed or it was refunded. Orders that are still paid keep them.
+func (ps *PaymentService) reverseRedemption(ctx context.Context, orderID string) {
+	if orderID == "" {
+		return
+	}
+	if _, err := ps.loyaltyRepo.ReverseOrderRedemption(ctx, orderID); err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_id", orderID).Msg("failed to reverse loyalty redemption")
+	}
+}
+
+func (ps *PaymentService) reverseGroupRedemption(ctx context.Context, orderGroupID string) {
+	if orderGroupID == "" {
+		return
+	}
+	orders, err := ps.orderRepo.ListGroupOrderVendors(ctx, orderGroupID)
+	if err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_group_id", orderGroupID).Msg("failed to reverse loyalty redemption")
+		return
+	}
+	for _, o := range orders {
+		ps.reverseRedemption(ctx, o.ID)
+	}
+}
+
+// releaseGroupStock gives back the stock every order of a group reserved.
+func (ps *PaymentService) releaseGroupStock(ctx context.Context, orderGroupID string) {
+	if orderGroupID == "" {
+		return
+	}
+	orders, err := ps.orderRepo.ListGroupOrderVendors(ctx, orderGroupID)
+	if err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_group_id", orderGroupID).Msg("failed to release stock")
+		return
+	}
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	ps.stockService.Release(ctx, ids...)
+}
+
+// sendConfirmation queues the confirmation email of a paid order; the email
+// worker issues the invoice. It never fails the payment.
+func (ps *PaymentService) sendConfirmation(ctx context.Context, orderID string) {
+	if err := ps.invoiceService.SendOrderConfirmation(ctx, orderID, []string{orderID}); err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_id", orderID).Msg("failed to send order confirmation")
+	}
+}
+
+// sendGroupConfirmation sends one email for the whole cart with the invoice
+// of every vendor attached.
+func (ps *PaymentService) sendGroupConfirmation(ctx context.Context, orderGroupID string) {
+	orders, err := ps.orderRepo.ListGroupOrderVendors(ctx, orderGroupID)
+	if err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_group_id", orderGroupID).Msg("failed to send order confirmation")
+		return
+	}
+	orderIDs := make([]string, 0, len(orders))
+	for _, o := range orders {
+		orderIDs = append(orderIDs, o.ID)
+	}
+	if err := ps.invoiceService.SendOrderConfirmation(ctx, orderGroupID, orderIDs); err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_group_id", orderGroupID).Msg("failed to send order confirmation")
+	}
+}
+
+// notifyPayment tells the customer how the payment of an order went and,
+// once it is paid, the vendor that it has a new order.
+func (ps *PaymentService) notifyPayment(ctx context.Context, orderID string, succeeded bool) {
+	if orderID == "" {
+		return
+	}
+	events := []string{notification.EventPaymentFailed}
+	if succeeded {
+		events = []string{notification.EventPaymentSucceeded, notification.EventOrderNew}
+	}
+	for _, event := range events {
+		if err := ps.notificationService.NotifyOrder(ctx, orderID, event, nil); err != nil {
+			ps.server.Logger.Error().Err(err).Str("order_id", orderID).Str("event", event).Msg("failed to send payment notifications")
+		}
+	}
+	if succeeded {
+		if err := ps.notificationService.SendVendorNewOrderEmail(ctx, orderID); err != nil {
+			ps.server.Logger.Error().Err(err).Str("order_id", orderID).Msg("failed to send new order email")
+		}
+	}
+}
+
+// notifyGroupPayment notifies every vendor order of a group, so each vendor
+// hears about its own part.
+func (ps *PaymentService) notifyGroupPayment(ctx context.Context, orderGroupID string, succeeded bool) {
+	if orderGroupID == "" {
+		return
+	}
+	orders, err := ps.orderRepo.ListGroupOrderVendors(ctx, orderGroupID)
+	if err != nil {
+		ps.server.Logger.Error().Err(err).Str("order_group_id", orderGroupID).Msg("failed to send payment notifications")
+		return
+	}
+	for _, o := range orders {
+		ps.notifyPayment(ctx, o.ID, succeeded)
 	}
 }
 
@@ -53,6 +176,28 @@ func (ps *PaymentService) ProcessKhaltiPayment(c echo.Context, payload *payment.
 	// 	return nil, echo.NewHTTPError(http.StatusConflict, "Payment already completed for this order")
 	// }
 
+	khaltiResp, err := ps.initiateKhaltiPayment(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4️⃣ Store payment info
+	p := &payment.OrderPayment{
+		OrderID:        payload.PurchaseOrderID,
+		PaymentGateway: "khalti",
+		TransactionID:  khaltiResp.Pidx,
+		Amount:         payload.Amount,
+		Status:         "initiated",
+		Method:         "khalti",
+	}
+	if err := ps.paymentRepo.CreateOrUpdateOrderPayment(ctx, p); err != nil {
+		return nil, fmt.Errorf("store payment info: %w", err)
+	}
+
+	return khaltiResp, nil
+}
+
+func (ps *PaymentService) initiateKhaltiPayment(payload *payment.KhaltiPaymentPayload) (*payment.KhaltiPaymentResponse, error) {
 	// 2️⃣ Prepare Khalti request
 	body := map[string]interface{}{
 		"return_url":          ps.server.Config.Khalti.ReturnURL,
@@ -80,19 +225,6 @@ func (ps *PaymentService) ProcessKhaltiPayment(c echo.Context, payload *payment.
 		return nil, fmt.Errorf("decode khalti response: %w", err)
 	}
 
-	// 4️⃣ Store payment info
-	p := &payment.OrderPayment{
-		OrderID:        payload.PurchaseOrderID,
-		PaymentGateway: "khalti",
-		TransactionID:  khaltiResp.Pidx,
-		Amount:         payload.Amount,
-		Status:         "initiated",
-		Method:         "khalti",
-	}
-	if err := ps.paymentRepo.CreateOrUpdateOrderPayment(ctx, p); err != nil {
-		return nil, fmt.Errorf("store payment info: %w", err)
-	}
-
 	return &khaltiResp, nil
 }
 
@@ -118,12 +250,23 @@ func (ps *PaymentService) VerifyKhaltiPayment(c echo.Context, payload *payment.K
 
 	status := verifyResp.Status
 
+	if _, err := ps.paymentRepo.GetOrderGroupPaymentByTransactionID(ctx, pidx); err == nil {
+		if _, err := ps.settleKhaltiGroupPayment(ctx, pidx, status); err != nil {
+			return nil, err
+		}
+		return &verifyResp, nil
+	}
+
 	// 2️⃣ Update payment and order status
 	if status == "Completed" {
 		if orderID, err := ps.paymentRepo.UpdatePaymentStatus(ctx, pidx, "success"); err == nil {
-			if err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID); err != nil {
+			changes, err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID, "khalti")
+			if err != nil {
 				return nil, fmt.Errorf("update order: %w", err)
 			}
+			ps.stockService.Publish(ctx, changes)
+			ps.sendConfirmation(ctx, orderID)
+			ps.notifyPayment(ctx, orderID, true)
 		}
 
 	} else {
@@ -155,17 +298,30 @@ func (ps *PaymentService) VerifyAndUpdateKhaltiPayment(ctx context.Context, payl
 	status := verifyResp.Status
 	var orderID string
 
+	// Combined payment for a multi-vendor cart
+	if _, err := ps.paymentRepo.GetOrderGroupPaymentByTransactionID(ctx, pidx); err == nil {
+		groupID, err := ps.settleKhaltiGroupPayment(ctx, pidx, status)
+		return groupID, status, err
+	}
+
 	// 2️⃣ Update payment + order
 	if status == "Completed" {
 		if oid, err := ps.paymentRepo.UpdatePaymentStatus(ctx, pidx, "success"); err == nil {
 			orderID = oid
-			if err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID); err != nil {
+			changes, err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID, "khalti")
+			if err != nil {
 				return "", "", fmt.Errorf("update order: %w", err)
 			}
+			ps.stockService.Publish(ctx, changes)
+			ps.sendConfirmation(ctx, orderID)
+			ps.notifyPayment(ctx, orderID, true)
 		}
 	} else {
 		oid, _ := ps.paymentRepo.UpdatePaymentStatus(ctx, pidx, "failed")
 		orderID = oid
+		ps.reverseRedemption(ctx, orderID)
+		ps.stockService.Release(ctx, orderID)
+		ps.notifyPayment(ctx, orderID, false)
 	}
 
 	return orderID, status, nil
@@ -178,13 +334,28 @@ func (ps *PaymentService) VerifyAndUpdateKhaltiPayment(ctx context.Context, payl
 func (ps *PaymentService) ProcessStripeCheckout(c echo.Context, payload *payment.StripePaymentPayload) (*payment.StripePaymentResponse, error) {
 
 	ctx := c.Request().Context()
-	accountId, err := ps.paymentRepo.GetStripeAccountID(ctx, payload.VendorUserId)
+	// the amount and the fee come from the order and its vendor, never from
+	// the request
+	charge, err := ps.paymentRepo.GetOrderCharge(ctx, payload.PurchaseOrderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		return nil, err
+	}
+	if charge.PaymentStatus != "unpaid" {
+		return nil, echo.NewHTTPError(http.StatusConflict, "order is already paid")
+	}
+	amount := charge.Total
+
+	// an outlet may be paid into its own account rather than the owner's
+	_, accountId, err := ps.paymentRepo.GetOrderPayoutAccount(ctx, payload.PurchaseOrderID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching the connectAccountID:%s", err)
 	}
 
 	if accountId == "" {
-		return nil, fmt.Errorf("stripe connected account not found for vendor user ID: %s", payload.VendorUserId)
+		return nil, fmt.Errorf("stripe connected account not found for order: %s", payload.PurchaseOrderID)
 	}
 	stripe.Key = ps.server.Config.Stripe.SecretKey
 
@@ -193,9 +364,10 @@ func (ps *PaymentService) ProcessStripeCheckout(c echo.Context, payload *payment
 		ps.server.Config.Stripe.SuccessURL,
 		url.QueryEscape(payload.PurchaseOrderID),
 		url.QueryEscape(payload.PurchaseOrderName),
-		payload.Amount,
+		amount,
 	))
-	fee := int64(payload.Amount * 100 * 0.03)
+	// commission_rate is a percent, so amount * rate is the fee in cents
+	fee := int64(math.Round(amount * charge.CommissionRate))
 
 // 	Parse request → apply rules → create CheckoutSession object →
 //   determine payment mode →
@@ -221,7 +393,7 @@ func (ps *PaymentService) ProcessStripeCheckout(c echo.Context, payload *payment
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name: stripe.String(payload.PurchaseOrderName),
 					},
-					UnitAmount: stripe.Int64(int64(payload.Amount * 100)), // Stripe uses cents
+					UnitAmount: stripe.Int64(int64(math.Round(amount * 100))), // Stripe uses cents
 				},
 				Quantity: stripe.Int64(1),
 			},
@@ -234,15 +406,15 @@ func (ps *PaymentService) ProcessStripeCheckout(c echo.Context, payload *payment
 			},
 			Metadata: map[string]string{
 				"purchase_order_id": payload.PurchaseOrderID,
-				"vendor_user_id":    payload.VendorUserId,
-				"amount":            fmt.Sprintf("%f", payload.Amount),
+				"vendor_user_id":    charge.VendorUserID,
+				"amount":            fmt.Sprintf("%f", amount),
 				"stripe_connect_acc_id":accountId,
 			},
 		},
 		Metadata: map[string]string{
 				"purchase_order_id": payload.PurchaseOrderID,
-				"vendor_user_id":    payload.VendorUserId,
-				"amount":            fmt.Sprintf("%f", payload.Amount),
+				"vendor_user_id":    charge.VendorUserID,
+				"amount":            fmt.Sprintf("%f", amount),
 				"stripe_connect_acc_id":accountId,
 			},
 	}
@@ -257,7 +429,7 @@ func (ps *PaymentService) ProcessStripeCheckout(c echo.Context, payload *payment
 		OrderID:        payload.PurchaseOrderID,
 		PaymentGateway: "stripe",
 		TransactionID:  s.ID,
-		Amount:         payload.Amount,
+		Amount:         amount,
 		Status:         "initiated",
 		Method:         "stripe",
 	}
@@ -284,7 +456,6 @@ func (ps *PaymentService) VerifyAndUpdateStripePayment(
 		return "", "", fmt.Errorf("fetch stripe session: %w", err)
 	}
 	vendorUserId := sess.Metadata["vendor_user_id"]
-	StripeConnectAccId:= sess.Metadata["stripe_connect_acc_id"]
 	// 2️⃣ Determine payment status
 	status := string(sess.PaymentStatus) // "paid", "unpaid", "no_payment_required"
 	var orderID string
@@ -296,11 +467,15 @@ func (ps *PaymentService) VerifyAndUpdateStripePayment(
 		if oid, err := ps.paymentRepo.UpdatePaymentStatus(ctx, sessionID, "success"); err == nil {
 			orderID = oid
 			// Mark order as paid
-			if err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID); err != nil {
+			changes, err := ps.orderRepo.MarkOrderPaidAndCheckout(ctx, orderID, "stripe")
+			if err != nil {
 				return "", "", fmt.Errorf("update order: %w", err)
 			}
+			ps.stockService.Publish(ctx, changes)
+			ps.sendConfirmation(ctx, orderID)
+			ps.notifyPayment(ctx, orderID, true)
 			var payoutAccId string
-			if payoutAccId, err = ps.paymentRepo.GetPayoutAccountID(ctx, vendorUserId); err != nil {
+			if payoutAccId, _, err = ps.paymentRepo.GetOrderPayoutAccount(ctx, orderID); err != nil {
 				return "", "", fmt.Errorf("get payout accountid: %w", err)
 			}
 			p := &payout.Payout{
@@ -318,19 +493,13 @@ func (ps *PaymentService) VerifyAndUpdateStripePayment(
 			if _,err := ps.paymentRepo.CreatePayout(ctx, p); err != nil {
 				return "", "", fmt.Errorf("create payout: %w", err)
 			}
-			
-			events.PublishPayoutRequested(ctx, events.PayoutRequestedEvent{
-                OrderID:      orderID,
-				VendorUserID: vendorUserId,
-				Amount:       amount,
-				SessionId: sessionID,
-				PayoutAccId:payoutAccId,
-				StripeConnectAccId: StripeConnectAccId,
-			})
 		}
 	case "unpaid":
 		oid, _ := ps.paymentRepo.UpdatePaymentStatus(ctx, sessionID, "failed")
 		orderID = oid
+		ps.reverseRedemption(ctx, orderID)
+		ps.stockService.Release(ctx, orderID)
+		ps.notifyPayment(ctx, orderID, false)
 	default:
 		// fallback for any other payment state
 		oid, _ := ps.paymentRepo.UpdatePaymentStatus(ctx, sessionID, string(sess.PaymentStatus))
@@ -347,11 +516,15 @@ func (ps *PaymentService) StripeCancelPayment(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "purchase_order_id is required")
 	}
 
-	// 1️⃣ Update payment as failed
-	if _, err := ps.paymentRepo.UpdatePaymentStatus(ctx, orderID, "failed"); err != nil {
+	// 1️⃣ Update payment as failed; the cancel URL only carries the order,
+	// not the checkout session
+	if err := ps.paymentRepo.FailOrderPayment(ctx, orderID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("update payment failed: %v", err))
 	}
 
+	// 2️⃣ Give back the stock the order held, unless it got paid after all
+	ps.stockService.ReleaseUnpaid(ctx, orderID)
+
 	// 3️⃣ Redirect user to frontend failure page
 	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/payment-failed?purchase_order_id=%s",
 		ps.server.Config.Stripe.FrontEndURL, orderID))
@@ -479,8 +652,9 @@ func (ps *PaymentService) CreatePayout(ctx context.Context, p *payout.Payout) (s
 	return ps.paymentRepo.CreatePayout(ctx, p)
 }
 
-func (ps *PaymentService) GetPayoutAccountID(ctx context.Context, vendorUserID string) (string, error) {
-	return ps.paymentRepo.GetPayoutAccountID(ctx, vendorUserID)
+func (ps *PaymentService) GetOrderPayoutAccountID(ctx context.Context, orderVendorID string) (string, error) {
+	accountID, _, err := ps.paymentRepo.GetOrderPayoutAccount(ctx, orderVendorID)
+	return accountID, err
 }
 
 
@@ -495,13 +669,551 @@ func (r *PaymentService) HandlePayoutStatus(ctx context.Context,event stripe.Eve
 	}
 
 	internalID := payout.Metadata["payout_id"]
+	settlementID := payout.Metadata["settlement_id"]
 
 	switch event.Type {
 	case "payout.paid":
-		return r.UpdatePayoutStatus(ctx, internalID, "paid")
+		if settlementID != "" {
+			st, err := r.settlementRepo.MarkSettlementPaid(ctx, settlementID, stripe.String(payout.ID))
+			if err != nil {
+				return err
+			}
+			if err := sendPayoutSentEmail(ctx, r.server, r.settlementRepo, st); err != nil {
+				r.server.Logger.Error().Err(err).Str("settlement_id", st.ID).Msg("failed to send payout email")
+			}
+			return nil
+		}
+		return r.UpdatePayoutStatus(ctx, internalID, "completed")
 	case "payout.failed":
+		if settlementID != "" {
+			return r.settlementRepo.MarkSettlementFailed(ctx, settlementID, internalID, string(payout.FailureCode))
+		}
 		return r.UpdatePayoutStatus(ctx, internalID, "failed")
 	default:
 		return nil
 	}
-}
\ No newline at end of file
+}
+
+// HandleSettlementTransfer marks a settlement paid once its transfer to the
+// vendor's connected account has been created. Transfers without a
+// settlement (group splits) are ignored, and so are redelivered events.
+func (r *PaymentService) HandleSettlementTransfer(ctx context.Context, event stripe.Event) error {
+	var t stripe.Transfer
+	if err := json.Unmarshal(event.Data.Raw, &t); err != nil {
+		return err
+	}
+
+	settlementID := t.Metadata["settlement_id"]
+	if settlementID == "" {
+		return nil
+	}
+
+	st, err := r.settlementRepo.MarkSettlementPaid(ctx, settlementID, stripe.String(t.ID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if err := sendPayoutSentEmail(ctx, r.server, r.settlementRepo, st); err != nil {
+		r.server.Logger.Error().Err(err).Str("settlement_id", st.ID).Msg("failed to send payout email")
+	}
+	return nil
+}
+
+// -- ==================================================
+// -- ORDER GROUP PAYMENT (one payment for a multi-vendor cart)
+// -- ==================================================
+
+// prepareGroupSplits loads an unpaid group and records what each vendor is
+// owed out of the combined payment.
+func (ps *PaymentService) prepareGroupSplits(ctx context.Context, userID, orderGroupID string) (*order.OrderGroup, []order.GroupOrderVendor, error) {
+	group, err := ps.orderRepo.GetOrderGroupByID(ctx, orderGroupID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, echo.NewHTTPError(http.StatusNotFound, "order group not found")
+		}
+		return nil, nil, fmt.Errorf("fetch order group: %w", err)
+	}
+	if group.UserID != userID {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "order group not found")
+	}
+	if group.PaymentStatus != "unpaid" {
+		return nil, nil, echo.NewHTTPError(http.StatusConflict, "order group is already "+group.PaymentStatus)
+	}
+
+	orders, err := ps.orderRepo.ListGroupOrderVendors(ctx, group.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "order group has no orders")
+	}
+
+	for _, o := range orders {
+		var stripeAccountID *string
+		_, accountID, err := ps.paymentRepo.GetOrderPayoutAccount(ctx, o.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accountID != "" {
+			stripeAccountID = stripe.String(accountID)
+		}
+
+		split := &payment.OrderVendorSplit{
+			OrderGroupID:    group.ID,
+			OrderVendorID:   o.ID,
+			VendorUserID:    o.VendorUserID,
+			StripeAccountID: stripeAccountID,
+			Amount:          o.Total,
+			Commission:      math.Round(o.Total*o.CommissionRate) / 100,
+		}
+		if err := ps.paymentRepo.UpsertOrderVendorSplit(ctx, split); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return group, orders, nil
+}
+
+func (ps *PaymentService) ProcessStripeGroupCheckout(c echo.Context, payload *payment.GroupPaymentPayload) (*payment.StripePaymentResponse, error) {
+	ctx := c.Request().Context()
+	logger := middleware.GetLogger(c)
+
+	group, orders, err := ps.prepareGroupSplits(ctx, middleware.GetUserID(c), payload.OrderGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	stripe.Key = ps.server.Config.Stripe.SecretKey
+
+	// One line item per vendor so the customer sees the breakdown
+	lineItems := make([]*stripe.CheckoutSessionLineItemParams, 0, len(orders))
+	for _, o := range orders {
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String("usd"),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name: stripe.String(o.VendorName),
+				},
+				UnitAmount: stripe.Int64(int64(math.Round(o.Total * 100))),
+			},
+			Quantity: stripe.Int64(1),
+		})
+	}
+
+	metadata := map[string]string{
+		"order_group_id": group.ID,
+		"user_id":        group.UserID,
+		"amount":         fmt.Sprintf("%f", group.Total),
+	}
+
+	// Separate charges and transfers: the platform collects the whole cart and
+	// moves each vendor's share once the payment succeeds.
+	// https://docs.stripe.com/connect/separate-charges-and-transfers
+	params := &stripe.CheckoutSessionParams{
+		Mode: stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL: stripe.String(fmt.Sprintf(
+			"%s?session_id={CHECKOUT_SESSION_ID}&purchase_order_id=%s&purchase_order_name=%s&amount=%f",
+			ps.server.Config.Stripe.SuccessURL,
+			url.QueryEscape(group.ID),
+			url.QueryEscape("order-group"),
+			group.Total,
+		)),
+		CancelURL: stripe.String(fmt.Sprintf("%s/?purchase_order_id=%s", ps.server.Config.Stripe.CancelURL, url.QueryEscape(group.ID))),
+		LineItems: lineItems,
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			TransferGroup: stripe.String(group.ID),
+			Metadata:      metadata,
+		},
+		Metadata: metadata,
+	}
+
+	s, err := session.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("create stripe session: %w", err)
+	}
+
+	gp := &payment.OrderGroupPayment{
+		OrderGroupID:   group.ID,
+		PaymentGateway: "stripe",
+		TransactionID:  s.ID,
+		Amount:         group.Total,
+		Status:         "initiated",
+		Method:         "stripe",
+	}
+	if err := ps.paymentRepo.CreateOrUpdateOrderGroupPayment(ctx, gp); err != nil {
+		return nil, fmt.Errorf("store group payment info: %w", err)
+	}
+
+	logger.Info().
+		Str("order_group_id", group.ID).
+		Str("session_id", s.ID).
+		Int("vendors", len(orders)).
+		Msg("Stripe group checkout created")
+
+	return &payment.StripePaymentResponse{PaymentUrl: s.URL}, nil
+}
+
+func (ps *PaymentService) ProcessKhaltiGroupPayment(c echo.Context, payload *payment.GroupPaymentPayload) (*payment.KhaltiPaymentResponse, error) {
+	ctx := c.Request().Context()
+
+	group, _, err := ps.prepareGroupSplits(ctx, middleware.GetUserID(c), payload.OrderGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	khaltiResp, err := ps.initiateKhaltiPayment(&payment.KhaltiPaymentPayload{
+		Amount:            group.Total,
+		PurchaseOrderID:   group.ID,
+		PurchaseOrderName: "order-group",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gp := &payment.OrderGroupPayment{
+		OrderGroupID:   group.ID,
+		PaymentGateway: "khalti",
+		TransactionID:  khaltiResp.Pidx,
+		Amount:         group.Total,
+		Status:         "initiated",
+		Method:         "khalti",
+	}
+	if err := ps.paymentRepo.CreateOrUpdateOrderGroupPayment(ctx, gp); err != nil {
+		return nil, fmt.Errorf("store group payment info: %w", err)
+	}
+
+	return khaltiResp, nil
+}
+
+// VerifyAndUpdateStripeGroupPayment settles a group checkout session. It is
+// safe to call again for the same session: only splits that were not yet
+// transferred are retried.
+func (ps *PaymentService) VerifyAndUpdateStripeGroupPayment(ctx context.Context, sessionID string) (string, string, error) {
+	stripe.Key = ps.server.Config.Stripe.SecretKey
+
+	params := &stripe.CheckoutSessionParams{}
+	params.AddExpand("payment_intent.latest_charge")
+	sess, err := session.Get(sessionID, params)
+	if err != nil {
+		return "", "", fmt.Errorf("fetch stripe session: %w", err)
+	}
+
+	status := string(sess.PaymentStatus)
+	if status != "paid" {
+		groupID, _ := ps.paymentRepo.UpdateOrderGroupPaymentStatus(ctx, sessionID, "failed", nil)
+		ps.reverseGroupRedemption(ctx, groupID)
+		ps.releaseGroupStock(ctx, groupID)
+		ps.notifyGroupPayment(ctx, groupID, false)
+		return groupID, status, nil
+	}
+
+	var chargeID *string
+	if sess.PaymentIntent != nil && sess.PaymentIntent.LatestCharge != nil {
+		chargeID = stripe.String(sess.PaymentIntent.LatestCharge.ID)
+	}
+
+	groupID, err := ps.paymentRepo.UpdateOrderGroupPaymentStatus(ctx, sessionID, "success", chargeID)
+	if err != nil {
+		return "", "", fmt.Errorf("update group payment: %w", err)
+	}
+	paid, changes, err := ps.orderRepo.MarkOrderGroupPaidAndCheckout(ctx, groupID, "stripe")
+	if err != nil {
+		return "", "", err
+	}
+	ps.stockService.Publish(ctx, changes)
+	if paid {
+		ps.sendGroupConfirmation(ctx, groupID)
+		ps.notifyGroupPayment(ctx, groupID, true)
+	}
+
+	if err := ps.transferGroupSplits(ctx, groupID, chargeID, sessionID); err != nil {
+		return "", "", err
+	}
+
+	return groupID, status, nil
+}
+
+// transferGroupSplits moves each vendor's share to its connected account. A
+// failing vendor is marked transfer_failed and does not block the others.
+func (ps *PaymentService) transferGroupSplits(ctx context.Context, orderGroupID string, chargeID *string, sessionID string) error {
+	splits, err := ps.paymentRepo.ListOrderVendorSplits(ctx, orderGroupID)
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range splits {
+		if sp.Status != "pending" && sp.Status != "transfer_failed" {
+			continue
+		}
+
+		if sp.StripeAccountID == nil || *sp.StripeAccountID == "" {
+			_ = ps.paymentRepo.UpdateOrderVendorSplit(ctx, sp.ID, "transfer_failed", nil, nil,
+				stripe.String("vendor has no connected stripe account"))
+			continue
+		}
+
+		params := &stripe.TransferParams{
+			Amount:            stripe.Int64(int64(math.Round(sp.VendorShare * 100))),
+			Currency:          stripe.String("usd"),
+			Destination:       sp.StripeAccountID,
+			TransferGroup:     stripe.String(orderGroupID),
+			SourceTransaction: chargeID,
+		}
+		params.AddMetadata("order_group_id", orderGroupID)
+		params.AddMetadata("order_id", sp.OrderVendorID)
+		// a redelivered webhook gets the same transfer back instead of paying
+		// the vendor twice
+		params.SetIdempotencyKey(fmt.Sprintf("group-transfer-%s-%s", orderGroupID, sp.OrderVendorID))
+
+		t, err := transfer.New(params)
+		if err != nil {
+			_ = ps.paymentRepo.UpdateOrderVendorSplit(ctx, sp.ID, "transfer_failed", nil, nil, stripe.String(err.Error()))
+			continue
+		}
+		if err := ps.paymentRepo.MarkOrderVendorSplitTransferred(ctx, &sp, t.ID); err != nil {
+			return err
+		}
+
+		if sp.VendorUserID == nil {
+			continue
+		}
+		payoutAccID, _, err := ps.paymentRepo.GetOrderPayoutAccount(ctx, sp.OrderVendorID)
+		if err != nil {
+			return fmt.Errorf("get payout accountid: %w", err)
+		}
+		entries := []*payout.Payout{
+			{
+				VendorUserID:   sp.VendorUserID,
+				Sender:         "customer",
+				OrderID:        stripe.String(sp.OrderVendorID),
+				PayoutType:     "user_payout",
+				AccountID:      stripe.String(payoutAccID),
+				Method:         "stripe",
+				Amount:         sp.Amount,
+				TransactionRef: stripe.String(sessionID),
+				Status:         "completed",
+			},
+			{
+				VendorUserID:   sp.VendorUserID,
+				Sender:         "platform",
+				OrderID:        stripe.String(sp.OrderVendorID),
+				PayoutType:     "commission",
+				AccountID:      stripe.String(payoutAccID),
+				Method:         "stripe",
+				Amount:         sp.Commission,
+				TransactionRef: stripe.String(t.ID),
+				Status:         "completed",
+			},
+		}
+		for _, p := range entries {
+			if _, err := ps.paymentRepo.CreatePayout(ctx, p); err != nil {
+				return fmt.Errorf("create payout: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// settleKhaltiGroupPayment marks a group paid after Khalti confirms it. Khalti
+// has no split payments, so vendor splits stay pending until settlement.
+func (ps *PaymentService) settleKhaltiGroupPayment(ctx context.Context, pidx string, status string) (string, error) {
+	if status != "Completed" {
+		groupID, _ := ps.paymentRepo.UpdateOrderGroupPaymentStatus(ctx, pidx, "failed", nil)
+		ps.reverseGroupRedemption(ctx, groupID)
+		ps.releaseGroupStock(ctx, groupID)
+		ps.notifyGroupPayment(ctx, groupID, false)
+		return groupID, nil
+	}
+
+	groupID, err := ps.paymentRepo.UpdateOrderGroupPaymentStatus(ctx, pidx, "success", nil)
+	if err != nil {
+		return "", fmt.Errorf("update group payment: %w", err)
+	}
+	paid, changes, err := ps.orderRepo.MarkOrderGroupPaidAndCheckout(ctx, groupID, "khalti")
+	if err != nil {
+		return "", err
+	}
+	ps.stockService.Publish(ctx, changes)
+	if paid {
+		ps.sendGroupConfirmation(ctx, groupID)
+		ps.notifyGroupPayment(ctx, groupID, true)
+	}
+	return groupID, nil
+}
+
+// RejectVendorOrder lets a vendor reject its part of an order and refunds it
+// if it was paid. If the order was paid as part of a group only that vendor's
+// share is refunded; the other vendors' orders are left untouched.
+func (ps *PaymentService) RejectVendorOrder(c echo.Context, vendorID string, payload *order.RejectOrderPayload) error {
+	ctx := c.Request().Context()
+	logger := middleware.GetLogger(c)
+
+	ov, err := ps.orderRepo.GetVendorOrder(ctx, payload.ID, vendorID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, "order not found")
+		}
+		return err
+	}
+	if ov.Status != "pending" && ov.Status != "accepted" {
+		return echo.NewHTTPError(http.StatusConflict, "order can no longer be rejected: "+ov.Status)
+	}
+
+	// Cancel first, from the status checked above, so an order moved on in
+	// the meantime (e.g. delivered) is not cancelled and refunded
+	if err := ps.orderRepo.TransitionOrderVendorStatus(ctx, ov.ID, ov.Status, "cancelled"); err != nil {
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return echo.NewHTTPError(http.StatusConflict, "order was updated by someone else, reload and try again")
+		}
+		return err
+	}
+
+	if ov.PaymentStatus == "paid" {
+		var err error
+		if ov.OrderGroupID != nil {
+			err = ps.refundGroupSplit(ctx, *ov.OrderGroupID, ov.ID, payload.Reason)
+		} else {
+			err = ps.refundOrder(ctx, ov)
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to refund rejected order")
+			// nothing moves a cancelled order on, so it can be put back for
+			// the vendor to reject again
+			if err := ps.orderRepo.TransitionOrderVendorStatus(ctx, ov.ID, "cancelled", ov.Status); err != nil {
+				logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to restore order after failed refund")
+			}
+			return err
+		}
+	}
+	ps.reverseRedemption(ctx, ov.ID)
+	ps.stockService.Release(ctx, ov.ID)
+
+	var extra map[string]string
+	if payload.Reason != nil {
+		extra = map[string]string{"Reason": *payload.Reason}
+	}
+	if err := ps.notificationService.NotifyOrder(ctx, ov.ID, notification.EventOrderCancelled, extra); err != nil {
+		logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to send cancellation notifications")
+	}
+	if err := ps.notificationService.SendOrderCancelledEmail(ctx, ov.ID, extra["Reason"]); err != nil {
+		logger.Error().Err(err).Str("order_id", ov.ID).Msg("failed to send cancellation email")
+	}
+
+	logger.Info().
+		Str("order_id", ov.ID).
+		Str("vendor_id", vendorID).
+		Msg("Order rejected by vendor")
+	return nil
+}
+
+// refundOrder refunds a paid order that is not part of a group. Its Stripe
+// payment is a destination charge, so the refund pulls the vendor's share
+// and the platform's fee back with it.
+func (ps *PaymentService) refundOrder(ctx context.Context, ov *order.GroupOrderVendor) error {
+	p, err := ps.paymentRepo.GetPaymentByOrderID(ctx, ov.ID)
+	if err != nil {
+		return fmt.Errorf("get order payment: %w", err)
+	}
+	if p.Status == "refunded" || p.Status == "refund_pending" {
+		return nil
+	}
+
+	// Khalti refunds are handled manually from the merchant dashboard; the
+	// refund comes out of the vendor's next settlement once it is recorded
+	if p.PaymentGateway != "stripe" {
+		return ps.paymentRepo.MarkOrderPaymentRefundPending(ctx, p, ov.Total)
+	}
+
+	stripe.Key = ps.server.Config.Stripe.SecretKey
+
+	sess, err := session.Get(p.TransactionID, nil)
+	if err != nil {
+		return fmt.Errorf("fetch stripe session: %w", err)
+	}
+	if sess.PaymentIntent == nil {
+		return fmt.Errorf("stripe session %s has no payment intent", p.TransactionID)
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent:        stripe.String(sess.PaymentIntent.ID),
+		Reason:               stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+		ReverseTransfer:      stripe.Bool(true),
+		RefundApplicationFee: stripe.Bool(true),
+	}
+	// a retry after a failed write below gets the same refund back
+	params.SetIdempotencyKey("order-refund-" + ov.ID)
+	rf, err := refund.New(params)
+	if err != nil {
+		return fmt.Errorf("refund order: %w", err)
+	}
+
+	return ps.paymentRepo.MarkOrderPaymentRefunded(ctx, p, ov.Total, rf.ID)
+}
+
+func (ps *PaymentService) refundGroupSplit(ctx context.Context, orderGroupID, orderVendorID string, reason *string) error {
+	sp, err := ps.paymentRepo.GetOrderVendorSplit(ctx, orderVendorID)
+	if err != nil {
+		return fmt.Errorf("get order vendor split: %w", err)
+	}
+	if sp.Status == "refunded" || sp.Status == "refund_pending" {
+		return nil
+	}
+
+	gp, err := ps.paymentRepo.GetOrderGroupPaymentByGroupID(ctx, orderGroupID)
+	if err != nil {
+		return fmt.Errorf("get group payment: %w", err)
+	}
+
+	// Khalti refunds are handled manually from the merchant dashboard; the
+	// refund comes out of the vendor's next settlement once it is recorded
+	if gp.PaymentGateway != "stripe" || gp.ChargeRef == nil {
+		return ps.paymentRepo.MarkOrderVendorSplitRefundPending(ctx, sp, reason)
+	}
+
+	stripe.Key = ps.server.Config.Stripe.SecretKey
+
+	// Refund the customer first: a failed refund leaves the vendor's share
+	// where it was. The refund is recorded before the reversal, so a retry
+	// after a failed reversal does not refund twice.
+	refundRef := sp.RefundRef
+	if refundRef == nil {
+		params := &stripe.RefundParams{
+			Charge: gp.ChargeRef,
+			Amount: stripe.Int64(int64(math.Round(sp.Amount * 100))),
+			Reason: stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+		}
+		params.SetIdempotencyKey("group-refund-" + orderVendorID)
+		rf, err := refund.New(params)
+		if err != nil {
+			return fmt.Errorf("refund split: %w", err)
+		}
+		refundRef = stripe.String(rf.ID)
+		if err := ps.paymentRepo.UpdateOrderVendorSplit(ctx, sp.ID, sp.Status, nil, refundRef, reason); err != nil {
+			return err
+		}
+	}
+
+	// Then pull the vendor's share back
+	if sp.TransferRef != nil {
+		params := &stripe.TransferReversalParams{
+			ID:     sp.TransferRef,
+			Amount: stripe.Int64(int64(math.Round(sp.VendorShare * 100))),
+		}
+		params.SetIdempotencyKey("group-transfer-reversal-" + orderVendorID)
+		if _, err := transferreversal.New(params); err != nil {
+			return fmt.Errorf("reverse transfer: %w", err)
+		}
+	}
+
+	if err := ps.paymentRepo.MarkOrderVendorSplitRefunded(ctx, sp, "stripe", *refundRef, reason); err != nil {
+		return err
+	}
+	if err := ps.orderRepo.MarkOrder(ctx, orderVendorID, "refunded"); err != nil {
+		return err
+	}
+	_, err = ps.orderRepo.RefreshOrderGroupPaymentStatus(ctx, orderGroupID)
+	return err
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/recommendation"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type RecommendationService struct {
+	server             *server.Server
+	recommendationRepo *repository.RecommendationRepository
+}
+
+func NewRecommendationService(s *server.Server, recommendationRepo *repository.RecommendationRepository) *RecommendationService {
+	return &RecommendationService{
+		server:             s,
+		recommendationRepo: recommendationRepo,
+	}
+}
+
+// GetRecommendations serves the lists precomputed by the nightly
+// recommendation job. Users the job has not covered yet (new sign-ups, or
+// a cache miss) get them computed on the spot and cached.
+func (s *RecommendationService) GetRecommendations(ctx echo.Context, userID string) (*recommendation.Recommendations, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	recs, err := s.recommendationRepo.GetCachedRecommendations(ctxx, userID)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("failed to read cached recommendations")
+	}
+	if recs != nil {
+		return recs, nil
+	}
+
+	recs, err = s.recommendationRepo.BuildRecommendations(ctxx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recommendationRepo.CacheRecommendations(ctxx, userID, recs); err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("failed to cache recommendations")
+	}
+	return recs, nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/referral"
+	"github.com/gitSanje/khajaride/internal/model/user"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type ReferralService struct {
+	server       *server.Server
+	referralRepo *repository.ReferralRepository
+}
+
+func NewReferralService(s *server.Server, referralRepo *repository.ReferralRepository) *ReferralService {
+	return &ReferralService{
+		server:       s,
+		referralRepo: referralRepo,
+	}
+}
+
+// RegisterSignup attributes a new user to the referral code they signed up
+// with, if any, and gives them a code of their own. The user is attributed
+// first so they can never match their own code.
+func (s *ReferralService) RegisterSignup(ctx echo.Context, u *user.User, attr *referral.SignupAttribution) (*referral.Referral, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	var ref *referral.Referral
+	if attr != nil {
+		var err error
+		ref, err = s.referralRepo.AttributeSignup(ctxx, u.ID, attr)
+		if err != nil {
+			if err == repository.ErrReferralCodeNotFound {
+				logger.Warn().Str("user_id", u.ID).Str("code", attr.Code).Msg("Signed up with an unknown referral code")
+			} else {
+				return nil, err
+			}
+		} else {
+			logger.Info().
+				Str("user_id", u.ID).
+				Str("referrer_id", ref.ReferrerID).
+				Str("status", ref.Status).
+				Msg("Referral attributed")
+		}
+	}
+
+	if _, err := s.referralRepo.GetOrCreateCode(ctxx, u.ID); err != nil {
+		return ref, err
+	}
+	return ref, nil
+}
+
+// GetMyReferrals returns the user's code together with how it performed.
+func (s *ReferralService) GetMyReferrals(ctx echo.Context, userID string) (*referral.MyReferrals, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	code, err := s.referralRepo.GetOrCreateCode(ctxx, userID)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to get referral code")
+		return nil, err
+	}
+	stats, err := s.referralRepo.GetUserStats(ctxx, userID)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to get referral stats")
+		return nil, err
+	}
+	referred, err := s.referralRepo.ListReferredUsers(ctxx, userID)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to list referred users")
+		return nil, err
+	}
+
+	return &referral.MyReferrals{
+		Code:      code.Code,
+		Stats:     *stats,
+		Referrals: referred,
+	}, nil
+}
+
+//-- ==================================================
+//-- ADMIN
+//-- ==================================================
+
+func (s *ReferralService) GetSettings(ctx echo.Context) (*referral.Settings, error) {
+	return s.referralRepo.GetSettings(ctx.Request().Context())
+}
+
+func (s *ReferralService) UpdateSettings(ctx echo.Context, payload *referral.UpdateSettingsPayload) (*referral.Settings, error) {
+	logger := middleware.GetLogger(ctx)
+
+	settings, err := s.referralRepo.UpdateSettings(ctx.Request().Context(), payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to update referral settings")
+		return nil, err
+	}
+
+	logger.Info().
+		Bool("is_active", settings.IsActive).
+		Str("reward_type", settings.RewardType).
+		Msg("Referral settings updated")
+	return settings, nil
+}
+
+func (s *ReferralService) GetProgramStats(ctx echo.Context) (*referral.ProgramStats, error) {
+	logger := middleware.GetLogger(ctx)
+
+	stats, err := s.referralRepo.GetProgramStats(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get referral program stats")
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (s *ReferralService) GetReferrals(ctx echo.Context, query *referral.GetReferralsQuery) (*model.PaginatedResponse[referral.Referral], error) {
+	logger := middleware.GetLogger(ctx)
+
+	res, err := s.referralRepo.GetReferrals(ctx.Request().Context(), query)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to list referrals")
+		return nil, err
+	}
+	return res, nil
+}
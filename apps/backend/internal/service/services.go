@@ -5,6 +5,7 @@ import (
 
 	"github.com/gitSanje/khajaride/internal/lib/aws"
 	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/lib/sms"
 	"github.com/gitSanje/khajaride/internal/repository"
 	"github.com/gitSanje/khajaride/internal/server"
 )
@@ -18,6 +19,21 @@ type Services struct {
 	Cart   *CartService
 	Order  *OrderService
 	Payment *PaymentService
+	Settlement *SettlementService
+	Ledger     *LedgerService
+	Invoice    *InvoiceService
+	Loyalty    *LoyaltyService
+	Referral   *ReferralService
+	Recommendation *RecommendationService
+	Notification   *NotificationService
+	Email          *EmailService
+	VendorVerification *VendorVerificationService
+	Analytics          *AnalyticsService
+	VendorMember       *VendorMemberService
+	Brand              *BrandService
+	Stock              *StockService
+	MenuImport         *MenuImportService
+	Image              *ImageService
 }
 
 func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
@@ -28,15 +44,45 @@ func NewServices(s *server.Server, repos *repository.Repositories) (*Services, e
 		return nil, fmt.Errorf("failed to create AWS client: %w", err)
 	}
 
-	
+	invoiceService := NewInvoiceService(s, repos.Invoice, awsClient)
+	smsSender := sms.NewSender(s.Config.SMS, s.Logger)
+
+	notificationService := NewNotificationService(s, repos.Notification)
+	stockService := NewStockService(s, repos.Stock, repos.Search)
+	menuImportService := NewMenuImportService(s, repos.MenuImport, repos.Search, awsClient)
+	imageService := NewImageService(s, repos.Image, awsClient)
+	// the job server delivers notifications and emails, issues invoices and
+	// runs menu imports but cannot import the repositories
+	if s.Job != nil {
+		s.Job.SetNotificationRecorder(repos.Notification)
+		s.Job.SetEmailRecorder(repos.Email)
+		s.Job.SetMenuImportRunner(menuImportService)
+		s.Job.SetInvoiceIssuer(invoiceService)
+	}
+
 	return &Services{
 		Job:    s.Job,
 		Auth:   authService,
-		User:   NewUserService(s, repos.User),
-		Vendor: NewVendorService(s, repos.Vendor,awsClient ),
+		User:   NewUserService(s, repos.User, repos.Order, repos.Payment, smsSender),
+		Vendor: NewVendorService(s, repos.Vendor, awsClient, repos.Search, imageService),
 		Search: NewSearchService(s, repos.Search),
 		Cart:   NewCartService(s, repos.Cart),
-		Order:  NewOrderService(s, repos.Order, repos.Cart),
-		Payment: NewPaymentService(s, repos.Payment,repos.Order),
+		Order:  NewOrderService(s, repos.Order, repos.Cart, repos.Loyalty, repos.Referral, repos.User, notificationService, stockService),
+		Payment: NewPaymentService(s, repos.Payment,repos.Order, repos.Settlement, repos.Loyalty, invoiceService, notificationService, stockService),
+		Settlement: NewSettlementService(s, repos.Settlement),
+		Ledger:     NewLedgerService(s, repos.Ledger),
+		Invoice:    invoiceService,
+		Loyalty:    NewLoyaltyService(s, repos.Loyalty),
+		Referral:   NewReferralService(s, repos.Referral),
+		Recommendation: NewRecommendationService(s, repos.Recommendation),
+		Notification:   notificationService,
+		Email:          NewEmailService(s, repos.Email),
+		VendorVerification: NewVendorVerificationService(s, repos.VendorVerification, repos.Search, awsClient),
+		Analytics:          NewAnalyticsService(s, repos.Analytics),
+		VendorMember:       NewVendorMemberService(s, repos.VendorMember),
+		Brand:              NewBrandService(s, repos.Brand, repos.Search),
+		Stock:              stockService,
+		MenuImport:         menuImportService,
+		Image:              imageService,
 	}, nil
 }
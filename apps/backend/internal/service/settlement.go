@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/settlement"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type SettlementService struct {
+	server         *server.Server
+	settlementRepo *repository.SettlementRepository
+}
+
+func NewSettlementService(s *server.Server, settlementRepo *repository.SettlementRepository) *SettlementService {
+	return &SettlementService{
+		server:         s,
+		settlementRepo: settlementRepo,
+	}
+}
+
+func (s *SettlementService) GetSettlements(ctx echo.Context, vendorUserID *string, query *settlement.GetSettlementsQuery) (*model.PaginatedResponse[settlement.VendorSettlement], error) {
+	logger := middleware.GetLogger(ctx)
+
+	res, err := s.settlementRepo.GetSettlements(ctx.Request().Context(), vendorUserID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to fetch settlements")
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetStatement returns a settlement with every entry in it. Vendors only see
+// their own settlements.
+func (s *SettlementService) GetStatement(ctx echo.Context, vendorUserID string, payload *settlement.GetSettlementByIDPayload) (*settlement.SettlementStatement, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	st, err := s.settlementRepo.GetSettlementByID(ctxx, payload.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "settlement not found")
+		}
+		logger.Error().Err(err).Str("settlement_id", payload.ID).Msg("Failed to fetch settlement")
+		return nil, err
+	}
+	if st.VendorUserID == nil || *st.VendorUserID != vendorUserID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "settlement not found")
+	}
+
+	entries, err := s.settlementRepo.ListSettlementEntries(ctxx, st.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settlement.SettlementStatement{
+		Settlement: *st,
+		Entries:    entries,
+	}, nil
+}
+
+func (s *SettlementService) GetUnsettledEntries(ctx echo.Context, vendorUserID string) ([]settlement.VendorEarning, error) {
+	return s.settlementRepo.ListUnsettledEntries(ctx.Request().Context(), vendorUserID)
+}
+
+func (s *SettlementService) CreateAdjustment(ctx echo.Context, adminID string, payload *settlement.CreateAdjustmentPayload) (*settlement.VendorEarning, error) {
+	logger := middleware.GetLogger(ctx)
+
+	entry, err := s.settlementRepo.CreateAdjustment(ctx.Request().Context(), payload, adminID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+		}
+		logger.Error().Err(err).Str("vendor_id", payload.VendorID).Msg("Failed to create adjustment")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("vendor_id", payload.VendorID).
+		Float64("amount", payload.Amount).
+		Str("created_by", adminID).
+		Msg("Settlement adjustment created")
+	return entry, nil
+}
+
+// RunSettlement runs a settlement cycle on demand, the same one the
+// vendor_settlement_worker runs on schedule.
+func (s *SettlementService) RunSettlement(ctx echo.Context, payload *settlement.RunSettlementPayload) (*settlement.RunSettlementResponse, error) {
+	logger := middleware.GetLogger(ctx)
+
+	res, err := s.settlementRepo.RunSettlementCycle(ctx.Request().Context(), payload.Frequency, time.Now())
+	if err != nil {
+		logger.Error().Err(err).Str("frequency", payload.Frequency).Msg("Settlement run failed")
+		return nil, err
+	}
+	return res, nil
+}
+
+// MarkSettlementPaid confirms a settlement that was paid outside Stripe
+// (bank transfer, eSewa, Khalti, cash).
+func (s *SettlementService) MarkSettlementPaid(ctx echo.Context, payload *settlement.MarkSettlementPaidPayload) (*settlement.VendorSettlement, error) {
+	logger := middleware.GetLogger(ctx)
+
+	st, err := s.settlementRepo.MarkSettlementPaid(ctx.Request().Context(), payload.ID, &payload.TransactionRef)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "settlement not found or already paid")
+		}
+		logger.Error().Err(err).Str("settlement_id", payload.ID).Msg("Failed to mark settlement paid")
+		return nil, err
+	}
+	if err := sendPayoutSentEmail(ctx.Request().Context(), s.server, s.settlementRepo, st); err != nil {
+		logger.Error().Err(err).Str("settlement_id", st.ID).Msg("failed to send payout email")
+	}
+	return st, nil
+}
+
+// sendPayoutSentEmail tells the vendor a settlement was paid out. Settlements
+// are marked paid by an admin or by Stripe's payout webhook.
+func sendPayoutSentEmail(ctx context.Context, srv *server.Server, settlementRepo *repository.SettlementRepository, st *settlement.VendorSettlement) error {
+	rcpt, err := settlementRepo.GetPayoutRecipient(ctx, st.ID)
+	if err != nil {
+		return fmt.Errorf("load payout recipient: %w", err)
+	}
+	if rcpt.Email == nil {
+		return nil
+	}
+
+	data := map[string]string{
+		"VendorName":  rcpt.Name,
+		"PeriodStart": st.PeriodStart.Format("2 Jan 2006"),
+		"PeriodEnd":   st.PeriodEnd.Format("2 Jan 2006"),
+		"Method":      strings.ReplaceAll(rcpt.Method, "_", " "),
+		"Gross":       formatMoney(st.Currency, st.GrossAmount),
+		"Commission":  formatMoney(st.Currency, st.CommissionAmount),
+		"Refunds":     formatMoney(st.Currency, st.RefundAmount),
+		"Adjustments": formatMoney(st.Currency, st.AdjustmentAmount),
+		"Amount":      formatMoney(st.Currency, st.NetAmount),
+	}
+	if st.TransactionRef != nil {
+		data["TransactionRef"] = *st.TransactionRef
+	}
+
+	task, err := job.NewPayoutSentEmailTask(job.PayoutSentEmailPayload{
+		SettlementID: st.ID,
+		To:           *rcpt.Email,
+		Data:         data,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := srv.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("enqueue payout email: %w", err)
+	}
+	return nil
+}
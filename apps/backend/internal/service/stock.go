@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/model/cart"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type StockService struct {
+	server     *server.Server
+	stockRepo  *repository.StockRepository
+	searchRepo *repository.SearchRepository
+}
+
+func NewStockService(s *server.Server, stockRepo *repository.StockRepository, searchRepo *repository.SearchRepository) *StockService {
+	return &StockService{
+		server:     s,
+		stockRepo:  stockRepo,
+		searchRepo: searchRepo,
+	}
+}
+
+//-- ==================================================
+//-- VENDOR STOCK
+//-- ==================================================
+
+func (s *StockService) GetStock(ctx echo.Context, vendorID string) ([]vendor.PopulatedMenuItemStock, error) {
+	return s.stockRepo.GetStock(ctx.Request().Context(), vendorID)
+}
+
+func (s *StockService) SetStock(ctx echo.Context, vendorID string, payload *vendor.SetMenuItemStockPayload) (*vendor.PopulatedMenuItemStock, error) {
+	ctxx := ctx.Request().Context()
+
+	stock, err := s.stockRepo.SetStock(ctxx, vendorID, payload)
+	if err != nil {
+		return nil, stockError(err)
+	}
+	s.indexMenuItem(ctxx, vendorID, payload.MenuItemID)
+	return stock, nil
+}
+
+func (s *StockService) DeleteStock(ctx echo.Context, vendorID string, payload *vendor.DeleteMenuItemStockPayload) error {
+	ctxx := ctx.Request().Context()
+
+	if err := s.stockRepo.DeleteStock(ctxx, vendorID, payload.MenuItemID); err != nil {
+		return stockError(err)
+	}
+	s.indexMenuItem(ctxx, vendorID, payload.MenuItemID)
+	return nil
+}
+
+//-- ==================================================
+//-- RESERVATIONS
+//-- ==================================================
+
+// Reserve takes the stock of an order's tracked items within the order's
+// transaction. The changes are published once the transaction commits.
+func (s *StockService) Reserve(ctx context.Context, tx pgx.Tx, orderVendorID, vendorID string, items []cart.CartItem) ([]vendor.StockChange, error) {
+	quantities := make(map[string]int, len(items))
+	for _, ci := range items {
+		quantities[ci.MenuItemID] += ci.Quantity
+	}
+
+	changes, err := s.stockRepo.ReserveStock(ctx, tx, orderVendorID, vendorID, quantities)
+	if err != nil {
+		return nil, stockError(err)
+	}
+	return changes, nil
+}
+
+// Release gives back the stock of cancelled orders and failed payments. The
+// order has already changed, so failures are only logged.
+func (s *StockService) Release(ctx context.Context, orderVendorIDs ...string) {
+	ids := make([]string, 0, len(orderVendorIDs))
+	for _, id := range orderVendorIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	changes, err := s.stockRepo.ReleaseStock(ctx, ids)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Strs("order_ids", ids).Msg("failed to release stock")
+		return
+	}
+	s.Publish(ctx, changes)
+}
+
+// ReleaseUnpaid gives back the stock of orders whose payment was abandoned
+// but could still go through, leaving alone those paid in the meantime.
+func (s *StockService) ReleaseUnpaid(ctx context.Context, orderVendorIDs ...string) {
+	ids := make([]string, 0, len(orderVendorIDs))
+	for _, id := range orderVendorIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	changes, err := s.stockRepo.ReleaseUnpaidStock(ctx, ids)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Strs("order_ids", ids).Msg("failed to release stock")
+		return
+	}
+	s.Publish(ctx, changes)
+}
+
+// ReleaseTx gives back the stock of orders dropped within the caller's
+// transaction, e.g. vendors removed from the cart before a re-checkout.
+func (s *StockService) ReleaseTx(ctx context.Context, tx pgx.Tx, orderVendorIDs []string) ([]vendor.StockChange, error) {
+	return s.stockRepo.ReleaseStockTx(ctx, tx, orderVendorIDs)
+}
+
+// Publish pushes sell-outs and restocks to the search index and alerts
+// vendors of items that ran low. Like the index updates elsewhere it never
+// fails the caller.
+func (s *StockService) Publish(ctx context.Context, changes []vendor.StockChange) {
+	for _, c := range changes {
+		if c.SoldOut() || c.Restocked() {
+			s.indexMenuItem(ctx, c.VendorID, c.MenuItemID)
+		}
+		if c.LowStock {
+			if err := s.enqueueLowStockAlert(ctx, c.VendorID, c.MenuItemID); err != nil {
+				s.server.Logger.Error().Err(err).
+					Str("vendor_id", c.VendorID).
+					Str("menu_item_id", c.MenuItemID).
+					Msg("failed to enqueue low stock alert")
+			}
+		}
+	}
+}
+
+func (s *StockService) indexMenuItem(ctx context.Context, vendorID, menuItemID string) {
+	logger := s.server.Logger
+
+	state, err := s.stockRepo.GetMenuState(ctx, vendorID, menuItemID)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("vendor_id", vendorID).
+			Str("menu_item_id", menuItemID).
+			Msg("Failed to get menu state")
+		return
+	}
+	err = s.searchRepo.UpdateOutletMenuItem(ctx, state.VendorID, state.MenuItemID, state.BasePrice, state.IsAvailable)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("vendor_id", vendorID).
+			Str("menu_item_id", menuItemID).
+			Msg("Failed to index menu item availability")
+	}
+}
+
+func (s *StockService) enqueueLowStockAlert(ctx context.Context, vendorID, menuItemID string) error {
+	alert, err := s.stockRepo.GetLowStockAlert(ctx, vendorID, menuItemID)
+	if err != nil {
+		return err
+	}
+	if alert.OwnerEmail == nil || *alert.OwnerEmail == "" {
+		return nil
+	}
+
+	data := map[string]string{
+		"VendorName": alert.VendorName,
+		"ItemName":   alert.ItemName,
+		"Quantity":   strconv.Itoa(alert.Quantity),
+	}
+	if alert.DailyReset != nil {
+		data["DailyReset"] = *alert.DailyReset
+	}
+
+	task, err := job.NewVendorLowStockEmailTask(job.VendorLowStockEmailPayload{
+		To:   *alert.OwnerEmail,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.server.Job.Client.Enqueue(task)
+	return err
+}
+
+func stockError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrStockMenuItemNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "menu item not found")
+	case errors.Is(err, repository.ErrStockNotTracked):
+		return echo.NewHTTPError(http.StatusNotFound, "stock is not tracked for this menu item")
+	case errors.Is(err, repository.ErrStockQuantityRequired):
+		return echo.NewHTTPError(http.StatusBadRequest, "quantity or dailyQuantity is required to start tracking stock")
+	}
+	return err
+}
@@ -1,11 +1,30 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/lib/sms"
 	"github.com/gitSanje/khajaride/internal/middleware"
 	"github.com/gitSanje/khajaride/internal/model"
 	"github.com/gitSanje/khajaride/internal/model/user"
 	"github.com/gitSanje/khajaride/internal/repository"
 	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -14,12 +33,18 @@ type UserService struct {
 	
 	server *server.Server
 	userRepo *repository.UserRepository
+	orderRepo   *repository.OrderRepository
+	paymentRepo *repository.PaymentRepository
+	sms         sms.Sender
 }
 
-func NewUserService(s *server.Server, userRepo *repository.UserRepository) *UserService {
+func NewUserService(s *server.Server, userRepo *repository.UserRepository, orderRepo *repository.OrderRepository, paymentRepo *repository.PaymentRepository, smsSender sms.Sender) *UserService {
 	return &UserService{
 		server:  s,
 		userRepo: userRepo,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		sms:         smsSender,
 	}
 }
 
@@ -170,22 +195,6 @@ func (s *UserService) GetUsers(ctx echo.Context, query *user.GetUsersQuery) (*mo
 }
 
 
-func (s *UserService) DeleteUser(ctx echo.Context, userID string) error {
-    logger := middleware.GetLogger(ctx)
-
-    logger.Info().Str("user_id", userID).Msg("Deleting user")
-
-    if err := s.userRepo.DeleteUser(ctx.Request().Context(), userID); err != nil {
-        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to delete user")
-        return err
-    }
-
-    logger.Info().Str("user_id", userID).Msg("User deleted successfully")
-    return nil
-}
-
-
-
 func (s *UserService) CreateAddress(ctx echo.Context, userID string, payload *user.CreateAddressPayload) (*user.UserAddress, error) {
     logger := middleware.GetLogger(ctx)
 
@@ -404,3 +413,354 @@ func (s *UserService) AdjustPoints(ctx echo.Context, performedBy string, payload
 }
 
 
+
+
+//-- ==================================================
+//--  CLERK SYNC
+//-- ==================================================
+
+// SyncClerkUser applies a Clerk user's state; see UserRepository.UpsertClerkUser
+// for the ordering rules. It returns nil when the state was stale.
+func (s *UserService) SyncClerkUser(ctx echo.Context, payload *user.ClerkUserSync) (*user.User, bool, error) {
+    logger := middleware.GetLogger(ctx)
+
+    synced, inserted, err := s.userRepo.UpsertClerkUser(ctx.Request().Context(), payload)
+    if err != nil {
+        logger.Error().
+            Err(err).
+            Str("user_id", payload.ID).
+            Msg("Failed to sync clerk user")
+        return nil, false, err
+    }
+    if synced == nil {
+        logger.Info().
+            Str("user_id", payload.ID).
+            Int64("clerk_updated_at", payload.ClerkUpdatedAt).
+            Msg("Skipped stale or deleted clerk user")
+        return nil, false, nil
+    }
+
+    logger.Info().
+        Str("event", "user_synced").
+        Str("user_id", synced.ID).
+        Bool("inserted", inserted).
+        Msg("Clerk user synced")
+    return synced, inserted, nil
+}
+
+func (s *UserService) DeleteClerkUser(ctx echo.Context, userID string, clerkTimestamp int64) error {
+    logger := middleware.GetLogger(ctx)
+
+    deleted, err := s.userRepo.DeleteClerkUser(ctx.Request().Context(), userID, clerkTimestamp)
+    if err != nil {
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to delete clerk user")
+        return err
+    }
+
+    logger.Info().
+        Str("event", "user_deleted").
+        Str("user_id", userID).
+        Bool("deactivated", deleted).
+        Msg("Clerk user deleted")
+    return nil
+}
+
+func (s *UserService) RecordClerkSignIn(ctx echo.Context, userID string, at time.Time) error {
+    return s.userRepo.RecordClerkSignIn(ctx.Request().Context(), userID, at)
+}
+
+// SendClerkEmail queues an email Clerk left for us to deliver.
+func (s *UserService) SendClerkEmail(ctx echo.Context, payload job.ClerkEmailPayload) error {
+    logger := middleware.GetLogger(ctx)
+
+    task, err := job.NewClerkEmailTask(payload)
+    if err != nil {
+        return err
+    }
+    if _, err := s.server.Job.Client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+        logger.Error().Err(err).Str("email_id", payload.EmailID).Msg("Failed to enqueue clerk email")
+        return err
+    }
+    return nil
+}
+
+func (s *UserService) IsClerkEventProcessed(ctx echo.Context, eventID string) (bool, error) {
+    return s.userRepo.IsClerkEventProcessed(ctx.Request().Context(), eventID)
+}
+
+func (s *UserService) RecordClerkEvent(ctx echo.Context, e *user.ClerkEvent) error {
+    return s.userRepo.RecordClerkEvent(ctx.Request().Context(), e)
+}
+
+
+
+//-- ==================================================
+//--  ACCOUNT DELETION & DATA EXPORT
+//-- ==================================================
+
+// RequestDeletion schedules the account for anonymization after the grace
+// period; until then the user can cancel.
+func (s *UserService) RequestDeletion(ctx echo.Context, userID string) (*user.DeletionStatus, error) {
+    logger := middleware.GetLogger(ctx)
+
+    u, err := s.userRepo.RequestDeletion(ctx.Request().Context(), userID, user.DeletionGracePeriod)
+    if err != nil {
+        if errors.Is(err, repository.ErrVendorAccountDeletion) {
+            return nil, echo.NewHTTPError(http.StatusConflict, "transfer or close your store before deleting your account")
+        }
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, echo.NewHTTPError(http.StatusNotFound, "user not found")
+        }
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to request account deletion")
+        return nil, err
+    }
+
+    logger.Info().
+        Str("event", "account_deletion_requested").
+        Str("user_id", userID).
+        Time("scheduled_at", *u.DeletionScheduledAt).
+        Msg("Account deletion requested")
+    return user.NewDeletionStatus(u), nil
+}
+
+func (s *UserService) GetDeletionStatus(ctx echo.Context, userID string) (*user.DeletionStatus, error) {
+    u, err := s.userRepo.GetUserByID(ctx.Request().Context(), userID)
+    if err != nil {
+        return nil, err
+    }
+    return user.NewDeletionStatus(u), nil
+}
+
+func (s *UserService) CancelDeletion(ctx echo.Context, userID string) (*user.DeletionStatus, error) {
+    logger := middleware.GetLogger(ctx)
+
+    u, err := s.userRepo.CancelDeletion(ctx.Request().Context(), userID)
+    if err != nil {
+        if errors.Is(err, repository.ErrNoDeletionPending) {
+            return nil, echo.NewHTTPError(http.StatusConflict, "no account deletion is pending")
+        }
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to cancel account deletion")
+        return nil, err
+    }
+
+    logger.Info().
+        Str("event", "account_deletion_cancelled").
+        Str("user_id", userID).
+        Msg("Account deletion cancelled")
+    return user.NewDeletionStatus(u), nil
+}
+
+// ExportData assembles everything held about the user into a ZIP of JSON
+// files.
+func (s *UserService) ExportData(ctx echo.Context, userID string) ([]byte, error) {
+    logger := middleware.GetLogger(ctx)
+    ctxx := ctx.Request().Context()
+
+    profile, err := s.userRepo.GetUserByID(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+    addresses, err := s.userRepo.GetUserAddressesByUserID(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+    orders, err := s.orderRepo.GetUserOrdersWithDetails(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+    orderPayments, err := s.paymentRepo.ListUserOrderPayments(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+    groupPayments, err := s.paymentRepo.ListUserOrderGroupPayments(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+    loyalty, err := s.userRepo.GetLoyaltyHistory(ctxx, userID)
+    if err != nil {
+        return nil, err
+    }
+
+    files := []struct {
+        name string
+        data any
+    }{
+        {"profile.json", profile},
+        {"addresses.json", addresses},
+        {"orders.json", orders},
+        {"payments.json", map[string]any{
+            "orderPayments":      orderPayments,
+            "orderGroupPayments": groupPayments,
+        }},
+        {"loyalty.json", loyalty},
+    }
+
+    var buf bytes.Buffer
+    zw := zip.NewWriter(&buf)
+    for _, f := range files {
+        w, err := zw.Create(f.name)
+        if err != nil {
+            return nil, err
+        }
+        raw, err := json.MarshalIndent(f.data, "", "  ")
+        if err != nil {
+            return nil, err
+        }
+        if _, err := w.Write(raw); err != nil {
+            return nil, err
+        }
+    }
+    if err := zw.Close(); err != nil {
+        return nil, err
+    }
+
+    logger.Info().
+        Str("event", "data_exported").
+        Str("user_id", userID).
+        Int("bytes", buf.Len()).
+        Msg("User data exported")
+    return buf.Bytes(), nil
+}
+
+
+
+//-- ==================================================
+//--  PHONE VERIFICATION
+//-- ==================================================
+
+// RequestPhoneOTP texts a one-time code to the number the user wants to
+// verify. Resends are throttled per user in Redis: one per cooldown and a
+// few per hour.
+func (s *UserService) RequestPhoneOTP(ctx echo.Context, userID string, payload *user.RequestPhoneOTPPayload) (*user.PhoneOTPIssued, error) {
+    logger := middleware.GetLogger(ctx)
+    ctxx := ctx.Request().Context()
+
+    taken, err := s.userRepo.IsPhoneNumberTaken(ctxx, userID, payload.PhoneNumber)
+    if err != nil {
+        return nil, err
+    }
+    if taken {
+        return nil, echo.NewHTTPError(http.StatusConflict, "phone number belongs to another account")
+    }
+
+    if err := s.throttlePhoneOTP(ctx, userID); err != nil {
+        return nil, err
+    }
+
+    code, err := generateOTP()
+    if err != nil {
+        return nil, err
+    }
+    token, err := s.userRepo.IssuePhoneOTP(ctxx, userID, payload.PhoneNumber, s.hashOTP(userID, code), user.OTPTTL)
+    if err != nil {
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to issue phone OTP")
+        return nil, err
+    }
+
+    message := fmt.Sprintf("Your KhajaRide verification code is %s. It expires in %d minutes.", code, int(user.OTPTTL.Minutes()))
+    if err := s.sms.Send(ctxx, payload.PhoneNumber, message); err != nil {
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to send phone OTP")
+        // the code never arrived, so it does not count against the cooldown
+        s.server.Redis.Del(ctxx, otpCooldownKey(userID))
+        return nil, echo.NewHTTPError(http.StatusBadGateway, "could not send the verification code, try again")
+    }
+
+    logger.Info().
+        Str("event", "phone_otp_sent").
+        Str("user_id", userID).
+        Msg("Phone verification code sent")
+
+    return &user.PhoneOTPIssued{
+        PhoneNumber: payload.PhoneNumber,
+        ExpiresAt:   token.ExpiresAt,
+        ResendAfter: int(user.OTPResendCooldown.Seconds()),
+    }, nil
+}
+
+// VerifyPhoneOTP checks the code and marks the number as the user's verified
+// number.
+func (s *UserService) VerifyPhoneOTP(ctx echo.Context, userID string, payload *user.VerifyPhoneOTPPayload) (*user.User, error) {
+    logger := middleware.GetLogger(ctx)
+
+    u, err := s.userRepo.VerifyPhoneOTP(ctx.Request().Context(), userID, s.hashOTP(userID, payload.Code), user.OTPMaxAttempts)
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrOTPNotFound):
+            return nil, echo.NewHTTPError(http.StatusBadRequest, "request a verification code first")
+        case errors.Is(err, repository.ErrOTPExpired):
+            return nil, echo.NewHTTPError(http.StatusBadRequest, "verification code expired, request a new one")
+        case errors.Is(err, repository.ErrOTPInvalid):
+            return nil, echo.NewHTTPError(http.StatusBadRequest, "wrong verification code")
+        case errors.Is(err, repository.ErrOTPTooManyAttempts):
+            return nil, echo.NewHTTPError(http.StatusTooManyRequests, "too many wrong codes, request a new one")
+        case errors.Is(err, repository.ErrPhoneNumberTaken):
+            return nil, echo.NewHTTPError(http.StatusConflict, "phone number belongs to another account")
+        }
+        logger.Error().Err(err).Str("user_id", userID).Msg("Failed to verify phone OTP")
+        return nil, err
+    }
+
+    logger.Info().
+        Str("event", "phone_verified").
+        Str("user_id", userID).
+        Msg("Phone number verified")
+    return u, nil
+}
+
+func otpCooldownKey(userID string) string {
+    return "otp:phone:cooldown:" + userID
+}
+
+func otpHourlyKey(userID string) string {
+    return "otp:phone:hourly:" + userID
+}
+
+func (s *UserService) throttlePhoneOTP(ctx echo.Context, userID string) error {
+    ctxx := ctx.Request().Context()
+
+    tooSoon := func(key string) error {
+        wait := s.server.Redis.TTL(ctxx, key).Val()
+        if wait < time.Second {
+            wait = time.Second
+        }
+        ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+        return echo.NewHTTPError(http.StatusTooManyRequests,
+            fmt.Sprintf("wait %d seconds before requesting another code", int(wait.Seconds())))
+    }
+
+    ok, err := s.server.Redis.SetNX(ctxx, otpCooldownKey(userID), 1, user.OTPResendCooldown).Result()
+    if err != nil {
+        return fmt.Errorf("failed to check otp cooldown: %w", err)
+    }
+    if !ok {
+        return tooSoon(otpCooldownKey(userID))
+    }
+
+    sent, err := s.server.Redis.Incr(ctxx, otpHourlyKey(userID)).Result()
+    if err != nil {
+        return fmt.Errorf("failed to count otp requests: %w", err)
+    }
+    if sent == 1 {
+        s.server.Redis.Expire(ctxx, otpHourlyKey(userID), time.Hour)
+    }
+    if sent > user.OTPMaxPerHour {
+        return tooSoon(otpHourlyKey(userID))
+    }
+    return nil
+}
+
+// hashOTP keys the code with the auth secret, so a leaked token table does
+// not give codes away to a 10^6 brute force.
+func (s *UserService) hashOTP(userID, code string) string {
+    mac := hmac.New(sha256.New, []byte(s.server.Config.Auth.SecretKey))
+    mac.Write([]byte(userID + ":" + code))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateOTP() (string, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(int64(math.Pow10(user.OTPLength))))
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%0*d", user.OTPLength, n.Int64()), nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"mime/multipart"
 	"net/http"
 
@@ -20,13 +21,17 @@ type VendorService struct {
   server *server.Server
   vendorRepo *repository.VendorRepository
   awsClient    *aws.AWS
+  searchRepo   *repository.SearchRepository
+  imageService *ImageService
 }
 
-func NewVendorService(s *server.Server, vendorRepo *repository.VendorRepository, awsClient *aws.AWS) *VendorService {
+func NewVendorService(s *server.Server, vendorRepo *repository.VendorRepository, awsClient *aws.AWS, searchRepo *repository.SearchRepository, imageService *ImageService) *VendorService {
 	return &VendorService{
 		server:  s,
 		vendorRepo: vendorRepo,
         awsClient:    awsClient,
+		searchRepo:   searchRepo,
+		imageService: imageService,
 	}
 }
 
@@ -40,6 +45,10 @@ func ( s *VendorService) GetVendorByID( ctx echo.Context, payload *vendor.GetVen
 	if err != nil {
         logger.Error().Err(err).Str("vendorID", payload.ID).Msg("Failed to fetch vendor  by ID")
         return nil, err
+    }
+    // vendors that are not live are not shown to customers
+    if v.Status != vendor.StatusActive {
+        return nil, echo.NewHTTPError(http.StatusNotFound, "Vendor not found")
     }
         logger.Info().Str("vendorID", payload.ID).Msg("Vendor  fetched successfully")
     
@@ -125,56 +134,94 @@ func (s *VendorService) CreateVendorAddress(ctx echo.Context, payload *vendor.Cr
     return vendor, nil
 }
 
-func (s *VendorService) UploadImages(ctx echo.Context,files []*multipart.FileHeader) (*vendor.UploadImagesResponse, error) {
-    logger := middleware.GetLogger(ctx)
-    
-    var uploadedURLs []string
+// UploadImages runs the files through the image pipeline; see ImageService.
+func (s *VendorService) UploadImages(ctx echo.Context, vendorID, userID string, files []*multipart.FileHeader) (*vendor.UploadImagesResponse, error) {
+	return s.imageService.UploadImages(ctx, vendorID, userID, files)
+}
 
 
-    for _, file := range files {
-		// Open the file
-		src, err := file.Open()
-		if err != nil {
-			logger.Error().Err(err).Msg("Failed to open file")
-			return nil, err
-		}
-		defer src.Close() // make sure to close the file
-
-		// Upload to S3
-		publicURL, err := s.awsClient.S3.UploadPublicFile(
-			ctx.Request().Context(),
-			s.server.Config.AWS.UploadBucket,
-			"vendors/images/"+file.Filename,
-			src,
-		)
-		if err != nil {
-			logger.Error().Err(err).Msg("Failed to upload file to S3")
-			return nil, err
-		}
+// GetMyVendor returns the vendor the caller works at, with its address.
+func (s *VendorService) GetMyVendor(ctx echo.Context, vendorID string) (*vendor.VendorWithAddress, error) {
+	logger := middleware.GetLogger(ctx)
 
-		uploadedURLs = append(uploadedURLs, publicURL)
+	data, err := s.vendorRepo.GetVendorWithAddress(ctx.Request().Context(), vendorID)
+	if err != nil {
+		logger.Error().Err(err).Str("vendorID", vendorID).Msg("Failed to fetch vendor")
+		return nil, err
 	}
-    imageRes := &vendor.UploadImagesResponse{
-		UploadedURLs: uploadedURLs,
+
+	if data == nil {
+		logger.Warn().Str("vendorID", vendorID).Msg("Vendor not found")
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Vendor not found")
 	}
-	return imageRes, nil
+
+	logger.Info().Str("vendorID", data.Vendor.ID).Msg("Vendor fetched successfully")
+	return data, nil
 }
 
 
-func (s *VendorService) GetVendorByUserID(ctx echo.Context, vendorUserID string) (*vendor.VendorWithAddress, error) {
+
+//-- ==================================================
+//-- FAVORITES
+//-- ==================================================
+
+func (s *VendorService) AddFavorite(ctx echo.Context, userID string, payload *vendor.AddFavoritePayload) (*vendor.FavoriteState, error) {
 	logger := middleware.GetLogger(ctx)
 
-	data, err := s.vendorRepo.GetVendorByUserID(ctx.Request().Context(), vendorUserID)
+	state, err := s.vendorRepo.AddFavorite(ctx.Request().Context(), userID, payload)
 	if err != nil {
-		logger.Error().Err(err).Str("vendorUserID", vendorUserID).Msg("Failed to fetch vendor by vendor_user_id")
+		if errors.Is(err, repository.ErrFavoriteEntityNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, payload.EntityType+" not found")
+		}
+		logger.Error().Err(err).Str("user_id", userID).Str("entity_id", payload.EntityID).Msg("Failed to add favorite")
 		return nil, err
 	}
 
-	if data == nil {
-		logger.Warn().Str("vendorUserID", vendorUserID).Msg("Vendor not found")
-		return nil, echo.NewHTTPError(http.StatusNotFound, "Vendor not found")
+	s.indexFavoriteCount(ctx, state)
+	return state, nil
+}
+
+func (s *VendorService) RemoveFavorite(ctx echo.Context, userID string, payload *vendor.RemoveFavoritePayload) (*vendor.FavoriteState, error) {
+	logger := middleware.GetLogger(ctx)
+
+	state, err := s.vendorRepo.RemoveFavorite(ctx.Request().Context(), userID, payload)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Str("entity_id", payload.EntityID).Msg("Failed to remove favorite")
+		return nil, err
 	}
 
-	logger.Info().Str("vendorID", data.Vendor.ID).Msg("Vendor fetched successfully")
-	return data, nil
+	s.indexFavoriteCount(ctx, state)
+	return state, nil
+}
+
+func (s *VendorService) GetFavorites(ctx echo.Context, userID string, query *vendor.GetFavoritesQuery) (*model.PaginatedResponse[vendor.PopulatedFavorite], error) {
+	logger := middleware.GetLogger(ctx)
+
+	res, err := s.vendorRepo.GetFavorites(ctx.Request().Context(), userID, query)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("Failed to list favorites")
+		return nil, err
+	}
+	return res, nil
+}
+
+// indexFavoriteCount pushes the new count to the search index. It writes the
+// absolute value, so a failed push is repaired by the next (un)favorite.
+func (s *VendorService) indexFavoriteCount(ctx echo.Context, state *vendor.FavoriteState) {
+	logger := middleware.GetLogger(ctx)
+
+	ctxx := ctx.Request().Context()
+
+	var err error
+	if state.EntityType == vendor.FavoriteMenuItem {
+		err = s.searchRepo.UpdateMenuItemFavoriteCount(ctxx, state.EntityID, state.FavoriteCount)
+	} else {
+		err = s.searchRepo.UpdateVendorFavoriteCount(ctxx, state.EntityID, state.FavoriteCount)
+	}
+	if err != nil {
+		logger.Error().Err(err).
+			Str("entity_type", state.EntityType).
+			Str("entity_id", state.EntityID).
+			Msg("Failed to index favorite count")
+	}
 }
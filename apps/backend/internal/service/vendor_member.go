@@ -0,0 +1,251 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/labstack/echo/v4"
+)
+
+type VendorMemberService struct {
+	server     *server.Server
+	memberRepo *repository.VendorMemberRepository
+}
+
+func NewVendorMemberService(s *server.Server, memberRepo *repository.VendorMemberRepository) *VendorMemberService {
+	return &VendorMemberService{
+		server:     s,
+		memberRepo: memberRepo,
+	}
+}
+
+//-- ==================================================
+//-- MEMBERSHIPS
+//-- ==================================================
+
+// GetMyMemberships lists the vendors the user works at, e.g. for a vendor
+// switcher; the chosen one is sent back in the X-Vendor-ID header.
+func (s *VendorMemberService) GetMyMemberships(ctx echo.Context, userID string) ([]vendor.Membership, error) {
+	return s.memberRepo.ListMemberships(ctx.Request().Context(), userID)
+}
+
+func (s *VendorMemberService) GetMembers(ctx echo.Context, vendorID string) ([]vendor.PopulatedVendorMember, error) {
+	return s.memberRepo.ListMembers(ctx.Request().Context(), vendorID)
+}
+
+// UpdateMemberRole changes a member's role. Both the member's current role
+// and the new one must be below the caller's.
+func (s *VendorMemberService) UpdateMemberRole(ctx echo.Context, vendorID, actorRole string, payload *vendor.UpdateMemberRolePayload) (*vendor.VendorMember, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	m, err := s.memberRepo.GetMember(ctxx, vendorID, payload.ID)
+	if err != nil {
+		return nil, memberError(err)
+	}
+	if !vendor.CanManageRole(actorRole, m.Role) || !vendor.CanManageRole(actorRole, payload.Role) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "your role cannot manage this member")
+	}
+
+	updated, err := s.memberRepo.UpdateMemberRole(ctxx, vendorID, m.ID, payload.Role)
+	if err != nil {
+		return nil, memberError(err)
+	}
+
+	logger.Info().
+		Str("vendor_id", vendorID).
+		Str("member_id", m.ID).
+		Str("role", payload.Role).
+		Msg("Vendor member role updated")
+	return updated, nil
+}
+
+func (s *VendorMemberService) RemoveMember(ctx echo.Context, vendorID, actorRole string, payload *vendor.RemoveMemberPayload) error {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	m, err := s.memberRepo.GetMember(ctxx, vendorID, payload.ID)
+	if err != nil {
+		return memberError(err)
+	}
+	if !vendor.CanManageRole(actorRole, m.Role) {
+		return echo.NewHTTPError(http.StatusForbidden, "your role cannot manage this member")
+	}
+
+	if err := s.memberRepo.RemoveMember(ctxx, vendorID, m.ID); err != nil {
+		return memberError(err)
+	}
+
+	logger.Info().
+		Str("vendor_id", vendorID).
+		Str("member_id", m.ID).
+		Str("member_user_id", m.UserID).
+		Msg("Vendor member removed")
+	return nil
+}
+
+// LeaveVendor ends the user's own membership. The owner cannot leave their
+// vendor.
+func (s *VendorMemberService) LeaveVendor(ctx echo.Context, vendorID, userID, role string) error {
+	if role == vendor.RoleOwner {
+		return echo.NewHTTPError(http.StatusConflict, "the owner cannot leave their vendor")
+	}
+	if err := s.memberRepo.LeaveVendor(ctx.Request().Context(), vendorID, userID); err != nil {
+		return memberError(err)
+	}
+	return nil
+}
+
+//-- ==================================================
+//-- INVITATIONS
+//-- ==================================================
+
+// InviteMember emails an invitation to join the vendor with a role below the
+// caller's. Inviting an address again replaces its pending invitation.
+func (s *VendorMemberService) InviteMember(ctx echo.Context, vendorID, actorRole, inviterID string, payload *vendor.InviteMemberPayload) (*vendor.VendorInvitation, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	if !vendor.CanManageRole(actorRole, payload.Role) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "your role cannot invite a "+payload.Role)
+	}
+	// the token only travels in the email
+	if s.server.Job == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "invitations cannot be sent right now")
+	}
+
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.memberRepo.CreateInvitation(ctxx, &vendor.VendorInvitation{
+		VendorID:  vendorID,
+		Email:     strings.TrimSpace(payload.Email),
+		Role:      payload.Role,
+		TokenHash: tokenHash,
+		InvitedBy: &inviterID,
+		ExpiresAt: time.Now().Add(vendor.InvitationTTL),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyVendorMember) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "this person is already a member")
+		}
+		logger.Error().Err(err).Str("vendor_id", vendorID).Msg("Failed to create vendor invitation")
+		return nil, err
+	}
+
+	vendorName, inviterName, err := s.memberRepo.GetInvitationContext(ctxx, vendorID, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := job.NewVendorInvitationEmailTask(job.VendorInvitationEmailPayload{
+		InvitationID: inv.ID,
+		To:           inv.Email,
+		Data: map[string]string{
+			"VendorName":  vendorName,
+			"InviterName": inviterName,
+			"Role":        inv.Role,
+			"AcceptURL":   strings.TrimRight(s.server.Config.Primary.FrontendURL, "/") + "/vendor/invitations/accept?token=" + url.QueryEscape(token),
+			"ExpiresAt":   inv.ExpiresAt.Format("2 Jan 2006"),
+		},
+	})
+	if err == nil {
+		_, err = s.server.Job.Client.Enqueue(task)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("invitation_id", inv.ID).Msg("Failed to enqueue vendor invitation email")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("vendor_id", vendorID).
+		Str("invitation_id", inv.ID).
+		Str("role", inv.Role).
+		Msg("Vendor member invited")
+	return inv, nil
+}
+
+func (s *VendorMemberService) GetInvitations(ctx echo.Context, vendorID string) ([]vendor.VendorInvitation, error) {
+	return s.memberRepo.ListInvitations(ctx.Request().Context(), vendorID)
+}
+
+func (s *VendorMemberService) RevokeInvitation(ctx echo.Context, vendorID, actorRole string, payload *vendor.RevokeInvitationPayload) error {
+	ctxx := ctx.Request().Context()
+
+	inv, err := s.memberRepo.GetInvitation(ctxx, vendorID, payload.ID)
+	if err != nil {
+		return memberError(err)
+	}
+	if !vendor.CanManageRole(actorRole, inv.Role) {
+		return echo.NewHTTPError(http.StatusForbidden, "your role cannot manage this invitation")
+	}
+	if err := s.memberRepo.RevokeInvitation(ctxx, vendorID, inv.ID); err != nil {
+		return memberError(err)
+	}
+	return nil
+}
+
+// AcceptInvitation joins the user to the vendor the token was issued for.
+func (s *VendorMemberService) AcceptInvitation(ctx echo.Context, userID string, payload *vendor.AcceptInvitationPayload) (*vendor.VendorMember, error) {
+	logger := middleware.GetLogger(ctx)
+
+	m, err := s.memberRepo.AcceptInvitation(ctx.Request().Context(), hashInvitationToken(payload.Token), userID)
+	if err != nil {
+		return nil, memberError(err)
+	}
+
+	logger.Info().
+		Str("vendor_id", m.VendorID).
+		Str("role", m.Role).
+		Msg("Vendor invitation accepted")
+	return m, nil
+}
+
+//-- ==================================================
+//-- HELPERS
+//-- ==================================================
+
+// newInvitationToken returns a random token and the hash it is stored as.
+func newInvitationToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func memberError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrVendorMemberNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "member not found")
+	case errors.Is(err, repository.ErrInvitationNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "invitation not found")
+	case errors.Is(err, repository.ErrInvitationExpired):
+		return echo.NewHTTPError(http.StatusGone, "invitation has expired")
+	case errors.Is(err, repository.ErrInvitationEmailMismatch):
+		return echo.NewHTTPError(http.StatusForbidden, "this invitation was sent to another email address")
+	case errors.Is(err, repository.ErrAlreadyVendorMember):
+		return echo.NewHTTPError(http.StatusConflict, "you are already a member of this vendor")
+	}
+	return err
+}
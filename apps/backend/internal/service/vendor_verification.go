@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/lib/email"
+	"github.com/gitSanje/khajaride/internal/lib/job"
+	"github.com/gitSanje/khajaride/internal/middleware"
+	"github.com/gitSanje/khajaride/internal/model"
+	"github.com/gitSanje/khajaride/internal/model/vendor"
+	"github.com/gitSanje/khajaride/internal/repository"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxDocumentSize is the largest verification document accepted
+const maxDocumentSize = 10 << 20
+
+// documentExtensions are the accepted document types, by sniffed content type
+var documentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+type VendorVerificationService struct {
+	server           *server.Server
+	verificationRepo *repository.VendorVerificationRepository
+	searchRepo       *repository.SearchRepository
+	awsClient        *aws.AWS
+}
+
+func NewVendorVerificationService(s *server.Server, verificationRepo *repository.VendorVerificationRepository, searchRepo *repository.SearchRepository, awsClient *aws.AWS) *VendorVerificationService {
+	return &VendorVerificationService{
+		server:           s,
+		verificationRepo: verificationRepo,
+		searchRepo:       searchRepo,
+		awsClient:        awsClient,
+	}
+}
+
+//-- ==================================================
+//-- VENDOR: DOCUMENTS & SUBMISSION
+//-- ==================================================
+
+func (s *VendorVerificationService) GetMyVerification(ctx echo.Context, vendorID, vendorUserID string) (*vendor.Verification, error) {
+	v, err := s.verificationRepo.GetOwnedVendor(ctx.Request().Context(), vendorID, vendorUserID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	return s.verification(ctx.Request().Context(), v)
+}
+
+// UploadDocument stores a document in the private bucket and replaces the
+// vendor's previous document of that type. Documents are frozen while the
+// vendor is under review or rejected.
+func (s *VendorVerificationService) UploadDocument(ctx echo.Context, vendorID, vendorUserID string, payload *vendor.UploadDocumentPayload, file *multipart.FileHeader) (*vendor.VendorDocument, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	v, err := s.verificationRepo.GetOwnedVendor(ctxx, vendorID, vendorUserID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	if v.Status == vendor.StatusPendingVerification || v.Status == vendor.StatusRejected {
+		return nil, echo.NewHTTPError(http.StatusConflict, "documents cannot be changed while the vendor is "+v.Status)
+	}
+
+	var expiry *time.Time
+	if payload.ExpiryDate != nil {
+		d, err := time.Parse("2006-01-02", *payload.ExpiryDate)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "expiryDate must be YYYY-MM-DD")
+		}
+		if !d.After(time.Now()) {
+			return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "document has already expired")
+		}
+		expiry = &d
+	}
+
+	// 1️⃣ Read and check the file
+	if file.Size > maxDocumentSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "document must be 10 MB or smaller")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read document")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxDocumentSize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read document")
+	}
+	if len(data) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "document is empty")
+	}
+	if len(data) > maxDocumentSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "document must be 10 MB or smaller")
+	}
+
+	// the declared type is not trusted; the content decides
+	contentType := http.DetectContentType(data)
+	ext, ok := documentExtensions[contentType]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, "document must be a PDF, JPEG, PNG or WebP file")
+	}
+
+	// 2️⃣ Upload under a fresh key so the document under review never changes
+	bucket := s.server.Config.AWS.UploadBucket
+	key := fmt.Sprintf("vendors/%s/documents/%s/%s%s", v.ID, payload.DocumentType, uuid.NewString(), ext)
+	if err := s.awsClient.S3.PutObject(ctxx, bucket, key, contentType, data); err != nil {
+		logger.Error().Err(err).Str("vendor_id", v.ID).Msg("Failed to upload vendor document")
+		return nil, err
+	}
+
+	size := int64(len(data))
+	doc, previousKey, err := s.verificationRepo.UpsertDocument(ctxx, &vendor.VendorDocument{
+		VendorUserID:   vendorUserID,
+		DocumentType:   payload.DocumentType,
+		FileKey:        key,
+		ContentType:    &contentType,
+		FileSize:       &size,
+		DocumentNumber: payload.DocumentNumber,
+		ExpiryDate:     expiry,
+	})
+	if err != nil {
+		s.deleteDocumentFile(ctx, key)
+		return nil, err
+	}
+	if previousKey != nil && *previousKey != key {
+		s.deleteDocumentFile(ctx, *previousKey)
+	}
+
+	logger.Info().
+		Str("vendor_id", v.ID).
+		Str("document_type", doc.DocumentType).
+		Msg("Vendor document uploaded")
+
+	s.presign(ctxx, doc)
+	return doc, nil
+}
+
+// DeleteDocument removes a document of a vendor still in draft; later on a
+// document can only be replaced.
+func (s *VendorVerificationService) DeleteDocument(ctx echo.Context, vendorID, vendorUserID string, payload *vendor.DeleteDocumentPayload) error {
+	ctxx := ctx.Request().Context()
+
+	v, err := s.verificationRepo.GetOwnedVendor(ctxx, vendorID, vendorUserID)
+	if err != nil {
+		return mapVerificationError(err)
+	}
+	if v.Status != vendor.StatusDraft {
+		return echo.NewHTTPError(http.StatusConflict, "documents can only be deleted before submitting; upload a replacement instead")
+	}
+
+	key, err := s.verificationRepo.DeleteDocument(ctxx, vendorUserID, payload.ID)
+	if err != nil {
+		return mapVerificationError(err)
+	}
+	s.deleteDocumentFile(ctx, key)
+	return nil
+}
+
+// Submit sends the vendor for review.
+func (s *VendorVerificationService) Submit(ctx echo.Context, vendorID, vendorUserID string) (*vendor.Verification, error) {
+	return s.ownerTransition(ctx, vendorID, vendorUserID, vendor.ActionSubmitted)
+}
+
+// Activate takes a verified vendor live.
+func (s *VendorVerificationService) Activate(ctx echo.Context, vendorID, vendorUserID string) (*vendor.Verification, error) {
+	return s.ownerTransition(ctx, vendorID, vendorUserID, vendor.ActionActivated)
+}
+
+func (s *VendorVerificationService) ownerTransition(ctx echo.Context, vendorID, vendorUserID, action string) (*vendor.Verification, error) {
+	ctxx := ctx.Request().Context()
+
+	v, err := s.verificationRepo.GetOwnedVendor(ctxx, vendorID, vendorUserID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	updated, err := s.TransitionVendor(ctxx, &vendor.Transition{VendorID: v.ID, Action: action})
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	return s.verification(ctxx, updated)
+}
+
+//-- ==================================================
+//-- ADMIN: REVIEW QUEUE & DECISIONS
+//-- ==================================================
+
+func (s *VendorVerificationService) GetQueue(ctx echo.Context, query *vendor.GetVerificationQueueQuery) (*model.PaginatedResponse[vendor.VerificationQueueItem], error) {
+	return s.verificationRepo.GetVerificationQueue(ctx.Request().Context(), query)
+}
+
+func (s *VendorVerificationService) GetVerification(ctx echo.Context, payload *vendor.GetVerificationPayload) (*vendor.Verification, error) {
+	v, err := s.verificationRepo.GetVendor(ctx.Request().Context(), payload.VendorID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	return s.verification(ctx.Request().Context(), v)
+}
+
+func (s *VendorVerificationService) Approve(ctx echo.Context, adminID string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	return s.review(ctx, adminID, vendor.ActionApproved, payload)
+}
+
+func (s *VendorVerificationService) Reject(ctx echo.Context, adminID string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	return s.review(ctx, adminID, vendor.ActionRejected, payload)
+}
+
+func (s *VendorVerificationService) RequestChanges(ctx echo.Context, adminID string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	return s.review(ctx, adminID, vendor.ActionRequestedChanges, payload)
+}
+
+func (s *VendorVerificationService) Suspend(ctx echo.Context, adminID string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	return s.review(ctx, adminID, vendor.ActionSuspended, payload)
+}
+
+func (s *VendorVerificationService) Reinstate(ctx echo.Context, adminID string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	return s.review(ctx, adminID, vendor.ActionReinstated, payload)
+}
+
+func (s *VendorVerificationService) review(ctx echo.Context, adminID, action string, payload *vendor.ReviewVendorPayload) (*vendor.Verification, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	// the vendor is told why, so only approving and reinstating may go
+	// without remarks
+	needsRemarks := action != vendor.ActionApproved && action != vendor.ActionReinstated
+	if needsRemarks && (payload.Remarks == nil || *payload.Remarks == "") {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "remarks are required")
+	}
+	if len(payload.RejectDocumentIDs) > 0 && action != vendor.ActionRequestedChanges {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "rejectDocumentIds only apply when requesting changes")
+	}
+
+	t := &vendor.Transition{
+		VendorID:          payload.VendorID,
+		Action:            action,
+		AdminID:           &adminID,
+		Remarks:           payload.Remarks,
+		RejectDocumentIDs: payload.RejectDocumentIDs,
+	}
+	if action == vendor.ActionSuspended {
+		t.SuspensionReason = vendor.SuspensionAdmin
+	}
+
+	updated, err := s.TransitionVendor(ctxx, t)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+
+	logger.Info().
+		Str("vendor_id", updated.ID).
+		Str("admin_id", adminID).
+		Str("action", action).
+		Str("status", updated.Status).
+		Msg("Vendor verification reviewed")
+
+	return s.verification(ctxx, updated)
+}
+
+// ReviewDocument approves or rejects a single document, e.g. a renewed
+// certificate of a vendor that is already live. Approving the last renewal
+// a vendor suspended for lapsed documents was waiting on reinstates it.
+func (s *VendorVerificationService) ReviewDocument(ctx echo.Context, adminID string, payload *vendor.ReviewDocumentPayload) (*vendor.VendorDocument, error) {
+	logger := middleware.GetLogger(ctx)
+	ctxx := ctx.Request().Context()
+
+	v, err := s.verificationRepo.GetVendor(ctxx, payload.VendorID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	if v.VendorUserID == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "vendor document not found")
+	}
+
+	doc, err := s.verificationRepo.ReviewDocument(ctxx, *v.VendorUserID, payload.DocumentID, payload.Status, payload.Remarks, adminID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+
+	if doc.Status == vendor.DocumentApproved && isComplianceSuspended(v) {
+		// the review itself stands even if reinstating fails; the vendor
+		// can still be reinstated by hand
+		if err := s.reinstateIfCompliant(ctxx, v, adminID); err != nil {
+			logger.Error().Err(err).Str("vendor_id", v.ID).Msg("Failed to reinstate compliant vendor")
+		}
+	}
+
+	s.presign(ctxx, doc)
+	return doc, nil
+}
+
+func (s *VendorVerificationService) reinstateIfCompliant(ctx context.Context, v *vendor.Vendor, adminID string) error {
+	docs, err := s.verificationRepo.ListDocuments(ctx, *v.VendorUserID)
+	if err != nil {
+		return err
+	}
+	if !vendor.BuildCompliance(v, docs, time.Now()).Compliant {
+		return nil
+	}
+
+	remarks := "Renewed documents approved"
+	_, err = s.TransitionVendor(ctx, &vendor.Transition{
+		VendorID: v.ID,
+		Action:   vendor.ActionReinstated,
+		AdminID:  &adminID,
+		Remarks:  &remarks,
+	})
+	return err
+}
+
+func isComplianceSuspended(v *vendor.Vendor) bool {
+	return v.Status == vendor.StatusSuspended && v.SuspensionReason != nil && *v.SuspensionReason == vendor.SuspensionCompliance
+}
+
+//-- ==================================================
+//-- COMPLIANCE
+//-- ==================================================
+
+func (s *VendorVerificationService) GetMyCompliance(ctx echo.Context, vendorID, vendorUserID string) (*vendor.Compliance, error) {
+	v, err := s.verificationRepo.GetOwnedVendor(ctx.Request().Context(), vendorID, vendorUserID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	return s.compliance(ctx.Request().Context(), v)
+}
+
+func (s *VendorVerificationService) GetCompliance(ctx echo.Context, payload *vendor.GetVerificationPayload) (*vendor.Compliance, error) {
+	v, err := s.verificationRepo.GetVendor(ctx.Request().Context(), payload.VendorID)
+	if err != nil {
+		return nil, mapVerificationError(err)
+	}
+	return s.compliance(ctx.Request().Context(), v)
+}
+
+func (s *VendorVerificationService) compliance(ctx context.Context, v *vendor.Vendor) (*vendor.Compliance, error) {
+	var docs []vendor.VendorDocument
+	if v.VendorUserID != nil {
+		var err error
+		docs, err = s.verificationRepo.ListDocuments(ctx, *v.VendorUserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vendor.BuildCompliance(v, docs, time.Now()), nil
+}
+
+//-- ==================================================
+//-- TRANSITIONS
+//-- ==================================================
+
+// TransitionVendor applies a status change and its side effects: the search
+// index follows the new status and review outcomes and reinstatements are
+// emailed to the owner. Both are side effects and only logged on failure.
+// Compliance suspensions are made by the daily consumer, which does the
+// same.
+func (s *VendorVerificationService) TransitionVendor(ctx context.Context, t *vendor.Transition) (*vendor.Vendor, error) {
+	updated, err := s.verificationRepo.Transition(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.searchRepo.UpdateVendorStatus(ctx, updated.ID, updated.Status); err != nil {
+		s.server.Logger.Error().Err(err).Str("vendor_id", updated.ID).Msg("Failed to index vendor status")
+	}
+
+	var outcome string
+	switch t.Action {
+	case vendor.ActionApproved:
+		outcome = email.VerificationApproved
+	case vendor.ActionRequestedChanges:
+		outcome = email.VerificationChangesRequested
+	case vendor.ActionRejected:
+		outcome = email.VerificationRejected
+	case vendor.ActionReinstated:
+		outcome = email.VerificationReinstated
+	}
+	if outcome != "" {
+		s.sendResultEmail(ctx, updated, outcome, t.Remarks)
+	}
+	return updated, nil
+}
+
+func (s *VendorVerificationService) sendResultEmail(ctx context.Context, v *vendor.Vendor, outcome string, remarks *string) {
+	if s.server.Job == nil {
+		return
+	}
+	to, err := s.verificationRepo.GetOwnerEmail(ctx, v.ID)
+	if err != nil || to == "" {
+		if err != nil {
+			s.server.Logger.Error().Err(err).Str("vendor_id", v.ID).Msg("Failed to load vendor owner email")
+		}
+		return
+	}
+
+	task, err := job.NewVendorVerificationResultEmailTask(job.VendorVerificationResultEmailPayload{
+		To:         to,
+		VendorName: v.Name,
+		Outcome:    outcome,
+		Reason:     deref(remarks),
+	})
+	if err == nil {
+		_, err = s.server.Job.Client.Enqueue(task)
+	}
+	if err != nil {
+		s.server.Logger.Error().Err(err).Str("vendor_id", v.ID).Msg("Failed to enqueue vendor verification email")
+	}
+}
+
+//-- ==================================================
+//-- HELPERS
+//-- ==================================================
+
+func (s *VendorVerificationService) verification(ctx context.Context, v *vendor.Vendor) (*vendor.Verification, error) {
+	res := &vendor.Verification{
+		VendorID:    v.ID,
+		VendorName:  v.Name,
+		Status:      v.Status,
+		SubmittedAt: v.SubmittedAt,
+		Documents:   []vendor.VendorDocument{},
+		Logs:        []vendor.VerificationLog{},
+	}
+
+	if v.VendorUserID != nil {
+		docs, err := s.verificationRepo.ListDocuments(ctx, *v.VendorUserID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range docs {
+			s.presign(ctx, &docs[i])
+		}
+		res.Documents = docs
+	}
+	res.MissingDocuments = vendor.MissingDocuments(res.Documents)
+
+	logs, err := s.verificationRepo.ListLogs(ctx, v.ID)
+	if err != nil {
+		return nil, err
+	}
+	res.Logs = logs
+	return res, nil
+}
+
+// presign sets a short-lived link to the document; the bucket is private.
+func (s *VendorVerificationService) presign(ctx context.Context, doc *vendor.VendorDocument) {
+	url, err := s.awsClient.S3.CreatePresignedUrl(ctx, s.server.Config.AWS.UploadBucket, doc.FileKey)
+	if err != nil {
+		s.server.Logger.Error().Err(err).Str("document_id", doc.ID).Msg("Failed to presign vendor document")
+		return
+	}
+	doc.URL = url
+}
+
+func (s *VendorVerificationService) deleteDocumentFile(ctx echo.Context, key string) {
+	if err := s.awsClient.S3.DeleteObject(ctx.Request().Context(), s.server.Config.AWS.UploadBucket, key); err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Str("key", key).Msg("Failed to delete vendor document file")
+	}
+}
+
+func mapVerificationError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrVendorNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "vendor not found")
+	case errors.Is(err, repository.ErrVendorDocumentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "vendor document not found")
+	case errors.Is(err, repository.ErrInvalidVendorTransition):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrMissingVendorDocuments):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, repository.ErrVendorMenuEmpty):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "add at least one available menu item before going live")
+	default:
+		return err
+	}
+}
@@ -22,9 +22,11 @@ type TestDB struct {
 	Config    *config.Config
 }
 
-// SetupTestDB creates a Postgres container and applies migrations
+// SetupTestDB creates a Postgres container and applies migrations; the test
+// is skipped when Docker is not available
 func SetupTestDB(t *testing.T) (*TestDB, func()) {
 	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
 
 	ctx := context.Background()
 	dbName := fmt.Sprintf("test_db_%s", uuid.New().String()[:8])
@@ -0,0 +1,73 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gitSanje/khajaride/internal/config"
+	"github.com/gitSanje/khajaride/internal/lib/aws"
+	"github.com/gitSanje/khajaride/internal/server"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// SetupTestS3 starts MinIO as a local S3-compatible stand-in, points the
+// server's AWS config at it through EndpointURL and creates the upload
+// bucket. Presigned URLs it hands out work against the container. The test
+// is skipped when Docker is not available.
+func SetupTestS3(t *testing.T, s *server.Server) *aws.AWS {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	accessKey := "testaccess"
+	secretKey := "testsecret"
+
+	req := testcontainers.ContainerRequest{
+		Image:        "minio/minio:latest",
+		ExposedPorts: []string{"9000/tcp"},
+		Env: map[string]string{
+			"MINIO_ROOT_USER":     accessKey,
+			"MINIO_ROOT_PASSWORD": secretKey,
+		},
+		Cmd:        []string{"server", "/data"},
+		WaitingFor: wait.ForHTTP("/minio/health/live").WithPort("9000/tcp").WithStartupTimeout(30 * time.Second),
+	}
+
+	s3Container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err, "failed to start minio container")
+
+	t.Cleanup(func() {
+		if err := s3Container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate container: %v", err)
+		}
+	})
+
+	host, err := s3Container.Host(ctx)
+	require.NoError(t, err, "failed to get container host")
+
+	mappedPort, err := s3Container.MappedPort(ctx, "9000")
+	require.NoError(t, err, "failed to get mapped port")
+
+	s.Config.AWS = config.AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		UploadBucket:    "test-uploads",
+		EndpointURL:     fmt.Sprintf("http://%s:%d", host, mappedPort.Int()),
+	}
+
+	awsClient, err := aws.NewAWS(s)
+	require.NoError(t, err, "failed to create AWS client")
+
+	err = awsClient.S3.EnsureBucket(ctx, s.Config.AWS.UploadBucket)
+	require.NoError(t, err, "failed to create upload bucket")
+
+	return awsClient
+}